@@ -0,0 +1,56 @@
+// Package vortludo embeds the web assets (templates and static files) so the
+// server can be deployed as a single binary.
+package vortludo
+
+import (
+	"embed"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// assetsDir, when set, makes the server read templates and static files from
+// disk instead of the embedded copies. It points at the repository root (the
+// directory containing templates/ and static/).
+var assetsDir string
+
+//go:embed templates
+var embeddedTemplates embed.FS
+
+//go:embed static
+var embeddedStatic embed.FS
+
+// TemplatesFS returns the filesystem containing index.html and partials/.
+func TemplatesFS() fs.FS {
+	return subFS(embeddedTemplates, "templates")
+}
+
+// StaticFS returns the filesystem containing client.js, theme.js, style.css and
+// favicons/.
+func StaticFS() fs.FS {
+	return subFS(embeddedStatic, "static")
+}
+
+// UseAssetsDir serves assets from the repository root dir instead of the
+// embedded copies; an empty dir keeps the embedded ones. It must be called
+// before the filesystems are first requested.
+func UseAssetsDir(dir string) {
+	assetsDir = dir
+}
+
+// FromDisk reports whether assets are being served from disk.
+func FromDisk() bool {
+	return assetsDir != ""
+}
+
+func subFS(embedded embed.FS, dir string) fs.FS {
+	if assetsDir != "" {
+		return os.DirFS(filepath.Join(assetsDir, dir))
+	}
+	sub, err := fs.Sub(embedded, dir)
+	if err != nil {
+		// The directory is fixed at compile time by the go:embed directive.
+		panic(err)
+	}
+	return sub
+}
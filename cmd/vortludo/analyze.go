@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	config "github.com/CodeAndHammer/vortludo/internal/config"
+	curation "github.com/CodeAndHammer/vortludo/internal/curation"
+	game "github.com/CodeAndHammer/vortludo/internal/game"
+	server "github.com/CodeAndHammer/vortludo/internal/server"
+)
+
+// errProblems makes `analyze -strict` exit with an error status.
+var errProblems = errors.New("the word list has problems")
+
+// analyze reviews the configured word list and prints the report, for
+// maintainers balancing the dictionary.
+func analyze(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("analyze", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: vortludo [-config file] analyze [flags]")
+		flags.PrintDefaults()
+	}
+	asJSON := flags.Bool("json", false, "print the report as JSON")
+	top := flags.Int("top", curation.DefaultTop, "list this many of the easiest and hardest words")
+	minHint := flags.Int("min-hint", curation.DefaultMinHintLength, "report hints shorter than this many characters")
+	strict := flags.Bool("strict", false, "exit with an error if any problems are found")
+	flags.Parse(args)
+
+	dict, err := server.OpenDictionary(cfg.Words)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	words, err := dict.Words(ctx)
+	if err != nil {
+		return err
+	}
+	accepted, err := dict.Accepted(ctx)
+	if err != nil {
+		return err
+	}
+	blocked, err := game.LoadBlockedWords(cfg.Words.BlockedFile)
+	if err != nil {
+		return err
+	}
+
+	report := curation.Analyze(words, curation.Options{
+		MinHintLength: *minHint,
+		Top:           *top,
+		Accepted:      accepted,
+		Blocked:       blocked,
+	})
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(report)
+	} else {
+		err = report.WriteText(os.Stdout)
+	}
+	if err != nil {
+		return err
+	}
+	if *strict && report.Problems() > 0 {
+		return errProblems
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"os/signal"
+	"slices"
+	"time"
+
+	config "github.com/CodeAndHammer/vortludo/internal/config"
+	loadtest "github.com/CodeAndHammer/vortludo/internal/loadtest"
+	server "github.com/CodeAndHammer/vortludo/internal/server"
+)
+
+// loadTest plays against a running server and prints latency percentiles,
+// so performance regressions show up before a release.
+func loadTest(cfg *config.Config, args []string) error {
+	scheme := "http"
+	if cfg.Server.TLS() {
+		scheme = "https"
+	}
+	flags := flag.NewFlagSet("loadtest", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: vortludo [-config file] loadtest [flags]")
+		flags.PrintDefaults()
+	}
+	serverURL := flags.String("url", scheme+"://localhost:"+cfg.Server.Port, "base `URL` of the server to test")
+	concurrency := flags.Int("c", loadtest.DefaultConcurrency, "number of players to run at once")
+	duration := flags.Duration("d", loadtest.DefaultDuration, "how long to run")
+	invalid := flags.Float64("invalid", loadtest.DefaultInvalidRate, "share of guesses that are not words")
+	state := flags.Float64("state", loadtest.DefaultStateRate, "share of turns that read the board instead of guessing")
+	asJSON := flags.Bool("json", false, "print the report as JSON")
+	maxP99 := flags.Duration("max-p99", 0, "exit with an error if any request type's 99th percentile is slower than this")
+	flags.Parse(args)
+
+	dict, err := server.OpenDictionary(cfg.Words)
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	accepted, err := dict.Accepted(ctx)
+	if err != nil {
+		return err
+	}
+
+	report, err := loadtest.Run(ctx, loadtest.Config{
+		URL:         *serverURL,
+		Concurrency: *concurrency,
+		Duration:    *duration,
+		Words:       slices.Sorted(maps.Keys(accepted)),
+		InvalidRate: *invalid,
+		StateRate:   *state,
+	})
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(report)
+	} else {
+		err = report.WriteText(os.Stdout)
+	}
+	if err != nil {
+		return err
+	}
+	if *maxP99 > 0 && report.SlowestP99() > *maxP99 {
+		return fmt.Errorf("99th percentile latency %s exceeds %s", report.SlowestP99().Round(time.Microsecond), *maxP99)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"os/signal"
+	"syscall"
+
+	config "github.com/CodeAndHammer/vortludo/internal/config"
+	server "github.com/CodeAndHammer/vortludo/internal/server"
+	util "github.com/CodeAndHammer/vortludo/internal/util"
+)
+
+func main() {
+	configFile := flag.String("config", "", "read settings from this YAML or TOML `file`; environment variables take precedence")
+	printConfig := flag.Bool("print-config", false, "print the configuration and exit")
+	flag.Parse()
+
+	cfg, err := config.Load(*configFile)
+	if err != nil {
+		util.LogFatal("Invalid configuration:\n%v", err)
+	}
+	if *printConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			util.LogFatal("Failed to print configuration: %v", err)
+		}
+		return
+	}
+	switch command := flag.Arg(0); command {
+	case "":
+	case "analyze":
+		if err := analyze(cfg, flag.Args()[1:]); err != nil {
+			util.LogFatal("Analysis failed: %v", err)
+		}
+		return
+	case "loadtest":
+		if err := loadTest(cfg, flag.Args()[1:]); err != nil {
+			util.LogFatal("Load test failed: %v", err)
+		}
+		return
+	default:
+		util.LogFatal("Unknown command %q; the commands are analyze and loadtest", command)
+	}
+	srv, err := server.New(*cfg)
+	if err != nil {
+		util.LogFatal("Failed to start: %v", err)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	// A second signal during shutdown stops the process at once.
+	context.AfterFunc(ctx, stop)
+	if err := srv.Run(ctx); err != nil {
+		util.LogFatal("Server exited: %v", err)
+	}
+}
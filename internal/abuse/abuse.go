@@ -0,0 +1,198 @@
+// Package abuse bans clients that keep misbehaving, e.g. by exceeding the
+// rate limit or sending bad CSRF tokens, for a while. Every ban a client
+// earns lasts twice as long as its previous one.
+package abuse
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultThreshold  = 20
+	DefaultWindow     = time.Minute
+	DefaultBaseBan    = time.Minute
+	DefaultMaxBan     = time.Hour
+	DefaultForget     = 24 * time.Hour
+	DefaultMaxTracked = 10000
+)
+
+// Config sets when clients are banned and for how long. Zero values take
+// the defaults.
+type Config struct {
+	// Threshold strikes within Window earn a ban.
+	Threshold int
+	Window    time.Duration
+	// BaseBan is the length of a first ban; each later one doubles, up to
+	// MaxBan.
+	BaseBan time.Duration
+	MaxBan  time.Duration
+	// Forget is how long a client must behave before its ban history is
+	// dropped and its next ban is a first one again.
+	Forget time.Duration
+	// MaxTracked caps the number of clients tracked at once.
+	MaxTracked int
+}
+
+// Ban is a client's current ban.
+type Ban struct {
+	Key    string    `json:"key"`
+	Reason string    `json:"reason"`
+	Until  time.Time `json:"until"`
+	// Level counts the client's bans; the ban lasted BaseBan*2^(Level-1).
+	Level int `json:"level"`
+}
+
+type client struct {
+	strikes     int
+	windowStart time.Time
+	lastStrike  time.Time
+	level       int
+	reason      string
+	bannedUntil time.Time
+}
+
+// Tracker counts strikes against clients and bans the ones over the
+// threshold. Clients are identified by opaque keys such as "ip:192.0.2.1".
+// A nil Tracker bans nobody.
+type Tracker struct {
+	cfg Config
+
+	mu      sync.Mutex
+	clients map[string]*client
+}
+
+func NewTracker(cfg Config) *Tracker {
+	cfg.Threshold = cmp.Or(cfg.Threshold, DefaultThreshold)
+	cfg.Window = cmp.Or(cfg.Window, DefaultWindow)
+	cfg.BaseBan = cmp.Or(cfg.BaseBan, DefaultBaseBan)
+	cfg.MaxBan = max(cmp.Or(cfg.MaxBan, DefaultMaxBan), cfg.BaseBan)
+	cfg.Forget = cmp.Or(cfg.Forget, DefaultForget)
+	cfg.MaxTracked = cmp.Or(cfg.MaxTracked, DefaultMaxTracked)
+	return &Tracker{cfg: cfg, clients: make(map[string]*client)}
+}
+
+// Strike records an offence by key. It returns the ban when this strike
+// earned one.
+func (t *Tracker) Strike(key, reason string, now time.Time) (Ban, bool) {
+	if t == nil {
+		return Ban{}, false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	c, ok := t.clients[key]
+	if !ok {
+		if len(t.clients) >= t.cfg.MaxTracked {
+			t.cleanupLocked(now)
+			if len(t.clients) >= t.cfg.MaxTracked {
+				return Ban{}, false
+			}
+		}
+		c = &client{}
+		t.clients[key] = c
+	}
+	if now.Sub(c.windowStart) > t.cfg.Window {
+		c.strikes, c.windowStart = 0, now
+	}
+	c.strikes++
+	c.lastStrike = now
+	if c.strikes < t.cfg.Threshold || now.Before(c.bannedUntil) {
+		return Ban{}, false
+	}
+
+	c.strikes = 0
+	c.level++
+	c.reason = reason
+	c.bannedUntil = now.Add(t.banLength(c.level))
+	return t.ban(key, c), true
+}
+
+func (t *Tracker) banLength(level int) time.Duration {
+	length := t.cfg.BaseBan
+	for range level - 1 {
+		if length >= t.cfg.MaxBan/2 {
+			return t.cfg.MaxBan
+		}
+		length *= 2
+	}
+	return min(length, t.cfg.MaxBan)
+}
+
+func (t *Tracker) ban(key string, c *client) Ban {
+	return Ban{Key: key, Reason: c.reason, Until: c.bannedUntil, Level: c.level}
+}
+
+// Banned returns the ban of the first of keys that is banned at now.
+func (t *Tracker) Banned(now time.Time, keys ...string) (Ban, bool) {
+	if t == nil {
+		return Ban{}, false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, key := range keys {
+		if c, ok := t.clients[key]; ok && now.Before(c.bannedUntil) {
+			return t.ban(key, c), true
+		}
+	}
+	return Ban{}, false
+}
+
+// Bans lists the bans in force at now, the longest-running first.
+func (t *Tracker) Bans(now time.Time) []Ban {
+	bans := []Ban{}
+	if t == nil {
+		return bans
+	}
+	t.mu.Lock()
+	for key, c := range t.clients {
+		if now.Before(c.bannedUntil) {
+			bans = append(bans, t.ban(key, c))
+		}
+	}
+	t.mu.Unlock()
+	slices.SortFunc(bans, func(a, b Ban) int {
+		return cmp.Or(b.Until.Compare(a.Until), cmp.Compare(a.Key, b.Key))
+	})
+	return bans
+}
+
+// Lift ends key's ban and forgets its history. It reports whether key was
+// tracked.
+func (t *Tracker) Lift(key string) bool {
+	if t == nil {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, ok := t.clients[key]
+	delete(t.clients, key)
+	return ok
+}
+
+// Cleanup forgets clients that are not banned and have behaved for the
+// Forget period, or for the strike window if they were never banned.
+func (t *Tracker) Cleanup(now time.Time) int {
+	if t == nil {
+		return 0
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.cleanupLocked(now)
+}
+
+func (t *Tracker) cleanupLocked(now time.Time) int {
+	removed := 0
+	for key, c := range t.clients {
+		idle := t.cfg.Forget
+		if c.level == 0 {
+			idle = t.cfg.Window
+		}
+		if !now.Before(c.bannedUntil) && now.Sub(c.lastStrike) > idle {
+			delete(t.clients, key)
+			removed++
+		}
+	}
+	return removed
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	abuse "github.com/CodeAndHammer/vortludo/internal/abuse"
+)
+
+func TestBansDoubleUpToMax(t *testing.T) {
+	tracker := abuse.NewTracker(abuse.Config{Threshold: 3, Window: time.Minute, BaseBan: time.Minute, MaxBan: 3 * time.Minute})
+	now := time.Now()
+	var lengths []time.Duration
+	for range 4 {
+		var ban abuse.Ban
+		for i := range 3 {
+			b, banned := tracker.Strike("ip:192.0.2.1", "rate limit exceeded", now)
+			if banned != (i == 2) {
+				t.Fatalf("Strike %d: banned = %v", i+1, banned)
+			}
+			ban = b
+		}
+		lengths = append(lengths, ban.Until.Sub(now))
+		if _, ok := tracker.Banned(now, "ip:192.0.2.1"); !ok {
+			t.Fatal("Expected the client to be banned")
+		}
+		now = ban.Until
+	}
+	want := []time.Duration{time.Minute, 2 * time.Minute, 3 * time.Minute, 3 * time.Minute}
+	for i := range want {
+		if lengths[i] != want[i] {
+			t.Errorf("Ban %d lasted %s, want %s", i+1, lengths[i], want[i])
+		}
+	}
+}
+
+func TestStrikesOutsideWindowDoNotBan(t *testing.T) {
+	tracker := abuse.NewTracker(abuse.Config{Threshold: 2, Window: time.Minute})
+	now := time.Now()
+	tracker.Strike("session:a", "invalid csrf token", now)
+	if _, banned := tracker.Strike("session:a", "invalid csrf token", now.Add(2*time.Minute)); banned {
+		t.Error("Strikes in different windows should not add up")
+	}
+	if removed := tracker.Cleanup(now.Add(4 * time.Minute)); removed != 1 {
+		t.Errorf("Expected the unbanned client to be forgotten, removed %d", removed)
+	}
+}
+
+func TestLiftBan(t *testing.T) {
+	tracker := abuse.NewTracker(abuse.Config{Threshold: 1})
+	now := time.Now()
+	tracker.Strike("ip:192.0.2.1", "rate limit exceeded", now)
+	if bans := tracker.Bans(now); len(bans) != 1 || bans[0].Key != "ip:192.0.2.1" || bans[0].Level != 1 {
+		t.Fatalf("Unexpected bans: %+v", bans)
+	}
+	if !tracker.Lift("ip:192.0.2.1") {
+		t.Fatal("Expected the ban to be lifted")
+	}
+	if _, ok := tracker.Banned(now, "ip:192.0.2.1"); ok {
+		t.Error("Client still banned after lifting")
+	}
+	if tracker.Lift("ip:192.0.2.1") {
+		t.Error("Lifting an unknown client should report false")
+	}
+}
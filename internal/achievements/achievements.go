@@ -0,0 +1,92 @@
+// Package achievements is the registry of achievements players unlock by
+// finishing games, and the rules that award them. Unlocks are kept with the
+// player's stats, in the session or the signed-in account.
+package achievements
+
+import (
+	"slices"
+	"time"
+
+	auth "github.com/CodeAndHammer/vortludo/internal/auth"
+)
+
+const (
+	FirstWin      = "first_win"
+	DayStreak7    = "day_streak_7"
+	TwoGuessWin   = "two_guess_win"
+	NoHintsWin    = "no_hints_win"
+	HardWordSolve = "hard_word"
+)
+
+// Achievement is something a player can unlock.
+type Achievement struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Icon        string `json:"icon"`
+}
+
+var achievements = []Achievement{
+	{ID: FirstWin, Name: "First Win", Description: "Win your first game.", Icon: "🎉"},
+	{ID: DayStreak7, Name: "Week Streak", Description: "Win a game on 7 days in a row.", Icon: "🔥"},
+	{ID: TwoGuessWin, Name: "Sharpshooter", Description: "Win in 2 guesses or fewer.", Icon: "🎯"},
+	{ID: NoHintsWin, Name: "Unaided", Description: "Win without revealing a hint or asking the solver.", Icon: "🧠"},
+	{ID: HardWordSolve, Name: "Tough Nut", Description: "Solve a word that is hard to guess.", Icon: "💎"},
+}
+
+// All returns the achievements in the order they are listed.
+func All() []Achievement {
+	return slices.Clone(achievements)
+}
+
+// Game is what the rules need to know of a finished game.
+type Game struct {
+	Won     bool
+	Guesses int
+	// Hints counts the staged hints revealed and solver hints asked for.
+	Hints int
+	// HardWord is set when any of the game's words is hard to guess.
+	HardWord bool
+	At       time.Time
+}
+
+// Award adds game, which the caller already recorded in stats, to the day
+// streak and unlocks the achievements it earns. It returns those that are
+// new, in listing order.
+func Award(stats *auth.Stats, game Game) []Achievement {
+	if !game.Won {
+		return nil
+	}
+	stats.RecordWinDay(game.At)
+	earned := map[string]bool{
+		FirstWin:      true,
+		DayStreak7:    stats.DayStreak >= 7,
+		TwoGuessWin:   game.Guesses <= 2,
+		NoHintsWin:    game.Hints == 0,
+		HardWordSolve: game.HardWord,
+	}
+	var unlocked []Achievement
+	for _, a := range achievements {
+		if earned[a.ID] && stats.Unlock(a.ID, game.At) {
+			unlocked = append(unlocked, a)
+		}
+	}
+	return unlocked
+}
+
+// Status is an achievement and whether, and when, the player unlocked it.
+type Status struct {
+	Achievement
+	Unlocked   bool      `json:"unlocked"`
+	UnlockedAt time.Time `json:"unlockedAt,omitzero"`
+}
+
+// Statuses returns every achievement with the player's unlocks.
+func Statuses(stats auth.Stats) []Status {
+	statuses := make([]Status, len(achievements))
+	for i, a := range achievements {
+		at, ok := stats.Achievements[a.ID]
+		statuses[i] = Status{Achievement: a, Unlocked: ok, UnlockedAt: at}
+	}
+	return statuses
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	achievements "github.com/CodeAndHammer/vortludo/internal/achievements"
+	auth "github.com/CodeAndHammer/vortludo/internal/auth"
+)
+
+func ids(unlocked []achievements.Achievement) []string {
+	out := make([]string, len(unlocked))
+	for i, a := range unlocked {
+		out[i] = a.ID
+	}
+	return out
+}
+
+func TestAward(t *testing.T) {
+	day := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	var stats auth.Stats
+
+	if got := achievements.Award(&stats, achievements.Game{Won: false, Guesses: 6, At: day}); got != nil {
+		t.Errorf("A loss should unlock nothing, got %v", ids(got))
+	}
+	got := ids(achievements.Award(&stats, achievements.Game{Won: true, Guesses: 4, Hints: 1, At: day}))
+	if len(got) != 1 || got[0] != achievements.FirstWin {
+		t.Errorf("Expected only the first win, got %v", got)
+	}
+	got = ids(achievements.Award(&stats, achievements.Game{Won: true, Guesses: 2, HardWord: true, At: day}))
+	want := []string{achievements.TwoGuessWin, achievements.NoHintsWin, achievements.HardWordSolve}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Expected %v in listing order, got %v", want, got)
+		}
+	}
+	if got := achievements.Award(&stats, achievements.Game{Won: true, Guesses: 1, At: day}); got != nil {
+		t.Errorf("Achievements unlock once, got %v again", ids(got))
+	}
+
+	for i := 1; i < 7; i++ {
+		got = ids(achievements.Award(&stats, achievements.Game{Won: true, Guesses: 4, Hints: 1, At: day.AddDate(0, 0, i)}))
+	}
+	if stats.DayStreak != 7 || len(got) != 1 || got[0] != achievements.DayStreak7 {
+		t.Errorf("Expected the week streak on the 7th day in a row, got %v with a %d-day streak", got, stats.DayStreak)
+	}
+
+	unlocked := 0
+	for _, status := range achievements.Statuses(stats) {
+		if status.Unlocked {
+			unlocked++
+		}
+	}
+	if unlocked != len(achievements.All()) {
+		t.Errorf("Expected every achievement unlocked, got %d", unlocked)
+	}
+}
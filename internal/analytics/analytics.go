@@ -0,0 +1,398 @@
+package analytics
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultMaxTrackedKeys    = 2000
+	DefaultMaxDays           = 7
+	DefaultMaxSessionsPerDay = 100000
+	topN                     = 10
+)
+
+// GameResult is what the collector learns from a finished game.
+type GameResult struct {
+	Won         bool          `json:"won"`
+	Guesses     int           `json:"guesses"`
+	MissedWords []string      `json:"missedWords,omitempty"`
+	Words       []WordOutcome `json:"words"`
+	// Flagged is set when the game's session was flagged as a bot.
+	Flagged bool `json:"flagged,omitempty"`
+	// Untracked is set when the game's session opted out of analytics. The
+	// result is still logged, for recovery, but never counted.
+	Untracked bool `json:"untracked,omitempty"`
+}
+
+// WordOutcome is how a finished game went for one of its target words.
+type WordOutcome struct {
+	Word    string `json:"word"`
+	Solved  bool   `json:"solved"`
+	Guesses int    `json:"guesses"`
+}
+
+// Collector aggregates gameplay analytics in process. Every map is capped so
+// memory stays bounded regardless of traffic: keys beyond the cap are counted
+// in aggregate but not tracked individually.
+type Collector struct {
+	mu                sync.Mutex
+	gamesCompleted    int
+	gamesWon          int
+	gamesFlagged      int
+	guessesToWin      int
+	missedWords       *boundedCounter
+	firstGuesses      *boundedCounter
+	words             map[string]*wordTally
+	wordsOverflow     int
+	days              []*dayActivity
+	maxDays           int
+	maxSessionsPerDay int
+}
+
+type wordTally struct {
+	plays   int
+	solves  int
+	guesses int
+}
+
+// dayActivity is one UTC day's share of the totals, for daily digests.
+type dayActivity struct {
+	date     string
+	sessions map[string]struct{}
+	overflow int
+	games    int
+	won      int
+	flagged  int
+	// solvedIn counts won games by their number of guesses, from 1.
+	solvedIn []int
+	missed   *boundedCounter
+}
+
+func NewCollector() *Collector {
+	return &Collector{
+		missedWords:       newBoundedCounter(DefaultMaxTrackedKeys),
+		firstGuesses:      newBoundedCounter(DefaultMaxTrackedKeys),
+		words:             make(map[string]*wordTally),
+		maxDays:           DefaultMaxDays,
+		maxSessionsPerDay: DefaultMaxSessionsPerDay,
+	}
+}
+
+// RecordActivity marks sessionID as active today.
+func (c *Collector) RecordActivity(sessionID string) {
+	if c == nil {
+		return
+	}
+	today := time.Now().UTC().Format(time.DateOnly)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	day := c.dayLocked(today)
+	if _, ok := day.sessions[sessionID]; ok {
+		return
+	}
+	if len(day.sessions) >= c.maxSessionsPerDay {
+		day.overflow++
+		return
+	}
+	day.sessions[sessionID] = struct{}{}
+}
+
+// Forget drops sessionID from the days it was active on, e.g. when its
+// player asked for their data to be erased.
+func (c *Collector) Forget(sessionID string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, day := range c.days {
+		delete(day.sessions, sessionID)
+	}
+}
+
+// RecordFirstGuess counts the opening guess of a game.
+func (c *Collector) RecordFirstGuess(guess string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	c.firstGuesses.add(guess)
+	c.mu.Unlock()
+}
+
+// RecordGame counts a finished game.
+func (c *Collector) RecordGame(result GameResult) {
+	if c == nil {
+		return
+	}
+	today := time.Now().UTC().Format(time.DateOnly)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	day := c.dayLocked(today)
+	if result.Flagged {
+		// Bots would skew the win rate and how hard each word looks, so
+		// their games are counted apart from the rest.
+		c.gamesFlagged++
+		day.flagged++
+		return
+	}
+	c.gamesCompleted++
+	day.games++
+	if result.Won {
+		c.gamesWon++
+		c.guessesToWin += result.Guesses
+		day.won++
+		if result.Guesses > 0 {
+			for len(day.solvedIn) < result.Guesses {
+				day.solvedIn = append(day.solvedIn, 0)
+			}
+			day.solvedIn[result.Guesses-1]++
+		}
+	}
+	for _, word := range result.MissedWords {
+		c.missedWords.add(word)
+		day.missed.add(word)
+	}
+	for _, outcome := range result.Words {
+		tally, ok := c.words[outcome.Word]
+		if !ok {
+			if len(c.words) >= DefaultMaxTrackedKeys {
+				c.wordsOverflow++
+				continue
+			}
+			tally = &wordTally{}
+			c.words[outcome.Word] = tally
+		}
+		tally.plays++
+		if outcome.Solved {
+			tally.solves++
+			tally.guesses += outcome.Guesses
+		}
+	}
+}
+
+type WordCount struct {
+	Word  string `json:"word"`
+	Count int    `json:"count"`
+}
+
+type DailyActive struct {
+	Date     string `json:"date"`
+	Sessions int    `json:"sessions"`
+}
+
+type Summary struct {
+	GamesCompleted         int           `json:"games_completed"`
+	GamesWon               int           `json:"games_won"`
+	GamesFlagged           int           `json:"games_flagged"`
+	WinRate                float64       `json:"win_rate"`
+	AverageGuessesToWin    float64       `json:"average_guesses_to_win"`
+	MostMissedWords        []WordCount   `json:"most_missed_words"`
+	MostCommonFirstGuesses []WordCount   `json:"most_common_first_guesses"`
+	DailyActiveSessions    []DailyActive `json:"daily_active_sessions"`
+}
+
+func (c *Collector) Summary() Summary {
+	if c == nil {
+		return Summary{}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s := Summary{
+		GamesCompleted:         c.gamesCompleted,
+		GamesWon:               c.gamesWon,
+		GamesFlagged:           c.gamesFlagged,
+		MostMissedWords:        c.missedWords.top(topN),
+		MostCommonFirstGuesses: c.firstGuesses.top(topN),
+		DailyActiveSessions:    make([]DailyActive, 0, len(c.days)),
+	}
+	if c.gamesCompleted > 0 {
+		s.WinRate = float64(c.gamesWon) / float64(c.gamesCompleted)
+	}
+	if c.gamesWon > 0 {
+		s.AverageGuessesToWin = float64(c.guessesToWin) / float64(c.gamesWon)
+	}
+	for _, day := range c.days {
+		s.DailyActiveSessions = append(s.DailyActiveSessions, DailyActive{
+			Date:     day.date,
+			Sessions: len(day.sessions) + day.overflow,
+		})
+	}
+	return s
+}
+
+// DayStats is how one UTC day was played. SolvedIn[i] counts the games won
+// in i+1 guesses.
+type DayStats struct {
+	Date           string      `json:"date"`
+	ActiveSessions int         `json:"active_sessions"`
+	GamesCompleted int         `json:"games_completed"`
+	GamesWon       int         `json:"games_won"`
+	GamesFlagged   int         `json:"games_flagged"`
+	WinRate        float64     `json:"win_rate"`
+	SolvedIn       []int       `json:"solved_in"`
+	MostMissed     []WordCount `json:"most_missed_words"`
+}
+
+// Day returns the stats of date, formatted as time.DateOnly, if it is one
+// of the last DefaultMaxDays days the collector saw played.
+func (c *Collector) Day(date string) (DayStats, bool) {
+	if c == nil {
+		return DayStats{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, day := range c.days {
+		if day.date != date {
+			continue
+		}
+		st := DayStats{
+			Date:           day.date,
+			ActiveSessions: len(day.sessions) + day.overflow,
+			GamesCompleted: day.games,
+			GamesWon:       day.won,
+			GamesFlagged:   day.flagged,
+			SolvedIn:       slices.Clone(day.solvedIn),
+			MostMissed:     day.missed.top(topN),
+		}
+		if st.SolvedIn == nil {
+			st.SolvedIn = []int{}
+		}
+		if day.games > 0 {
+			st.WinRate = float64(day.won) / float64(day.games)
+		}
+		return st, true
+	}
+	return DayStats{}, false
+}
+
+// WordStats is how players fare against one target word. AverageGuesses
+// covers solved plays only.
+type WordStats struct {
+	Word           string  `json:"word"`
+	Plays          int     `json:"plays"`
+	Solves         int     `json:"solves"`
+	SolveRate      float64 `json:"solve_rate"`
+	AverageGuesses float64 `json:"average_guesses"`
+}
+
+// Word stats orderings accepted by WordStats.
+const (
+	SortBySolveRate      = "solve_rate"
+	SortByPlays          = "plays"
+	SortByAverageGuesses = "average_guesses"
+)
+
+// WordStats lists the target words played at least minPlays times. The
+// default order, SortBySolveRate, puts the hardest words first.
+func (c *Collector) WordStats(sortBy string, minPlays int) []WordStats {
+	if c == nil {
+		return []WordStats{}
+	}
+	c.mu.Lock()
+	out := make([]WordStats, 0, len(c.words))
+	for word, tally := range c.words {
+		if tally.plays < minPlays {
+			continue
+		}
+		st := WordStats{Word: word, Plays: tally.plays, Solves: tally.solves}
+		st.SolveRate = float64(tally.solves) / float64(tally.plays)
+		if tally.solves > 0 {
+			st.AverageGuesses = float64(tally.guesses) / float64(tally.solves)
+		}
+		out = append(out, st)
+	}
+	c.mu.Unlock()
+
+	slices.SortFunc(out, func(a, b WordStats) int {
+		var c int
+		switch sortBy {
+		case SortByPlays:
+			c = cmp.Compare(b.Plays, a.Plays)
+		case SortByAverageGuesses:
+			c = cmp.Compare(b.AverageGuesses, a.AverageGuesses)
+		default:
+			c = cmp.Compare(a.SolveRate, b.SolveRate)
+		}
+		if c != 0 {
+			return c
+		}
+		if c := cmp.Compare(b.Plays, a.Plays); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Word, b.Word)
+	})
+	return out
+}
+
+// SolveRate returns the share of plays of word that were solved, and the
+// number of plays it is based on.
+func (c *Collector) SolveRate(word string) (float64, int) {
+	if c == nil {
+		return 0, 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	tally, ok := c.words[word]
+	if !ok || tally.plays == 0 {
+		return 0, 0
+	}
+	return float64(tally.solves) / float64(tally.plays), tally.plays
+}
+
+func (c *Collector) dayLocked(date string) *dayActivity {
+	if n := len(c.days); n > 0 && c.days[n-1].date == date {
+		return c.days[n-1]
+	}
+	day := &dayActivity{
+		date:     date,
+		sessions: make(map[string]struct{}),
+		missed:   newBoundedCounter(DefaultMaxTrackedKeys),
+	}
+	c.days = append(c.days, day)
+	if len(c.days) > c.maxDays {
+		c.days = slices.Delete(c.days, 0, len(c.days)-c.maxDays)
+	}
+	return day
+}
+
+type boundedCounter struct {
+	counts  map[string]int
+	maxKeys int
+	other   int
+}
+
+func newBoundedCounter(maxKeys int) *boundedCounter {
+	return &boundedCounter{counts: make(map[string]int), maxKeys: maxKeys}
+}
+
+func (b *boundedCounter) add(key string) {
+	if _, ok := b.counts[key]; !ok && len(b.counts) >= b.maxKeys {
+		b.other++
+		return
+	}
+	b.counts[key]++
+}
+
+func (b *boundedCounter) top(n int) []WordCount {
+	out := make([]WordCount, 0, len(b.counts))
+	for word, count := range b.counts {
+		out = append(out, WordCount{Word: word, Count: count})
+	}
+	slices.SortFunc(out, func(a, b WordCount) int {
+		if c := cmp.Compare(b.Count, a.Count); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Word, b.Word)
+	})
+	if len(out) > n {
+		out = out[:n]
+	}
+	return out
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	analytics "github.com/CodeAndHammer/vortludo/internal/analytics"
+)
+
+func TestSummary(t *testing.T) {
+	c := analytics.NewCollector()
+	c.RecordGame(analytics.GameResult{Won: true, Guesses: 3})
+	c.RecordGame(analytics.GameResult{Won: true, Guesses: 5})
+	c.RecordGame(analytics.GameResult{Won: false, Guesses: 6, MissedWords: []string{"APPLE"}})
+	c.RecordGame(analytics.GameResult{Won: false, Guesses: 6, MissedWords: []string{"APPLE"}})
+	c.RecordFirstGuess("CRANE")
+	c.RecordFirstGuess("CRANE")
+	c.RecordFirstGuess("SLATE")
+	c.RecordActivity("a")
+	c.RecordActivity("a")
+	c.RecordActivity("b")
+
+	s := c.Summary()
+	if s.GamesCompleted != 4 || s.GamesWon != 2 || s.WinRate != 0.5 || s.AverageGuessesToWin != 4 {
+		t.Errorf("Unexpected totals: %+v", s)
+	}
+	if len(s.MostMissedWords) != 1 || s.MostMissedWords[0] != (analytics.WordCount{Word: "APPLE", Count: 2}) {
+		t.Errorf("Unexpected missed words: %v", s.MostMissedWords)
+	}
+	if s.MostCommonFirstGuesses[0].Word != "CRANE" || s.MostCommonFirstGuesses[1].Word != "SLATE" {
+		t.Errorf("Unexpected first guesses order: %v", s.MostCommonFirstGuesses)
+	}
+	if len(s.DailyActiveSessions) != 1 || s.DailyActiveSessions[0].Sessions != 2 {
+		t.Errorf("Unexpected daily active sessions: %v", s.DailyActiveSessions)
+	}
+
+	c.RecordGame(analytics.GameResult{Won: true, Guesses: 2, Flagged: true, Words: []analytics.WordOutcome{{Word: "APPLE", Solved: true, Guesses: 2}}})
+	if s := c.Summary(); s.GamesFlagged != 1 || s.GamesCompleted != 4 || len(c.WordStats("", 1)) != 0 {
+		t.Errorf("Flagged games should be counted apart: %+v", s)
+	}
+
+	c.Forget("a")
+	if s := c.Summary(); s.DailyActiveSessions[0].Sessions != 1 {
+		t.Errorf("A forgotten session should no longer count as active: %v", s.DailyActiveSessions)
+	}
+}
+
+func TestFirstGuessesBounded(t *testing.T) {
+	c := analytics.NewCollector()
+	for i := range analytics.DefaultMaxTrackedKeys + 100 {
+		c.RecordFirstGuess(fmt.Sprintf("W%04d", i))
+	}
+	if got := len(c.Summary().MostCommonFirstGuesses); got > 10 {
+		t.Errorf("Summary should list at most 10 guesses, got %d", got)
+	}
+}
+
+func TestNilCollectorIsNoop(t *testing.T) {
+	var c *analytics.Collector
+	c.RecordActivity("a")
+	c.RecordFirstGuess("CRANE")
+	c.RecordGame(analytics.GameResult{Won: true})
+	if s := c.Summary(); s.GamesCompleted != 0 {
+		t.Errorf("Nil collector should report nothing, got %+v", s)
+	}
+}
+
+func TestWordStats(t *testing.T) {
+	c := analytics.NewCollector()
+	play := func(word string, solved bool, guesses int) {
+		c.RecordGame(analytics.GameResult{Won: solved, Guesses: guesses, Words: []analytics.WordOutcome{{Word: word, Solved: solved, Guesses: guesses}}})
+	}
+	play("APPLE", true, 3)
+	play("APPLE", true, 5)
+	play("FJORD", false, 6)
+	play("FJORD", true, 6)
+	play("NYMPH", false, 6)
+
+	stats := c.WordStats("", 2)
+	if len(stats) != 2 || stats[0].Word != "FJORD" || stats[1].Word != "APPLE" {
+		t.Fatalf("Expected the hardest word first and NYMPH filtered out, got %+v", stats)
+	}
+	if stats[0].SolveRate != 0.5 || stats[0].AverageGuesses != 6 {
+		t.Errorf("Unexpected FJORD stats: %+v", stats[0])
+	}
+	if stats[1].Plays != 2 || stats[1].Solves != 2 || stats[1].AverageGuesses != 4 {
+		t.Errorf("Unexpected APPLE stats: %+v", stats[1])
+	}
+	if got := c.WordStats(analytics.SortByPlays, 0); len(got) != 3 || got[2].Word != "NYMPH" {
+		t.Errorf("Unexpected order by plays: %+v", got)
+	}
+}
+
+func TestDayStats(t *testing.T) {
+	c := analytics.NewCollector()
+	c.RecordActivity("a")
+	c.RecordGame(analytics.GameResult{Won: true, Guesses: 3})
+	c.RecordGame(analytics.GameResult{Won: true, Guesses: 3})
+	c.RecordGame(analytics.GameResult{Won: true, Guesses: 1})
+	c.RecordGame(analytics.GameResult{Won: false, Guesses: 6, MissedWords: []string{"FJORD"}})
+	c.RecordGame(analytics.GameResult{Won: true, Guesses: 2, Flagged: true})
+
+	today := time.Now().UTC().Format(time.DateOnly)
+	day, ok := c.Day(today)
+	if !ok {
+		t.Fatal("Expected today's stats")
+	}
+	if day.ActiveSessions != 1 || day.GamesCompleted != 4 || day.GamesWon != 3 || day.GamesFlagged != 1 || day.WinRate != 0.75 {
+		t.Errorf("Unexpected totals: %+v", day)
+	}
+	if !slices.Equal(day.SolvedIn, []int{1, 0, 2}) {
+		t.Errorf("Expected wins in 1 and 3 guesses, got %v", day.SolvedIn)
+	}
+	if len(day.MostMissed) != 1 || day.MostMissed[0].Word != "FJORD" {
+		t.Errorf("Unexpected missed words: %v", day.MostMissed)
+	}
+	if _, ok := c.Day("2000-01-01"); ok {
+		t.Error("A day without games should have no stats")
+	}
+}
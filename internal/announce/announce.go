@@ -0,0 +1,175 @@
+// Package announce keeps the site-wide announcement operators set to warn
+// players of maintenance or celebrate a milestone. It is shown on every page
+// until it expires or is cleared, and persisted so it survives restarts.
+package announce
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	util "github.com/CodeAndHammer/vortludo/internal/util"
+)
+
+// MaxTextLength is the longest announcement text accepted, in characters.
+const MaxTextLength = 280
+
+// Severities are the kinds of announcement, from the most to the least
+// urgent. They double as Bootstrap alert colors.
+var Severities = []string{"danger", "warning", "info", "success"}
+
+// Announcement is a message shown to every player. A zero Expires means it
+// is shown until cleared.
+type Announcement struct {
+	Text     string    `json:"text"`
+	Severity string    `json:"severity"`
+	Expires  time.Time `json:"expires,omitzero"`
+	Created  time.Time `json:"created"`
+}
+
+// Active reports whether a is shown at now.
+func (a Announcement) Active(now time.Time) bool {
+	return a.Text != "" && (a.Expires.IsZero() || now.Before(a.Expires))
+}
+
+// Validate checks that a can be shown.
+func (a Announcement) Validate() error {
+	text := strings.TrimSpace(a.Text)
+	switch {
+	case text == "":
+		return errors.New("text is required")
+	case len([]rune(text)) > MaxTextLength:
+		return fmt.Errorf("text must be at most %d characters", MaxTextLength)
+	case !slices.Contains(Severities, a.Severity):
+		return fmt.Errorf("severity must be one of %s", strings.Join(Severities, ", "))
+	}
+	return nil
+}
+
+// Store holds the current announcement and persists it to path after every
+// change. An empty path keeps it in memory. A nil Store has no announcement.
+type Store struct {
+	mu      sync.RWMutex
+	path    string
+	current *Announcement
+	version int
+}
+
+// Open loads the store from path. A missing file yields an empty store.
+func Open(path string) (*Store, error) {
+	s := &Store{path: path}
+	if path == "" {
+		return s, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return s, nil
+		}
+		return nil, err
+	}
+	var a Announcement
+	if err := json.Unmarshal(data, &a); err != nil {
+		return nil, fmt.Errorf("decode announcement: %w", err)
+	}
+	if a.Text != "" {
+		s.current = &a
+	}
+	return s, nil
+}
+
+// Current returns the announcement shown at now, if there is one.
+func (s *Store) Current(now time.Time) (Announcement, bool) {
+	if s == nil {
+		return Announcement{}, false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.current == nil || !s.current.Active(now) {
+		return Announcement{}, false
+	}
+	return *s.current, true
+}
+
+// Version changes whenever the announcement is set or cleared, so event
+// streams can tell players' pages to fetch it again. An announcement
+// expiring does not change it.
+func (s *Store) Version() int {
+	if s == nil {
+		return 0
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.version
+}
+
+// Set replaces the announcement with a, once it is valid.
+func (s *Store) Set(a Announcement) error {
+	a.Text = strings.TrimSpace(a.Text)
+	if err := a.Validate(); err != nil {
+		return err
+	}
+	if a.Created.IsZero() {
+		a.Created = time.Now()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.current = &a
+	s.version++
+	s.saveLocked()
+	return nil
+}
+
+// Clear removes the announcement, reporting whether there was one.
+func (s *Store) Clear() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.current == nil {
+		return false
+	}
+	s.current = nil
+	s.version++
+	s.saveLocked()
+	return true
+}
+
+// Funcs returns the template function "announcement", which yields the
+// current announcement or nil, so every page can show it without each
+// handler passing it along.
+func (s *Store) Funcs() template.FuncMap {
+	return template.FuncMap{
+		"announcement": func() *Announcement {
+			if a, ok := s.Current(time.Now()); ok {
+				return &a
+			}
+			return nil
+		},
+	}
+}
+
+func (s *Store) saveLocked() {
+	if s.path == "" {
+		return
+	}
+	if s.current == nil {
+		if err := os.Remove(s.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			util.LogWarn("Failed to remove announcement file: %v", err)
+		}
+		return
+	}
+	data, err := json.Marshal(s.current)
+	if err != nil {
+		util.LogWarn("Failed to encode announcement: %v", err)
+		return
+	}
+	if err := util.WriteFileAtomic(s.path, data); err != nil {
+		util.LogWarn("Failed to save announcement: %v", err)
+	}
+}
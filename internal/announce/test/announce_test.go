@@ -0,0 +1,73 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	announce "github.com/CodeAndHammer/vortludo/internal/announce"
+)
+
+func TestAnnouncementLifecycle(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "announcement.json")
+	store, err := announce.Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if _, ok := store.Current(time.Now()); ok {
+		t.Fatal("A new store should have no announcement")
+	}
+
+	now := time.Now()
+	if err := store.Set(announce.Announcement{Text: " Maintenance at noon ", Severity: "warning", Expires: now.Add(time.Hour)}); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if a, ok := store.Current(now); !ok || a.Text != "Maintenance at noon" || a.Severity != "warning" {
+		t.Errorf("Expected the trimmed warning, got %+v", a)
+	}
+	if _, ok := store.Current(now.Add(2 * time.Hour)); ok {
+		t.Error("An expired announcement should not be shown")
+	}
+	version := store.Version()
+
+	reopened, err := announce.Open(path)
+	if err != nil {
+		t.Fatalf("Reopen: %v", err)
+	}
+	if a, ok := reopened.Current(now); !ok || a.Text != "Maintenance at noon" {
+		t.Errorf("The announcement should survive a restart, got %+v", a)
+	}
+
+	if !store.Clear() || store.Version() == version {
+		t.Error("Clear should remove the announcement and change the version")
+	}
+	if store.Clear() {
+		t.Error("Clearing twice should report nothing to clear")
+	}
+	reopened, _ = announce.Open(path)
+	if _, ok := reopened.Current(now); ok {
+		t.Error("A cleared announcement should not come back after a restart")
+	}
+}
+
+func TestAnnouncementValidation(t *testing.T) {
+	store, _ := announce.Open("")
+	for _, a := range []announce.Announcement{
+		{Text: "  ", Severity: "info"},
+		{Text: "Hello", Severity: "purple"},
+		{Text: strings.Repeat("x", announce.MaxTextLength+1), Severity: "info"},
+	} {
+		if err := store.Set(a); err == nil {
+			t.Errorf("Expected %q (%s) to be refused", a.Text, a.Severity)
+		}
+	}
+	if store.Version() != 0 {
+		t.Error("Refused announcements should not change the version")
+	}
+
+	var none *announce.Store
+	if _, ok := none.Current(time.Now()); ok || none.Version() != 0 {
+		t.Error("A nil store should have no announcement")
+	}
+}
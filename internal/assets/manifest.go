@@ -0,0 +1,171 @@
+// Package assets fingerprints the static files so they can be cached forever:
+// every file is also served under a name carrying a hash of its content, and
+// templates link to that name through the "asset" helper.
+package assets
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"html/template"
+	"io/fs"
+	"mime"
+	"path"
+	"strings"
+	"time"
+
+	compress "github.com/CodeAndHammer/vortludo/internal/compress"
+)
+
+const (
+	hashLength = 12
+
+	// ImmutableMaxAge is how long browsers may cache fingerprinted files.
+	ImmutableMaxAge = 365 * 24 * time.Hour
+)
+
+type entry struct {
+	hashed string
+	etag   string
+	// variants holds the file compressed in each content coding it is
+	// smaller in.
+	variants map[string][]byte
+}
+
+// Manifest maps logical asset names such as "style.css" to their fingerprinted
+// names such as "style.1a2b3c4d5e6f.css".
+type Manifest struct {
+	fsys    fs.FS
+	prefix  string
+	entries map[string]entry
+	logical map[string]string
+	// passthrough is set on manifests that serve fsys as it is.
+	passthrough bool
+}
+
+// Build hashes every file of fsys. URLs are rooted at prefix, e.g. "/static".
+func Build(fsys fs.FS, prefix string) (*Manifest, error) {
+	m := Passthrough(fsys, prefix)
+	m.passthrough = false
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || isSibling(fsys, name) {
+			return err
+		}
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(data)
+		hash := hex.EncodeToString(sum[:])[:hashLength]
+		hashed := fingerprint(name, hash)
+		variants, err := buildVariants(fsys, name, data)
+		if err != nil {
+			return err
+		}
+		m.entries[name] = entry{hashed: hashed, etag: hash, variants: variants}
+		m.logical[hashed] = name
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Passthrough returns a manifest that serves fsys without fingerprints. It is
+// used in development, where files change while the server runs.
+func Passthrough(fsys fs.FS, prefix string) *Manifest {
+	return &Manifest{
+		fsys:        fsys,
+		prefix:      strings.TrimSuffix(prefix, "/"),
+		entries:     make(map[string]entry),
+		logical:     make(map[string]string),
+		passthrough: true,
+	}
+}
+
+// buildVariants compresses a file in every supported coding up front. A
+// pre-compressed sibling such as "style.css.br" is used as it is.
+func buildVariants(fsys fs.FS, name string, data []byte) (map[string][]byte, error) {
+	if !compress.Compressible(mime.TypeByExtension(path.Ext(name))) {
+		return nil, nil
+	}
+	variants := make(map[string][]byte)
+	for _, encoding := range compress.Encodings {
+		encoded, err := fs.ReadFile(fsys, name+compress.Extension(encoding))
+		if err != nil {
+			if encoded, err = compress.Encode(encoding, data); err != nil {
+				return nil, err
+			}
+		}
+		if len(encoded) < len(data) {
+			variants[encoding] = encoded
+		}
+	}
+	return variants, nil
+}
+
+// isSibling reports whether name is a pre-compressed copy of another file.
+func isSibling(fsys fs.FS, name string) bool {
+	for _, encoding := range compress.Encodings {
+		if base, ok := strings.CutSuffix(name, compress.Extension(encoding)); ok {
+			if _, err := fs.Stat(fsys, base); err == nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func fingerprint(name, hash string) string {
+	ext := path.Ext(name)
+	return strings.TrimSuffix(name, ext) + "." + hash + ext
+}
+
+// Len returns the number of fingerprinted files.
+func (m *Manifest) Len() int {
+	return len(m.entries)
+}
+
+// URL returns the URL templates should link to for the logical name.
+func (m *Manifest) URL(name string) string {
+	name = strings.TrimPrefix(name, "/")
+	if e, ok := m.entries[name]; ok {
+		return m.prefix + "/" + e.hashed
+	}
+	return m.prefix + "/" + name
+}
+
+// Resolve maps a requested file name to the logical file to serve. immutable
+// is set when the request used the fingerprinted name.
+func (m *Manifest) Resolve(name string) (logical, etag string, immutable bool) {
+	name = strings.TrimPrefix(name, "/")
+	if logical, ok := m.logical[name]; ok {
+		return logical, m.entries[logical].etag, true
+	}
+	return name, m.entries[name].etag, false
+}
+
+// Variant returns the logical file compressed in encoding, if it is
+// available that way. Without fingerprints, only pre-compressed siblings are
+// served, read as they are requested.
+func (m *Manifest) Variant(name, encoding string) ([]byte, bool) {
+	if e, ok := m.entries[name]; ok {
+		data, ok := e.variants[encoding]
+		return data, ok
+	}
+	if !m.passthrough || !compress.Compressible(mime.TypeByExtension(path.Ext(name))) {
+		return nil, false
+	}
+	data, err := fs.ReadFile(m.fsys, name+compress.Extension(encoding))
+	return data, err == nil
+}
+
+// Open opens a logical file.
+func (m *Manifest) Open(name string) (fs.File, error) {
+	return m.fsys.Open(name)
+}
+
+// Funcs returns the template helpers backed by the manifest.
+func (m *Manifest) Funcs() template.FuncMap {
+	return template.FuncMap{"asset": m.URL}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"html/template"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	assets "github.com/CodeAndHammer/vortludo/internal/assets"
+	compress "github.com/CodeAndHammer/vortludo/internal/compress"
+)
+
+func TestManifestFingerprints(t *testing.T) {
+	fsys := fstest.MapFS{
+		"style.css":            {Data: []byte("body{}")},
+		"favicons/favicon.ico": {Data: []byte("icon")},
+	}
+	m, err := assets.Build(fsys, "/static")
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	if m.Len() != 2 {
+		t.Fatalf("Expected 2 assets, got %d", m.Len())
+	}
+
+	url := m.URL("style.css")
+	if url == "/static/style.css" || !strings.HasPrefix(url, "/static/style.") || !strings.HasSuffix(url, ".css") {
+		t.Fatalf("Expected a fingerprinted URL, got %s", url)
+	}
+	name, etag, immutable := m.Resolve(strings.TrimPrefix(url, "/static"))
+	if name != "style.css" || etag == "" || !immutable {
+		t.Errorf("Resolve(%s) = %s, %s, %v", url, name, etag, immutable)
+	}
+	if name, _, immutable := m.Resolve("/style.css"); name != "style.css" || immutable {
+		t.Errorf("Plain names should resolve without the immutable flag, got %s, %v", name, immutable)
+	}
+	if got := m.URL("missing.js"); got != "/static/missing.js" {
+		t.Errorf("Unknown assets should keep their name, got %s", got)
+	}
+
+	fsys["style.css"] = &fstest.MapFile{Data: []byte("body{color:red}")}
+	changed, err := assets.Build(fsys, "/static")
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	if changed.URL("style.css") == url {
+		t.Error("Changing a file should change its fingerprint")
+	}
+}
+
+func TestManifestTemplateHelper(t *testing.T) {
+	m := assets.Passthrough(fstest.MapFS{}, "/static/")
+	tmpl := template.Must(template.New("t").Funcs(m.Funcs()).Parse(`{{asset "client.js"}}`))
+	var out strings.Builder
+	if err := tmpl.Execute(&out, nil); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "/static/client.js" {
+		t.Errorf("Passthrough manifest should not fingerprint, got %s", out.String())
+	}
+}
+
+func TestManifestVariants(t *testing.T) {
+	css := []byte(strings.Repeat("body{color:red}", 100))
+	fsys := fstest.MapFS{
+		"style.css":            {Data: css},
+		"client.js":            {Data: []byte(strings.Repeat("let a = 1;", 100))},
+		"client.js.br":         {Data: []byte("pre-compressed")},
+		"favicons/favicon.png": {Data: []byte("png")},
+	}
+	m, err := assets.Build(fsys, "/static")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Len() != 3 {
+		t.Errorf("Pre-compressed siblings should not be assets of their own, got %d assets", m.Len())
+	}
+	if data, ok := m.Variant("style.css", compress.Brotli); !ok || len(data) >= len(css) {
+		t.Error("Expected a smaller Brotli variant of style.css")
+	}
+	if data, ok := m.Variant("client.js", compress.Brotli); !ok || string(data) != "pre-compressed" {
+		t.Error("Expected the pre-compressed sibling to be used")
+	}
+	if _, ok := m.Variant("favicons/favicon.png", compress.Gzip); ok {
+		t.Error("Images should not get compressed variants")
+	}
+
+	dev := assets.Passthrough(fsys, "/static")
+	if _, ok := dev.Variant("client.js", compress.Brotli); !ok {
+		t.Error("Passthrough manifests should serve pre-compressed siblings")
+	}
+	if _, ok := dev.Variant("style.css", compress.Brotli); ok {
+		t.Error("Passthrough manifests should not compress up front")
+	}
+}
@@ -0,0 +1,181 @@
+// Package audit keeps an append-only record of admin actions: who did what,
+// when, in which request, and what changed. Entries are written as JSON
+// lines to a file, and the latest are kept in memory for the admin API.
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	util "github.com/CodeAndHammer/vortludo/internal/util"
+)
+
+const (
+	// DefaultRecent is how many entries are kept in memory.
+	DefaultRecent = 500
+
+	// maxLine bounds one entry when reading the file back.
+	maxLine = 1 << 20
+)
+
+// Actions recorded by the admin handlers.
+const (
+	ReloadBlocklist   = "reload_blocklist"
+	Maintenance       = "maintenance"
+	LiftBan           = "lift_ban"
+	ClearBot          = "clear_bot"
+	SetAnnouncement   = "set_announcement"
+	ClearAnnouncement = "clear_announcement"
+	AddWord           = "add_word"
+	RemoveWord        = "remove_word"
+	RetireWord        = "retire_word"
+	RestoreWord       = "restore_word"
+	ScheduleWord      = "schedule_word"
+	UnscheduleWord    = "unschedule_word"
+)
+
+// Entry is one admin action. Before and After summarize the state it
+// changed.
+type Entry struct {
+	Time      time.Time `json:"time"`
+	Action    string    `json:"action"`
+	Actor     string    `json:"actor"`
+	IP        string    `json:"ip,omitempty"`
+	RequestID string    `json:"requestId,omitempty"`
+	Before    any       `json:"before,omitempty"`
+	After     any       `json:"after,omitempty"`
+}
+
+// Log appends entries to its file. Without a file entries are only kept in
+// memory, and a nil Log records nothing.
+type Log struct {
+	mu     sync.Mutex
+	file   *os.File
+	recent []Entry
+	max    int
+}
+
+// Open opens the log at path, reading back its latest entries. An empty path
+// keeps the log in memory.
+func Open(path string) (*Log, error) {
+	l := &Log{max: DefaultRecent}
+	if path == "" {
+		return l, nil
+	}
+	if err := l.load(path); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	if err := endTornLine(file); err != nil {
+		file.Close()
+		return nil, err
+	}
+	l.file = file
+	return l, nil
+}
+
+// endTornLine ends a last line left unfinished by a crash, so the next entry
+// starts on a line of its own.
+func endTornLine(file *os.File) error {
+	info, err := file.Stat()
+	if err != nil || info.Size() == 0 {
+		return err
+	}
+	last := make([]byte, 1)
+	if _, err := file.ReadAt(last, info.Size()-1); err != nil {
+		return err
+	}
+	if last[0] != '\n' {
+		_, err = file.Write([]byte{'\n'})
+	}
+	return err
+}
+
+func (l *Log) load(path string) error {
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxLine)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			// A line torn by a crash is skipped, not fatal.
+			continue
+		}
+		l.remember(e)
+	}
+	return scanner.Err()
+}
+
+// Record appends e, stamped with the current time if it has none. A failed
+// write is logged, as the action it records has already happened.
+func (l *Log) Record(e Entry) {
+	if l == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.remember(e)
+	if l.file == nil {
+		return
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		util.LogWarn("Failed to encode audit entry %s: %v", e.Action, err)
+		return
+	}
+	if _, err := l.file.Write(append(line, '\n')); err != nil {
+		util.LogWarn("Failed to write audit entry %s: %v", e.Action, err)
+	}
+}
+
+func (l *Log) remember(e Entry) {
+	l.recent = append(l.recent, e)
+	if len(l.recent) > l.max {
+		l.recent = slices.Delete(l.recent, 0, len(l.recent)-l.max)
+	}
+}
+
+// Recent returns up to n of the latest entries, newest first, optionally
+// only those of action.
+func (l *Log) Recent(n int, action string) []Entry {
+	out := []Entry{}
+	if l == nil {
+		return out
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i := len(l.recent) - 1; i >= 0 && len(out) < n; i-- {
+		if action == "" || l.recent[i].Action == action {
+			out = append(out, l.recent[i])
+		}
+	}
+	return out
+}
+
+func (l *Log) Close() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
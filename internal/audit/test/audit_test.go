@@ -0,0 +1,65 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	audit "github.com/CodeAndHammer/vortludo/internal/audit"
+)
+
+func TestAuditLogAppendsAndReopens(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	log, err := audit.Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	log.Record(audit.Entry{Action: audit.Maintenance, Actor: "ops", RequestID: "req-1",
+		Before: map[string]any{"maintenance": false}, After: map[string]any{"maintenance": true}})
+	log.Record(audit.Entry{Action: audit.LiftBan, Actor: "ops", Before: map[string]any{"key": "ip:192.0.2.1"}})
+	if err := log.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	// A line torn by a crash is skipped when the log is read back.
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	f.WriteString(`{"action":"clear_b`)
+	f.Close()
+
+	reopened, err := audit.Open(path)
+	if err != nil {
+		t.Fatalf("Reopen: %v", err)
+	}
+	entries := reopened.Recent(10, "")
+	if len(entries) != 2 || entries[0].Action != audit.LiftBan || entries[1].Action != audit.Maintenance {
+		t.Fatalf("Expected both entries, newest first, got %+v", entries)
+	}
+	if entries[1].RequestID != "req-1" || entries[1].Time.IsZero() {
+		t.Errorf("Expected the request ID and time kept, got %+v", entries[1])
+	}
+	if got := reopened.Recent(10, audit.Maintenance); len(got) != 1 {
+		t.Errorf("Expected one maintenance entry, got %+v", got)
+	}
+	if got := reopened.Recent(1, ""); len(got) != 1 || got[0].Action != audit.LiftBan {
+		t.Errorf("Expected only the latest entry, got %+v", got)
+	}
+
+	reopened.Record(audit.Entry{Action: audit.ClearBot, Actor: "ops"})
+	reopened.Close()
+	again, err := audit.Open(path)
+	if err != nil {
+		t.Fatalf("Reopen: %v", err)
+	}
+	defer again.Close()
+	if got := again.Recent(10, ""); len(got) != 3 || got[0].Action != audit.ClearBot {
+		t.Errorf("An entry written after a torn line should be read back, got %+v", got)
+	}
+}
+
+func TestNilAuditLog(t *testing.T) {
+	var log *audit.Log
+	log.Record(audit.Entry{Action: audit.ClearBot})
+	if got := log.Recent(10, ""); len(got) != 0 {
+		t.Errorf("A nil log should record nothing, got %+v", got)
+	}
+}
@@ -0,0 +1,445 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// OIDCLoginTTL is how long a player has to complete a sign-in at the
+	// identity provider.
+	OIDCLoginTTL = 10 * time.Minute
+
+	oidcClockSkew = time.Minute
+	jwksMaxAge    = time.Hour
+)
+
+var ErrOIDCLogin = errors.New("identity provider sign-in failed")
+
+// OIDCConfig configures sign-in through an OpenID Connect provider.
+type OIDCConfig struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+// Identity is a player as asserted by an identity provider.
+type Identity struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Username      string
+}
+
+// Key identifies the identity across providers.
+func (id Identity) Key() string {
+	return id.Issuer + "#" + id.Subject
+}
+
+// OIDCLogin is a sign-in in progress. It is kept in a cookie between sending
+// the player to the provider and the provider sending them back.
+type OIDCLogin struct {
+	State    string
+	Nonce    string
+	Verifier string
+}
+
+// Encode returns the login as a cookie value.
+func (l OIDCLogin) Encode() string {
+	return l.State + "." + l.Nonce + "." + l.Verifier
+}
+
+// DecodeOIDCLogin parses a value written by Encode.
+func DecodeOIDCLogin(value string) (OIDCLogin, bool) {
+	parts := strings.Split(value, ".")
+	if len(parts) != 3 || slices.Contains(parts, "") {
+		return OIDCLogin{}, false
+	}
+	return OIDCLogin{State: parts[0], Nonce: parts[1], Verifier: parts[2]}, true
+}
+
+type providerMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+	EndSessionEndpoint    string `json:"end_session_endpoint"`
+}
+
+// OIDCProvider signs players in with the authorization code flow, using PKCE,
+// state and nonce. The provider's metadata is discovered on first use.
+type OIDCProvider struct {
+	cfg    OIDCConfig
+	client *http.Client
+
+	mu          sync.Mutex
+	meta        *providerMetadata
+	keys        map[string]crypto.PublicKey
+	keysFetched time.Time
+}
+
+// NewOIDCProvider checks cfg and returns a provider. client may be nil.
+func NewOIDCProvider(cfg OIDCConfig, client *http.Client) (*OIDCProvider, error) {
+	cfg.Issuer = strings.TrimSuffix(cfg.Issuer, "/")
+	if cfg.Issuer == "" || cfg.ClientID == "" || cfg.RedirectURL == "" {
+		return nil, errors.New("OIDC needs an issuer, a client ID and a redirect URL")
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "profile", "email"}
+	}
+	if !slices.Contains(cfg.Scopes, "openid") {
+		cfg.Scopes = append([]string{"openid"}, cfg.Scopes...)
+	}
+	if cfg.Name == "" {
+		cfg.Name = "single sign-on"
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &OIDCProvider{cfg: cfg, client: client}, nil
+}
+
+// Name is how the provider is shown to players.
+func (p *OIDCProvider) Name() string {
+	return p.cfg.Name
+}
+
+// Start begins a sign-in. The player is sent to the returned URL and login is
+// kept until they come back.
+func (p *OIDCProvider) Start(ctx context.Context) (string, OIDCLogin, error) {
+	meta, err := p.metadata(ctx)
+	if err != nil {
+		return "", OIDCLogin{}, err
+	}
+	login := OIDCLogin{State: rand.Text(), Nonce: rand.Text(), Verifier: rand.Text() + rand.Text()}
+	challenge := sha256.Sum256([]byte(login.Verifier))
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.cfg.ClientID},
+		"redirect_uri":          {p.cfg.RedirectURL},
+		"scope":                 {strings.Join(p.cfg.Scopes, " ")},
+		"state":                 {login.State},
+		"nonce":                 {login.Nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	return addQuery(meta.AuthorizationEndpoint, q), login, nil
+}
+
+// Finish completes a sign-in with the state and code the provider sent the
+// player back with, and returns the verified identity.
+func (p *OIDCProvider) Finish(ctx context.Context, login OIDCLogin, state, code string) (Identity, error) {
+	if state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(login.State)) != 1 {
+		return Identity{}, fmt.Errorf("%w: state mismatch", ErrOIDCLogin)
+	}
+	if code == "" {
+		return Identity{}, fmt.Errorf("%w: missing code", ErrOIDCLogin)
+	}
+	meta, err := p.metadata(ctx)
+	if err != nil {
+		return Identity{}, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.cfg.RedirectURL},
+		"code_verifier": {login.Verifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return Identity{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	var tokens struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := p.do(req, &tokens); err != nil {
+		return Identity{}, fmt.Errorf("%w: token exchange: %v", ErrOIDCLogin, err)
+	}
+	if tokens.IDToken == "" {
+		return Identity{}, fmt.Errorf("%w: no ID token", ErrOIDCLogin)
+	}
+	return p.verifyIDToken(ctx, tokens.IDToken, login.Nonce)
+}
+
+// LogoutURL returns the provider's logout page, which sends the player on to
+// redirectURL, or "" when the provider has none.
+func (p *OIDCProvider) LogoutURL(ctx context.Context, redirectURL string) string {
+	meta, err := p.metadata(ctx)
+	if err != nil || meta.EndSessionEndpoint == "" {
+		return ""
+	}
+	return addQuery(meta.EndSessionEndpoint, url.Values{
+		"client_id":                {p.cfg.ClientID},
+		"post_logout_redirect_uri": {redirectURL},
+	})
+}
+
+type idClaims struct {
+	Issuer            string          `json:"iss"`
+	Subject           string          `json:"sub"`
+	Audience          json.RawMessage `json:"aud"`
+	AuthorizedParty   string          `json:"azp"`
+	Expiry            int64           `json:"exp"`
+	IssuedAt          int64           `json:"iat"`
+	Nonce             string          `json:"nonce"`
+	Email             string          `json:"email"`
+	EmailVerified     any             `json:"email_verified"`
+	PreferredUsername string          `json:"preferred_username"`
+	Name              string          `json:"name"`
+}
+
+func (p *OIDCProvider) verifyIDToken(ctx context.Context, token, nonce string) (Identity, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Identity{}, fmt.Errorf("%w: malformed ID token", ErrOIDCLogin)
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return Identity{}, fmt.Errorf("%w: ID token header: %v", ErrOIDCLogin, err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Identity{}, fmt.Errorf("%w: ID token signature: %v", ErrOIDCLogin, err)
+	}
+	key, err := p.key(ctx, header.Kid)
+	if err != nil {
+		return Identity{}, err
+	}
+	if err := verifySignature(header.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return Identity{}, fmt.Errorf("%w: %v", ErrOIDCLogin, err)
+	}
+
+	var claims idClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return Identity{}, fmt.Errorf("%w: ID token claims: %v", ErrOIDCLogin, err)
+	}
+	now := time.Now()
+	switch {
+	case claims.Issuer != p.cfg.Issuer:
+		return Identity{}, fmt.Errorf("%w: unexpected issuer %q", ErrOIDCLogin, claims.Issuer)
+	case !audienceContains(claims.Audience, p.cfg.ClientID):
+		return Identity{}, fmt.Errorf("%w: token is not for this client", ErrOIDCLogin)
+	case claims.AuthorizedParty != "" && claims.AuthorizedParty != p.cfg.ClientID:
+		return Identity{}, fmt.Errorf("%w: token was issued to %q", ErrOIDCLogin, claims.AuthorizedParty)
+	case now.After(time.Unix(claims.Expiry, 0).Add(oidcClockSkew)):
+		return Identity{}, fmt.Errorf("%w: token expired", ErrOIDCLogin)
+	case subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1:
+		return Identity{}, fmt.Errorf("%w: nonce mismatch", ErrOIDCLogin)
+	case claims.Subject == "":
+		return Identity{}, fmt.Errorf("%w: token has no subject", ErrOIDCLogin)
+	}
+
+	username := claims.PreferredUsername
+	if username == "" {
+		username = claims.Name
+	}
+	verified := claims.EmailVerified == true || claims.EmailVerified == "true"
+	return Identity{
+		Issuer:        claims.Issuer,
+		Subject:       claims.Subject,
+		Email:         strings.ToLower(claims.Email),
+		EmailVerified: verified,
+		Username:      username,
+	}, nil
+}
+
+func audienceContains(raw json.RawMessage, clientID string) bool {
+	var single string
+	if json.Unmarshal(raw, &single) == nil {
+		return single == clientID
+	}
+	var many []string
+	if json.Unmarshal(raw, &many) == nil {
+		return slices.Contains(many, clientID)
+	}
+	return false
+}
+
+func verifySignature(alg string, key crypto.PublicKey, signed string, signature []byte) error {
+	digest := sha256.Sum256([]byte(signed))
+	switch alg {
+	case "RS256":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("key does not match RS256")
+		}
+		return rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], signature)
+	case "ES256":
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok || len(signature) != 64 {
+			return errors.New("key does not match ES256")
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(pub, digest[:], r, s) {
+			return errors.New("invalid ES256 signature")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported signing algorithm %q", alg)
+	}
+}
+
+func (p *OIDCProvider) metadata(ctx context.Context) (*providerMetadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.meta != nil {
+		return p.meta, nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.cfg.Issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+	var meta providerMetadata
+	if err := p.do(req, &meta); err != nil {
+		return nil, fmt.Errorf("OIDC discovery: %w", err)
+	}
+	if strings.TrimSuffix(meta.Issuer, "/") != p.cfg.Issuer {
+		return nil, fmt.Errorf("OIDC discovery: issuer %q does not match %q", meta.Issuer, p.cfg.Issuer)
+	}
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JWKSURI == "" {
+		return nil, errors.New("OIDC discovery: provider metadata is incomplete")
+	}
+	p.meta = &meta
+	return p.meta, nil
+}
+
+// key returns the signing key kid. The key set is fetched again when it is
+// stale or does not know kid, which is how providers roll keys over.
+func (p *OIDCProvider) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	meta, err := p.metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if key, ok := p.keys[kid]; ok && time.Since(p.keysFetched) < jwksMaxAge {
+		return key, nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, meta.JWKSURI, nil)
+	if err != nil {
+		return nil, err
+	}
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := p.do(req, &set); err != nil {
+		return nil, fmt.Errorf("fetch OIDC signing keys: %w", err)
+	}
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		if pub, err := k.publicKey(); err == nil {
+			keys[k.Kid] = pub
+		}
+	}
+	p.keys, p.keysFetched = keys, time.Now()
+	key, ok := keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown signing key %q", ErrOIDCLogin, kid)
+	}
+	return key, nil
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		pub := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+			return nil, errors.New("EC key is not on its curve")
+		}
+		return pub, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func (p *OIDCProvider) do(req *http.Request, out any) error {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s", req.URL.Redacted(), resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func decodeSegment(segment string, out any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+func addQuery(endpoint string, q url.Values) string {
+	sep := "?"
+	if strings.Contains(endpoint, "?") {
+		sep = "&"
+	}
+	return endpoint + sep + q.Encode()
+}
@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+
+	util "github.com/CodeAndHammer/vortludo/internal/util"
+)
+
+// SMTPMailer emails sign-in links through an SMTP server. It upgrades the
+// connection to TLS when the server offers STARTTLS, and signs in with
+// Username and Password when Username is set.
+type SMTPMailer struct {
+	// Addr is the server's host:port.
+	Addr     string
+	Username string
+	Password string
+	// From is the sender, such as "Vortludo <play@example.org>".
+	From string
+}
+
+func (m SMTPMailer) SendMagicLink(email, link string) error {
+	from, err := mail.ParseAddress(m.From)
+	if err != nil {
+		return fmt.Errorf("sender %q: %w", m.From, err)
+	}
+	var auth smtp.Auth
+	if m.Username != "" {
+		host, _, _ := net.SplitHostPort(m.Addr)
+		auth = smtp.PlainAuth("", m.Username, m.Password, host)
+	}
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", from)
+	fmt.Fprintf(&msg, "To: %s\r\n", email)
+	msg.WriteString("Subject: Your Vortludo sign-in link\r\n")
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n")
+	fmt.Fprintf(&msg, "Open this link to sign in to Vortludo:\r\n\r\n%s\r\n\r\n", link)
+	fmt.Fprintf(&msg, "It works once and expires in %d minutes. If you did not ask for it, ignore this email.\r\n", int(MagicLinkTTL.Minutes()))
+	return smtp.SendMail(m.Addr, auth, from.Address, []string{email}, []byte(msg.String()))
+}
+
+// mailQueueSize is how many sign-in links a MailQueue holds before it turns
+// more away.
+const mailQueueSize = 64
+
+// MailQueue hands sign-in links to its mailer from a background worker, so
+// asking for a link takes as long whether or not the address has an account.
+type MailQueue struct {
+	mailer Mailer
+	queue  chan magicLink
+}
+
+type magicLink struct{ email, link string }
+
+// NewMailQueue queues links for mailer. Nothing is sent until Run.
+func NewMailQueue(mailer Mailer) *MailQueue {
+	return &MailQueue{mailer: mailer, queue: make(chan magicLink, mailQueueSize)}
+}
+
+// SendMagicLink queues the link, or reports ErrMailQueueFull.
+func (q *MailQueue) SendMagicLink(email, link string) error {
+	select {
+	case q.queue <- magicLink{email, link}:
+		return nil
+	default:
+		return ErrMailQueueFull
+	}
+}
+
+// Run sends the queued links until ctx is done. Links still queued then are
+// dropped; they expire within MagicLinkTTL anyway.
+func (q *MailQueue) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case m := <-q.queue:
+			if err := q.mailer.SendMagicLink(m.email, m.link); err != nil {
+				util.LogWarn("Failed to send sign-in link: %v", err)
+			}
+		}
+	}
+}
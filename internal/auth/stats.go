@@ -0,0 +1,151 @@
+package auth
+
+import (
+	"maps"
+	"slices"
+	"time"
+)
+
+// Stats are a player's game record. Distribution counts wins by the number
+// of guesses they took.
+type Stats struct {
+	Played        int         `json:"played"`
+	Won           int         `json:"won"`
+	CurrentStreak int         `json:"currentStreak"`
+	MaxStreak     int         `json:"maxStreak"`
+	Distribution  map[int]int `json:"distribution,omitempty"`
+	// Packs holds the words solved in each puzzle pack, by pack ID.
+	Packs map[string][]string `json:"packs,omitempty"`
+	// Achievements holds when each achievement was unlocked, by ID.
+	Achievements map[string]time.Time `json:"achievements,omitempty"`
+	// WinDay is the last UTC day with a won game, and DayStreak the number
+	// of days in a row up to it with one.
+	WinDay    string `json:"winDay,omitempty"`
+	DayStreak int    `json:"dayStreak,omitempty"`
+	// Points totals the points scored in points games, and BestRun is the
+	// highest score a run of them reached.
+	Points  int `json:"points,omitempty"`
+	BestRun int `json:"bestRun,omitempty"`
+}
+
+// Record adds a finished game.
+func (s *Stats) Record(won bool, guesses int) {
+	s.Played++
+	if !won {
+		s.CurrentStreak = 0
+		return
+	}
+	s.Won++
+	s.CurrentStreak++
+	s.MaxStreak = max(s.MaxStreak, s.CurrentStreak)
+	if s.Distribution == nil {
+		s.Distribution = make(map[int]int)
+	}
+	s.Distribution[guesses]++
+}
+
+// RecordPoints adds the points of a game that brought its run to score.
+func (s *Stats) RecordPoints(points, score int) {
+	s.Points += points
+	s.BestRun = max(s.BestRun, score)
+}
+
+// Merge adds games played later, e.g. anonymously before signing in, to s.
+// The later games continue s's streak unless they include a loss.
+func (s *Stats) Merge(later Stats) {
+	for pack, words := range later.Packs {
+		for _, word := range words {
+			s.SolvePackWord(pack, word)
+		}
+	}
+	for id, at := range later.Achievements {
+		if !s.Unlock(id, at) && at.Before(s.Achievements[id]) {
+			s.Achievements[id] = at
+		}
+	}
+	if later.WinDay > s.WinDay {
+		s.WinDay, s.DayStreak = later.WinDay, later.DayStreak
+	} else if later.WinDay == s.WinDay {
+		s.DayStreak = max(s.DayStreak, later.DayStreak)
+	}
+	s.Points += later.Points
+	s.BestRun = max(s.BestRun, later.BestRun)
+	if later.Played == 0 {
+		return
+	}
+	s.Played += later.Played
+	s.Won += later.Won
+	if later.CurrentStreak == later.Played {
+		s.CurrentStreak += later.CurrentStreak
+	} else {
+		s.CurrentStreak = later.CurrentStreak
+	}
+	s.MaxStreak = max(s.MaxStreak, later.MaxStreak, s.CurrentStreak)
+	for guesses, n := range later.Distribution {
+		if s.Distribution == nil {
+			s.Distribution = make(map[int]int)
+		}
+		s.Distribution[guesses] += n
+	}
+}
+
+// SolvePackWord notes word as solved in the puzzle pack, once.
+func (s *Stats) SolvePackWord(pack, word string) {
+	if slices.Contains(s.Packs[pack], word) {
+		return
+	}
+	if s.Packs == nil {
+		s.Packs = make(map[string][]string)
+	}
+	s.Packs[pack] = append(s.Packs[pack], word)
+}
+
+// RecordWinDay notes a game won at t, extending the day streak if the last
+// win was the day before.
+func (s *Stats) RecordWinDay(t time.Time) {
+	day := t.UTC().Format(time.DateOnly)
+	switch s.WinDay {
+	case day:
+		return
+	case t.UTC().AddDate(0, 0, -1).Format(time.DateOnly):
+		s.DayStreak++
+	default:
+		s.DayStreak = 1
+	}
+	s.WinDay = day
+}
+
+// Unlock notes achievement id as unlocked at t, unless it already was, and
+// reports whether it was new.
+func (s *Stats) Unlock(id string, at time.Time) bool {
+	if _, ok := s.Achievements[id]; ok {
+		return false
+	}
+	if s.Achievements == nil {
+		s.Achievements = make(map[string]time.Time)
+	}
+	s.Achievements[id] = at
+	return true
+}
+
+// Clone returns a copy of s that shares no maps or slices with it.
+func (s Stats) Clone() Stats {
+	s.Distribution = maps.Clone(s.Distribution)
+	s.Achievements = maps.Clone(s.Achievements)
+	if s.Packs != nil {
+		packs := make(map[string][]string, len(s.Packs))
+		for pack, words := range s.Packs {
+			packs[pack] = slices.Clone(words)
+		}
+		s.Packs = packs
+	}
+	return s
+}
+
+// WinRate is the share of played games that were won, as a percentage.
+func (s Stats) WinRate() int {
+	if s.Played == 0 {
+		return 0
+	}
+	return s.Won * 100 / s.Played
+}
@@ -0,0 +1,501 @@
+// Package auth provides optional player accounts. A player can register with
+// a username and password, or with an email address and sign in through
+// one-time links, so that stats and settings follow them across devices.
+// Anonymous cookie sessions keep working without an account.
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/mail"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	util "github.com/CodeAndHammer/vortludo/internal/util"
+	"github.com/google/uuid"
+)
+
+const (
+	// LoginTTL is how long a sign-in lasts.
+	LoginTTL = 30 * 24 * time.Hour
+	// MagicLinkTTL is how long an emailed sign-in link stays valid.
+	MagicLinkTTL = 15 * time.Minute
+
+	MinPasswordLength = 8
+	MaxPasswordLength = 128
+)
+
+var (
+	ErrInvalidUsername    = errors.New("username must be 3-20 letters, digits, '-' or '_'")
+	ErrInvalidEmail       = errors.New("invalid email address")
+	ErrInvalidPassword    = fmt.Errorf("password must be %d-%d characters", MinPasswordLength, MaxPasswordLength)
+	ErrMissingCredentials = errors.New("a password or an email address is required")
+	ErrUsernameTaken      = errors.New("username is taken")
+	ErrEmailTaken         = errors.New("email address is already registered")
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	ErrUnknownEmail       = errors.New("no account with that email address")
+	ErrInvalidLink        = errors.New("sign-in link is invalid or has expired")
+	ErrPasswordRequired   = errors.New("a password is required")
+	ErrLinksDisabled      = errors.New("sign-in links are not sent")
+	ErrMailQueueFull      = errors.New("too many sign-in links waiting to be sent")
+)
+
+var usernamePattern = regexp.MustCompile(`^[a-z0-9_-]{3,20}$`)
+
+type User struct {
+	ID           string    `json:"id"`
+	Username     string    `json:"username"`
+	Email        string    `json:"email,omitempty"`
+	PasswordHash string    `json:"passwordHash,omitempty"`
+	CreatedAt    time.Time `json:"createdAt"`
+	// Settings holds the player's preferences as the session layer encodes
+	// them; the store does not interpret them.
+	Settings json.RawMessage `json:"settings,omitempty"`
+	Stats    Stats           `json:"stats"`
+	// Identities are the identity provider accounts linked to this one, as
+	// returned by Identity.Key.
+	Identities []string `json:"identities,omitempty"`
+}
+
+type login struct {
+	UserID    string    `json:"userId"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// Mailer delivers sign-in links.
+type Mailer interface {
+	SendMagicLink(email, link string) error
+}
+
+// LogMailer writes sign-in links to the log instead of sending them. It is
+// meant for development: anyone who can read the log can use the links.
+type LogMailer struct{}
+
+func (LogMailer) SendMagicLink(email, link string) error {
+	util.LogInfo("Sign-in link for %s: %s", email, link)
+	return nil
+}
+
+// Store holds the accounts and their sign-ins, and persists both to path
+// after every change. An empty path keeps everything in memory. Tokens are
+// only kept as hashes, so the file cannot be used to sign in.
+type Store struct {
+	mu     sync.Mutex
+	path   string
+	mailer Mailer
+	users  map[string]*User
+	logins map[string]login
+	links  map[string]login
+}
+
+type storeFile struct {
+	Users  map[string]*User `json:"users"`
+	Logins map[string]login `json:"logins"`
+}
+
+// Open loads the store from path. A missing file yields an empty store. A
+// nil mailer turns sign-in links off, so accounts then need a password.
+func Open(path string, mailer Mailer) (*Store, error) {
+	s := &Store{
+		path:   path,
+		mailer: mailer,
+		users:  make(map[string]*User),
+		logins: make(map[string]login),
+		links:  make(map[string]login),
+	}
+	if path == "" {
+		return s, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return s, nil
+		}
+		return nil, err
+	}
+	var file storeFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("decode account store: %w", err)
+	}
+	if file.Users != nil {
+		s.users = file.Users
+	}
+	if file.Logins != nil {
+		s.logins = file.Logins
+	}
+	return s, nil
+}
+
+// Len returns the number of accounts.
+func (s *Store) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.users)
+}
+
+// Register creates an account. Either a password or an email address is
+// required; an account without a password signs in through emailed links.
+func (s *Store) Register(username, email, password string) (User, error) {
+	username = strings.ToLower(strings.TrimSpace(username))
+	if !usernamePattern.MatchString(username) {
+		return User{}, ErrInvalidUsername
+	}
+	email, err := normalizeEmail(email)
+	if err != nil {
+		return User{}, err
+	}
+	if password == "" && email == "" {
+		return User{}, ErrMissingCredentials
+	}
+	if password == "" && s.mailer == nil {
+		return User{}, ErrPasswordRequired
+	}
+	var hash string
+	if password != "" {
+		if len(password) < MinPasswordLength || len(password) > MaxPasswordLength {
+			return User{}, ErrInvalidPassword
+		}
+		if hash, err = HashPassword(password); err != nil {
+			return User{}, err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, u := range s.users {
+		if u.Username == username {
+			return User{}, ErrUsernameTaken
+		}
+		if email != "" && u.Email == email {
+			return User{}, ErrEmailTaken
+		}
+	}
+	u := &User{
+		ID:           uuid.NewString(),
+		Username:     username,
+		Email:        email,
+		PasswordHash: hash,
+		CreatedAt:    time.Now().UTC(),
+	}
+	s.users[u.ID] = u
+	s.saveLocked()
+	util.LogInfo("Registered account %s", username)
+	return u.clone(), nil
+}
+
+// dummyHash is verified against when a username does not exist, so that
+// failed sign-ins take as long whether or not the account exists.
+var dummyHash = sync.OnceValue(func() string {
+	hash, _ := HashPassword("not a real password")
+	return hash
+})
+
+// Authenticate checks a username and password.
+func (s *Store) Authenticate(username, password string) (User, error) {
+	username = strings.ToLower(strings.TrimSpace(username))
+	s.mu.Lock()
+	var hash string
+	var found *User
+	for _, u := range s.users {
+		if u.Username == username {
+			found, hash = u, u.PasswordHash
+			break
+		}
+	}
+	s.mu.Unlock()
+
+	if found == nil || hash == "" {
+		VerifyPassword(password, dummyHash())
+		return User{}, ErrInvalidCredentials
+	}
+	if !VerifyPassword(password, hash) {
+		return User{}, ErrInvalidCredentials
+	}
+	return s.User(found.ID)
+}
+
+// SendsLinks reports whether the store has a mailer to send sign-in links.
+func (s *Store) SendsLinks() bool {
+	return s.mailer != nil
+}
+
+// SendMagicLink emails a one-time sign-in link to the account registered with
+// email. baseURL is the link without its token, e.g.
+// "https://example.org/account/magic?token=".
+func (s *Store) SendMagicLink(email, baseURL string) error {
+	if s.mailer == nil {
+		return ErrLinksDisabled
+	}
+	email, err := normalizeEmail(email)
+	if err != nil || email == "" {
+		return ErrInvalidEmail
+	}
+	token := newToken()
+
+	s.mu.Lock()
+	var userID string
+	for _, u := range s.users {
+		if u.Email == email {
+			userID = u.ID
+			break
+		}
+	}
+	if userID == "" {
+		s.mu.Unlock()
+		return ErrUnknownEmail
+	}
+	now := time.Now()
+	for key, link := range s.links {
+		if now.After(link.ExpiresAt) {
+			delete(s.links, key)
+		}
+	}
+	s.links[hashToken(token)] = login{UserID: userID, ExpiresAt: now.Add(MagicLinkTTL)}
+	s.mu.Unlock()
+
+	return s.mailer.SendMagicLink(email, baseURL+token)
+}
+
+// RedeemMagicLink consumes a sign-in link token.
+func (s *Store) RedeemMagicLink(token string) (User, error) {
+	s.mu.Lock()
+	key := hashToken(token)
+	link, ok := s.links[key]
+	delete(s.links, key)
+	s.mu.Unlock()
+	if !ok || time.Now().After(link.ExpiresAt) {
+		return User{}, ErrInvalidLink
+	}
+	return s.User(link.UserID)
+}
+
+// UserForIdentity returns the account an identity provider sign-in belongs
+// to. An identity seen for the first time is linked to linkTo if it is set,
+// and otherwise gets a new account named after it.
+func (s *Store) UserForIdentity(id Identity, linkTo string) (User, error) {
+	if id.Issuer == "" || id.Subject == "" {
+		return User{}, ErrInvalidCredentials
+	}
+	key := id.Key()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, u := range s.users {
+		if slices.Contains(u.Identities, key) {
+			return u.clone(), nil
+		}
+	}
+	if u, ok := s.users[linkTo]; ok {
+		u.Identities = append(u.Identities, key)
+		s.saveLocked()
+		util.LogInfo("Linked %s to account %s", id.Issuer, u.Username)
+		return u.clone(), nil
+	}
+
+	email := ""
+	if id.EmailVerified {
+		email, _ = normalizeEmail(id.Email)
+	}
+	for _, u := range s.users {
+		if email != "" && u.Email == email {
+			email = ""
+			break
+		}
+	}
+	u := &User{
+		ID:         uuid.NewString(),
+		Username:   s.freeUsernameLocked(id),
+		Email:      email,
+		CreatedAt:  time.Now().UTC(),
+		Identities: []string{key},
+	}
+	s.users[u.ID] = u
+	s.saveLocked()
+	util.LogInfo("Registered account %s through %s", u.Username, id.Issuer)
+	return u.clone(), nil
+}
+
+// freeUsernameLocked derives an unused username from an identity's preferred
+// name or email address.
+func (s *Store) freeUsernameLocked(id Identity) string {
+	base := id.Username
+	if base == "" {
+		base, _, _ = strings.Cut(id.Email, "@")
+	}
+	base = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		case r == ' ', r == '.':
+			return '_'
+		}
+		return -1
+	}, strings.ToLower(base))
+	if len(base) < 3 {
+		base = "player"
+	}
+	base = base[:min(len(base), 15)]
+
+	taken := make(map[string]bool, len(s.users))
+	for _, u := range s.users {
+		taken[u.Username] = true
+	}
+	name := base
+	for n := 2; taken[name]; n++ {
+		name = fmt.Sprintf("%s-%d", base, n)
+	}
+	return name
+}
+
+// StartLogin signs userID in and returns the token for their cookie.
+func (s *Store) StartLogin(userID string) string {
+	token := newToken()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for key, l := range s.logins {
+		if now.After(l.ExpiresAt) {
+			delete(s.logins, key)
+		}
+	}
+	s.logins[hashToken(token)] = login{UserID: userID, ExpiresAt: now.Add(LoginTTL)}
+	s.saveLocked()
+	return token
+}
+
+// EndLogin signs out the login identified by token.
+func (s *Store) EndLogin(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.logins, hashToken(token))
+	s.saveLocked()
+}
+
+// UserForLogin returns the user signed in with token.
+func (s *Store) UserForLogin(token string) (User, bool) {
+	if token == "" {
+		return User{}, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	l, ok := s.logins[hashToken(token)]
+	if !ok || time.Now().After(l.ExpiresAt) {
+		return User{}, false
+	}
+	u, ok := s.users[l.UserID]
+	if !ok {
+		return User{}, false
+	}
+	return u.clone(), true
+}
+
+// User returns the account with the given ID.
+func (s *Store) User(id string) (User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[id]
+	if !ok {
+		return User{}, ErrInvalidCredentials
+	}
+	return u.clone(), nil
+}
+
+// Delete removes the account and signs out its logins, and reports whether
+// there was one.
+func (s *Store) Delete(userID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.users[userID]; !ok {
+		return false
+	}
+	delete(s.users, userID)
+	for _, logins := range []map[string]login{s.logins, s.links} {
+		for key, l := range logins {
+			if l.UserID == userID {
+				delete(logins, key)
+			}
+		}
+	}
+	s.saveLocked()
+	return true
+}
+
+// SaveSettings replaces the account's settings.
+func (s *Store) SaveSettings(userID string, settings json.RawMessage) {
+	s.update(userID, func(u *User) { u.Settings = settings })
+}
+
+// RecordGame adds a finished game to the account's stats.
+func (s *Store) RecordGame(userID string, won bool, guesses int) {
+	s.update(userID, func(u *User) { u.Stats.Record(won, guesses) })
+}
+
+// UpdateStats changes the account's stats with fn, which must not keep
+// the pointer it is given.
+func (s *Store) UpdateStats(userID string, fn func(stats *Stats)) {
+	s.update(userID, func(u *User) { fn(&u.Stats) })
+}
+
+// MergeStats adds stats gathered anonymously to the account.
+func (s *Store) MergeStats(userID string, stats Stats) {
+	s.update(userID, func(u *User) { u.Stats.Merge(stats) })
+}
+
+func (s *Store) update(userID string, fn func(u *User)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if u, ok := s.users[userID]; ok {
+		fn(u)
+		s.saveLocked()
+	}
+}
+
+func (u *User) clone() User {
+	cp := *u
+	cp.Stats = u.Stats.Clone()
+	cp.Identities = slices.Clone(u.Identities)
+	return cp
+}
+
+func normalizeEmail(email string) (string, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return "", nil
+	}
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return "", ErrInvalidEmail
+	}
+	return email, nil
+}
+
+func newToken() string {
+	return rand.Text()
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *Store) saveLocked() {
+	if s.path == "" {
+		return
+	}
+	data, err := json.Marshal(storeFile{Users: s.users, Logins: s.logins})
+	if err != nil {
+		util.LogWarn("Failed to encode account store: %v", err)
+		return
+	}
+	if err := util.WriteFileAtomic(s.path, data); err != nil {
+		util.LogWarn("Failed to save account store: %v", err)
+	}
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	auth "github.com/CodeAndHammer/vortludo/internal/auth"
+)
+
+type captureMailer struct{ links []string }
+
+func (m *captureMailer) SendMagicLink(_, link string) error {
+	m.links = append(m.links, link)
+	return nil
+}
+
+func TestPasswordHash(t *testing.T) {
+	hash, err := auth.HashPassword("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$") {
+		t.Errorf("Expected a PHC argon2id string, got %s", hash)
+	}
+	if !auth.VerifyPassword("correct horse", hash) {
+		t.Error("Correct password should verify")
+	}
+	if auth.VerifyPassword("wrong horse", hash) || auth.VerifyPassword("correct horse", "garbage") {
+		t.Error("Wrong password or malformed hash should not verify")
+	}
+}
+
+func TestRegisterAndAuthenticate(t *testing.T) {
+	store, _ := auth.Open("", &captureMailer{})
+	user, err := store.Register("Alice", "", "password1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Username != "alice" || user.PasswordHash == "password1" {
+		t.Errorf("Unexpected user: %+v", user)
+	}
+	cases := []struct {
+		username, email, password string
+		want                      error
+	}{
+		{"alice", "", "password2", auth.ErrUsernameTaken},
+		{"a", "", "password1", auth.ErrInvalidUsername},
+		{"bob", "", "short", auth.ErrInvalidPassword},
+		{"bob", "not an email", "", auth.ErrInvalidEmail},
+		{"bob", "", "", auth.ErrMissingCredentials},
+	}
+	for _, tc := range cases {
+		if _, err := store.Register(tc.username, tc.email, tc.password); !errors.Is(err, tc.want) {
+			t.Errorf("Register(%q, %q, %q) = %v, want %v", tc.username, tc.email, tc.password, err, tc.want)
+		}
+	}
+
+	if _, err := store.Authenticate("ALICE", "password1"); err != nil {
+		t.Errorf("Username should be case-insensitive: %v", err)
+	}
+	if _, err := store.Authenticate("alice", "password2"); !errors.Is(err, auth.ErrInvalidCredentials) {
+		t.Errorf("Wrong password should fail, got %v", err)
+	}
+	if _, err := store.Authenticate("nobody", "password1"); !errors.Is(err, auth.ErrInvalidCredentials) {
+		t.Errorf("Unknown user should fail, got %v", err)
+	}
+}
+
+func TestMagicLinkIsSingleUse(t *testing.T) {
+	mailer := &captureMailer{}
+	store, _ := auth.Open("", mailer)
+	user, err := store.Register("carol", "Carol@Example.org", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SendMagicLink("nobody@example.org", "/magic?token="); !errors.Is(err, auth.ErrUnknownEmail) {
+		t.Errorf("Unknown address should be reported to the caller, got %v", err)
+	}
+	if err := store.SendMagicLink("carol@example.org", "/magic?token="); err != nil {
+		t.Fatal(err)
+	}
+	if len(mailer.links) != 1 {
+		t.Fatalf("Expected one link, got %v", mailer.links)
+	}
+	token := strings.TrimPrefix(mailer.links[0], "/magic?token=")
+	got, err := store.RedeemMagicLink(token)
+	if err != nil || got.ID != user.ID {
+		t.Fatalf("Link should sign in carol, got %+v, %v", got, err)
+	}
+	if _, err := store.RedeemMagicLink(token); !errors.Is(err, auth.ErrInvalidLink) {
+		t.Errorf("Link should only work once, got %v", err)
+	}
+}
+
+func TestNoMailerNoLinks(t *testing.T) {
+	store, _ := auth.Open("", nil)
+	if store.SendsLinks() {
+		t.Error("A store without a mailer should not send links")
+	}
+	if _, err := store.Register("erin", "erin@example.org", ""); !errors.Is(err, auth.ErrPasswordRequired) {
+		t.Errorf("An account without links to sign in with needs a password, got %v", err)
+	}
+	if _, err := store.Register("erin", "erin@example.org", "password1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SendMagicLink("erin@example.org", "/magic?token="); !errors.Is(err, auth.ErrLinksDisabled) {
+		t.Errorf("Expected links disabled, got %v", err)
+	}
+}
+
+func TestSMTPMailer(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	received := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		fmt.Fprint(conn, "220 localhost ESMTP\r\n")
+		var data strings.Builder
+		for inData := false; ; {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch {
+			case inData && line == ".\r\n":
+				inData = false
+				received <- data.String()
+				fmt.Fprint(conn, "250 queued\r\n")
+			case inData:
+				data.WriteString(line)
+			case strings.HasPrefix(line, "EHLO"):
+				fmt.Fprint(conn, "250 localhost\r\n")
+			case strings.HasPrefix(line, "DATA"):
+				inData = true
+				fmt.Fprint(conn, "354 go ahead\r\n")
+			case strings.HasPrefix(line, "QUIT"):
+				fmt.Fprint(conn, "221 bye\r\n")
+				return
+			default:
+				fmt.Fprint(conn, "250 ok\r\n")
+			}
+		}
+	}()
+
+	mailer := auth.SMTPMailer{Addr: ln.Addr().String(), From: "Vortludo <play@example.org>"}
+	if err := mailer.SendMagicLink("frank@example.org", "https://example.org/account/magic?token=abc"); err != nil {
+		t.Fatal(err)
+	}
+	msg := <-received
+	for _, want := range []string{"To: frank@example.org\r\n", "From: \"Vortludo\" <play@example.org>\r\n", "https://example.org/account/magic?token=abc"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("Expected %q in the email:\n%s", want, msg)
+		}
+	}
+}
+
+func TestMailQueue(t *testing.T) {
+	mailer := &captureMailer{}
+	queue := auth.NewMailQueue(mailer)
+	var err error
+	for i := 0; err == nil; i++ {
+		if i > 1000 {
+			t.Fatal("Expected the queue to fill up")
+		}
+		err = queue.SendMagicLink("grace@example.org", fmt.Sprintf("/magic?token=%d", i))
+	}
+	if !errors.Is(err, auth.ErrMailQueueFull) {
+		t.Fatalf("Expected a full queue, got %v", err)
+	}
+	if len(mailer.links) != 0 {
+		t.Fatal("Expected nothing sent before Run")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		queue.Run(ctx)
+		close(done)
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for queue.SendMagicLink("grace@example.org", "/magic?token=last") != nil && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+	if len(mailer.links) == 0 || mailer.links[0] != "/magic?token=0" {
+		t.Errorf("Expected the queued links sent in order, got %d", len(mailer.links))
+	}
+}
+
+func TestLoginsPersistHashed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "accounts.json")
+	store, _ := auth.Open(path, auth.LogMailer{})
+	user, _ := store.Register("dave", "", "password1")
+	token := store.StartLogin(user.ID)
+	store.RecordGame(user.ID, true, 3)
+
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), token) {
+		t.Error("Login tokens should only be stored hashed")
+	}
+	reopened, err := auth.Open(path, auth.LogMailer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, ok := reopened.UserForLogin(token)
+	if !ok || got.Username != "dave" || got.Stats.Won != 1 {
+		t.Errorf("Login should survive a restart, got %+v, %v", got, ok)
+	}
+	reopened.EndLogin(token)
+	if _, ok := reopened.UserForLogin(token); ok {
+		t.Error("Ended login should no longer be valid")
+	}
+}
+
+func TestStatsMerge(t *testing.T) {
+	var account auth.Stats
+	account.Record(true, 3)
+	account.Record(true, 4)
+
+	var anonymous auth.Stats
+	anonymous.Record(true, 2)
+	merged := account
+	merged.Merge(anonymous)
+	if merged.Played != 3 || merged.CurrentStreak != 3 || merged.MaxStreak != 3 || merged.Distribution[2] != 1 {
+		t.Errorf("Unbroken streak should continue: %+v", merged)
+	}
+
+	anonymous.Record(false, 6)
+	anonymous.Record(true, 5)
+	merged = auth.Stats{Played: 2, Won: 2, CurrentStreak: 2, MaxStreak: 2}
+	merged.Merge(anonymous)
+	if merged.Played != 5 || merged.Won != 4 || merged.CurrentStreak != 1 || merged.MaxStreak != 2 {
+		t.Errorf("Loss should reset the streak: %+v", merged)
+	}
+	if merged.WinRate() != 80 {
+		t.Errorf("WinRate = %d, want 80", merged.WinRate())
+	}
+}
+
+func TestStatsPoints(t *testing.T) {
+	var stats auth.Stats
+	stats.RecordPoints(4, 4)
+	stats.RecordPoints(10, 14)
+	stats.RecordPoints(3, 3)
+	if stats.Points != 17 || stats.BestRun != 14 {
+		t.Errorf("Expected 17 points and a best run of 14, got %d and %d", stats.Points, stats.BestRun)
+	}
+	stats.Merge(auth.Stats{Points: 20, BestRun: 20})
+	if stats.Points != 37 || stats.BestRun != 20 {
+		t.Errorf("Expected merged 37 points and a best run of 20, got %d and %d", stats.Points, stats.BestRun)
+	}
+}
+
+func TestStatsDayStreak(t *testing.T) {
+	day := time.Date(2026, 3, 1, 23, 0, 0, 0, time.UTC)
+	var stats auth.Stats
+	stats.RecordWinDay(day)
+	stats.RecordWinDay(day.Add(30 * time.Minute))
+	stats.RecordWinDay(day.Add(time.Hour))
+	if stats.DayStreak != 2 || stats.WinDay != "2026-03-02" {
+		t.Errorf("Expected a 2-day streak up to March 2, got %d up to %s", stats.DayStreak, stats.WinDay)
+	}
+	stats.RecordWinDay(day.AddDate(0, 0, 3))
+	if stats.DayStreak != 1 {
+		t.Errorf("A missed day should restart the streak, got %d", stats.DayStreak)
+	}
+
+	var anonymous auth.Stats
+	anonymous.Unlock("first_win", day)
+	stats.Unlock("first_win", day.AddDate(0, 0, 1))
+	if stats.Unlock("first_win", day) {
+		t.Error("Unlock should report an achievement already unlocked")
+	}
+	stats.Merge(anonymous)
+	if !stats.Achievements["first_win"].Equal(day) {
+		t.Errorf("Merge should keep the earliest unlock, got %v", stats.Achievements["first_win"])
+	}
+}
+
+func TestStatsPacks(t *testing.T) {
+	var account auth.Stats
+	account.SolvePackWord("animals", "HORSE")
+	account.SolvePackWord("animals", "HORSE")
+	if got := account.Packs["animals"]; !slices.Equal(got, []string{"HORSE"}) {
+		t.Errorf("Solving a word twice should note it once, got %v", got)
+	}
+
+	var anonymous auth.Stats
+	anonymous.Record(true, 3)
+	anonymous.SolvePackWord("animals", "HORSE")
+	anonymous.SolvePackWord("animals", "TIGER")
+	anonymous.SolvePackWord("food", "BREAD")
+	account.Merge(anonymous)
+	if !slices.Equal(account.Packs["animals"], []string{"HORSE", "TIGER"}) || !slices.Equal(account.Packs["food"], []string{"BREAD"}) {
+		t.Errorf("Merge should join the solved pack words, got %v", account.Packs)
+	}
+
+	clone := account.Clone()
+	clone.SolvePackWord("animals", "ZEBRA")
+	if len(account.Packs["animals"]) != 2 {
+		t.Errorf("Clone shares pack progress with the original: %v", account.Packs)
+	}
+}
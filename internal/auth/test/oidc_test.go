@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	auth "github.com/CodeAndHammer/vortludo/internal/auth"
+)
+
+// fakeProvider is a minimal OpenID Connect provider that issues an ID token
+// for whatever claims the test sets.
+type fakeProvider struct {
+	*httptest.Server
+	key    *rsa.PrivateKey
+	claims map[string]any
+	nonce  string
+	// challenge is the PKCE challenge of the last authorization request.
+	challenge string
+}
+
+func newFakeProvider(t *testing.T) *fakeProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &fakeProvider{key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 p.URL,
+			"authorization_endpoint": p.URL + "/authorize",
+			"token_endpoint":         p.URL + "/token",
+			"jwks_uri":               p.URL + "/jwks",
+			"end_session_endpoint":   p.URL + "/logout",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "k1",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		id, secret, _ := r.BasicAuth()
+		sum := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+		if id != "vortludo" || secret != "s3cret" || r.PostFormValue("code") != "good-code" ||
+			base64.RawURLEncoding.EncodeToString(sum[:]) != p.challenge {
+			http.Error(w, "invalid_grant", http.StatusBadRequest)
+			return
+		}
+		claims := map[string]any{
+			"iss":   p.URL,
+			"sub":   "user-1",
+			"aud":   "vortludo",
+			"exp":   time.Now().Add(time.Minute).Unix(),
+			"iat":   time.Now().Unix(),
+			"nonce": p.nonce,
+		}
+		for k, v := range p.claims {
+			claims[k] = v
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id_token": p.sign(t, claims)})
+	})
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Close)
+	return p
+}
+
+func (p *fakeProvider) sign(t *testing.T, claims map[string]any) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "k1", "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, p.key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+// authorize plays the player's visit to the provider's sign-in page.
+func (p *fakeProvider) authorize(t *testing.T, authURL string) url.Values {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	p.nonce = q.Get("nonce")
+	p.challenge = q.Get("code_challenge")
+	return q
+}
+
+func newTestOIDC(t *testing.T, p *fakeProvider) *auth.OIDCProvider {
+	provider, err := auth.NewOIDCProvider(auth.OIDCConfig{
+		Issuer:       p.URL,
+		ClientID:     "vortludo",
+		ClientSecret: "s3cret",
+		RedirectURL:  "https://vortludo.test/account/oidc/callback",
+	}, p.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return provider
+}
+
+func TestOIDCLogin(t *testing.T) {
+	fake := newFakeProvider(t)
+	fake.claims = map[string]any{"email": "Carol@Example.org", "email_verified": true, "preferred_username": "Carol Smith"}
+	provider := newTestOIDC(t, fake)
+	ctx := context.Background()
+
+	authURL, login, err := provider.Start(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := fake.authorize(t, authURL)
+	if q.Get("state") != login.State || q.Get("code_challenge_method") != "S256" || q.Get("scope") != "openid profile email" {
+		t.Errorf("Unexpected authorization request: %v", q)
+	}
+	decoded, ok := auth.DecodeOIDCLogin(login.Encode())
+	if !ok || decoded != login {
+		t.Fatalf("Login should survive its cookie encoding, got %+v", decoded)
+	}
+
+	if _, err := provider.Finish(ctx, login, "forged", "good-code"); !errors.Is(err, auth.ErrOIDCLogin) {
+		t.Errorf("Mismatched state should fail, got %v", err)
+	}
+	id, err := provider.Finish(ctx, login, login.State, "good-code")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.Subject != "user-1" || id.Email != "carol@example.org" || !id.EmailVerified || id.Username != "Carol Smith" {
+		t.Errorf("Unexpected identity: %+v", id)
+	}
+
+	store, _ := auth.Open("", &captureMailer{})
+	user, err := store.UserForIdentity(id, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Username != "carol_smith" || user.Email != "carol@example.org" {
+		t.Errorf("Unexpected new account: %+v", user)
+	}
+	again, _ := store.UserForIdentity(id, "")
+	if again.ID != user.ID || store.Len() != 1 {
+		t.Error("A known identity should sign in to its account")
+	}
+
+	if logout := provider.LogoutURL(ctx, "https://vortludo.test/account"); logout == "" {
+		t.Error("Expected a logout URL from the end_session_endpoint")
+	}
+}
+
+func TestOIDCRejectsBadTokens(t *testing.T) {
+	cases := map[string]map[string]any{
+		"wrong audience": {"aud": "someone-else"},
+		"wrong issuer":   {"iss": "https://evil.example"},
+		"expired":        {"exp": time.Now().Add(-time.Hour).Unix()},
+		"replayed nonce": {"nonce": "old"},
+	}
+	for name, claims := range cases {
+		fake := newFakeProvider(t)
+		fake.claims = claims
+		provider := newTestOIDC(t, fake)
+		authURL, login, err := provider.Start(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		fake.authorize(t, authURL)
+		if _, err := provider.Finish(context.Background(), login, login.State, "good-code"); !errors.Is(err, auth.ErrOIDCLogin) {
+			t.Errorf("%s: expected ErrOIDCLogin, got %v", name, err)
+		}
+	}
+}
+
+func TestUserForIdentityLinksAndAvoidsClashes(t *testing.T) {
+	store, _ := auth.Open("", &captureMailer{})
+	alice, _ := store.Register("alice", "alice@example.org", "password1")
+
+	linked, err := store.UserForIdentity(auth.Identity{Issuer: "https://id", Subject: "a"}, alice.ID)
+	if err != nil || linked.ID != alice.ID {
+		t.Fatalf("Identity should link to the signed-in account, got %+v, %v", linked, err)
+	}
+
+	// An unverified address must not take over, and a taken name gets a suffix.
+	other, err := store.UserForIdentity(auth.Identity{Issuer: "https://id", Subject: "b", Username: "alice", Email: "alice@example.org"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.ID == alice.ID || other.Username != "alice-2" || other.Email != "" {
+		t.Errorf("Unexpected account for a clashing identity: %+v", other)
+	}
+}
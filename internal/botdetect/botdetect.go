@@ -0,0 +1,265 @@
+// Package botdetect flags sessions whose play looks automated: guesses sent
+// faster or more evenly than people type them, or games where every guess
+// was the one that revealed the most about the answer. Each finished game is
+// scored; a session is flagged once its scores add up to the threshold,
+// while games that look human wear its score down again.
+package botdetect
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultMinInterval = time.Second
+	DefaultFlagScore   = 100
+	DefaultForget      = 24 * time.Hour
+	DefaultMaxTracked  = 10000
+)
+
+// Points a game scores for each sign of automation, and the points a game
+// without any takes off its session's score.
+const (
+	fastPoints    = 60
+	regularPoints = 40
+	optimalPoints = 50
+	humanCredit   = 25
+)
+
+// Reasons a game scored points.
+const (
+	ReasonFast    = "fast_guesses"
+	ReasonRegular = "regular_timing"
+	ReasonOptimal = "optimal_play"
+)
+
+// minTimedIntervals is how many intervals between guesses a game needs for
+// its timing to count, and minRegularIntervals how many for its regularity
+// to.
+const (
+	minTimedIntervals   = 3
+	minRegularIntervals = 4
+	// maxRegularSpread is the largest coefficient of variation of the
+	// intervals that still counts as machine-regular.
+	maxRegularSpread = 0.1
+	// minDecisions is how many guesses with a real choice a game needs for
+	// its play to count as optimal.
+	minDecisions = 2
+)
+
+// Config sets how games are scored and when sessions are flagged. Zero
+// values take the defaults.
+type Config struct {
+	// MinInterval is the shortest time between two guesses that counts as
+	// human.
+	MinInterval time.Duration
+	// FlagScore is the score at which a session is flagged.
+	FlagScore int
+	// Forget is how long a session must stay idle before it is dropped,
+	// flag and all.
+	Forget time.Duration
+	// MaxTracked caps the number of sessions tracked at once.
+	MaxTracked int
+}
+
+// Game is what the heuristics look at in a finished game.
+type Game struct {
+	// Intervals are the times between consecutive guesses.
+	Intervals []time.Duration
+	// Decisions counts the guesses made while several answers were still
+	// possible, and Optimal those of them that split the answers best.
+	Decisions int
+	Optimal   int
+}
+
+// Verdict is the score of one game and the reasons for it.
+type Verdict struct {
+	Score   int      `json:"score"`
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+// Session is what the detector knows of a session.
+type Session struct {
+	ID        string    `json:"id"`
+	Score     int       `json:"score"`
+	Games     int       `json:"games"`
+	Suspect   int       `json:"suspectGames"`
+	Reasons   []string  `json:"reasons,omitempty"`
+	Flagged   bool      `json:"flagged"`
+	FlaggedAt time.Time `json:"flaggedAt,omitzero"`
+	LastSeen  time.Time `json:"lastSeen"`
+}
+
+// Detector scores finished games and keeps the score of each session. A nil
+// Detector flags nobody.
+type Detector struct {
+	cfg Config
+
+	mu       sync.Mutex
+	sessions map[string]*Session
+}
+
+func New(cfg Config) *Detector {
+	cfg.MinInterval = cmp.Or(cfg.MinInterval, DefaultMinInterval)
+	cfg.FlagScore = cmp.Or(cfg.FlagScore, DefaultFlagScore)
+	cfg.Forget = cmp.Or(cfg.Forget, DefaultForget)
+	cfg.MaxTracked = cmp.Or(cfg.MaxTracked, DefaultMaxTracked)
+	return &Detector{cfg: cfg, sessions: make(map[string]*Session)}
+}
+
+// Score rates game without recording it.
+func (d *Detector) Score(game Game) Verdict {
+	var v Verdict
+	if len(game.Intervals) >= minTimedIntervals {
+		fast := 0
+		for _, interval := range game.Intervals {
+			if interval < d.cfg.MinInterval {
+				fast++
+			}
+		}
+		if fast*2 > len(game.Intervals) {
+			v.add(fastPoints, ReasonFast)
+		}
+	}
+	if len(game.Intervals) >= minRegularIntervals && spread(game.Intervals) <= maxRegularSpread {
+		v.add(regularPoints, ReasonRegular)
+	}
+	if game.Decisions >= minDecisions && game.Optimal == game.Decisions {
+		v.add(optimalPoints, ReasonOptimal)
+	}
+	return v
+}
+
+func (v *Verdict) add(points int, reason string) {
+	v.Score += points
+	v.Reasons = append(v.Reasons, reason)
+}
+
+// spread is the coefficient of variation of the intervals: their standard
+// deviation over their mean.
+func spread(intervals []time.Duration) float64 {
+	var sum float64
+	for _, interval := range intervals {
+		sum += interval.Seconds()
+	}
+	mean := sum / float64(len(intervals))
+	if mean == 0 {
+		return 0
+	}
+	var squares float64
+	for _, interval := range intervals {
+		squares += (interval.Seconds() - mean) * (interval.Seconds() - mean)
+	}
+	return math.Sqrt(squares/float64(len(intervals))) / mean
+}
+
+// Record scores game and adds it to the session's score. It returns the
+// game's verdict and whether this game got the session flagged.
+func (d *Detector) Record(sessionID string, game Game, now time.Time) (Verdict, bool) {
+	if d == nil {
+		return Verdict{}, false
+	}
+	v := d.Score(game)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	s, ok := d.sessions[sessionID]
+	if !ok {
+		if len(d.sessions) >= d.cfg.MaxTracked && d.cleanupLocked(now) == 0 && v.Score == 0 {
+			return v, false
+		}
+		s = &Session{ID: sessionID}
+		d.sessions[sessionID] = s
+	}
+	s.Games++
+	s.LastSeen = now
+	if v.Score == 0 {
+		s.Score = max(s.Score-humanCredit, 0)
+		return v, false
+	}
+	s.Suspect++
+	s.Score += v.Score
+	for _, reason := range v.Reasons {
+		if !slices.Contains(s.Reasons, reason) {
+			s.Reasons = append(s.Reasons, reason)
+		}
+	}
+	if s.Flagged || s.Score < d.cfg.FlagScore {
+		return v, false
+	}
+	s.Flagged = true
+	s.FlaggedAt = now
+	return v, true
+}
+
+// Flagged reports whether the session was flagged.
+func (d *Detector) Flagged(sessionID string) bool {
+	if d == nil {
+		return false
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	s, ok := d.sessions[sessionID]
+	return ok && s.Flagged
+}
+
+// Sessions returns the sessions that played a suspect game, highest score
+// first.
+func (d *Detector) Sessions() []Session {
+	if d == nil {
+		return []Session{}
+	}
+	d.mu.Lock()
+	out := make([]Session, 0, len(d.sessions))
+	for _, s := range d.sessions {
+		if s.Suspect > 0 {
+			cp := *s
+			cp.Reasons = slices.Clone(s.Reasons)
+			out = append(out, cp)
+		}
+	}
+	d.mu.Unlock()
+	slices.SortFunc(out, func(a, b Session) int {
+		if c := cmp.Compare(b.Score, a.Score); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return out
+}
+
+// Clear forgets the session, e.g. after an admin found it was a person.
+func (d *Detector) Clear(sessionID string) bool {
+	if d == nil {
+		return false
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	_, ok := d.sessions[sessionID]
+	delete(d.sessions, sessionID)
+	return ok
+}
+
+// Cleanup drops the sessions idle for longer than Forget and returns how
+// many it dropped.
+func (d *Detector) Cleanup(now time.Time) int {
+	if d == nil {
+		return 0
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.cleanupLocked(now)
+}
+
+func (d *Detector) cleanupLocked(now time.Time) int {
+	removed := 0
+	for id, s := range d.sessions {
+		if now.Sub(s.LastSeen) > d.cfg.Forget {
+			delete(d.sessions, id)
+			removed++
+		}
+	}
+	return removed
+}
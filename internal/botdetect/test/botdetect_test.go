@@ -0,0 +1,76 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	botdetect "github.com/CodeAndHammer/vortludo/internal/botdetect"
+)
+
+func intervals(seconds ...float64) []time.Duration {
+	out := make([]time.Duration, len(seconds))
+	for i, s := range seconds {
+		out[i] = time.Duration(s * float64(time.Second))
+	}
+	return out
+}
+
+func TestScore(t *testing.T) {
+	d := botdetect.New(botdetect.Config{})
+	tests := []struct {
+		name string
+		game botdetect.Game
+		want []string
+	}{
+		{"human", botdetect.Game{Intervals: intervals(4, 9.5, 3, 12), Decisions: 3, Optimal: 1}, nil},
+		{"fast", botdetect.Game{Intervals: intervals(0.2, 0.3, 5)}, []string{botdetect.ReasonFast}},
+		{"regular", botdetect.Game{Intervals: intervals(3, 3.1, 2.95, 3)}, []string{botdetect.ReasonRegular}},
+		{"scripted", botdetect.Game{Intervals: intervals(0.5, 0.5, 0.5, 0.5)}, []string{botdetect.ReasonFast, botdetect.ReasonRegular}},
+		{"optimal", botdetect.Game{Decisions: 3, Optimal: 3}, []string{botdetect.ReasonOptimal}},
+		{"lucky", botdetect.Game{Decisions: 1, Optimal: 1}, nil},
+	}
+	for _, tt := range tests {
+		if got := d.Score(tt.game).Reasons; !slices.Equal(got, tt.want) {
+			t.Errorf("%s: reasons %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRecordFlagsSessions(t *testing.T) {
+	d := botdetect.New(botdetect.Config{FlagScore: 100})
+	now := time.Now()
+	optimal := botdetect.Game{Decisions: 2, Optimal: 2}
+	human := botdetect.Game{Intervals: intervals(5, 8, 4)}
+
+	if _, flagged := d.Record("sess", optimal, now); flagged || d.Flagged("sess") {
+		t.Fatal("One perfect game should not flag a session")
+	}
+	d.Record("sess", human, now)
+	if _, flagged := d.Record("sess", optimal, now); flagged {
+		t.Fatal("Human games in between should wear the score down")
+	}
+	if _, flagged := d.Record("sess", optimal, now); !flagged || !d.Flagged("sess") {
+		t.Fatal("Expected the session flagged once its score reached the threshold")
+	}
+	if _, flagged := d.Record("sess", optimal, now); flagged {
+		t.Error("A session is only flagged once")
+	}
+
+	d.Record("person", human, now)
+	sessions := d.Sessions()
+	if len(sessions) != 1 || sessions[0].ID != "sess" || sessions[0].Suspect != 4 || sessions[0].Games != 5 {
+		t.Errorf("Expected only the suspect session listed, got %+v", sessions)
+	}
+	if !d.Clear("sess") || d.Flagged("sess") || d.Clear("sess") {
+		t.Error("Clear should forget the session once")
+	}
+	if removed := d.Cleanup(now.Add(botdetect.DefaultForget + time.Minute)); removed != 1 {
+		t.Errorf("Expected the idle session dropped, removed %d", removed)
+	}
+
+	var none *botdetect.Detector
+	if _, flagged := none.Record("sess", optimal, now); flagged || none.Flagged("sess") {
+		t.Error("A nil detector should flag nobody")
+	}
+}
@@ -0,0 +1,125 @@
+// Package compress negotiates the content coding of responses and provides
+// the Brotli and gzip encoders for them.
+package compress
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"mime"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+const (
+	Brotli = "br"
+	Gzip   = "gzip"
+)
+
+// Encodings lists the supported codings in order of preference.
+var Encodings = []string{Brotli, Gzip}
+
+// Extension returns the file extension of pre-compressed siblings, e.g.
+// "style.css.br", in the given coding.
+func Extension(encoding string) string {
+	switch encoding {
+	case Brotli:
+		return ".br"
+	case Gzip:
+		return ".gz"
+	}
+	return ""
+}
+
+// Negotiate picks the coding to send, out of offered, for a request's
+// Accept-Encoding header. Higher q-values win; ties go to the earlier entry of
+// offered. It returns "" when the response should not be encoded.
+func Negotiate(acceptEncoding string, offered ...string) string {
+	weights := make(map[string]float64)
+	wildcard := -1.0
+	for part := range strings.SplitSeq(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if name == "*" {
+			wildcard = q
+		} else {
+			weights[name] = q
+		}
+	}
+
+	best, bestQ := "", 0.0
+	for _, encoding := range offered {
+		q, ok := weights[encoding]
+		if !ok {
+			q = max(wildcard, 0)
+		}
+		if q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+	return best
+}
+
+// Compressible reports whether a response of contentType is worth
+// compressing. Event streams are left alone, since compression would hold
+// events back until enough of them pile up.
+func Compressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch {
+	case mediaType == "text/event-stream":
+		return false
+	case strings.HasPrefix(mediaType, "text/"):
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/javascript", "application/xml",
+		"application/manifest+json", "image/svg+xml":
+		return true
+	}
+	return false
+}
+
+// NewWriter returns an encoder for responses written on the fly. It favours
+// speed over size.
+func NewWriter(encoding string, w io.Writer) io.WriteCloser {
+	if encoding == Brotli {
+		return brotli.NewWriterLevel(w, 4)
+	}
+	gz, _ := gzip.NewWriterLevel(w, gzip.DefaultCompression)
+	return gz
+}
+
+// Encode compresses data as small as the coding allows, for content that is
+// compressed once and served many times.
+func Encode(encoding string, data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	if encoding == Brotli {
+		w = brotli.NewWriterLevel(&buf, brotli.BestCompression)
+	} else {
+		w, _ = gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	compress "github.com/CodeAndHammer/vortludo/internal/compress"
+	"github.com/andybalholm/brotli"
+)
+
+func TestNegotiate(t *testing.T) {
+	cases := map[string]string{
+		"":                          "",
+		"gzip, deflate, br":         "br",
+		"gzip":                      "gzip",
+		"br;q=0.5, gzip":            "gzip",
+		"br;q=0, gzip;q=0":          "",
+		"*":                         "br",
+		"*;q=0.1, gzip;q=0":         "br",
+		"identity":                  "",
+		"GZIP;q=0.8, deflate;q=0.9": "gzip",
+	}
+	for header, want := range cases {
+		if got := compress.Negotiate(header, compress.Encodings...); got != want {
+			t.Errorf("Negotiate(%q) = %q, want %q", header, got, want)
+		}
+	}
+	if got := compress.Negotiate("br, gzip", compress.Gzip); got != "gzip" {
+		t.Errorf("Only offered codings may be picked, got %q", got)
+	}
+}
+
+func TestCompressible(t *testing.T) {
+	for contentType, want := range map[string]bool{
+		"text/html; charset=utf-8":        true,
+		"application/json; charset=utf-8": true,
+		"text/javascript; charset=utf-8":  true,
+		"text/event-stream":               false,
+		"image/png":                       false,
+		"":                                false,
+	} {
+		if got := compress.Compressible(contentType); got != want {
+			t.Errorf("Compressible(%q) = %v", contentType, got)
+		}
+	}
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	data := bytes.Repeat([]byte("vortludo "), 200)
+	encoded, err := compress.Encode(compress.Brotli, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := io.ReadAll(brotli.NewReader(bytes.NewReader(encoded)))
+	if err != nil || !bytes.Equal(decoded, data) || len(encoded) >= len(data) {
+		t.Errorf("Brotli round trip failed: %v, %d bytes", err, len(encoded))
+	}
+}
@@ -0,0 +1,705 @@
+// Package config reads the server's configuration from the environment, once,
+// into a Config. Every setting is a field tagged with the variable it is read
+// from, so loading, validating and printing the configuration share one list
+// of knobs.
+package config
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"net/mail"
+	"net/url"
+	"os"
+	"path"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	abuse "github.com/CodeAndHammer/vortludo/internal/abuse"
+	botdetect "github.com/CodeAndHammer/vortludo/internal/botdetect"
+	constants "github.com/CodeAndHammer/vortludo/internal/constants"
+	digest "github.com/CodeAndHammer/vortludo/internal/digest"
+	errreport "github.com/CodeAndHammer/vortludo/internal/errreport"
+	eventlog "github.com/CodeAndHammer/vortludo/internal/eventlog"
+	render "github.com/CodeAndHammer/vortludo/internal/render"
+	telemetry "github.com/CodeAndHammer/vortludo/internal/telemetry"
+)
+
+// Config is the whole configuration. Fields tagged `env` are read from that
+// variable, and from the `file` key of their section in a configuration file;
+// `secret` ones are redacted when printed; `mode` ones can be set for one mode
+// only by adding _PRODUCTION or _DEVELOPMENT to the variable's name, or in a
+// production or development table of their section; `sep` splits list values.
+// `process` ones apply to the whole process and cannot be set for a tenant;
+// `state` ones name what an instance writes, which tenants may not share.
+type Config struct {
+	GinMode string `env:"GIN_MODE" file:"gin_mode" process:"true"`
+	Env     string `env:"ENV" file:"env" process:"true"`
+	// Production is set when GIN_MODE is release or ENV is production.
+	Production bool
+
+	Server    Server    `file:"server"`
+	Sessions  Sessions  `file:"sessions"`
+	Store     Store     `file:"store"`
+	Assets    Assets    `file:"assets"`
+	RateLimit RateLimit `file:"rate_limit"`
+	Abuse     Abuse     `file:"abuse"`
+	Bots      Bots      `file:"bots"`
+	Words     Words     `file:"words"`
+	Game      Game      `file:"game"`
+	Accounts  Accounts  `file:"accounts"`
+	OIDC      OIDC      `file:"oidc"`
+	Mail      Mail      `file:"mail"`
+	Security  Security  `file:"security"`
+	EventLog  EventLog  `file:"event_log"`
+	Telemetry Telemetry `file:"telemetry"`
+	Digest    Digest    `file:"digest"`
+	Errors    Errors    `file:"errors"`
+}
+
+type Server struct {
+	// Port defaults to 443 when TLS is on and 8080 otherwise.
+	Port      string `env:"PORT" file:"port" process:"true"`
+	PublicURL string `env:"PUBLIC_URL" file:"public_url"`
+	// BasePath is the path prefix the game is served under, such as
+	// /games/vortludo, for a reverse proxy that passes it through.
+	BasePath        string        `env:"BASE_PATH" file:"base_path"`
+	ShutdownTimeout time.Duration `env:"SHUTDOWN_TIMEOUT" file:"shutdown_timeout" process:"true"`
+	Maintenance     bool          `env:"MAINTENANCE_MODE" file:"maintenance"`
+	// MaxRequestBytes caps request bodies; larger ones are answered 413.
+	MaxRequestBytes int `env:"MAX_REQUEST_BYTES" file:"max_request_bytes"`
+	// RequestTimeout is the deadline of a request; GuessTimeout and
+	// AdminTimeout replace it for /guess and the admin routes. 0 sets none.
+	RequestTimeout time.Duration `env:"REQUEST_TIMEOUT" file:"request_timeout"`
+	GuessTimeout   time.Duration `env:"GUESS_TIMEOUT" file:"guess_timeout"`
+	AdminTimeout   time.Duration `env:"ADMIN_TIMEOUT" file:"admin_timeout"`
+	// AnnouncementFile keeps the announcement set by admins across restarts.
+	AnnouncementFile string   `env:"ANNOUNCEMENT_FILE" file:"announcement_file" state:"true"`
+	Socket           string   `env:"LISTEN_SOCKET" file:"socket" process:"true"`
+	SocketMode       FileMode `env:"LISTEN_SOCKET_MODE" file:"socket_mode" process:"true"`
+	TLSCertFile      string   `env:"TLS_CERT_FILE" file:"tls_cert_file" process:"true"`
+	TLSKeyFile       string   `env:"TLS_KEY_FILE" file:"tls_key_file" process:"true"`
+	ACMEHosts        []string `env:"ACME_HOSTS" file:"acme_hosts" sep:"," process:"true"`
+	ACMEEmail        string   `env:"ACME_EMAIL" file:"acme_email" process:"true"`
+	ACMECacheDir     string   `env:"ACME_CACHE_DIR" file:"acme_cache_dir" process:"true"`
+	ACMEDirectory    string   `env:"ACME_DIRECTORY_URL" file:"acme_directory_url" process:"true"`
+	// RedirectPort is "off" to disable the HTTP to HTTPS redirect.
+	RedirectPort string `env:"HTTP_REDIRECT_PORT" file:"redirect_port" process:"true"`
+	HTTP2        bool   `env:"HTTP2_ENABLED" file:"http2" process:"true"`
+	H2C          bool   `env:"H2C_ENABLED" file:"h2c" process:"true"`
+	// TenantsFile lists the tenants of a multi-tenant process; see
+	// LoadTenants.
+	TenantsFile string `env:"TENANTS_FILE" file:"tenants_file" process:"true"`
+}
+
+// TLS reports whether the server terminates TLS itself.
+func (s Server) TLS() bool {
+	return s.TLSCertFile != "" || s.TLSKeyFile != "" || len(s.ACMEHosts) > 0
+}
+
+type Sessions struct {
+	CookieMaxAge time.Duration `env:"COOKIE_MAX_AGE" file:"cookie_max_age"`
+	Timeout      time.Duration `env:"SESSION_TIMEOUT" file:"timeout"`
+	SnapshotFile string        `env:"SESSION_SNAPSHOT_FILE" file:"snapshot_file" state:"true"`
+	// SnapshotInterval also snapshots sessions while the server runs; 0
+	// snapshots only on shutdown.
+	SnapshotInterval time.Duration `env:"SESSION_SNAPSHOT_INTERVAL" file:"snapshot_interval"`
+	// Max caps the sessions holding a game; 0 means no limit.
+	Max         int    `env:"MAX_SESSIONS" file:"max"`
+	LimitPolicy string `env:"SESSION_LIMIT_POLICY" file:"limit_policy"`
+	// MinimalCookies keeps to the one session cookie, which also carries
+	// the CSRF secret, and persists nothing until the player consents.
+	MinimalCookies bool `env:"MINIMAL_COOKIES" file:"minimal_cookies"`
+}
+
+// Store persists sessions in memory:, sqlite:<file> or a postgres:// URL.
+// It is off when URL is empty. The connection settings apply to PostgreSQL.
+type Store struct {
+	URL             string        `env:"STORE_URL" file:"url" secret:"true" state:"true"`
+	MaxConns        int           `env:"STORE_MAX_CONNS" file:"max_conns"`
+	MaxIdleConns    int           `env:"STORE_MAX_IDLE_CONNS" file:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `env:"STORE_CONN_MAX_LIFETIME" file:"conn_max_lifetime"`
+	// FlushInterval is how long writes may wait in the write-behind queue,
+	// and so how much a crash can lose; 0 writes during the request.
+	FlushInterval time.Duration `env:"STORE_FLUSH_INTERVAL" file:"flush_interval"`
+	FlushBatch    int           `env:"STORE_FLUSH_BATCH" file:"flush_batch"`
+}
+
+type Assets struct {
+	// Dir serves templates and static files from disk instead of the copies
+	// embedded in the binary.
+	Dir                    string        `env:"ASSETS_DIR" file:"dir" process:"true"`
+	StaticCacheAge         time.Duration `env:"STATIC_CACHE_AGE" file:"static_cache_age"`
+	TemplateReloadInterval time.Duration `env:"TEMPLATE_RELOAD_INTERVAL" file:"template_reload_interval"`
+	RenderCacheSize        int           `env:"RENDER_CACHE_SIZE" file:"render_cache_size"`
+}
+
+type RateLimit struct {
+	RPS           int  `env:"RATE_LIMIT_RPS" file:"rps"`
+	Burst         int  `env:"RATE_LIMIT_BURST" file:"burst"`
+	IPv6PrefixLen int  `env:"RATE_LIMIT_IPV6_PREFIX" file:"ipv6_prefix"`
+	ValidateAPI   bool `env:"VALIDATE_API_ENABLED" file:"validate_api"`
+	ValidateRPS   int  `env:"VALIDATE_RATE_LIMIT_RPS" file:"validate_rps"`
+	ValidateBurst int  `env:"VALIDATE_RATE_LIMIT_BURST" file:"validate_burst"`
+}
+
+type Bots struct {
+	MinGuessInterval     time.Duration `env:"BOT_MIN_GUESS_INTERVAL" file:"min_guess_interval"`
+	FlagScore            int           `env:"BOT_FLAG_SCORE" file:"flag_score"`
+	ExcludeFromStandings bool          `env:"BOT_EXCLUDE_FROM_STANDINGS" file:"exclude_from_standings"`
+}
+
+type Abuse struct {
+	Threshold int           `env:"ABUSE_STRIKE_THRESHOLD" file:"strike_threshold"`
+	Window    time.Duration `env:"ABUSE_STRIKE_WINDOW" file:"strike_window"`
+	BaseBan   time.Duration `env:"ABUSE_BAN_BASE" file:"ban_base"`
+	MaxBan    time.Duration `env:"ABUSE_BAN_MAX" file:"ban_max"`
+}
+
+type Words struct {
+	Source          string        `env:"WORDS_SOURCE" file:"source"`
+	SourceSHA256    string        `env:"WORDS_SOURCE_SHA256" file:"source_sha256"`
+	AcceptedSource  string        `env:"ACCEPTED_WORDS_SOURCE" file:"accepted_source"`
+	AcceptedSHA256  string        `env:"ACCEPTED_WORDS_SOURCE_SHA256" file:"accepted_source_sha256"`
+	CacheDir        string        `env:"WORDS_CACHE_DIR" file:"cache_dir" state:"true"`
+	RefreshInterval time.Duration `env:"WORDS_REFRESH_INTERVAL" file:"refresh_interval"`
+	BlockedFile     string        `env:"BLOCKED_WORDS_FILE" file:"blocked_file" state:"true"`
+	DefinitionsFile string        `env:"DEFINITIONS_FILE" file:"definitions_file"`
+	PacksFile       string        `env:"PACKS_FILE" file:"packs_file"`
+	S3Endpoint      string        `env:"S3_ENDPOINT" file:"s3_endpoint"`
+	S3Region        string        `env:"S3_REGION" file:"s3_region"`
+	S3AccessKey     string        `env:"AWS_ACCESS_KEY_ID" file:"s3_access_key"`
+	S3SecretKey     string        `env:"AWS_SECRET_ACCESS_KEY" file:"s3_secret_key" secret:"true"`
+	S3SessionToken  string        `env:"AWS_SESSION_TOKEN" file:"s3_session_token" secret:"true"`
+}
+
+type Game struct {
+	BotRaceInterval   time.Duration `env:"BOT_RACE_INTERVAL" file:"bot_race_interval"`
+	SolverHintLimit   int           `env:"SOLVER_HINT_LIMIT" file:"solver_hint_limit"`
+	HintLimit         int           `env:"HINT_LIMIT" file:"hint_limit"`
+	AutoContinueDelay time.Duration `env:"AUTO_CONTINUE_DELAY" file:"auto_continue_delay"`
+	// GuessCooldown is the least time between two guesses of a game; 0
+	// turns it off.
+	GuessCooldown  time.Duration `env:"GUESS_COOLDOWN" file:"guess_cooldown"`
+	TournamentFile string        `env:"TOURNAMENT_FILE" file:"tournament_file" state:"true"`
+	// ScheduleFile keeps the words admins have scheduled for daily puzzles.
+	ScheduleFile string `env:"SCHEDULE_FILE" file:"schedule_file" state:"true"`
+	// DefaultTheme is the theme of players who have not picked one.
+	DefaultTheme string `env:"DEFAULT_THEME" file:"default_theme"`
+}
+
+type Accounts struct {
+	File       string `env:"ACCOUNTS_FILE" file:"file" state:"true"`
+	AdminToken string `env:"ADMIN_TOKEN" file:"admin_token" secret:"true"`
+	// AuditFile is the append-only log of admin actions.
+	AuditFile string `env:"AUDIT_LOG_FILE" file:"audit_file" state:"true"`
+}
+
+type OIDC struct {
+	Issuer       string   `env:"OIDC_ISSUER" file:"issuer"`
+	ProviderName string   `env:"OIDC_PROVIDER_NAME" file:"provider_name"`
+	ClientID     string   `env:"OIDC_CLIENT_ID" file:"client_id"`
+	ClientSecret string   `env:"OIDC_CLIENT_SECRET" file:"client_secret" secret:"true"`
+	RedirectURL  string   `env:"OIDC_REDIRECT_URL" file:"redirect_url"`
+	Scopes       []string `env:"OIDC_SCOPES" file:"scopes" sep:" "`
+}
+
+// Mail sends sign-in links through an SMTP server when Host is set.
+type Mail struct {
+	Host     string `env:"SMTP_HOST" file:"host"`
+	Port     string `env:"SMTP_PORT" file:"port"`
+	Username string `env:"SMTP_USERNAME" file:"username"`
+	Password string `env:"SMTP_PASSWORD" file:"password" secret:"true"`
+	// From is the sender, such as "Vortludo <play@example.org>".
+	From string `env:"SMTP_FROM" file:"from"`
+}
+
+type Security struct {
+	CSP            string `env:"CSP_POLICY" file:"csp" mode:"true"`
+	ScriptNonce    bool   `env:"CSP_SCRIPT_NONCE" file:"script_nonce" mode:"true"`
+	UnsafeEval     bool   `env:"CSP_UNSAFE_EVAL" file:"unsafe_eval" mode:"true"`
+	FrameOptions   string `env:"FRAME_OPTIONS" file:"frame_options" mode:"true"`
+	ReferrerPolicy string `env:"REFERRER_POLICY" file:"referrer_policy" mode:"true"`
+	HSTS           string `env:"HSTS_HEADER" file:"hsts" mode:"true"`
+	// CSRFKey signs anti-forgery tokens. Instances behind one load balancer
+	// need the same key; a random one is used when it is unset.
+	CSRFKey string `env:"CSRF_KEY" file:"csrf_key" secret:"true"`
+}
+
+type EventLog struct {
+	Dir               string `env:"EVENT_LOG_DIR" file:"dir" state:"true"`
+	MaxSize           int64  `env:"EVENT_LOG_MAX_SIZE" file:"max_size"`
+	MaxFiles          int    `env:"EVENT_LOG_MAX_FILES" file:"max_files"`
+	Sync              bool   `env:"EVENT_LOG_SYNC" file:"sync"`
+	BackfillAnalytics bool   `env:"EVENT_LOG_BACKFILL_ANALYTICS" file:"backfill_analytics"`
+}
+
+// Telemetry is off unless Enabled; it then reports to File, Endpoint or both.
+type Telemetry struct {
+	Enabled  bool          `env:"TELEMETRY_ENABLED" file:"enabled"`
+	Interval time.Duration `env:"TELEMETRY_INTERVAL" file:"interval"`
+	File     string        `env:"TELEMETRY_FILE" file:"file" state:"true"`
+	Endpoint string        `env:"TELEMETRY_ENDPOINT" file:"endpoint"`
+}
+
+// Errors forwards recovered panics to a Sentry-compatible DSN, a file of
+// JSON lines, or both. Panics are only logged when neither is set.
+type Errors struct {
+	DSN  string `env:"ERROR_REPORT_DSN" file:"dsn" secret:"true"`
+	File string `env:"ERROR_REPORT_FILE" file:"file" state:"true"`
+}
+
+// Digest writes a daily stats digest to Destination, a directory or
+// s3://bucket/prefix, Offset after midnight UTC. It is off when Destination
+// is empty.
+type Digest struct {
+	Destination string        `env:"DIGEST_DESTINATION" file:"destination" state:"true"`
+	Formats     []string      `env:"DIGEST_FORMATS" file:"formats" sep:","`
+	Offset      time.Duration `env:"DIGEST_OFFSET" file:"offset"`
+}
+
+// FileMode is a permission mode written in octal, e.g. 660.
+type FileMode os.FileMode
+
+func (m FileMode) String() string {
+	return strconv.FormatUint(uint64(m), 8)
+}
+
+// Default returns the configuration used when no variable is set.
+func Default() *Config {
+	return &Config{
+		Server: Server{
+			ShutdownTimeout: 10 * time.Second,
+			RequestTimeout:  constants.RequestTimeoutDefault,
+			GuessTimeout:    constants.GuessTimeoutDefault,
+			AdminTimeout:    constants.AdminTimeoutDefault,
+			MaxRequestBytes: constants.MaxRequestBytesDefault,
+			SocketMode:      0o660,
+			ACMECacheDir:    "acme-cache",
+			RedirectPort:    "80",
+			HTTP2:           true,
+		},
+		Sessions: Sessions{
+			CookieMaxAge:     2 * time.Hour,
+			Timeout:          constants.SessionTimeoutDefault,
+			SnapshotInterval: constants.SessionSnapshotIntervalDefault,
+			LimitPolicy:      constants.SessionLimitEvict,
+		},
+		Store: Store{
+			MaxConns:        constants.StoreMaxConnsDefault,
+			MaxIdleConns:    constants.StoreMaxIdleConnsDefault,
+			ConnMaxLifetime: constants.StoreConnMaxLifetimeDefault,
+			FlushInterval:   constants.StoreFlushIntervalDefault,
+			FlushBatch:      constants.StoreFlushBatchDefault,
+		},
+		Assets: Assets{
+			StaticCacheAge:         5 * time.Minute,
+			TemplateReloadInterval: time.Second,
+			RenderCacheSize:        render.DefaultMaxEntries,
+		},
+		RateLimit: RateLimit{
+			RPS:           5,
+			Burst:         10,
+			IPv6PrefixLen: constants.IPv6PrefixLenDefault,
+			ValidateAPI:   true,
+			ValidateRPS:   constants.ValidateRateLimitRPSDefault,
+			ValidateBurst: constants.ValidateRateLimitBurstDefault,
+		},
+		Abuse: Abuse{
+			Threshold: abuse.DefaultThreshold,
+			Window:    abuse.DefaultWindow,
+			BaseBan:   abuse.DefaultBaseBan,
+			MaxBan:    abuse.DefaultMaxBan,
+		},
+		Bots: Bots{
+			MinGuessInterval: botdetect.DefaultMinInterval,
+			FlagScore:        botdetect.DefaultFlagScore,
+		},
+		Words: Words{
+			Source:          "data/words.json",
+			AcceptedSource:  "data/accepted_words.txt",
+			BlockedFile:     "data/blocked_words.txt",
+			DefinitionsFile: "data/definitions.json",
+			PacksFile:       "data/packs.json",
+		},
+		Game: Game{
+			BotRaceInterval:   constants.BotRaceIntervalDefault,
+			SolverHintLimit:   constants.SolverHintLimitDefault,
+			HintLimit:         constants.HintLimitDefault,
+			AutoContinueDelay: constants.AutoContinueDelayDefault,
+			DefaultTheme:      constants.ThemeAuto,
+		},
+		Mail: Mail{
+			Port: "587",
+		},
+		Security: Security{
+			UnsafeEval: true,
+		},
+		EventLog: EventLog{
+			MaxSize:           eventlog.DefaultMaxSize,
+			MaxFiles:          eventlog.DefaultMaxFiles,
+			BackfillAnalytics: true,
+		},
+		Telemetry: Telemetry{
+			Interval: telemetry.DefaultInterval,
+		},
+		Digest: Digest{
+			Formats: slices.Clone(digest.Formats),
+			Offset:  digest.DefaultOffset,
+		},
+	}
+}
+
+// Load reads the configuration file at path, if path is not empty, and then
+// the environment, whose variables take precedence over the file.
+func Load(path string) (*Config, error) {
+	return LoadFile(path, os.LookupEnv)
+}
+
+// LoadFrom reads the configuration through lookup alone, which has the
+// signature of os.LookupEnv.
+func LoadFrom(lookup func(string) (string, bool)) (*Config, error) {
+	return LoadFile("", lookup)
+}
+
+// LoadFile reads the configuration file at path, if any, and then the
+// variables found through lookup. Unset and empty variables keep the file's
+// value or the default. Every invalid value is reported, not just the first.
+func LoadFile(path string, lookup func(string) (string, bool)) (*Config, error) {
+	cfg := Default()
+	fields := cfg.fields()
+	var file *file
+	var errs []error
+	if path != "" {
+		var err error
+		if file, err = readFile(path); err != nil {
+			return nil, err
+		}
+		errs = append(errs, file.unknownKeys(fields)...)
+	}
+
+	resolve := func(f field, mode string) error {
+		raw, source, ok := f.lookupEnv(lookup, mode)
+		if !ok && file != nil {
+			value, key, found := file.lookup(f, mode)
+			if !found {
+				return nil
+			}
+			var err error
+			if raw, err = fileValue(value, f.sep); err != nil {
+				return fmt.Errorf("%s: %s: %w", path, key, err)
+			}
+			source = fmt.Sprintf("%s: %s=%q", path, key, raw)
+		}
+		if raw == "" {
+			return nil
+		}
+		if err := f.set(raw); err != nil {
+			return fmt.Errorf("%s: %w", source, err)
+		}
+		return nil
+	}
+	// The mode picks which mode-specific values apply, so it comes first.
+	for _, f := range fields[:2] {
+		if err := resolve(f, ""); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	cfg.Production = cfg.GinMode == "release" || cfg.Env == "production"
+	mode := "development"
+	if cfg.Production {
+		mode = "production"
+	}
+	for _, f := range fields[2:] {
+		if err := resolve(f, mode); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	cfg.normalize()
+	errs = append(errs, cfg.validate()...)
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return cfg, nil
+}
+
+// normalize fills in the settings whose default depends on others and
+// trims the ones written in more than one way.
+func (c *Config) normalize() {
+	if c.Server.Port == "" {
+		c.Server.Port = "8080"
+		if c.Server.TLS() {
+			c.Server.Port = "443"
+		}
+	}
+	for i, host := range c.Server.ACMEHosts {
+		c.Server.ACMEHosts[i] = strings.ToLower(host)
+	}
+	c.Server.PublicURL = strings.TrimSuffix(c.Server.PublicURL, "/")
+	c.Server.BasePath = strings.TrimSuffix(c.Server.BasePath, "/")
+}
+
+func (c *Config) validate() []error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+	port := func(key, value string) {
+		n, err := strconv.Atoi(value)
+		check(err == nil && n > 0 && n <= 65535, "%s=%q: want a port number between 1 and 65535", key, value)
+	}
+
+	s := c.Server
+	port("PORT", s.Port)
+	if s.RedirectPort != "off" {
+		port("HTTP_REDIRECT_PORT", s.RedirectPort)
+	}
+	check(s.ShutdownTimeout > 0, "SHUTDOWN_TIMEOUT must be positive")
+	check(s.RequestTimeout >= 0 && s.GuessTimeout >= 0 && s.AdminTimeout >= 0,
+		"REQUEST_TIMEOUT, GUESS_TIMEOUT and ADMIN_TIMEOUT must not be negative")
+	check(s.MaxRequestBytes > 0, "MAX_REQUEST_BYTES must be positive")
+	check(s.SocketMode <= 0o777, "LISTEN_SOCKET_MODE=%s: want permissions no wider than 777", s.SocketMode)
+	check((s.TLSCertFile == "") == (s.TLSKeyFile == ""), "TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	check(s.TLSCertFile == "" || len(s.ACMEHosts) == 0, "set either TLS_CERT_FILE or ACME_HOSTS, not both")
+	check(len(s.ACMEHosts) == 0 || s.ACMECacheDir != "", "ACME_CACHE_DIR must be set when ACME_HOSTS is")
+	if s.PublicURL != "" {
+		u, err := url.Parse(s.PublicURL)
+		check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "",
+			"PUBLIC_URL=%q: want an absolute http(s) URL such as https://play.example.com", s.PublicURL)
+	}
+	check(s.BasePath == "" || strings.HasPrefix(s.BasePath, "/") && !strings.ContainsAny(s.BasePath, ":*?#") && path.Clean(s.BasePath) == s.BasePath,
+		"BASE_PATH=%q: want a clean path such as /games/vortludo", s.BasePath)
+
+	check(c.Sessions.CookieMaxAge > 0, "COOKIE_MAX_AGE must be positive")
+	check(c.Sessions.Timeout > 0, "SESSION_TIMEOUT must be positive")
+	check(c.Sessions.SnapshotInterval >= 0, "SESSION_SNAPSHOT_INTERVAL must not be negative")
+	check(c.Sessions.Max >= 0, "MAX_SESSIONS must not be negative")
+	check(c.Sessions.LimitPolicy == constants.SessionLimitEvict || c.Sessions.LimitPolicy == constants.SessionLimitReject,
+		"SESSION_LIMIT_POLICY=%q: want %s or %s", c.Sessions.LimitPolicy, constants.SessionLimitEvict, constants.SessionLimitReject)
+	if u := strings.ToLower(c.Store.URL); u != "" {
+		check(strings.HasPrefix(u, "memory:") || strings.HasPrefix(u, "sqlite:") ||
+			strings.HasPrefix(u, "postgres://") || strings.HasPrefix(u, "postgresql://"),
+			"STORE_URL: want memory:, sqlite:<file> or a postgres:// URL")
+	}
+	check(c.Store.MaxConns > 0 && c.Store.MaxIdleConns >= 0, "STORE_MAX_CONNS must be positive and STORE_MAX_IDLE_CONNS not negative")
+	check(c.Store.ConnMaxLifetime >= 0, "STORE_CONN_MAX_LIFETIME must not be negative")
+	check(c.Store.FlushInterval >= 0, "STORE_FLUSH_INTERVAL must not be negative")
+	check(c.Store.FlushBatch > 0, "STORE_FLUSH_BATCH must be positive")
+	check(c.Assets.StaticCacheAge >= 0, "STATIC_CACHE_AGE must not be negative")
+	check(c.Assets.TemplateReloadInterval > 0, "TEMPLATE_RELOAD_INTERVAL must be positive")
+	check(c.Assets.RenderCacheSize > 0, "RENDER_CACHE_SIZE must be positive")
+
+	r := c.RateLimit
+	check(r.RPS > 0 && r.Burst > 0, "RATE_LIMIT_RPS and RATE_LIMIT_BURST must be positive")
+	check(r.ValidateRPS > 0 && r.ValidateBurst > 0, "VALIDATE_RATE_LIMIT_RPS and VALIDATE_RATE_LIMIT_BURST must be positive")
+	check(r.IPv6PrefixLen >= 0 && r.IPv6PrefixLen <= 128, "RATE_LIMIT_IPV6_PREFIX=%d: want a prefix length between 0 and 128", r.IPv6PrefixLen)
+
+	a := c.Abuse
+	check(a.Threshold > 0, "ABUSE_STRIKE_THRESHOLD must be positive")
+	check(a.Window > 0 && a.BaseBan > 0 && a.MaxBan > 0, "ABUSE_STRIKE_WINDOW, ABUSE_BAN_BASE and ABUSE_BAN_MAX must be positive")
+	check(c.Bots.MinGuessInterval > 0 && c.Bots.FlagScore > 0, "BOT_MIN_GUESS_INTERVAL and BOT_FLAG_SCORE must be positive")
+
+	check(c.Words.RefreshInterval >= 0, "WORDS_REFRESH_INTERVAL must not be negative")
+	check(c.Game.BotRaceInterval > 0, "BOT_RACE_INTERVAL must be positive")
+	check(c.Game.SolverHintLimit >= 0, "SOLVER_HINT_LIMIT must not be negative")
+	check(c.Game.HintLimit >= 0, "HINT_LIMIT must not be negative")
+	check(c.Game.AutoContinueDelay > 0, "AUTO_CONTINUE_DELAY must be positive")
+	check(c.Game.GuessCooldown >= 0, "GUESS_COOLDOWN must not be negative")
+	check(slices.Contains(constants.SupportedThemes, c.Game.DefaultTheme),
+		"DEFAULT_THEME=%q: want %s", c.Game.DefaultTheme, strings.Join(constants.SupportedThemes, ", "))
+	check(c.OIDC.Issuer == "" || c.OIDC.ClientID != "", "OIDC_CLIENT_ID must be set when OIDC_ISSUER is")
+	if c.Mail.Host != "" {
+		port("SMTP_PORT", c.Mail.Port)
+		check(s.PublicURL != "", "PUBLIC_URL must be set when SMTP_HOST is, for the address of emailed sign-in links")
+		_, err := mail.ParseAddress(c.Mail.From)
+		check(err == nil, "SMTP_FROM=%q: want an address such as Vortludo <play@example.org> when SMTP_HOST is set", c.Mail.From)
+	}
+	check(c.EventLog.MaxSize > 0 && c.EventLog.MaxFiles > 0, "EVENT_LOG_MAX_SIZE and EVENT_LOG_MAX_FILES must be positive")
+
+	t := c.Telemetry
+	check(t.Interval > 0, "TELEMETRY_INTERVAL must be positive")
+	check(!t.Enabled || t.File != "" || t.Endpoint != "", "TELEMETRY_FILE or TELEMETRY_ENDPOINT must be set when TELEMETRY_ENABLED is")
+	if t.Endpoint != "" {
+		u, err := url.Parse(t.Endpoint)
+		check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "",
+			"TELEMETRY_ENDPOINT=%q: want an absolute http(s) URL", t.Endpoint)
+	}
+
+	d := c.Digest
+	check(d.Offset >= 0 && d.Offset < 24*time.Hour, "DIGEST_OFFSET=%s: want a duration under 24h", d.Offset)
+	for _, format := range d.Formats {
+		check(slices.Contains(digest.Formats, format), "DIGEST_FORMATS: %q: want %s", format, strings.Join(digest.Formats, " or "))
+	}
+	check(d.Destination == "" || len(d.Formats) > 0, "DIGEST_FORMATS must not be empty when DIGEST_DESTINATION is set")
+	if dsn := c.Errors.DSN; dsn != "" {
+		_, err := errreport.NewSentry(dsn, nil)
+		check(err == nil, "ERROR_REPORT_DSN: %v", err)
+	}
+	return errs
+}
+
+// Print writes the configuration as the environment variables that produce
+// it, one section at a time. Secrets are redacted.
+func (c *Config) Print(w io.Writer) error {
+	section := ""
+	for _, f := range c.fields() {
+		if f.section != section {
+			heading := "\n# %s\n"
+			if section == "" {
+				heading = "# %s\n"
+			}
+			section = f.section
+			if _, err := fmt.Fprintf(w, heading, section); err != nil {
+				return err
+			}
+		}
+		value := f.String()
+		if f.secret && value != "" {
+			value = "<redacted>"
+		}
+		if _, err := fmt.Fprintf(w, "%s=%s\n", f.key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// field is one tagged field of the configuration.
+type field struct {
+	key     string
+	section string
+	// fileSection and fileKey name the field in a configuration file.
+	fileSection string
+	fileKey     string
+	secret      bool
+	mode        bool
+	process     bool
+	state       bool
+	sep         string
+	value       reflect.Value
+}
+
+// lookupEnv returns the field's variable, preferring its mode-specific
+// variant, and how to name it in an error.
+func (f field) lookupEnv(lookup func(string) (string, bool), mode string) (raw, source string, ok bool) {
+	key := f.key
+	value, ok := "", false
+	if f.mode {
+		key = f.key + "_" + strings.ToUpper(mode)
+		value, ok = lookup(key)
+	}
+	if !ok {
+		key = f.key
+		value, ok = lookup(key)
+	}
+	raw = strings.TrimSpace(value)
+	return raw, fmt.Sprintf("%s=%q", key, raw), raw != ""
+}
+
+// fields lists the tagged fields of c in declaration order.
+func (c *Config) fields() []field {
+	var fields []field
+	var walk func(v reflect.Value, section, fileSection string)
+	walk = func(v reflect.Value, section, fileSection string) {
+		for i := range v.NumField() {
+			sf := v.Type().Field(i)
+			if sf.Type.Kind() == reflect.Struct {
+				walk(v.Field(i), sf.Name, sf.Tag.Get("file"))
+				continue
+			}
+			key := sf.Tag.Get("env")
+			if key == "" {
+				continue
+			}
+			fields = append(fields, field{
+				key:         key,
+				section:     section,
+				fileSection: fileSection,
+				fileKey:     sf.Tag.Get("file"),
+				secret:      sf.Tag.Get("secret") == "true",
+				mode:        sf.Tag.Get("mode") == "true",
+				process:     sf.Tag.Get("process") == "true",
+				state:       sf.Tag.Get("state") == "true",
+				sep:         sf.Tag.Get("sep"),
+				value:       v.Field(i),
+			})
+		}
+	}
+	walk(reflect.ValueOf(c).Elem(), "Mode", "")
+	return fields
+}
+
+// clone returns a copy of c that shares no lists with it.
+func (c *Config) clone() *Config {
+	cfg := *c
+	for _, f := range cfg.fields() {
+		if f.value.Kind() == reflect.Slice && !f.value.IsNil() {
+			f.value.Set(reflect.AppendSlice(reflect.MakeSlice(f.value.Type(), 0, f.value.Len()), f.value))
+		}
+	}
+	return &cfg
+}
+
+func (f field) set(raw string) error {
+	v := f.value
+	switch v.Interface().(type) {
+	case time.Duration:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return errors.New("want a duration such as 30s, 15m or 2h")
+		}
+		v.SetInt(int64(d))
+		return nil
+	case FileMode:
+		m, err := strconv.ParseUint(raw, 8, 32)
+		if err != nil {
+			return errors.New("want octal permissions such as 660")
+		}
+		v.SetUint(m)
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return errors.New("want true or false")
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return errors.New("want a whole number")
+		}
+		v.SetInt(n)
+	case reflect.Slice:
+		var items []string
+		for item := range strings.SplitSeq(raw, cmp.Or(f.sep, ",")) {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported setting type %s", v.Type())
+	}
+	return nil
+}
+
+func (f field) String() string {
+	switch value := f.value.Interface().(type) {
+	case []string:
+		return strings.Join(value, cmp.Or(f.sep, ","))
+	case fmt.Stringer:
+		return value.String()
+	}
+	return fmt.Sprint(f.value.Interface())
+}
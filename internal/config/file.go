@@ -0,0 +1,135 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/pelletier/go-toml/v2"
+)
+
+// modes are the tables of a section that hold mode-specific values.
+var modes = []string{"production", "development"}
+
+// file is a parsed configuration file: top-level settings and sections of
+// settings, as in
+//
+//	[rate_limit]
+//	rps = 10
+//
+//	[security.production]
+//	script_nonce = true
+type file struct {
+	path string
+	doc  map[string]any
+}
+
+// readFile parses the YAML (.yaml, .yml) or TOML (.toml) file at path.
+func readFile(path string) (*file, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	doc := make(map[string]any)
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &doc)
+	case ".toml":
+		err = toml.Unmarshal(data, &doc)
+	default:
+		return nil, fmt.Errorf("%s: unknown configuration format %q, want .yaml or .toml", path, ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &file{path: path, doc: doc}, nil
+}
+
+// lookup returns the file's value for f, preferring the one in the mode's
+// table, and its dotted key.
+func (fl *file) lookup(f field, mode string) (any, string, bool) {
+	if f.fileSection == "" {
+		value, ok := fl.doc[f.fileKey]
+		return value, f.fileKey, ok
+	}
+	section, _ := fl.doc[f.fileSection].(map[string]any)
+	if f.mode {
+		if table, ok := section[mode].(map[string]any); ok {
+			if value, ok := table[f.fileKey]; ok {
+				return value, f.fileSection + "." + mode + "." + f.fileKey, true
+			}
+		}
+	}
+	value, ok := section[f.fileKey]
+	return value, f.fileSection + "." + f.fileKey, ok
+}
+
+// unknownKeys reports the file's keys that name no setting, which are most
+// likely typos.
+func (fl *file) unknownKeys(fields []field) []error {
+	known := make(map[string]bool)
+	sections := make(map[string]bool)
+	for _, f := range fields {
+		if f.fileSection == "" {
+			known[f.fileKey] = true
+			continue
+		}
+		sections[f.fileSection] = true
+		known[f.fileSection+"."+f.fileKey] = true
+		if f.mode {
+			for _, mode := range modes {
+				sections[f.fileSection+"."+mode] = true
+				known[f.fileSection+"."+mode+"."+f.fileKey] = true
+			}
+		}
+	}
+
+	var errs []error
+	var walk func(prefix string, doc map[string]any)
+	walk = func(prefix string, doc map[string]any) {
+		for key, value := range doc {
+			name := prefix + key
+			if table, ok := value.(map[string]any); ok && sections[name] {
+				walk(name+".", table)
+			} else if !known[name] {
+				errs = append(errs, fmt.Errorf("%s: unknown setting %q", fl.path, name))
+			}
+		}
+	}
+	walk("", fl.doc)
+	return errs
+}
+
+// fileValue renders a value decoded from a file as the environment variable
+// would spell it, so both are parsed alike.
+func fileValue(value any, sep string) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return strings.TrimSpace(v), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			s, err := fileValue(item, sep)
+			if err != nil {
+				return "", err
+			}
+			items[i] = s
+		}
+		if sep == "" {
+			return "", fmt.Errorf("want a single value, not a list")
+		}
+		return strings.Join(items, sep), nil
+	case map[string]any:
+		return "", fmt.Errorf("want a value, not a table")
+	}
+	return fmt.Sprint(value), nil
+}
@@ -0,0 +1,177 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+)
+
+// Tenant is one of the instances a multi-tenant process serves: at Host, at
+// its configuration's BASE_PATH, or both.
+type Tenant struct {
+	Name string
+	// Host is the host name the tenant answers on; empty answers on any.
+	Host   string
+	Config *Config
+}
+
+// LoadTenants reads the tenants file at path, which is written like the
+// configuration file: a tenants list whose entries have a name, a host, a
+// path or both, and sections of settings that replace those of base for the
+// tenant, as in
+//
+//	tenants:
+//	  - name: eo
+//	    host: eo.example.com
+//	    words:
+//	      source: data/eo/words.json
+//	  - name: club
+//	    path: /club
+//	    game:
+//	      default_theme: dark
+//
+// Each tenant gets its own instance, so its word lists, theme, sessions and
+// stores are its own. Process settings cannot be set for a tenant, and
+// tenants may neither share state settings nor serve overlapping addresses.
+// Every error is reported, not just the first.
+func LoadTenants(path string, base *Config) ([]Tenant, error) {
+	fl, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var errs []error
+	for key := range fl.doc {
+		if key != "tenants" {
+			errs = append(errs, fmt.Errorf("%s: unknown setting %q", path, key))
+		}
+	}
+	entries, _ := fl.doc["tenants"].([]any)
+	if len(entries) == 0 {
+		errs = append(errs, fmt.Errorf("%s: want a list of tenants", path))
+	}
+
+	mode := "development"
+	if base.Production {
+		mode = "production"
+	}
+	var tenants []Tenant
+	for i, entry := range entries {
+		doc, ok := entry.(map[string]any)
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: tenant %d: want a table of settings", path, i+1))
+			continue
+		}
+		tenant, tenantErrs := loadTenant(path, i, doc, base, mode)
+		errs = append(errs, tenantErrs...)
+		tenants = append(tenants, tenant)
+	}
+	errs = append(errs, checkTenants(path, tenants)...)
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return tenants, nil
+}
+
+// loadTenant reads the i-th entry of the tenants file over a copy of base.
+func loadTenant(path string, i int, doc map[string]any, base *Config, mode string) (Tenant, []error) {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+	str := func(key string) string {
+		value, err := fileValue(doc[key], "")
+		check(err == nil, "%s: tenant %d: %s: %v", path, i+1, key, err)
+		return value
+	}
+	tenant := Tenant{Name: str("name"), Host: strings.ToLower(str("host"))}
+	basePath := str("path")
+	check(tenant.Name != "", "%s: tenant %d: want a name", path, i+1)
+	check(tenant.Host != "" || basePath != "", "%s: tenant %d: want a host, a path or both", path, i+1)
+	check(!strings.ContainsAny(tenant.Host, ":/"), "%s: tenant %d: host=%q: want a host name such as eo.example.com", path, i+1, tenant.Host)
+
+	cfg := base.clone()
+	cfg.Server.TenantsFile = ""
+	overrides := maps.Clone(doc)
+	delete(overrides, "name")
+	delete(overrides, "host")
+	delete(overrides, "path")
+	fl := &file{path: fmt.Sprintf("%s: tenant %s", path, tenant.Name), doc: overrides}
+	fields := cfg.fields()
+	errs = append(errs, fl.unknownKeys(fields)...)
+	for _, f := range fields {
+		value, key, found := fl.lookup(f, mode)
+		if !found {
+			continue
+		}
+		if f.process {
+			errs = append(errs, fmt.Errorf("%s: %s applies to the whole process and cannot be set for a tenant", fl.path, key))
+			continue
+		}
+		raw, err := fileValue(value, f.sep)
+		if err == nil && raw != "" {
+			err = f.set(raw)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %s=%q: %w", fl.path, key, raw, err))
+		}
+	}
+	if basePath != "" {
+		cfg.Server.BasePath = basePath
+	}
+	cfg.normalize()
+	for _, err := range cfg.validate() {
+		errs = append(errs, fmt.Errorf("%s: %w", fl.path, err))
+	}
+	tenant.Config = cfg
+	return tenant, errs
+}
+
+// checkTenants reports tenants that share a name, an address or state. Two
+// tenants whose paths nest on a host they both answer on would be sent each
+// other's cookies.
+func checkTenants(path string, tenants []Tenant) []error {
+	var errs []error
+	fields := make([][]field, len(tenants))
+	for i, t := range tenants {
+		if t.Config != nil {
+			fields[i] = t.Config.fields()
+		}
+	}
+	for i, a := range tenants {
+		for j := i + 1; j < len(tenants); j++ {
+			b := tenants[j]
+			if a.Name == b.Name {
+				errs = append(errs, fmt.Errorf("%s: two tenants are named %s", path, a.Name))
+				continue
+			}
+			if a.Config == nil || b.Config == nil {
+				continue
+			}
+			sameHost := a.Host == "" || b.Host == "" || a.Host == b.Host
+			if sameHost && pathsNest(a.Config.Server.BasePath, b.Config.Server.BasePath) {
+				errs = append(errs, fmt.Errorf("%s: tenants %s and %s serve overlapping addresses; give them different hosts or paths that do not nest",
+					path, a.Name, b.Name))
+			}
+			for k, f := range fields[i] {
+				if !f.state || f.String() == "" || f.String() != fields[j][k].String() {
+					continue
+				}
+				value := fmt.Sprintf("=%q", f.String())
+				if f.secret {
+					value = ""
+				}
+				errs = append(errs, fmt.Errorf("%s: tenants %s and %s both set %s%s; each needs its own", path, a.Name, b.Name, f.key, value))
+			}
+		}
+	}
+	return errs
+}
+
+// pathsNest reports whether one base path is the other or under it. The
+// root, "", is over every path.
+func pathsNest(a, b string) bool {
+	return a == b || strings.HasPrefix(b, a+"/") || strings.HasPrefix(a, b+"/")
+}
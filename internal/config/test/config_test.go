@@ -0,0 +1,391 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	config "github.com/CodeAndHammer/vortludo/internal/config"
+)
+
+func env(vars map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := vars[key]
+		return value, ok
+	}
+}
+
+func TestLoadDefaults(t *testing.T) {
+	cfg, err := config.LoadFrom(env(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Production || cfg.Server.Port != "8080" || cfg.Sessions.CookieMaxAge != 2*time.Hour {
+		t.Errorf("Unexpected defaults: production %v, port %s, cookie max age %v", cfg.Production, cfg.Server.Port, cfg.Sessions.CookieMaxAge)
+	}
+	if !cfg.RateLimit.ValidateAPI || !cfg.Security.UnsafeEval || !cfg.EventLog.BackfillAnalytics {
+		t.Error("Settings on by default should be on")
+	}
+}
+
+func TestLoadValues(t *testing.T) {
+	cfg, err := config.LoadFrom(env(map[string]string{
+		"GIN_MODE":                    "release",
+		"COOKIE_MAX_AGE":              "45m",
+		"RATE_LIMIT_RPS":              " 42 ",
+		"EVENT_LOG_SYNC":              "true",
+		"EVENT_LOG_MAX_SIZE":          "1048576",
+		"LISTEN_SOCKET_MODE":          "600",
+		"ACME_HOSTS":                  "Play.Example.com, ,www.example.com",
+		"OIDC_ISSUER":                 "https://id.example.com",
+		"OIDC_CLIENT_ID":              "vortludo",
+		"OIDC_SCOPES":                 "openid  email",
+		"PUBLIC_URL":                  "https://play.example.com/",
+		"BASE_PATH":                   "/games/vortludo/",
+		"CSP_SCRIPT_NONCE":            "false",
+		"CSP_SCRIPT_NONCE_PRODUCTION": "true",
+		"FRAME_OPTIONS_DEVELOPMENT":   "SAMEORIGIN",
+		"SESSION_TIMEOUT":             "",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.Production || cfg.Sessions.CookieMaxAge != 45*time.Minute || cfg.RateLimit.RPS != 42 {
+		t.Errorf("Values not read: %+v", cfg)
+	}
+	if !cfg.EventLog.Sync || cfg.EventLog.MaxSize != 1<<20 || cfg.Server.SocketMode != 0o600 {
+		t.Errorf("Values not read: %+v", cfg.EventLog)
+	}
+	if strings.Join(cfg.Server.ACMEHosts, ",") != "play.example.com,www.example.com" || strings.Join(cfg.OIDC.Scopes, ",") != "openid,email" {
+		t.Errorf("Lists not split: %q, %q", cfg.Server.ACMEHosts, cfg.OIDC.Scopes)
+	}
+	if cfg.Server.Port != "443" || cfg.Server.PublicURL != "https://play.example.com" || cfg.Server.BasePath != "/games/vortludo" {
+		t.Errorf("Expected port 443 with TLS and a trimmed public URL and base path, got %s, %s, %s",
+			cfg.Server.Port, cfg.Server.PublicURL, cfg.Server.BasePath)
+	}
+	if !cfg.Security.ScriptNonce || cfg.Security.FrameOptions != "" {
+		t.Errorf("Mode overrides not applied: %+v", cfg.Security)
+	}
+	if cfg.Sessions.Timeout != config.Default().Sessions.Timeout {
+		t.Errorf("An empty variable should keep the default, got %v", cfg.Sessions.Timeout)
+	}
+}
+
+func TestLoadReportsEveryError(t *testing.T) {
+	_, err := config.LoadFrom(env(map[string]string{
+		"COOKIE_MAX_AGE":       "2 hours",
+		"RATE_LIMIT_BURST":     "lots",
+		"EVENT_LOG_SYNC":       "yes please",
+		"LISTEN_SOCKET_MODE":   "rw",
+		"PORT":                 "99999",
+		"TLS_CERT_FILE":        "cert.pem",
+		"SESSION_TIMEOUT":      "-1m",
+		"PUBLIC_URL":           "play.example.com",
+		"BASE_PATH":            "games/../vortludo",
+		"TELEMETRY_ENABLED":    "true",
+		"SESSION_LIMIT_POLICY": "drop",
+		"SMTP_HOST":            "mail.example.com",
+		"SMTP_FROM":            "nobody",
+	}))
+	if err == nil {
+		t.Fatal("Expected invalid values to be reported")
+	}
+	for _, want := range []string{
+		`COOKIE_MAX_AGE="2 hours": want a duration`,
+		`RATE_LIMIT_BURST="lots": want a whole number`,
+		`EVENT_LOG_SYNC="yes please": want true or false`,
+		`LISTEN_SOCKET_MODE="rw": want octal permissions`,
+		`PORT="99999"`,
+		"TLS_CERT_FILE and TLS_KEY_FILE",
+		"SESSION_TIMEOUT must be positive",
+		`PUBLIC_URL="play.example.com"`,
+		`BASE_PATH="games/../vortludo"`,
+		"TELEMETRY_FILE or TELEMETRY_ENDPOINT must be set",
+		`SESSION_LIMIT_POLICY="drop": want evict or reject`,
+		`SMTP_FROM="nobody"`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected %q in the errors, got:\n%v", want, err)
+		}
+	}
+}
+
+func TestSMTPNeedsPublicURL(t *testing.T) {
+	vars := map[string]string{"SMTP_HOST": "mail.example.com", "SMTP_FROM": "play@example.com"}
+	if _, err := config.LoadFrom(env(vars)); err == nil || !strings.Contains(err.Error(), "PUBLIC_URL must be set when SMTP_HOST is") {
+		t.Errorf("Expected SMTP_HOST without PUBLIC_URL refused, got %v", err)
+	}
+	vars["PUBLIC_URL"] = "https://play.example.com"
+	if _, err := config.LoadFrom(env(vars)); err != nil {
+		t.Errorf("Expected SMTP with PUBLIC_URL accepted, got %v", err)
+	}
+}
+
+func TestPrintRedactsSecrets(t *testing.T) {
+	cfg, err := config.LoadFrom(env(map[string]string{
+		"ADMIN_TOKEN":    "hunter2",
+		"OIDC_ISSUER":    "https://id.example.com",
+		"OIDC_CLIENT_ID": "vortludo",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := cfg.Print(&out); err != nil {
+		t.Fatal(err)
+	}
+	printed := out.String()
+	if strings.Contains(printed, "hunter2") || !strings.Contains(printed, "ADMIN_TOKEN=<redacted>\n") {
+		t.Errorf("Secrets should be redacted:\n%s", printed)
+	}
+	for _, want := range []string{"# Server\n", "PORT=8080\n", "COOKIE_MAX_AGE=2h0m0s\n", "LISTEN_SOCKET_MODE=660\n", "OIDC_CLIENT_SECRET=\n", "OIDC_CLIENT_ID=vortludo\n"} {
+		if !strings.Contains(printed, want) {
+			t.Errorf("Expected %q in:\n%s", want, printed)
+		}
+	}
+
+	// What Print writes loads back into the same configuration.
+	vars := make(map[string]string)
+	for line := range strings.Lines(printed) {
+		if key, value, ok := strings.Cut(strings.TrimSpace(line), "="); ok && value != "<redacted>" {
+			vars[key] = value
+		}
+	}
+	reloaded, err := config.LoadFrom(env(vars))
+	if err != nil {
+		t.Fatalf("Printed configuration does not load: %v", err)
+	}
+	var again bytes.Buffer
+	reloaded.Print(&again)
+	if strings.ReplaceAll(again.String(), "ADMIN_TOKEN=\n", "ADMIN_TOKEN=<redacted>\n") != printed {
+		t.Errorf("Printed configuration does not round-trip:\n%s\nvs\n%s", again.String(), printed)
+	}
+}
+
+func writeConfig(t *testing.T, name, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadFile(t *testing.T) {
+	files := map[string]string{
+		"vortludo.yaml": `
+gin_mode: release
+rate_limit:
+  rps: 10
+  burst: 20
+sessions:
+  cookie_max_age: 45m
+words:
+  source: https://words.example.com/words.json
+server:
+  acme_hosts: [play.example.com, www.example.com]
+  redirect_port: off
+security:
+  script_nonce: false
+  frame_options: SAMEORIGIN
+  production:
+    script_nonce: true
+  development:
+    frame_options: DENY
+`,
+		"vortludo.toml": `
+gin_mode = "release"
+
+[rate_limit]
+rps = 10
+burst = 20
+
+[sessions]
+cookie_max_age = "45m"
+
+[words]
+source = "https://words.example.com/words.json"
+
+[server]
+acme_hosts = ["play.example.com", "www.example.com"]
+redirect_port = "off"
+
+[security]
+script_nonce = false
+frame_options = "SAMEORIGIN"
+
+[security.production]
+script_nonce = true
+
+[security.development]
+frame_options = "DENY"
+`,
+	}
+	for name, data := range files {
+		cfg, err := config.LoadFile(writeConfig(t, name, data), env(map[string]string{"RATE_LIMIT_BURST": "30"}))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !cfg.Production || cfg.RateLimit.RPS != 10 || cfg.Sessions.CookieMaxAge != 45*time.Minute {
+			t.Errorf("%s: values not read: %+v", name, cfg)
+		}
+		if cfg.RateLimit.Burst != 30 {
+			t.Errorf("%s: the environment should override the file, got burst %d", name, cfg.RateLimit.Burst)
+		}
+		if cfg.Words.Source != "https://words.example.com/words.json" || cfg.Server.RedirectPort != "off" {
+			t.Errorf("%s: values not read: %+v, %+v", name, cfg.Words, cfg.Server)
+		}
+		if strings.Join(cfg.Server.ACMEHosts, ",") != "play.example.com,www.example.com" || cfg.Server.Port != "443" {
+			t.Errorf("%s: lists not read: %q", name, cfg.Server.ACMEHosts)
+		}
+		if !cfg.Security.ScriptNonce || cfg.Security.FrameOptions != "SAMEORIGIN" {
+			t.Errorf("%s: mode tables not applied: %+v", name, cfg.Security)
+		}
+	}
+}
+
+func TestLoadFileErrors(t *testing.T) {
+	path := writeConfig(t, "vortludo.yaml", `
+rate_limit:
+  rsp: 10
+  burst: lots
+sessions: 5
+security:
+  production:
+    rps: 3
+`)
+	_, err := config.LoadFile(path, env(nil))
+	if err == nil {
+		t.Fatal("Expected the file's problems to be reported")
+	}
+	for _, want := range []string{
+		`unknown setting "rate_limit.rsp"`,
+		`rate_limit.burst="lots": want a whole number`,
+		`unknown setting "sessions"`,
+		`unknown setting "security.production.rps"`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected %q in the errors, got:\n%v", want, err)
+		}
+	}
+
+	if _, err := config.LoadFile(writeConfig(t, "vortludo.ini", "rps=1"), env(nil)); err == nil {
+		t.Error("Unknown file formats should be rejected")
+	}
+	if _, err := config.LoadFile(writeConfig(t, "vortludo.toml", "[rate_limit\n"), env(nil)); err == nil {
+		t.Error("Malformed files should be rejected")
+	}
+	if _, err := config.LoadFile(filepath.Join(t.TempDir(), "missing.yaml"), env(nil)); err == nil {
+		t.Error("A missing file should be reported")
+	}
+}
+
+func TestLoadTenants(t *testing.T) {
+	path := writeConfig(t, "tenants.yaml", `
+tenants:
+  - name: eo
+    host: EO.example.com
+    words:
+      source: data/eo/words.json
+      blocked_file: data/eo/blocked_words.txt
+  - name: club
+    host: play.example.com
+    path: /club/
+    words:
+      blocked_file: data/club/blocked_words.txt
+    game:
+      default_theme: dark
+    security:
+      production:
+        frame_options: DENY
+`)
+	base := config.Default()
+	base.Production = true
+	tenants, err := config.LoadTenants(path, base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tenants) != 2 {
+		t.Fatalf("Expected 2 tenants, got %d", len(tenants))
+	}
+	eo, club := tenants[0], tenants[1]
+	if eo.Name != "eo" || eo.Host != "eo.example.com" || eo.Config.Server.BasePath != "" || eo.Config.Words.Source != "data/eo/words.json" {
+		t.Errorf("Tenant eo not read: %+v, %+v", eo, eo.Config.Words)
+	}
+	if club.Host != "play.example.com" || club.Config.Server.BasePath != "/club" || club.Config.Game.DefaultTheme != "dark" || club.Config.Security.FrameOptions != "DENY" {
+		t.Errorf("Tenant club not read: %+v, %+v", club, club.Config.Server)
+	}
+	if club.Config.Words.Source != base.Words.Source || base.Words.BlockedFile != config.Default().Words.BlockedFile {
+		t.Error("Tenants should inherit the base configuration without changing it")
+	}
+}
+
+func TestLoadTenantsCopyLists(t *testing.T) {
+	path := writeConfig(t, "tenants.yaml", `
+tenants:
+  - name: eo
+    path: /eo
+  - name: club
+    path: /club
+`)
+	base := config.Default()
+	base.Server.ACMEHosts = []string{"Play.Example.com"}
+	base.Digest.Formats = []string{"json"}
+	base.Words.BlockedFile = ""
+	tenants, err := config.LoadTenants(path, base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if base.Server.ACMEHosts[0] != "Play.Example.com" {
+		t.Errorf("Loading tenants changed the base ACME hosts to %v", base.Server.ACMEHosts)
+	}
+	eo, club := tenants[0].Config, tenants[1].Config
+	eo.Server.ACMEHosts[0] = "eo.example.com"
+	eo.Digest.Formats[0] = "csv"
+	if club.Server.ACMEHosts[0] == "eo.example.com" || club.Digest.Formats[0] == "csv" || base.Digest.Formats[0] == "csv" {
+		t.Error("Tenants should not share lists with each other or the base configuration")
+	}
+}
+
+func TestLoadTenantsErrors(t *testing.T) {
+	path := writeConfig(t, "tenants.yaml", `
+listen: true
+tenants:
+  - name: eo
+    host: eo.example.com
+    server:
+      port: 9090
+    game:
+      default_theme: sepia
+  - name: club
+    path: /club
+    sessions:
+      snapshot_file: data/sessions.json
+  - name: inner
+    path: /club/inner
+    sessions:
+      snapshot_file: data/sessions.json
+  - host: nameless.example.com
+`)
+	base := config.Default()
+	base.Words.BlockedFile = ""
+	_, err := config.LoadTenants(path, base)
+	if err == nil {
+		t.Fatal("Expected the tenants' problems to be reported")
+	}
+	for _, want := range []string{
+		`unknown setting "listen"`,
+		"server.port applies to the whole process",
+		`DEFAULT_THEME="sepia"`,
+		"tenants club and inner serve overlapping addresses",
+		`tenants club and inner both set SESSION_SNAPSHOT_FILE="data/sessions.json"`,
+		"tenant 4: want a name",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected %q in the errors, got:\n%v", want, err)
+		}
+	}
+}
@@ -7,32 +7,428 @@ const (
 	WordLength = 5
 )
 
+// Multi-board games get one extra row per additional board: 7 rows for two
+// boards, 9 rows for four.
+var AllowedBoardCounts = []int{2, 4}
+
+const (
+	ModeClassic = "classic"
+	ModeDordle  = "dordle"
+	ModeQuordle = "quordle"
+	ModeRace    = "race"
+	// ModeAdversarial games choose their word as late as possible, keeping
+	// the most words in play after each guess.
+	ModeAdversarial = "adversarial"
+
+	ModeTournament = "tournament"
+)
+
+const (
+	LanguageEnglish   = "en"
+	LanguageEsperanto = "eo"
+
+	KeyboardLayoutQwerty    = "qwerty"
+	KeyboardLayoutAzerty    = "azerty"
+	KeyboardLayoutEsperanto = "esperanto"
+)
+
+var SupportedLanguages = []string{LanguageEnglish, LanguageEsperanto}
+
+// KeyEnter and KeyBackspace are the keys besides letters the keyboard
+// sends, in the browser and to the server-side draft row alike.
+const (
+	KeyEnter     = "ENTER"
+	KeyBackspace = "BACKSPACE"
+)
+
+// ThemeAuto follows the browser's colour scheme; the others force one.
+const (
+	ThemeAuto  = "auto"
+	ThemeLight = "light"
+	ThemeDark  = "dark"
+)
+
+var SupportedThemes = []string{ThemeAuto, ThemeLight, ThemeDark}
+
+const BotRaceIntervalDefault = 20 * time.Second
+
+const (
+	SolverHintLimitDefault = 3
+	SolverSuggestionCount  = 5
+)
+
+// HintLimitDefault is how many of a word's staged hints a game may reveal.
+const HintLimitDefault = 2
+
+// PoolClassic is the word pool of words that name no pool, and of games
+// that ask for none.
+const PoolClassic = "classic"
+
+// A word is hard once at least HardWordMinPlays plays of it were solved at
+// most HardWordSolveRate of the time. Until then its letters decide; see
+// game.IsHardWord.
+const (
+	HardWordMinPlays  = 10
+	HardWordSolveRate = 0.5
+)
+
+// AutoContinueDelayDefault is how long a finished game stays on screen
+// before the next word starts, for players who turned on auto-continue.
+const AutoContinueDelayDefault = 5 * time.Second
+
+// RevealStaggerMs is the delay between successive tile flips of a new row.
+const RevealStaggerMs = 100
+
 const (
 	GuessStatusCorrect = "correct"
 	GuessStatusPresent = "present"
 	GuessStatusAbsent  = "absent"
 )
 
+// HeatLevels is how many shades the letter heatmap uses.
+const HeatLevels = 5
+
+// IPv6PrefixLenDefault is the prefix rate limiting groups IPv6 clients by.
+const IPv6PrefixLenDefault = 64
+
+// With minimal cookies, the session cookie holds a signed random ID that
+// doubles as the CSRF secret, and no other cookie is set until the player
+// consents.
 const (
 	SessionCookieName     = "session_id"
 	SessionTimeoutDefault = 30 * time.Minute
+	// SessionSnapshotIntervalDefault is how often sessions are snapshotted
+	// while the server runs, when a snapshot file is set.
+	SessionSnapshotIntervalDefault = 5 * time.Minute
+)
+
+// The connection pool of a PostgreSQL store: at most StoreMaxConnsDefault
+// connections, of which StoreMaxIdleConnsDefault are kept open while idle,
+// each replaced after StoreConnMaxLifetimeDefault.
+const (
+	StoreMaxConnsDefault        = 10
+	StoreMaxIdleConnsDefault    = 5
+	StoreConnMaxLifetimeDefault = 30 * time.Minute
+)
+
+// Writes to a store are queued and flushed every StoreFlushIntervalDefault,
+// at most StoreFlushBatchDefault sessions per transaction.
+const (
+	StoreFlushIntervalDefault = time.Second
+	StoreFlushBatchDefault    = 256
+)
+
+// Expired sessions are collected every SessionGCInterval plus up to
+// SessionGCJitter, so instances started together do not scan at once. Each
+// shard is swept SessionGCBatch sessions at a time, pausing SessionGCPause
+// between batches so requests waiting on its lock get through.
+const (
+	SessionGCInterval = 10 * time.Minute
+	SessionGCJitter   = time.Minute
+	SessionGCBatch    = 256
+	SessionGCPause    = time.Millisecond
+)
+
+// What happens to a new session once MAX_SESSIONS sessions hold a game: the
+// least recently used ones are evicted to make room, or it is turned away
+// with a "server full" page until one expires.
+const (
+	SessionLimitEvict  = "evict"
+	SessionLimitReject = "reject"
+	// ServerFullRetryAfter is the Retry-After sent with a "server full" page.
+	ServerFullRetryAfter = time.Minute
+)
+
+// The player cookie identifies a tournament player for the whole week, well
+// beyond the lifetime of a session.
+const (
+	PlayerCookieName   = "player_id"
+	PlayerCookieMaxAge = 8 * 24 * time.Hour
+)
+
+const AccountCookieName = "account_token"
+
+// The CSRF cookie holds a browser's anti-forgery secret, which script cannot
+// read. Pages and responses carry tokens derived from it instead: in the
+// header, in a csrf-token meta tag and in forms' CSRFFormField.
+const (
+	CSRFCookieName = "csrf_secret"
+	CSRFHeader     = "X-CSRF-Token"
+	CSRFFormField  = "csrf_token"
+)
+
+// OIDCLoginCookieName holds the state, nonce and PKCE verifier of a sign-in in
+// progress at the identity provider.
+const OIDCLoginCookieName = "oidc_login"
+
+// RateLimitNoticeID is the element on the page that HTMX requests rejected by
+// the rate limiter, or failed by a server error, render their notice into.
+const RateLimitNoticeID = "rate-limit-notice"
+
+// Sign-in attempts are limited per client to slow down password guessing and
+// sign-in link spam.
+const (
+	AccountRateLimitRPS   = 1
+	AccountRateLimitBurst = 5
+)
+
+const (
+	RouteHome         = "/"
+	RouteNewGame      = "/new-game"
+	RouteRetryWord    = "/retry-word"
+	RouteHint         = "/hint"
+	RouteGuess        = "/guess"
+	RouteKey          = "/key"
+	RouteGameState    = "/game-state"
+	RouteRaceState    = "/race-state"
+	RouteSettings     = "/settings"
+	RouteStats        = "/stats"
+	RoutePacks        = "/packs"
+	RouteAchievements = "/achievements"
+	RouteStatic       = "/static"
+
+	RouteSettingsTheme = "/settings/theme"
+	RouteShareImage    = "/share/image"
+	RoutePreviewImage  = "/preview/image"
+
+	RouteTournament = "/tournament"
+
+	RouteAccount          = "/account"
+	RouteAccountRegister  = "/account/register"
+	RouteAccountLogin     = "/account/login"
+	RouteAccountLogout    = "/account/logout"
+	RouteAccountMagicLink = "/account/magic-link"
+	RouteAccountMagic     = "/account/magic"
+
+	RouteAccountOIDC         = "/account/oidc"
+	RouteAccountOIDCLogin    = "/account/oidc/login"
+	RouteAccountOIDCCallback = "/account/oidc/callback"
+	RouteAccountOIDCComplete = "/account/oidc/complete"
+	RouteAccountOIDCLogout   = "/account/oidc/logout"
+
+	RoutePrivacyExport = "/privacy/export"
+	RoutePrivacyDelete = "/privacy/delete"
+	RouteConsent       = "/consent"
+
+	RouteAnnouncement       = "/announcement"
+	RouteAnnouncementEvents = "/announcement/events"
+
+	RouteSpectate       = "/spectate"
+	RouteSpectateRevoke = "/spectate/revoke"
+	RouteWatch          = "/watch"
+
+	RouteLivez  = "/livez"
+	RouteReadyz = "/readyz"
+)
+
+// A guess repeated with the same idempotency key within IdempotencyWindow is
+// answered with the original response. Longer keys are ignored.
+const (
+	IdempotencyKeyHeader = "Idempotency-Key"
+	IdempotencyKeyField  = "idempotency_key"
+	IdempotencyWindow    = 30 * time.Second
+	IdempotencyKeyMaxLen = 128
+)
+
+// Spectators poll a game's state every SpectatePollInterval; each event
+// stream is closed after SpectateStreamMax and reopened by the browser.
+// Finished games stay watchable for SpectateEndGrace.
+const (
+	SpectatePollInterval = time.Second
+	SpectateStreamMax    = 5 * time.Minute
+	SpectateEndGrace     = time.Minute
+)
+
+// Pages check for a changed announcement every AnnouncementPollInterval;
+// each event stream is closed after AnnouncementStreamMax and reopened by the
+// browser.
+const (
+	AnnouncementPollInterval = 5 * time.Second
+	AnnouncementStreamMax    = 10 * time.Minute
+)
+
+// A finished game's board is drawn as a PNG at RouteShareImage, without its
+// letters when ShareSpoilerFreeParam is set. The last ShareImageCacheSize
+// images drawn are kept.
+const (
+	ShareSpoilerFreeParam = "spoiler_free"
+	ShareImageCacheSize   = 256
+)
+
+// Shared links unfurl with a card whose image is drawn at RoutePreviewImage:
+// the spectated board when PreviewWatchParam names a spectate token, and
+// the daily puzzle otherwise. Crawlers may keep it for PreviewImageMaxAge.
+const (
+	PreviewWatchParam  = "watch"
+	PreviewImageMaxAge = 5 * time.Minute
+)
+
+// Browsers that post a move without JavaScript are redirected back to the
+// board when it is refused, with the error's code in ErrorParam so that the
+// page can say what went wrong.
+const ErrorParam = "error"
+
+// CompressMinSize is the smallest response body worth compressing.
+const CompressMinSize = 1024
+
+// GameIDLength is the length of the public game IDs.
+const GameIDLength = 10
+
+// ReadinessTimeout bounds how long /readyz waits on the session store before
+// reporting it unreachable.
+const ReadinessTimeout = 2 * time.Second
+
+const (
+	RouteAPIPrefix   = "/api/"
+	RouteAPIHintNext = "/api/v1/hint/next"
+	RouteAPIValidate = "/api/v1/validate"
 )
 
+// The validate endpoint is meant for an occasional check before a guess is
+// submitted, so it is limited far more tightly than the rest of the site.
 const (
-	RouteHome      = "/"
-	RouteNewGame   = "/new-game"
-	RouteRetryWord = "/retry-word"
-	RouteGuess     = "/guess"
-	RouteGameState = "/game-state"
+	ValidateRateLimitRPSDefault   = 1
+	ValidateRateLimitBurstDefault = 5
+)
+
+const (
+	RouteAdminPrefix          = "/admin"
+	RouteAdminReloadBlocklist = "/reload-blocklist"
+	RouteAdminMetricsSummary  = "/metrics/summary"
+	RouteAdminWordStats       = "/metrics/words"
+	RouteAdminMaintenance     = "/maintenance"
+	RouteAdminGame            = "/games/:id"
+	RouteAdminBans            = "/bans"
+	RouteAdminLiftBan         = "/bans/lift"
+	RouteAdminBots            = "/bots"
+	RouteAdminClearBot        = "/bots/clear"
+	RouteAdminAnnouncement    = "/announcement"
+	RouteAdminAudit           = "/audit"
+	RouteAdminWords           = "/words"
+	RouteAdminWord            = "/words/:word"
+	RouteAdminRetireWord      = "/words/:word/retire"
+	RouteAdminRestoreWord     = "/words/:word/restore"
+	RouteAdminSchedule        = "/schedule"
+	RouteAdminScheduleDay     = "/schedule/:date"
+
+	// RouteDebugGame serves the admin game route without a token, in
+	// development only.
+	RouteDebugGame = "/debug/games/:id"
+	// RouteDebugScore explains how a guess scores against a target, in
+	// development only.
+	RouteDebugScore = "/debug/score"
 )
 
 const (
 	ErrorCodeGameOver        = "game_over"
+	ErrorCodeGameInProgress  = "game_in_progress"
 	ErrorCodeInvalidLength   = "invalid_length"
 	ErrorCodeNoMoreGuesses   = "no_more_guesses"
 	ErrorCodeNotInWordList   = "not_in_word_list"
 	ErrorCodeWordNotAccepted = "word_not_accepted"
 	ErrorCodeDuplicateGuess  = "duplicate_guess"
+	ErrorCodeWordBlocked     = "word_blocked"
+	ErrorCodeInternal        = "internal_error"
+	ErrorCodeHardMode        = "hard_mode_violation"
+	ErrorCodeAssistLetter    = "assist_letter_moved"
+	ErrorCodeInvalidSettings = "invalid_settings"
+	ErrorCodeHintUnavailable = "hint_unavailable"
+	ErrorCodeHintsExhausted  = "hints_exhausted"
+	ErrorCodeSessionExpired  = "session_expired"
+	ErrorCodeOutOfSync       = "state_out_of_sync"
+	ErrorCodeInvalidKey      = "invalid_key"
+
+	ErrorCodeInvalidCompletedWords = "invalid_completed_words"
+
+	ErrorCodeTournamentPlayed = "tournament_played"
+	ErrorCodeUnknownPool      = "unknown_pool"
+	ErrorCodeUnknownPack      = "unknown_pack"
+	ErrorCodeValidateDisabled = "validate_disabled"
+
+	ErrorCodeInvalidAccount     = "invalid_account"
+	ErrorCodeAccountExists      = "account_exists"
+	ErrorCodeInvalidCredentials = "invalid_credentials"
+	ErrorCodeInvalidLink        = "invalid_link"
+	ErrorCodeOIDCFailed         = "oidc_failed"
+	ErrorCodeConsentRequired    = "consent_required"
+	ErrorCodeInvalidConsent     = "invalid_consent"
+
+	ErrorCodeRateLimited = "rate_limited"
+	ErrorCodeTooFast     = "too_fast"
+	ErrorCodeBanned      = "temporarily_banned"
+	ErrorCodeServerFull  = "server_full"
+
+	ErrorCodePayloadTooLarge = "payload_too_large"
+	ErrorCodeTimeout         = "request_timeout"
+
+	ErrorCodeNotFound         = "not_found"
+	ErrorCodeMethodNotAllowed = "method_not_allowed"
 )
 
 const RequestIDKey = "request_id"
+
+// Requests get RequestTimeoutDefault to answer; a guess, which only touches
+// memory and the store, gets GuessTimeoutDefault, and admin routes, which
+// may scan every session, AdminTimeoutDefault.
+const (
+	RequestTimeoutDefault = 10 * time.Second
+	GuessTimeoutDefault   = 3 * time.Second
+	AdminTimeoutDefault   = time.Minute
+)
+
+// MaxRequestBytesDefault caps request bodies: a guess form is a few hundred
+// bytes, and a generous list of completed words fits well within it.
+const MaxRequestBytesDefault = 4 << 10
+
+// FormFieldLimits caps the length in bytes of form and query fields that
+// handlers parse further, so a payload under the body limit still cannot
+// make them work hard.
+var FormFieldLimits = map[string]int{
+	"guess":          64,
+	"completedWords": 3 << 10,
+}
+
+// AdminActorHeader names the operator behind an admin request in the audit
+// log. Admins share one token, so it is taken on trust.
+const AdminActorHeader = "X-Admin-Actor"
+
+// AuditListDefault and AuditListMax bound the entries /admin/audit lists.
+const (
+	AuditListDefault = 50
+	AuditListMax     = 500
+)
+
+// ScheduleCalendarDefault and ScheduleCalendarMax bound the days
+// /admin/schedule lists.
+const (
+	ScheduleCalendarDefault = 28
+	ScheduleCalendarMax     = 366
+)
+
+// NewSessionKey marks, in the gin context, a request whose session cookie was
+// created while handling it.
+const NewSessionKey = "new_session"
+
+// SessionIDKey holds, in the gin context, the session whose cookie was set
+// while handling the request, which takes precedence over the session cookie.
+const SessionIDKey = "session_id"
+
+// CSPNonceKey holds, in the gin context, the request's script nonce when the
+// content security policy uses nonces.
+const CSPNonceKey = "csp_nonce"
+
+// CSRFTokenKey holds, in the gin context, the CSRF token issued with the
+// response.
+const CSRFTokenKey = "csrf_token"
+
+// CSRFSecretKey holds, in the gin context, the CSRF secret whose cookie was
+// set while handling the request, which takes precedence over the cookie.
+const CSRFSecretKey = "csrf_secret"
+
+// ThemeKey holds, in the gin context, the theme of the request's session,
+// for pages to render in from the first paint.
+const ThemeKey = "theme"
+
+// AccountTokenKey holds, in the gin context, a login token issued or revoked
+// during the request, which takes precedence over the account cookie.
+const AccountTokenKey = "account_token"
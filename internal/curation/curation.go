@@ -0,0 +1,265 @@
+// Package curation reviews the target word list for maintainers: how letters
+// are spread across the words, which words lean on repeated letters, which
+// are unusually easy or hard to find, and which hints are missing, too short
+// or give the word away.
+package curation
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	models "github.com/CodeAndHammer/vortludo/internal/models"
+)
+
+const (
+	DefaultMinHintLength = 12
+	DefaultTop           = 10
+
+	// positionTop is how many letters are listed for each position.
+	positionTop = 5
+)
+
+type Options struct {
+	// MinHintLength is the fewest characters a useful hint has.
+	MinHintLength int
+	// Top is how many words the easiest and hardest rankings list.
+	Top int
+	// Accepted and Blocked, when set, are checked for target words that
+	// cannot be guessed or would never be picked.
+	Accepted map[string]struct{}
+	Blocked  map[string]struct{}
+}
+
+// LetterCount is how many words have a letter. Share is their fraction of
+// all words.
+type LetterCount struct {
+	Letter string  `json:"letter"`
+	Words  int     `json:"words"`
+	Share  float64 `json:"share"`
+}
+
+// WordScore rates a word by how common its distinct letters are: the sum of
+// their shares. Words with common letters are found sooner.
+type WordScore struct {
+	Word  string  `json:"word"`
+	Score float64 `json:"score"`
+}
+
+type Report struct {
+	// Words counts the words in play; the letter figures and rankings are
+	// of them alone, and Retired lists the rest.
+	Words     int             `json:"words"`
+	Letters   []LetterCount   `json:"letters"`
+	Positions [][]LetterCount `json:"positions"`
+	// Repeated counts the words with a letter more than once; DuplicateHeavy
+	// lists those with a letter three times or two letters twice.
+	Repeated       int         `json:"repeated"`
+	DuplicateHeavy []string    `json:"duplicateHeavy"`
+	Easiest        []WordScore `json:"easiest"`
+	Hardest        []WordScore `json:"hardest"`
+	MissingHints   []string    `json:"missingHints"`
+	ShortHints     []string    `json:"shortHints"`
+	RevealingHints []string    `json:"revealingHints"`
+	Duplicates     []string    `json:"duplicates"`
+	NotAccepted    []string    `json:"notAccepted"`
+	Blocked        []string    `json:"blocked"`
+	Retired        []string    `json:"retired"`
+
+	minHintLength int
+}
+
+// Problems counts the findings that need fixing rather than balancing.
+func (r *Report) Problems() int {
+	return len(r.MissingHints) + len(r.ShortHints) + len(r.RevealingHints) +
+		len(r.Duplicates) + len(r.NotAccepted) + len(r.Blocked)
+}
+
+// Analyze reviews words, which are expected to be normalized as
+// game.ParseWordList leaves them.
+func Analyze(words []models.WordEntry, opts Options) *Report {
+	opts.MinHintLength = cmp.Or(opts.MinHintLength, DefaultMinHintLength)
+	opts.Top = cmp.Or(opts.Top, DefaultTop)
+	r := &Report{minHintLength: opts.MinHintLength}
+
+	seen := make(map[string]bool)
+	var unique []string
+	for _, entry := range words {
+		word := entry.Word
+		if seen[word] {
+			if !slices.Contains(r.Duplicates, word) {
+				r.Duplicates = append(r.Duplicates, word)
+			}
+			continue
+		}
+		seen[word] = true
+		if entry.Retired() {
+			r.Retired = append(r.Retired, word)
+		} else {
+			unique = append(unique, word)
+		}
+
+		hint := strings.TrimSpace(entry.Hint)
+		switch {
+		case hint == "":
+			r.MissingHints = append(r.MissingHints, word)
+		case utf8.RuneCountInString(hint) < opts.MinHintLength:
+			r.ShortHints = append(r.ShortHints, word)
+		case strings.Contains(strings.ToUpper(hint), word):
+			r.RevealingHints = append(r.RevealingHints, word)
+		}
+		if _, ok := opts.Accepted[word]; opts.Accepted != nil && !ok {
+			r.NotAccepted = append(r.NotAccepted, word)
+		}
+		if _, ok := opts.Blocked[word]; ok {
+			r.Blocked = append(r.Blocked, word)
+		}
+	}
+	r.Words = len(unique)
+	if r.Words == 0 {
+		return r
+	}
+
+	letters := make(map[rune]int)
+	var positions []map[rune]int
+	for _, word := range unique {
+		counts := make(map[rune]int)
+		for i, letter := range []rune(word) {
+			for len(positions) <= i {
+				positions = append(positions, make(map[rune]int))
+			}
+			positions[i][letter]++
+			counts[letter]++
+		}
+		for letter := range counts {
+			letters[letter]++
+		}
+		if repeated(counts) {
+			r.Repeated++
+		}
+		if duplicateHeavy(counts) {
+			r.DuplicateHeavy = append(r.DuplicateHeavy, word)
+		}
+	}
+	r.Letters = r.rank(letters)
+	for _, position := range positions {
+		ranked := r.rank(position)
+		r.Positions = append(r.Positions, ranked[:min(positionTop, len(ranked))])
+	}
+
+	share := make(map[rune]float64, len(letters))
+	for letter, n := range letters {
+		share[letter] = float64(n) / float64(r.Words)
+	}
+	scores := make([]WordScore, 0, len(unique))
+	for _, word := range unique {
+		score := 0.0
+		var counted []rune
+		for _, letter := range word {
+			if !slices.Contains(counted, letter) {
+				counted = append(counted, letter)
+				score += share[letter]
+			}
+		}
+		scores = append(scores, WordScore{Word: word, Score: score})
+	}
+	slices.SortFunc(scores, func(a, b WordScore) int {
+		return cmp.Or(cmp.Compare(b.Score, a.Score), strings.Compare(a.Word, b.Word))
+	})
+	top := min(opts.Top, len(scores))
+	r.Easiest = slices.Clone(scores[:top])
+	r.Hardest = slices.Clone(scores[len(scores)-top:])
+	slices.Reverse(r.Hardest)
+	return r
+}
+
+func repeated(counts map[rune]int) bool {
+	for _, n := range counts {
+		if n > 1 {
+			return true
+		}
+	}
+	return false
+}
+
+func duplicateHeavy(counts map[rune]int) bool {
+	pairs := 0
+	for _, n := range counts {
+		if n >= 3 {
+			return true
+		}
+		if n == 2 {
+			pairs++
+		}
+	}
+	return pairs >= 2
+}
+
+// rank lists the letters of counts, most frequent first.
+func (r *Report) rank(counts map[rune]int) []LetterCount {
+	ranked := make([]LetterCount, 0, len(counts))
+	for letter, n := range counts {
+		ranked = append(ranked, LetterCount{Letter: string(letter), Words: n, Share: float64(n) / float64(r.Words)})
+	}
+	slices.SortFunc(ranked, func(a, b LetterCount) int {
+		return cmp.Or(cmp.Compare(b.Words, a.Words), strings.Compare(a.Letter, b.Letter))
+	})
+	return ranked
+}
+
+// WriteText writes the report for reading in a terminal.
+func (r *Report) WriteText(w io.Writer) error {
+	b := bufio.NewWriter(w)
+	fmt.Fprintf(b, "%d words\n", r.Words)
+
+	fmt.Fprintln(b, "\nLetters, by share of words containing them:")
+	for _, l := range r.Letters {
+		fmt.Fprintf(b, "  %s %5.1f%% %s\n", l.Letter, 100*l.Share, strings.Repeat("#", int(50*l.Share+0.5)))
+	}
+	fmt.Fprintln(b, "\nMost common letters by position:")
+	for i, position := range r.Positions {
+		items := make([]string, len(position))
+		for j, l := range position {
+			items[j] = fmt.Sprintf("%s %.1f%%", l.Letter, 100*l.Share)
+		}
+		fmt.Fprintf(b, "  %d: %s\n", i+1, strings.Join(items, ", "))
+	}
+	if r.Words > 0 {
+		fmt.Fprintf(b, "\n%d words (%.1f%%) repeat a letter.\n", r.Repeated, 100*float64(r.Repeated)/float64(r.Words))
+	}
+	writeList(b, "Duplicate-heavy words", r.DuplicateHeavy)
+	writeScores(b, "Easiest words, with the most common letters", r.Easiest)
+	writeScores(b, "Hardest words, with the rarest letters", r.Hardest)
+	writeList(b, "Retired words", r.Retired)
+
+	writeList(b, "Words without a hint", r.MissingHints)
+	writeList(b, fmt.Sprintf("Hints shorter than %d characters", r.minHintLength), r.ShortHints)
+	writeList(b, "Hints that contain their word", r.RevealingHints)
+	writeList(b, "Words listed more than once", r.Duplicates)
+	writeList(b, "Words missing from the accepted list", r.NotAccepted)
+	writeList(b, "Blocked words", r.Blocked)
+	fmt.Fprintf(b, "\n%d problems found.\n", r.Problems())
+	return b.Flush()
+}
+
+func writeList(w io.Writer, title string, words []string) {
+	if len(words) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\n%s (%d):\n  %s\n", title, len(words), strings.Join(words, ", "))
+}
+
+func writeScores(w io.Writer, title string, scores []WordScore) {
+	if len(scores) == 0 {
+		return
+	}
+	items := make([]string, len(scores))
+	for i, s := range scores {
+		items[i] = fmt.Sprintf("%s %.2f", s.Word, s.Score)
+	}
+	fmt.Fprintf(w, "\n%s:\n  %s\n", title, strings.Join(items, ", "))
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+
+	curation "github.com/CodeAndHammer/vortludo/internal/curation"
+	models "github.com/CodeAndHammer/vortludo/internal/models"
+)
+
+var words = []models.WordEntry{
+	{Word: "CRANE", Hint: "A tall bird, or a machine for lifting."},
+	{Word: "SLATE", Hint: "Fine-grained grey rock."},
+	{Word: "FUZZY", Hint: "Covered in soft hair."},
+	{Word: "LEVEL", Hint: "Flat and even."},
+	{Word: "GAMMA", Hint: ""},
+	{Word: "PHOTO", Hint: "Short for photograph."},
+	{Word: "TRAIN", Hint: "Rail car."},
+	{Word: "SLATE", Hint: "Fine-grained grey rock."},
+	{Word: "ĈAMBO", Hint: "Ĉambro de domo, sen la R."},
+}
+
+func TestAnalyze(t *testing.T) {
+	report := curation.Analyze(words, curation.Options{
+		Top:      2,
+		Accepted: map[string]struct{}{"CRANE": {}, "SLATE": {}, "FUZZY": {}, "LEVEL": {}, "GAMMA": {}, "PHOTO": {}, "TRAIN": {}},
+		Blocked:  map[string]struct{}{"GAMMA": {}},
+	})
+
+	if report.Words != 8 {
+		t.Errorf("Expected 8 distinct words, got %d", report.Words)
+	}
+	if report.Letters[0].Letter != "A" || report.Letters[0].Words != 5 {
+		t.Errorf("A should be the most common letter, in 5 words, got %+v", report.Letters[0])
+	}
+	if len(report.Positions) != 5 || len(report.Positions[0]) > 5 {
+		t.Errorf("Expected up to 5 letters for each of 5 positions, got %+v", report.Positions)
+	}
+	if report.Repeated != 4 || !slices.Equal(report.DuplicateHeavy, []string{"LEVEL", "GAMMA"}) {
+		t.Errorf("Unexpected repeated letters: %d, %v", report.Repeated, report.DuplicateHeavy)
+	}
+	if len(report.Easiest) != 2 || len(report.Hardest) != 2 || report.Hardest[0].Word != "FUZZY" {
+		t.Errorf("FUZZY should be the hardest word, got %+v", report.Hardest)
+	}
+	if report.Easiest[0].Score < report.Easiest[1].Score || report.Hardest[0].Score > report.Hardest[1].Score {
+		t.Errorf("Rankings are out of order: %+v %+v", report.Easiest, report.Hardest)
+	}
+
+	for name, got := range map[string][]string{
+		"missing":      report.MissingHints,
+		"short":        report.ShortHints,
+		"revealing":    report.RevealingHints,
+		"duplicates":   report.Duplicates,
+		"not accepted": report.NotAccepted,
+		"blocked":      report.Blocked,
+	} {
+		want := map[string][]string{
+			"missing":      {"GAMMA"},
+			"short":        {"TRAIN"},
+			"revealing":    {"PHOTO"},
+			"duplicates":   {"SLATE"},
+			"not accepted": {"ĈAMBO"},
+			"blocked":      {"GAMMA"},
+		}[name]
+		if !slices.Equal(got, want) {
+			t.Errorf("%s = %v, want %v", name, got, want)
+		}
+	}
+	if report.Problems() != 6 {
+		t.Errorf("Expected 6 problems, got %d", report.Problems())
+	}
+
+	var out bytes.Buffer
+	if err := report.WriteText(&out); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"8 words\n", "Duplicate-heavy words (2)", "Hints shorter than 12 characters (1):\n  TRAIN", "6 problems found."} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected %q in the report:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestAnalyzeEmpty(t *testing.T) {
+	report := curation.Analyze(nil, curation.Options{})
+	var out bytes.Buffer
+	if err := report.WriteText(&out); err != nil || report.Words != 0 || report.Problems() != 0 {
+		t.Errorf("Empty list: %+v, %v", report, err)
+	}
+}
+
+func TestAnalyzeRetired(t *testing.T) {
+	report := curation.Analyze([]models.WordEntry{
+		{Word: "CRANE", Hint: "A tall bird, or a machine for lifting."},
+		{Word: "FUZZY", Hint: "Covered in soft hair.", Status: models.WordRetired},
+	}, curation.Options{})
+	if report.Words != 1 || !slices.Equal(report.Retired, []string{"FUZZY"}) || report.Hardest[0].Word != "CRANE" {
+		t.Errorf("Expected FUZZY left out of the figures, got %+v", report)
+	}
+}
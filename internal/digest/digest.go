@@ -0,0 +1,165 @@
+// Package digest writes an end-of-day digest of gameplay stats: games
+// played, how many guesses the wins took and the words most often missed.
+// Digests are JSON or CSV files written to a directory or an S3 bucket, for
+// operators who run without a metrics stack.
+package digest
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	analytics "github.com/CodeAndHammer/vortludo/internal/analytics"
+	util "github.com/CodeAndHammer/vortludo/internal/util"
+	wordsource "github.com/CodeAndHammer/vortludo/internal/wordsource"
+)
+
+const (
+	// DefaultOffset is how long after midnight UTC the previous day's digest
+	// is written, so games finishing around midnight are counted.
+	DefaultOffset = 5 * time.Minute
+
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+
+	digestFormat = 1
+	filePrefix   = "vortludo-digest-"
+)
+
+// Formats are the file formats a digest can be written in.
+var Formats = []string{FormatJSON, FormatCSV}
+
+// Digest is the report of one UTC day.
+type Digest struct {
+	Format    int       `json:"format"`
+	Generated time.Time `json:"generated"`
+	analytics.DayStats
+}
+
+// New returns the digest of date, formatted as time.DateOnly. A day the
+// collector has no record of yields an empty digest.
+func New(c *analytics.Collector, date string, now time.Time) Digest {
+	stats, ok := c.Day(date)
+	if !ok {
+		stats = analytics.DayStats{Date: date, SolvedIn: []int{}, MostMissed: []analytics.WordCount{}}
+	}
+	return Digest{Format: digestFormat, Generated: now.UTC(), DayStats: stats}
+}
+
+// JSON encodes d as an indented JSON document.
+func (d Digest) JSON() ([]byte, error) {
+	return json.MarshalIndent(d, "", "  ")
+}
+
+// CSV encodes d as date,metric,key,value rows: one per total, one per
+// number of guesses wins took and one per missed word.
+func (d Digest) CSV() ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	row := func(metric, key string, value any) {
+		w.Write([]string{d.Date, metric, key, fmt.Sprint(value)})
+	}
+	w.Write([]string{"date", "metric", "key", "value"})
+	row("active_sessions", "", d.ActiveSessions)
+	row("games_completed", "", d.GamesCompleted)
+	row("games_won", "", d.GamesWon)
+	row("games_flagged", "", d.GamesFlagged)
+	row("win_rate", "", strconv.FormatFloat(d.WinRate, 'f', 4, 64))
+	for i, n := range d.SolvedIn {
+		row("solved_in", strconv.Itoa(i+1), n)
+	}
+	for _, missed := range d.MostMissed {
+		row("missed_word", missed.Word, missed.Count)
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// Writer stores digests in a directory or under an S3 prefix.
+type Writer struct {
+	formats []string
+	dir     string
+	// bucket and prefix locate the digests in S3 when bucket is set.
+	bucket string
+	prefix string
+	s3     wordsource.S3Config
+}
+
+// NewWriter returns a writer of formats to destination: a directory, or
+// s3://bucket/prefix/ with credentials from s3.
+func NewWriter(destination string, formats []string, s3 wordsource.S3Config) (*Writer, error) {
+	for _, format := range formats {
+		if !slices.Contains(Formats, format) {
+			return nil, fmt.Errorf("digest format %q: want %s", format, strings.Join(Formats, " or "))
+		}
+	}
+	w := &Writer{formats: formats, s3: s3}
+	u, err := url.Parse(destination)
+	if err != nil || u.Scheme != "s3" {
+		w.dir = destination
+		return w, nil
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("digest destination %q: want s3://bucket/prefix", destination)
+	}
+	w.bucket = u.Host
+	if w.prefix = strings.Trim(u.Path, "/"); w.prefix != "" {
+		w.prefix += "/"
+	}
+	return w, nil
+}
+
+// Write stores d in each of the writer's formats and returns where.
+func (w *Writer) Write(ctx context.Context, d Digest) ([]string, error) {
+	var written []string
+	for _, format := range w.formats {
+		var data []byte
+		var err error
+		contentType := "application/json"
+		if format == FormatCSV {
+			data, err = d.CSV()
+			contentType = "text/csv"
+		} else {
+			data, err = d.JSON()
+		}
+		if err != nil {
+			return written, fmt.Errorf("encode %s digest: %w", format, err)
+		}
+		name := filePrefix + d.Date + "." + format
+		where, err := w.put(ctx, name, data, contentType)
+		if err != nil {
+			return written, err
+		}
+		written = append(written, where)
+	}
+	return written, nil
+}
+
+func (w *Writer) put(ctx context.Context, name string, data []byte, contentType string) (string, error) {
+	if w.bucket == "" {
+		if err := os.MkdirAll(w.dir, 0o755); err != nil {
+			return "", err
+		}
+		path := filepath.Join(w.dir, name)
+		return path, util.WriteFileAtomic(path, data)
+	}
+	object := &wordsource.S3Source{Bucket: w.bucket, Key: w.prefix + name, Config: w.s3}
+	return object.String(), object.Put(ctx, data, contentType)
+}
+
+// String describes where the writer stores digests.
+func (w *Writer) String() string {
+	if w.bucket != "" {
+		return "s3://" + w.bucket + "/" + w.prefix
+	}
+	return w.dir
+}
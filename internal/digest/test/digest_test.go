@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	analytics "github.com/CodeAndHammer/vortludo/internal/analytics"
+	digest "github.com/CodeAndHammer/vortludo/internal/digest"
+	wordsource "github.com/CodeAndHammer/vortludo/internal/wordsource"
+)
+
+func TestWriteDigest(t *testing.T) {
+	c := analytics.NewCollector()
+	c.RecordGame(analytics.GameResult{Won: true, Guesses: 2})
+	c.RecordGame(analytics.GameResult{Won: false, Guesses: 6, MissedWords: []string{"FJORD"}})
+	now := time.Now()
+	today := now.UTC().Format(time.DateOnly)
+
+	dir := filepath.Join(t.TempDir(), "digests")
+	writer, err := digest.NewWriter(dir, digest.Formats, wordsource.S3Config{})
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	written, err := writer.Write(context.Background(), digest.New(c, today, now))
+	if err != nil || len(written) != 2 {
+		t.Fatalf("Expected a JSON and a CSV digest, got %v, %v", written, err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "vortludo-digest-"+today+".json"))
+	if err != nil {
+		t.Fatalf("Read JSON digest: %v", err)
+	}
+	var decoded digest.Digest
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Decode JSON digest: %v", err)
+	}
+	if decoded.Date != today || decoded.GamesCompleted != 2 || decoded.GamesWon != 1 || decoded.SolvedIn[1] != 1 {
+		t.Errorf("Unexpected JSON digest: %+v", decoded)
+	}
+
+	data, err = os.ReadFile(filepath.Join(dir, "vortludo-digest-"+today+".csv"))
+	if err != nil {
+		t.Fatalf("Read CSV digest: %v", err)
+	}
+	for _, row := range []string{
+		"date,metric,key,value",
+		today + ",games_completed,,2",
+		today + ",win_rate,,0.5000",
+		today + ",solved_in,2,1",
+		today + ",missed_word,FJORD,1",
+	} {
+		if !strings.Contains(string(data), row+"\n") {
+			t.Errorf("CSV digest is missing %q:\n%s", row, data)
+		}
+	}
+}
+
+func TestEmptyDigest(t *testing.T) {
+	d := digest.New(analytics.NewCollector(), "2026-10-15", time.Now())
+	data, err := d.JSON()
+	if err != nil {
+		t.Fatalf("JSON: %v", err)
+	}
+	if !strings.Contains(string(data), `"solved_in": []`) || d.GamesCompleted != 0 {
+		t.Errorf("A day without games should give an empty digest, got %s", data)
+	}
+}
+
+func TestNewWriterValidation(t *testing.T) {
+	if _, err := digest.NewWriter("data", []string{"xml"}, wordsource.S3Config{}); err == nil {
+		t.Error("Expected an unknown format to be refused")
+	}
+	if _, err := digest.NewWriter("s3:///prefix", digest.Formats, wordsource.S3Config{}); err == nil {
+		t.Error("Expected an S3 destination without a bucket to be refused")
+	}
+	writer, err := digest.NewWriter("s3://stats/daily", digest.Formats, wordsource.S3Config{})
+	if err != nil || writer.String() != "s3://stats/daily/" {
+		t.Errorf("Expected an S3 writer under daily/, got %v, %v", writer, err)
+	}
+}
@@ -0,0 +1,256 @@
+// Package errreport forwards the panics the server recovers from to error
+// trackers: a Sentry-compatible endpoint, given its DSN, or a file of JSON
+// lines. A report names the request and a hash of its session, never the
+// session ID itself, which is a credential.
+package errreport
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	util "github.com/CodeAndHammer/vortludo/internal/util"
+)
+
+const sendTimeout = 10 * time.Second
+
+// Report is one recovered panic.
+type Report struct {
+	ID        string    `json:"id"`
+	Time      time.Time `json:"time"`
+	RequestID string    `json:"requestId,omitempty"`
+	Session   string    `json:"session,omitempty"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	Panic     string    `json:"panic"`
+	Stack     string    `json:"stack"`
+}
+
+// NewReport describes a panic with the value recovered and the stack it was
+// raised on. sessionID is hashed.
+func NewReport(recovered any, stack []byte, r *http.Request, requestID, sessionID string) Report {
+	id := make([]byte, 16)
+	rand.Read(id)
+	return Report{
+		ID:        hex.EncodeToString(id),
+		Time:      time.Now().UTC(),
+		RequestID: requestID,
+		Session:   HashSession(sessionID),
+		Method:    r.Method,
+		Path:      r.URL.Path,
+		Panic:     fmt.Sprint(recovered),
+		Stack:     string(stack),
+	}
+}
+
+// HashSession shortens a session ID to a hash that tells sessions apart in
+// reports without letting anyone take the session over. An empty ID stays
+// empty.
+func HashSession(sessionID string) string {
+	if sessionID == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(sessionID))
+	return hex.EncodeToString(sum[:6])
+}
+
+// Sink is somewhere reports go. Sinks that hold resources also implement
+// io.Closer.
+type Sink interface {
+	Send(ctx context.Context, report Report) error
+}
+
+// Reporter sends each report to its sinks in the background, so a panicking
+// request is answered without waiting for them. A nil Reporter, which is
+// what a server without error reporting has, drops reports.
+type Reporter struct {
+	sinks    []Sink
+	inflight sync.WaitGroup
+}
+
+// New returns a Reporter for sinks, or nil if there are none.
+func New(sinks ...Sink) *Reporter {
+	if len(sinks) == 0 {
+		return nil
+	}
+	return &Reporter{sinks: sinks}
+}
+
+func (r *Reporter) Report(report Report) {
+	if r == nil {
+		return
+	}
+	for _, sink := range r.sinks {
+		r.inflight.Go(func() {
+			ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+			defer cancel()
+			if err := sink.Send(ctx, report); err != nil {
+				util.LogWarn("Failed to report panic %s: %v", report.ID, err)
+			}
+		})
+	}
+}
+
+// Close waits for the reports being sent, until ctx is done, and closes the
+// sinks.
+func (r *Reporter) Close(ctx context.Context) error {
+	if r == nil {
+		return nil
+	}
+	done := make(chan struct{})
+	go func() {
+		r.inflight.Wait()
+		close(done)
+	}()
+	var errs []error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		errs = append(errs, fmt.Errorf("reports still being sent: %w", ctx.Err()))
+	}
+	for _, sink := range r.sinks {
+		if closer, ok := sink.(io.Closer); ok {
+			errs = append(errs, closer.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Sentry posts reports as events to a Sentry-compatible store endpoint,
+// such as Sentry's own or GlitchTip's.
+type Sentry struct {
+	endpoint string
+	key      string
+	client   *http.Client
+}
+
+// NewSentry reads a DSN of the form https://<key>@<host>[/<path>]/<project>.
+func NewSentry(dsn string, client *http.Client) (*Sentry, error) {
+	u, err := url.Parse(dsn)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, errors.New("want an http(s) DSN")
+	}
+	key := u.User.Username()
+	project := path.Base(u.Path)
+	if key == "" || project == "." || project == "/" {
+		return nil, errors.New("want https://<key>@<host>/<project>")
+	}
+	u.User = nil
+	u.Path = strings.TrimSuffix(path.Dir(u.Path), "/") + "/api/" + project + "/store/"
+	if client == nil {
+		client = &http.Client{Timeout: sendTimeout}
+	}
+	return &Sentry{endpoint: u.String(), key: key, client: client}, nil
+}
+
+// sentryEvent is the part of Sentry's event payload a report fills in.
+type sentryEvent struct {
+	EventID   string            `json:"event_id"`
+	Timestamp string            `json:"timestamp"`
+	Level     string            `json:"level"`
+	Platform  string            `json:"platform"`
+	Logger    string            `json:"logger"`
+	Message   string            `json:"message"`
+	Exception sentryValues      `json:"exception"`
+	Tags      map[string]string `json:"tags,omitempty"`
+	Request   sentryRequest     `json:"request"`
+	Extra     map[string]string `json:"extra"`
+}
+
+type sentryValues struct {
+	Values []sentryException `json:"values"`
+}
+
+type sentryException struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type sentryRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+}
+
+func (s *Sentry) Send(ctx context.Context, report Report) error {
+	tags := make(map[string]string)
+	if report.RequestID != "" {
+		tags["request_id"] = report.RequestID
+	}
+	if report.Session != "" {
+		tags["session"] = report.Session
+	}
+	data, err := json.Marshal(sentryEvent{
+		EventID:   report.ID,
+		Timestamp: report.Time.Format(time.RFC3339),
+		Level:     "error",
+		Platform:  "go",
+		Logger:    "vortludo",
+		Message:   "panic: " + report.Panic,
+		Exception: sentryValues{Values: []sentryException{{Type: "panic", Value: report.Panic}}},
+		Tags:      tags,
+		Request:   sentryRequest{Method: report.Method, URL: report.Path},
+		Extra:     map[string]string{"stack": report.Stack},
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sentry-Auth", "Sentry sentry_version=7, sentry_client=vortludo/1, sentry_key="+s.key)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("post to error tracker: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("post to error tracker: %s", resp.Status)
+	}
+	return nil
+}
+
+// File appends reports to a file as JSON lines.
+type File struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func OpenFile(name string) (*File, error) {
+	file, err := os.OpenFile(name, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &File{file: file}, nil
+}
+
+func (f *File) Send(_ context.Context, report Report) error {
+	line, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, err = f.file.Write(append(line, '\n'))
+	return err
+}
+
+func (f *File) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.file.Close()
+}
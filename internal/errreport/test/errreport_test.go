@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	errreport "github.com/CodeAndHammer/vortludo/internal/errreport"
+)
+
+func testReport() errreport.Report {
+	req := httptest.NewRequest(http.MethodPost, "/guess", nil)
+	return errreport.NewReport(errors.New("boom"), []byte("goroutine 1 [running]:"), req, "req-1", "session-secret")
+}
+
+func TestSentry(t *testing.T) {
+	var mu sync.Mutex
+	var path, auth, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		path, auth = r.URL.Path, r.Header.Get("X-Sentry-Auth")
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+	}))
+	defer server.Close()
+
+	dsn := strings.Replace(server.URL, "http://", "http://public@", 1) + "/sentry/42"
+	sentry, err := errreport.NewSentry(dsn, server.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	reporter := errreport.New(sentry)
+	reporter.Report(testReport())
+	if err := reporter.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if path != "/sentry/api/42/store/" {
+		t.Errorf("Expected the project's store endpoint, got %s", path)
+	}
+	if !strings.Contains(auth, "sentry_key=public") {
+		t.Errorf("Expected the DSN's key in the auth header, got %q", auth)
+	}
+	var event struct {
+		Tags map[string]string `json:"tags"`
+	}
+	json.Unmarshal([]byte(body), &event)
+	if event.Tags["request_id"] != "req-1" || event.Tags["session"] != errreport.HashSession("session-secret") {
+		t.Errorf("Expected the request and hashed session tagged, got %v", event.Tags)
+	}
+	if strings.Contains(body, "session-secret") {
+		t.Error("The session ID must not be sent")
+	}
+}
+
+func TestNewSentryRejectsBadDSN(t *testing.T) {
+	for _, dsn := range []string{"sentry.example.com/1", "https://sentry.example.com/1", "ftp://key@sentry.example.com/1", "https://key@sentry.example.com"} {
+		if _, err := errreport.NewSentry(dsn, nil); err == nil {
+			t.Errorf("Expected %q to be rejected", dsn)
+		}
+	}
+}
+
+func TestFile(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "panics.jsonl")
+	file, err := errreport.OpenFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reporter := errreport.New(file)
+	reporter.Report(testReport())
+	reporter.Report(testReport())
+	if err := reporter.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var reports []errreport.Report
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var report errreport.Report
+		if err := json.Unmarshal(scanner.Bytes(), &report); err != nil {
+			t.Fatal(err)
+		}
+		reports = append(reports, report)
+	}
+	if len(reports) != 2 || reports[0].ID == reports[1].ID {
+		t.Fatalf("Expected two reports with their own IDs, got %+v", reports)
+	}
+	if r := reports[0]; r.Panic != "boom" || r.Path != "/guess" || r.Stack == "" {
+		t.Errorf("Expected the panic, path and stack, got %+v", r)
+	}
+}
+
+func TestNilReporter(t *testing.T) {
+	var reporter *errreport.Reporter
+	reporter.Report(testReport())
+	if err := reporter.Close(context.Background()); err != nil {
+		t.Errorf("Expected a nil reporter to close cleanly, got %v", err)
+	}
+}
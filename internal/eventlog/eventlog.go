@@ -0,0 +1,309 @@
+// Package eventlog is an append-only log of game events, written as JSON
+// lines to size-capped segment files. Reading it back lets the server
+// recover games lost in a crash, backfill analytics after a restart and
+// replay games that are no longer in memory, without a database.
+package eventlog
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	util "github.com/CodeAndHammer/vortludo/internal/util"
+)
+
+const (
+	SessionCreated = "session_created"
+	GameStarted    = "game_started"
+	GuessMade      = "guess_made"
+	HintRevealed   = "hint_revealed"
+	GameWon        = "game_won"
+	GameLost       = "game_lost"
+	// SessionErased marks a session whose data its player had erased; the
+	// games it logged before are not recovered.
+	SessionErased = "session_erased"
+)
+
+const (
+	DefaultMaxSize  = 64 << 20
+	DefaultMaxFiles = 8
+
+	segmentPrefix = "events-"
+	segmentSuffix = ".jsonl"
+	// maxLine bounds one event when reading, well above any game state.
+	maxLine = 1 << 20
+)
+
+// Event is one line of the log. Data is the event's payload, whose shape
+// depends on Type.
+type Event struct {
+	Seq     uint64          `json:"seq"`
+	Time    time.Time       `json:"time"`
+	Type    string          `json:"type"`
+	Session string          `json:"session,omitempty"`
+	Game    string          `json:"game,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+// Options configure rotation and durability. Zero values take the defaults.
+type Options struct {
+	// MaxSize is the size a segment is rotated at.
+	MaxSize int64
+	// MaxFiles is how many segments are kept; the oldest are deleted.
+	MaxFiles int
+	// Sync flushes every event to stable storage before Append returns. Events
+	// written without it survive a crash of the process, not of the machine.
+	Sync bool
+}
+
+// Log appends events to the newest segment in its directory.
+type Log struct {
+	dir  string
+	opts Options
+
+	mu      sync.Mutex
+	file    *os.File
+	segment int
+	size    int64
+	seq     uint64
+}
+
+// Open opens the log in dir, creating the directory if needed. Appends
+// continue the newest segment.
+func Open(dir string, opts Options) (*Log, error) {
+	if opts.MaxSize <= 0 {
+		opts.MaxSize = DefaultMaxSize
+	}
+	if opts.MaxFiles <= 0 {
+		opts.MaxFiles = DefaultMaxFiles
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	l := &Log{dir: dir, opts: opts}
+	segments, err := l.segments()
+	if err != nil {
+		return nil, err
+	}
+	l.segment = 1
+	if len(segments) > 0 {
+		l.segment = segments[len(segments)-1]
+		if err := readSegment(l.path(l.segment), func(e Event) error {
+			l.seq = e.Seq
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+	if err := l.openSegment(); err != nil {
+		return nil, err
+	}
+	util.LogInfo("Appending game events to %s (segment %d, next event %d)", dir, l.segment, l.seq+1)
+	return l, nil
+}
+
+// Append writes an event with data as its payload. A nil Log discards
+// events, so callers need not check whether the log is enabled.
+func (l *Log) Append(eventType, sessionID, gameID string, data any) error {
+	if l == nil {
+		return nil
+	}
+	e := Event{Time: time.Now().UTC(), Type: eventType, Session: sessionID, Game: gameID}
+	if data != nil {
+		payload, err := json.Marshal(data)
+		if err != nil {
+			return fmt.Errorf("encode %s event: %w", eventType, err)
+		}
+		e.Data = payload
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return errors.New("event log is closed")
+	}
+	e.Seq = l.seq + 1
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("encode %s event: %w", eventType, err)
+	}
+	line = append(line, '\n')
+	if l.size > 0 && l.size+int64(len(line)) > l.opts.MaxSize {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := l.file.Write(line)
+	l.size += int64(n)
+	if err != nil {
+		return err
+	}
+	if l.opts.Sync {
+		if err := l.file.Sync(); err != nil {
+			return err
+		}
+	}
+	l.seq = e.Seq
+	return nil
+}
+
+// Replay calls fn with every event still in the log, oldest first. A line
+// torn by a crash mid-write is skipped. Events appended while Replay runs
+// may or may not be seen.
+func (l *Log) Replay(fn func(Event) error) error {
+	l.mu.Lock()
+	segments, err := l.segments()
+	l.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	for _, segment := range segments {
+		err := readSegment(l.path(segment), fn)
+		if errors.Is(err, fs.ErrNotExist) {
+			// Rotated away since it was listed.
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Seq returns the sequence number of the last event appended, 0 for a nil
+// or empty log. Events appended later have greater numbers.
+func (l *Log) Seq() uint64 {
+	if l == nil {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.seq
+}
+
+func (l *Log) Close() error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+func (l *Log) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return err
+	}
+	l.segment++
+	if err := l.openSegment(); err != nil {
+		return err
+	}
+	segments, err := l.segments()
+	if err != nil {
+		return err
+	}
+	for len(segments) > l.opts.MaxFiles {
+		if err := os.Remove(l.path(segments[0])); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			util.LogWarn("Failed to remove old event log segment: %v", err)
+		}
+		segments = segments[1:]
+	}
+	return nil
+}
+
+func (l *Log) openSegment() error {
+	f, err := os.OpenFile(l.path(l.segment), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	l.file, l.size = f, info.Size()
+	return l.endTornLine()
+}
+
+// endTornLine terminates a last line left unfinished by a crash, so the next
+// event starts on a line of its own.
+func (l *Log) endTornLine() error {
+	if l.size == 0 {
+		return nil
+	}
+	last := make([]byte, 1)
+	r, err := os.Open(l.path(l.segment))
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	if _, err := r.ReadAt(last, l.size-1); err != nil {
+		return err
+	}
+	if last[0] == '\n' {
+		return nil
+	}
+	n, err := l.file.Write([]byte{'\n'})
+	l.size += int64(n)
+	return err
+}
+
+func (l *Log) path(segment int) string {
+	return filepath.Join(l.dir, fmt.Sprintf("%s%08d%s", segmentPrefix, segment, segmentSuffix))
+}
+
+// segments returns the numbers of the segments in the directory, in order.
+func (l *Log) segments() ([]int, error) {
+	entries, err := os.ReadDir(l.dir)
+	if err != nil {
+		return nil, err
+	}
+	var segments []int
+	for _, entry := range entries {
+		name, ok := strings.CutPrefix(entry.Name(), segmentPrefix)
+		if !ok {
+			continue
+		}
+		name, ok = strings.CutSuffix(name, segmentSuffix)
+		if n, err := strconv.Atoi(name); ok && err == nil {
+			segments = append(segments, n)
+		}
+	}
+	slices.Sort(segments)
+	return segments, nil
+}
+
+func readSegment(path string, fn func(Event) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), maxLine)
+	for scanner.Scan() {
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			util.LogWarn("Skipping unreadable event in %s: %v", path, err)
+			continue
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	eventlog "github.com/CodeAndHammer/vortludo/internal/eventlog"
+)
+
+func replayAll(t *testing.T, log *eventlog.Log) []eventlog.Event {
+	t.Helper()
+	var events []eventlog.Event
+	if err := log.Replay(func(e eventlog.Event) error {
+		events = append(events, e)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return events
+}
+
+func TestAppendAndRotate(t *testing.T) {
+	dir := t.TempDir()
+	log, err := eventlog.Open(dir, eventlog.Options{MaxSize: 300, MaxFiles: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer log.Close()
+	for i := range 10 {
+		if err := log.Append(eventlog.GuessMade, "session", "game", map[string]int{"row": i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	segments, _ := filepath.Glob(filepath.Join(dir, "events-*.jsonl"))
+	if len(segments) != 2 {
+		t.Errorf("Expected rotation to keep 2 segments, got %d", len(segments))
+	}
+	events := replayAll(t, log)
+	if len(events) == 0 || len(events) >= 10 {
+		t.Fatalf("Expected only the newest events to remain, got %d", len(events))
+	}
+	for i, e := range events {
+		if want := uint64(10 - len(events) + i + 1); e.Seq != want {
+			t.Errorf("Event %d has seq %d, want %d", i, e.Seq, want)
+		}
+	}
+}
+
+func TestReopenAfterTornWrite(t *testing.T) {
+	dir := t.TempDir()
+	log, err := eventlog.Open(dir, eventlog.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	log.Append(eventlog.SessionCreated, "a", "", nil)
+	log.Append(eventlog.SessionCreated, "b", "", nil)
+	log.Close()
+
+	segments, _ := filepath.Glob(filepath.Join(dir, "events-*.jsonl"))
+	f, err := os.OpenFile(segments[0], os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"seq":3,"type":"sess`)
+	f.Close()
+
+	log, err = eventlog.Open(dir, eventlog.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer log.Close()
+	if err := log.Append(eventlog.SessionCreated, "c", "", nil); err != nil {
+		t.Fatal(err)
+	}
+	events := replayAll(t, log)
+	if len(events) != 3 || events[2].Session != "c" || events[2].Seq != 3 {
+		t.Errorf("Expected the torn line skipped and seq to continue, got %+v", events)
+	}
+}
+
+func TestNilLogDiscards(t *testing.T) {
+	var log *eventlog.Log
+	if err := log.Append(eventlog.GameWon, "session", "game", nil); err != nil {
+		t.Errorf("Expected a nil log to discard events, got %v", err)
+	}
+}
@@ -0,0 +1,153 @@
+// Package events is the catalog of events the server sends to the client
+// through the HX-Trigger response headers, and the emitter handlers use to
+// send them. Events emitted during a request are merged, so one response can
+// trigger several of them.
+package events
+
+import (
+	"encoding/json"
+
+	util "github.com/CodeAndHammer/vortludo/internal/util"
+	"github.com/gin-gonic/gin"
+)
+
+type Name string
+
+const (
+	ServerErrorCode     Name = "server_error_code"
+	ServerErrorDetails  Name = "server_error_details"
+	ClearCompletedWords Name = "clear-completed-words"
+	SessionExpired      Name = "session-expired"
+	RaceFinished        Name = "race-finished"
+	RateLimitExceeded   Name = "rate-limit-exceeded"
+	AchievementUnlocked Name = "achievement-unlocked"
+)
+
+// Spec documents an event: what it means and the JSON schema of its payload.
+type Spec struct {
+	Description string          `json:"description"`
+	Schema      json.RawMessage `json:"schema"`
+}
+
+var flagSchema = json.RawMessage(`{"type":"boolean","const":true}`)
+
+var catalog = map[Name]Spec{
+	ServerErrorCode: {
+		Description: "The request failed; the value is a game error code.",
+		Schema:      json.RawMessage(`{"type":"string"}`),
+	},
+	ServerErrorDetails: {
+		Description: "Details of the error named by server_error_code. Errors about particular letters, such as hard_mode_violation and invalid_length, list the 1-based guess positions concerned in positions.",
+		Schema:      json.RawMessage(`{"type":"object"}`),
+	},
+	ClearCompletedWords: {
+		Description: "Every word has been played; the client should forget its completed words.",
+		Schema:      flagSchema,
+	},
+	SessionExpired: {
+		Description: "The previous game expired and was replaced by a new one.",
+		Schema:      flagSchema,
+	},
+	RaceFinished: {
+		Description: "The bot opponent finished the race; the board should be reloaded.",
+		Schema:      flagSchema,
+	},
+	RateLimitExceeded: {
+		Description: "The client is sending requests too quickly.",
+		Schema:      flagSchema,
+	},
+	AchievementUnlocked: {
+		Description: "The game just finished unlocked achievements; the value lists them.",
+		Schema:      json.RawMessage(`{"type":"array","items":{"type":"object","required":["id","name","description","icon"],"properties":{"id":{"type":"string"},"name":{"type":"string"},"description":{"type":"string"},"icon":{"type":"string"}}}}`),
+	},
+}
+
+// Catalog returns the spec of every known event.
+func Catalog() map[Name]Spec {
+	out := make(map[Name]Spec, len(catalog))
+	for name, spec := range catalog {
+		out[name] = spec
+	}
+	return out
+}
+
+// Phase selects when htmx fires an event.
+type Phase int
+
+const (
+	OnReceive Phase = iota
+	AfterSwap
+	AfterSettle
+)
+
+var phaseHeaders = [...]string{
+	OnReceive:   "HX-Trigger",
+	AfterSwap:   "HX-Trigger-After-Swap",
+	AfterSettle: "HX-Trigger-After-Settle",
+}
+
+const contextKey = "events.emitter"
+
+// Emitter collects the events of one response and keeps its trigger headers
+// up to date.
+type Emitter struct {
+	c       *gin.Context
+	pending [len(phaseHeaders)]map[Name]any
+}
+
+// From returns the emitter of the request, creating it on first use.
+func From(c *gin.Context) *Emitter {
+	if v, ok := c.Get(contextKey); ok {
+		return v.(*Emitter)
+	}
+	e := &Emitter{c: c}
+	c.Set(contextKey, e)
+	return e
+}
+
+// Trigger fires name as soon as the response is received.
+func (e *Emitter) Trigger(name Name, payload any) *Emitter {
+	return e.emit(OnReceive, name, payload)
+}
+
+// TriggerAfterSwap fires name once htmx has swapped the response in.
+func (e *Emitter) TriggerAfterSwap(name Name, payload any) *Emitter {
+	return e.emit(AfterSwap, name, payload)
+}
+
+// TriggerAfterSettle fires name once htmx has settled the swapped content.
+func (e *Emitter) TriggerAfterSettle(name Name, payload any) *Emitter {
+	return e.emit(AfterSettle, name, payload)
+}
+
+// Flag fires an event that carries no payload.
+func (e *Emitter) Flag(name Name) *Emitter {
+	return e.Trigger(name, true)
+}
+
+// Error reports a failed request with its error code and optional details.
+func (e *Emitter) Error(code string, details map[string]any) *Emitter {
+	e.Trigger(ServerErrorCode, code)
+	if len(details) > 0 {
+		e.Trigger(ServerErrorDetails, details)
+	}
+	return e
+}
+
+func (e *Emitter) emit(phase Phase, name Name, payload any) *Emitter {
+	if _, ok := catalog[name]; !ok {
+		util.LogWarn("Emitting event %q that is missing from the catalog", name)
+	}
+	if e.pending[phase] == nil {
+		e.pending[phase] = make(map[Name]any)
+	}
+	e.pending[phase][name] = payload
+	b, err := json.Marshal(e.pending[phase])
+	if err != nil {
+		util.LogWarn("Failed to marshal %s payload: %v", phaseHeaders[phase], err)
+		delete(e.pending[phase], name)
+		return e
+	}
+	e.c.Header(phaseHeaders[phase], string(b))
+	return e
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	events "github.com/CodeAndHammer/vortludo/internal/events"
+	"github.com/gin-gonic/gin"
+)
+
+func decodeHeader(t *testing.T, w *httptest.ResponseRecorder, header string) map[string]any {
+	t.Helper()
+	var payload map[string]any
+	if err := json.Unmarshal([]byte(w.Header().Get(header)), &payload); err != nil {
+		t.Fatalf("%s is not JSON: %q", header, w.Header().Get(header))
+	}
+	return payload
+}
+
+func TestEventsAreMerged(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	events.From(c).Flag(events.SessionExpired)
+	events.From(c).Error("invalid_word", map[string]any{"word": "XXXXX"})
+
+	payload := decodeHeader(t, w, "HX-Trigger")
+	if payload["session-expired"] != true {
+		t.Errorf("Earlier event was lost: %v", payload)
+	}
+	if payload["server_error_code"] != "invalid_word" {
+		t.Errorf("Unexpected error code: %v", payload)
+	}
+	if details, _ := payload["server_error_details"].(map[string]any); details["word"] != "XXXXX" {
+		t.Errorf("Unexpected error details: %v", payload)
+	}
+}
+
+func TestPhasesUseTheirOwnHeader(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	events.From(c).TriggerAfterSettle(events.RaceFinished, true)
+	events.From(c).Error("game_over", nil)
+
+	if payload := decodeHeader(t, w, "HX-Trigger-After-Settle"); payload["race-finished"] != true || len(payload) != 1 {
+		t.Errorf("Unexpected after-settle events: %v", payload)
+	}
+	payload := decodeHeader(t, w, "HX-Trigger")
+	if _, ok := payload["server_error_details"]; ok || payload["server_error_code"] != "game_over" {
+		t.Errorf("Unexpected events: %v", payload)
+	}
+	if w.Header().Get("HX-Trigger-After-Swap") != "" {
+		t.Error("No after-swap events were emitted")
+	}
+}
+
+func TestCatalogSchemas(t *testing.T) {
+	for name, spec := range events.Catalog() {
+		if spec.Description == "" || !json.Valid(spec.Schema) {
+			t.Errorf("Event %s needs a description and a valid schema", name)
+		}
+	}
+}
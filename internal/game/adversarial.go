@@ -0,0 +1,91 @@
+package game
+
+import (
+	"context"
+
+	constants "github.com/CodeAndHammer/vortludo/internal/constants"
+	models "github.com/CodeAndHammer/vortludo/internal/models"
+)
+
+// StartAdversarial turns game into an adversarial game, whose word is not
+// fixed up front: each guess is answered so as to keep as many words in play
+// as possible. Only classic single-word games can be played this way, and
+// they cannot be assisted, as there is no first letter to reveal.
+func StartAdversarial(game *models.GameState) {
+	if IsMultiBoard(game) || game.Tournament != nil || game.Race != nil || game.Pack != "" {
+		return
+	}
+	game.Adversarial = &models.AdversarialState{}
+	game.Assisted = false
+}
+
+// ApplyAdversarialGuess scores guess in an adversarial game. The words still
+// in play are split by the feedback guess would get from each, and the
+// largest group is kept; the feedback is that group's. The game's word
+// stands for the group until one word is left and the player finds it.
+func ApplyAdversarialGuess(app *models.App, ctx context.Context, game *models.GameState, guess string) {
+	applyAdversarialGuess(app, ctx, game, NewGuess(guess))
+}
+
+func applyAdversarialGuess(app *models.App, ctx context.Context, game *models.GameState, parsed Guess) {
+	guess := parsed.Word
+	candidates := game.Adversarial.Candidates
+	if candidates == nil {
+		release := holdWords(app)
+		for _, entry := range notWithheld(app, selectableWords(app, game.Pool)) {
+			if WordLen(entry.Word) == constants.WordLength {
+				candidates = append(candidates, entry.Word)
+			}
+		}
+		release()
+	}
+	if len(candidates) > 0 {
+		game.Adversarial.Candidates = largestGroup(guess, candidates)
+		game.SessionWord = game.Adversarial.Candidates[0]
+	}
+	targetWord := game.SessionWord
+	runes, counts := targetLetters(app, targetWord)
+	result := scoreGuess(app, parsed, runes, counts)
+	UpdateGameState(app, ctx, game, guess, targetWord, result, !IsValidWord(app, guess))
+	ReleaseResult(app, result)
+}
+
+// largestGroup returns the words that give guess the most common feedback.
+// Ties go to the feedback that reveals less: fewer letters in place, then
+// fewer letters present. A guess is only ever scored all correct once it is
+// the one word left.
+func largestGroup(guess string, words []string) []string {
+	groups := make(map[int][]string)
+	for _, word := range words {
+		pattern := feedbackPattern(guess, word)
+		groups[pattern] = append(groups[pattern], word)
+	}
+	best, bestScore := -1, 0
+	for pattern, group := range groups {
+		score := revealed(pattern)
+		switch {
+		case best < 0,
+			len(group) > len(groups[best]),
+			len(group) == len(groups[best]) && score < bestScore,
+			len(group) == len(groups[best]) && score == bestScore && pattern < best:
+			best, bestScore = pattern, score
+		}
+	}
+	return groups[best]
+}
+
+// revealed weighs how much a feedback pattern gives away: each letter in
+// place outweighs any number of letters present.
+func revealed(pattern int) int {
+	score := 0
+	for range constants.WordLength {
+		switch pattern % 3 {
+		case 2:
+			score += constants.WordLength + 1
+		case 1:
+			score++
+		}
+		pattern /= 3
+	}
+	return score
+}
@@ -0,0 +1,33 @@
+package game
+
+import (
+	constants "github.com/CodeAndHammer/vortludo/internal/constants"
+	models "github.com/CodeAndHammer/vortludo/internal/models"
+)
+
+// CanAssist reports whether game can be played assisted. Multi-board games
+// have no single first letter to reveal, and tournament games are scored
+// against other players.
+func CanAssist(game *models.GameState) bool {
+	return !IsMultiBoard(game) && game.Tournament == nil
+}
+
+// AssistLetter returns the letter an assisted game reveals: its word's
+// first. Other games reveal none.
+func AssistLetter(game *models.GameState) string {
+	if !game.Assisted || game.SessionWord == "" {
+		return ""
+	}
+	return string(wordRunes(game.SessionWord)[0])
+}
+
+// CheckAssist refuses a guess that does not start with the letter an
+// assisted game revealed: the tile showing it is pre-filled and cannot be
+// typed over.
+func CheckAssist(game *models.GameState, guess string) error {
+	letter := AssistLetter(game)
+	if letter == "" || string(wordRunes(guess)[0]) == letter {
+		return nil
+	}
+	return NewGameError(constants.ErrorCodeAssistLetter).WithDetail("letter", letter).WithDetail("position", 1)
+}
@@ -0,0 +1,51 @@
+package game
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	models "github.com/CodeAndHammer/vortludo/internal/models"
+)
+
+// ArmAutoContinue schedules the next word after game, which just ended.
+// Tournament games are not continued, as each day has only one.
+func ArmAutoContinue(app *models.App, game *models.GameState, now time.Time) {
+	if !game.GameOver || game.Tournament != nil {
+		return
+	}
+	game.ContinueAt = now.Add(app.AutoContinueDelay)
+}
+
+// ContinueDue reports whether game is over and its next word due.
+func ContinueDue(game *models.GameState, now time.Time) bool {
+	return game.GameOver && !game.ContinueAt.IsZero() && !now.Before(game.ContinueAt)
+}
+
+// ContinueGame starts the game after game, of the same kind and pool or
+// pack. A pool game gets words that are neither in completedWords nor the
+// ones game played; a pack game gets one the player has not solved, going by
+// packSolved, the player's progress through game's pack. It reports whether
+// every word of the pool was completed, as CreateNewGameWithCompletedWords
+// does.
+func ContinueGame(app *models.App, ctx context.Context, sessionID string, game *models.GameState, completedWords, packSolved []string) (*models.GameState, bool) {
+	var next *models.GameState
+	var needsReset bool
+	if pack, ok := FindPack(app, game.Pack); ok {
+		return CreatePackGame(app, ctx, sessionID, pack, packSolved), false
+	}
+	if IsMultiBoard(game) {
+		exclude := slices.Concat(completedWords, BoardWords(game))
+		next, needsReset = CreateMultiBoardGame(app, ctx, sessionID, game.Pool, len(game.Boards), exclude)
+	} else {
+		exclude := append(slices.Clone(completedWords), game.SessionWord)
+		next, needsReset = CreateNewGameWithCompletedWords(app, ctx, sessionID, game.Pool, exclude)
+		if game.Race != nil {
+			StartRace(next, app.BotRaceInterval)
+		}
+		if game.Adversarial != nil {
+			StartAdversarial(next)
+		}
+	}
+	return next, needsReset
+}
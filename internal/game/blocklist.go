@@ -0,0 +1,83 @@
+package game
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"strings"
+
+	models "github.com/CodeAndHammer/vortludo/internal/models"
+	util "github.com/CodeAndHammer/vortludo/internal/util"
+)
+
+// LoadBlockedWords reads one word per line, ignoring blank lines and lines
+// starting with '#'. A missing file yields an empty set: the blocklist is optional.
+func LoadBlockedWords(path string) (map[string]struct{}, error) {
+	blocked := make(map[string]struct{})
+	if path == "" {
+		return blocked, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return blocked, nil
+		}
+		return nil, err
+	}
+
+	for line := range strings.Lines(string(data)) {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		blocked[strings.ToUpper(line)] = struct{}{}
+	}
+	return blocked, nil
+}
+
+// ReloadBlockedWords re-reads app.BlocklistPath and swaps in the new set.
+func ReloadBlockedWords(app *models.App) (int, error) {
+	blocked, err := LoadBlockedWords(app.BlocklistPath)
+	if err != nil {
+		return 0, err
+	}
+	app.BlockedMutex.Lock()
+	app.BlockedWordSet = blocked
+	app.BlockedMutex.Unlock()
+	util.LogInfo("Loaded %d blocked words from %s", len(blocked), app.BlocklistPath)
+	return len(blocked), nil
+}
+
+func IsBlockedWord(app *models.App, word string) bool {
+	app.BlockedMutex.RLock()
+	defer app.BlockedMutex.RUnlock()
+	_, ok := app.BlockedWordSet[word]
+	return ok
+}
+
+func BlockedWordCount(app *models.App) int {
+	app.BlockedMutex.RLock()
+	defer app.BlockedMutex.RUnlock()
+	return len(app.BlockedWordSet)
+}
+
+// selectableWords returns the words of pool minus any blocked words.
+func selectableWords(app *models.App, pool string) []models.WordEntry {
+	wordList := poolWords(app, pool)
+	app.BlockedMutex.RLock()
+	defer app.BlockedMutex.RUnlock()
+	if len(app.BlockedWordSet) == 0 {
+		return wordList
+	}
+	words := make([]models.WordEntry, 0, len(wordList))
+	for _, entry := range wordList {
+		if _, blocked := app.BlockedWordSet[entry.Word]; !blocked {
+			words = append(words, entry)
+		}
+	}
+	if len(words) == 0 {
+		util.LogWarn("Every word in the word list is blocked, ignoring blocklist for selection")
+		return wordList
+	}
+	return words
+}
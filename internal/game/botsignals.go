@@ -0,0 +1,59 @@
+package game
+
+import (
+	botdetect "github.com/CodeAndHammer/vortludo/internal/botdetect"
+	constants "github.com/CodeAndHammer/vortludo/internal/constants"
+	models "github.com/CodeAndHammer/vortludo/internal/models"
+)
+
+// maxDecisionAnswers caps the answers a guess is judged against, which
+// keeps judging a game quick. Guesses made with more answers still possible,
+// usually openers, are not judged.
+const maxDecisionAnswers = 200
+
+// BotSignals returns what bot detection looks at in a finished game: the
+// times between its guesses, and how many of the guesses after the first,
+// made while at least three answers were possible, revealed as much about
+// the answer as the best of those answers would have. Multi-board games are
+// judged on their timing only.
+func BotSignals(app *models.App, game *models.GameState) botdetect.Game {
+	var signals botdetect.Game
+	for i := 1; i < len(game.GuessTimes); i++ {
+		signals.Intervals = append(signals.Intervals, game.GuessTimes[i].Sub(game.GuessTimes[i-1]))
+	}
+	if IsMultiBoard(game) {
+		return signals
+	}
+
+	var remaining []string
+	for _, entry := range selectableWords(app, game.Pool) {
+		if WordLen(entry.Word) == constants.WordLength {
+			remaining = append(remaining, entry.Word)
+		}
+	}
+	var counts [243]int
+	for i, guess := range game.GuessHistory {
+		if WordLen(guess) != constants.WordLength {
+			return signals
+		}
+		if i > 0 && len(remaining) >= 3 && len(remaining) <= maxDecisionAnswers {
+			best := 0.0
+			for _, candidate := range remaining {
+				best = max(best, guessEntropy(candidate, remaining, &counts))
+			}
+			signals.Decisions++
+			if guessEntropy(guess, remaining, &counts) >= best-1e-9 {
+				signals.Optimal++
+			}
+		}
+		pattern := feedbackPattern(guess, game.SessionWord)
+		kept := remaining[:0]
+		for _, answer := range remaining {
+			if feedbackPattern(guess, answer) == pattern {
+				kept = append(kept, answer)
+			}
+		}
+		remaining = kept
+	}
+	return signals
+}
@@ -0,0 +1,23 @@
+package game
+
+import (
+	"time"
+
+	constants "github.com/CodeAndHammer/vortludo/internal/constants"
+	models "github.com/CodeAndHammer/vortludo/internal/models"
+)
+
+// CheckCooldown refuses a guess sent less than cooldown after the game's
+// last one, with the milliseconds left to wait. Guesses it lets through,
+// accepted or not, start the next cooldown; refused ones do not, so a client
+// that retries in time gets in.
+func CheckCooldown(game *models.GameState, cooldown time.Duration, now time.Time) error {
+	if cooldown <= 0 {
+		return nil
+	}
+	if wait := game.LastAttempt.Add(cooldown).Sub(now); wait > 0 {
+		return NewGameError(constants.ErrorCodeTooFast).WithDetail("retry_after_ms", (wait + time.Millisecond - 1).Milliseconds())
+	}
+	game.LastAttempt = now
+	return nil
+}
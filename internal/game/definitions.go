@@ -0,0 +1,44 @@
+package game
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	models "github.com/CodeAndHammer/vortludo/internal/models"
+)
+
+// LoadDefinitions reads the dictionary definitions in path, keyed by the
+// normalized word. Definitions are optional, so a missing file yields none.
+func LoadDefinitions(path string) (map[string]models.Definition, error) {
+	definitions := make(map[string]models.Definition)
+	if path == "" {
+		return definitions, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return definitions, nil
+		}
+		return nil, err
+	}
+	var list models.DefinitionList
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("decode %s: %w", path, err)
+	}
+	for _, entry := range list.Definitions {
+		entry.Word = NormalizeWord(entry.Word)
+		if entry.Word != "" && entry.Definition != "" {
+			definitions[entry.Word] = entry
+		}
+	}
+	return definitions, nil
+}
+
+// GetDefinition returns the dictionary definition of word, if there is one.
+func GetDefinition(app *models.App, word string) (models.Definition, bool) {
+	definition, ok := app.Definitions[word]
+	return definition, ok
+}
@@ -0,0 +1,106 @@
+package game
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	constants "github.com/CodeAndHammer/vortludo/internal/constants"
+	models "github.com/CodeAndHammer/vortludo/internal/models"
+	wordset "github.com/CodeAndHammer/vortludo/internal/wordset"
+)
+
+// ParseWordList parses a words.json document of target words and hints.
+func ParseWordList(data []byte) ([]models.WordEntry, error) {
+	var wordList models.WordList
+	if err := json.Unmarshal(data, &wordList); err != nil {
+		return nil, err
+	}
+	if len(wordList.Words) == 0 {
+		return nil, errors.New("word list contains no words")
+	}
+	for i, entry := range wordList.Words {
+		wordList.Words[i].Word = NormalizeWord(entry.Word)
+		if entry.Status != "" && !entry.Retired() {
+			return nil, fmt.Errorf("word %s has unknown status %q", entry.Word, entry.Status)
+		}
+	}
+	if !slices.ContainsFunc(wordList.Words, func(e models.WordEntry) bool { return !e.Retired() }) {
+		return nil, errors.New("word list contains only retired words")
+	}
+	return wordList.Words, nil
+}
+
+// ParseAcceptedWords parses a list of accepted guesses, one per line. Words
+// of the wrong length, however long the line, are skipped.
+func ParseAcceptedWords(data []byte) (map[string]struct{}, error) {
+	accepted := make(map[string]struct{})
+	for line := range bytes.Lines(data) {
+		word := NormalizeWord(string(line))
+		if WordLen(word) == constants.WordLength {
+			accepted[word] = struct{}{}
+		}
+	}
+	return accepted, nil
+}
+
+// SetDictionary swaps in a new list of target words and accepted guesses.
+// Target words are always accepted. Retired words are left out of every
+// pool, so they are never drawn, but stay target words for the games that
+// have them. Games in progress keep their words.
+func SetDictionary(app *models.App, words []models.WordEntry, accepted map[string]struct{}) {
+	acceptedWords := slices.AppendSeq(make([]string, 0, len(accepted)+len(words)), maps.Keys(accepted))
+	// The entries are decoded in a copy, which readers of the dictionary
+	// being replaced may still be using.
+	words = slices.Clone(words)
+	wordSet := make(map[string]struct{}, len(words))
+	index := make(map[string]int, len(words))
+	stages := make(map[string][]string)
+	pools := make(map[string][]int)
+	for i, entry := range words {
+		words[i].Runes = wordRunes(entry.Word)
+		words[i].Counts = models.CountLetters(words[i].Runes)
+		wordSet[entry.Word] = struct{}{}
+		if _, ok := index[entry.Word]; !ok {
+			index[entry.Word] = i
+		}
+		acceptedWords = append(acceptedWords, entry.Word)
+		if len(entry.Hints) > 0 {
+			stages[entry.Word] = entry.Hints
+		}
+		if entry.Retired() {
+			continue
+		}
+		entryPools := entry.Pools
+		if len(entryPools) == 0 {
+			entryPools = []string{constants.PoolClassic}
+		}
+		for _, pool := range slices.Compact(slices.Sorted(slices.Values(entryPools))) {
+			pools[pool] = append(pools[pool], i)
+		}
+	}
+	app.SetDictionary(&models.Dictionary{
+		Words:      words,
+		WordSet:    wordSet,
+		Accepted:   wordset.New(acceptedWords),
+		Hints:      BuildHintMap(words),
+		HintStages: stages,
+		Pools:      pools,
+		Index:      index,
+	})
+}
+
+// DictionarySize returns the number of target words and accepted guesses.
+func DictionarySize(app *models.App) (words, accepted int) {
+	dict := app.Dictionary()
+	return len(dict.Words), dict.Accepted.Len()
+}
+
+// LookupHint returns the hint of a target word.
+func LookupHint(app *models.App, word string) (string, bool) {
+	hint, ok := app.Dictionary().Hints[word]
+	return hint, ok
+}
@@ -0,0 +1,36 @@
+package game
+
+import (
+	"slices"
+	"strings"
+
+	constants "github.com/CodeAndHammer/vortludo/internal/constants"
+	models "github.com/CodeAndHammer/vortludo/internal/models"
+)
+
+// rareLetters are the letters least often found in target words.
+const rareLetters = "JQXZ"
+
+// IsHardWord reports whether word is hard to guess: by how seldom players
+// solve it once it was played often enough to tell, and until then by its
+// letters, as words with a repeated or a rare letter are.
+func IsHardWord(app *models.App, word string) bool {
+	if rate, plays := app.Analytics.SolveRate(word); plays >= constants.HardWordMinPlays {
+		return rate <= constants.HardWordSolveRate
+	}
+	letters := []rune(word)
+	for i, r := range letters {
+		if strings.ContainsRune(rareLetters, r) || slices.Contains(letters[i+1:], r) {
+			return true
+		}
+	}
+	return false
+}
+
+// WordDifficulty rates word for scoring: 2 if it is hard, 1 otherwise.
+func WordDifficulty(app *models.App, word string) int {
+	if IsHardWord(app, word) {
+		return 2
+	}
+	return 1
+}
@@ -0,0 +1,50 @@
+package game
+
+import (
+	"unicode"
+	"unicode/utf8"
+
+	constants "github.com/CodeAndHammer/vortludo/internal/constants"
+	models "github.com/CodeAndHammer/vortludo/internal/models"
+)
+
+// TypeKey applies one key press to the game's draft row, as the on-screen
+// keyboard does in the browser: a letter is added while the row has room,
+// Backspace takes the last letter off and Enter reports true, leaving the
+// draft for the caller to guess.
+func TypeKey(game *models.GameState, key string) (bool, error) {
+	if game.GameOver {
+		return false, NewGameError(constants.ErrorCodeGameOver)
+	}
+	switch key = NormalizeWord(key); key {
+	case constants.KeyEnter:
+		return true, nil
+	case constants.KeyBackspace:
+		if _, size := utf8.DecodeLastRuneInString(game.Draft); size > 0 {
+			game.Draft = game.Draft[:len(game.Draft)-size]
+		}
+		return false, nil
+	}
+	letter, size := utf8.DecodeRuneInString(key)
+	if size == 0 || size != len(key) || !unicode.IsLetter(letter) {
+		return false, NewGameError(constants.ErrorCodeInvalidKey).WithDetail("key", key)
+	}
+	if draft := NormalizeWord(game.Draft + key); WordLen(draft) <= constants.WordLength {
+		game.Draft = draft
+	}
+	return false, nil
+}
+
+// draftTiles spreads draft over the tiles of a row.
+func draftTiles(draft string) [constants.WordLength]string {
+	var tiles [constants.WordLength]string
+	i := 0
+	for _, r := range draft {
+		if i == len(tiles) {
+			break
+		}
+		tiles[i] = string(r)
+		i++
+	}
+	return tiles
+}
@@ -0,0 +1,150 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+
+	constants "github.com/CodeAndHammer/vortludo/internal/constants"
+)
+
+// GameError is a client-facing error. Code is the stable identifier shared
+// with the client, Status the HTTP status used by the JSON API, and MessageKey
+// the key the UI looks up to display a localised message.
+type GameError struct {
+	Code       string         `json:"code"`
+	Status     int            `json:"-"`
+	MessageKey string         `json:"message_key"`
+	Details    map[string]any `json:"details,omitempty"`
+}
+
+func (e *GameError) Error() string {
+	return e.Code
+}
+
+var gameErrorStatus = map[string]int{
+	constants.ErrorCodeGameOver:        http.StatusConflict,
+	constants.ErrorCodeGameInProgress:  http.StatusConflict,
+	constants.ErrorCodeInvalidLength:   http.StatusUnprocessableEntity,
+	constants.ErrorCodeNoMoreGuesses:   http.StatusConflict,
+	constants.ErrorCodeNotInWordList:   http.StatusUnprocessableEntity,
+	constants.ErrorCodeWordNotAccepted: http.StatusUnprocessableEntity,
+	constants.ErrorCodeDuplicateGuess:  http.StatusConflict,
+	constants.ErrorCodeWordBlocked:     http.StatusUnprocessableEntity,
+	constants.ErrorCodeHardMode:        http.StatusUnprocessableEntity,
+	constants.ErrorCodeAssistLetter:    http.StatusUnprocessableEntity,
+	constants.ErrorCodeInvalidSettings: http.StatusBadRequest,
+	constants.ErrorCodeHintUnavailable: http.StatusConflict,
+	constants.ErrorCodeHintsExhausted:  http.StatusTooManyRequests,
+	constants.ErrorCodeSessionExpired:  http.StatusConflict,
+	constants.ErrorCodeOutOfSync:       http.StatusConflict,
+	constants.ErrorCodeInvalidKey:      http.StatusBadRequest,
+
+	constants.ErrorCodeInvalidCompletedWords: http.StatusBadRequest,
+
+	constants.ErrorCodeTournamentPlayed: http.StatusConflict,
+	constants.ErrorCodeUnknownPool:      http.StatusBadRequest,
+	constants.ErrorCodeUnknownPack:      http.StatusBadRequest,
+	constants.ErrorCodeValidateDisabled: http.StatusNotFound,
+
+	constants.ErrorCodeInvalidAccount:     http.StatusBadRequest,
+	constants.ErrorCodeAccountExists:      http.StatusConflict,
+	constants.ErrorCodeInvalidCredentials: http.StatusUnauthorized,
+	constants.ErrorCodeInvalidLink:        http.StatusBadRequest,
+	constants.ErrorCodeOIDCFailed:         http.StatusBadRequest,
+	constants.ErrorCodeConsentRequired:    http.StatusForbidden,
+	constants.ErrorCodeInvalidConsent:     http.StatusBadRequest,
+
+	constants.ErrorCodeRateLimited: http.StatusTooManyRequests,
+	constants.ErrorCodeTooFast:     http.StatusTooManyRequests,
+	constants.ErrorCodeBanned:      http.StatusTooManyRequests,
+	constants.ErrorCodeServerFull:  http.StatusServiceUnavailable,
+
+	constants.ErrorCodePayloadTooLarge: http.StatusRequestEntityTooLarge,
+	constants.ErrorCodeTimeout:         http.StatusServiceUnavailable,
+
+	constants.ErrorCodeNotFound:         http.StatusNotFound,
+	constants.ErrorCodeMethodNotAllowed: http.StatusMethodNotAllowed,
+	constants.ErrorCodeInternal:         http.StatusInternalServerError,
+}
+
+// errorMessages are the messages shown on the page for the codes a player
+// can run into, for browsers without JavaScript; the client shows its own.
+var errorMessages = map[string]string{
+	constants.ErrorCodeGameOver:        "Game is already over! Start a new game!",
+	constants.ErrorCodeInvalidLength:   fmt.Sprintf("Word must be %d letters long!", constants.WordLength),
+	constants.ErrorCodeNoMoreGuesses:   "No more guesses allowed! Start a new game!",
+	constants.ErrorCodeNotInWordList:   "Word not recognised!",
+	constants.ErrorCodeWordNotAccepted: "Word not accepted. Try another word!",
+	constants.ErrorCodeDuplicateGuess:  "You already guessed that word!",
+	constants.ErrorCodeWordBlocked:     "That word isn't allowed. Try another!",
+	constants.ErrorCodeHardMode:        "Hard mode: use every revealed hint!",
+	constants.ErrorCodeAssistLetter:    "Assisted: keep the revealed first letter!",
+	constants.ErrorCodeHintUnavailable: "That hint is not available right now.",
+	constants.ErrorCodeHintsExhausted:  "No hints of that kind left for this game!",
+	constants.ErrorCodeSessionExpired:  "Your previous game expired. Here is a new word!",
+	constants.ErrorCodeOutOfSync:       "Your board was out of date and has been refreshed.",
+	constants.ErrorCodeInvalidKey:      "That key cannot be typed.",
+
+	constants.ErrorCodeTournamentPlayed: "You've already played today's tournament word!",
+	constants.ErrorCodeUnknownPool:      "That word pool does not exist. Here is a classic word!",
+	constants.ErrorCodeUnknownPack:      "That puzzle pack does not exist. Here is a classic word!",
+
+	constants.ErrorCodeTooFast:  "Not so fast! Wait a moment before your next guess.",
+	constants.ErrorCodeTimeout:  "The server took too long to answer. Please try again!",
+	constants.ErrorCodeInternal: "Something went wrong on our side. Please try again!",
+}
+
+// IsErrorCode reports whether code is one of the codes in the constants
+// package.
+func IsErrorCode(code string) bool {
+	_, ok := gameErrorStatus[code]
+	return ok
+}
+
+// ErrorMessage returns the message shown for code.
+func ErrorMessage(code string) string {
+	if message, ok := errorMessages[code]; ok {
+		return message
+	}
+	return "An unexpected error occurred."
+}
+
+// NewGameError builds the error for a code from the constants package.
+// Unknown codes are reported as internal errors.
+func NewGameError(code string) *GameError {
+	status, ok := gameErrorStatus[code]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+	return &GameError{
+		Code:       code,
+		Status:     status,
+		MessageKey: "error." + code,
+	}
+}
+
+// WithDetail returns a copy of e with key set to value in Details.
+func (e *GameError) WithDetail(key string, value any) *GameError {
+	cp := *e
+	cp.Details = maps.Clone(e.Details)
+	if cp.Details == nil {
+		cp.Details = make(map[string]any)
+	}
+	cp.Details[key] = value
+	return &cp
+}
+
+// AsGameError unwraps err to a *GameError, wrapping anything else as an
+// internal error so callers always have a code to render.
+func AsGameError(err error) *GameError {
+	if err == nil {
+		return nil
+	}
+	var gameErr *GameError
+	if errors.As(err, &gameErr) {
+		return gameErr
+	}
+	return NewGameError(constants.ErrorCodeInternal)
+}
@@ -2,8 +2,7 @@ package game
 
 import (
 	"context"
-	"crypto/rand"
-	"math/big"
+	"math/rand/v2"
 	"slices"
 	"time"
 
@@ -14,91 +13,79 @@ import (
 )
 
 func GetRandomWordEntry(app *models.App, ctx context.Context) models.WordEntry {
-	reqID, _ := ctx.Value(constants.RequestIDKey).(string)
-
-	select {
-	case <-ctx.Done():
-		if reqID != "" {
-			util.LogWarn("[request_id=%v] GetRandomWordEntry cancelled: %v", reqID, ctx.Err())
-		} else {
-			util.LogWarn("GetRandomWordEntry cancelled: %v", ctx.Err())
-		}
-		return app.WordList[0]
-	default:
-	}
-
-	n, err := rand.Int(rand.Reader, big.NewInt(int64(len(app.WordList))))
-	if err != nil {
-		if reqID != "" {
-			util.LogWarn("[request_id=%v] Error generating random number: %v, using fallback", reqID, err)
-		} else {
-			util.LogWarn("Error generating random number: %v, using fallback", err)
-		}
-		return app.WordList[0]
-	}
-
-	if reqID != "" {
-		util.LogInfo("[request_id=%v] Selected random word index: %d", reqID, n.Int64())
-	}
-	return app.WordList[n.Int64()]
+	defer holdWords(app)()
+	entry, _ := pickWordEntry(app, ctx, seededRand(NewSeed()), "", nil)
+	return entry
 }
 
 func GetRandomWordEntryExcluding(app *models.App, ctx context.Context, completedWords []string) (models.WordEntry, bool) {
-	reqID, _ := ctx.Value(constants.RequestIDKey).(string)
-
-	if len(completedWords) == 0 {
-		return GetRandomWordEntry(app, ctx), false
-	}
+	defer holdWords(app)()
+	return pickWordEntry(app, ctx, seededRand(NewSeed()), "", completedWords)
+}
 
-	availableWords := lo.Filter(app.WordList, func(entry models.WordEntry, _ int) bool {
-		return !slices.Contains(completedWords, entry.Word)
-	})
+// pickWordEntry draws a word of pool that is not in completedWords from rng.
+// Given the same rng seed, word list and completed words it picks the same
+// word, which is what makes a game reproducible from its seed. It reports
+// whether every word of the pool was completed, in which case any of them
+// may be picked. Words being removed are not picked; the caller holds the
+// words (see holdWords).
+func pickWordEntry(app *models.App, ctx context.Context, rng *rand.Rand, pool string, completedWords []string) (models.WordEntry, bool) {
+	return pickFrom(ctx, rng, notWithheld(app, selectableWords(app, pool)), completedWords)
+}
 
-	if len(availableWords) == 0 {
-		if reqID != "" {
-			util.LogInfo("[request_id=%v] All words completed, reset needed. Total words: %d, Completed: %d", reqID, len(app.WordList), len(completedWords))
-		} else {
-			util.LogInfo("All words completed, reset needed. Total words: %d, Completed: %d", len(app.WordList), len(completedWords))
-		}
-		return GetRandomWordEntry(app, ctx), true
-	}
+// pickFrom draws a word of words, which must not be empty, as pickWordEntry
+// does.
+func pickFrom(ctx context.Context, rng *rand.Rand, words []models.WordEntry, completedWords []string) (models.WordEntry, bool) {
+	reqID, _ := ctx.Value(constants.RequestIDKey).(string)
 
+	// A request past its deadline gets the first word it has not completed
+	// rather than waiting for the filter.
 	select {
 	case <-ctx.Done():
 		if reqID != "" {
-			util.LogWarn("[request_id=%v] GetRandomWordEntryExcluding cancelled: %v", reqID, ctx.Err())
+			util.LogWarn("[request_id=%v] Word selection cancelled: %v", reqID, ctx.Err())
 		} else {
-			util.LogWarn("GetRandomWordEntryExcluding cancelled: %v", ctx.Err())
+			util.LogWarn("Word selection cancelled: %v", ctx.Err())
 		}
-		return availableWords[0], false
+		for _, entry := range words {
+			if !slices.Contains(completedWords, entry.Word) {
+				return entry, false
+			}
+		}
+		return words[0], true
 	default:
 	}
 
-	n, err := rand.Int(rand.Reader, big.NewInt(int64(len(availableWords))))
-	if err != nil {
-		if reqID != "" {
-			util.LogWarn("[request_id=%v] Error generating random number for filtered words: %v, using fallback", reqID, err)
+	total := len(words)
+	needsReset := false
+	if len(completedWords) > 0 {
+		availableWords := lo.Filter(words, func(entry models.WordEntry, _ int) bool {
+			return !slices.Contains(completedWords, entry.Word)
+		})
+		if len(availableWords) == 0 {
+			if reqID != "" {
+				util.LogInfo("[request_id=%v] All words completed, reset needed. Total words: %d, Completed: %d", reqID, total, len(completedWords))
+			} else {
+				util.LogInfo("All words completed, reset needed. Total words: %d, Completed: %d", total, len(completedWords))
+			}
+			needsReset = true
 		} else {
-			util.LogWarn("Error generating random number for filtered words: %v, using fallback", err)
+			words = availableWords
 		}
-		return availableWords[0], false
 	}
 
-	selected := availableWords[n.Int64()]
+	n := rng.IntN(len(words))
 	if reqID != "" {
-		util.LogInfo("[request_id=%v] Selected word from %d available options (excluding %d completed): %s", reqID, len(availableWords), len(completedWords), selected.Word)
-	} else {
-		util.LogInfo("Selected word from %d available options (excluding %d completed): %s", len(availableWords), len(completedWords), selected.Word)
+		util.LogInfo("[request_id=%v] Selected word index %d of %d (excluding %d completed)", reqID, n, len(words), len(completedWords))
 	}
-
-	return selected, false
+	return words[n], needsReset
 }
 
 func GetHintForWord(app *models.App, wordValue string) string {
 	if wordValue == "" {
 		return ""
 	}
-	hint, ok := app.HintMap[wordValue]
+	hint, ok := LookupHint(app, wordValue)
 	if ok {
 		return hint
 	}
@@ -121,16 +108,42 @@ func GetTargetWord(app *models.App, ctx context.Context, game *models.GameState)
 	return game.SessionWord
 }
 
+// ApplyGuess scores guess and advances the game, whichever kind it is.
+func ApplyGuess(app *models.App, ctx context.Context, game *models.GameState, guess string) {
+	ApplyParsedGuess(app, ctx, game, NewGuess(guess))
+}
+
+// ApplyParsedGuess is ApplyGuess for a guess already decoded, as the guess
+// handler has it. Any draft typed on the server is done with.
+func ApplyParsedGuess(app *models.App, ctx context.Context, game *models.GameState, guess Guess) {
+	game.Draft = ""
+	if IsMultiBoard(game) {
+		applyMultiBoardGuess(app, ctx, game, guess)
+		return
+	}
+	if game.Adversarial != nil {
+		applyAdversarialGuess(app, ctx, game, guess)
+		return
+	}
+	targetWord := GetTargetWord(app, ctx, game)
+	isInvalid := !IsValidWord(app, guess.Word)
+	runes, counts := targetLetters(app, targetWord)
+	result := scoreGuess(app, guess, runes, counts)
+	UpdateGameState(app, ctx, game, guess.Word, targetWord, result, isInvalid)
+	ReleaseResult(app, result)
+}
+
 func UpdateGameState(app *models.App, ctx context.Context, game *models.GameState, guess, targetWord string, result []models.GuessResult, isInvalid bool) {
 	reqID, _ := ctx.Value(constants.RequestIDKey).(string)
 
-	if game.CurrentRow >= constants.MaxGuesses {
+	if game.CurrentRow >= MaxRows(game) {
 		return
 	}
 
-	game.Guesses[game.CurrentRow] = result
+	game.Guesses.Set(game.CurrentRow, result)
 	game.GuessHistory = append(game.GuessHistory, guess)
 	game.LastAccessTime = time.Now()
+	game.Version++
 
 	if !isInvalid && guess == targetWord {
 		game.Won = true
@@ -143,7 +156,7 @@ func UpdateGameState(app *models.App, ctx context.Context, game *models.GameStat
 	} else {
 		game.CurrentRow++
 
-		if game.CurrentRow >= constants.MaxGuesses {
+		if game.CurrentRow >= MaxRows(game) {
 			game.GameOver = true
 			if reqID != "" {
 				util.LogInfo("[request_id=%v] Player lost. Target word was: %s", reqID, targetWord)
@@ -158,88 +171,148 @@ func UpdateGameState(app *models.App, ctx context.Context, game *models.GameStat
 	}
 }
 
-func CheckGuess(guess, target string, app *models.App) []models.GuessResult {
-	result := make([]models.GuessResult, constants.WordLength)
-	var targetCopy []rune
-	var pooledBuf []rune
-	usedPool := false
-
-	if app.RuneBufPool != nil {
-		if v := app.RuneBufPool.Get(); v != nil {
-			if ptr, ok := v.(*[]rune); ok && ptr != nil {
-				pooledBuf = *ptr
-				targetCopy = pooledBuf[:constants.WordLength]
-				copy(targetCopy, []rune(target))
-				usedPool = true
-			} else {
-				targetCopy = []rune(target)
+// BuildBoard converts the game's guesses into rows for the game-board
+// template. newRow is the index of the row just revealed, or -1. Rows given
+// up for hints are the last ones.
+func BuildBoard(gameState *models.GameState, newRow int) []models.BoardRow {
+	rows := buildRows(gameState.Guesses, gameState.CurrentRow, !gameState.GameOver, newRow, gameState.Draft)
+	for i := max(MaxRows(gameState), 0); i < len(rows); i++ {
+		rows[i].IsSpent = true
+	}
+	if letter := AssistLetter(gameState); letter != "" {
+		for i := range rows {
+			if rows[i].IsCurrent {
+				rows[i].Assist = letter
 			}
-		} else {
-			targetCopy = []rune(target)
 		}
-	} else {
-		targetCopy = []rune(target)
 	}
+	return rows
+}
 
-	for i := range constants.WordLength {
-		if guess[i] == target[i] {
-			result[i] = models.GuessResult{Letter: string(guess[i]), Status: constants.GuessStatusCorrect}
-			targetCopy[i] = ' '
+func buildRows(guessRows models.Rows, currentRow int, active bool, newRow int, draft string) []models.BoardRow {
+	rows := make([]models.BoardRow, guessRows.Len())
+	for i := range rows {
+		guesses := guessRows.Row(i)
+		row := models.BoardRow{
+			Index:     i,
+			Tiles:     make([]models.BoardTile, len(guesses)),
+			IsCurrent: active && i == currentRow,
+			IsNewRow:  i == newRow,
 		}
+		if row.IsCurrent {
+			row.Draft = draftTiles(draft)
+		}
+		for j, guess := range guesses {
+			tile := models.BoardTile{Letter: guess.Letter, Status: guess.Status, RevealOrder: j}
+			if row.IsNewRow {
+				tile.RevealDelayMs = j * constants.RevealStaggerMs
+			}
+			row.Tiles[j] = tile
+		}
+		rows[i] = row
 	}
+	return rows
+}
 
-	for i := range constants.WordLength {
-		if result[i].Status == "" {
-			letter := string(guess[i])
-			result[i].Letter = letter
-
-			found := false
-			for j := range constants.WordLength {
-				if targetCopy[j] == rune(guess[i]) {
-					result[i].Status = constants.GuessStatusPresent
-					targetCopy[j] = ' '
-					found = true
-					break
-				}
-			}
+// CheckGuess scores guess against target. Words are compared rune by rune,
+// so a letter such as Ĉ counts as one letter however many bytes it takes.
+// Each tile's letter is a slice of guess, and the result row comes from
+// app.ResultPool when there is one, so scoring allocates nothing once the
+// pools are warm. A caller done with the result may hand it back with
+// ReleaseResult.
+func CheckGuess(guess, target string, app *models.App) []models.GuessResult {
+	runes := wordRunes(target)
+	return scoreGuess(app, NewGuess(guess), runes, models.CountLetters(runes))
+}
 
-			if !found {
-				result[i].Status = constants.GuessStatusAbsent
-			}
+// targetLetters returns the letters of target and their counts, as worked
+// out when the dictionary was set if it is a target word. The guess path
+// looks them up rather than decoding and counting the game's word again for
+// every guess.
+func targetLetters(app *models.App, target string) (models.WordRunes, models.LetterCounts) {
+	if entry, ok := app.Dictionary().Entry(target); ok {
+		return entry.Runes, entry.Counts
+	}
+	runes := wordRunes(target)
+	return runes, models.CountLetters(runes)
+}
+
+// scoreGuess scores guess against a target's letters. Letters in place are
+// taken off the target's counts first; each other letter of the guess is
+// present while its count lasts, so a letter guessed twice is only marked
+// twice if the target has it twice.
+func scoreGuess(app *models.App, guess Guess, target models.WordRunes, counts models.LetterCounts) []models.GuessResult {
+	result := newResult(app)
+	remaining := counts.Counts
+
+	for i, r := range guess.Runes {
+		result[i] = models.GuessResult{Letter: guess.letters[i]}
+		if r == target[i] {
+			result[i].Status = constants.GuessStatusCorrect
+			remaining[counts.Index(r)]--
 		}
 	}
 
-	if usedPool {
-		for i := range pooledBuf {
-			pooledBuf[i] = 0
+	for i, r := range guess.Runes {
+		if result[i].Status != "" {
+			continue
 		}
-		if app.RuneBufPool != nil {
-			buf := pooledBuf
-			app.RuneBufPool.Put(&buf)
+		result[i].Status = constants.GuessStatusAbsent
+		if j := counts.Index(r); j >= 0 && remaining[j] > 0 {
+			result[i].Status = constants.GuessStatusPresent
+			remaining[j]--
 		}
 	}
 
 	return result
 }
 
+// newResult returns a row of WordLength tiles, from app.ResultPool if it has
+// one.
+func newResult(app *models.App) []models.GuessResult {
+	if app.ResultPool != nil {
+		if row, ok := app.ResultPool.Get().(*[constants.WordLength]models.GuessResult); ok && row != nil {
+			return row[:]
+		}
+	}
+	return make([]models.GuessResult, constants.WordLength)
+}
+
+// ReleaseResult hands a row scored by CheckGuess back to app.ResultPool. The
+// row must not be used afterwards; Rows.Set copies what it keeps, so a row
+// may be released as soon as it is stored.
+func ReleaseResult(app *models.App, result []models.GuessResult) {
+	if app.ResultPool == nil || len(result) != constants.WordLength {
+		return
+	}
+	row := (*[constants.WordLength]models.GuessResult)(result)
+	*row = [constants.WordLength]models.GuessResult{}
+	app.ResultPool.Put(row)
+}
+
 func IsValidWord(app *models.App, word string) bool {
-	_, ok := app.WordSet[word]
+	_, ok := app.Dictionary().WordSet[word]
 	return ok
 }
 
 func IsAcceptedWord(app *models.App, word string) bool {
-	_, ok := app.AcceptedWordSet[word]
-	return ok
+	return app.Dictionary().Accepted.Contains(word)
+}
+
+// IsRetiredWord reports whether word is a target word retired from play.
+func IsRetiredWord(app *models.App, word string) bool {
+	entry, ok := app.Dictionary().Entry(word)
+	return ok && entry.Retired()
 }
 
 func CreateNewGame(app *models.App, ctx context.Context, sessionID string) *models.GameState {
-	selectedEntry := GetRandomWordEntry(app, ctx)
-	util.LogInfo("New game created for session %s with word: %s (hint: %s)", sessionID, selectedEntry.Word, selectedEntry.Hint)
-	guesses := lo.Times(constants.MaxGuesses, func(_ int) []models.GuessResult {
-		return lo.Times(constants.WordLength, func(_ int) models.GuessResult { return models.GuessResult{} })
-	})
+	defer holdWords(app)()
+	seed := NewSeed()
+	selectedEntry, _ := pickWordEntry(app, ctx, seededRand(seed), "", nil)
 	game := &models.GameState{
-		Guesses:        guesses,
+		ID:             NewGameID(),
+		Seed:           seed,
+		Guesses:        models.NewRows(constants.MaxGuesses),
 		CurrentRow:     0,
 		GameOver:       false,
 		Won:            false,
@@ -248,20 +321,83 @@ func CreateNewGame(app *models.App, ctx context.Context, sessionID string) *mode
 		GuessHistory:   []string{},
 		LastAccessTime: time.Now(),
 	}
-	app.GameSessions[sessionID] = game
+	util.LogInfo("New game %s created for session %s with word: %s (hint: %s)", game.ID, sessionID, selectedEntry.Word, selectedEntry.Hint)
+	SaveNewGame(app, sessionID, game)
 	return game
 }
 
-func CreateNewGameWithCompletedWords(app *models.App, ctx context.Context, sessionID string, completedWords []string) (*models.GameState, bool) {
-	selectedEntry, needsReset := GetRandomWordEntryExcluding(app, ctx, completedWords)
-	util.LogInfo("New game created for session %s with word: %s (hint: %s, completed words: %d, needs reset: %v)",
-		sessionID, selectedEntry.Word, selectedEntry.Hint, len(completedWords), needsReset)
+// SaveNewGame stores game as the session's game, numbered after the game it
+// replaces. The session's settings decide whether it is assisted, where the
+// game allows it (see CanAssist), and whether it scores points, continuing
+// the run of the game it replaces.
+func SaveNewGame(app *models.App, sessionID string, game *models.GameState) {
+	shard := app.Sessions.Shard(sessionID)
+	shard.Lock()
+	defer shard.Unlock()
+	settings, hasSettings := shard.Settings[sessionID]
+	if hasSettings && settings.Assisted && CanAssist(game) {
+		game.Assisted = true
+	}
+	game.Scored = hasSettings && settings.Points
+	game.Number = 1
+	if previous, ok := shard.Games[sessionID]; ok {
+		game.Number = previous.Number + 1
+		continueRun(game, previous)
+	}
+	shard.PutGame(sessionID, game)
+}
 
-	guesses := lo.Times(constants.MaxGuesses, func(_ int) []models.GuessResult {
-		return lo.Times(constants.WordLength, func(_ int) models.GuessResult { return models.GuessResult{} })
-	})
+// RetryGame starts game over with the same words and seed, of the same
+// kind, and stores it for the session. It returns nil, storing nothing, when
+// a word of game is no longer a target word or is being removed.
+func RetryGame(app *models.App, sessionID string, game *models.GameState) *models.GameState {
+	defer holdWords(app)()
+	words := []string{game.SessionWord}
+	if IsMultiBoard(game) {
+		words = BoardWords(game)
+	}
+	for _, word := range words {
+		if !IsValidWord(app, word) || withheld(app, word) {
+			return nil
+		}
+	}
+	var retry *models.GameState
+	if IsMultiBoard(game) {
+		retry = NewMultiBoardGame(words)
+		retry.Pool = game.Pool
+	} else {
+		retry = &models.GameState{
+			ID:             NewGameID(),
+			Guesses:        models.NewRows(constants.MaxGuesses),
+			SessionWord:    game.SessionWord,
+			GuessHistory:   []string{},
+			LastAccessTime: time.Now(),
+			Pool:           game.Pool,
+			Pack:           game.Pack,
+		}
+		if game.Race != nil {
+			StartRace(retry, app.BotRaceInterval)
+		}
+		if game.Adversarial != nil {
+			StartAdversarial(retry)
+		}
+	}
+	retry.Seed = game.Seed
+	SaveNewGame(app, sessionID, retry)
+	return retry
+}
+
+// CreateNewGameWithCompletedWords starts a game with a word of pool that is
+// not in completedWords. It reports whether every word of the pool was
+// completed.
+func CreateNewGameWithCompletedWords(app *models.App, ctx context.Context, sessionID, pool string, completedWords []string) (*models.GameState, bool) {
+	defer holdWords(app)()
+	seed := NewSeed()
+	selectedEntry, needsReset := pickWordEntry(app, ctx, seededRand(seed), pool, completedWords)
 	game := &models.GameState{
-		Guesses:        guesses,
+		ID:             NewGameID(),
+		Seed:           seed,
+		Guesses:        models.NewRows(constants.MaxGuesses),
 		CurrentRow:     0,
 		GameOver:       false,
 		Won:            false,
@@ -269,7 +405,10 @@ func CreateNewGameWithCompletedWords(app *models.App, ctx context.Context, sessi
 		SessionWord:    selectedEntry.Word,
 		GuessHistory:   []string{},
 		LastAccessTime: time.Now(),
+		Pool:           poolName(pool),
 	}
-	app.GameSessions[sessionID] = game
+	util.LogInfo("New game %s created for session %s with word: %s (hint: %s, pool: %s, completed words: %d, needs reset: %v)",
+		game.ID, sessionID, selectedEntry.Word, selectedEntry.Hint, pool, len(completedWords), needsReset)
+	SaveNewGame(app, sessionID, game)
 	return game, needsReset
 }
@@ -0,0 +1,103 @@
+package game
+
+import (
+	"slices"
+
+	constants "github.com/CodeAndHammer/vortludo/internal/constants"
+	keyboard "github.com/CodeAndHammer/vortludo/internal/keyboard"
+	models "github.com/CodeAndHammer/vortludo/internal/models"
+)
+
+// GuessTiles returns the scored tiles of the guess in row. On a multi-board
+// game each tile takes its best status across the boards the guess was
+// scored on.
+func GuessTiles(game *models.GameState, row int) []models.GuessResult {
+	if !IsMultiBoard(game) {
+		return game.Guesses.Row(row)
+	}
+	var tiles []models.GuessResult
+	for _, board := range game.Boards {
+		if row >= board.Guesses.Len() || board.Guesses.Words[row] == "" {
+			continue
+		}
+		boardTiles := board.Guesses.Row(row)
+		if tiles == nil {
+			tiles = boardTiles
+			continue
+		}
+		for i, tile := range boardTiles {
+			if statusRank(tile.Status) > statusRank(tiles[i].Status) {
+				tiles[i].Status = tile.Status
+			}
+		}
+	}
+	return tiles
+}
+
+func statusRank(status string) int {
+	switch status {
+	case constants.GuessStatusCorrect:
+		return 3
+	case constants.GuessStatusPresent:
+		return 2
+	case constants.GuessStatusAbsent:
+		return 1
+	}
+	return 0
+}
+
+// BuildHeatmap lays the heatmap out on the player's keyboard. Letters the
+// layout has no key for, such as Esperanto's, get a row of their own.
+func BuildHeatmap(heatmap models.Heatmap, layout string) models.HeatmapView {
+	keys := keyboard.Lookup(layout)
+	var extra []string
+	for letter := range heatmap.Letters {
+		if !keys.Has(letter) {
+			extra = append(extra, letter)
+		}
+	}
+	slices.Sort(extra)
+
+	maxCount := 0
+	for _, count := range heatmap.Letters {
+		maxCount = max(maxCount, count)
+	}
+	view := models.HeatmapView{Guesses: heatmap.Guesses}
+	addRow := func(letters []string) {
+		keys := make([]models.HeatKey, len(letters))
+		for i, letter := range letters {
+			count := heatmap.Letters[letter]
+			keys[i] = models.HeatKey{Letter: letter, Count: count, Level: heatLevel(count, maxCount)}
+		}
+		view.Rows = append(view.Rows, keys)
+	}
+	for _, row := range keys.Rows {
+		addRow(row)
+	}
+	if len(extra) > 0 {
+		addRow(extra)
+	}
+
+	maxMisses := slices.Max(append([]int{0}, heatmap.PositionMisses...))
+	for i := range constants.WordLength {
+		position := models.HeatPosition{Number: i + 1}
+		if i < len(heatmap.PositionMisses) {
+			position.Misses = heatmap.PositionMisses[i]
+		}
+		if heatmap.Guesses > 0 {
+			position.Rate = position.Misses * 100 / heatmap.Guesses
+		}
+		position.Level = heatLevel(position.Misses, maxMisses)
+		view.Positions = append(view.Positions, position)
+	}
+	return view
+}
+
+// heatLevel maps count onto 1..HeatLevels-1 relative to the largest count;
+// only a zero count gets level 0.
+func heatLevel(count, maxCount int) int {
+	if count <= 0 || maxCount <= 0 {
+		return 0
+	}
+	return (count*(constants.HeatLevels-1) + maxCount - 1) / maxCount
+}
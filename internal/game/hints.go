@@ -0,0 +1,84 @@
+package game
+
+import (
+	"fmt"
+	"time"
+
+	constants "github.com/CodeAndHammer/vortludo/internal/constants"
+	models "github.com/CodeAndHammer/vortludo/internal/models"
+)
+
+// HintStages returns the staged hints of a target word, from the least to
+// the most revealing. Words without hints of their own are given ones about
+// their first and last letters.
+func HintStages(app *models.App, word string) []string {
+	if word == "" {
+		return nil
+	}
+	if stages, ok := app.Dictionary().HintStages[word]; ok {
+		return stages
+	}
+	letters := wordRunes(word)
+	return []string{
+		fmt.Sprintf("It starts with %c.", letters[0]),
+		fmt.Sprintf("It ends with %c.", letters[constants.WordLength-1]),
+	}
+}
+
+func hintLimit(app *models.App, game *models.GameState) int {
+	return min(app.HintLimit, len(HintStages(app, game.SessionWord)))
+}
+
+// HintsLeft returns how many more staged hints the game may reveal. In hint
+// tax mode each costs a row, and the last row is never given up.
+func HintsLeft(app *models.App, game *models.GameState, tax bool) int {
+	if game.GameOver || IsMultiBoard(game) || game.Adversarial != nil {
+		return 0
+	}
+	left := hintLimit(app, game) - game.HintsRevealed
+	if tax {
+		left = min(left, MaxRows(game)-game.CurrentRow-1)
+	}
+	return max(left, 0)
+}
+
+// RevealHint reveals the game's next staged hint. With tax set the game
+// gives up its last row for it.
+func RevealHint(app *models.App, game *models.GameState, tax bool) (string, error) {
+	limit := hintLimit(app, game)
+	switch {
+	case game.GameOver:
+		return "", NewGameError(constants.ErrorCodeGameOver)
+	case IsMultiBoard(game):
+		return "", NewGameError(constants.ErrorCodeHintUnavailable).WithDetail("reason", "multi_board")
+	case game.Adversarial != nil:
+		return "", NewGameError(constants.ErrorCodeHintUnavailable).WithDetail("reason", "adversarial")
+	case game.HintsRevealed >= limit:
+		return "", NewGameError(constants.ErrorCodeHintsExhausted).WithDetail("limit", limit)
+	case tax && game.CurrentRow >= MaxRows(game)-1:
+		return "", NewGameError(constants.ErrorCodeHintUnavailable).WithDetail("reason", "last_guess")
+	}
+
+	hint := HintStages(app, game.SessionWord)[game.HintsRevealed]
+	game.HintsRevealed++
+	if tax {
+		game.HintTax++
+	}
+	game.LastAccessTime = time.Now()
+	return hint, nil
+}
+
+// BuildHintView returns the hint area of the game for rendering.
+// Adversarial games have none, as their word is not chosen yet.
+func BuildHintView(app *models.App, game *models.GameState, tax bool) models.HintView {
+	if game.Adversarial != nil {
+		return models.HintView{Tax: tax}
+	}
+	stages := HintStages(app, game.SessionWord)
+	return models.HintView{
+		Text:     GetHintForWord(app, game.SessionWord),
+		Revealed: stages[:min(game.HintsRevealed, len(stages))],
+		Left:     HintsLeft(app, game, tax),
+		Tax:      tax,
+	}
+}
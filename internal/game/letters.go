@@ -0,0 +1,87 @@
+package game
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	constants "github.com/CodeAndHammer/vortludo/internal/constants"
+	models "github.com/CodeAndHammer/vortludo/internal/models"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Tile letters NewGuess cannot slice out of the word: the one for a byte
+// that is not valid UTF-8, and the one for a missing letter. They are what
+// string(r) gives for the rune NewGuess decodes in their place.
+const (
+	invalidLetter = string(utf8.RuneError)
+	missingLetter = "\x00"
+)
+
+// NormalizeWord puts a word in the form the word lists use: trimmed, composed
+// (NFC) and upper case. Composing first means "C" followed by a combining
+// circumflex compares equal to a precomposed "Ĉ".
+func NormalizeWord(word string) string {
+	return norm.NFC.String(strings.ToUpper(norm.NFC.String(strings.TrimSpace(word))))
+}
+
+// WordLen returns the number of letters in word.
+func WordLen(word string) int {
+	return utf8.RuneCountInString(word)
+}
+
+// wordRunes returns the first WordLength letters of word. Missing letters are
+// left as zero.
+func wordRunes(word string) [constants.WordLength]rune {
+	var runes [constants.WordLength]rune
+	i := 0
+	for _, r := range word {
+		if i == constants.WordLength {
+			break
+		}
+		runes[i] = r
+		i++
+	}
+	return runes
+}
+
+// Guess is a guess decoded once for the checks and scoring it goes through:
+// the normalized word, its length in letters, and its first WordLength
+// letters both as runes and as the slices of Word that spell them.
+type Guess struct {
+	Word    string
+	Runes   models.WordRunes
+	Len     int
+	letters [constants.WordLength]string
+}
+
+// ParseGuess normalizes input, as typed by a player, into a Guess.
+func ParseGuess(input string) Guess {
+	return NewGuess(NormalizeWord(input))
+}
+
+// NewGuess decodes word, which is already normalized.
+func NewGuess(word string) Guess {
+	g := Guess{Word: word}
+	for at := 0; at < len(word); g.Len++ {
+		r, size := utf8.DecodeRuneInString(word[at:])
+		if g.Len < constants.WordLength {
+			g.Runes[g.Len] = r
+			g.letters[g.Len] = word[at : at+size]
+			if r == utf8.RuneError && size == 1 {
+				g.letters[g.Len] = invalidLetter
+			}
+		}
+		at += size
+	}
+	for i := g.Len; i < constants.WordLength; i++ {
+		g.letters[i] = missingLetter
+	}
+	return g
+}
+
+// CheckLength refuses a guess that is not WordLength letters long, going by
+// the length NewGuess counted. The error is an ErrorCodeInvalidLength
+// GameError that lists the positions that are missing or extra.
+func (g Guess) CheckLength() error {
+	return checkLength(g.Len)
+}
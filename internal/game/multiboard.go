@@ -0,0 +1,178 @@
+package game
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	constants "github.com/CodeAndHammer/vortludo/internal/constants"
+	models "github.com/CodeAndHammer/vortludo/internal/models"
+	util "github.com/CodeAndHammer/vortludo/internal/util"
+	"github.com/samber/lo"
+)
+
+func IsMultiBoard(game *models.GameState) bool {
+	return len(game.Boards) > 0
+}
+
+// Mode names the kind of game, as one of the constants.Mode values.
+func Mode(game *models.GameState) string {
+	switch {
+	case game.Tournament != nil:
+		return constants.ModeTournament
+	case game.Race != nil:
+		return constants.ModeRace
+	case game.Adversarial != nil:
+		return constants.ModeAdversarial
+	case len(game.Boards) == 2:
+		return constants.ModeDordle
+	case len(game.Boards) == 4:
+		return constants.ModeQuordle
+	}
+	return constants.ModeClassic
+}
+
+func IsAllowedBoardCount(n int) bool {
+	return slices.Contains(constants.AllowedBoardCounts, n)
+}
+
+// MaxRows returns how many guesses the game allows, less any rows given up
+// for hints.
+func MaxRows(game *models.GameState) int {
+	if len(game.Boards) > 1 {
+		return constants.MaxGuesses + len(game.Boards) - 1
+	}
+	return constants.MaxGuesses - game.HintTax
+}
+
+// NewMultiBoardGame returns a fresh game with one board per word.
+func NewMultiBoardGame(words []string) *models.GameState {
+	rows := constants.MaxGuesses + len(words) - 1
+	boards := make([]models.Board, len(words))
+	for i, word := range words {
+		boards[i] = models.Board{
+			SessionWord: word,
+			Guesses:     models.NewRows(rows),
+			SolvedAtRow: -1,
+		}
+	}
+	return &models.GameState{
+		ID:             NewGameID(),
+		Boards:         boards,
+		GuessHistory:   []string{},
+		LastAccessTime: time.Now(),
+	}
+}
+
+// CreateMultiBoardGame picks boardCount distinct words of pool, skipping
+// completedWords where possible, and stores the new game for the session.
+func CreateMultiBoardGame(app *models.App, ctx context.Context, sessionID, pool string, boardCount int, completedWords []string) (*models.GameState, bool) {
+	defer holdWords(app)()
+	seed := NewSeed()
+	rng := seededRand(seed)
+	exclude := slices.Clone(completedWords)
+	words := make([]string, 0, boardCount)
+	needsReset := false
+	for range boardCount {
+		entry, reset := pickWordEntry(app, ctx, rng, pool, exclude)
+		if reset && !needsReset {
+			// The session has completed the pool: start it over, still
+			// keeping this game's words apart.
+			needsReset = true
+			exclude = slices.Clone(words)
+			entry, _ = pickWordEntry(app, ctx, rng, pool, exclude)
+		}
+		words = append(words, entry.Word)
+		exclude = append(exclude, entry.Word)
+	}
+
+	game := NewMultiBoardGame(words)
+	game.Seed = seed
+	game.Pool = poolName(pool)
+	util.LogInfo("New %d-board game %s created for session %s with words: %v", boardCount, game.ID, sessionID, words)
+	SaveNewGame(app, sessionID, game)
+	return game, needsReset
+}
+
+// BoardWords returns the target word of every board.
+func BoardWords(game *models.GameState) []string {
+	return lo.Map(game.Boards, func(board models.Board, _ int) string { return board.SessionWord })
+}
+
+// ApplyMultiBoardGuess scores guess against every unsolved board and advances
+// the game. The game is won once every board is solved and lost when the rows
+// run out first.
+func ApplyMultiBoardGuess(app *models.App, ctx context.Context, game *models.GameState, guess string) {
+	applyMultiBoardGuess(app, ctx, game, NewGuess(guess))
+}
+
+func applyMultiBoardGuess(app *models.App, ctx context.Context, game *models.GameState, parsed Guess) {
+	guess := parsed.Word
+	reqID, _ := ctx.Value(constants.RequestIDKey).(string)
+
+	if game.CurrentRow >= MaxRows(game) {
+		return
+	}
+
+	row := game.CurrentRow
+	for i := range game.Boards {
+		board := &game.Boards[i]
+		if board.Solved {
+			continue
+		}
+		runes, counts := targetLetters(app, board.SessionWord)
+		result := scoreGuess(app, parsed, runes, counts)
+		board.Guesses.Set(row, result)
+		ReleaseResult(app, result)
+		if guess == board.SessionWord {
+			board.Solved = true
+			board.SolvedAtRow = row
+		}
+	}
+	game.GuessHistory = append(game.GuessHistory, guess)
+	game.LastAccessTime = time.Now()
+	game.Version++
+	game.CurrentRow++
+
+	allSolved := lo.EveryBy(game.Boards, func(board models.Board) bool { return board.Solved })
+	switch {
+	case allSolved:
+		game.Won = true
+		game.GameOver = true
+		if reqID != "" {
+			util.LogInfo("[request_id=%v] Player solved all %d boards in %d guesses", reqID, len(game.Boards), game.CurrentRow)
+		} else {
+			util.LogInfo("Player solved all %d boards in %d guesses", len(game.Boards), game.CurrentRow)
+		}
+	case game.CurrentRow >= MaxRows(game):
+		game.GameOver = true
+		if reqID != "" {
+			util.LogInfo("[request_id=%v] Player lost multi-board game. Words were: %v", reqID, BoardWords(game))
+		} else {
+			util.LogInfo("Player lost multi-board game. Words were: %v", BoardWords(game))
+		}
+	}
+
+	if game.GameOver {
+		for i := range game.Boards {
+			game.Boards[i].TargetWord = game.Boards[i].SessionWord
+		}
+	}
+}
+
+// BuildBoards is BuildBoard for multi-board games. Boards solved before
+// newRow have no new row to reveal.
+func BuildBoards(game *models.GameState, newRow int) []models.BoardView {
+	return lo.Map(game.Boards, func(board models.Board, i int) models.BoardView {
+		boardNewRow := newRow
+		if board.Solved && board.SolvedAtRow < newRow {
+			boardNewRow = -1
+		}
+		return models.BoardView{
+			Index:      i,
+			Rows:       buildRows(board.Guesses, game.CurrentRow, !game.GameOver && !board.Solved, boardNewRow, game.Draft),
+			Solved:     board.Solved,
+			TargetWord: board.TargetWord,
+		}
+	})
+}
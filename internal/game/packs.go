@@ -0,0 +1,152 @@
+package game
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"slices"
+	"time"
+
+	constants "github.com/CodeAndHammer/vortludo/internal/constants"
+	models "github.com/CodeAndHammer/vortludo/internal/models"
+	util "github.com/CodeAndHammer/vortludo/internal/util"
+)
+
+// LoadPacks reads the puzzle packs in path. Packs are optional, so a missing
+// file yields none.
+func LoadPacks(path string) ([]models.Pack, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var list models.PackList
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("decode %s: %w", path, err)
+	}
+	seen := make(map[string]bool)
+	for i, pack := range list.Packs {
+		switch {
+		case pack.ID == "" || pack.Name == "":
+			return nil, fmt.Errorf("%s: pack %d needs an id and a name", path, i+1)
+		case seen[pack.ID]:
+			return nil, fmt.Errorf("%s: pack %q is defined twice", path, pack.ID)
+		}
+		seen[pack.ID] = true
+		words := make([]string, 0, len(pack.Words))
+		for _, word := range pack.Words {
+			if word = NormalizeWord(word); word != "" && !slices.Contains(words, word) {
+				words = append(words, word)
+			}
+		}
+		if len(words) == 0 {
+			return nil, fmt.Errorf("%s: pack %q has no words", path, pack.ID)
+		}
+		list.Packs[i].Words = words
+	}
+	return list.Packs, nil
+}
+
+// FindPack returns the pack with id, if there is one with words that can be
+// played: target words in the word list that are not blocked.
+func FindPack(app *models.App, id string) (models.Pack, bool) {
+	for _, pack := range app.Packs {
+		if pack.ID == id {
+			return pack, len(packWords(app, pack)) > 0
+		}
+	}
+	return models.Pack{}, false
+}
+
+// packWords returns the entries of pack's words that can be played.
+func packWords(app *models.App, pack models.Pack) []models.WordEntry {
+	var words []models.WordEntry
+	for _, entry := range app.Dictionary().Words {
+		if slices.Contains(pack.Words, entry.Word) && !entry.Retired() && !IsBlockedWord(app, entry.Word) {
+			words = append(words, entry)
+		}
+	}
+	return words
+}
+
+// PackProgress is how far a player got through a pack.
+type PackProgress struct {
+	Pack   models.Pack
+	Solved int
+	Total  int
+}
+
+// Complete reports whether every word of the pack was solved.
+func (p PackProgress) Complete() bool {
+	return p.Total > 0 && p.Solved >= p.Total
+}
+
+// Percent is the share of the pack's words that were solved.
+func (p PackProgress) Percent() int {
+	if p.Total == 0 {
+		return 0
+	}
+	return p.Solved * 100 / p.Total
+}
+
+// PacksProgress returns the progress through each pack that can be played,
+// given the words solved in each, by pack ID.
+func PacksProgress(app *models.App, solved map[string][]string) []PackProgress {
+	var progress []PackProgress
+	for _, pack := range app.Packs {
+		if p := packProgress(app, pack, solved[pack.ID]); p.Total > 0 {
+			progress = append(progress, p)
+		}
+	}
+	return progress
+}
+
+// FindPackProgress returns the progress through the pack with id.
+func FindPackProgress(app *models.App, id string, solved []string) (PackProgress, bool) {
+	pack, ok := FindPack(app, id)
+	if !ok {
+		return PackProgress{}, false
+	}
+	return packProgress(app, pack, solved), true
+}
+
+func packProgress(app *models.App, pack models.Pack, solved []string) PackProgress {
+	words := packWords(app, pack)
+	p := PackProgress{Pack: pack, Total: len(words)}
+	for _, entry := range words {
+		if slices.Contains(solved, entry.Word) {
+			p.Solved++
+		}
+	}
+	return p
+}
+
+// CreatePackGame starts a game with a word of pack that is not in solved,
+// or with any of its words once all were solved. The pack must be one
+// FindPack returned.
+func CreatePackGame(app *models.App, ctx context.Context, sessionID string, pack models.Pack, solved []string) *models.GameState {
+	defer holdWords(app)()
+	seed := NewSeed()
+	selectedEntry, replay := pickFrom(ctx, seededRand(seed), notWithheld(app, packWords(app, pack)), solved)
+	game := &models.GameState{
+		ID:             NewGameID(),
+		Seed:           seed,
+		Guesses:        models.NewRows(constants.MaxGuesses),
+		GuessHistory:   []string{},
+		SessionWord:    selectedEntry.Word,
+		LastAccessTime: time.Now(),
+		Pack:           pack.ID,
+	}
+	util.LogInfo("New game %s created for session %s with word: %s (pack: %s, solved: %d, replaying: %v)",
+		game.ID, sessionID, selectedEntry.Word, pack.ID, len(solved), replay)
+	SaveNewGame(app, sessionID, game)
+	return game
+}
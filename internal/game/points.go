@@ -0,0 +1,39 @@
+package game
+
+import (
+	models "github.com/CodeAndHammer/vortludo/internal/models"
+)
+
+// AwardPoints scores game, which just ended, if it is a points game: each
+// word solved is worth the rows left unused when it was solved times its
+// difficulty, which hard mode raises by one. The points are added to the
+// run's score; a lost game scores none and its run ends with it.
+func AwardPoints(app *models.App, game *models.GameState, hardMode bool) int {
+	if !game.Scored || !game.GameOver || !game.Won || game.Points > 0 {
+		return 0
+	}
+	bonus := 0
+	if hardMode {
+		bonus = 1
+	}
+	rows := MaxRows(game)
+	points := 0
+	if IsMultiBoard(game) {
+		for _, board := range game.Boards {
+			points += (rows - board.SolvedAtRow - 1) * (WordDifficulty(app, board.SessionWord) + bonus)
+		}
+	} else {
+		points = (rows - len(game.GuessHistory)) * (WordDifficulty(app, game.SessionWord) + bonus)
+	}
+	game.Points = points
+	game.Score += points
+	return points
+}
+
+// continueRun carries the score of previous into game when both are points
+// games and previous was won; any other game starts a new run.
+func continueRun(game, previous *models.GameState) {
+	if game.Scored && previous.Scored && previous.Won {
+		game.Score = previous.Score
+	}
+}
@@ -0,0 +1,50 @@
+package game
+
+import (
+	"maps"
+	"slices"
+
+	constants "github.com/CodeAndHammer/vortludo/internal/constants"
+	models "github.com/CodeAndHammer/vortludo/internal/models"
+)
+
+// PoolNames returns the names of the word pools, classic first.
+func PoolNames(app *models.App) []string {
+	names := slices.Sorted(maps.Keys(app.Dictionary().Pools))
+	names = slices.DeleteFunc(names, func(name string) bool { return name == constants.PoolClassic })
+	return append([]string{constants.PoolClassic}, names...)
+}
+
+// HasPool reports whether the word list defines pool. The classic pool
+// always exists, even when every word names other pools.
+func HasPool(app *models.App, pool string) bool {
+	_, ok := app.Dictionary().Pools[pool]
+	return ok || pool == "" || pool == constants.PoolClassic
+}
+
+// poolName is the Pool of a game drawn from pool, which is left empty for
+// the classic pool.
+func poolName(pool string) string {
+	if pool == constants.PoolClassic {
+		return ""
+	}
+	return pool
+}
+
+// poolWords returns the words of pool. An empty classic pool holds every
+// word not retired.
+func poolWords(app *models.App, pool string) []models.WordEntry {
+	dict := app.Dictionary()
+	if pool == "" {
+		pool = constants.PoolClassic
+	}
+	indexes, ok := dict.Pools[pool]
+	if !ok {
+		return slices.DeleteFunc(slices.Clone(dict.Words), models.WordEntry.Retired)
+	}
+	words := make([]models.WordEntry, len(indexes))
+	for i, index := range indexes {
+		words[i] = dict.Words[index]
+	}
+	return words
+}
@@ -0,0 +1,132 @@
+package game
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	constants "github.com/CodeAndHammer/vortludo/internal/constants"
+	models "github.com/CodeAndHammer/vortludo/internal/models"
+	util "github.com/CodeAndHammer/vortludo/internal/util"
+)
+
+// StartRace turns game into a bot race: a bot guesses the same word once per
+// interval and the player has to solve it first.
+func StartRace(game *models.GameState, interval time.Duration) {
+	if interval <= 0 {
+		interval = constants.BotRaceIntervalDefault
+	}
+	game.Race = &models.RaceState{
+		StartedAt:       time.Now(),
+		IntervalSeconds: int(interval.Seconds()),
+		BotGuesses:      []string{},
+		BotRows:         [][]string{},
+	}
+}
+
+// AdvanceRace plays every bot turn that has come due by now. The bot has no
+// goroutine of its own; its progress is derived from the elapsed time whenever
+// the game is looked at. It reports whether the bot finished the game.
+func AdvanceRace(app *models.App, ctx context.Context, game *models.GameState, now time.Time) bool {
+	race := game.Race
+	if race == nil || game.GameOver || race.BotSolved || race.IntervalSeconds <= 0 {
+		return false
+	}
+	reqID, _ := ctx.Value(constants.RequestIDKey).(string)
+
+	interval := time.Duration(race.IntervalSeconds) * time.Second
+	due := min(int(now.Sub(race.StartedAt)/interval), constants.MaxGuesses)
+	for len(race.BotGuesses) < due && !race.BotSolved {
+		guess := botNextGuess(app, game.Pool, race.BotGuesses, game.SessionWord)
+		if guess == "" {
+			break
+		}
+		result := CheckGuess(guess, game.SessionWord, app)
+		statuses := make([]string, len(result))
+		for i, r := range result {
+			statuses[i] = r.Status
+		}
+		race.BotGuesses = append(race.BotGuesses, guess)
+		race.BotRows = append(race.BotRows, statuses)
+		race.BotSolved = guess == game.SessionWord
+	}
+
+	if !race.BotSolved {
+		return false
+	}
+	game.GameOver = true
+	game.Won = false
+	game.TargetWord = game.SessionWord
+	if reqID != "" {
+		util.LogInfo("[request_id=%v] Bot won the race in %d guesses. Target word was: %s", reqID, len(race.BotGuesses), game.SessionWord)
+	} else {
+		util.LogInfo("Bot won the race in %d guesses. Target word was: %s", len(race.BotGuesses), game.SessionWord)
+	}
+	return true
+}
+
+// botNextGuess picks, among the target words of pool consistent with the
+// bot's previous feedback, the one covering the most frequent letters of
+// that candidate set.
+func botNextGuess(app *models.App, pool string, previous []string, target string) string {
+	feedback := make([][]models.GuessResult, len(previous))
+	for i, guess := range previous {
+		feedback[i] = CheckGuess(guess, target, app)
+	}
+
+	var candidates []string
+	for _, entry := range selectableWords(app, pool) {
+		if slices.Contains(previous, entry.Word) || WordLen(entry.Word) != constants.WordLength {
+			continue
+		}
+		if consistentWithFeedback(app, entry.Word, previous, feedback) {
+			candidates = append(candidates, entry.Word)
+		}
+	}
+	if len(candidates) == 0 {
+		return ""
+	}
+
+	freq := make(map[rune]int)
+	for _, word := range candidates {
+		for _, letter := range uniqueLetters(word) {
+			freq[letter]++
+		}
+	}
+	score := func(word string) int {
+		total := 0
+		for _, letter := range uniqueLetters(word) {
+			total += freq[letter]
+		}
+		return total
+	}
+	return slices.MinFunc(candidates, func(a, b string) int {
+		if c := cmp.Compare(score(b), score(a)); c != 0 {
+			return c
+		}
+		return cmp.Compare(a, b)
+	})
+}
+
+func consistentWithFeedback(app *models.App, word string, guesses []string, feedback [][]models.GuessResult) bool {
+	for i, guess := range guesses {
+		result := CheckGuess(guess, word, app)
+		for j := range result {
+			if result[j].Status != feedback[i][j].Status {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func uniqueLetters(word string) []rune {
+	letters := make([]rune, 0, constants.WordLength)
+	for _, r := range word {
+		if !slices.Contains(letters, r) {
+			letters = append(letters, r)
+		}
+	}
+	return letters
+}
@@ -0,0 +1,54 @@
+package game
+
+import (
+	models "github.com/CodeAndHammer/vortludo/internal/models"
+)
+
+// Evaluation is one guess of a game scored again against one board's word.
+// Stored is what the game recorded for the same row and board, so a
+// mismatch points at a scoring bug rather than at the player.
+type Evaluation struct {
+	Row     int      `json:"row"`
+	Guess   string   `json:"guess"`
+	Board   int      `json:"board"`
+	Target  string   `json:"target"`
+	Result  []string `json:"result"`
+	Stored  []string `json:"stored"`
+	Matches bool     `json:"matches"`
+}
+
+// ReplayGame scores the game's guess history again, in order, against the
+// words it was played with.
+func ReplayGame(app *models.App, game *models.GameState) []Evaluation {
+	var evaluations []Evaluation
+	for row, guess := range game.GuessHistory {
+		if !IsMultiBoard(game) {
+			if row < game.Guesses.Len() {
+				evaluations = append(evaluations, evaluate(app, row, guess, 0, game.SessionWord, game.Guesses))
+			}
+			continue
+		}
+		for i, board := range game.Boards {
+			if board.Solved && board.SolvedAtRow < row {
+				continue
+			}
+			if row < board.Guesses.Len() {
+				evaluations = append(evaluations, evaluate(app, row, guess, i, board.SessionWord, board.Guesses))
+			}
+		}
+	}
+	return evaluations
+}
+
+func evaluate(app *models.App, row int, guess string, board int, target string, stored models.Rows) Evaluation {
+	e := Evaluation{Row: row, Guess: guess, Board: board, Target: target, Matches: true}
+	recorded := stored.Row(row)
+	for i, tile := range CheckGuess(guess, target, app) {
+		e.Result = append(e.Result, tile.Status)
+		e.Stored = append(e.Stored, recorded[i].Status)
+		if tile.Status != recorded[i].Status || tile.Letter != recorded[i].Letter {
+			e.Matches = false
+		}
+	}
+	return e
+}
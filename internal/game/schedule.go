@@ -0,0 +1,181 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	models "github.com/CodeAndHammer/vortludo/internal/models"
+	schedule "github.com/CodeAndHammer/vortludo/internal/schedule"
+	tournament "github.com/CodeAndHammer/vortludo/internal/tournament"
+	util "github.com/CodeAndHammer/vortludo/internal/util"
+)
+
+// Reasons ScheduleWord and UnscheduleWord refuse a change.
+var (
+	ErrScheduleDate  = errors.New("only days after today can be scheduled")
+	ErrWordScheduled = errors.New("the word is already scheduled")
+	ErrNotScheduled  = errors.New("no word is scheduled for that day")
+)
+
+// DailyWords returns the daily words of the week starting at start: the
+// words scheduled for its days, and the deterministic weekly selection,
+// less the scheduled words, for the rest. A scheduled word that is no longer
+// a target word, or is retired or blocked, is passed over. The days a small word list
+// runs out for are left empty.
+func DailyWords(app *models.App, start time.Time) []string {
+	words := make([]string, tournament.Days)
+	scheduled := make(map[string]bool)
+	for day := range words {
+		if word, ok := app.Schedule.Word(start.AddDate(0, 0, day)); ok && IsValidWord(app, word) && !IsRetiredWord(app, word) && !IsBlockedWord(app, word) {
+			words[day] = word
+			scheduled[word] = true
+		}
+	}
+	picks := WeeklyWords(app, tournament.WeekID(start), tournament.Days+len(scheduled))
+	for day := range words {
+		for words[day] == "" && len(picks) > 0 {
+			if !scheduled[picks[0]] {
+				words[day] = picks[0]
+			}
+			picks = picks[1:]
+		}
+	}
+	for len(words) > 0 && words[len(words)-1] == "" {
+		words = words[:len(words)-1]
+	}
+	return words
+}
+
+// ScheduleWord makes entry.Word the daily word of entry.Date, replacing any
+// word scheduled for it, and returns the entry saved and the one replaced.
+// A date in the running tournament's week takes effect at once.
+func ScheduleWord(app *models.App, entry schedule.Entry, now time.Time) (schedule.Entry, *schedule.Entry, error) {
+	entry.Word = NormalizeWord(entry.Word)
+	entry.Note = strings.TrimSpace(entry.Note)
+	day, err := schedule.ParseDate(entry.Date)
+	if err != nil {
+		return entry, nil, err
+	}
+	switch {
+	case !day.After(schedule.Day(now)):
+		return entry, nil, ErrScheduleDate
+	case !IsValidWord(app, entry.Word):
+		return entry, nil, ErrWordMissing
+	case IsRetiredWord(app, entry.Word):
+		return entry, nil, ErrWordRetired
+	case IsBlockedWord(app, entry.Word):
+		return entry, nil, ErrWordBlocked
+	}
+	if date, ok := app.Schedule.DateOf(entry.Word, now); ok && date != entry.Date {
+		return entry, nil, fmt.Errorf("%w for %s", ErrWordScheduled, date)
+	}
+	old, replaced, err := app.Schedule.Set(entry)
+	if err != nil {
+		return entry, nil, err
+	}
+	syncTournament(app, day, now)
+	util.LogInfo("Scheduled %s for %s", entry.Word, entry.Date)
+	if !replaced {
+		return entry, nil, nil
+	}
+	return entry, &old, nil
+}
+
+// UnscheduleWord hands date back to the deterministic selection and returns
+// the entry removed.
+func UnscheduleWord(app *models.App, date string, now time.Time) (schedule.Entry, error) {
+	day, err := schedule.ParseDate(date)
+	if err != nil {
+		return schedule.Entry{}, err
+	}
+	if !day.After(schedule.Day(now)) {
+		return schedule.Entry{}, ErrScheduleDate
+	}
+	entry, ok := app.Schedule.Remove(date)
+	if !ok {
+		return schedule.Entry{}, ErrNotScheduled
+	}
+	syncTournament(app, day, now)
+	util.LogInfo("Unscheduled %s from %s", entry.Word, date)
+	return entry, nil
+}
+
+// syncTournament brings the days yet to begin of the running tournament in
+// line with the schedule when day falls in its week. The rest of the week
+// is picked again too, so a word newly scheduled is not played twice.
+func syncTournament(app *models.App, day, now time.Time) {
+	t := CurrentTournament(app, now)
+	if tournament.WeekID(day) != t.Week {
+		return
+	}
+	for i, word := range DailyWords(app, t.StartsAt) {
+		app.Tournament.SetWord(t.Week, i, word, now)
+	}
+}
+
+// CalendarDay is a daily puzzle as the admin calendar shows it. Conflicts
+// explain what would go wrong with the day as it stands.
+type CalendarDay struct {
+	Date      string   `json:"date"`
+	Puzzle    int      `json:"puzzle"`
+	Word      string   `json:"word"`
+	Scheduled bool     `json:"scheduled"`
+	Note      string   `json:"note,omitempty"`
+	Conflicts []string `json:"conflicts,omitempty"`
+}
+
+// Calendar lists the daily puzzles of the days days from today: the words
+// of the running tournament for its week, then the words the schedule and
+// selection would give later weeks with the word list as it is now.
+func Calendar(app *models.App, now time.Time, days int) []CalendarDay {
+	t := CurrentTournament(app, now)
+	weeks := map[string][]string{t.Week: t.Words}
+	calendar := make([]CalendarDay, 0, days)
+	seen := make(map[string]string)
+	for i := range days {
+		day := schedule.Day(now).AddDate(0, 0, i)
+		week := tournament.WeekID(day)
+		words, ok := weeks[week]
+		if !ok {
+			words = DailyWords(app, tournament.WeekStart(day))
+			weeks[week] = words
+		}
+		cd := CalendarDay{Date: day.Format(schedule.DateLayout), Puzzle: tournament.PuzzleNumber(day)}
+		if index := tournament.DayIndex(day); index < len(words) {
+			cd.Word = words[index]
+		}
+		if entry, ok := app.Schedule.Get(cd.Date); ok {
+			cd.Scheduled = true
+			cd.Note = entry.Note
+			cd.Conflicts = scheduleConflicts(app, entry, cd.Word)
+		}
+		if cd.Word == "" {
+			cd.Conflicts = append(cd.Conflicts, "the word list has too few words for this day")
+		} else if other, ok := seen[cd.Word]; ok {
+			cd.Conflicts = append(cd.Conflicts, fmt.Sprintf("%s is also the word of %s", cd.Word, other))
+		} else {
+			seen[cd.Word] = cd.Date
+		}
+		calendar = append(calendar, cd)
+	}
+	return calendar
+}
+
+func scheduleConflicts(app *models.App, entry schedule.Entry, word string) []string {
+	var conflicts []string
+	if !IsValidWord(app, entry.Word) {
+		conflicts = append(conflicts, entry.Word+" is not a target word")
+	}
+	if IsRetiredWord(app, entry.Word) {
+		conflicts = append(conflicts, entry.Word+" is retired")
+	}
+	if IsBlockedWord(app, entry.Word) {
+		conflicts = append(conflicts, entry.Word+" is blocked")
+	}
+	if word != "" && word != entry.Word {
+		conflicts = append(conflicts, fmt.Sprintf("%s is scheduled, but %s is the word of the day", entry.Word, word))
+	}
+	return conflicts
+}
@@ -0,0 +1,31 @@
+package game
+
+import (
+	crand "crypto/rand"
+	"encoding/binary"
+	"math/rand/v2"
+	"strings"
+
+	constants "github.com/CodeAndHammer/vortludo/internal/constants"
+)
+
+// seedStream is the second PCG word of a game's generator. It only needs to
+// stay the same, or old seeds stop reproducing their games.
+const seedStream = 0x766f72746c75646f
+
+// NewSeed returns a random seed for a new game.
+func NewSeed() uint64 {
+	var b [8]byte
+	crand.Read(b[:])
+	return binary.BigEndian.Uint64(b[:])
+}
+
+// NewGameID returns a public ID for a game. It is drawn separately from the
+// seed, which together with the word list would give the word away.
+func NewGameID() string {
+	return strings.ToLower(crand.Text()[:constants.GameIDLength])
+}
+
+func seededRand(seed uint64) *rand.Rand {
+	return rand.New(rand.NewPCG(seed, seedStream))
+}
@@ -0,0 +1,129 @@
+package game
+
+import (
+	"slices"
+	"strings"
+
+	constants "github.com/CodeAndHammer/vortludo/internal/constants"
+	keyboard "github.com/CodeAndHammer/vortludo/internal/keyboard"
+	models "github.com/CodeAndHammer/vortludo/internal/models"
+)
+
+func DefaultSettings() models.UserSettings {
+	return models.UserSettings{
+		Language:       constants.LanguageEnglish,
+		KeyboardLayout: constants.KeyboardLayoutQwerty,
+		Theme:          constants.ThemeAuto,
+	}
+}
+
+// ValidateSettings checks the enumerated fields of settings. Empty values are
+// replaced by their defaults.
+func ValidateSettings(settings *models.UserSettings) error {
+	defaults := DefaultSettings()
+	settings.Language = strings.ToLower(strings.TrimSpace(settings.Language))
+	if settings.Language == "" {
+		settings.Language = defaults.Language
+	}
+	if !slices.Contains(constants.SupportedLanguages, settings.Language) {
+		return NewGameError(constants.ErrorCodeInvalidSettings).WithDetail("language", settings.Language)
+	}
+	settings.KeyboardLayout = strings.ToLower(strings.TrimSpace(settings.KeyboardLayout))
+	if settings.KeyboardLayout == "" {
+		settings.KeyboardLayout = defaults.KeyboardLayout
+	}
+	if !slices.Contains(keyboard.Names(), settings.KeyboardLayout) {
+		return NewGameError(constants.ErrorCodeInvalidSettings).WithDetail("keyboard_layout", settings.KeyboardLayout)
+	}
+	settings.Theme = strings.ToLower(strings.TrimSpace(settings.Theme))
+	if settings.Theme == "" {
+		settings.Theme = defaults.Theme
+	}
+	if !slices.Contains(constants.SupportedThemes, settings.Theme) {
+		return NewGameError(constants.ErrorCodeInvalidSettings).WithDetail("theme", settings.Theme)
+	}
+	return nil
+}
+
+// HardModeViolation is a hint a guess failed to use: a letter revealed as
+// correct that left its position, or one revealed as present that was not
+// reused.
+type HardModeViolation struct {
+	Letter   string `json:"letter"`
+	Position int    `json:"position,omitempty"`
+	Rule     string `json:"rule"`
+}
+
+// CheckHardMode enforces the hard mode rule: letters revealed as correct must
+// stay in place and letters revealed as present must be reused. The error
+// names the first violation, and lists them all with the guess positions
+// they concern so the client can point at them.
+func CheckHardMode(game *models.GameState, guess string) error {
+	violations := hardModeViolations(game, guess)
+	if len(violations) == 0 {
+		return nil
+	}
+	err := NewGameError(constants.ErrorCodeHardMode).WithDetail("letter", violations[0].Letter)
+	if violations[0].Position > 0 {
+		err = err.WithDetail("position", violations[0].Position)
+	}
+	positions := []int{}
+	for _, v := range violations {
+		if v.Position > 0 {
+			positions = append(positions, v.Position)
+		}
+	}
+	slices.Sort(positions)
+	return err.WithDetail("violations", violations).WithDetail("positions", slices.Compact(positions))
+}
+
+func hardModeViolations(game *models.GameState, guess string) []HardModeViolation {
+	letters := []rune(guess)
+	var violations []HardModeViolation
+	add := func(v HardModeViolation) {
+		if !slices.Contains(violations, v) {
+			violations = append(violations, v)
+		}
+	}
+	for _, row := range revealedRows(game) {
+		for i, r := range row {
+			if r.Status == constants.GuessStatusCorrect && (i >= len(letters) || string(letters[i]) != r.Letter) {
+				add(HardModeViolation{Letter: r.Letter, Position: i + 1, Rule: constants.GuessStatusCorrect})
+			}
+		}
+		for _, r := range row {
+			if r.Status == constants.GuessStatusPresent && countStatus(row, r.Letter) > strings.Count(guess, r.Letter) {
+				add(HardModeViolation{Letter: r.Letter, Rule: constants.GuessStatusPresent})
+			}
+		}
+	}
+	return violations
+}
+
+// revealedRows returns the scored rows the player has seen. Rows of solved
+// boards no longer constrain the next guess.
+func revealedRows(game *models.GameState) [][]models.GuessResult {
+	if !IsMultiBoard(game) {
+		return game.Guesses.Expand(game.CurrentRow)
+	}
+	var rows [][]models.GuessResult
+	for _, board := range game.Boards {
+		if board.Solved {
+			continue
+		}
+		rows = append(rows, board.Guesses.Expand(game.CurrentRow)...)
+	}
+	return rows
+}
+
+// countStatus counts the tiles of letter in row that are correct or present,
+// i.e. the minimum number of times the letter must appear.
+func countStatus(row []models.GuessResult, letter string) int {
+	n := 0
+	for _, r := range row {
+		if r.Letter == letter && (r.Status == constants.GuessStatusCorrect || r.Status == constants.GuessStatusPresent) {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,189 @@
+package game
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"strings"
+
+	constants "github.com/CodeAndHammer/vortludo/internal/constants"
+	models "github.com/CodeAndHammer/vortludo/internal/models"
+)
+
+// solverBudget caps the number of (guess, answer) pairs scored per request.
+// When the remaining answers times the guess pool exceed it, the answers are
+// sampled evenly to estimate each guess's entropy.
+const solverBudget = 2_000_000
+
+// Suggestion is a guess proposed by the solver hint. Entropy is the expected
+// information, in bits, the guess reveals about the remaining answers.
+type Suggestion struct {
+	Word      string  `json:"word"`
+	Entropy   float64 `json:"entropy"`
+	Candidate bool    `json:"candidate"`
+}
+
+// SuggestGuesses ranks the accepted words by how well they split the answers
+// still consistent with the board and returns the best n, along with the
+// number of answers remaining.
+func SuggestGuesses(app *models.App, game *models.GameState, n int) ([]Suggestion, int) {
+	remaining := RemainingAnswers(app, game)
+	if len(remaining) == 0 {
+		return []Suggestion{}, 0
+	}
+	candidates := make(map[string]struct{}, len(remaining))
+	for _, word := range remaining {
+		candidates[word] = struct{}{}
+	}
+
+	accepted := app.Dictionary().Accepted
+	pool := make([]string, 0, accepted.Len())
+	for word := range accepted.All() {
+		if WordLen(word) == constants.WordLength && !slices.Contains(game.GuessHistory, word) && !IsBlockedWord(app, word) {
+			pool = append(pool, word)
+		}
+	}
+
+	sample := remaining
+	if len(pool) > 0 && len(remaining)*len(pool) > solverBudget {
+		size := max(solverBudget/len(pool), 1)
+		sample = make([]string, 0, size)
+		for i := range size {
+			sample = append(sample, remaining[i*len(remaining)/size])
+		}
+	}
+
+	suggestions := make([]Suggestion, 0, len(pool))
+	var counts [243]int
+	for _, guess := range pool {
+		entropy := guessEntropy(guess, sample, &counts)
+		_, isCandidate := candidates[guess]
+		if entropy == 0 && !isCandidate {
+			continue
+		}
+		suggestions = append(suggestions, Suggestion{Word: guess, Entropy: math.Round(entropy*100) / 100, Candidate: isCandidate})
+	}
+
+	slices.SortFunc(suggestions, func(a, b Suggestion) int {
+		if c := cmp.Compare(b.Entropy, a.Entropy); c != 0 {
+			return c
+		}
+		if a.Candidate != b.Candidate {
+			if a.Candidate {
+				return -1
+			}
+			return 1
+		}
+		return cmp.Compare(a.Word, b.Word)
+	})
+	if len(suggestions) > n {
+		suggestions = suggestions[:n]
+	}
+	return suggestions, len(remaining)
+}
+
+// guessEntropy is the expected information, in bits, guess reveals about
+// answers. counts is scratch space.
+func guessEntropy(guess string, answers []string, counts *[243]int) float64 {
+	clear(counts[:])
+	for _, answer := range answers {
+		counts[feedbackPattern(guess, answer)]++
+	}
+	entropy := 0.0
+	for _, count := range counts {
+		if count > 0 {
+			p := float64(count) / float64(len(answers))
+			entropy -= p * math.Log2(p)
+		}
+	}
+	return entropy
+}
+
+// RemainingAnswers returns the target words consistent with every row the
+// player has revealed so far.
+func RemainingAnswers(app *models.App, game *models.GameState) []string {
+	if game.Adversarial != nil && game.Adversarial.Candidates != nil {
+		return slices.Clone(game.Adversarial.Candidates)
+	}
+	type clue struct {
+		guess   models.WordRunes
+		pattern int
+	}
+	var clues []clue
+	for _, row := range game.Guesses.Expand(game.CurrentRow) {
+		var guess strings.Builder
+		pattern := 0
+		for i := len(row) - 1; i >= 0; i-- {
+			pattern = pattern*3 + statusDigit(row[i].Status)
+		}
+		for _, r := range row {
+			guess.WriteString(r.Letter)
+		}
+		if WordLen(guess.String()) == constants.WordLength {
+			clues = append(clues, clue{guess: wordRunes(guess.String()), pattern: pattern})
+		}
+	}
+
+	var remaining []string
+	for _, entry := range selectableWords(app, game.Pool) {
+		if WordLen(entry.Word) != constants.WordLength {
+			continue
+		}
+		consistent := true
+		for _, c := range clues {
+			if runesPattern(c.guess, entry.Runes, entry.Counts) != c.pattern {
+				consistent = false
+				break
+			}
+		}
+		if consistent {
+			remaining = append(remaining, entry.Word)
+		}
+	}
+	return remaining
+}
+
+func statusDigit(status string) int {
+	switch status {
+	case constants.GuessStatusCorrect:
+		return 2
+	case constants.GuessStatusPresent:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// feedbackPattern is an allocation-free CheckGuess that encodes the statuses
+// as a base-3 number, least significant digit first.
+func feedbackPattern(guessWord, answerWord string) int {
+	answer := wordRunes(answerWord)
+	return runesPattern(wordRunes(guessWord), answer, models.CountLetters(answer))
+}
+
+// runesPattern is feedbackPattern for decoded words, such as the target
+// words of the dictionary with their letter counts.
+func runesPattern(guess, answer models.WordRunes, counts models.LetterCounts) int {
+	remaining := counts.Counts
+	var marks [constants.WordLength]int
+	for i, r := range guess {
+		if r == answer[i] {
+			marks[i] = 2
+			remaining[counts.Index(r)]--
+		}
+	}
+	for i, r := range guess {
+		if marks[i] != 0 {
+			continue
+		}
+		if j := counts.Index(r); j >= 0 && remaining[j] > 0 {
+			marks[i] = 1
+			remaining[j]--
+		}
+	}
+	pattern := 0
+	for i := constants.WordLength - 1; i >= 0; i-- {
+		pattern = pattern*3 + marks[i]
+	}
+	return pattern
+}
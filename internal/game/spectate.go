@@ -0,0 +1,72 @@
+package game
+
+import (
+	"crypto/rand"
+	"time"
+
+	constants "github.com/CodeAndHammer/vortludo/internal/constants"
+	models "github.com/CodeAndHammer/vortludo/internal/models"
+)
+
+// StartSpectating returns the spectate token of the session's game, creating
+// and indexing one if the game has none yet. The caller holds the session's
+// lock.
+func StartSpectating(app *models.App, sessionID string, game *models.GameState) string {
+	if game.Spectate == nil {
+		game.Spectate = &models.Spectate{Token: rand.Text()}
+		app.Sessions.IndexSpectate(sessionID, game.Spectate.Token)
+	}
+	return game.Spectate.Token
+}
+
+// StopSpectating closes the spectate link of the session's game. The caller
+// holds the session's lock.
+func StopSpectating(app *models.App, sessionID string, game *models.GameState) {
+	game.Spectate = nil
+	app.Sessions.UnindexSpectate(sessionID)
+}
+
+// SpectateOpen reports whether the game's spectate link still works at now.
+// Spectators get to see the finished board for constants.SpectateEndGrace
+// after the game ends; the grace starts when a spectator first sees the game
+// over, much like the race bot, which only moves when someone looks.
+func SpectateOpen(game *models.GameState, now time.Time) bool {
+	if game.Spectate == nil {
+		return false
+	}
+	if game.GameOver && game.Spectate.EndsAt.IsZero() {
+		game.Spectate.EndsAt = now.Add(constants.SpectateEndGrace)
+	}
+	return game.Spectate.EndsAt.IsZero() || now.Before(game.Spectate.EndsAt)
+}
+
+// SpectatorBoards returns the game's boards with the letters and target
+// words left out, so spectators see only the colors of each guess.
+func SpectatorBoards(game *models.GameState) []models.BoardView {
+	var boards []models.BoardView
+	if IsMultiBoard(game) {
+		boards = BuildBoards(game, -1)
+	} else {
+		boards = []models.BoardView{{Rows: BuildBoard(game, -1), Solved: game.Won}}
+	}
+	for i := range boards {
+		boards[i].TargetWord = ""
+		for j := range boards[i].Rows {
+			row := &boards[i].Rows[j]
+			row.IsCurrent = false
+			for k := range row.Tiles {
+				row.Tiles[k].Letter = ""
+			}
+		}
+	}
+	return boards
+}
+
+// SpectateVersion changes whenever what spectators see of the game changes.
+func SpectateVersion(game *models.GameState) int {
+	version := len(game.GuessHistory) * 2
+	if game.GameOver {
+		version++
+	}
+	return version
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"testing"
+
+	config "github.com/CodeAndHammer/vortludo/internal/config"
+	constants "github.com/CodeAndHammer/vortludo/internal/constants"
+	game "github.com/CodeAndHammer/vortludo/internal/game"
+	models "github.com/CodeAndHammer/vortludo/internal/models"
+)
+
+// Allocation budgets of the guess path, per guess. Parsing a normalized
+// guess and scoring it allocate nothing once the app's pools are warm;
+// UpdateGameState allocates the word it stores in the row.
+const (
+	checkGuessAllocBudget  = 0
+	updateStateAllocBudget = 1
+)
+
+var benchGuesses = []struct{ guess, target string }{
+	{"PAPER", "APPLE"},
+	{"APPLE", "APPLE"},
+	{"ĈAMBO", "ŜAĈOJ"},
+}
+
+// benchApp returns an app with the pools NewApp sets up, as the server has.
+func benchApp() *models.App {
+	app := models.NewApp(config.Default())
+	game.SetDictionary(app, []models.WordEntry{{Word: "APPLE"}, {Word: "ŜAĈOJ"}}, nil)
+	return app
+}
+
+// benchGame returns a game with no guesses yet, reusing gameState's rows.
+func benchGame(gameState *models.GameState) *models.GameState {
+	gameState.CurrentRow = 0
+	gameState.GameOver = false
+	gameState.Won = false
+	gameState.GuessHistory = gameState.GuessHistory[:0]
+	return gameState
+}
+
+func TestCheckGuessAllocBudget(t *testing.T) {
+	app := benchApp()
+	for _, tt := range benchGuesses {
+		allocs := testing.AllocsPerRun(100, func() {
+			game.ReleaseResult(app, game.CheckGuess(tt.guess, tt.target, app))
+		})
+		if allocs > checkGuessAllocBudget {
+			t.Errorf("CheckGuess(%q, %q) made %.0f allocations, budget %d", tt.guess, tt.target, allocs, checkGuessAllocBudget)
+		}
+	}
+}
+
+func TestParseGuessAllocBudget(t *testing.T) {
+	for _, tt := range benchGuesses {
+		if allocs := testing.AllocsPerRun(100, func() { game.ParseGuess(tt.guess) }); allocs > checkGuessAllocBudget {
+			t.Errorf("ParseGuess(%q) made %.0f allocations, budget %d", tt.guess, allocs, checkGuessAllocBudget)
+		}
+	}
+}
+
+func TestUpdateGameStateAllocBudget(t *testing.T) {
+	app := benchApp()
+	gameState := &models.GameState{Guesses: models.NewRows(constants.MaxGuesses)}
+	allocs := testing.AllocsPerRun(100, func() {
+		result := game.CheckGuess("PAPER", "APPLE", app)
+		game.UpdateGameState(app, dummyContext(), benchGame(gameState), "PAPER", "APPLE", result, false)
+		game.ReleaseResult(app, result)
+	})
+	if allocs > updateStateAllocBudget {
+		t.Errorf("UpdateGameState made %.0f allocations, budget %d", allocs, updateStateAllocBudget)
+	}
+}
+
+func TestReleasedResultsAreReused(t *testing.T) {
+	app := benchApp()
+	first := game.CheckGuess("PAPER", "APPLE", app)
+	game.ReleaseResult(app, first)
+	second := game.CheckGuess("ĈAMBO", "ŜAĈOJ", app)
+	if second[0].Letter != "Ĉ" || second[1].Status != constants.GuessStatusCorrect || second[4].Status != constants.GuessStatusPresent {
+		t.Errorf("A reused row should be scored afresh, got %+v", second)
+	}
+
+	// Rows.Set copies what it keeps, so releasing a stored row is safe.
+	gameState := &models.GameState{SessionWord: "APPLE", Guesses: models.NewRows(constants.MaxGuesses)}
+	game.ApplyGuess(app, dummyContext(), gameState, "APPLE")
+	game.CheckGuess("ZZZZZ", "APPLE", app)
+	if row := gameState.Guesses.Row(0); row[0].Letter != "A" || row[0].Status != constants.GuessStatusCorrect {
+		t.Errorf("The stored row should survive its result being reused, got %+v", row)
+	}
+}
+
+func BenchmarkCheckGuess(b *testing.B) {
+	app := benchApp()
+	for _, tt := range benchGuesses {
+		b.Run(tt.guess+"/"+tt.target, func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				game.ReleaseResult(app, game.CheckGuess(tt.guess, tt.target, app))
+			}
+		})
+	}
+}
+
+func BenchmarkApplyParsedGuess(b *testing.B) {
+	app := benchApp()
+	ctx := dummyContext()
+	gameState := &models.GameState{SessionWord: "APPLE", Guesses: models.NewRows(constants.MaxGuesses)}
+	b.ReportAllocs()
+	for b.Loop() {
+		game.ApplyParsedGuess(app, ctx, benchGame(gameState), game.ParseGuess("paper"))
+	}
+}
+
+func BenchmarkUpdateGameState(b *testing.B) {
+	app := benchApp()
+	ctx := dummyContext()
+	gameState := &models.GameState{Guesses: models.NewRows(constants.MaxGuesses)}
+	b.ReportAllocs()
+	for b.Loop() {
+		result := game.CheckGuess("PAPER", "APPLE", app)
+		game.UpdateGameState(app, ctx, benchGame(gameState), "PAPER", "APPLE", result, false)
+		game.ReleaseResult(app, result)
+	}
+}
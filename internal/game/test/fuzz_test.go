@@ -0,0 +1,201 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"unicode/utf8"
+
+	constants "github.com/CodeAndHammer/vortludo/internal/constants"
+	game "github.com/CodeAndHammer/vortludo/internal/game"
+	models "github.com/CodeAndHammer/vortludo/internal/models"
+)
+
+var fuzzWords = []string{
+	"SPEED", "ERASE", "LLAMA", "HELLO", "ABBEY", "BABES", "ŜAĈOJ", "ĈAMBO",
+	"", "A", "TOOLONGWORD", "\xff\xfe\xfd\xfc\xfb", "ĈAMBO", " apple ",
+}
+
+// referenceScore scores guess against target the way players expect: exact
+// matches first, then each remaining guess letter is present only while the
+// target has unmatched copies of it left, from left to right.
+func referenceScore(guess, target []rune) []string {
+	statuses := make([]string, len(guess))
+	remaining := make(map[rune]int)
+	for i := range guess {
+		if guess[i] == target[i] {
+			statuses[i] = constants.GuessStatusCorrect
+		} else {
+			remaining[target[i]]++
+		}
+	}
+	for i := range guess {
+		if statuses[i] != "" {
+			continue
+		}
+		if remaining[guess[i]] > 0 {
+			remaining[guess[i]]--
+			statuses[i] = constants.GuessStatusPresent
+		} else {
+			statuses[i] = constants.GuessStatusAbsent
+		}
+	}
+	return statuses
+}
+
+func FuzzCheckGuess(f *testing.F) {
+	for _, guess := range fuzzWords {
+		for _, target := range fuzzWords {
+			f.Add(guess, target)
+		}
+	}
+	pool := &sync.Pool{New: func() any {
+		return new([constants.WordLength]models.GuessResult)
+	}}
+	apps := []*models.App{{}, {ResultPool: pool}}
+
+	f.Fuzz(func(t *testing.T, guess, target string) {
+		for _, app := range apps {
+			res := game.CheckGuess(guess, target, app)
+			if len(res) != constants.WordLength {
+				t.Fatalf("CheckGuess(%q, %q) returned %d tiles", guess, target, len(res))
+			}
+			if !utf8.ValidString(guess) || !utf8.ValidString(target) ||
+				game.WordLen(guess) != constants.WordLength || game.WordLen(target) != constants.WordLength {
+				continue
+			}
+			guessRunes, targetRunes := []rune(guess), []rune(target)
+			want := referenceScore(guessRunes, targetRunes)
+			for i, tile := range res {
+				if tile.Letter != string(guessRunes[i]) || tile.Status != want[i] {
+					t.Fatalf("CheckGuess(%q, %q) tile %d = %+v, want %q %s", guess, target, i, tile, string(guessRunes[i]), want[i])
+				}
+			}
+			if guess == target {
+				for _, tile := range res {
+					if tile.Status != constants.GuessStatusCorrect {
+						t.Fatalf("CheckGuess(%q, %q) should be all correct, got %+v", guess, target, res)
+					}
+				}
+			}
+		}
+	})
+}
+
+func FuzzNormalizeWord(f *testing.F) {
+	for _, word := range fuzzWords {
+		f.Add(word)
+	}
+	f.Add("ĉambo ")
+	f.Add("ß")
+	f.Add("ͅ")
+
+	f.Fuzz(func(t *testing.T, word string) {
+		got := game.NormalizeWord(word)
+		if !utf8.ValidString(got) {
+			t.Fatalf("NormalizeWord(%q) = %q, not valid UTF-8", word, got)
+		}
+		if strings.TrimSpace(got) != got {
+			t.Fatalf("NormalizeWord(%q) = %q, not trimmed", word, got)
+		}
+		if again := game.NormalizeWord(got); again != got {
+			t.Fatalf("NormalizeWord is not idempotent: %q -> %q -> %q", word, got, again)
+		}
+	})
+}
+
+func FuzzParseWordList(f *testing.F) {
+	f.Add([]byte(`{"words": [{"word": "apple", "hint": "A fruit"}]}`))
+	f.Add([]byte(`{"words": []}`))
+	f.Add([]byte(`{"words": [{"word": 5}]}`))
+	f.Add([]byte(`{"words": [{"word": "\xff\xfe"}]`))
+	f.Add([]byte(`null`))
+	f.Add([]byte(`{"words": [{"word": "` + strings.Repeat("A", 100000) + `"}]}`))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		words, err := game.ParseWordList(data)
+		if err != nil {
+			return
+		}
+		if len(words) == 0 {
+			t.Fatal("ParseWordList accepted a list with no words")
+		}
+		for _, entry := range words {
+			if game.NormalizeWord(entry.Word) != entry.Word {
+				t.Fatalf("ParseWordList left %q unnormalized", entry.Word)
+			}
+		}
+	})
+}
+
+func FuzzParseAcceptedWords(f *testing.F) {
+	f.Add([]byte("apple\nspeed\n\nĉambo\r\n"))
+	f.Add([]byte("\xff\xfe\xfd\xfc\xfb\n"))
+	f.Add([]byte(strings.Repeat("A", 1<<17) + "\nHELLO"))
+	f.Add([]byte("  llama  \n\x00\x00\x00\x00\x00"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		accepted, err := game.ParseAcceptedWords(data)
+		if err != nil {
+			t.Fatalf("ParseAcceptedWords failed: %v", err)
+		}
+		for word := range accepted {
+			if game.WordLen(word) != constants.WordLength {
+				t.Fatalf("ParseAcceptedWords accepted %q of the wrong length", word)
+			}
+			if game.NormalizeWord(word) != word {
+				t.Fatalf("ParseAcceptedWords left %q unnormalized", word)
+			}
+		}
+	})
+}
+
+func FuzzLoadDefinitions(f *testing.F) {
+	f.Add([]byte(`{"definitions": [{"word": "apple", "definition": "A round fruit."}]}`))
+	f.Add([]byte(`{"definitions": [{"word": "", "definition": "none"}]}`))
+	f.Add([]byte(`{"definitions": {}}`))
+	f.Add([]byte("\xff\xfe"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		path := filepath.Join(t.TempDir(), "definitions.json")
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		definitions, err := game.LoadDefinitions(path)
+		if err != nil {
+			return
+		}
+		for word, entry := range definitions {
+			if word == "" || entry.Definition == "" || game.NormalizeWord(word) != word {
+				t.Fatalf("LoadDefinitions kept %q: %+v", word, entry)
+			}
+		}
+	})
+}
+
+func FuzzLoadBlockedWords(f *testing.F) {
+	f.Add([]byte("# comment\nbadwd\n\n  WORSE \n"))
+	f.Add([]byte(strings.Repeat("x", 1<<17)))
+	f.Add([]byte("\xff\xfe\n#\n"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		path := filepath.Join(t.TempDir(), "blocked.txt")
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		blocked, err := game.LoadBlockedWords(path)
+		if err != nil {
+			t.Fatalf("LoadBlockedWords failed: %v", err)
+		}
+		for word := range blocked {
+			if word == "" || strings.HasPrefix(word, "#") || strings.TrimSpace(word) != word {
+				t.Fatalf("LoadBlockedWords kept %q", word)
+			}
+			if strings.ContainsRune(word, '\n') {
+				t.Fatalf("LoadBlockedWords kept %q spanning lines", word)
+			}
+		}
+	})
+}
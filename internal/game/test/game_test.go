@@ -2,29 +2,29 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
 	"testing"
+	"time"
 
+	analytics "github.com/CodeAndHammer/vortludo/internal/analytics"
 	constants "github.com/CodeAndHammer/vortludo/internal/constants"
 	game "github.com/CodeAndHammer/vortludo/internal/game"
 	models "github.com/CodeAndHammer/vortludo/internal/models"
+	schedule "github.com/CodeAndHammer/vortludo/internal/schedule"
+	tournament "github.com/CodeAndHammer/vortludo/internal/tournament"
 )
 
 func testAppWithWords(words []models.WordEntry) *models.App {
-	wordSet := make(map[string]struct{})
-	acceptedSet := make(map[string]struct{})
-	hintMap := make(map[string]string)
-	for _, w := range words {
-		wordSet[w.Word] = struct{}{}
-		acceptedSet[w.Word] = struct{}{}
-		hintMap[w.Word] = w.Hint
-	}
-	return &models.App{
-		WordList:        words,
-		WordSet:         wordSet,
-		AcceptedWordSet: acceptedSet,
-		HintMap:         hintMap,
-		GameSessions:    make(map[string]*models.GameState),
-	}
+	app := &models.App{}
+	game.SetDictionary(app, words, nil)
+	return app
 }
 
 func dummyContext() context.Context {
@@ -106,7 +106,7 @@ func TestUpdateGameState_WinLose(t *testing.T) {
 	app := testAppWithWords(words)
 	ctx := dummyContext()
 	gameState := &models.GameState{
-		Guesses:      make([][]models.GuessResult, constants.MaxGuesses),
+		Guesses:      models.NewRows(constants.MaxGuesses),
 		CurrentRow:   0,
 		GameOver:     false,
 		Won:          false,
@@ -121,7 +121,7 @@ func TestUpdateGameState_WinLose(t *testing.T) {
 	}
 
 	gameState = &models.GameState{
-		Guesses:      make([][]models.GuessResult, constants.MaxGuesses),
+		Guesses:      models.NewRows(constants.MaxGuesses),
 		CurrentRow:   constants.MaxGuesses - 1,
 		GameOver:     false,
 		Won:          false,
@@ -187,10 +187,10 @@ func TestCreateNewGame(t *testing.T) {
 	if gameState.SessionWord != "apple" {
 		t.Error("SessionWord should be 'apple'")
 	}
-	if len(gameState.Guesses) != constants.MaxGuesses {
+	if gameState.Guesses.Len() != constants.MaxGuesses {
 		t.Error("Guesses length incorrect")
 	}
-	if app.GameSessions["sess1"] == nil {
+	if _, ok := app.Sessions.Game("sess1"); !ok {
 		t.Error("Game not stored in session map")
 	}
 }
@@ -199,12 +199,1081 @@ func TestCreateNewGameWithCompletedWords(t *testing.T) {
 	words := []models.WordEntry{{Word: "apple", Hint: "fruit"}, {Word: "table", Hint: "furniture"}}
 	app := testAppWithWords(words)
 	ctx := dummyContext()
-	gameState, reset := game.CreateNewGameWithCompletedWords(app, ctx, "sess2", []string{"apple"})
+	gameState, reset := game.CreateNewGameWithCompletedWords(app, ctx, "sess2", "", []string{"apple"})
 	if gameState.SessionWord != "table" || reset {
 		t.Error("Should select 'table' and reset=false")
 	}
-	_, reset = game.CreateNewGameWithCompletedWords(app, ctx, "sess3", []string{"apple", "table"})
+	_, reset = game.CreateNewGameWithCompletedWords(app, ctx, "sess3", "", []string{"apple", "table"})
 	if !reset {
 		t.Error("Should set reset=true when all words completed")
 	}
 }
+
+func TestWordPools(t *testing.T) {
+	words := []models.WordEntry{
+		{Word: "APPLE", Hint: "fruit"},
+		{Word: "TABLE", Hint: "furniture", Pools: []string{"classic", "kids"}},
+		{Word: "ZEBRA", Hint: "animal", Pools: []string{"kids"}},
+	}
+	app := testAppWithWords(words)
+	ctx := dummyContext()
+
+	if names := game.PoolNames(app); !slices.Equal(names, []string{"classic", "kids"}) {
+		t.Errorf("Expected pools classic and kids, got %v", names)
+	}
+	if !game.HasPool(app, "") || !game.HasPool(app, "kids") || game.HasPool(app, "themed") {
+		t.Error("HasPool disagrees with the word list")
+	}
+	for range 20 {
+		gameState, reset := game.CreateNewGameWithCompletedWords(app, ctx, "sess", "kids", []string{"ZEBRA"})
+		if gameState.SessionWord != "TABLE" || gameState.Pool != "kids" || reset {
+			t.Fatalf("Expected TABLE from the kids pool, got %s from %q (reset %v)", gameState.SessionWord, gameState.Pool, reset)
+		}
+		gameState, _ = game.CreateNewGameWithCompletedWords(app, ctx, "sess", "classic", nil)
+		if gameState.SessionWord == "ZEBRA" || gameState.Pool != "" {
+			t.Fatalf("Expected a classic word, got %s from %q", gameState.SessionWord, gameState.Pool)
+		}
+	}
+	// Words completed in another pool do not exhaust this one.
+	if _, reset := game.CreateNewGameWithCompletedWords(app, ctx, "sess", "kids", []string{"APPLE"}); reset {
+		t.Error("Completing a classic word should not exhaust the kids pool")
+	}
+	if _, reset := game.CreateNewGameWithCompletedWords(app, ctx, "sess", "kids", []string{"TABLE", "ZEBRA"}); !reset {
+		t.Error("Expected a reset once every kids word is completed")
+	}
+}
+
+func TestPuzzlePacks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "packs.json")
+	data := `{"packs": [
+		{"id": "animals", "name": "Animals", "words": ["zebra", "HORSE", "TIGER", "ZEBRA"]},
+		{"id": "space", "name": "Space", "words": ["COMET"]}
+	]}`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	packs, err := game.LoadPacks(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(packs) != 2 || !slices.Equal(packs[0].Words, []string{"ZEBRA", "HORSE", "TIGER"}) {
+		t.Fatalf("Expected normalized, deduplicated pack words, got %+v", packs)
+	}
+	if packs, err := game.LoadPacks(filepath.Join(t.TempDir(), "missing.json")); err != nil || packs != nil {
+		t.Errorf("A missing packs file should yield no packs, got %v, %v", packs, err)
+	}
+
+	app := testAppWithWords([]models.WordEntry{
+		{Word: "ZEBRA", Hint: "animal"},
+		{Word: "HORSE", Hint: "animal"},
+		{Word: "APPLE", Hint: "fruit"},
+	})
+	app.Packs = packs
+	ctx := dummyContext()
+
+	if _, ok := game.FindPack(app, "space"); ok {
+		t.Error("A pack without target words should not be playable")
+	}
+	pack, ok := game.FindPack(app, "animals")
+	if !ok {
+		t.Fatal("Expected the animals pack")
+	}
+	for range 20 {
+		gameState := game.CreatePackGame(app, ctx, "sess", pack, []string{"ZEBRA"})
+		if gameState.SessionWord != "HORSE" || gameState.Pack != "animals" {
+			t.Fatalf("Expected the unsolved HORSE from the animals pack, got %s from %q", gameState.SessionWord, gameState.Pack)
+		}
+	}
+	if gameState := game.CreatePackGame(app, ctx, "sess", pack, []string{"ZEBRA", "HORSE"}); gameState.SessionWord == "APPLE" {
+		t.Error("A finished pack should replay its own words")
+	}
+
+	progress := game.PacksProgress(app, map[string][]string{"animals": {"ZEBRA", "TIGER"}})
+	if len(progress) != 1 || progress[0].Solved != 1 || progress[0].Total != 2 || progress[0].Complete() || progress[0].Percent() != 50 {
+		t.Errorf("Expected 1 of 2 animals solved, got %+v", progress)
+	}
+	if p, _ := game.FindPackProgress(app, "animals", []string{"HORSE", "ZEBRA"}); !p.Complete() {
+		t.Errorf("Expected the animals pack complete, got %+v", p)
+	}
+}
+
+func TestAutoContinuePackGame(t *testing.T) {
+	app := testAppWithWords([]models.WordEntry{
+		{Word: "ZEBRA", Hint: "animal"},
+		{Word: "HORSE", Hint: "animal"},
+		{Word: "TIGER", Hint: "animal"},
+	})
+	app.Packs = []models.Pack{{ID: "animals", Name: "Animals", Words: []string{"ZEBRA", "HORSE", "TIGER"}}}
+	ctx := dummyContext()
+	pack, ok := game.FindPack(app, "animals")
+	if !ok {
+		t.Fatal("Expected the animals pack")
+	}
+
+	// HORSE was solved in an earlier session; the client never saw it.
+	for range 20 {
+		gameState := game.CreatePackGame(app, ctx, "sess", pack, []string{"HORSE"})
+		gameState.GameOver = true
+		next, _ := game.ContinueGame(app, ctx, "sess", gameState, nil, []string{"HORSE", gameState.SessionWord})
+		if next.Pack != "animals" || next.SessionWord == "HORSE" || next.SessionWord == gameState.SessionWord {
+			t.Fatalf("Expected the one unsolved word of the pack after %s, got %s from %q",
+				gameState.SessionWord, next.SessionWord, next.Pack)
+		}
+	}
+}
+
+func TestIsHardWord(t *testing.T) {
+	app := testAppWithWords(nil)
+	for word, want := range map[string]bool{"TABLE": false, "APPLE": true, "ZEBRA": true, "HORSE": false} {
+		if got := game.IsHardWord(app, word); got != want {
+			t.Errorf("IsHardWord(%s) = %v before any plays, want %v", word, got, want)
+		}
+	}
+
+	app.Analytics = analytics.NewCollector()
+	for i := range constants.HardWordMinPlays {
+		solved := i < constants.HardWordMinPlays/4
+		app.Analytics.RecordGame(analytics.GameResult{Words: []analytics.WordOutcome{{Word: "TABLE", Solved: solved}, {Word: "APPLE", Solved: true}}})
+	}
+	if !game.IsHardWord(app, "TABLE") || game.IsHardWord(app, "APPLE") {
+		t.Error("Once played often enough, solve rates should decide")
+	}
+}
+
+func TestAssistedGames(t *testing.T) {
+	app := testAppWithWords([]models.WordEntry{{Word: "GRATE"}, {Word: "CRATE"}})
+	app.Sessions.Shard("sess").Settings = map[string]*models.UserSettings{"sess": {Assisted: true}}
+
+	gameState := game.CreateNewGame(app, dummyContext(), "sess")
+	gameState.SessionWord = "GRATE"
+	if !gameState.Assisted || game.AssistLetter(gameState) != "G" {
+		t.Fatalf("Expected an assisted game revealing G, got %+v", gameState)
+	}
+	if rows := game.BuildBoard(gameState, -1); rows[0].Assist != "G" || rows[1].Assist != "" {
+		t.Errorf("Expected G pre-filled in the current row only, got %q and %q", rows[0].Assist, rows[1].Assist)
+	}
+	if err := game.CheckAssist(gameState, "CRATE"); game.AsGameError(err).Code != constants.ErrorCodeAssistLetter {
+		t.Errorf("Expected a guess moving the revealed letter refused, got %v", err)
+	}
+	if err := game.CheckAssist(gameState, "GRAND"); err != nil {
+		t.Errorf("Expected a guess keeping the revealed letter accepted, got %v", err)
+	}
+
+	multi, _ := game.CreateMultiBoardGame(app, dummyContext(), "sess", "", 2, nil)
+	if multi.Assisted || game.CheckAssist(multi, "CRATE") != nil {
+		t.Error("Multi-board games should not be assisted")
+	}
+	delete(app.Sessions.Shard("sess").Settings, "sess")
+	if game.CreateNewGame(app, dummyContext(), "sess").Assisted {
+		t.Error("Games should only be assisted when the setting is on")
+	}
+}
+
+func TestPointsRun(t *testing.T) {
+	app := testAppWithWords([]models.WordEntry{{Word: "GRATE"}, {Word: "CRATE"}})
+	app.Sessions.Shard("sess").Settings = map[string]*models.UserSettings{"sess": {Points: true}}
+
+	first := game.CreateNewGame(app, dummyContext(), "sess")
+	first.SessionWord = "GRATE"
+	game.ApplyGuess(app, dummyContext(), first, "CRATE")
+	game.ApplyGuess(app, dummyContext(), first, "GRATE")
+	// Four rows were left unused on an easy word.
+	if points := game.AwardPoints(app, first, false); points != 4 || first.Score != 4 {
+		t.Fatalf("Expected 4 points for a run of 4, got %d for %d", points, first.Score)
+	}
+	if game.AwardPoints(app, first, false) != 0 || first.Score != 4 {
+		t.Error("A game should only score once")
+	}
+
+	second := game.CreateNewGame(app, dummyContext(), "sess")
+	second.SessionWord = "GRATE"
+	game.ApplyGuess(app, dummyContext(), second, "GRATE")
+	if points := game.AwardPoints(app, second, true); points != 10 || second.Score != 14 {
+		t.Errorf("Expected 10 hard-mode points for a run of 14, got %d for %d", points, second.Score)
+	}
+
+	third := game.CreateNewGame(app, dummyContext(), "sess")
+	third.SessionWord = "GRATE"
+	for range game.MaxRows(third) {
+		game.ApplyGuess(app, dummyContext(), third, "CRATE")
+	}
+	if game.AwardPoints(app, third, false) != 0 || third.Score != 14 {
+		t.Errorf("Expected a lost game to score nothing, got a run of %d", third.Score)
+	}
+	if fourth := game.CreateNewGame(app, dummyContext(), "sess"); fourth.Score != 0 {
+		t.Errorf("Expected a loss to end the run, got a score of %d", fourth.Score)
+	}
+
+	delete(app.Sessions.Shard("sess").Settings, "sess")
+	if game.CreateNewGame(app, dummyContext(), "sess").Scored {
+		t.Error("Games should only score points when the setting is on")
+	}
+}
+
+func TestBotSignals(t *testing.T) {
+	words := []models.WordEntry{{Word: "BRAKE"}, {Word: "CRATE"}, {Word: "GRATE"}, {Word: "IRATE"}, {Word: "TRACE"}, {Word: "CRANE"}}
+	app := testAppWithWords(words)
+	gameState := game.CreateNewGame(app, dummyContext(), "sess")
+	gameState.SessionWord = "GRATE"
+	start := time.Now()
+	for i, guess := range []string{"BRAKE", "CRATE", "GRATE"} {
+		game.ApplyGuess(app, dummyContext(), gameState, guess)
+		gameState.GuessTimes = append(gameState.GuessTimes, start.Add(time.Duration(i)*300*time.Millisecond))
+	}
+	signals := game.BotSignals(app, gameState)
+	if len(signals.Intervals) != 2 || signals.Intervals[0] != 300*time.Millisecond {
+		t.Errorf("Expected two 300ms intervals, got %v", signals.Intervals)
+	}
+	// After BRAKE, CRATE, GRATE, IRATE and TRACE remain; CRATE splits them
+	// no better than GRATE, so the second guess was a decision played well,
+	// and GRATE, guessed with only GRATE and IRATE left, no decision at all.
+	if signals.Decisions != 1 || signals.Optimal != 1 {
+		t.Errorf("Expected 1 optimal decision, got %d of %d", signals.Optimal, signals.Decisions)
+	}
+}
+
+func TestGameNumbers(t *testing.T) {
+	words := []models.WordEntry{{Word: "apple", Hint: "fruit"}, {Word: "table", Hint: "furniture"}}
+	app := testAppWithWords(words)
+	ctx := dummyContext()
+	first := game.CreateNewGame(app, ctx, "sess1")
+	second, _ := game.CreateMultiBoardGame(app, ctx, "sess1", "", 2, nil)
+	third, _ := game.CreateNewGameWithCompletedWords(app, ctx, "sess1", "", nil)
+	if first.Number != 1 || second.Number != 2 || third.Number != 3 {
+		t.Errorf("Expected games numbered 1, 2, 3, got %d, %d, %d", first.Number, second.Number, third.Number)
+	}
+	if other := game.CreateNewGame(app, ctx, "sess2"); other.Number != 1 {
+		t.Errorf("Each session should number its own games, got %d", other.Number)
+	}
+	if got := third.Label(); got != "Vortludo game 3" {
+		t.Errorf("Label = %q", got)
+	}
+	third.Puzzle = 125
+	if got := third.Label(); got != "Vortludo #125" {
+		t.Errorf("Daily puzzle label = %q", got)
+	}
+}
+
+func TestReplayGame(t *testing.T) {
+	words := []models.WordEntry{{Word: "APPLE", Hint: "fruit"}}
+	app := testAppWithWords(words)
+	ctx := dummyContext()
+	gameState := game.CreateNewGame(app, ctx, "sess1")
+	other := game.CreateNewGame(app, ctx, "sess2")
+	if len(gameState.ID) != constants.GameIDLength || gameState.ID == other.ID || gameState.Seed == other.Seed {
+		t.Errorf("Expected distinct game IDs and seeds, got %q/%d and %q/%d", gameState.ID, gameState.Seed, other.ID, other.Seed)
+	}
+
+	for _, guess := range []string{"PAPER", "APPLE"} {
+		game.UpdateGameState(app, ctx, gameState, guess, "APPLE", game.CheckGuess(guess, "APPLE", app), false)
+	}
+	evaluations := game.ReplayGame(app, gameState)
+	if len(evaluations) != 2 || !evaluations[0].Matches || !evaluations[1].Matches {
+		t.Fatalf("Expected two matching evaluations, got %+v", evaluations)
+	}
+
+	// A row stored with the wrong statuses shows up as a mismatch.
+	tampered := game.CheckGuess("PAPER", "APPLE", app)
+	tampered[0].Status = constants.GuessStatusCorrect
+	gameState.Guesses.Set(0, tampered)
+	if evaluations := game.ReplayGame(app, gameState); evaluations[0].Matches {
+		t.Error("Expected the tampered row not to match")
+	}
+}
+
+func TestSpectatorBoardsHideLetters(t *testing.T) {
+	app := testAppWithWords([]models.WordEntry{{Word: "APPLE", Hint: "fruit"}})
+	gameState := game.CreateNewGame(app, dummyContext(), "sess1")
+	token := game.StartSpectating(app, "sess1", gameState)
+	if token == "" || game.StartSpectating(app, "sess1", gameState) != token {
+		t.Fatal("Expected one stable spectate token per game")
+	}
+	game.UpdateGameState(app, dummyContext(), gameState, "PAPER", "APPLE", game.CheckGuess("PAPER", "APPLE", app), false)
+
+	boards := game.SpectatorBoards(gameState)
+	if len(boards) != 1 || boards[0].TargetWord != "" {
+		t.Fatalf("Unexpected spectator boards: %+v", boards)
+	}
+	for _, tile := range boards[0].Rows[0].Tiles {
+		if tile.Letter != "" || tile.Status == "" {
+			t.Errorf("Spectators should see statuses only, got %+v", tile)
+		}
+	}
+
+	now := time.Now()
+	if !game.SpectateOpen(gameState, now) {
+		t.Error("Link should work while the game is on")
+	}
+	gameState.GameOver = true
+	if !game.SpectateOpen(gameState, now) || game.SpectateOpen(gameState, now.Add(constants.SpectateEndGrace+time.Second)) {
+		t.Error("Link should close a grace period after the game ends")
+	}
+}
+
+func TestLoadBlockedWords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocked.txt")
+	if err := os.WriteFile(path, []byte("# comment\nbadwd\n\n  WORSE \n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	blocked, err := game.LoadBlockedWords(path)
+	if err != nil {
+		t.Fatalf("LoadBlockedWords: %v", err)
+	}
+	if len(blocked) != 2 {
+		t.Errorf("Expected 2 blocked words, got %d", len(blocked))
+	}
+	if _, ok := blocked["BADWD"]; !ok {
+		t.Error("Expected BADWD to be blocked")
+	}
+
+	blocked, err = game.LoadBlockedWords(path + "-missing")
+	if err != nil || len(blocked) != 0 {
+		t.Errorf("Missing blocklist should yield empty set, got %v (%v)", blocked, err)
+	}
+}
+
+func TestBlockedWordsNeverSelected(t *testing.T) {
+	words := []models.WordEntry{{Word: "apple", Hint: "fruit"}, {Word: "table", Hint: "furniture"}}
+	app := testAppWithWords(words)
+	app.BlockedWordSet = map[string]struct{}{"apple": {}}
+	ctx := dummyContext()
+	for i := 0; i < 10; i++ {
+		if w := game.GetRandomWordEntry(app, ctx); w.Word != "table" {
+			t.Fatalf("Blocked word selected: %v", w.Word)
+		}
+	}
+	if !game.IsBlockedWord(app, "apple") || game.IsBlockedWord(app, "table") {
+		t.Error("IsBlockedWord returned wrong result")
+	}
+}
+
+func TestGameError(t *testing.T) {
+	base := game.NewGameError(constants.ErrorCodeInvalidLength)
+	if base.Status != http.StatusUnprocessableEntity || base.MessageKey != "error.invalid_length" {
+		t.Errorf("Unexpected error fields: %+v", base)
+	}
+	withLen := base.WithDetail("length", 3)
+	if base.Details != nil {
+		t.Error("WithDetail should not mutate the receiver")
+	}
+	if withLen.Details["length"] != 3 {
+		t.Errorf("Expected length detail, got %v", withLen.Details)
+	}
+
+	var err error = withLen
+	if got := game.AsGameError(fmt.Errorf("wrapped: %w", err)); got != withLen {
+		t.Errorf("AsGameError should unwrap, got %+v", got)
+	}
+	if got := game.AsGameError(errors.New("boom")); got.Code != constants.ErrorCodeInternal || got.Status != http.StatusInternalServerError {
+		t.Errorf("Unknown errors should become internal errors, got %+v", got)
+	}
+	if game.AsGameError(nil) != nil {
+		t.Error("AsGameError(nil) should be nil")
+	}
+}
+
+func TestBuildBoardRevealMetadata(t *testing.T) {
+	words := []models.WordEntry{{Word: "APPLE", Hint: "fruit"}}
+	app := testAppWithWords(words)
+	gameState := game.CreateNewGame(app, dummyContext(), "sess-board")
+	result := game.CheckGuess("TABLE", "APPLE", app)
+	game.UpdateGameState(app, dummyContext(), gameState, "TABLE", "APPLE", result, false)
+
+	board := game.BuildBoard(gameState, len(gameState.GuessHistory)-1)
+	if len(board) != constants.MaxGuesses {
+		t.Fatalf("Expected %d rows, got %d", constants.MaxGuesses, len(board))
+	}
+	if !board[0].IsNewRow || board[1].IsNewRow {
+		t.Error("Only the first row should be marked new")
+	}
+	if !board[1].IsCurrent || board[0].IsCurrent {
+		t.Error("Second row should be the current input row")
+	}
+	for i, tile := range board[0].Tiles {
+		if tile.RevealOrder != i || tile.RevealDelayMs != i*constants.RevealStaggerMs {
+			t.Errorf("Tile %d has order %d delay %d", i, tile.RevealOrder, tile.RevealDelayMs)
+		}
+	}
+
+	for _, row := range game.BuildBoard(gameState, -1) {
+		if row.IsNewRow {
+			t.Errorf("Row %d marked new without a fresh guess", row.Index)
+		}
+	}
+}
+
+func TestMultiBoardGame(t *testing.T) {
+	gameState := game.NewMultiBoardGame([]string{"APPLE", "TABLE"})
+	app := &models.App{}
+	ctx := dummyContext()
+	if game.MaxRows(gameState) != constants.MaxGuesses+1 {
+		t.Fatalf("Expected %d rows for two boards, got %d", constants.MaxGuesses+1, game.MaxRows(gameState))
+	}
+
+	game.ApplyMultiBoardGuess(app, ctx, gameState, "TABLE")
+	if !gameState.Boards[1].Solved || gameState.Boards[0].Solved || gameState.GameOver {
+		t.Fatal("Only the TABLE board should be solved")
+	}
+
+	game.ApplyMultiBoardGuess(app, ctx, gameState, "APPLE")
+	if gameState.Boards[1].Guesses.Row(1)[0].Letter != "" {
+		t.Error("Solved boards should not record later guesses")
+	}
+	if !gameState.Won || !gameState.GameOver {
+		t.Error("Game should be won once every board is solved")
+	}
+	if gameState.Boards[0].TargetWord != "APPLE" || gameState.Boards[1].TargetWord != "TABLE" {
+		t.Error("Target words should be revealed at game over")
+	}
+
+	views := game.BuildBoards(gameState, 1)
+	if !views[0].Rows[1].IsNewRow || views[1].Rows[1].IsNewRow {
+		t.Error("Only boards still in play on the last guess get a new row")
+	}
+}
+
+func TestMultiBoardGameDistinctWords(t *testing.T) {
+	app := testAppWithWords([]models.WordEntry{{Word: "APPLE"}, {Word: "TABLE"}, {Word: "CRANE"}, {Word: "SLATE"}})
+	cancelled, cancel := context.WithCancel(dummyContext())
+	cancel()
+	for _, tc := range []struct {
+		name      string
+		ctx       context.Context
+		completed []string
+		reset     bool
+	}{
+		{"every word completed", dummyContext(), []string{"APPLE", "TABLE", "CRANE", "SLATE"}, true},
+		{"fewer words left than boards", dummyContext(), []string{"APPLE", "TABLE", "CRANE"}, true},
+		{"cancelled", cancelled, nil, false},
+		{"cancelled with fewer words left than boards", cancelled, []string{"APPLE"}, true},
+	} {
+		for range 20 {
+			gameState, reset := game.CreateMultiBoardGame(app, tc.ctx, "sess", "", 4, tc.completed)
+			words := game.BoardWords(gameState)
+			distinct := slices.Compact(slices.Sorted(slices.Values(words)))
+			if len(distinct) != len(words) || reset != tc.reset {
+				t.Fatalf("%s: got words %v and reset %v, want four distinct words and reset %v", tc.name, words, reset, tc.reset)
+			}
+		}
+	}
+}
+
+func TestGuessesAdvanceVersion(t *testing.T) {
+	app := testAppWithWords([]models.WordEntry{{Word: "APPLE"}, {Word: "TABLE"}})
+	gameState := game.CreateNewGame(app, dummyContext(), "sess1")
+	game.ApplyGuess(app, dummyContext(), gameState, "ZZZZZ")
+	game.ApplyGuess(app, dummyContext(), gameState, "YYYYY")
+	if gameState.Version != 2 {
+		t.Errorf("Expected version 2 after two guesses, got %d", gameState.Version)
+	}
+
+	multi := game.NewMultiBoardGame([]string{"APPLE", "TABLE"})
+	game.ApplyGuess(app, dummyContext(), multi, "ZZZZZ")
+	if multi.Version != 1 {
+		t.Errorf("Expected version 1 after one multi-board guess, got %d", multi.Version)
+	}
+}
+
+func TestRevealHint(t *testing.T) {
+	app := testAppWithWords([]models.WordEntry{
+		{Word: "APPLE", Hint: "fruit", Hints: []string{"Grows on trees.", "Keeps the doctor away."}},
+		{Word: "TABLE", Hint: "furniture"},
+	})
+	app.HintLimit = 3
+	gameState := &models.GameState{SessionWord: "APPLE", Guesses: models.NewRows(constants.MaxGuesses)}
+	for _, want := range []string{"Grows on trees.", "Keeps the doctor away."} {
+		if hint, err := game.RevealHint(app, gameState, false); err != nil || hint != want {
+			t.Fatalf("RevealHint = %q, %v; want %q", hint, err, want)
+		}
+	}
+	_, err := game.RevealHint(app, gameState, false)
+	if gameErr := game.AsGameError(err); gameErr.Code != constants.ErrorCodeHintsExhausted {
+		t.Errorf("Expected the staged hints to run out, got %v", err)
+	}
+	view := game.BuildHintView(app, gameState, false)
+	if view.Text != "fruit" || len(view.Revealed) != 2 || view.Left != 0 {
+		t.Errorf("Unexpected hint view %+v", view)
+	}
+	if game.MaxRows(gameState) != constants.MaxGuesses {
+		t.Error("Free hints should not cost rows")
+	}
+
+	// Words without staged hints get letter hints, and in hint tax mode
+	// each costs the last row.
+	app.HintLimit = 2
+	taxed := &models.GameState{SessionWord: "TABLE", Guesses: models.NewRows(constants.MaxGuesses)}
+	if hint, err := game.RevealHint(app, taxed, true); err != nil || hint != "It starts with T." {
+		t.Fatalf("RevealHint = %q, %v", hint, err)
+	}
+	if game.MaxRows(taxed) != constants.MaxGuesses-1 || game.HintsLeft(app, taxed, true) != 1 {
+		t.Errorf("Expected one row given up, got %d rows and %d hints left", game.MaxRows(taxed), game.HintsLeft(app, taxed, true))
+	}
+	board := game.BuildBoard(taxed, -1)
+	if !board[constants.MaxGuesses-1].IsSpent || board[constants.MaxGuesses-2].IsSpent {
+		t.Error("Expected only the last row to be marked as spent")
+	}
+	for range constants.MaxGuesses - 2 {
+		game.ApplyGuess(app, dummyContext(), taxed, "ZZZZZ")
+	}
+	_, err = game.RevealHint(app, taxed, true)
+	if gameErr := game.AsGameError(err); gameErr.Code != constants.ErrorCodeHintUnavailable || gameErr.Details["reason"] != "last_guess" {
+		t.Errorf("Expected the last row to be kept, got %v", err)
+	}
+	game.ApplyGuess(app, dummyContext(), taxed, "YYYYY")
+	if !taxed.GameOver || taxed.CurrentRow != constants.MaxGuesses-1 {
+		t.Errorf("Expected the game to end a row early, got row %d, over %v", taxed.CurrentRow, taxed.GameOver)
+	}
+
+	multi := game.NewMultiBoardGame([]string{"APPLE", "TABLE"})
+	if _, err := game.RevealHint(app, multi, false); game.AsGameError(err).Code != constants.ErrorCodeHintUnavailable {
+		t.Errorf("Expected no staged hints in multi-board games, got %v", err)
+	}
+}
+
+func TestAutoContinue(t *testing.T) {
+	words := []models.WordEntry{{Word: "APPLE", Hint: "fruit"}, {Word: "TABLE", Hint: "furniture"}, {Word: "CRANE", Hint: "bird"}}
+	app := testAppWithWords(words)
+	app.AutoContinueDelay = 5 * time.Second
+	ctx := dummyContext()
+	now := time.Now()
+
+	gameState, _ := game.CreateNewGameWithCompletedWords(app, ctx, "sess", "", []string{"CRANE"})
+	game.ArmAutoContinue(app, gameState, now)
+	if !gameState.ContinueAt.IsZero() {
+		t.Fatal("A game still in play should not be continued")
+	}
+	gameState.GameOver = true
+	game.ArmAutoContinue(app, gameState, now)
+	if game.ContinueDue(gameState, now.Add(4*time.Second)) || !game.ContinueDue(gameState, now.Add(5*time.Second)) {
+		t.Errorf("Expected the next word to be due 5s after the game ended, at %v", gameState.ContinueAt)
+	}
+
+	next, reset := game.ContinueGame(app, ctx, "sess", gameState, []string{"CRANE"}, nil)
+	if reset || next.SessionWord == gameState.SessionWord || next.SessionWord == "CRANE" || next.Number != gameState.Number+1 {
+		t.Errorf("Expected the other word in game %d, got %s in game %d (reset %v)", gameState.Number+1, next.SessionWord, next.Number, reset)
+	}
+
+	tournamentGame := &models.GameState{GameOver: true, Tournament: &models.TournamentRef{}}
+	game.ArmAutoContinue(app, tournamentGame, now)
+	if !tournamentGame.ContinueAt.IsZero() {
+		t.Error("Tournament games should not be continued")
+	}
+}
+
+func TestMultiBoardGameLoss(t *testing.T) {
+	gameState := game.NewMultiBoardGame([]string{"APPLE", "TABLE", "CHAIR", "HOUSE"})
+	app := &models.App{}
+	for range game.MaxRows(gameState) {
+		game.ApplyMultiBoardGuess(app, dummyContext(), gameState, "ZZZZZ")
+	}
+	if !gameState.GameOver || gameState.Won {
+		t.Error("Game should be lost after all rows are used")
+	}
+	if gameState.CurrentRow != constants.MaxGuesses+3 {
+		t.Errorf("Expected %d rows used, got %d", constants.MaxGuesses+3, gameState.CurrentRow)
+	}
+}
+
+func TestAdvanceRace(t *testing.T) {
+	words := []models.WordEntry{{Word: "APPLE"}, {Word: "TABLE"}, {Word: "CHAIR"}}
+	app := testAppWithWords(words)
+	gameState := game.CreateNewGame(app, dummyContext(), "sess1")
+	game.StartRace(gameState, 10*time.Second)
+	start := gameState.Race.StartedAt
+
+	if game.AdvanceRace(app, dummyContext(), gameState, start.Add(5*time.Second)) {
+		t.Fatal("Bot should not have played before its first turn")
+	}
+	if len(gameState.Race.BotGuesses) != 0 {
+		t.Fatalf("Expected no bot guesses yet, got %v", gameState.Race.BotGuesses)
+	}
+
+	finished := game.AdvanceRace(app, dummyContext(), gameState, start.Add(time.Minute))
+	if !finished || !gameState.Race.BotSolved {
+		t.Fatalf("Bot should solve a three-word list in time, guesses: %v", gameState.Race.BotGuesses)
+	}
+	if !gameState.GameOver || gameState.Won {
+		t.Error("Player should lose once the bot solves the word")
+	}
+	if len(gameState.Race.BotRows) != len(gameState.Race.BotGuesses) {
+		t.Error("Every bot guess should have a row of statuses")
+	}
+}
+
+func TestCheckHardMode(t *testing.T) {
+	app := &models.App{}
+	gameState := &models.GameState{Guesses: models.PackRows([][]models.GuessResult{game.CheckGuess("TABLE", "APPLE", app)}), CurrentRow: 1}
+
+	err := game.CheckHardMode(gameState, "CHAIR")
+	var gameErr *game.GameError
+	if !errors.As(err, &gameErr) || gameErr.Code != constants.ErrorCodeHardMode {
+		t.Fatalf("Expected hard mode violation, got %v", err)
+	}
+	if gameErr.Details["position"] != 4 || gameErr.Details["letter"] != "L" {
+		t.Errorf("Expected the misplaced L at position 4, got %v", gameErr.Details)
+	}
+	want := []game.HardModeViolation{
+		{Letter: "L", Position: 4, Rule: constants.GuessStatusCorrect},
+		{Letter: "E", Position: 5, Rule: constants.GuessStatusCorrect},
+	}
+	if got, _ := gameErr.Details["violations"].([]game.HardModeViolation); !slices.Equal(got, want) {
+		t.Errorf("Expected violations %v, got %v", want, gameErr.Details["violations"])
+	}
+	if got, _ := gameErr.Details["positions"].([]int); !slices.Equal(got, []int{4, 5}) {
+		t.Errorf("Expected positions 4 and 5, got %v", gameErr.Details["positions"])
+	}
+	err = game.CheckHardMode(gameState, "CHILE")
+	if !errors.As(err, &gameErr) || gameErr.Details["letter"] != "A" || gameErr.Details["position"] != nil {
+		t.Errorf("Dropping the present A should be rejected without a position, got %v", err)
+	}
+	if err := game.CheckHardMode(gameState, "ANKLE"); err != nil {
+		t.Errorf("Guess using every hint should pass, got %v", err)
+	}
+}
+
+func TestCheckLength(t *testing.T) {
+	if err := game.CheckLength("ĈEVAL"); err != nil {
+		t.Errorf("Five letters should pass, got %v", err)
+	}
+	for guess, positions := range map[string][]int{"CAT": {4, 5}, "PLANETS": {6, 7}} {
+		gameErr := game.AsGameError(game.CheckLength(guess))
+		if gameErr.Code != constants.ErrorCodeInvalidLength || !slices.Equal(gameErr.Details["positions"].([]int), positions) {
+			t.Errorf("CheckLength(%q) = %v, want positions %v", guess, gameErr.Details, positions)
+		}
+	}
+}
+
+func TestCheckCooldown(t *testing.T) {
+	gameState := &models.GameState{}
+	now := time.Now()
+	if err := game.CheckCooldown(gameState, 0, now); err != nil || !gameState.LastAttempt.IsZero() {
+		t.Errorf("No cooldown should let every guess through, got %v", err)
+	}
+	if err := game.CheckCooldown(gameState, 2*time.Second, now); err != nil {
+		t.Fatalf("The first guess should pass, got %v", err)
+	}
+
+	gameErr := game.AsGameError(game.CheckCooldown(gameState, 2*time.Second, now.Add(500*time.Millisecond)))
+	if gameErr == nil || gameErr.Code != constants.ErrorCodeTooFast || gameErr.Status != http.StatusTooManyRequests {
+		t.Fatalf("Expected too_fast, got %v", gameErr)
+	}
+	if wait := gameErr.Details["retry_after_ms"]; wait != int64(1500) {
+		t.Errorf("Expected 1500ms left, got %v", wait)
+	}
+	if !gameState.LastAttempt.Equal(now) {
+		t.Error("A refused guess should not restart the cooldown")
+	}
+	if err := game.CheckCooldown(gameState, 2*time.Second, now.Add(2*time.Second)); err != nil {
+		t.Errorf("A guess after the cooldown should pass, got %v", err)
+	}
+}
+
+func TestValidateSettings(t *testing.T) {
+	settings := models.UserSettings{Language: " EO "}
+	if err := game.ValidateSettings(&settings); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if settings.Language != "eo" || settings.KeyboardLayout != constants.KeyboardLayoutQwerty {
+		t.Errorf("Settings not normalised: %+v", settings)
+	}
+	settings.KeyboardLayout = "dvorak"
+	if err := game.ValidateSettings(&settings); game.AsGameError(err).Code != constants.ErrorCodeInvalidSettings {
+		t.Errorf("Expected invalid_settings, got %v", err)
+	}
+}
+
+func TestSuggestGuesses(t *testing.T) {
+	words := []models.WordEntry{{Word: "APPLE"}, {Word: "AMPLE"}, {Word: "ANGLE"}, {Word: "TABLE"}}
+	app := testAppWithWords(words)
+	game.SetDictionary(app, words, map[string]struct{}{"MANGO": {}})
+	gameState := game.CreateNewGame(app, dummyContext(), "sess1")
+	gameState.SessionWord = "APPLE"
+
+	suggestions, remaining := game.SuggestGuesses(app, gameState, 3)
+	if remaining != 4 || len(suggestions) != 3 {
+		t.Fatalf("Expected 3 suggestions over 4 answers, got %d over %d", len(suggestions), remaining)
+	}
+	if suggestions[0].Entropy < suggestions[1].Entropy {
+		t.Error("Suggestions should be ordered by entropy")
+	}
+
+	game.UpdateGameState(app, dummyContext(), gameState, "ANGLE", "APPLE", game.CheckGuess("ANGLE", "APPLE", app), false)
+	if got := game.RemainingAnswers(app, gameState); len(got) != 2 {
+		t.Errorf("Expected APPLE and AMPLE to remain after ANGLE, got %v", got)
+	}
+	suggestions, _ = game.SuggestGuesses(app, gameState, 5)
+	for _, s := range suggestions {
+		if s.Word == "ANGLE" {
+			t.Error("Previous guesses should not be suggested")
+		}
+	}
+}
+
+func TestWeeklyWordsDeterministic(t *testing.T) {
+	words := []models.WordEntry{{Word: "APPLE"}, {Word: "TABLE"}, {Word: "CHAIR"}, {Word: "HOUSE"}, {Word: "PLANT"}, {Word: "BRICK"}, {Word: "STONE"}, {Word: "CLOUD"}}
+	app := testAppWithWords(words)
+	first := game.WeeklyWords(app, "2026-W42", 7)
+	if len(first) != 7 {
+		t.Fatalf("Expected 7 words, got %v", first)
+	}
+	if fmt.Sprint(first) != fmt.Sprint(game.WeeklyWords(app, "2026-W42", 7)) {
+		t.Error("The same week should always get the same words")
+	}
+	if fmt.Sprint(first) == fmt.Sprint(game.WeeklyWords(app, "2026-W43", 7)) {
+		t.Error("Different weeks should get different words")
+	}
+}
+
+func TestSetDictionaryDuringLookups(t *testing.T) {
+	app := testAppWithWords([]models.WordEntry{{Word: "APPLE", Hint: "fruit"}})
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		for i := range 100 {
+			word := fmt.Sprintf("WORD%d", i%10)
+			game.SetDictionary(app, []models.WordEntry{{Word: "APPLE", Hint: "fruit"}, {Word: word}}, nil)
+		}
+	})
+	for range 4 {
+		wg.Go(func() {
+			for range 100 {
+				if !game.IsAcceptedWord(app, "APPLE") || !game.IsValidWord(app, "APPLE") {
+					t.Error("APPLE should stay in every dictionary")
+					return
+				}
+				if hint, ok := game.LookupHint(app, "APPLE"); !ok || hint != "fruit" {
+					t.Errorf("Unexpected hint %q", hint)
+					return
+				}
+			}
+		})
+	}
+	wg.Wait()
+	if words, accepted := game.DictionarySize(app); words != 2 || accepted != 2 {
+		t.Errorf("Expected the last dictionary, got %d words and %d accepted", words, accepted)
+	}
+}
+
+func TestCheckWord(t *testing.T) {
+	app := testAppWithWords([]models.WordEntry{{Word: "APPLE"}, {Word: "APRON"}, {Word: "TABLE"}})
+	app.BlockedWordSet = map[string]struct{}{"TABLE": {}}
+
+	cases := []struct {
+		word          string
+		prefix, valid bool
+		complete      bool
+	}{
+		{"AP", true, false, false},
+		{"APR", true, false, false},
+		{"APX", false, false, false},
+		{"APPLE", true, true, true},
+		{"TABLE", true, false, true},
+		{"ZZZZZ", false, false, true},
+		{"", false, false, false},
+	}
+	for _, tc := range cases {
+		got := game.CheckWord(app, tc.word)
+		if got.Prefix != tc.prefix || got.Valid != tc.valid || got.Complete != tc.complete {
+			t.Errorf("CheckWord(%q) = %+v", tc.word, got)
+		}
+	}
+}
+
+func TestCheckGuessUnicode(t *testing.T) {
+	pool := &sync.Pool{New: func() any {
+		return new([constants.WordLength]models.GuessResult)
+	}}
+	for _, app := range []*models.App{{}, {ResultPool: pool}} {
+		res := game.CheckGuess("ĈAMBO", "ŜAĈOJ", app)
+		want := []string{constants.GuessStatusPresent, constants.GuessStatusCorrect, constants.GuessStatusAbsent, constants.GuessStatusAbsent, constants.GuessStatusPresent}
+		for i, r := range res {
+			if r.Status != want[i] {
+				t.Errorf("Letter %d (%s): got %s, want %s", i, r.Letter, r.Status, want[i])
+			}
+		}
+		if res[0].Letter != "Ĉ" {
+			t.Errorf("Expected whole letter Ĉ, got %q", res[0].Letter)
+		}
+		for _, r := range game.CheckGuess("ŜAĈOJ", "ŜAĈOJ", app) {
+			if r.Status != constants.GuessStatusCorrect {
+				t.Errorf("Identical words should be all correct, got %+v", r)
+			}
+		}
+	}
+}
+
+func TestParseGuess(t *testing.T) {
+	guess := game.ParseGuess(" ĉambo ")
+	if guess.Word != "ĈAMBO" || guess.Len != constants.WordLength || guess.Runes[0] != 'Ĉ' || guess.CheckLength() != nil {
+		t.Fatalf("Unexpected parsed guess: %+v", guess)
+	}
+	if short := game.ParseGuess("ĉam"); short.Len != 3 || short.CheckLength() == nil {
+		t.Errorf("A short guess should fail the length check, got %+v", short)
+	}
+
+	app := testAppWithWords([]models.WordEntry{{Word: "ŜAĈOJ"}})
+	if entry, ok := app.Dictionary().Entry("ŜAĈOJ"); !ok || entry.Runes != (models.WordRunes{'Ŝ', 'A', 'Ĉ', 'O', 'J'}) {
+		t.Errorf("Expected the target word decoded with the dictionary, got %+v %v", entry, ok)
+	}
+	gameState := &models.GameState{SessionWord: "ŜAĈOJ", Guesses: models.NewRows(constants.MaxGuesses)}
+	game.ApplyParsedGuess(app, dummyContext(), gameState, guess)
+	if got, want := gameState.Guesses.Row(0), game.CheckGuess("ĈAMBO", "ŜAĈOJ", app); !slices.Equal(got, want) {
+		t.Errorf("A parsed guess should score as CheckGuess does: got %+v, want %+v", got, want)
+	}
+}
+
+func TestLetterCounts(t *testing.T) {
+	counts := models.CountLetters(models.WordRunes{'L', 'E', 'V', 'E', 'L'})
+	if counts.N != 3 || counts.Count('L') != 2 || counts.Count('E') != 2 || counts.Count('V') != 1 || counts.Count('Z') != 0 {
+		t.Fatalf("Unexpected counts of LEVEL: %+v", counts)
+	}
+
+	app := testAppWithWords([]models.WordEntry{{Word: "LEVEL"}})
+	if entry, ok := app.Dictionary().Entry("LEVEL"); !ok || entry.Counts != counts {
+		t.Errorf("Expected the counts worked out with the dictionary, got %+v", entry)
+	}
+	// Three Ls guessed against two: the one in place and the next are
+	// marked, the third is not.
+	if got, want := pattern(game.CheckGuess("LLLAM", "LEVEL", app)), "GY---"; got != want {
+		t.Errorf("CheckGuess(LLLAM, LEVEL) = %s, want %s", got, want)
+	}
+}
+
+func TestNormalizeWord(t *testing.T) {
+	decomposed := "ĉambo"
+	if got := game.NormalizeWord("  " + decomposed + " "); got != "ĈAMBO" {
+		t.Errorf("NormalizeWord(%q) = %q, want ĈAMBO", decomposed, got)
+	}
+	if got := game.WordLen(game.NormalizeWord(decomposed)); got != constants.WordLength {
+		t.Errorf("Normalized word should have %d letters, got %d", constants.WordLength, got)
+	}
+	if got := game.NormalizeWord("ŝaĉoj"); got != "ŜAĈOJ" {
+		t.Errorf("NormalizeWord should upper-case diacritics, got %q", got)
+	}
+}
+
+func TestHardModeUnicode(t *testing.T) {
+	app := &models.App{}
+	gameState := &models.GameState{Guesses: models.PackRows([][]models.GuessResult{game.CheckGuess("ĈAMBO", "ĈEVAL", app)}), CurrentRow: 1}
+	if err := game.CheckHardMode(gameState, "ĈEVAL"); err != nil {
+		t.Errorf("Guess keeping Ĉ in place should be allowed: %v", err)
+	}
+	if err := game.CheckHardMode(gameState, "ŜEVAL"); err == nil {
+		t.Error("Guess dropping the correct Ĉ should be rejected")
+	}
+}
+
+func TestLoadDefinitions(t *testing.T) {
+	dir := t.TempDir()
+	defs, err := game.LoadDefinitions(filepath.Join(dir, "missing.json"))
+	if err != nil || len(defs) != 0 {
+		t.Fatalf("A missing definitions file should yield none, got %v, %v", defs, err)
+	}
+
+	path := filepath.Join(dir, "definitions.json")
+	data := `{"definitions": [
+		{"word": "apple", "partOfSpeech": "noun", "definition": "A round fruit."},
+		{"word": "EMPTY", "definition": ""}
+	]}`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	defs, err = game.LoadDefinitions(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := &models.App{Definitions: defs}
+	if def, ok := game.GetDefinition(app, "APPLE"); !ok || def.PartOfSpeech != "noun" {
+		t.Errorf("Expected a normalized entry for APPLE, got %+v", def)
+	}
+	if _, ok := game.GetDefinition(app, "EMPTY"); ok {
+		t.Error("Entries without a definition should be skipped")
+	}
+
+	if err := os.WriteFile(path, []byte("not json"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := game.LoadDefinitions(path); err == nil {
+		t.Error("Expected an error for a malformed file")
+	}
+}
+
+func TestRowsRoundTrip(t *testing.T) {
+	app := &models.App{}
+	rows := models.NewRows(3)
+	first := game.CheckGuess("ĈAMBO", "ŜAĈOJ", app)
+	rows.Set(1, first)
+	if rows.Words[1] != "ĈAMBO" {
+		t.Errorf("Expected the guess to be stored as a word, got %q", rows.Words[1])
+	}
+	if got := rows.Row(1); !slices.Equal(got, first) {
+		t.Errorf("Row(1) = %v, want %v", got, first)
+	}
+	if got := rows.Row(0); len(got) != constants.WordLength || got[0] != (models.GuessResult{}) {
+		t.Errorf("An unplayed row should expand to empty tiles, got %v", got)
+	}
+
+	data, err := json.Marshal(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded models.Rows
+	if err := json.Unmarshal(data, &decoded); err != nil || !slices.Equal(decoded.Row(1), first) {
+		t.Errorf("Rows should survive JSON, got %v, %v", decoded.Row(1), err)
+	}
+
+	legacy, _ := json.Marshal([][]models.GuessResult{first, make([]models.GuessResult, constants.WordLength)})
+	if err := json.Unmarshal(legacy, &decoded); err != nil || decoded.Len() != 2 || !slices.Equal(decoded.Row(0), first) {
+		t.Errorf("Legacy GuessResult grids should still decode, got %+v, %v", decoded, err)
+	}
+}
+
+func TestHeatmap(t *testing.T) {
+	app := &models.App{}
+	var heatmap models.Heatmap
+	heatmap.Record(game.CheckGuess("CRANE", "CRONE", app))
+	heatmap.Record(game.CheckGuess("CREPT", "CRONE", app))
+	heatmap.Record(game.CheckGuess("ĈARMO", "CRONE", app))
+	if heatmap.Guesses != 3 || heatmap.Letters["C"] != 2 || heatmap.Letters["R"] != 3 {
+		t.Fatalf("Unexpected letter counts: %+v", heatmap)
+	}
+	if !slices.Equal(heatmap.PositionMisses, []int{1, 1, 3, 2, 2}) {
+		t.Errorf("Expected position misses [1 1 3 2 2], got %v", heatmap.PositionMisses)
+	}
+
+	view := game.BuildHeatmap(heatmap, constants.KeyboardLayoutQwerty)
+	levels := map[string]int{}
+	for _, row := range view.Rows {
+		for _, key := range row {
+			levels[key.Letter] = key.Level
+		}
+	}
+	if levels["R"] != constants.HeatLevels-1 || levels["Q"] != 0 || levels["C"] == 0 {
+		t.Errorf("Unexpected levels: %v", levels)
+	}
+	if _, ok := levels["Ĉ"]; !ok || len(view.Rows) != 4 {
+		t.Errorf("Expected Ĉ on a row of its own, got %d rows", len(view.Rows))
+	}
+	if view.Positions[2].Rate != 100 || view.Positions[0].Rate != 33 {
+		t.Errorf("Unexpected position rates: %+v", view.Positions)
+	}
+}
+
+func TestDailyWordsFollowSchedule(t *testing.T) {
+	words := []models.WordEntry{{Word: "APPLE"}, {Word: "TABLE"}, {Word: "CHAIR"}, {Word: "HOUSE"}, {Word: "PLANT"}, {Word: "BRICK"}, {Word: "STONE"}, {Word: "CLOUD"}}
+	app := testAppWithWords(words)
+	app.Schedule, _ = schedule.Open("")
+	app.Tournament, _ = tournament.Open("")
+	monday := time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)
+	if fmt.Sprint(game.DailyWords(app, monday)) != fmt.Sprint(game.WeeklyWords(app, "2026-W42", 7)) {
+		t.Error("An unscheduled week should get the weekly selection")
+	}
+
+	wednesday := monday.AddDate(0, 0, 2)
+	for _, tt := range []struct {
+		date, word string
+		want       error
+	}{
+		{"2026-10-12", "CLOUD", game.ErrScheduleDate},
+		{"2026-10-13", "QUEUE", game.ErrWordMissing},
+		{"2026-10-16", "CLOUD", nil},
+		{"2026-10-17", "cloud", game.ErrWordScheduled},
+	} {
+		_, _, err := game.ScheduleWord(app, schedule.Entry{Date: tt.date, Word: tt.word}, monday.Add(time.Hour))
+		if !errors.Is(err, tt.want) {
+			t.Errorf("Scheduling %s for %s: got %v, want %v", tt.word, tt.date, err, tt.want)
+		}
+	}
+	week := game.DailyWords(app, monday)
+	if week[4] != "CLOUD" || slices.Index(week, "CLOUD") != 4 {
+		t.Errorf("Expected CLOUD on Friday only, got %v", week)
+	}
+
+	// A tournament already running takes a schedule for its later days, but
+	// keeps the days begun.
+	current := game.CurrentTournament(app, wednesday)
+	played := current.Words[:3]
+	if _, _, err := game.ScheduleWord(app, schedule.Entry{Date: "2026-10-18", Word: played[0]}, wednesday); err != nil {
+		t.Fatal(err)
+	}
+	updated := game.CurrentTournament(app, wednesday)
+	if updated.Words[6] != played[0] || fmt.Sprint(updated.Words[:3]) != fmt.Sprint(played) {
+		t.Errorf("Expected Sunday scheduled and the days begun kept, got %v from %v", updated.Words, current.Words)
+	}
+	calendar := game.Calendar(app, wednesday, 7)
+	if len(calendar) != 7 || calendar[0].Date != "2026-10-14" || !calendar[4].Scheduled {
+		t.Fatalf("Calendar = %+v", calendar)
+	}
+	if calendar[4].Word != played[0] || len(calendar[4].Conflicts) != 0 {
+		t.Errorf("Expected Sunday to play %s, got %+v", played[0], calendar[4])
+	}
+	app.BlockedWordSet = map[string]struct{}{played[0]: {}}
+	if calendar := game.Calendar(app, wednesday, 7); len(calendar[4].Conflicts) != 1 {
+		t.Errorf("Expected a blocked word scheduled to conflict, got %+v", calendar[4])
+	}
+	app.BlockedWordSet = nil
+	if _, err := game.UnscheduleWord(app, "2026-10-18", wednesday); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := game.UnscheduleWord(app, "2026-10-18", wednesday); !errors.Is(err, game.ErrNotScheduled) {
+		t.Errorf("Expected nothing left to unschedule, got %v", err)
+	}
+	if _, _, err := game.ScheduleWord(app, schedule.Entry{Date: "12/10/2026", Word: "CLOUD"}, wednesday); err == nil {
+		t.Error("Expected a malformed date to be refused")
+	}
+}
+
+func TestRetiredWords(t *testing.T) {
+	if _, err := game.ParseWordList([]byte(`{"words": [{"word": "apple", "status": "gone"}]}`)); err == nil {
+		t.Error("Expected an unknown status to be refused")
+	}
+	if _, err := game.ParseWordList([]byte(`{"words": [{"word": "apple", "status": "retired"}]}`)); err == nil {
+		t.Error("Expected a list of retired words only to be refused")
+	}
+	words, err := game.ParseWordList([]byte(`{"words": [{"word": "apple"}, {"word": "table", "status": "retired", "pools": ["kids"]}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := testAppWithWords(words)
+	for range 20 {
+		if entry := game.GetRandomWordEntry(app, dummyContext()); entry.Word != "APPLE" {
+			t.Fatalf("A retired word was drawn: %s", entry.Word)
+		}
+	}
+	if !game.IsValidWord(app, "TABLE") || !game.IsAcceptedWord(app, "TABLE") || !game.IsRetiredWord(app, "TABLE") {
+		t.Error("A retired word should stay a target word and an accepted guess")
+	}
+	if game.HasPool(app, "kids") {
+		t.Error("A pool of retired words only should not be offered")
+	}
+	if got := game.WeeklyWords(app, "2026-W42", 7); !slices.Equal(got, []string{"APPLE"}) {
+		t.Errorf("WeeklyWords = %v, want only APPLE", got)
+	}
+}
+
+func TestTypeKey(t *testing.T) {
+	gameState := &models.GameState{Guesses: models.NewRows(constants.MaxGuesses)}
+	for _, key := range []string{"c", "R", "A", "N", "Ĉ", "E", constants.KeyBackspace, "e"} {
+		if enter, err := game.TypeKey(gameState, key); enter || err != nil {
+			t.Fatalf("TypeKey(%q) = %v, %v", key, enter, err)
+		}
+	}
+	if gameState.Draft != "CRANE" {
+		t.Errorf("Draft = %q, want CRANE", gameState.Draft)
+	}
+	for _, key := range []string{"", "AB", "1", "?"} {
+		if _, err := game.TypeKey(gameState, key); err == nil || game.AsGameError(err).Code != constants.ErrorCodeInvalidKey {
+			t.Errorf("Expected %q to be refused, got %v", key, err)
+		}
+	}
+	if enter, err := game.TypeKey(gameState, "enter"); !enter || err != nil || gameState.Draft != "CRANE" {
+		t.Errorf("Enter should leave the draft to guess, got %v %v %q", enter, err, gameState.Draft)
+	}
+	if board := game.BuildBoard(gameState, -1); board[0].Draft != [5]string{"C", "R", "A", "N", "E"} {
+		t.Errorf("Expected the draft on the current row, got %v", board[0].Draft)
+	}
+
+	gameState.GameOver = true
+	if _, err := game.TypeKey(gameState, "A"); err == nil {
+		t.Error("A finished game should not take keys")
+	}
+}
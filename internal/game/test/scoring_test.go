@@ -0,0 +1,177 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	constants "github.com/CodeAndHammer/vortludo/internal/constants"
+	game "github.com/CodeAndHammer/vortludo/internal/game"
+	models "github.com/CodeAndHammer/vortludo/internal/models"
+)
+
+// pattern writes statuses the way players read them: G for correct, Y for
+// present and - for absent.
+func pattern(result []models.GuessResult) string {
+	var b strings.Builder
+	for _, tile := range result {
+		switch tile.Status {
+		case constants.GuessStatusCorrect:
+			b.WriteByte('G')
+		case constants.GuessStatusPresent:
+			b.WriteByte('Y')
+		default:
+			b.WriteByte('-')
+		}
+	}
+	return b.String()
+}
+
+func TestRepeatedLetterScoring(t *testing.T) {
+	tests := []struct {
+		guess, target, want string
+		why                 string
+	}{
+		{"SPEED", "ABIDE", "--Y-Y", "one E in the target, used by the first E"},
+		{"SPEED", "ERASE", "Y-YY-", "two Es in the target, one for each E of the guess"},
+		{"SPEED", "STEAL", "G-G--", "the in-place E wins over the second"},
+		{"SPEED", "CREPE", "-YGY-", "the second E uses the E at target position 5"},
+		{"ABBEY", "BABES", "YYGG-", "each B uses a different B"},
+		{"BOBBY", "BLOBS", "GY-G-", "the middle B finds both Bs matched in place"},
+		{"EERIE", "THREE", "Y-G-G", "the in-place E is scored first, leaving one E for the first"},
+		{"EERIE", "EVERY", "GYY--", "one E left after the match, taken by the second E, not the last"},
+		{"EERIE", "CRANE", "--Y-G", "a single E goes to its in-place copy, leaving the earlier Es grey"},
+		{"LLAMA", "HELLO", "YY---", "two Ls in each word, out of place"},
+		{"HELLO", "LLAMA", "--YY-", "both Ls present, the O absent"},
+		{"MAMMA", "AMMAM", "YYGYY", "three Ms and two As, all used once"},
+		{"EEEEE", "THERE", "--G-G", "only the in-place Es count"},
+		{"EEEEE", "EMBER", "G--G-", "two Es, both in place"},
+		{"TATTY", "TREAT", "GYY--", "the target's two Ts: one in place, one for the first spare T"},
+		{"ALLOY", "LOYAL", "YYYYY", "every letter out of place, the As and Ls pair up"},
+		{"ŜAĈOJ", "ĈAMBO", "-GYY-", "letters with diacritics are single letters"},
+	}
+	for _, tt := range tests {
+		got := pattern(game.CheckGuess(tt.guess, tt.target, &models.App{}))
+		if got != tt.want {
+			t.Errorf("%s against %s scored %s, want %s: %s", tt.guess, tt.target, got, tt.want, tt.why)
+		}
+		if traced := pattern(game.TraceGuess(tt.guess, tt.target).Result); traced != got {
+			t.Errorf("Trace of %s against %s scored %s, CheckGuess %s", tt.guess, tt.target, traced, got)
+		}
+	}
+}
+
+// TestTraceMatchesCheckGuess compares the trace with CheckGuess and the
+// reference scoring for every pair of words over a three-letter alphabet,
+// which covers every arrangement of double and triple letters.
+func TestTraceMatchesCheckGuess(t *testing.T) {
+	var words []string
+	var build func(prefix string)
+	build = func(prefix string) {
+		if len(prefix) == constants.WordLength {
+			words = append(words, prefix)
+			return
+		}
+		for _, letter := range "ABC" {
+			build(prefix + string(letter))
+		}
+	}
+	build("")
+
+	app := &models.App{}
+	for _, guess := range words {
+		for _, target := range words {
+			trace := game.TraceGuess(guess, target)
+			want := referenceScore([]rune(guess), []rune(target))
+			for i, tile := range game.CheckGuess(guess, target, app) {
+				if tile != trace.Result[i] || tile.Status != want[i] {
+					t.Fatalf("%s against %s: tile %d is %+v, traced %+v, want %s", guess, target, i+1, tile, trace.Result[i], want[i])
+				}
+			}
+			consumed := make(map[int]bool)
+			for _, step := range trace.Steps {
+				if step.Consumed == 0 {
+					continue
+				}
+				if consumed[step.Consumed] || target[step.Consumed-1] != guess[step.Position-1] {
+					t.Fatalf("%s against %s: step %+v consumes a wrong or used letter", guess, target, step)
+				}
+				consumed[step.Consumed] = true
+			}
+		}
+	}
+}
+
+func TestTraceExplainsSteps(t *testing.T) {
+	trace := game.TraceGuess("BOBBY", "BLOBS")
+	want := []game.ScoreStep{
+		{Position: 1, Letter: "B", Status: constants.GuessStatusCorrect, Consumed: 1, Pass: 1},
+		{Position: 2, Letter: "O", Status: constants.GuessStatusPresent, Consumed: 3, Pass: 2},
+		{Position: 3, Letter: "B", Status: constants.GuessStatusAbsent, Pass: 2},
+		{Position: 4, Letter: "B", Status: constants.GuessStatusCorrect, Consumed: 4, Pass: 1},
+		{Position: 5, Letter: "Y", Status: constants.GuessStatusAbsent, Pass: 2},
+	}
+	for i, step := range trace.Steps {
+		reason := step.Reason
+		step.Reason = ""
+		if step != want[i] || reason == "" {
+			t.Errorf("Step %d = %+v (%q), want %+v", i+1, step, reason, want[i])
+		}
+	}
+	if reason := trace.Steps[2].Reason; !strings.Contains(reason, "1 (by guess position 1)") || !strings.Contains(reason, "4 (by guess position 4)") {
+		t.Errorf("Expected the absent B to name the Bs that used the target's, got %q", reason)
+	}
+	if reason := trace.Steps[4].Reason; reason != "the target has no Y" {
+		t.Errorf("Unexpected reason for Y: %q", reason)
+	}
+}
+
+func TestAdversarialGames(t *testing.T) {
+	words := []models.WordEntry{{Word: "BRAKE"}, {Word: "CRATE"}, {Word: "GRATE"}, {Word: "IRATE"}, {Word: "TRACE"}, {Word: "CRANE"}, {Word: "BLOND"}}
+	app := testAppWithWords(words)
+	gameState := game.CreateNewGame(app, dummyContext(), "sess")
+	game.StartAdversarial(gameState)
+	if game.Mode(gameState) != constants.ModeAdversarial || game.HintsLeft(app, gameState, false) != 0 {
+		t.Fatalf("Expected an adversarial game without hints, got %+v", gameState)
+	}
+	if _, err := game.RevealHint(app, gameState, false); game.AsGameError(err).Code != constants.ErrorCodeHintUnavailable {
+		t.Errorf("Expected hints unavailable, got %v", err)
+	}
+
+	inPlay := make([]string, 0, len(words))
+	for _, entry := range words {
+		inPlay = append(inPlay, entry.Word)
+	}
+	for row, guess := range []string{"CRATE", "GRATE", "IRATE", "BRAKE", "TRACE", "CRANE", "BLOND"} {
+		if gameState.GameOver {
+			break
+		}
+		groups := make(map[string]int)
+		for _, word := range inPlay {
+			groups[pattern(game.CheckGuess(guess, word, app))]++
+		}
+		game.ApplyGuess(app, dummyContext(), gameState, guess)
+		got := pattern(gameState.Guesses.Row(row))
+		for _, size := range groups {
+			if size > groups[got] {
+				t.Fatalf("Guess %s kept %d words as %s, but a group of %d existed", guess, groups[got], got, size)
+			}
+		}
+		inPlay = gameState.Adversarial.Candidates
+		for _, word := range inPlay {
+			if pattern(game.CheckGuess(guess, word, app)) != got {
+				t.Fatalf("Word %s in play does not give %s the feedback %s", word, guess, got)
+			}
+		}
+		if gameState.Won != (len(inPlay) == 1 && inPlay[0] == guess) {
+			t.Fatalf("Guess %s won=%v with %v in play", guess, gameState.Won, inPlay)
+		}
+	}
+	if !gameState.Won {
+		t.Errorf("Expected the game won once one word was left, got %+v", gameState)
+	}
+
+	multi, _ := game.CreateMultiBoardGame(app, dummyContext(), "sess", "", 2, nil)
+	if game.StartAdversarial(multi); multi.Adversarial != nil {
+		t.Error("Multi-board games should not be adversarial")
+	}
+}
@@ -0,0 +1,90 @@
+package game
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"math/rand/v2"
+	"slices"
+	"time"
+
+	constants "github.com/CodeAndHammer/vortludo/internal/constants"
+	models "github.com/CodeAndHammer/vortludo/internal/models"
+	tournament "github.com/CodeAndHammer/vortludo/internal/tournament"
+	util "github.com/CodeAndHammer/vortludo/internal/util"
+)
+
+// WeeklyWords deterministically picks n distinct target words for seed, so
+// every instance of the server agrees on a week's tournament words.
+func WeeklyWords(app *models.App, seed string, n int) []string {
+	entries := selectableWords(app, constants.PoolClassic)
+	words := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		words = append(words, entry.Word)
+	}
+	slices.Sort(words)
+	words = slices.Compact(words)
+
+	sum := sha256.Sum256([]byte(seed))
+	rng := rand.New(rand.NewPCG(binary.BigEndian.Uint64(sum[:8]), binary.BigEndian.Uint64(sum[8:16])))
+	rng.Shuffle(len(words), func(i, j int) { words[i], words[j] = words[j], words[i] })
+	return words[:min(n, len(words))]
+}
+
+// CurrentTournament returns this week's tournament, starting it with the
+// week's DailyWords if needed.
+func CurrentTournament(app *models.App, now time.Time) tournament.Tournament {
+	return app.Tournament.Current(now, func(string) []string {
+		return DailyWords(app, tournament.WeekStart(now))
+	})
+}
+
+// CreateTournamentGame starts today's tournament game for player. It returns
+// nil when the player has already played today.
+func CreateTournamentGame(app *models.App, ctx context.Context, sessionID, player string) *models.GameState {
+	defer holdWords(app)()
+	now := time.Now()
+	t := CurrentTournament(app, now)
+	day := tournament.DayIndex(now)
+	if day >= len(t.Words) || t.Words[day] == "" || app.Tournament.HasPlayed(t.Week, day, player) {
+		return nil
+	}
+	word := t.Words[day]
+	util.LogInfo("Tournament %s day %d game created for session %s", t.Week, day+1, sessionID)
+
+	game := &models.GameState{
+		ID:             NewGameID(),
+		Guesses:        models.NewRows(constants.MaxGuesses),
+		SessionWord:    word,
+		GuessHistory:   []string{},
+		LastAccessTime: now,
+		Tournament: &models.TournamentRef{
+			Week:      t.Week,
+			Day:       day,
+			Player:    player,
+			StartedAt: now,
+		},
+		Puzzle: tournament.PuzzleNumber(now),
+	}
+	SaveNewGame(app, sessionID, game)
+	return game
+}
+
+// RecordTournamentResult scores a finished tournament game. The results of
+// sessions flagged as bots are marked, to be left out of the standings,
+// when app.ExcludeBots is set.
+func RecordTournamentResult(app *models.App, sessionID string, game *models.GameState) {
+	ref := game.Tournament
+	if ref == nil || !game.GameOver {
+		return
+	}
+	result := tournament.DayResult{
+		Solved:  game.Won,
+		Guesses: len(game.GuessHistory),
+		Seconds: int(time.Since(ref.StartedAt).Seconds()),
+		Flagged: app.ExcludeBots && app.Bots.Flagged(sessionID),
+	}
+	if app.Tournament.Record(ref.Week, ref.Day, ref.Player, result) {
+		util.LogInfo("Recorded tournament %s day %d result for %s: solved=%v guesses=%d flagged=%v", ref.Week, ref.Day+1, tournament.PlayerLabel(ref.Player), result.Solved, result.Guesses, result.Flagged)
+	}
+}
@@ -0,0 +1,92 @@
+package game
+
+import (
+	"fmt"
+	"strings"
+
+	constants "github.com/CodeAndHammer/vortludo/internal/constants"
+	models "github.com/CodeAndHammer/vortludo/internal/models"
+)
+
+// ScoreStep explains how one letter of a guess was scored. Positions count
+// from 1. Consumed is the target position whose letter the guess letter used
+// up, or 0 when it found none left.
+type ScoreStep struct {
+	Position int    `json:"position"`
+	Letter   string `json:"letter"`
+	Status   string `json:"status"`
+	Consumed int    `json:"consumed,omitempty"`
+	// Pass is 1 for letters matched in place, which are scored first, and 2
+	// for the rest, scored from left to right.
+	Pass   int    `json:"pass"`
+	Reason string `json:"reason"`
+}
+
+// ScoreTrace is the full scoring of a guess against a target.
+type ScoreTrace struct {
+	Guess  string               `json:"guess"`
+	Target string               `json:"target"`
+	Result []models.GuessResult `json:"result"`
+	Steps  []ScoreStep          `json:"steps"`
+}
+
+// TraceGuess scores guess against target the way CheckGuess does, recording
+// which target letter each guess letter consumed. It is for explaining
+// puzzling results with repeated letters, not for scoring games. Both words
+// must have WordLength letters.
+func TraceGuess(guess, target string) ScoreTrace {
+	guessRunes, targetRunes := wordRunes(guess), wordRunes(target)
+	trace := ScoreTrace{
+		Guess:  guess,
+		Target: target,
+		Result: make([]models.GuessResult, constants.WordLength),
+		Steps:  make([]ScoreStep, constants.WordLength),
+	}
+	consumedBy := make([]int, constants.WordLength)
+	for i := range constants.WordLength {
+		trace.Steps[i] = ScoreStep{Position: i + 1, Letter: string(guessRunes[i])}
+		if guessRunes[i] == targetRunes[i] {
+			consumedBy[i] = i + 1
+			trace.Steps[i].Status = constants.GuessStatusCorrect
+			trace.Steps[i].Consumed = i + 1
+			trace.Steps[i].Pass = 1
+			trace.Steps[i].Reason = "matches the target letter in place"
+		}
+	}
+	for i := range constants.WordLength {
+		step := &trace.Steps[i]
+		if step.Status != "" {
+			continue
+		}
+		step.Pass = 2
+		letter := guessRunes[i]
+		for j := range constants.WordLength {
+			if targetRunes[j] == letter && consumedBy[j] == 0 {
+				consumedBy[j] = i + 1
+				step.Status = constants.GuessStatusPresent
+				step.Consumed = j + 1
+				step.Reason = fmt.Sprintf("uses the unmatched %c at target position %d", letter, j+1)
+				break
+			}
+		}
+		if step.Status != "" {
+			continue
+		}
+		step.Status = constants.GuessStatusAbsent
+		var users []string
+		for j := range constants.WordLength {
+			if targetRunes[j] == letter {
+				users = append(users, fmt.Sprintf("%d (by guess position %d)", j+1, consumedBy[j]))
+			}
+		}
+		if len(users) == 0 {
+			step.Reason = fmt.Sprintf("the target has no %c", letter)
+		} else {
+			step.Reason = fmt.Sprintf("every %c of the target is already used: target position %s", letter, strings.Join(users, ", "))
+		}
+	}
+	for i, step := range trace.Steps {
+		trace.Result[i] = models.GuessResult{Letter: step.Letter, Status: step.Status}
+	}
+	return trace
+}
@@ -0,0 +1,49 @@
+package game
+
+import (
+	"unicode/utf8"
+
+	constants "github.com/CodeAndHammer/vortludo/internal/constants"
+	models "github.com/CodeAndHammer/vortludo/internal/models"
+)
+
+// CheckLength refuses a guess of the wrong length. The error lists the
+// positions that are missing or extra.
+func CheckLength(guess string) error {
+	return checkLength(WordLen(guess))
+}
+
+func checkLength(length int) error {
+	if length == constants.WordLength {
+		return nil
+	}
+	positions := []int{}
+	for i := min(length, constants.WordLength); i < max(length, constants.WordLength); i++ {
+		positions = append(positions, i+1)
+	}
+	return NewGameError(constants.ErrorCodeInvalidLength).
+		WithDetail("length", length).
+		WithDetail("expected", constants.WordLength).
+		WithDetail("positions", positions)
+}
+
+// WordCheck is the answer of the validate endpoint. Prefix reports whether
+// any accepted word starts with Word; Valid is only set for a complete word
+// that would be accepted as a guess.
+type WordCheck struct {
+	Word     string `json:"word"`
+	Complete bool   `json:"complete"`
+	Prefix   bool   `json:"prefix"`
+	Valid    bool   `json:"valid"`
+}
+
+// CheckWord reports whether word, or a partial word, can become a guess.
+func CheckWord(app *models.App, word string) WordCheck {
+	check := WordCheck{Word: word, Complete: utf8.RuneCountInString(word) == constants.WordLength}
+	if word == "" || utf8.RuneCountInString(word) > constants.WordLength {
+		return check
+	}
+	check.Prefix = app.Dictionary().Accepted.HasPrefix(word)
+	check.Valid = check.Complete && IsAcceptedWord(app, word) && !IsBlockedWord(app, word)
+	return check
+}
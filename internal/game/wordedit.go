@@ -0,0 +1,252 @@
+package game
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	constants "github.com/CodeAndHammer/vortludo/internal/constants"
+	models "github.com/CodeAndHammer/vortludo/internal/models"
+	util "github.com/CodeAndHammer/vortludo/internal/util"
+)
+
+// Reasons AddWord and RemoveWord refuse an edit.
+var (
+	ErrWordListReadOnly = errors.New("the word list is not a local, unpinned file, so it cannot be edited")
+	ErrWordLength       = fmt.Errorf("a word must have %d letters", constants.WordLength)
+	ErrWordHint         = errors.New("a word needs a hint")
+	ErrWordExists       = errors.New("the word is already a target word")
+	ErrWordNotAccepted  = errors.New("the word is not an accepted guess")
+	ErrWordBlocked      = errors.New("the word is blocked")
+	ErrWordMissing      = errors.New("the word is not a target word")
+	ErrLastWord         = errors.New("the last target word in play cannot be removed or retired")
+	ErrWordRetired      = errors.New("the word is retired")
+	ErrWordNotRetired   = errors.New("the word is not retired")
+	ErrWordInPlay       = errors.New("the word is being played")
+)
+
+// AddWord makes entry a target word: it is checked, appended to
+// app.WordsFile, which is replaced in one step, and swapped into the
+// dictionary, so new games can draw it at once. The entry is returned as it
+// was saved.
+func AddWord(app *models.App, entry models.WordEntry) (models.WordEntry, error) {
+	entry = models.WordEntry{
+		Word:  NormalizeWord(entry.Word),
+		Hint:  strings.TrimSpace(entry.Hint),
+		Hints: entry.Hints,
+		Pools: entry.Pools,
+	}
+	switch {
+	case WordLen(entry.Word) != constants.WordLength:
+		return entry, ErrWordLength
+	case entry.Hint == "":
+		return entry, ErrWordHint
+	case IsBlockedWord(app, entry.Word):
+		return entry, ErrWordBlocked
+	case !IsAcceptedWord(app, entry.Word):
+		return entry, ErrWordNotAccepted
+	}
+	err := editWordList(app, func(words []models.WordEntry) ([]models.WordEntry, error) {
+		if slices.ContainsFunc(words, func(e models.WordEntry) bool { return NormalizeWord(e.Word) == entry.Word }) {
+			return nil, ErrWordExists
+		}
+		return append(words, entry), nil
+	})
+	if err != nil {
+		return entry, err
+	}
+	util.LogInfo("Added %s to the word list", entry.Word)
+	return entry, nil
+}
+
+// RemoveWord takes word out of the target words, in app.WordsFile and the
+// dictionary, and returns the entry removed. It refuses with ErrWordInPlay
+// while playing, which counts the unfinished games that have word, finds
+// any: they would no longer count it as a valid guess, so their players
+// could not win. The word is withheld from new games while they are counted,
+// so none can draw it between the count and the removal. It stays an
+// accepted guess until the word lists are next loaded.
+func RemoveWord(app *models.App, word string, playing func(word string) int) (models.WordEntry, error) {
+	word = NormalizeWord(word)
+	var removed models.WordEntry
+	release := withholdWord(app, word)
+	defer release()
+	if playing(word) > 0 {
+		return removed, ErrWordInPlay
+	}
+	err := editWordList(app, func(words []models.WordEntry) ([]models.WordEntry, error) {
+		i := slices.IndexFunc(words, func(e models.WordEntry) bool { return NormalizeWord(e.Word) == word })
+		if i < 0 {
+			return nil, ErrWordMissing
+		}
+		if lastInPlay(words, i) {
+			return nil, ErrLastWord
+		}
+		removed = words[i]
+		return slices.Delete(words, i, i+1), nil
+	})
+	if err != nil {
+		return removed, err
+	}
+	util.LogInfo("Removed %s from the word list", word)
+	return removed, nil
+}
+
+// RetireWord stops word being drawn as a target word, in app.WordsFile and
+// the dictionary, and returns its entry. Unlike a removed word, it stays a
+// target word for the games and statistics that already have it, and can be
+// restored.
+func RetireWord(app *models.App, word string) (models.WordEntry, error) {
+	return setWordStatus(app, word, models.WordRetired)
+}
+
+// RestoreWord puts a retired word back in play and returns its entry.
+func RestoreWord(app *models.App, word string) (models.WordEntry, error) {
+	return setWordStatus(app, word, "")
+}
+
+func setWordStatus(app *models.App, word, status string) (models.WordEntry, error) {
+	word = NormalizeWord(word)
+	var changed models.WordEntry
+	err := editWordList(app, func(words []models.WordEntry) ([]models.WordEntry, error) {
+		i := slices.IndexFunc(words, func(e models.WordEntry) bool { return NormalizeWord(e.Word) == word })
+		switch {
+		case i < 0:
+			return nil, ErrWordMissing
+		case words[i].Status == status && status == "":
+			return nil, ErrWordNotRetired
+		case words[i].Status == status:
+			return nil, ErrWordRetired
+		case status != "" && lastInPlay(words, i):
+			return nil, ErrLastWord
+		}
+		words[i].Status = status
+		changed = words[i]
+		return words, nil
+	})
+	if err != nil {
+		return changed, err
+	}
+	if status == "" {
+		util.LogInfo("Restored %s to the word list", word)
+	} else {
+		util.LogInfo("Retired %s from the word list", word)
+	}
+	return changed, nil
+}
+
+// withholdWord keeps word from new games until the returned function is
+// called. Once it returns, every game that drew word before is stored, as
+// taking the write lock waits for the draws that hold the read lock, so the
+// games playing word can then only get fewer.
+func withholdWord(app *models.App, word string) func() {
+	app.WordsMutex.Lock()
+	if app.WordsWithheld == nil {
+		app.WordsWithheld = make(map[string]int)
+	}
+	app.WordsWithheld[word]++
+	app.WordsMutex.Unlock()
+	return func() {
+		app.WordsMutex.Lock()
+		defer app.WordsMutex.Unlock()
+		if app.WordsWithheld[word]--; app.WordsWithheld[word] <= 0 {
+			delete(app.WordsWithheld, word)
+		}
+	}
+}
+
+// holdWords keeps words from being withheld while a new game draws its
+// words and is stored, and returns the function that lets go. Draws do not
+// nest, as a pending withholdWord would block the inner one.
+func holdWords(app *models.App) func() {
+	app.WordsMutex.RLock()
+	return app.WordsMutex.RUnlock
+}
+
+// withheld reports whether word is withheld from new games. The caller
+// holds the words.
+func withheld(app *models.App, word string) bool {
+	return app.WordsWithheld[word] > 0
+}
+
+// notWithheld drops the withheld words from words, unless that leaves none.
+// The caller holds the words.
+func notWithheld(app *models.App, words []models.WordEntry) []models.WordEntry {
+	if len(app.WordsWithheld) == 0 {
+		return words
+	}
+	kept := slices.DeleteFunc(slices.Clone(words), func(e models.WordEntry) bool { return withheld(app, e.Word) })
+	if len(kept) == 0 {
+		return words
+	}
+	return kept
+}
+
+// lastInPlay reports whether words[i] is the only word not retired.
+func lastInPlay(words []models.WordEntry, i int) bool {
+	for j, e := range words {
+		if j != i && !e.Retired() {
+			return false
+		}
+	}
+	return true
+}
+
+// editWordList applies edit to the entries of app.WordsFile as they are on
+// disk, writes them back and swaps the result into the dictionary. Edits
+// are made one at a time; a word list refresh afterwards finds the file
+// changed and loads the same words.
+func editWordList(app *models.App, edit func([]models.WordEntry) ([]models.WordEntry, error)) error {
+	if app.WordsFile == "" {
+		return ErrWordListReadOnly
+	}
+	app.WordsMutex.Lock()
+	defer app.WordsMutex.Unlock()
+
+	info, err := os.Stat(app.WordsFile)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(app.WordsFile)
+	if err != nil {
+		return err
+	}
+	var wordList models.WordList
+	if err := json.Unmarshal(data, &wordList); err != nil {
+		return fmt.Errorf("parse %s: %w", app.WordsFile, err)
+	}
+	if wordList.Words, err = edit(wordList.Words); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "    ")
+	if err := enc.Encode(wordList); err != nil {
+		return err
+	}
+	words, err := ParseWordList(buf.Bytes())
+	if err != nil {
+		return err
+	}
+	if err := util.WriteFileAtomic(app.WordsFile, buf.Bytes()); err != nil {
+		return err
+	}
+	if err := os.Chmod(app.WordsFile, info.Mode().Perm()); err != nil {
+		util.LogWarn("Failed to keep the permissions of %s: %v", app.WordsFile, err)
+	}
+
+	// The accepted set also holds the target words, so a removed word
+	// stays in it until the lists are loaded again.
+	accepted := make(map[string]struct{}, app.Dictionary().Accepted.Len())
+	for word := range app.Dictionary().Accepted.All() {
+		accepted[word] = struct{}{}
+	}
+	SetDictionary(app, words, accepted)
+	return nil
+}
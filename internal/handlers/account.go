@@ -0,0 +1,323 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	achievements "github.com/CodeAndHammer/vortludo/internal/achievements"
+	auth "github.com/CodeAndHammer/vortludo/internal/auth"
+	constants "github.com/CodeAndHammer/vortludo/internal/constants"
+	events "github.com/CodeAndHammer/vortludo/internal/events"
+	game "github.com/CodeAndHammer/vortludo/internal/game"
+	models "github.com/CodeAndHammer/vortludo/internal/models"
+	session "github.com/CodeAndHammer/vortludo/internal/session"
+	util "github.com/CodeAndHammer/vortludo/internal/util"
+	"github.com/gin-gonic/gin"
+)
+
+// AccountHandler shows the signed-in account and its stats, or the sign-up
+// and sign-in forms along with the stats of the anonymous session.
+func AccountHandler(app *models.App, c *gin.Context) {
+	sessionID := session.GetOrCreateSession(app, c)
+	renderAccount(app, c, sessionID, http.StatusOK, gin.H{"notice": c.Query("notice")})
+}
+
+func renderAccount(app *models.App, c *gin.Context, sessionID string, status int, data gin.H) {
+	user, signedIn := currentUser(app, c)
+	stats := playerStats(app, c, sessionID)
+	body := gin.H{"signed_in": signedIn, "stats": stats}
+	if signedIn {
+		body["username"] = user.Username
+		body["email"] = user.Email
+	}
+	data["title"] = "Vortludo - Account"
+	data["user"] = user
+	data["signed_in"] = signedIn
+	data["oidc_name"] = ""
+	data["oidc_linked"] = oidcLinked(app, user)
+	data["magic_links"] = app.Accounts != nil && app.Accounts.SendsLinks()
+	if app.OIDC != nil {
+		data["oidc_name"] = app.OIDC.Name()
+	}
+	data["stats"] = stats
+	data["settings"] = session.GetSettings(app, sessionID)
+	data["minimal_cookies"] = app.MinimalCookies
+	data["consent"] = session.Consented(app, sessionID)
+	data["error_code"] = ""
+	if _, ok := data["notice"]; !ok {
+		data["notice"] = ""
+	}
+	resp := Response{Status: status, Page: "account.html", Data: data, JSON: body}
+	if gameErr, ok := data["error"].(*game.GameError); ok {
+		resp.Fail(c, gameErr)
+		return
+	}
+	resp.Send(c)
+}
+
+// RegisterHandler creates an account and signs the session in. Games played
+// and settings chosen anonymously carry over to the new account.
+func RegisterHandler(app *models.App, c *gin.Context) {
+	sessionID := session.GetOrCreateSession(app, c)
+	unlock := session.Lock(app, sessionID)
+	defer unlock()
+	if !consentGiven(app, c, sessionID) {
+		return
+	}
+	user, err := app.Accounts.Register(c.PostForm("username"), c.PostForm("email"), c.PostForm("password"))
+	if err != nil {
+		renderAccountError(app, c, sessionID, err)
+		return
+	}
+	signIn(app, c, sessionID, user)
+	redirectToAccount(app, c, sessionID)
+}
+
+func LoginHandler(app *models.App, c *gin.Context) {
+	sessionID := session.GetOrCreateSession(app, c)
+	unlock := session.Lock(app, sessionID)
+	defer unlock()
+	if !consentGiven(app, c, sessionID) {
+		return
+	}
+	user, err := app.Accounts.Authenticate(c.PostForm("username"), c.PostForm("password"))
+	if err != nil {
+		util.LogWarn("Failed sign-in for %q from %s", c.PostForm("username"), c.ClientIP())
+		renderAccountError(app, c, sessionID, err)
+		return
+	}
+	signIn(app, c, sessionID, user)
+	redirectToAccount(app, c, sessionID)
+}
+
+// MagicLinkHandler emails a sign-in link. Whether the address has an account
+// is not revealed: the email is queued, and the answer is the same for every
+// address.
+func MagicLinkHandler(app *models.App, c *gin.Context) {
+	sessionID := session.GetOrCreateSession(app, c)
+	if !consentGiven(app, c, sessionID) {
+		return
+	}
+	base, ok := magicLinkBase(app, c)
+	if !ok {
+		util.LogWarn("Not sending a sign-in link: PUBLIC_URL is not set")
+		renderAccount(app, c, sessionID, http.StatusOK, gin.H{"notice": "link_sent"})
+		return
+	}
+	err := app.Accounts.SendMagicLink(c.PostForm("email"), base)
+	switch {
+	case errors.Is(err, auth.ErrInvalidEmail):
+		renderAccountError(app, c, sessionID, err)
+		return
+	case errors.Is(err, auth.ErrUnknownEmail):
+	case err != nil:
+		util.LogWarn("Failed to send sign-in link: %v", err)
+	}
+	renderAccount(app, c, sessionID, http.StatusOK, gin.H{"notice": "link_sent"})
+}
+
+func MagicLinkRedeemHandler(app *models.App, c *gin.Context) {
+	sessionID := session.GetOrCreateSession(app, c)
+	unlock := session.Lock(app, sessionID)
+	defer unlock()
+	if !consentGiven(app, c, sessionID) {
+		return
+	}
+	user, err := app.Accounts.RedeemMagicLink(c.Query("token"))
+	if err != nil {
+		renderAccountError(app, c, sessionID, err)
+		return
+	}
+	signIn(app, c, sessionID, user)
+	redirectToAccount(app, c, sessionID)
+}
+
+func LogoutHandler(app *models.App, c *gin.Context) {
+	sessionID := session.GetOrCreateSession(app, c)
+	endLogin(app, c)
+	redirectToAccount(app, c, sessionID)
+}
+
+func endLogin(app *models.App, c *gin.Context) {
+	if token, err := c.Cookie(constants.AccountCookieName); err == nil {
+		app.Accounts.EndLogin(token)
+	}
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(constants.AccountCookieName, "", -1, app.CookiePath(), "", app.IsProduction, true)
+	c.Set(constants.AccountTokenKey, "")
+}
+
+// consentGiven reports whether the session may sign in to an account,
+// which keeps its stats beyond the session: with minimal cookies, only once
+// the player has consented. Otherwise it answers ErrorCodeConsentRequired.
+func consentGiven(app *models.App, c *gin.Context, sessionID string) bool {
+	if session.Consented(app, sessionID) {
+		return true
+	}
+	gameErr := game.NewGameError(constants.ErrorCodeConsentRequired)
+	renderAccount(app, c, sessionID, gameErr.Status, gin.H{"error": gameErr})
+	return false
+}
+
+func redirectToAccount(app *models.App, c *gin.Context, sessionID string) {
+	if Negotiate(c) == FormatJSON {
+		renderAccount(app, c, sessionID, http.StatusOK, gin.H{})
+		return
+	}
+	c.Redirect(http.StatusSeeOther, app.Path(constants.RouteAccount))
+}
+
+func renderAccountError(app *models.App, c *gin.Context, sessionID string, err error) {
+	gameErr := accountError(err)
+	renderAccount(app, c, sessionID, gameErr.Status, gin.H{"error": gameErr})
+}
+
+func accountError(err error) *game.GameError {
+	switch {
+	case errors.Is(err, auth.ErrInvalidUsername), errors.Is(err, auth.ErrInvalidEmail),
+		errors.Is(err, auth.ErrInvalidPassword), errors.Is(err, auth.ErrMissingCredentials), errors.Is(err, auth.ErrPasswordRequired):
+		return game.NewGameError(constants.ErrorCodeInvalidAccount).WithDetail("reason", err.Error())
+	case errors.Is(err, auth.ErrUsernameTaken), errors.Is(err, auth.ErrEmailTaken):
+		return game.NewGameError(constants.ErrorCodeAccountExists).WithDetail("reason", err.Error())
+	case errors.Is(err, auth.ErrInvalidCredentials):
+		return game.NewGameError(constants.ErrorCodeInvalidCredentials)
+	case errors.Is(err, auth.ErrInvalidLink):
+		return game.NewGameError(constants.ErrorCodeInvalidLink)
+	default:
+		util.LogWarn("Account error: %v", err)
+		return game.NewGameError(constants.ErrorCodeInternal)
+	}
+}
+
+// currentUser returns the account the request is signed in to.
+func currentUser(app *models.App, c *gin.Context) (auth.User, bool) {
+	if app.Accounts == nil {
+		return auth.User{}, false
+	}
+	token, ok := c.Get(constants.AccountTokenKey)
+	if !ok {
+		token, _ = c.Cookie(constants.AccountCookieName)
+	}
+	return app.Accounts.UserForLogin(token.(string))
+}
+
+// signIn sets the account cookie and merges the anonymous session into the
+// account: its stats are added to the account's, and its settings become the
+// account's if the account has none yet. Otherwise the account's settings
+// replace the session's.
+func signIn(app *models.App, c *gin.Context, sessionID string, user auth.User) {
+	token := app.Accounts.StartLogin(user.ID)
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(constants.AccountCookieName, token, int(auth.LoginTTL.Seconds()), app.CookiePath(), "", app.IsProduction, true)
+	c.Set(constants.AccountTokenKey, token)
+
+	app.Accounts.MergeStats(user.ID, session.TakeStats(app, sessionID))
+	if len(user.Settings) == 0 {
+		saveAccountSettings(app, user.ID, session.GetSettings(app, sessionID))
+	} else {
+		loadAccountSettings(app, sessionID, user)
+	}
+	util.LogInfo("Session %s signed in to account %s", sessionID, user.Username)
+}
+
+// syncAccountSettings copies a signed-in account's settings to the session,
+// so they follow the player to a new device or a new session.
+func syncAccountSettings(app *models.App, c *gin.Context, sessionID string) {
+	if user, ok := currentUser(app, c); ok && len(user.Settings) > 0 {
+		loadAccountSettings(app, sessionID, user)
+	}
+}
+
+func loadAccountSettings(app *models.App, sessionID string, user auth.User) {
+	settings := session.DefaultSettings(app)
+	if err := json.Unmarshal(user.Settings, &settings); err != nil || game.ValidateSettings(&settings) != nil {
+		util.LogWarn("Ignoring invalid settings of account %s", user.Username)
+		return
+	}
+	session.SaveSettings(app, sessionID, settings)
+}
+
+func saveAccountSettings(app *models.App, userID string, settings models.UserSettings) {
+	data, err := json.Marshal(settings)
+	if err != nil {
+		util.LogWarn("Failed to encode account settings: %v", err)
+		return
+	}
+	app.Accounts.SaveSettings(userID, data)
+}
+
+// recordPlayerStats adds a finished game, and the pack word it solved if
+// any, to the stats of the signed-in account, or of the anonymous session,
+// and announces the achievements it unlocks.
+func recordPlayerStats(app *models.App, c *gin.Context, sessionID string, gameState *models.GameState) {
+	if !gameState.GameOver {
+		return
+	}
+	guesses := len(gameState.GuessHistory)
+	result := achievements.Game{
+		Won:     gameState.Won,
+		Guesses: guesses,
+		Hints:   gameState.HintsRevealed + gameState.SolverHints,
+		At:      time.Now(),
+	}
+	words := []string{gameState.SessionWord}
+	if game.IsMultiBoard(gameState) {
+		words = game.BoardWords(gameState)
+	}
+	for _, word := range words {
+		result.HardWord = result.HardWord || game.IsHardWord(app, word)
+	}
+	var unlocked []achievements.Achievement
+	record := func(stats *auth.Stats) {
+		stats.Record(gameState.Won, guesses)
+		if gameState.Scored {
+			stats.RecordPoints(gameState.Points, gameState.Score)
+		}
+		if gameState.Won && gameState.Pack != "" {
+			stats.SolvePackWord(gameState.Pack, gameState.SessionWord)
+		}
+		unlocked = achievements.Award(stats, result)
+	}
+	if user, ok := currentUser(app, c); ok {
+		app.Accounts.UpdateStats(user.ID, record)
+	} else {
+		session.UpdateStats(app, sessionID, record)
+	}
+	if gameState.Won {
+		rememberCompletedWords(app, c, sessionID, words)
+	}
+	if len(unlocked) > 0 {
+		util.LogInfo("Session %s unlocked %d achievements", sessionID, len(unlocked))
+		events.From(c).Trigger(events.AchievementUnlocked, unlocked)
+	}
+}
+
+// magicLinkBase is a sign-in link without its token, built from PUBLIC_URL,
+// which SMTP_HOST requires. The request's Host header is chosen by whoever
+// asks for the link, and would point a victim's emailed token at their site,
+// so it is only used in development, where links go to the log.
+func magicLinkBase(app *models.App, c *gin.Context) (string, bool) {
+	base := app.PublicURL
+	if base == "" {
+		if app.IsProduction {
+			return "", false
+		}
+		base = baseURL(app, c)
+	}
+	return base + app.Path(constants.RouteAccountMagic) + "?token=", true
+}
+
+// baseURL is the address the site is reached at: PUBLIC_URL, or else the
+// request's host.
+func baseURL(app *models.App, c *gin.Context) string {
+	if app.PublicURL != "" {
+		return app.PublicURL
+	}
+	scheme := "http"
+	if c.Request.TLS != nil || app.IsProduction {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	achievements "github.com/CodeAndHammer/vortludo/internal/achievements"
+	models "github.com/CodeAndHammer/vortludo/internal/models"
+	session "github.com/CodeAndHammer/vortludo/internal/session"
+	"github.com/gin-gonic/gin"
+)
+
+// AchievementsHandler lists every achievement and which the player has
+// unlocked.
+func AchievementsHandler(app *models.App, c *gin.Context) {
+	sessionID := session.GetOrCreateSession(app, c)
+	stats := playerStats(app, c, sessionID)
+	statuses := achievements.Statuses(stats)
+	unlocked := 0
+	for _, status := range statuses {
+		if status.Unlocked {
+			unlocked++
+		}
+	}
+	c.Header("Cache-Control", "no-store")
+	Response{
+		Page: "achievements.html",
+		Data: gin.H{
+			"title":        "Vortludo - Achievements",
+			"achievements": statuses,
+			"unlocked":     unlocked,
+			"dayStreak":    stats.DayStreak,
+			"settings":     session.GetSettings(app, sessionID),
+		},
+		JSON: gin.H{
+			"achievements": statuses,
+			"unlocked":     unlocked,
+			"day_streak":   stats.DayStreak,
+		},
+	}.Send(c)
+}
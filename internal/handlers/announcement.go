@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"cmp"
+	"io"
+	"net/http"
+	"time"
+
+	announce "github.com/CodeAndHammer/vortludo/internal/announce"
+	audit "github.com/CodeAndHammer/vortludo/internal/audit"
+	constants "github.com/CodeAndHammer/vortludo/internal/constants"
+	models "github.com/CodeAndHammer/vortludo/internal/models"
+	util "github.com/CodeAndHammer/vortludo/internal/util"
+	"github.com/gin-gonic/gin"
+)
+
+// AnnouncementHandler renders the current announcement, for pages to fetch
+// when they are told it changed.
+func AnnouncementHandler(app *models.App, c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	Response{
+		Fragment: "announcement",
+		Redirect: app.Path(constants.RouteHome),
+		Data:     gin.H{},
+		JSON:     gin.H{"announcement": currentAnnouncement(app)},
+	}.Send(c)
+}
+
+// AnnouncementEventsHandler streams a server-sent "announcement" event
+// whenever the announcement is set, cleared or expires.
+func AnnouncementEventsHandler(app *models.App, c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("X-Accel-Buffering", "no")
+
+	ticker := time.NewTicker(constants.AnnouncementPollInterval)
+	defer ticker.Stop()
+	deadline := time.After(constants.AnnouncementStreamMax)
+	// The page was rendered with the announcement current when the stream
+	// opened, so only later changes are sent.
+	version := app.Announcements.Version()
+	_, shown := app.Announcements.Current(time.Now())
+	c.Stream(func(w io.Writer) bool {
+		_, active := app.Announcements.Current(time.Now())
+		if v := app.Announcements.Version(); v != version || active != shown {
+			version, shown = v, active
+			c.SSEvent("announcement", version)
+			return true
+		}
+		select {
+		case <-ticker.C:
+			return true
+		case <-deadline:
+		case <-app.Closing:
+		case <-c.Request.Context().Done():
+		}
+		return false
+	})
+}
+
+// AdminAnnouncementHandler shows the current announcement, if any.
+func AdminAnnouncementHandler(app *models.App, c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"announcement": currentAnnouncement(app)})
+}
+
+// AdminSetAnnouncementHandler shows the form's text to every player, with
+// severity one of announce.Severities (info by default). expires is a
+// duration such as 2h or an RFC 3339 time; without it the announcement is
+// shown until cleared.
+func AdminSetAnnouncementHandler(app *models.App, c *gin.Context) {
+	a := announce.Announcement{
+		Text:     c.PostForm("text"),
+		Severity: cmp.Or(c.PostForm("severity"), "info"),
+	}
+	if expires := c.PostForm("expires"); expires != "" {
+		at, ok := parseExpiry(expires, time.Now())
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "expires must be a duration such as 2h or an RFC 3339 time in the future"})
+			return
+		}
+		a.Expires = at
+	}
+	before := currentAnnouncement(app)
+	if err := app.Announcements.Set(a); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	util.LogInfo("Announcement set (%s): %s", a.Severity, a.Text)
+	after := currentAnnouncement(app)
+	recordAdminAction(app, c, audit.SetAnnouncement, before, after)
+	c.JSON(http.StatusOK, gin.H{"announcement": after})
+}
+
+// AdminClearAnnouncementHandler removes the announcement.
+func AdminClearAnnouncementHandler(app *models.App, c *gin.Context) {
+	before := currentAnnouncement(app)
+	if !app.Announcements.Clear() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no announcement"})
+		return
+	}
+	util.LogInfo("Announcement cleared")
+	recordAdminAction(app, c, audit.ClearAnnouncement, before, nil)
+	c.Status(http.StatusNoContent)
+}
+
+func currentAnnouncement(app *models.App) *announce.Announcement {
+	if a, ok := app.Announcements.Current(time.Now()); ok {
+		return &a
+	}
+	return nil
+}
+
+// parseExpiry reads value as a duration from now or an absolute time, which
+// must be in the future.
+func parseExpiry(value string, now time.Time) (time.Time, bool) {
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(d), d > 0
+	}
+	at, err := time.Parse(time.RFC3339, value)
+	return at, err == nil && at.After(now)
+}
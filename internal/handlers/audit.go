@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	audit "github.com/CodeAndHammer/vortludo/internal/audit"
+	constants "github.com/CodeAndHammer/vortludo/internal/constants"
+	models "github.com/CodeAndHammer/vortludo/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// AdminAuditHandler lists the latest admin actions, newest first: ?limit=
+// of them (50 by default), only those of ?action= if given.
+func AdminAuditHandler(app *models.App, c *gin.Context) {
+	limit := constants.AuditListDefault
+	if n, err := strconv.Atoi(c.Query("limit")); err == nil && n > 0 {
+		limit = min(n, constants.AuditListMax)
+	}
+	c.JSON(http.StatusOK, gin.H{"entries": app.Audit.Recent(limit, c.Query("action"))})
+}
+
+// recordAdminAction adds an admin action taken in c to the audit log, with
+// summaries of the state before and after it.
+func recordAdminAction(app *models.App, c *gin.Context, action string, before, after any) {
+	requestID, _ := c.Request.Context().Value(constants.RequestIDKey).(string)
+	actor := c.GetHeader(constants.AdminActorHeader)
+	if actor == "" {
+		actor = "admin"
+	}
+	app.Audit.Record(audit.Entry{
+		Action:    action,
+		Actor:     actor,
+		IP:        c.ClientIP(),
+		RequestID: requestID,
+		Before:    before,
+		After:     after,
+	})
+}
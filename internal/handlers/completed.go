@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"encoding/json"
+	"slices"
+
+	constants "github.com/CodeAndHammer/vortludo/internal/constants"
+	events "github.com/CodeAndHammer/vortludo/internal/events"
+	game "github.com/CodeAndHammer/vortludo/internal/game"
+	models "github.com/CodeAndHammer/vortludo/internal/models"
+	session "github.com/CodeAndHammer/vortludo/internal/session"
+	util "github.com/CodeAndHammer/vortludo/internal/util"
+	"github.com/gin-gonic/gin"
+)
+
+// ParseCompletedWords reads the JSON list of words a client has completed,
+// which it keeps in local storage. Words are normalized like guesses,
+// duplicates and words that are not target words are dropped, and no more
+// entries are looked at than the dictionary has words, keeping the latest.
+// Anything but an array of strings is an invalid_completed_words error.
+func ParseCompletedWords(app *models.App, raw string) ([]string, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	var entries []json.RawMessage
+	if err := json.Unmarshal([]byte(raw), &entries); err != nil {
+		return nil, game.NewGameError(constants.ErrorCodeInvalidCompletedWords)
+	}
+	dict := app.Dictionary()
+	entries = entries[max(0, len(entries)-len(dict.WordSet)):]
+
+	words := make([]string, 0, len(entries))
+	seen := make(map[string]struct{}, len(entries))
+	for i, entry := range entries {
+		var word string
+		if err := json.Unmarshal(entry, &word); err != nil {
+			return nil, game.NewGameError(constants.ErrorCodeInvalidCompletedWords).WithDetail("index", i)
+		}
+		word = game.NormalizeWord(word)
+		if _, dup := seen[word]; dup {
+			continue
+		}
+		seen[word] = struct{}{}
+		if _, ok := dict.WordSet[word]; ok {
+			words = append(words, word)
+		}
+	}
+	return words, nil
+}
+
+// completedWords parses the client's completed words for sessionID. An
+// invalid list is reported to the client and treated as empty, so the game
+// it asked for still starts. With a store, the words it holds for the
+// session come first, so words survive the client clearing its storage.
+func completedWords(app *models.App, c *gin.Context, sessionID, raw string) []string {
+	words, err := ParseCompletedWords(app, raw)
+	if err != nil {
+		util.LogWarn("Invalid completed words from session %s: %v", sessionID, err)
+		setErrorTrigger(c, game.AsGameError(err))
+		words = nil
+	}
+	if words != nil {
+		util.LogInfo("Validated %d completed words for session %s", len(words), sessionID)
+	}
+	if app.Store == nil {
+		return words
+	}
+	stored, err := app.Store.CompletedWords(c.Request.Context(), sessionID)
+	if err != nil {
+		util.LogWarn("Failed to read the completed words of session %s: %v", sessionID, err)
+		return words
+	}
+	if len(stored) == 0 {
+		return words
+	}
+	merged := slices.Clone(stored)
+	for _, word := range words {
+		if !slices.Contains(stored, word) {
+			merged = append(merged, word)
+		}
+	}
+	return merged
+}
+
+// rememberCompletedWords notes the words of a won game in the store, if
+// there is one.
+func rememberCompletedWords(app *models.App, c *gin.Context, sessionID string, words []string) {
+	session.PersistCompletedWords(c.Request.Context(), app, sessionID, false, words...)
+}
+
+// clearCompletedWords starts the session's completed words over, once every
+// word has been played, on the client and in the store.
+func clearCompletedWords(app *models.App, c *gin.Context, sessionID string) {
+	events.From(c).Flag(events.ClearCompletedWords)
+	session.PersistCompletedWords(c.Request.Context(), app, sessionID, true)
+}
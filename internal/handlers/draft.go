@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"time"
+
+	constants "github.com/CodeAndHammer/vortludo/internal/constants"
+	game "github.com/CodeAndHammer/vortludo/internal/game"
+	models "github.com/CodeAndHammer/vortludo/internal/models"
+	session "github.com/CodeAndHammer/vortludo/internal/session"
+	"github.com/gin-gonic/gin"
+)
+
+// KeyHandler types the posted key into the game's draft row, kept on the
+// server so that the game can be played without JavaScript: a letter or
+// BACKSPACE updates the draft, and ENTER guesses it as GuessHandler would.
+// htmx gets the current row back, JSON clients the draft, and browsers are
+// sent back to the board.
+func KeyHandler(app *models.App, c *gin.Context) {
+	ctx := c.Request.Context()
+	sessionID := session.GetOrCreateSession(app, c)
+	unlock := session.Lock(app, sessionID)
+	defer unlock()
+	expired := session.GameExpired(app, c, sessionID)
+	gameState := session.GetGameState(app, ctx, sessionID)
+	game.AdvanceRace(app, ctx, gameState, time.Now())
+
+	fail := func(err error) {
+		draftResponse(app, c, sessionID, gameState).Fail(c, game.AsGameError(err))
+	}
+	if expired {
+		gameState.AfterExpiry = true
+		fail(game.NewGameError(constants.ErrorCodeSessionExpired))
+		return
+	}
+	if err := ValidateGameState(app, c, gameState); err != nil {
+		fail(err)
+		return
+	}
+	enter, err := game.TypeKey(gameState, c.PostForm("key"))
+	if err != nil {
+		fail(err)
+		return
+	}
+	if !enter {
+		session.SaveGameState(app, sessionID, gameState)
+		draftResponse(app, c, sessionID, gameState).Send(c)
+		return
+	}
+
+	if err := game.CheckCooldown(gameState, app.GuessCooldown, time.Now()); err != nil {
+		fail(err)
+		return
+	}
+	guess := game.ParseGuess(gameState.Draft)
+	if err := checkGuess(app, sessionID, gameState, guess); err != nil {
+		fail(err)
+		return
+	}
+	if deadlineExceeded(app, c) {
+		return
+	}
+	if err := ProcessGuess(app, ctx, c, sessionID, gameState, guess); err != nil {
+		fail(err)
+	}
+}
+
+// draftResponse shows the draft row: the current row to htmx, or the whole
+// game for multi-board games, whose boards each have one.
+func draftResponse(app *models.App, c *gin.Context, sessionID string, gameState *models.GameState) Response {
+	status := gin.H{"draft": gameState.Draft, "row": gameState.CurrentRow, "version": gameState.Version}
+	if !game.IsMultiBoard(gameState) {
+		for _, row := range game.BuildBoard(gameState, -1) {
+			if row.IsCurrent {
+				return Response{Fragment: "draft-row", Redirect: app.Path(constants.RouteHome), Data: row, JSON: status}
+			}
+		}
+	}
+	resp := gameResponse(app, c, sessionID, gameState, -1, nil)
+	resp.Page, resp.Redirect, resp.JSON = "", app.Path(constants.RouteHome), status
+	return resp
+}
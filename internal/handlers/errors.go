@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	constants "github.com/CodeAndHammer/vortludo/internal/constants"
+	game "github.com/CodeAndHammer/vortludo/internal/game"
+	models "github.com/CodeAndHammer/vortludo/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+type errorPage struct {
+	heading string
+	message string
+}
+
+var errorPages = map[string]errorPage{
+	constants.ErrorCodeNotFound: {
+		"Page not found",
+		"There is no page at this address. It may have moved, or the link may be mistyped.",
+	},
+	constants.ErrorCodeMethodNotAllowed: {
+		"Not allowed",
+		"This page cannot be used that way.",
+	},
+	constants.ErrorCodeServerFull: {
+		"Server full",
+		"So many people are playing right now that there is no room for a new game. Please try again in a few minutes.",
+	},
+	constants.ErrorCodePayloadTooLarge: {
+		"Request too large",
+		"The request sent more data than the server accepts. Please reload the page and try again.",
+	},
+	constants.ErrorCodeTimeout: {
+		"Request timed out",
+		"The server took too long to answer. Please reload the page and try again.",
+	},
+	constants.ErrorCodeGameInProgress: {
+		"Game in progress",
+		"Only a finished game can be shared as an image. Finish the current word and try again.",
+	},
+	constants.ErrorCodeInternal: {
+		"Something went wrong",
+		"The server hit an unexpected error. Please try again; if it keeps happening, report the request ID below.",
+	},
+}
+
+// ErrorPage answers with gameErr: as the JSON error envelope to API, htmx
+// and JSON clients, and as a themed page to browsers.
+func ErrorPage(app *models.App, c *gin.Context, gameErr *game.GameError) {
+	if Negotiate(c) != FormatPage || strings.HasPrefix(c.Request.URL.Path, app.Path(constants.RouteAPIPrefix)) {
+		RespondGameError(c, gameErr)
+		return
+	}
+	page, ok := errorPages[gameErr.Code]
+	if !ok {
+		page = errorPages[constants.ErrorCodeInternal]
+	}
+	requestID, _ := c.Request.Context().Value(constants.RequestIDKey).(string)
+	c.HTML(gameErr.Status, "error.html", gin.H{
+		"title":      "Vortludo - " + page.heading,
+		"status":     gameErr.Status,
+		"heading":    page.heading,
+		"message":    page.message,
+		"error_code": gameErr.Code,
+		"request_id": requestID,
+		"theme":      theme(c),
+		"csp_nonce":  cspNonce(c),
+	})
+	c.Abort()
+}
+
+func NotFoundHandler(app *models.App, c *gin.Context) {
+	ErrorPage(app, c, game.NewGameError(constants.ErrorCodeNotFound))
+}
+
+// MethodNotAllowedHandler answers a request for a route that exists with
+// another method. The router has already set the Allow header.
+func MethodNotAllowedHandler(app *models.App, c *gin.Context) {
+	ErrorPage(app, c, game.NewGameError(constants.ErrorCodeMethodNotAllowed))
+}
+
+// ServerFullHandler answers a new player turned away by the session limit.
+func ServerFullHandler(app *models.App, c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	ErrorPage(app, c, game.NewGameError(constants.ErrorCodeServerFull))
+}
+
+// PayloadTooLargeHandler answers a request whose body or fields are over the
+// limits.
+func PayloadTooLargeHandler(app *models.App, c *gin.Context) {
+	ErrorPage(app, c, game.NewGameError(constants.ErrorCodePayloadTooLarge))
+}
+
+// TimeoutHandler answers a request that ran past its deadline.
+func TimeoutHandler(app *models.App, c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	ErrorPage(app, c, game.NewGameError(constants.ErrorCodeTimeout))
+}
+
+// deadlineExceeded answers the request with TimeoutHandler if its deadline
+// has passed. Handlers check it before changing a game, so a request the
+// client has given up on changes nothing.
+func deadlineExceeded(app *models.App, c *gin.Context) bool {
+	if !errors.Is(c.Request.Context().Err(), context.DeadlineExceeded) {
+		return false
+	}
+	TimeoutHandler(app, c)
+	return true
+}
+
+// InternalErrorHandler answers a request whose handler panicked. HTMX gets a
+// notice fragment for the page's notice slot, so the board it was updating
+// is left as it was.
+func InternalErrorHandler(app *models.App, c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	if Negotiate(c) != FormatFragment {
+		ErrorPage(app, c, game.NewGameError(constants.ErrorCodeInternal))
+		return
+	}
+	requestID, _ := c.Request.Context().Value(constants.RequestIDKey).(string)
+	c.Header("HX-Retarget", "#"+constants.RateLimitNoticeID)
+	c.Header("HX-Reswap", "innerHTML")
+	c.HTML(http.StatusInternalServerError, "server-error", gin.H{"request_id": requestID})
+	c.Abort()
+}
@@ -1,318 +1,1063 @@
 package handlers
 
 import (
+	"bytes"
 	"context"
-	"encoding/json"
-	"errors"
+	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"path"
 	"runtime"
 	"slices"
-	"strings"
+	"strconv"
 	"time"
 
+	analytics "github.com/CodeAndHammer/vortludo/internal/analytics"
+	assets "github.com/CodeAndHammer/vortludo/internal/assets"
+	audit "github.com/CodeAndHammer/vortludo/internal/audit"
+	auth "github.com/CodeAndHammer/vortludo/internal/auth"
+	compress "github.com/CodeAndHammer/vortludo/internal/compress"
 	constants "github.com/CodeAndHammer/vortludo/internal/constants"
+	eventlog "github.com/CodeAndHammer/vortludo/internal/eventlog"
+	events "github.com/CodeAndHammer/vortludo/internal/events"
 	game "github.com/CodeAndHammer/vortludo/internal/game"
+	keyboard "github.com/CodeAndHammer/vortludo/internal/keyboard"
 	models "github.com/CodeAndHammer/vortludo/internal/models"
 	session "github.com/CodeAndHammer/vortludo/internal/session"
+	tournament "github.com/CodeAndHammer/vortludo/internal/tournament"
 	util "github.com/CodeAndHammer/vortludo/internal/util"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"github.com/samber/lo"
 )
 
 func HomeHandler(app *models.App, c *gin.Context) {
 	ctx := c.Request.Context()
 	sessionID := session.GetOrCreateSession(app, c)
+	unlock := session.Lock(app, sessionID)
+	defer unlock()
+	expired := session.GameExpired(app, c, sessionID)
 	gameState := session.GetGameState(app, ctx, sessionID)
-	hint := game.GetHintForWord(app, gameState.SessionWord)
+	if expired {
+		noteExpiredGame(c, gameState)
+	}
+	game.AdvanceRace(app, ctx, gameState, time.Now())
+	syncAccountSettings(app, c, sessionID)
+	if !session.GetSettings(app, sessionID).DoNotTrack {
+		app.Analytics.RecordActivity(sessionID)
+	}
+	resp := gameResponse(app, c, sessionID, gameState, -1, nil)
+	if code := c.Query(constants.ErrorParam); game.IsErrorCode(code) && Negotiate(c) == FormatPage {
+		resp.Data.(View).fail(code)
+	}
+	resp.Send(c)
+}
 
-	csrfToken, _ := c.Cookie("csrf_token")
-	c.HTML(http.StatusOK, "index.html", gin.H{
-		"title":      "Vortludo - A Libre Wordle Clone",
-		"message":    "Guess the 5-letter word!",
-		"hint":       hint,
-		"game":       gameState,
-		"csrf_token": csrfToken,
-	})
+// gameResponse shows the session's game: the home page to browsers, the
+// game-content fragment to htmx and the game's status to JSON clients.
+// newRow is the row to animate, if any, and before the player's stats
+// before the game, if it just ended.
+func gameResponse(app *models.App, c *gin.Context, sessionID string, gameState *models.GameState, newRow int, before *auth.Stats) Response {
+	return Response{
+		Page:     "index.html",
+		Fragment: "game-content",
+		Data:     presentIndex(app, c, sessionID, gameState, newRow, before),
+		JSON:     gameStatus(gameState),
+	}
+}
+
+// moveResponse shows the game after a move as gameResponse does, but sends
+// browsers back to the home page, so that reloading it does not post the
+// move again.
+func moveResponse(app *models.App, c *gin.Context, sessionID string, gameState *models.GameState, newRow int, before *auth.Stats) Response {
+	resp := gameResponse(app, c, sessionID, gameState, newRow, before)
+	resp.Page, resp.Redirect = "", app.Path(constants.RouteHome)
+	return resp
+}
+
+// newGameResponse shows a game that just started.
+func newGameResponse(app *models.App, c *gin.Context, sessionID string, gameState *models.GameState) Response {
+	resp := gameResponse(app, c, sessionID, gameState, -1, nil)
+	resp.Data.(*IndexView).NewGame = true
+	return resp
+}
+
+// gameStatus is the JSON form of a game.
+func gameStatus(gameState *models.GameState) gin.H {
+	status := gin.H{
+		"id":          gameState.ID,
+		"number":      gameState.Number,
+		"puzzle":      gameState.Puzzle,
+		"label":       gameState.Label(),
+		"boards":      statusRows(game.SpectatorBoards(gameState)),
+		"guesses":     gameState.GuessHistory,
+		"current_row": gameState.CurrentRow,
+		"game_over":   gameState.GameOver,
+		"won":         gameState.Won,
+	}
+	if !gameState.ContinueAt.IsZero() {
+		status["continue_at"] = gameState.ContinueAt
+	}
+	if gameState.Pool != "" {
+		status["pool"] = gameState.Pool
+	}
+	if gameState.Pack != "" {
+		status["pack"] = gameState.Pack
+	}
+	if gameState.Assisted {
+		status["assist_letter"] = game.AssistLetter(gameState)
+	}
+	if gameState.Scored {
+		status["score"] = gameState.Score
+		status["points"] = gameState.Points
+	}
+	return status
 }
 
 func NewGameHandler(app *models.App, c *gin.Context) {
 	ctx := c.Request.Context()
 	sessionID := session.GetOrCreateSession(app, c)
+	unlock := session.Lock(app, sessionID)
+	defer unlock()
 	util.LogInfo("Creating new game for session: %s", sessionID)
 
-	var completedWords []string
+	var completed []string
 	if c.Request.Method == "POST" {
-		completedWordsStr := c.PostForm("completedWords")
-		if completedWordsStr != "" {
-			if err := json.Unmarshal([]byte(completedWordsStr), &completedWords); err != nil {
-				util.LogWarn("Failed to parse completed words: %v", err)
-				completedWords = []string{}
-			} else {
-				validCompletedWords := lo.Filter(completedWords, func(word string, _ int) bool {
-					_, exists := app.WordSet[word]
-					if !exists {
-						util.LogWarn("Invalid completed word ignored: %s", word)
-					}
-					return exists
-				})
-				completedWords = validCompletedWords
-				util.LogInfo("Validated %d completed words for session %s", len(completedWords), sessionID)
+		completed = completedWords(app, c, sessionID, c.PostForm("completedWords"))
+	}
+	if deadlineExceeded(app, c) {
+		return
+	}
+
+	// warned is the last warning set in the HX-Trigger header, for the
+	// browsers redirected home, which lose it.
+	var warned string
+	warn := func(gameErr *game.GameError) {
+		setErrorTrigger(c, gameErr)
+		warned = gameErr.Code
+	}
+
+	mode := c.DefaultPostForm("mode", c.Query("mode"))
+	boardCount, _ := strconv.Atoi(c.DefaultPostForm("boards", c.Query("boards")))
+	switch mode {
+	case constants.ModeDordle:
+		boardCount = 2
+	case constants.ModeQuordle:
+		boardCount = 4
+	}
+	pool := c.DefaultPostForm("pool", c.Query("pool"))
+	if !game.HasPool(app, pool) {
+		warn(game.NewGameError(constants.ErrorCodeUnknownPool).WithDetail("pool", pool))
+		pool = constants.PoolClassic
+	}
+	var pack models.Pack
+	if id := c.DefaultPostForm("pack", c.Query("pack")); id != "" {
+		var ok bool
+		if pack, ok = game.FindPack(app, id); !ok {
+			warn(game.NewGameError(constants.ErrorCodeUnknownPack).WithDetail("pack", id))
+		}
+	}
+	createGame := func(id string) {
+		var newGame *models.GameState
+		var needsReset bool
+		switch {
+		case pack.ID != "":
+			newGame = game.CreatePackGame(app, ctx, id, pack, playerStats(app, c, id).Packs[pack.ID])
+		case mode == constants.ModeTournament:
+			newGame = game.CreateTournamentGame(app, ctx, id, tournamentPlayer(app, c))
+			if newGame == nil {
+				warn(game.NewGameError(constants.ErrorCodeTournamentPlayed))
+				newGame = game.CreateNewGame(app, ctx, id)
 			}
+		case game.IsAllowedBoardCount(boardCount):
+			newGame, needsReset = game.CreateMultiBoardGame(app, ctx, id, pool, boardCount, completed)
+		default:
+			newGame, needsReset = game.CreateNewGameWithCompletedWords(app, ctx, id, pool, completed)
+		}
+		switch {
+		case mode == constants.ModeRace && !game.IsMultiBoard(newGame):
+			game.StartRace(newGame, app.BotRaceInterval)
+		case mode == constants.ModeAdversarial:
+			game.StartAdversarial(newGame)
+		}
+		if needsReset {
+			clearCompletedWords(app, c, id)
 		}
 	}
 
-	app.SessionMutex.Lock()
-	delete(app.GameSessions, sessionID)
-	app.SessionMutex.Unlock()
-	util.LogInfo("Cleared old session data for: %s", sessionID)
-
 	if c.Query("reset") == "1" {
-		c.SetSameSite(http.SameSiteStrictMode)
-		secure := app.IsProduction
-		c.SetCookie(constants.SessionCookieName, "", -1, "/", "", secure, true)
+		app.Sessions.DeleteGame(sessionID)
+		util.LogInfo("Cleared old session data for: %s", sessionID)
 
-		newSessionID := uuid.NewString()
-		c.SetSameSite(http.SameSiteStrictMode)
-		c.SetCookie(constants.SessionCookieName, newSessionID, int(app.CookieMaxAge.Seconds()), "/", "", secure, true)
+		newSessionID := session.NewID(app)
+		session.SetCookie(app, c, newSessionID)
 		util.LogInfo("Created new session ID: %s", newSessionID)
 
-		if len(completedWords) > 0 {
-			_, needsReset := game.CreateNewGameWithCompletedWords(app, ctx, newSessionID, completedWords)
-			if needsReset {
-				c.Header("HX-Trigger", "clear-completed-words")
-			}
-		} else {
-			game.CreateNewGame(app, ctx, newSessionID)
-		}
+		createGame(newSessionID)
 		sessionID = newSessionID
 	} else {
-		if len(completedWords) > 0 {
-			_, needsReset := game.CreateNewGameWithCompletedWords(app, ctx, sessionID, completedWords)
-			if needsReset {
-				c.Header("HX-Trigger", "clear-completed-words")
-			}
-		} else {
-			game.CreateNewGame(app, ctx, sessionID)
-		}
+		createGame(sessionID)
 	}
 
-	isHTMX := c.GetHeader("HX-Request") == "true"
-	if isHTMX {
-		gameState := session.GetGameState(app, ctx, sessionID)
-		hint := game.GetHintForWord(app, gameState.SessionWord)
-		csrfToken, _ := c.Cookie("csrf_token")
-		c.HTML(http.StatusOK, "game-content", gin.H{
-			"game":       gameState,
-			"hint":       hint,
-			"newGame":    true,
-			"csrf_token": csrfToken,
-		})
-	} else {
-		c.Redirect(http.StatusSeeOther, constants.RouteHome)
+	// Browsers reload the home page rather than the new game's, so that
+	// refreshing it does not start yet another game.
+	resp := newGameResponse(app, c, sessionID, session.GetGameState(app, ctx, sessionID))
+	resp.Page, resp.Redirect = "", app.Path(constants.RouteHome)
+	if warned != "" {
+		resp.Redirect = withErrorCode(resp.Redirect, warned)
 	}
+	resp.Send(c)
 }
 
 func GuessHandler(app *models.App, c *gin.Context) {
 	ctx := c.Request.Context()
 	sessionID := session.GetOrCreateSession(app, c)
+	unlock := session.Lock(app, sessionID)
+	defer unlock()
+	replayed, record := idempotent(app, c, sessionID)
+	if replayed {
+		return
+	}
+	defer record()
+	expired := session.GameExpired(app, c, sessionID)
 	gameState := session.GetGameState(app, ctx, sessionID)
-	hint := game.GetHintForWord(app, gameState.SessionWord)
-
-	renderBoard := func(errCode string) {
-		csrfToken, _ := c.Cookie("csrf_token")
-		if errCode != "" {
-			payload := map[string]string{"server_error_code": errCode}
-			if b, jerr := json.Marshal(payload); jerr == nil {
-				c.Header("HX-Trigger", string(b))
-			} else {
-				util.LogWarn("Failed to marshal HX-Trigger payload: %v", jerr)
-			}
-		}
-		c.HTML(http.StatusOK, "game-content", gin.H{
-			"game":       gameState,
-			"hint":       hint,
-			"error_code": errCode,
-			"csrf_token": csrfToken,
-		})
+	game.AdvanceRace(app, ctx, gameState, time.Now())
+
+	fail := func(err error) {
+		moveResponse(app, c, sessionID, gameState, -1, nil).Fail(c, game.AsGameError(err))
 	}
 
-	renderFullPage := func(errCode string) {
-		csrfToken, _ := c.Cookie("csrf_token")
-		if errCode != "" {
-			payload := map[string]string{"server_error_code": errCode}
-			if b, jerr := json.Marshal(payload); jerr == nil {
-				c.Header("HX-Trigger", string(b))
-			} else {
-				util.LogWarn("Failed to marshal HX-Trigger payload: %v", jerr)
-			}
-		}
-		c.HTML(http.StatusOK, "index.html", gin.H{
-			"title":      "Vortludo - A Libre Wordle Clone",
-			"message":    "Guess the 5-letter word!",
-			"hint":       hint,
-			"game":       gameState,
-			"error_code": errCode,
-			"csrf_token": csrfToken,
-		})
+	if expired {
+		gameState.AfterExpiry = true
+		fail(game.NewGameError(constants.ErrorCodeSessionExpired))
+		return
 	}
 
-	isHTMX := c.GetHeader("HX-Request") == "true"
-	var errCode string
 	if err := ValidateGameState(app, c, gameState); err != nil {
-		errCode = err.Error()
-		if isHTMX {
-			renderBoard(errCode)
-		} else {
-			renderFullPage(errCode)
-		}
+		fail(err)
+		return
+	}
+	if err := game.CheckCooldown(gameState, app.GuessCooldown, time.Now()); err != nil {
+		fail(err)
 		return
 	}
 
-	guess := NormalizeGuess(c.PostForm("guess"))
-	if !game.IsAcceptedWord(app, guess) {
-		errCode = constants.ErrorCodeWordNotAccepted
-		if isHTMX {
-			renderBoard(errCode)
-		} else {
-			renderFullPage(errCode)
-		}
+	guess := game.ParseGuess(c.PostForm("guess"))
+	if err := checkGuess(app, sessionID, gameState, guess); err != nil {
+		fail(err)
+		return
+	}
+	if deadlineExceeded(app, c) {
 		return
 	}
+	if err := ProcessGuess(app, ctx, c, sessionID, gameState, guess); err != nil {
+		fail(err)
+		return
+	}
+}
 
-	if slices.Contains(gameState.GuessHistory, guess) {
-		errCode = constants.ErrorCodeDuplicateGuess
-		if isHTMX {
-			renderBoard(errCode)
-		} else {
-			renderFullPage(errCode)
-		}
+// checkGuess refuses a guess the game cannot take: one of the wrong length,
+// blocked, not accepted, already made, or breaking the assist or hard mode
+// rules.
+func checkGuess(app *models.App, sessionID string, gameState *models.GameState, guess game.Guess) error {
+	if err := guess.CheckLength(); err != nil {
+		return err
+	}
+	if game.IsBlockedWord(app, guess.Word) {
+		return game.NewGameError(constants.ErrorCodeWordBlocked)
+	}
+	if !game.IsAcceptedWord(app, guess.Word) {
+		return game.NewGameError(constants.ErrorCodeWordNotAccepted).WithDetail("guess", guess.Word)
+	}
+	if slices.Contains(gameState.GuessHistory, guess.Word) {
+		return game.NewGameError(constants.ErrorCodeDuplicateGuess).WithDetail("guess", guess.Word)
+	}
+	if err := game.CheckAssist(gameState, guess.Word); err != nil {
+		return err
+	}
+	if session.GetSettings(app, sessionID).HardMode {
+		return game.CheckHardMode(gameState, guess.Word)
+	}
+	return nil
+}
+
+// recordGuessAnalytics counts a guess, and the game once it is over, unless
+// the session opted out of analytics.
+func recordGuessAnalytics(app *models.App, sessionID string, gameState *models.GameState) {
+	if session.GetSettings(app, sessionID).DoNotTrack {
 		return
 	}
-	if err := ProcessGuess(app, ctx, c, sessionID, gameState, guess, isHTMX, hint); err != nil {
-		errCode = err.Error()
-		if isHTMX {
-			renderBoard(errCode)
-		} else {
-			renderFullPage(errCode)
-		}
+	app.Analytics.RecordActivity(sessionID)
+	if len(gameState.GuessHistory) == 1 {
+		app.Analytics.RecordFirstGuess(gameState.GuessHistory[0])
+	}
+	if !gameState.GameOver {
 		return
 	}
+	app.Analytics.RecordGame(gameResult(app, sessionID, gameState))
+}
+
+// recordBotSignals scores a finished game for signs of automation.
+func recordBotSignals(app *models.App, sessionID string, gameState *models.GameState) {
+	verdict, flagged := app.Bots.Record(sessionID, game.BotSignals(app, gameState), time.Now())
+	if flagged {
+		util.LogWarn("Flagged session %s as a likely bot: %v", sessionID, verdict.Reasons)
+	}
+}
+
+// gameResult is what analytics learns from a finished game.
+func gameResult(app *models.App, sessionID string, gameState *models.GameState) analytics.GameResult {
+	result := analytics.GameResult{
+		Won:       gameState.Won,
+		Guesses:   len(gameState.GuessHistory),
+		Flagged:   app.Bots.Flagged(sessionID),
+		Untracked: session.GetSettings(app, sessionID).DoNotTrack,
+	}
+	if game.IsMultiBoard(gameState) {
+		for _, board := range gameState.Boards {
+			result.Words = append(result.Words, analytics.WordOutcome{Word: board.TargetWord, Solved: board.Solved, Guesses: board.SolvedAtRow + 1})
+			if !board.Solved {
+				result.MissedWords = append(result.MissedWords, board.TargetWord)
+			}
+		}
+	} else {
+		result.Words = []analytics.WordOutcome{{Word: gameState.TargetWord, Solved: gameState.Won, Guesses: result.Guesses}}
+		if !gameState.Won {
+			result.MissedWords = []string{gameState.TargetWord}
+		}
+	}
+	return result
+}
+
+// logEvent appends to the game event log, if one is configured. A failed
+// write is logged but does not fail the request.
+func logEvent(app *models.App, eventType, sessionID, gameID string, data any) {
+	if err := app.EventLog.Append(eventType, sessionID, gameID, data); err != nil {
+		util.LogWarn("Failed to log %s event for session %s: %v", eventType, sessionID, err)
+	}
+}
+
+// noteExpiredGame flags a game that replaced an expired one so the templates
+// show the expiry banner, and tells the client to drop its local game state.
+func noteExpiredGame(c *gin.Context, gameState *models.GameState) {
+	gameState.AfterExpiry = true
+	events.From(c).Flag(events.SessionExpired)
+}
+
+// boardView returns what the game-board template renders: rows for a classic
+// game, one BoardView per word for a multi-board game.
+func boardView(gameState *models.GameState, newRow int) any {
+	if game.IsMultiBoard(gameState) {
+		return game.BuildBoards(gameState, newRow)
+	}
+	return game.BuildBoard(gameState, newRow)
+}
+
+func setErrorTrigger(c *gin.Context, gameErr *game.GameError) {
+	events.From(c).Error(gameErr.Code, gameErr.Details)
+}
+
+// theme returns the theme pages render in, that of the request's session.
+func theme(c *gin.Context) string {
+	if theme := c.GetString(constants.ThemeKey); theme != "" {
+		return theme
+	}
+	return constants.ThemeAuto
+}
+
+func csrfToken(c *gin.Context) string {
+	return c.GetString(constants.CSRFTokenKey)
+}
+
+func cspNonce(c *gin.Context) string {
+	return c.GetString(constants.CSPNonceKey)
 }
 
 func GameStateHandler(app *models.App, c *gin.Context) {
 	ctx := c.Request.Context()
 	sessionID := session.GetOrCreateSession(app, c)
+	unlock := session.Lock(app, sessionID)
+	defer unlock()
+	expired := session.GameExpired(app, c, sessionID)
 	gameState := session.GetGameState(app, ctx, sessionID)
-	hint := game.GetHintForWord(app, gameState.SessionWord)
+	if expired {
+		noteExpiredGame(c, gameState)
+	}
+	game.AdvanceRace(app, ctx, gameState, time.Now())
+	if game.ContinueDue(gameState, time.Now()) {
+		// The client sends its completed words with the poll that is due
+		// to start the next game, as it does when asking for one.
+		completed := completedWords(app, c, sessionID, c.Query("completedWords"))
+		packSolved := playerStats(app, c, sessionID).Packs[gameState.Pack]
+		next, needsReset := game.ContinueGame(app, ctx, sessionID, gameState, completed, packSolved)
+		if needsReset {
+			clearCompletedWords(app, c, sessionID)
+		}
+		util.LogInfo("Session %s continued from game %s to game %s", sessionID, gameState.ID, next.ID)
+		newGameResponse(app, c, sessionID, next).Send(c)
+		return
+	}
+	gameResponse(app, c, sessionID, gameState, -1, nil).Send(c)
+}
 
-	csrfToken, _ := c.Cookie("csrf_token")
-	c.HTML(http.StatusOK, "game-content", gin.H{
-		"game":       gameState,
-		"hint":       hint,
-		"csrf_token": csrfToken,
+// RaceStateHandler renders the bot's progress in a race game. It is polled by
+// the race board; when the bot finishes the game the client is told to reload
+// the whole board.
+func RaceStateHandler(app *models.App, c *gin.Context) {
+	ctx := c.Request.Context()
+	sessionID := session.GetOrCreateSession(app, c)
+	unlock := session.Lock(app, sessionID)
+	defer unlock()
+	gameState := session.GetGameState(app, ctx, sessionID)
+	if game.AdvanceRace(app, ctx, gameState, time.Now()) {
+		events.From(c).Flag(events.RaceFinished)
+	}
+	Response{
+		Fragment: "race-board",
+		Redirect: app.Path(constants.RouteHome),
+		Data:     presentGameContent(app, c, sessionID, gameState, -1, nil),
+		JSON:     gameStatus(gameState),
+	}.Send(c)
+}
+
+// SolverHintHandler suggests high-information next guesses. Each call uses up
+// one of the game's solver hints; the hint is not offered in hard mode or in
+// multi-board games.
+func SolverHintHandler(app *models.App, c *gin.Context) {
+	ctx := c.Request.Context()
+	sessionID := session.GetOrCreateSession(app, c)
+	unlock := session.Lock(app, sessionID)
+	defer unlock()
+	gameState := session.GetGameState(app, ctx, sessionID)
+	game.AdvanceRace(app, ctx, gameState, time.Now())
+
+	switch {
+	case gameState.GameOver:
+		RespondGameError(c, game.NewGameError(constants.ErrorCodeGameOver))
+		return
+	case session.GetSettings(app, sessionID).HardMode:
+		RespondGameError(c, game.NewGameError(constants.ErrorCodeHintUnavailable).WithDetail("reason", "hard_mode"))
+		return
+	case game.IsMultiBoard(gameState):
+		RespondGameError(c, game.NewGameError(constants.ErrorCodeHintUnavailable).WithDetail("reason", "multi_board"))
+		return
+	case gameState.SolverHints >= app.SolverHintLimit:
+		RespondGameError(c, game.NewGameError(constants.ErrorCodeHintsExhausted).WithDetail("limit", app.SolverHintLimit))
+		return
+	}
+
+	suggestions, remaining := game.SuggestGuesses(app, gameState, constants.SolverSuggestionCount)
+	gameState.SolverHints++
+	session.SaveGameState(app, sessionID, gameState)
+	util.LogInfo("Session %s used solver hint %d/%d (%d answers remaining)", sessionID, gameState.SolverHints, app.SolverHintLimit, remaining)
+
+	c.JSON(http.StatusOK, gin.H{
+		"suggestions": suggestions,
+		"remaining":   remaining,
+		"hints_used":  gameState.SolverHints,
+		"hints_left":  app.SolverHintLimit - gameState.SolverHints,
 	})
 }
 
+// HintHandler reveals the next of the word's staged hints. In hint tax mode
+// each one costs the game a row.
+func HintHandler(app *models.App, c *gin.Context) {
+	ctx := c.Request.Context()
+	sessionID := session.GetOrCreateSession(app, c)
+	unlock := session.Lock(app, sessionID)
+	defer unlock()
+	gameState := session.GetGameState(app, ctx, sessionID)
+	game.AdvanceRace(app, ctx, gameState, time.Now())
+	settings := session.GetSettings(app, sessionID)
+
+	hint, err := game.RevealHint(app, gameState, settings.HintTax)
+	resp := moveResponse(app, c, sessionID, gameState, -1, nil)
+	if err != nil {
+		resp.Fail(c, game.AsGameError(err))
+		return
+	}
+	session.SaveGameState(app, sessionID, gameState)
+	logEvent(app, eventlog.HintRevealed, sessionID, gameState.ID, models.HintEvent{Stage: gameState.HintsRevealed - 1, Taxed: settings.HintTax})
+	util.LogInfo("Session %s revealed hint %d (hint tax: %t)", sessionID, gameState.HintsRevealed, settings.HintTax)
+
+	view := game.BuildHintView(app, gameState, settings.HintTax)
+	resp.JSON = gin.H{
+		"hint":       hint,
+		"hints":      view.Revealed,
+		"hints_left": view.Left,
+		"rows_left":  game.MaxRows(gameState) - gameState.CurrentRow,
+	}
+	resp.Send(c)
+}
+
+// TournamentHandler shows the standings of this week's tournament and the
+// winners of past weeks.
+func TournamentHandler(app *models.App, c *gin.Context) {
+	now := time.Now()
+	current := game.CurrentTournament(app, now)
+	standings := app.Tournament.Standings(now)
+	archive := app.Tournament.Archive()
+
+	var me string
+	if player, ok := currentPlayer(app, c); ok {
+		me = tournament.PlayerLabel(player)
+	}
+	sessionID := session.GetOrCreateSession(app, c)
+	Response{
+		Page: "tournament.html",
+		Data: gin.H{
+			"title":     "Vortludo - Weekly Tournament",
+			"week":      current.Week,
+			"day":       tournament.DayIndex(now) + 1,
+			"days":      tournament.Days,
+			"standings": standings,
+			"archive":   archive,
+			"me":        me,
+			"settings":  session.GetSettings(app, sessionID),
+			"preview":   dailyPreview(app, c, tournament.PuzzleNumber(now)),
+		},
+		JSON: gin.H{
+			"week":      current.Week,
+			"day":       tournament.DayIndex(now) + 1,
+			"puzzle":    tournament.PuzzleNumber(now),
+			"standings": standings,
+			"archive":   archive,
+		},
+	}.Send(c)
+}
+
+// currentPlayer returns the tournament player of the request, if it has one.
+func currentPlayer(app *models.App, c *gin.Context) (string, bool) {
+	if app.MinimalCookies {
+		sessionID := session.CookieID(app, c)
+		return sessionID, sessionID != ""
+	}
+	player, err := c.Cookie(constants.PlayerCookieName)
+	return player, err == nil
+}
+
+// tournamentPlayer returns the player ID from the long-lived player cookie,
+// issuing a new one when needed. With minimal cookies the session stands in
+// for the player, and no player cookie is set.
+func tournamentPlayer(app *models.App, c *gin.Context) string {
+	if app.MinimalCookies {
+		return session.GetOrCreateSession(app, c)
+	}
+	player, err := c.Cookie(constants.PlayerCookieName)
+	if err != nil || len(player) < 10 {
+		player = uuid.NewString()
+		util.LogInfo("Created new tournament player: %s", tournament.PlayerLabel(player))
+	}
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(constants.PlayerCookieName, player, int(constants.PlayerCookieMaxAge.Seconds()), app.CookiePath(), "", app.IsProduction, true)
+	return player
+}
+
+func SettingsHandler(app *models.App, c *gin.Context) {
+	sessionID := session.GetOrCreateSession(app, c)
+	settingsResponse(session.GetSettings(app, sessionID)).Send(c)
+}
+
+// settingsResponse shows the settings page, or the settings themselves to
+// JSON clients.
+func settingsResponse(settings models.UserSettings) Response {
+	return Response{
+		Page: "settings.html",
+		Data: gin.H{
+			"title":     "Vortludo - Settings",
+			"settings":  settings,
+			"languages": constants.SupportedLanguages,
+			"themes":    constants.SupportedThemes,
+			"layouts":   keyboard.All(),
+		},
+		JSON: settings,
+	}
+}
+
+// UpdateSettingsHandler accepts either a JSON body, applied on top of the
+// current settings, or the settings page form, where an unchecked box means
+// false.
+func UpdateSettingsHandler(app *models.App, c *gin.Context) {
+	sessionID := session.GetOrCreateSession(app, c)
+	unlock := session.Lock(app, sessionID)
+	defer unlock()
+	settings := session.GetSettings(app, sessionID)
+	if c.ContentType() == gin.MIMEJSON {
+		if err := c.ShouldBindJSON(&settings); err != nil {
+			RespondGameError(c, game.NewGameError(constants.ErrorCodeInvalidSettings))
+			return
+		}
+	} else {
+		settings = models.UserSettings{
+			HardMode:       c.PostForm("hardMode") == "on",
+			HintTax:        c.PostForm("hintTax") == "on",
+			AutoContinue:   c.PostForm("autoContinue") == "on",
+			Assisted:       c.PostForm("assisted") == "on",
+			Points:         c.PostForm("points") == "on",
+			ColorBlind:     c.PostForm("colorBlind") == "on",
+			Language:       c.PostForm("language"),
+			KeyboardLayout: c.PostForm("keyboardLayout"),
+			Theme:          c.PostForm("theme"),
+			ReducedMotion:  c.PostForm("reducedMotion") == "on",
+			DoNotTrack:     c.PostForm("doNotTrack") == "on",
+		}
+	}
+	if err := game.ValidateSettings(&settings); err != nil {
+		gameErr := game.AsGameError(err)
+		resp := settingsResponse(settings)
+		resp.Status = gameErr.Status
+		resp.Fail(c, gameErr)
+		return
+	}
+	session.SaveSettings(app, sessionID, settings)
+	if user, ok := currentUser(app, c); ok {
+		saveAccountSettings(app, user.ID, settings)
+	}
+
+	Response{Redirect: app.Path(constants.RouteHome), JSON: settings}.Send(c)
+}
+
+// ThemeHandler sets the session's theme alone, for the theme toggle. htmx
+// requests get no content back, as the page has already switched.
+func ThemeHandler(app *models.App, c *gin.Context) {
+	sessionID := session.GetOrCreateSession(app, c)
+	unlock := session.Lock(app, sessionID)
+	defer unlock()
+	settings := session.GetSettings(app, sessionID)
+	settings.Theme = c.PostForm("theme")
+	if err := game.ValidateSettings(&settings); err != nil {
+		RespondGameError(c, game.AsGameError(err))
+		return
+	}
+	session.SaveSettings(app, sessionID, settings)
+	if user, ok := currentUser(app, c); ok {
+		saveAccountSettings(app, user.ID, settings)
+	}
+
+	if Negotiate(c) == FormatFragment {
+		c.Status(http.StatusNoContent)
+		return
+	}
+	Response{Redirect: app.Path(constants.RouteSettings), JSON: gin.H{"theme": settings.Theme}}.Send(c)
+}
+
 func RetryWordHandler(app *models.App, c *gin.Context) {
 	ctx := c.Request.Context()
 	sessionID := session.GetOrCreateSession(app, c)
-	app.SessionMutex.Lock()
-	gameState, exists := app.GameSessions[sessionID]
+	unlock := session.Lock(app, sessionID)
+	defer unlock()
+	gameState, exists := app.Sessions.Game(sessionID)
 	if !exists {
-		app.SessionMutex.Unlock()
 		game.CreateNewGame(app, ctx, sessionID)
-		c.Redirect(http.StatusSeeOther, "/")
+		c.Redirect(http.StatusSeeOther, app.Path(constants.RouteHome))
+		return
+	}
+	if game.RetryGame(app, sessionID, gameState) == nil {
+		game.CreateNewGame(app, ctx, sessionID)
+	}
+	c.Redirect(http.StatusSeeOther, app.Path(constants.RouteHome))
+}
+
+// StaticHandler serves the static files. Requests for a fingerprinted name
+// are cached for a year; plain names keep the short StaticCacheAge.
+func StaticHandler(app *models.App, c *gin.Context) {
+	name, etag, immutable := app.Assets.Resolve(c.Param("filepath"))
+	f, err := app.Assets.Open(name)
+	if err != nil {
+		c.Status(http.StatusNotFound)
 		return
 	}
-	sessionWord := gameState.SessionWord
-	guesses := lo.Times(constants.MaxGuesses, func(_ int) []models.GuessResult {
-		return lo.Times(constants.WordLength, func(_ int) models.GuessResult { return models.GuessResult{} })
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil || stat.IsDir() {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		data, err := io.ReadAll(f)
+		if err != nil {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		content = bytes.NewReader(data)
+	}
+
+	if immutable {
+		c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d, immutable", int(assets.ImmutableMaxAge.Seconds())))
+	} else {
+		c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(app.StaticCacheAge.Seconds())))
+	}
+	if compress.Compressible(mime.TypeByExtension(path.Ext(name))) {
+		c.Header("Vary", "Accept-Encoding")
+		var offered []string
+		for _, encoding := range compress.Encodings {
+			if _, ok := app.Assets.Variant(name, encoding); ok {
+				offered = append(offered, encoding)
+			}
+		}
+		if encoding := compress.Negotiate(c.GetHeader("Accept-Encoding"), offered...); encoding != "" {
+			data, _ := app.Assets.Variant(name, encoding)
+			content = bytes.NewReader(data)
+			c.Header("Content-Encoding", encoding)
+			if etag != "" {
+				etag += "-" + encoding
+			}
+		}
+	}
+	if etag != "" {
+		c.Header("ETag", `"`+etag+`"`)
+	}
+	http.ServeContent(c.Writer, c.Request, name, stat.ModTime(), content)
+}
+
+// LivezHandler reports that the process is up and serving requests. It
+// checks nothing else, so a probe failing on it means the instance should be
+// restarted.
+func LivezHandler(app *models.App, c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{
+		"status": "ok",
+		"uptime": util.FormatUptime(time.Since(app.StartTime)),
 	})
-	newGame := &models.GameState{
-		Guesses:        guesses,
-		CurrentRow:     0,
-		GameOver:       false,
-		Won:            false,
-		TargetWord:     "",
-		SessionWord:    sessionWord,
-		GuessHistory:   []string{},
-		LastAccessTime: time.Now(),
-	}
-	app.GameSessions[sessionID] = newGame
-	app.SessionMutex.Unlock()
-	c.Redirect(http.StatusSeeOther, "/")
-}
-
-func HealthzHandler(app *models.App, c *gin.Context) {
+}
+
+// ReadyzHandler reports whether the instance can take players: the dictionary
+// is loaded, the session store and any persistent store respond and
+// maintenance mode is off. It
+// responds 503 with the failing checks otherwise, so the instance is taken
+// out of rotation without being restarted.
+func ReadyzHandler(app *models.App, c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	checks, ready := readinessChecks(app, c.Request.Context())
+	if !ready {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status": "unavailable",
+			"checks": checks,
+		})
+		return
+	}
+
 	var m runtime.MemStats
 	runtime.ReadMemStats(&m)
 
 	uptime := time.Since(app.StartTime)
-
-	app.SessionMutex.RLock()
-	sessionCount := len(app.GameSessions)
-	app.SessionMutex.RUnlock()
+	words, accepted := game.DictionarySize(app)
+	limits := session.Limits(app)
 
 	app.LimiterMutex.RLock()
 	limiterCount := len(app.LimiterMap)
+	limitersCreated, limiterPeak := app.LimitersCreated, app.LimiterPeak
 	app.LimiterMutex.RUnlock()
 
-	c.JSON(http.StatusOK, gin.H{
+	body := gin.H{
 		"status":          "ok",
+		"checks":          checks,
 		"env":             map[bool]string{true: "production", false: "development"}[app.IsProduction],
-		"words_loaded":    len(app.WordList),
-		"accepted_words":  len(app.AcceptedWordSet),
-		"active_sessions": sessionCount,
+		"words_loaded":    words,
+		"accepted_words":  accepted,
+		"blocked_words":   game.BlockedWordCount(app),
+		"active_sessions": limits.Active,
+		"session_limit":   limits,
 		"active_limiters": limiterCount,
+		"limiters_total":  limitersCreated,
+		"limiter_peak":    limiterPeak,
 		"memory_alloc_mb": m.Alloc / 1024 / 1024,
 		"memory_sys_mb":   m.Sys / 1024 / 1024,
 		"memory_gc_count": m.NumGC,
 		"uptime":          util.FormatUptime(uptime),
 		"timestamp":       time.Now().UTC().Format(time.RFC3339),
+	}
+	if app.Store != nil {
+		body["store"] = app.Store.Pool()
+	}
+	if app.WriteBehind != nil {
+		body["store_queue"] = app.WriteBehind.Stats()
+	}
+	c.JSON(http.StatusOK, body)
+}
+
+// readinessChecks runs the readiness checks, mapping each to "ok" or the
+// reason it failed.
+func readinessChecks(app *models.App, ctx context.Context) (map[string]string, bool) {
+	checks := map[string]string{
+		"dictionary":  "ok",
+		"sessions":    "ok",
+		"maintenance": "ok",
+	}
+	if words, accepted := game.DictionarySize(app); words == 0 || accepted == 0 {
+		checks["dictionary"] = "not loaded"
+	}
+	ctx, cancel := context.WithTimeout(ctx, constants.ReadinessTimeout)
+	defer cancel()
+	if err := session.Ping(ctx, app); err != nil {
+		checks["sessions"] = "unreachable: " + err.Error()
+	}
+	if app.Store != nil {
+		checks["store"] = "ok"
+		if err := app.Store.Ping(ctx); err != nil {
+			checks["store"] = "unreachable: " + err.Error()
+		}
+	}
+	if app.Maintenance.Load() {
+		checks["maintenance"] = "enabled"
+	}
+	for _, result := range checks {
+		if result != "ok" {
+			return checks, false
+		}
+	}
+	return checks, true
+}
+
+// AdminMaintenanceHandler turns maintenance mode on or off with
+// ?enabled=true|false and reports the current state.
+func AdminMaintenanceHandler(app *models.App, c *gin.Context) {
+	if value := c.Query("enabled"); value != "" {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "enabled must be true or false"})
+			return
+		}
+		if was := app.Maintenance.Swap(enabled); was != enabled {
+			util.LogInfo("Maintenance mode %s", map[bool]string{true: "enabled", false: "disabled"}[enabled])
+			recordAdminAction(app, c, audit.Maintenance, gin.H{"maintenance": was}, gin.H{"maintenance": enabled})
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"maintenance": app.Maintenance.Load()})
+}
+
+// AdminBansHandler lists the clients currently banned for abuse.
+func AdminBansHandler(app *models.App, c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"bans": app.Abuse.Bans(time.Now())})
+}
+
+// AdminBotsHandler lists the sessions that played games that looked
+// automated, flagged ones among them.
+func AdminBotsHandler(app *models.App, c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"sessions": app.Bots.Sessions()})
+}
+
+// AdminClearBotHandler forgets what bot detection learned of ?session=,
+// e.g. after checking it is a person.
+func AdminClearBotHandler(app *models.App, c *gin.Context) {
+	sessionID := c.Query("session")
+	if sessionID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "session is required"})
+		return
+	}
+	var before any = gin.H{"id": sessionID}
+	for _, suspect := range app.Bots.Sessions() {
+		if suspect.ID == sessionID {
+			before = suspect
+		}
+	}
+	if !app.Bots.Clear(sessionID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no such session"})
+		return
+	}
+	util.LogInfo("Cleared the bot score of session %s", sessionID)
+	recordAdminAction(app, c, audit.ClearBot, before, nil)
+	c.JSON(http.StatusOK, gin.H{"cleared": sessionID})
+}
+
+// AdminLiftBanHandler ends the ban of ?key=, e.g. "ip:192.0.2.1", and
+// clears its history so a later ban starts short again.
+func AdminLiftBanHandler(app *models.App, c *gin.Context) {
+	key := c.Query("key")
+	if key == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "key is required"})
+		return
+	}
+	var before any = gin.H{"key": key}
+	for _, ban := range app.Abuse.Bans(time.Now()) {
+		if ban.Key == key {
+			before = ban
+		}
+	}
+	if !app.Abuse.Lift(key) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no such client"})
+		return
+	}
+	util.LogInfo("Lifted the ban of %s", key)
+	recordAdminAction(app, c, audit.LiftBan, before, nil)
+	c.JSON(http.StatusOK, gin.H{"lifted": key})
+}
+
+func AdminReloadBlocklistHandler(app *models.App, c *gin.Context) {
+	before := game.BlockedWordCount(app)
+	count, err := game.ReloadBlockedWords(app)
+	if err != nil {
+		util.LogWarn("Failed to reload blocklist: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to reload blocklist"})
+		return
+	}
+	recordAdminAction(app, c, audit.ReloadBlocklist, gin.H{"blocked_words": before}, gin.H{"blocked_words": count})
+	c.JSON(http.StatusOK, gin.H{
+		"status":        "ok",
+		"blocked_words": count,
+	})
+}
+
+func AdminMetricsSummaryHandler(app *models.App, c *gin.Context) {
+	c.JSON(http.StatusOK, struct {
+		analytics.Summary
+		Sessions  session.LimitStatus   `json:"sessions"`
+		SessionGC models.SessionGCStats `json:"session_gc"`
+	}{app.Analytics.Summary(), session.Limits(app), app.SessionGC.Stats()})
+}
+
+// DebugGameHandler reconstructs the evaluations of the game with the public
+// ID :id from its guess history, to reproduce reported scoring bugs. It
+// reveals the game's words, so it is only served to admins and in
+// development.
+func DebugGameHandler(app *models.App, c *gin.Context) {
+	gameID := c.Param("id")
+	var gameState *models.GameState
+	if sessionID, ok := session.FindGame(app, gameID); ok {
+		if snapshot, ok := session.Snapshot(app, sessionID); ok && snapshot.ID == gameID {
+			gameState = &snapshot
+		}
+	}
+	if gameState == nil && app.EventLog != nil {
+		logged, err := session.GameFromEventLog(app, app.EventLog, gameID)
+		if err != nil {
+			util.LogWarn("Failed to read game %s from the event log: %v", gameID, err)
+		}
+		gameState = logged
+	}
+	if gameState == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "game not found"})
+		return
+	}
+	evaluations := game.ReplayGame(app, gameState)
+	targets := []string{gameState.SessionWord}
+	if game.IsMultiBoard(gameState) {
+		targets = game.BoardWords(gameState)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"id":          gameState.ID,
+		"seed":        strconv.FormatUint(gameState.Seed, 10),
+		"targets":     targets,
+		"guesses":     gameState.GuessHistory,
+		"game_over":   gameState.GameOver,
+		"won":         gameState.Won,
+		"evaluations": evaluations,
+		"consistent":  !slices.ContainsFunc(evaluations, func(e game.Evaluation) bool { return !e.Matches }),
 	})
 }
 
-func ValidateGameState(app *models.App, _ *gin.Context, game *models.GameState) error {
-	if game.GameOver {
+// DebugScoreHandler explains how ?guess= scores against ?target=: which
+// target letter each guess letter used up, and why the others found none.
+func DebugScoreHandler(app *models.App, c *gin.Context) {
+	guess, target := NormalizeGuess(c.Query("guess")), NormalizeGuess(c.Query("target"))
+	for _, arg := range []struct{ param, word string }{{"guess", guess}, {"target", target}} {
+		if err := game.CheckLength(arg.word); err != nil {
+			RespondGameError(c, game.AsGameError(err).WithDetail("param", arg.param))
+			return
+		}
+	}
+	c.JSON(http.StatusOK, game.TraceGuess(guess, target))
+}
+
+// ValidateWordHandler tells the client whether ?word= is, or can still grow
+// into, an accepted guess, so it can warn before a guess is submitted.
+func ValidateWordHandler(app *models.App, c *gin.Context) {
+	if !app.ValidateAPI {
+		RespondGameError(c, game.NewGameError(constants.ErrorCodeValidateDisabled))
+		return
+	}
+	c.JSON(http.StatusOK, game.CheckWord(app, NormalizeGuess(c.Query("word"))))
+}
+
+// AdminWordStatsHandler reports solve rates per target word, hardest first
+// unless ?sort= asks otherwise. ?min_plays= hides rarely played words and
+// ?limit= caps the list.
+func AdminWordStatsHandler(app *models.App, c *gin.Context) {
+	minPlays, _ := strconv.Atoi(c.DefaultQuery("min_plays", "1"))
+	stats := app.Analytics.WordStats(c.Query("sort"), minPlays)
+	if limit, err := strconv.Atoi(c.Query("limit")); err == nil && limit >= 0 && limit < len(stats) {
+		stats = stats[:limit]
+	}
+	c.JSON(http.StatusOK, gin.H{"words": stats})
+}
+
+func ValidateGameState(app *models.App, c *gin.Context, gameState *models.GameState) error {
+	if gameState.GameOver {
 		util.LogWarn("Session attempted guess on completed game")
-		return errors.New(constants.ErrorCodeGameOver)
+		return game.NewGameError(constants.ErrorCodeGameOver)
+	}
+	// The browser sends the row and version of the board it played on;
+	// clients that send neither are not checked.
+	for field, want := range map[string]int{"row": gameState.CurrentRow, "version": gameState.Version} {
+		claimed := c.PostForm(field)
+		if claimed == "" {
+			continue
+		}
+		if n, err := strconv.Atoi(claimed); err != nil || n != want {
+			util.LogWarn("Guess sent from a stale board: %s %q, server has %d", field, claimed, want)
+			return game.NewGameError(constants.ErrorCodeOutOfSync).
+				WithDetail("row", gameState.CurrentRow).
+				WithDetail("version", gameState.Version)
+		}
 	}
 	return nil
 }
 
 func NormalizeGuess(input string) string {
-	return strings.ToUpper(strings.TrimSpace(input))
+	return game.NormalizeWord(input)
 }
 
-func ProcessGuess(app *models.App, ctx context.Context, c *gin.Context, sessionID string, gameState *models.GameState, guess string, isHTMX bool, hint string) error {
-	util.LogInfo("Session %s guessed: %s (attempt %d/%d)", sessionID, guess, gameState.CurrentRow+1, constants.MaxGuesses)
+func ProcessGuess(app *models.App, ctx context.Context, c *gin.Context, sessionID string, gameState *models.GameState, guess game.Guess) error {
+	util.LogInfo("Session %s guessed: %s (attempt %d/%d)", sessionID, guess.Word, gameState.CurrentRow+1, game.MaxRows(gameState))
 
-	if len(guess) != constants.WordLength {
-		util.LogWarn("Session %s submitted invalid length guess: %s (%d letters)", sessionID, guess, len(guess))
-		return errors.New(constants.ErrorCodeInvalidLength)
+	if err := guess.CheckLength(); err != nil {
+		util.LogWarn("Session %s submitted invalid length guess: %s (%d letters)", sessionID, guess.Word, guess.Len)
+		return err
 	}
 
-	if gameState.CurrentRow >= constants.MaxGuesses {
+	if gameState.CurrentRow >= game.MaxRows(gameState) {
 		util.LogWarn("Session %s attempted guess after max guesses reached", sessionID)
-		return errors.New(constants.ErrorCodeNoMoreGuesses)
+		return game.NewGameError(constants.ErrorCodeNoMoreGuesses)
 	}
 
-	targetWord := game.GetTargetWord(app, ctx, gameState)
-	isInvalid := !game.IsValidWord(app, guess)
-	result := game.CheckGuess(guess, targetWord, app)
-	game.UpdateGameState(app, ctx, gameState, guess, targetWord, result, isInvalid)
+	settings := session.GetSettings(app, sessionID)
+	if len(gameState.GuessHistory) == 0 {
+		logEvent(app, eventlog.GameStarted, sessionID, gameState.ID, gameState)
+		if !settings.DoNotTrack {
+			app.Telemetry.GameStarted(game.Mode(gameState), settings.Language)
+		}
+	}
+	game.ApplyParsedGuess(app, ctx, gameState, guess)
+	gameState.GuessTimes = append(gameState.GuessTimes, time.Now())
+	if points := game.AwardPoints(app, gameState, settings.HardMode); points > 0 {
+		util.LogInfo("Session %s scored %d points, run total %d", sessionID, points, gameState.Score)
+	}
+	if settings.AutoContinue {
+		game.ArmAutoContinue(app, gameState, time.Now())
+	}
 	session.SaveGameState(app, sessionID, gameState)
-
-	if isHTMX {
-		c.HTML(http.StatusOK, "game-content", gin.H{"game": gameState, "hint": hint})
-	} else {
-		c.HTML(http.StatusOK, "index.html", gin.H{
-			"title":   "Vortludo - A Libre Wordle Clone",
-			"message": "Guess the 5-letter word!",
-			"hint":    hint,
-			"game":    gameState,
-		})
+	logEvent(app, eventlog.GuessMade, sessionID, gameState.ID, models.GuessEvent{Guess: guess.Word, Row: len(gameState.GuessHistory) - 1, Untracked: settings.DoNotTrack})
+	if gameState.GameOver {
+		recordBotSignals(app, sessionID, gameState)
+		eventType := eventlog.GameLost
+		if gameState.Won {
+			eventType = eventlog.GameWon
+		}
+		logEvent(app, eventType, sessionID, gameState.ID, gameResult(app, sessionID, gameState))
+		if !settings.DoNotTrack {
+			app.Telemetry.GameFinished(gameState.Won)
+		}
+		// Tokens that leaked during the game, e.g. into a shared page,
+		// stop working once it is over.
+		session.RotateCSRF(app, c)
 	}
+	session.RecordHeatmap(app, sessionID, game.GuessTiles(gameState, len(gameState.GuessHistory)-1))
+	recordGuessAnalytics(app, sessionID, gameState)
+	statsBefore := playerStats(app, c, sessionID)
+	recordPlayerStats(app, c, sessionID, gameState)
+	session.Persist(ctx, app, sessionID, gameState)
+	game.RecordTournamentResult(app, sessionID, gameState)
+	moveResponse(app, c, sessionID, gameState, len(gameState.GuessHistory)-1, &statsBefore).Send(c)
 	return nil
 }
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"bytes"
+	"cmp"
+	"net/http"
+	"time"
+
+	constants "github.com/CodeAndHammer/vortludo/internal/constants"
+	models "github.com/CodeAndHammer/vortludo/internal/models"
+	session "github.com/CodeAndHammer/vortludo/internal/session"
+	util "github.com/CodeAndHammer/vortludo/internal/util"
+	"github.com/gin-gonic/gin"
+)
+
+// recordingWriter keeps a copy of the response body as it is written.
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// idempotent answers a request that repeats the session's last idempotency
+// key with the original response and reports true. Otherwise it starts
+// recording the response; the returned function, deferred by the caller
+// while it still holds the session lock, keeps it for later repeats.
+// Requests without a key are not recorded.
+func idempotent(app *models.App, c *gin.Context, sessionID string) (bool, func()) {
+	key := cmp.Or(c.GetHeader(constants.IdempotencyKeyHeader), c.PostForm(constants.IdempotencyKeyField))
+	if key == "" || len(key) > constants.IdempotencyKeyMaxLen {
+		return false, func() {}
+	}
+	if replay, ok := session.GetReplay(app, sessionID, key); ok {
+		util.LogInfo("Session %s repeated request %s, replaying the response", sessionID, key)
+		header := c.Writer.Header()
+		for name, values := range replay.Header {
+			if name != "X-Request-Id" {
+				header[name] = values
+			}
+		}
+		header.Set("Idempotent-Replayed", "true")
+		c.Data(replay.Status, header.Get("Content-Type"), replay.Body)
+		return true, func() {}
+	}
+
+	recorder := &recordingWriter{ResponseWriter: c.Writer}
+	c.Writer = recorder
+	return false, func() {
+		if recorder.Status() >= http.StatusInternalServerError {
+			return
+		}
+		// The body is recorded before compression, so the headers that
+		// describe the compressed form are left out.
+		header := recorder.Header().Clone()
+		for _, name := range []string{"Content-Encoding", "Content-Length", "Vary"} {
+			header.Del(name)
+		}
+		session.SaveReplay(app, sessionID, &models.Replay{
+			Key:     key,
+			Status:  recorder.Status(),
+			Header:  header,
+			Body:    recorder.body.Bytes(),
+			Created: time.Now(),
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"net/http"
+
+	auth "github.com/CodeAndHammer/vortludo/internal/auth"
+	constants "github.com/CodeAndHammer/vortludo/internal/constants"
+	game "github.com/CodeAndHammer/vortludo/internal/game"
+	models "github.com/CodeAndHammer/vortludo/internal/models"
+	session "github.com/CodeAndHammer/vortludo/internal/session"
+	util "github.com/CodeAndHammer/vortludo/internal/util"
+	"github.com/gin-gonic/gin"
+)
+
+// OIDCLoginHandler sends the player to the identity provider. The state,
+// nonce and PKCE verifier of the sign-in are kept in a short-lived cookie.
+func OIDCLoginHandler(app *models.App, c *gin.Context) {
+	sessionID := session.GetOrCreateSession(app, c)
+	if !consentGiven(app, c, sessionID) {
+		return
+	}
+	authURL, login, err := app.OIDC.Start(c.Request.Context())
+	if err != nil {
+		util.LogWarn("Failed to start sign-in with %s: %v", app.OIDC.Name(), err)
+		renderOIDCError(app, c, sessionID)
+		return
+	}
+	// Lax, unlike the other cookies, because the provider's redirect back to
+	// the callback is a cross-site navigation.
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(constants.OIDCLoginCookieName, login.Encode(), int(auth.OIDCLoginTTL.Seconds()), app.Path(constants.RouteAccountOIDC), "", app.IsProduction, true)
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// OIDCCallbackHandler receives the player back from the identity provider.
+// The session and account cookies are SameSite=Strict and so are not sent
+// along with the provider's cross-site redirect; the callback therefore only
+// forwards the response to OIDCCompleteHandler from a same-site page.
+func OIDCCallbackHandler(app *models.App, c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.HTML(http.StatusOK, "redirect.html", gin.H{
+		"title": "Vortludo - Signing in",
+		"url":   app.Path(constants.RouteAccountOIDCComplete) + "?" + c.Request.URL.RawQuery,
+	})
+}
+
+// OIDCCompleteHandler verifies the provider's response and signs the session
+// in to the account linked to the identity. A player who is already signed
+// in links the identity to their account; otherwise an identity seen for the
+// first time gets a new account.
+func OIDCCompleteHandler(app *models.App, c *gin.Context) {
+	sessionID := session.GetOrCreateSession(app, c)
+	unlock := session.Lock(app, sessionID)
+	defer unlock()
+
+	value, _ := c.Cookie(constants.OIDCLoginCookieName)
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(constants.OIDCLoginCookieName, "", -1, app.Path(constants.RouteAccountOIDC), "", app.IsProduction, true)
+
+	if reason := c.Query("error"); reason != "" {
+		util.LogWarn("%s refused sign-in: %s", app.OIDC.Name(), reason)
+		renderOIDCError(app, c, sessionID)
+		return
+	}
+	login, ok := auth.DecodeOIDCLogin(value)
+	if !ok {
+		renderOIDCError(app, c, sessionID)
+		return
+	}
+	identity, err := app.OIDC.Finish(c.Request.Context(), login, c.Query("state"), c.Query("code"))
+	if err != nil {
+		util.LogWarn("Failed sign-in with %s from %s: %v", app.OIDC.Name(), c.ClientIP(), err)
+		renderOIDCError(app, c, sessionID)
+		return
+	}
+	current, _ := currentUser(app, c)
+	user, err := app.Accounts.UserForIdentity(identity, current.ID)
+	if err != nil {
+		renderAccountError(app, c, sessionID, err)
+		return
+	}
+	if user.ID != current.ID {
+		signIn(app, c, sessionID, user)
+	}
+	redirectToAccount(app, c, sessionID)
+}
+
+// OIDCLogoutHandler signs out locally and then at the identity provider, if
+// it supports RP-initiated logout.
+func OIDCLogoutHandler(app *models.App, c *gin.Context) {
+	endLogin(app, c)
+	logoutURL := ""
+	if app.PublicURL != "" {
+		logoutURL = app.OIDC.LogoutURL(c.Request.Context(), app.PublicURL+app.Path(constants.RouteAccount))
+	}
+	if logoutURL == "" {
+		c.Redirect(http.StatusSeeOther, app.Path(constants.RouteAccount))
+		return
+	}
+	// The CSP's form-action blocks a form post from redirecting off-site, so
+	// the provider is reached from a page instead.
+	c.Header("Cache-Control", "no-store")
+	c.HTML(http.StatusOK, "redirect.html", gin.H{
+		"title":   "Vortludo - Signing out",
+		"message": "Signing you out",
+		"url":     logoutURL,
+	})
+}
+
+func renderOIDCError(app *models.App, c *gin.Context, sessionID string) {
+	gameErr := game.NewGameError(constants.ErrorCodeOIDCFailed)
+	renderAccount(app, c, sessionID, gameErr.Status, gin.H{"error": gameErr})
+}
+
+// oidcLinked reports whether user signs in through the configured provider.
+func oidcLinked(app *models.App, user auth.User) bool {
+	return app.OIDC != nil && len(user.Identities) > 0
+}
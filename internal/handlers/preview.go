@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	constants "github.com/CodeAndHammer/vortludo/internal/constants"
+	game "github.com/CodeAndHammer/vortludo/internal/game"
+	models "github.com/CodeAndHammer/vortludo/internal/models"
+	shareimage "github.com/CodeAndHammer/vortludo/internal/shareimage"
+	tournament "github.com/CodeAndHammer/vortludo/internal/tournament"
+	util "github.com/CodeAndHammer/vortludo/internal/util"
+	"github.com/gin-gonic/gin"
+)
+
+// Preview is the Open Graph card of a page, which chat apps and social
+// sites unfurl shared links into. It never gives a word away.
+type Preview struct {
+	Title       string
+	Description string
+	URL         string
+	Image       string
+}
+
+// homePreview describes the home page, or today's puzzle for a daily
+// tournament game.
+func homePreview(app *models.App, c *gin.Context, gameState *models.GameState) *Preview {
+	if gameState.Puzzle > 0 {
+		return dailyPreview(app, c, gameState.Puzzle)
+	}
+	return &Preview{
+		Title:       "Vortludo",
+		Description: "A libre Wordle clone: guess the hidden 5-letter word in 6 tries.",
+		URL:         baseURL(app, c) + app.Path(constants.RouteHome),
+		Image:       previewImageURL(app, c, ""),
+	}
+}
+
+// dailyPreview describes daily puzzle number puzzle.
+func dailyPreview(app *models.App, c *gin.Context, puzzle int) *Preview {
+	return &Preview{
+		Title:       "Vortludo #" + strconv.Itoa(puzzle),
+		Description: "Today's puzzle: guess the hidden 5-letter word in 6 tries, and see how you rank in this week's tournament.",
+		URL:         baseURL(app, c) + c.Request.URL.RequestURI(),
+		Image:       previewImageURL(app, c, ""),
+	}
+}
+
+// watchPreview describes a spectated game by its progress alone.
+func watchPreview(app *models.App, c *gin.Context, token string, view spectatorView, open bool) *Preview {
+	guesses := strconv.Itoa(view.Guesses) + " guesses"
+	if view.Guesses == 1 {
+		guesses = "1 guess"
+	}
+	description := "This game can no longer be watched."
+	switch {
+	case open && view.Won:
+		description = "Solved in " + guesses + "."
+	case open && view.GameOver:
+		description = "Not solved this time."
+	case open:
+		description = "Watch a game of Vortludo live: " + guesses + " so far."
+	}
+	return &Preview{
+		Title:       "Watching a game of Vortludo",
+		Description: description,
+		URL:         baseURL(app, c) + app.Path(constants.RouteWatch) + "/" + url.PathEscape(token),
+		Image:       previewImageURL(app, c, token),
+	}
+}
+
+func previewImageURL(app *models.App, c *gin.Context, token string) string {
+	u := baseURL(app, c) + app.Path(constants.RoutePreviewImage)
+	if token != "" {
+		u += "?" + url.Values{constants.PreviewWatchParam: {token}}.Encode()
+	}
+	return u
+}
+
+// PreviewImageHandler draws the image of a preview card: the colours of a
+// spectated game's board, or an empty board under today's puzzle number.
+func PreviewImageHandler(app *models.App, c *gin.Context) {
+	now := time.Now()
+	title := "Vortludo #" + strconv.Itoa(tournament.PuzzleNumber(now))
+	board := make(shareimage.Board, constants.MaxGuesses)
+	for i := range board {
+		board[i] = make([]shareimage.Tile, constants.WordLength)
+	}
+	boards := []shareimage.Board{board}
+
+	if token := c.Query(constants.PreviewWatchParam); token != "" {
+		view, ok := watchGame(app, token)
+		if !ok {
+			ErrorPage(app, c, game.NewGameError(constants.ErrorCodeNotFound))
+			return
+		}
+		title = "Watching Vortludo"
+		boards = make([]shareimage.Board, len(view.Boards))
+		for i, b := range view.Boards {
+			for _, row := range b.Rows {
+				if len(row.Tiles) == 0 || row.Tiles[0].Status == "" {
+					break
+				}
+				tiles := make([]shareimage.Tile, len(row.Tiles))
+				for j, tile := range row.Tiles {
+					tiles[j].Status = tile.Status
+				}
+				boards[i] = append(boards[i], tiles)
+			}
+		}
+	}
+
+	data, etag, err := app.ShareImages.Image(title, boards, shareimage.Options{SpoilerFree: true})
+	if err != nil {
+		util.LogWarn("Failed to draw preview image: %v", err)
+		ErrorPage(app, c, game.NewGameError(constants.ErrorCodeInternal))
+		return
+	}
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(constants.PreviewImageMaxAge.Seconds())))
+	c.Header("ETag", `"`+etag+`"`)
+	http.ServeContent(c.Writer, c.Request, "preview.png", time.Time{}, bytes.NewReader(data))
+}
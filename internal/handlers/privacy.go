@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	constants "github.com/CodeAndHammer/vortludo/internal/constants"
+	eventlog "github.com/CodeAndHammer/vortludo/internal/eventlog"
+	game "github.com/CodeAndHammer/vortludo/internal/game"
+	models "github.com/CodeAndHammer/vortludo/internal/models"
+	session "github.com/CodeAndHammer/vortludo/internal/session"
+	util "github.com/CodeAndHammer/vortludo/internal/util"
+	"github.com/gin-gonic/gin"
+)
+
+// PrivacyExportHandler answers with everything kept for the requesting
+// session, and for the account it is signed in to, as a JSON download. The
+// game in progress is given as its status, so the export does not give its
+// word away.
+func PrivacyExportHandler(app *models.App, c *gin.Context) {
+	ctx := c.Request.Context()
+	sessionID := session.GetOrCreateSession(app, c)
+	unlock := session.Lock(app, sessionID)
+	defer unlock()
+
+	export := gin.H{
+		"session_id": sessionID,
+		"settings":   session.GetSettings(app, sessionID),
+		"stats":      session.GetStats(app, sessionID),
+		"heatmap":    session.GetHeatmap(app, sessionID),
+	}
+	if consent, answered := session.Consent(app, sessionID); answered {
+		export["consent"] = consent
+	}
+	if gameState, ok := app.Sessions.Game(sessionID); ok {
+		export["game"] = gameStatus(gameState)
+	}
+	if app.Store != nil {
+		words, err := app.Store.CompletedWords(ctx, sessionID)
+		if err != nil {
+			util.LogWarn("Failed to read the completed words of session %s: %v", sessionID, err)
+			RespondGameError(c, game.NewGameError(constants.ErrorCodeInternal))
+			return
+		}
+		export["completed_words"] = words
+	}
+	if player, ok := currentPlayer(app, c); ok {
+		if week, results := app.Tournament.Results(player); results != nil {
+			export["tournament"] = gin.H{"week": week, "days": results}
+		}
+	}
+	if user, ok := currentUser(app, c); ok {
+		user.PasswordHash = ""
+		export["account"] = user
+	}
+	c.Header("Content-Disposition", `attachment; filename="vortludo-data.json"`)
+	c.JSON(http.StatusOK, export)
+}
+
+// PrivacyDeleteHandler erases the requesting session: its game, settings,
+// stats, heatmap and completed words, in memory and in the store, its
+// activity in analytics, its results in the running tournament, and the
+// account it is signed in to. The cookies naming them are cleared, so the
+// next request starts afresh. The event log keeps what it recorded until
+// rotated out, but games it holds for the session are no longer recovered.
+func PrivacyDeleteHandler(app *models.App, c *gin.Context) {
+	sessionID := session.GetOrCreateSession(app, c)
+	unlock := session.Lock(app, sessionID)
+	defer unlock()
+
+	if err := session.Erase(c.Request.Context(), app, sessionID); err != nil {
+		util.LogWarn("Failed to erase session %s: %v", sessionID, err)
+		RespondGameError(c, game.NewGameError(constants.ErrorCodeInternal))
+		return
+	}
+	logEvent(app, eventlog.SessionErased, sessionID, "", nil)
+	erased := gin.H{"session": true, "account": false, "tournament": false}
+	if player, ok := currentPlayer(app, c); ok {
+		erased["tournament"] = app.Tournament.Forget(player)
+	}
+	if user, ok := currentUser(app, c); ok {
+		erased["account"] = app.Accounts.Delete(user.ID)
+		util.LogInfo("Deleted account %s at its owner's request", user.Username)
+	}
+	endLogin(app, c)
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(constants.SessionCookieName, "", -1, app.CookiePath(), "", app.IsProduction, true)
+	c.SetCookie(constants.PlayerCookieName, "", -1, app.CookiePath(), "", app.IsProduction, true)
+	util.LogInfo("Erased session %s at its owner's request", sessionID)
+
+	Response{Redirect: app.Path(constants.RouteHome), JSON: gin.H{"erased": erased}}.Send(c)
+}
+
+// ConsentHandler records whether the player, with minimal cookies, lets
+// their stats and preferences be kept beyond the browser session. Consent
+// makes the session cookie last and stores the session; withdrawing it
+// drops the stored session and signs the account out. Either way the game
+// goes on.
+func ConsentHandler(app *models.App, c *gin.Context) {
+	sessionID := session.GetOrCreateSession(app, c)
+	unlock := session.Lock(app, sessionID)
+	defer unlock()
+
+	consent, err := strconv.ParseBool(c.PostForm("consent"))
+	if err != nil {
+		RespondGameError(c, game.NewGameError(constants.ErrorCodeInvalidConsent))
+		return
+	}
+	if err := session.SetConsent(c.Request.Context(), app, c, sessionID, consent); err != nil {
+		util.LogWarn("Failed to apply the consent of session %s to the store: %v", sessionID, err)
+		RespondGameError(c, game.NewGameError(constants.ErrorCodeInternal))
+		return
+	}
+	if !consent {
+		endLogin(app, c)
+	}
+	Response{Redirect: app.Path(constants.RouteHome), JSON: gin.H{"consent": consent}}.Send(c)
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+
+	constants "github.com/CodeAndHammer/vortludo/internal/constants"
+	game "github.com/CodeAndHammer/vortludo/internal/game"
+	"github.com/gin-gonic/gin"
+)
+
+// Format is the kind of response a client asked for.
+type Format int
+
+const (
+	// FormatPage is a full HTML page, for plain browser requests.
+	FormatPage Format = iota
+	// FormatFragment is a partial for htmx to swap in.
+	FormatFragment
+	// FormatJSON is for API clients.
+	FormatJSON
+)
+
+// Negotiate returns the format to answer c in: JSON when the client prefers
+// it to HTML, a fragment for htmx requests and a full page otherwise.
+func Negotiate(c *gin.Context) Format {
+	switch {
+	case WantsJSON(c):
+		return FormatJSON
+	case c.GetHeader("HX-Request") == "true":
+		return FormatFragment
+	default:
+		return FormatPage
+	}
+}
+
+// WantsJSON reports whether the client asked for a JSON response.
+func WantsJSON(c *gin.Context) bool {
+	return c.NegotiateFormat(gin.MIMEHTML, gin.MIMEJSON) == gin.MIMEJSON
+}
+
+// Response is what a route answers with, in each format it supports. A route
+// without a Page redirects browsers to Redirect; one without a Fragment
+// gives htmx the page; one without JSON gives JSON clients HTML.
+//
+// Template data that is a View is stamped with the request's CSRF token,
+// script nonce and theme; a gin.H gets them unless it sets them itself.
+type Response struct {
+	// Status defaults to 200 OK.
+	Status   int
+	Page     string
+	Fragment string
+	Redirect string
+	Data     any
+	JSON     any
+}
+
+// Send writes r in the format the client asked for.
+func (r Response) Send(c *gin.Context) {
+	status := r.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	format := Negotiate(c)
+	if format == FormatJSON && r.JSON == nil {
+		format = FormatFragment
+		if r.Fragment == "" {
+			format = FormatPage
+		}
+	}
+	if format == FormatFragment && r.Fragment == "" {
+		format = FormatPage
+	}
+
+	switch {
+	case format == FormatJSON:
+		c.JSON(status, r.JSON)
+	case format == FormatFragment:
+		c.HTML(status, r.Fragment, r.templateData(c, false))
+	case r.Page != "":
+		c.HTML(status, r.Page, r.templateData(c, true))
+	default:
+		c.Redirect(http.StatusSeeOther, r.Redirect)
+	}
+}
+
+// With returns r with key set in its template data, if that is a gin.H.
+func (r Response) With(key string, value any) Response {
+	if data, ok := r.Data.(gin.H); ok {
+		data[key] = value
+	}
+	return r
+}
+
+// Fail answers with gameErr: as the JSON error envelope to JSON clients, and
+// otherwise as r with the error set in the HX-Trigger header and in the
+// template's error_code. Browsers redirected to a page are sent its code in
+// the ErrorParam query parameter, since they lose the header on the way.
+func (r Response) Fail(c *gin.Context, gameErr *game.GameError) {
+	format := Negotiate(c)
+	if format == FormatJSON {
+		RespondGameError(c, gameErr)
+		return
+	}
+	if r.Page == "" && (format == FormatPage || r.Fragment == "") {
+		r.Redirect = withErrorCode(r.Redirect, gameErr.Code)
+	}
+	setErrorTrigger(c, gameErr)
+	if view, ok := r.Data.(View); ok {
+		view.fail(gameErr.Code)
+	}
+	r.With("error_code", gameErr.Code).Send(c)
+}
+
+// withErrorCode adds code to the query of redirect.
+func withErrorCode(redirect, code string) string {
+	u, err := url.Parse(redirect)
+	if err != nil {
+		return redirect
+	}
+	query := u.Query()
+	query.Set(constants.ErrorParam, code)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+func (r Response) templateData(c *gin.Context, page bool) any {
+	if view, ok := r.Data.(View); ok {
+		view.stamp(c, page)
+		return view
+	}
+	data, ok := r.Data.(gin.H)
+	if !ok {
+		return r.Data
+	}
+	if _, ok := data["csrf_token"]; !ok {
+		data["csrf_token"] = csrfToken(c)
+	}
+	if _, ok := data["csp_nonce"]; !ok && page {
+		data["csp_nonce"] = cspNonce(c)
+	}
+	if _, ok := data["theme"]; !ok && page {
+		data["theme"] = theme(c)
+	}
+	return data
+}
+
+// RespondGameError writes the JSON error envelope used by every API endpoint.
+func RespondGameError(c *gin.Context, gameErr *game.GameError) {
+	c.AbortWithStatusJSON(gameErr.Status, gin.H{"error": gameErr})
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	audit "github.com/CodeAndHammer/vortludo/internal/audit"
+	constants "github.com/CodeAndHammer/vortludo/internal/constants"
+	game "github.com/CodeAndHammer/vortludo/internal/game"
+	models "github.com/CodeAndHammer/vortludo/internal/models"
+	schedule "github.com/CodeAndHammer/vortludo/internal/schedule"
+	"github.com/gin-gonic/gin"
+)
+
+// AdminScheduleHandler lists the daily puzzles from today, ?days= of them,
+// with the days whose words conflict counted.
+func AdminScheduleHandler(app *models.App, c *gin.Context) {
+	days := constants.ScheduleCalendarDefault
+	if n, err := strconv.Atoi(c.Query("days")); err == nil && n > 0 {
+		days = min(n, constants.ScheduleCalendarMax)
+	}
+	calendar := game.Calendar(app, time.Now(), days)
+	conflicts := 0
+	for _, day := range calendar {
+		if len(day.Conflicts) > 0 {
+			conflicts++
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"calendar": calendar, "conflicts": conflicts})
+}
+
+// AdminScheduleWordHandler schedules the posted word for the daily puzzle of
+// the posted date.
+func AdminScheduleWordHandler(app *models.App, c *gin.Context) {
+	entry, replaced, err := game.ScheduleWord(app, schedule.Entry{
+		Date: c.PostForm("date"),
+		Word: c.PostForm("word"),
+		Note: c.PostForm("note"),
+	}, time.Now())
+	if err != nil {
+		scheduleFailed(c, err)
+		return
+	}
+	recordAdminAction(app, c, audit.ScheduleWord, replaced, entry)
+	c.JSON(http.StatusOK, gin.H{"scheduled": entry, "replaced": replaced})
+}
+
+// AdminUnscheduleWordHandler hands the daily puzzle of :date back to the
+// deterministic selection.
+func AdminUnscheduleWordHandler(app *models.App, c *gin.Context) {
+	entry, err := game.UnscheduleWord(app, c.Param("date"), time.Now())
+	if err != nil {
+		scheduleFailed(c, err)
+		return
+	}
+	recordAdminAction(app, c, audit.UnscheduleWord, entry, nil)
+	c.JSON(http.StatusOK, gin.H{"removed": entry})
+}
+
+func scheduleFailed(c *gin.Context, err error) {
+	status := http.StatusUnprocessableEntity
+	switch {
+	case errors.Is(err, game.ErrWordScheduled):
+		status = http.StatusConflict
+	case errors.Is(err, game.ErrNotScheduled):
+		status = http.StatusNotFound
+	}
+	c.JSON(status, gin.H{"error": err.Error()})
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"time"
+
+	constants "github.com/CodeAndHammer/vortludo/internal/constants"
+	game "github.com/CodeAndHammer/vortludo/internal/game"
+	models "github.com/CodeAndHammer/vortludo/internal/models"
+	session "github.com/CodeAndHammer/vortludo/internal/session"
+	shareimage "github.com/CodeAndHammer/vortludo/internal/shareimage"
+	util "github.com/CodeAndHammer/vortludo/internal/util"
+	"github.com/gin-gonic/gin"
+)
+
+// ShareImageHandler draws the session's finished game as a PNG, with only
+// the colours of its tiles when constants.ShareSpoilerFreeParam is set. The
+// image is revalidated by its ETag, as the next game is shared at the same
+// address.
+func ShareImageHandler(app *models.App, c *gin.Context) {
+	sessionID := session.GetOrCreateSession(app, c)
+	gameState, ok := session.Snapshot(app, sessionID)
+	if !ok || !gameState.GameOver {
+		ErrorPage(app, c, game.NewGameError(constants.ErrorCodeGameInProgress))
+		return
+	}
+	spoilerFree, _ := strconv.ParseBool(c.Query(constants.ShareSpoilerFreeParam))
+	opts := shareimage.Options{
+		SpoilerFree: spoilerFree,
+		ColorBlind:  session.GetSettings(app, sessionID).ColorBlind,
+	}
+	boards := shareBoards(&gameState)
+	data, etag, err := app.ShareImages.Image(shareTitle(&gameState, boards), boards, opts)
+	if err != nil {
+		util.LogWarn("Failed to draw share image of game %s: %v", gameState.ID, err)
+		ErrorPage(app, c, game.NewGameError(constants.ErrorCodeInternal))
+		return
+	}
+	c.Header("Cache-Control", "private, no-cache")
+	c.Header("ETag", `"`+etag+`"`)
+	http.ServeContent(c.Writer, c.Request, "vortludo.png", time.Time{}, bytes.NewReader(data))
+}
+
+// shareTitle is the game's label and score, as in the shared emoji grid:
+// the guesses taken to win, or X.
+func shareTitle(gameState *models.GameState, boards []shareimage.Board) string {
+	score := "X"
+	if gameState.Won {
+		guesses := 0
+		for _, board := range boards {
+			guesses = max(guesses, len(board))
+		}
+		score = strconv.Itoa(guesses)
+	}
+	return gameState.Label() + " " + score + "/" + strconv.Itoa(game.MaxRows(gameState))
+}
+
+// shareBoards returns the played rows of each of the game's boards.
+func shareBoards(gameState *models.GameState) []shareimage.Board {
+	if !game.IsMultiBoard(gameState) {
+		return []shareimage.Board{playedRows(gameState.Guesses)}
+	}
+	boards := make([]shareimage.Board, len(gameState.Boards))
+	for i, board := range gameState.Boards {
+		boards[i] = playedRows(board.Guesses)
+	}
+	return boards
+}
+
+// playedRows returns the rows of guesses up to the first unplayed one.
+func playedRows(rows models.Rows) shareimage.Board {
+	var board shareimage.Board
+	for i := range rows.Len() {
+		if rows.Words[i] == "" {
+			break
+		}
+		row := make([]shareimage.Tile, 0, constants.WordLength)
+		for _, tile := range rows.Row(i) {
+			row = append(row, shareimage.Tile{Letter: tile.Letter, Status: tile.Status})
+		}
+		board = append(board, row)
+	}
+	return board
+}
@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	constants "github.com/CodeAndHammer/vortludo/internal/constants"
+	game "github.com/CodeAndHammer/vortludo/internal/game"
+	models "github.com/CodeAndHammer/vortludo/internal/models"
+	session "github.com/CodeAndHammer/vortludo/internal/session"
+	util "github.com/CodeAndHammer/vortludo/internal/util"
+	"github.com/gin-gonic/gin"
+)
+
+// spectatorView is what spectators are shown of a game.
+type spectatorView struct {
+	Boards   []models.BoardView
+	Guesses  int
+	GameOver bool
+	Won      bool
+	Version  int
+}
+
+// SpectateHandler creates a read-only spectate link for the session's current
+// game, or returns the existing one.
+func SpectateHandler(app *models.App, c *gin.Context) {
+	ctx := c.Request.Context()
+	sessionID := session.GetOrCreateSession(app, c)
+	unlock := session.Lock(app, sessionID)
+	defer unlock()
+	gameState := session.GetGameState(app, ctx, sessionID)
+	if gameState.GameOver {
+		RespondGameError(c, game.NewGameError(constants.ErrorCodeGameOver))
+		return
+	}
+	token := game.StartSpectating(app, sessionID, gameState)
+	util.LogInfo("Session %s opened game %s to spectators", sessionID, gameState.ID)
+	Response{
+		Fragment: "spectate-link",
+		Redirect: app.Path(constants.RouteHome),
+		Data:     presentGameContent(app, c, sessionID, gameState, -1, nil),
+		JSON:     gin.H{"url": app.PublicURL + app.Path(constants.RouteWatch) + "/" + token},
+	}.Send(c)
+}
+
+// SpectateRevokeHandler closes the current game's spectate link.
+func SpectateRevokeHandler(app *models.App, c *gin.Context) {
+	ctx := c.Request.Context()
+	sessionID := session.GetOrCreateSession(app, c)
+	unlock := session.Lock(app, sessionID)
+	defer unlock()
+	gameState := session.GetGameState(app, ctx, sessionID)
+	game.StopSpectating(app, sessionID, gameState)
+	if Negotiate(c) == FormatJSON {
+		c.Status(http.StatusNoContent)
+		return
+	}
+	Response{Fragment: "spectate-link", Redirect: app.Path(constants.RouteHome), Data: presentGameContent(app, c, sessionID, gameState, -1, nil)}.Send(c)
+}
+
+// WatchHandler shows a spectated game. The page keeps itself up to date
+// through WatchEventsHandler.
+func WatchHandler(app *models.App, c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	token := c.Param("token")
+	view, ok := watchGame(app, token)
+	status := http.StatusOK
+	if !ok {
+		status = http.StatusGone
+	}
+	Response{
+		Status: status,
+		Page:   "spectate.html",
+		Data: gin.H{
+			"title":   "Vortludo - Watching a game",
+			"token":   token,
+			"view":    view,
+			"open":    ok,
+			"preview": watchPreview(app, c, token, view, ok),
+		},
+	}.Send(c)
+}
+
+// WatchBoardHandler renders the spectated board, for polling clients and
+// for the page to fetch when it is told the game changed.
+func WatchBoardHandler(app *models.App, c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	view, ok := watchGame(app, c.Param("token"))
+	status := http.StatusOK
+	if !ok {
+		status = http.StatusGone
+	}
+	if Negotiate(c) == FormatJSON {
+		if !ok {
+			c.JSON(status, gin.H{"open": false})
+			return
+		}
+		c.JSON(status, gin.H{
+			"open":      true,
+			"boards":    statusRows(view.Boards),
+			"guesses":   view.Guesses,
+			"game_over": view.GameOver,
+			"won":       view.Won,
+		})
+		return
+	}
+	// htmx does not swap error responses, and the partial says the game
+	// has ended itself.
+	c.HTML(http.StatusOK, "spectate-board", gin.H{"view": view, "open": ok})
+}
+
+// WatchEventsHandler streams a server-sent "update" event whenever the
+// spectated game changes, and "closed" once the link stops working.
+func WatchEventsHandler(app *models.App, c *gin.Context) {
+	token := c.Param("token")
+	c.Header("Cache-Control", "no-store")
+	c.Header("X-Accel-Buffering", "no")
+
+	ticker := time.NewTicker(constants.SpectatePollInterval)
+	defer ticker.Stop()
+	deadline := time.After(constants.SpectateStreamMax)
+	version := -1
+	c.Stream(func(w io.Writer) bool {
+		view, ok := watchGame(app, token)
+		if !ok {
+			c.SSEvent("closed", "")
+			return false
+		}
+		if view.Version != version {
+			version = view.Version
+			c.SSEvent("update", version)
+			return true
+		}
+		select {
+		case <-ticker.C:
+			return true
+		case <-deadline:
+		case <-app.Closing:
+		case <-c.Request.Context().Done():
+		}
+		return false
+	})
+}
+
+// watchGame returns the spectator view of the game with the spectate token,
+// or false if no open game has it.
+func watchGame(app *models.App, token string) (spectatorView, bool) {
+	sessionID, ok := session.FindSpectated(app, token)
+	if !ok {
+		return spectatorView{}, false
+	}
+	unlock := session.Lock(app, sessionID)
+	defer unlock()
+	gameState, _ := app.Sessions.Game(sessionID)
+	if gameState == nil || gameState.Spectate == nil || gameState.Spectate.Token != token ||
+		!game.SpectateOpen(gameState, time.Now()) {
+		return spectatorView{}, false
+	}
+	return spectatorView{
+		Boards:   game.SpectatorBoards(gameState),
+		Guesses:  len(gameState.GuessHistory),
+		GameOver: gameState.GameOver,
+		Won:      gameState.Won,
+		Version:  game.SpectateVersion(gameState),
+	}, true
+}
+
+func statusRows(boards []models.BoardView) [][][]string {
+	out := make([][][]string, len(boards))
+	for i, board := range boards {
+		for _, row := range board.Rows {
+			statuses := make([]string, len(row.Tiles))
+			for j, tile := range row.Tiles {
+				statuses[j] = tile.Status
+			}
+			out[i] = append(out[i], statuses)
+		}
+	}
+	return out
+}
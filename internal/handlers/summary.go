@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"time"
+
+	auth "github.com/CodeAndHammer/vortludo/internal/auth"
+	constants "github.com/CodeAndHammer/vortludo/internal/constants"
+	game "github.com/CodeAndHammer/vortludo/internal/game"
+	models "github.com/CodeAndHammer/vortludo/internal/models"
+	session "github.com/CodeAndHammer/vortludo/internal/session"
+	"github.com/gin-gonic/gin"
+)
+
+// GameSummary is what the game-summary template shows once a game is over.
+type GameSummary struct {
+	Words []SummaryWord
+	Stats auth.Stats
+	// Delta is how the game just finished changed Stats. It is nil when the
+	// summary is shown again later, e.g. after a reload.
+	Delta   *StatsDelta
+	Heatmap models.HeatmapView
+	// ContinueIn is how long until the next word starts by itself, when
+	// AutoContinue is set.
+	AutoContinue bool
+	ContinueIn   time.Duration
+	// Pack is the player's progress through the game's puzzle pack.
+	Pack *game.PackProgress
+}
+
+type SummaryWord struct {
+	Word       string
+	Hint       string
+	Definition *models.Definition
+}
+
+type StatsDelta struct {
+	Played  int
+	WinRate int
+	Streak  int
+}
+
+// buildSummary returns the summary of a finished game, or nil while it is
+// still going. before, if set, is the player's stats before the game.
+func buildSummary(app *models.App, c *gin.Context, sessionID string, gameState *models.GameState, before *auth.Stats) *GameSummary {
+	if !gameState.GameOver {
+		return nil
+	}
+	targets := []string{gameState.TargetWord}
+	if game.IsMultiBoard(gameState) {
+		targets = targets[:0]
+		for _, board := range gameState.Boards {
+			targets = append(targets, board.TargetWord)
+		}
+	}
+	summary := &GameSummary{
+		Stats:   playerStats(app, c, sessionID),
+		Heatmap: game.BuildHeatmap(session.GetHeatmap(app, sessionID), session.GetSettings(app, sessionID).KeyboardLayout),
+	}
+	if !gameState.ContinueAt.IsZero() {
+		summary.AutoContinue = true
+		summary.ContinueIn = max(time.Until(gameState.ContinueAt), 0)
+	}
+	if progress, ok := game.FindPackProgress(app, gameState.Pack, summary.Stats.Packs[gameState.Pack]); ok {
+		summary.Pack = &progress
+	}
+	for _, word := range targets {
+		hint, _ := game.LookupHint(app, word)
+		entry := SummaryWord{Word: word, Hint: hint}
+		if definition, ok := game.GetDefinition(app, word); ok {
+			entry.Definition = &definition
+		}
+		summary.Words = append(summary.Words, entry)
+	}
+	if before != nil && summary.Stats.Played > before.Played {
+		summary.Delta = &StatsDelta{
+			Played:  summary.Stats.Played - before.Played,
+			WinRate: summary.Stats.WinRate() - before.WinRate(),
+			Streak:  summary.Stats.CurrentStreak - before.CurrentStreak,
+		}
+	}
+	return summary
+}
+
+// playerStats returns the stats of the signed-in account, or of the
+// anonymous session.
+func playerStats(app *models.App, c *gin.Context, sessionID string) auth.Stats {
+	if user, ok := currentUser(app, c); ok {
+		return user.Stats
+	}
+	return session.GetStats(app, sessionID)
+}
+
+// PacksHandler lists the puzzle packs with the player's progress through
+// each.
+func PacksHandler(app *models.App, c *gin.Context) {
+	sessionID := session.GetOrCreateSession(app, c)
+	progress := game.PacksProgress(app, playerStats(app, c, sessionID).Packs)
+	packs := make([]gin.H, len(progress))
+	for i, p := range progress {
+		packs[i] = gin.H{
+			"id":          p.Pack.ID,
+			"name":        p.Pack.Name,
+			"description": p.Pack.Description,
+			"solved":      p.Solved,
+			"total":       p.Total,
+			"complete":    p.Complete(),
+		}
+	}
+	c.Header("Cache-Control", "no-store")
+	Response{
+		Page: "packs.html",
+		Data: gin.H{
+			"title":    "Vortludo - Puzzle Packs",
+			"packs":    progress,
+			"settings": session.GetSettings(app, sessionID),
+		},
+		JSON: gin.H{"packs": packs},
+	}.Send(c)
+}
+
+// StatsHandler returns the player's stats and the heatmap of the letters
+// the session guessed, as JSON or as the stats-heatmap partial.
+func StatsHandler(app *models.App, c *gin.Context) {
+	sessionID := session.GetOrCreateSession(app, c)
+	heatmap := session.GetHeatmap(app, sessionID)
+	c.Header("Cache-Control", "no-store")
+	Response{
+		Fragment: "stats-heatmap",
+		Redirect: app.Path(constants.RouteHome),
+		Data:     game.BuildHeatmap(heatmap, session.GetSettings(app, sessionID).KeyboardLayout),
+		JSON: gin.H{
+			"stats":           playerStats(app, c, sessionID),
+			"guesses":         heatmap.Guesses,
+			"letters":         heatmap.Letters,
+			"position_misses": heatmap.PositionMisses,
+		},
+	}.Send(c)
+}
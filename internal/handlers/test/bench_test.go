@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	config "github.com/CodeAndHammer/vortludo/internal/config"
+	constants "github.com/CodeAndHammer/vortludo/internal/constants"
+	game "github.com/CodeAndHammer/vortludo/internal/game"
+	handlers "github.com/CodeAndHammer/vortludo/internal/handlers"
+	models "github.com/CodeAndHammer/vortludo/internal/models"
+	session "github.com/CodeAndHammer/vortludo/internal/session"
+	"github.com/gin-gonic/gin"
+)
+
+// guessRoundTripAllocBudget caps the allocations of one JSON guess through
+// the router, from parsing the form to encoding the response. Most of them
+// are gin's and encoding/json's; the budget is there to catch the guess path
+// growing new ones.
+const guessRoundTripAllocBudget = 300
+
+const benchSessionID = "bench-session"
+
+// guessRoundTrip serves GuessHandler and returns a function that posts guess
+// to it as a JSON client would, each time to a fresh game. Setting up the
+// game counts towards the allocations measured.
+func guessRoundTrip(tb testing.TB) func(guess string) *httptest.ResponseRecorder {
+	tb.Helper()
+	gin.SetMode(gin.TestMode)
+	app := models.NewApp(config.Default())
+	words := []models.WordEntry{{Word: "APPLE"}, {Word: "PAPER"}}
+	game.SetDictionary(app, words, map[string]struct{}{"APPLE": {}, "PAPER": {}})
+	router := gin.New()
+	router.POST(constants.RouteGuess, func(c *gin.Context) { handlers.GuessHandler(app, c) })
+
+	body := url.Values{"guess": {""}}
+	return func(guess string) *httptest.ResponseRecorder {
+		gameState := game.CreateNewGame(app, context.Background(), benchSessionID)
+		gameState.SessionWord = "APPLE"
+		session.SaveGameState(app, benchSessionID, gameState)
+
+		body.Set("guess", guess)
+		req := httptest.NewRequest(http.MethodPost, constants.RouteGuess, strings.NewReader(body.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Accept", "application/json")
+		req.AddCookie(&http.Cookie{Name: constants.SessionCookieName, Value: benchSessionID})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+}
+
+func TestGuessRoundTripAllocBudget(t *testing.T) {
+	post := guessRoundTrip(t)
+	if w := post("PAPER"); w.Code != http.StatusOK {
+		t.Fatalf("Expected the guess accepted, got %d %s", w.Code, w.Body)
+	}
+	allocs := testing.AllocsPerRun(50, func() { post("PAPER") })
+	if allocs > guessRoundTripAllocBudget {
+		t.Errorf("A guess round trip made %.0f allocations, budget %d", allocs, guessRoundTripAllocBudget)
+	}
+}
+
+func BenchmarkGuessRoundTrip(b *testing.B) {
+	post := guessRoundTrip(b)
+	for _, guess := range []string{"PAPER", "APPLE"} {
+		b.Run(guess, func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				post(guess)
+			}
+		})
+	}
+}
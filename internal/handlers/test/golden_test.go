@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"html/template"
+	"maps"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	vortludo "github.com/CodeAndHammer/vortludo"
+	announce "github.com/CodeAndHammer/vortludo/internal/announce"
+	assets "github.com/CodeAndHammer/vortludo/internal/assets"
+	auth "github.com/CodeAndHammer/vortludo/internal/auth"
+	constants "github.com/CodeAndHammer/vortludo/internal/constants"
+	game "github.com/CodeAndHammer/vortludo/internal/game"
+	handlers "github.com/CodeAndHammer/vortludo/internal/handlers"
+	keyboard "github.com/CodeAndHammer/vortludo/internal/keyboard"
+	models "github.com/CodeAndHammer/vortludo/internal/models"
+	render "github.com/CodeAndHammer/vortludo/internal/render"
+)
+
+// Run with -update after an intended change to the templates or views, and
+// review the diff of testdata/ like any other change.
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func goldenTemplates(t *testing.T) *template.Template {
+	t.Helper()
+	announcements, _ := announce.Open("")
+	funcs := assets.Passthrough(vortludo.StaticFS(), constants.RouteStatic).Funcs()
+	maps.Copy(funcs, announcements.Funcs())
+	maps.Copy(funcs, (&models.App{}).Funcs())
+	templates, err := render.NewTemplates(vortludo.TemplatesFS(), render.NewCache(16), funcs)
+	if err != nil {
+		t.Fatalf("Expected the templates to parse, got %v", err)
+	}
+	return templates.Template()
+}
+
+// goldenGame plays guesses against target in a new classic game.
+func goldenGame(target string, guesses ...string) *models.GameState {
+	app := &models.App{}
+	gameState := &models.GameState{
+		ID:          "golden0001",
+		Number:      3,
+		Guesses:     models.NewRows(constants.MaxGuesses),
+		SessionWord: target,
+	}
+	for _, guess := range guesses {
+		result := game.CheckGuess(guess, target, app)
+		game.UpdateGameState(app, context.Background(), gameState, guess, target, result, false)
+	}
+	return gameState
+}
+
+func goldenContent(gameState *models.GameState, errorCode string) handlers.GameContentView {
+	view := handlers.GameContentView{
+		Game:      gameState,
+		Board:     game.BuildBoard(gameState, -1),
+		Hint:      models.HintView{Left: 2},
+		Settings:  models.UserSettings{KeyboardLayout: constants.KeyboardLayoutQwerty, Theme: constants.ThemeDark},
+		Motion:    handlers.Motion{Reveal: true, Shake: true, Celebrate: true},
+		CSRFToken: "golden-csrf-token",
+	}
+	if errorCode != "" {
+		view.ErrorCode, view.ErrorMessage = errorCode, game.ErrorMessage(errorCode)
+	}
+	if gameState.GameOver {
+		var heatmap models.Heatmap
+		for _, guess := range gameState.GuessHistory {
+			heatmap.Record(game.CheckGuess(guess, gameState.TargetWord, &models.App{}))
+		}
+		stats := auth.Stats{Played: 4, Won: 3, CurrentStreak: 2, MaxStreak: 3, Distribution: map[int]int{3: 1, 4: 2}}
+		view.Summary = &handlers.GameSummary{
+			Words:   []handlers.SummaryWord{{Word: gameState.TargetWord, Hint: "a tall wading bird"}},
+			Stats:   stats,
+			Delta:   &handlers.StatsDelta{Played: 1, WinRate: 5, Streak: 1},
+			Heatmap: game.BuildHeatmap(heatmap, constants.KeyboardLayoutQwerty),
+		}
+	}
+	return view
+}
+
+func TestGoldenFragments(t *testing.T) {
+	templates := goldenTemplates(t)
+	still := goldenContent(goldenGame("CRANE", "SLATE", "CRANE"), "")
+	still.Settings.ReducedMotion = true
+	still.Motion = handlers.Motion{}
+	drafted := goldenGame("CRANE", "SLATE")
+	drafted.Draft = "CRĈ"
+	draftRow := game.BuildBoard(drafted, -1)[1]
+
+	tests := []struct {
+		name     string
+		template string
+		data     any
+	}{
+		{"index-empty", "index.html", &handlers.IndexView{
+			GameContentView: goldenContent(goldenGame("CRANE"), ""),
+			Title:           "Vortludo - A Libre Wordle Clone",
+			Keyboard:        keyboard.Lookup(constants.KeyboardLayoutQwerty),
+			Pools:           []string{constants.PoolClassic},
+			CSPNonce:        "golden-nonce",
+			Theme:           constants.ThemeDark,
+			Preview: &handlers.Preview{
+				Title:       "Vortludo",
+				Description: "A libre Wordle clone: guess the hidden 5-letter word in 6 tries.",
+				URL:         "https://vortludo.example.org/",
+				Image:       "https://vortludo.example.org/preview/image",
+			},
+		}},
+		{"game-content-empty", "game-content", goldenContent(goldenGame("CRANE"), "")},
+		{"game-content-mid-game", "game-content", goldenContent(goldenGame("CRANE", "SLATE", "CRONE"), "")},
+		{"game-content-won", "game-content", goldenContent(goldenGame("CRANE", "SLATE", "CRANE"), "")},
+		{"game-content-lost", "game-content", goldenContent(goldenGame("CRANE", "SLATE", "CRONE", "BRINE", "TRACE", "GRADE", "CRATE"), "")},
+		{"game-content-error", "game-content", goldenContent(goldenGame("CRANE", "SLATE"), constants.ErrorCodeNotInWordList)},
+		{"game-content-reduced-motion", "game-content", still},
+		{"draft-row", "draft-row", draftRow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := templates.ExecuteTemplate(&buf, tt.template, tt.data); err != nil {
+				t.Fatalf("Expected %s to render, got %v", tt.template, err)
+			}
+			path := filepath.Join("testdata", tt.name+".golden.html")
+			if *update {
+				if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("Expected golden file %s, got %v (run with -update to create it)", path, err)
+			}
+			if got := buf.String(); got != string(want) {
+				t.Errorf("%s differs from %s at %s", tt.template, path, firstDiff(got, string(want)))
+			}
+		})
+	}
+}
+
+// firstDiff describes the first line where got and want differ.
+func firstDiff(got, want string) string {
+	gotLines, wantLines := strings.Split(got, "\n"), strings.Split(want, "\n")
+	for i := range max(len(gotLines), len(wantLines)) {
+		var g, w string
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if g != w {
+			return "line " + strconv.Itoa(i+1) + ":\n got: " + strings.TrimSpace(g) + "\nwant: " + strings.TrimSpace(w)
+		}
+	}
+	return "end of file"
+}
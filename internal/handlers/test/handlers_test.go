@@ -0,0 +1,764 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	audit "github.com/CodeAndHammer/vortludo/internal/audit"
+	auth "github.com/CodeAndHammer/vortludo/internal/auth"
+	config "github.com/CodeAndHammer/vortludo/internal/config"
+	constants "github.com/CodeAndHammer/vortludo/internal/constants"
+	game "github.com/CodeAndHammer/vortludo/internal/game"
+	handlers "github.com/CodeAndHammer/vortludo/internal/handlers"
+	models "github.com/CodeAndHammer/vortludo/internal/models"
+	session "github.com/CodeAndHammer/vortludo/internal/session"
+	shareimage "github.com/CodeAndHammer/vortludo/internal/shareimage"
+	store "github.com/CodeAndHammer/vortludo/internal/store"
+	"github.com/gin-gonic/gin"
+)
+
+func TestValidateGameStateSync(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	gameState := &models.GameState{CurrentRow: 2, Version: 2}
+	validate := func(form url.Values) error {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodPost, "/guess", strings.NewReader(form.Encode()))
+		c.Request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return handlers.ValidateGameState(&models.App{}, c, gameState)
+	}
+
+	for _, form := range []url.Values{
+		{"guess": {"CRANE"}},
+		{"guess": {"CRANE"}, "row": {"2"}, "version": {"2"}},
+		{"guess": {"CRANE"}, "row": {"2"}},
+	} {
+		if err := validate(form); err != nil {
+			t.Errorf("%v should be accepted, got %v", form, err)
+		}
+	}
+
+	for _, form := range []url.Values{
+		{"row": {"1"}, "version": {"2"}},
+		{"row": {"2"}, "version": {"1"}},
+		{"version": {"x"}},
+	} {
+		var gameErr *game.GameError
+		if err := validate(form); !errors.As(err, &gameErr) || gameErr.Code != constants.ErrorCodeOutOfSync || gameErr.Status != http.StatusConflict {
+			t.Errorf("%v should be out of sync, got %v", form, err)
+			continue
+		}
+		if gameErr.Details["row"] != 2 || gameErr.Details["version"] != 2 {
+			t.Errorf("The error should carry the server's row and version, got %v", gameErr.Details)
+		}
+	}
+}
+
+func TestParseCompletedWords(t *testing.T) {
+	app := &models.App{}
+	app.SetDictionary(&models.Dictionary{WordSet: map[string]struct{}{
+		"CRANE": {}, "SLATE": {}, "ĈAPELO": {}, "ABBEY": {}, "ZEBRA": {},
+	}})
+
+	words, err := handlers.ParseCompletedWords(app, `["crane", " CRANE ", "slate", "nope", "ĉapelo"]`)
+	if err != nil || !slices.Equal(words, []string{"CRANE", "SLATE", "ĈAPELO"}) {
+		t.Errorf("Expected [CRANE SLATE ĈAPELO], got %v (%v)", words, err)
+	}
+	if words, err := handlers.ParseCompletedWords(app, ""); words != nil || err != nil {
+		t.Errorf("An empty field should give no words, got %v (%v)", words, err)
+	}
+
+	// Only as many entries as the dictionary has words are read, the latest.
+	words, _ = handlers.ParseCompletedWords(app, `["crane", "nope", "nope", "nope", "nope", "nope", "slate"]`)
+	if !slices.Equal(words, []string{"SLATE"}) {
+		t.Errorf("Expected only the latest entries to count, got %v", words)
+	}
+
+	for _, raw := range []string{`["crane", 5]`, `{"a": "crane"}`, `["crane", ["slate"]]`, `[`, `"crane"`} {
+		var gameErr *game.GameError
+		if _, err := handlers.ParseCompletedWords(app, raw); !errors.As(err, &gameErr) ||
+			gameErr.Code != constants.ErrorCodeInvalidCompletedWords || gameErr.Status != http.StatusBadRequest {
+			t.Errorf("%s should be invalid_completed_words, got %v", raw, err)
+		}
+	}
+}
+
+func TestResponseNegotiation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	templates := template.Must(template.New("page").Parse(`page {{.name}}`))
+	template.Must(templates.New("fragment").Parse(`fragment {{.name}}`))
+	router.SetHTMLTemplate(templates)
+	resp := func() handlers.Response {
+		return handlers.Response{
+			Page:     "page",
+			Fragment: "fragment",
+			Data:     gin.H{"name": "vortludo"},
+			JSON:     gin.H{"name": "vortludo"},
+		}
+	}
+	router.GET("/", func(c *gin.Context) { resp().Send(c) })
+	router.GET("/no-page", func(c *gin.Context) {
+		r := resp()
+		r.Page, r.Redirect = "", "/"
+		r.Send(c)
+	})
+	router.GET("/no-json", func(c *gin.Context) {
+		r := resp()
+		r.JSON = nil
+		r.Send(c)
+	})
+	router.GET("/fail", func(c *gin.Context) {
+		resp().Fail(c, game.NewGameError(constants.ErrorCodeGameOver))
+	})
+
+	tests := []struct {
+		path, accept string
+		htmx         bool
+		status       int
+		body         string
+	}{
+		{"/", "text/html", false, http.StatusOK, "page vortludo"},
+		{"/", "text/html", true, http.StatusOK, "fragment vortludo"},
+		{"/", "application/json", false, http.StatusOK, `{"name":"vortludo"}`},
+		{"/", "application/json", true, http.StatusOK, `{"name":"vortludo"}`},
+		{"/no-page", "text/html", false, http.StatusSeeOther, ""},
+		{"/no-page", "text/html", true, http.StatusOK, "fragment vortludo"},
+		{"/no-json", "application/json", false, http.StatusOK, "fragment vortludo"},
+		{"/fail", "text/html", true, http.StatusOK, "fragment vortludo"},
+		{"/fail", "application/json", false, http.StatusConflict, `"code":"game_over"`},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		req.Header.Set("Accept", tt.accept)
+		if tt.htmx {
+			req.Header.Set("HX-Request", "true")
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != tt.status || !strings.Contains(w.Body.String(), tt.body) {
+			t.Errorf("%s (Accept %s, htmx %v): got %d %q, want %d %q", tt.path, tt.accept, tt.htmx, w.Code, w.Body.String(), tt.status, tt.body)
+		}
+	}
+}
+
+func TestViewsAreStamped(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	templates := template.Must(template.New("page").Parse(`page {{.CSRFToken}} {{.CSPNonce}} {{.ErrorCode}}`))
+	template.Must(templates.New("fragment").Parse(`fragment {{.CSRFToken}} {{.ErrorCode}}`))
+	router.SetHTMLTemplate(templates)
+	router.Use(func(c *gin.Context) {
+		c.Set(constants.CSRFTokenKey, "token")
+		c.Set(constants.CSPNonceKey, "nonce")
+	})
+	router.GET("/", func(c *gin.Context) {
+		handlers.Response{Page: "page", Fragment: "fragment", Data: &handlers.IndexView{}}.Send(c)
+	})
+	router.GET("/fail", func(c *gin.Context) {
+		handlers.Response{Page: "page", Fragment: "fragment", Data: &handlers.IndexView{}}.
+			Fail(c, game.NewGameError(constants.ErrorCodeGameOver))
+	})
+
+	for _, tt := range []struct {
+		path string
+		htmx bool
+		body string
+	}{
+		{"/", false, "page token nonce "},
+		{"/", true, "fragment token "},
+		{"/fail", true, "fragment token game_over"},
+	} {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		req.Header.Set("Accept", "text/html")
+		if tt.htmx {
+			req.Header.Set("HX-Request", "true")
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Body.String() != tt.body {
+			t.Errorf("%s (htmx %v): got %q, want %q", tt.path, tt.htmx, w.Body.String(), tt.body)
+		}
+	}
+}
+
+func TestThemeHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	app := &models.App{}
+	router := gin.New()
+	router.POST(constants.RouteSettingsTheme, func(c *gin.Context) { handlers.ThemeHandler(app, c) })
+	post := func(theme string) *httptest.ResponseRecorder {
+		form := url.Values{"theme": {theme}}
+		req := httptest.NewRequest(http.MethodPost, constants.RouteSettingsTheme, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("HX-Request", "true")
+		req.AddCookie(&http.Cookie{Name: constants.SessionCookieName, Value: "theme-session"})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := post("Dark"); w.Code != http.StatusNoContent {
+		t.Fatalf("Expected 204, got %d", w.Code)
+	}
+	settings := session.GetSettings(app, "theme-session")
+	if settings.Theme != constants.ThemeDark || settings.KeyboardLayout != constants.KeyboardLayoutQwerty {
+		t.Errorf("Expected only the theme changed to dark, got %+v", settings)
+	}
+	if w := post("sepia"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected an unknown theme to be refused, got %d", w.Code)
+	}
+	if theme := session.GetSettings(app, "theme-session").Theme; theme != constants.ThemeDark {
+		t.Errorf("Expected a refused theme to leave dark, got %s", theme)
+	}
+}
+
+func TestShareImageHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	app := &models.App{ShareImages: shareimage.NewCache(4)}
+	gameState := goldenGame("CRANE", "SLATE")
+	app.Sessions.SetGame("share-session", gameState)
+	router := gin.New()
+	router.GET(constants.RouteShareImage, func(c *gin.Context) { handlers.ShareImageHandler(app, c) })
+	get := func(etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, constants.RouteShareImage, nil)
+		req.AddCookie(&http.Cookie{Name: constants.SessionCookieName, Value: "share-session"})
+		req.Header.Set("Accept", "application/json")
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := get(""); w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), constants.ErrorCodeGameInProgress) {
+		t.Errorf("Expected a game in progress to be refused, got %d %s", w.Code, w.Body.String())
+	}
+	*gameState = *goldenGame("CRANE", "SLATE", "CRANE")
+	w := get("")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "image/png" || w.Header().Get("ETag") == "" {
+		t.Fatalf("Expected a PNG with an ETag, got %d %v", w.Code, w.Header())
+	}
+	if w := get(w.Header().Get("ETag")); w.Code != http.StatusNotModified {
+		t.Errorf("Expected an unchanged image to be revalidated, got %d", w.Code)
+	}
+}
+
+func TestPreviewImageHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	app := &models.App{ShareImages: shareimage.NewCache(4)}
+	router := gin.New()
+	router.GET(constants.RoutePreviewImage, func(c *gin.Context) { handlers.PreviewImageHandler(app, c) })
+	get := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, constants.RoutePreviewImage+query, nil)
+		req.Header.Set("Accept", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := get(""); w.Code != http.StatusOK || w.Header().Get("Content-Type") != "image/png" ||
+		!strings.HasPrefix(w.Header().Get("Cache-Control"), "public") {
+		t.Errorf("Expected a public PNG of the daily puzzle, got %d %v", w.Code, w.Header())
+	}
+	if w := get("?" + constants.PreviewWatchParam + "=unknown"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected an unknown spectate token to be not found, got %d", w.Code)
+	}
+}
+
+func TestAdminActionsAudited(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	app := &models.App{}
+	app.Audit, _ = audit.Open("")
+	router := gin.New()
+	router.POST("/admin/maintenance", func(c *gin.Context) { handlers.AdminMaintenanceHandler(app, c) })
+
+	for _, enabled := range []string{"true", "true", "false"} {
+		req := httptest.NewRequest(http.MethodPost, "/admin/maintenance?enabled="+enabled, nil)
+		req.Header.Set(constants.AdminActorHeader, "ops")
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	entries := app.Audit.Recent(10, audit.Maintenance)
+	if len(entries) != 2 {
+		t.Fatalf("Expected only the two changes audited, got %+v", entries)
+	}
+	if entries[1].Actor != "ops" || entries[1].Before.(gin.H)["maintenance"] != false || entries[1].After.(gin.H)["maintenance"] != true {
+		t.Errorf("Expected ops enabling maintenance, got %+v", entries[1])
+	}
+}
+
+func TestReadyzChecksStore(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	app := &models.App{}
+	game.SetDictionary(app, []models.WordEntry{{Word: "CRANE"}}, map[string]struct{}{"CRANE": {}})
+	router := gin.New()
+	router.GET(constants.RouteReadyz, func(c *gin.Context) { handlers.ReadyzHandler(app, c) })
+	get := func() (int, map[string]any) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, constants.RouteReadyz, nil))
+		var body map[string]any
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("Expected a JSON body, got %q", w.Body.String())
+		}
+		return w.Code, body
+	}
+
+	app.Store = store.NewMemory()
+	code, body := get()
+	if code != http.StatusOK || body["checks"].(map[string]any)["store"] != "ok" {
+		t.Fatalf("Expected a ready store, got %d %v", code, body)
+	}
+	if pool, _ := body["store"].(map[string]any); pool["backend"] != "memory" {
+		t.Errorf("Expected the store's pool reported, got %v", body["store"])
+	}
+
+	closed, err := store.Open(context.Background(), "sqlite:"+filepath.Join(t.TempDir(), "closed.db"), store.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed.Close()
+	app.Store = closed
+	code, body = get()
+	if result, _ := body["checks"].(map[string]any)["store"].(string); code != http.StatusServiceUnavailable || !strings.HasPrefix(result, "unreachable") {
+		t.Errorf("Expected an unreachable store to fail readiness, got %d %v", code, body)
+	}
+}
+
+func TestAdminWords(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	app := &models.App{WordsFile: filepath.Join(t.TempDir(), "words.json")}
+	if err := os.WriteFile(app.WordsFile, []byte(`{"words": [{"word": "CRANE", "hint": "A bird."}, {"word": "SLATE", "hint": "A rock."}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	game.SetDictionary(app, []models.WordEntry{{Word: "CRANE"}, {Word: "SLATE"}}, map[string]struct{}{"PLANT": {}, "FUZZY": {}})
+	app.BlockedWordSet = map[string]struct{}{"FUZZY": {}}
+	router := gin.New()
+	router.POST(constants.RouteAdminWords, func(c *gin.Context) { handlers.AdminAddWordHandler(app, c) })
+	router.DELETE(constants.RouteAdminWord, func(c *gin.Context) { handlers.AdminRemoveWordHandler(app, c) })
+	add := func(word, hint string) int {
+		form := url.Values{"word": {word}, "hint": {hint}}
+		req := httptest.NewRequest(http.MethodPost, constants.RouteAdminWords, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+	remove := func(word string) int {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, constants.RouteAdminWords+"/"+word, nil))
+		return w.Code
+	}
+
+	for _, tt := range []struct {
+		word, hint string
+		want       int
+	}{
+		{"PLAN", "Short.", http.StatusUnprocessableEntity},
+		{"PLANT", "", http.StatusUnprocessableEntity},
+		{"QWXYZ", "Not a word.", http.StatusUnprocessableEntity},
+		{"FUZZY", "Blocked.", http.StatusUnprocessableEntity},
+		{"crane", "Again.", http.StatusConflict},
+		{"plant", "A living thing.", http.StatusCreated},
+	} {
+		if got := add(tt.word, tt.hint); got != tt.want {
+			t.Errorf("Adding %s: got %d, want %d", tt.word, got, tt.want)
+		}
+	}
+	if !game.IsValidWord(app, "PLANT") {
+		t.Error("An added word should be a target word at once")
+	}
+	data, _ := os.ReadFile(app.WordsFile)
+	if words, err := game.ParseWordList(data); err != nil || len(words) != 3 || words[2].Word != "PLANT" || words[2].Hint != "A living thing." {
+		t.Errorf("Expected PLANT appended to the file, got %+v %v", words, err)
+	}
+
+	sessionID := "sess1"
+	session.SaveGameState(app, sessionID, &models.GameState{SessionWord: "SLATE"})
+	if got := remove("SLATE"); got != http.StatusConflict {
+		t.Errorf("A word being played should not be removed, got %d", got)
+	}
+	if got := remove("BRICK"); got != http.StatusNotFound {
+		t.Errorf("Removing a missing word: got %d", got)
+	}
+	if got := remove("plant"); got != http.StatusOK || game.IsValidWord(app, "PLANT") {
+		t.Errorf("Expected PLANT removed, got %d", got)
+	}
+	data, _ = os.ReadFile(app.WordsFile)
+	if words, _ := game.ParseWordList(data); len(words) != 2 {
+		t.Errorf("Expected PLANT removed from the file, got %+v", words)
+	}
+
+	app.WordsFile = ""
+	if got := add("PLANT", "A living thing."); got != http.StatusConflict {
+		t.Errorf("A word list that cannot be edited should refuse, got %d", got)
+	}
+}
+
+func TestAdminRemoveWordWhileDrawing(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for trial := range 40 {
+		app := &models.App{WordsFile: filepath.Join(t.TempDir(), "words.json"), SessionTimeout: time.Hour}
+		if err := os.WriteFile(app.WordsFile, []byte(`{"words": [{"word": "CRANE", "hint": "A bird."}, {"word": "SLATE", "hint": "A rock."}, {"word": "PLANT", "hint": "A living thing."}]}`), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		game.SetDictionary(app, []models.WordEntry{{Word: "CRANE"}, {Word: "SLATE"}, {Word: "PLANT"}}, nil)
+		router := gin.New()
+		router.DELETE(constants.RouteAdminWord, func(c *gin.Context) { handlers.AdminRemoveWordHandler(app, c) })
+
+		var wg sync.WaitGroup
+		start := make(chan struct{})
+		for i := range 4 {
+			wg.Go(func() {
+				<-start
+				sessionID := fmt.Sprintf("trial-%d-%d", trial, i)
+				unlock := session.Lock(app, sessionID)
+				defer unlock()
+				game.CreateNewGame(app, context.Background(), sessionID)
+			})
+		}
+		w := httptest.NewRecorder()
+		close(start)
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, constants.RouteAdminWords+"/CRANE", nil))
+		wg.Wait()
+
+		switch w.Code {
+		case http.StatusOK:
+			if playing := session.GamesPlaying(app, "CRANE"); playing > 0 {
+				t.Fatalf("Trial %d: CRANE was removed under %d games", trial, playing)
+			}
+		case http.StatusConflict:
+		default:
+			t.Fatalf("Trial %d: got %d %s", trial, w.Code, w.Body)
+		}
+	}
+}
+
+func TestAdminRetireWord(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	app := &models.App{WordsFile: filepath.Join(t.TempDir(), "words.json")}
+	if err := os.WriteFile(app.WordsFile, []byte(`{"words": [{"word": "CRANE", "hint": "A bird."}, {"word": "SLATE", "hint": "A rock."}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	game.SetDictionary(app, []models.WordEntry{{Word: "CRANE"}, {Word: "SLATE"}}, nil)
+	router := gin.New()
+	router.POST(constants.RouteAdminRetireWord, func(c *gin.Context) { handlers.AdminRetireWordHandler(app, c) })
+	router.POST(constants.RouteAdminRestoreWord, func(c *gin.Context) { handlers.AdminRestoreWordHandler(app, c) })
+	post := func(word, action string) int {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, constants.RouteAdminWords+"/"+word+"/"+action, nil))
+		return w.Code
+	}
+
+	for _, tt := range []struct {
+		word, action string
+		want         int
+	}{
+		{"crane", "restore", http.StatusConflict},
+		{"crane", "retire", http.StatusOK},
+		{"CRANE", "retire", http.StatusConflict},
+		{"SLATE", "retire", http.StatusConflict},
+		{"BRICK", "retire", http.StatusNotFound},
+	} {
+		if got := post(tt.word, tt.action); got != tt.want {
+			t.Errorf("%s %s: got %d, want %d", tt.action, tt.word, got, tt.want)
+		}
+	}
+	if !game.IsRetiredWord(app, "CRANE") || !game.IsValidWord(app, "CRANE") {
+		t.Error("A retired word should stay a target word for games that have it")
+	}
+	data, _ := os.ReadFile(app.WordsFile)
+	if words, _ := game.ParseWordList(data); len(words) != 2 || !words[0].Retired() {
+		t.Errorf("Expected CRANE retired in the file, got %+v", words)
+	}
+	if got := post("CRANE", "restore"); got != http.StatusOK || game.IsRetiredWord(app, "CRANE") {
+		t.Errorf("Expected CRANE restored, got %d", got)
+	}
+}
+
+func TestKeyHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	app := models.NewApp(config.Default())
+	game.SetDictionary(app, []models.WordEntry{{Word: "APPLE"}, {Word: "PAPER"}}, nil)
+	gameState := game.CreateNewGame(app, context.Background(), "key-session")
+	gameState.SessionWord = "APPLE"
+	session.SaveGameState(app, "key-session", gameState)
+	router := gin.New()
+	router.POST(constants.RouteKey, func(c *gin.Context) { handlers.KeyHandler(app, c) })
+	press := func(key, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, constants.RouteKey, strings.NewReader(url.Values{"key": {key}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		req.AddCookie(&http.Cookie{Name: constants.SessionCookieName, Value: "key-session"})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, key := range []string{"P", "A", "P", "E", "S", constants.KeyBackspace} {
+		if w := press(key, "application/json"); w.Code != http.StatusOK {
+			t.Fatalf("Key %s: got %d %s", key, w.Code, w.Body)
+		}
+	}
+	if w := press("R", ""); w.Code != http.StatusSeeOther || w.Header().Get("Location") != constants.RouteHome {
+		t.Errorf("A browser should be sent back to the board, got %d %s", w.Code, w.Header().Get("Location"))
+	}
+	if w := press("7", "application/json"); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), constants.ErrorCodeInvalidKey) {
+		t.Errorf("Expected an invalid key refused, got %d %s", w.Code, w.Body)
+	}
+	w := press(constants.KeyEnter, "application/json")
+	gameState = session.GetGameState(app, context.Background(), "key-session")
+	if w.Code != http.StatusOK || !slices.Equal(gameState.GuessHistory, []string{"PAPER"}) || gameState.Draft != "" {
+		t.Errorf("Expected Enter to guess PAPER and clear the draft, got %d %v %q", w.Code, gameState.GuessHistory, gameState.Draft)
+	}
+
+	press("P", "application/json")
+	if w := press(constants.KeyEnter, "application/json"); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected a short draft refused, got %d %s", w.Code, w.Body)
+	}
+	if gameState = session.GetGameState(app, context.Background(), "key-session"); gameState.Draft != "P" {
+		t.Errorf("A refused guess should keep its draft, got %q", gameState.Draft)
+	}
+}
+
+// Without JavaScript, moves are plain form posts: the browser is sent back
+// to the board, with the code of a refused move for the page to explain.
+func TestPlayWithoutJavaScript(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	app := models.NewApp(config.Default())
+	game.SetDictionary(app, []models.WordEntry{{Word: "APPLE"}, {Word: "PAPER"}}, nil)
+	gameState := game.CreateNewGame(app, context.Background(), "nojs-session")
+	gameState.SessionWord = "APPLE"
+	session.SaveGameState(app, "nojs-session", gameState)
+	router := gin.New()
+	router.SetHTMLTemplate(template.Must(template.New("index.html").Parse(`{{.ErrorCode}}: {{.ErrorMessage}}`)))
+	router.GET(constants.RouteHome, func(c *gin.Context) { handlers.HomeHandler(app, c) })
+	router.POST(constants.RouteGuess, func(c *gin.Context) { handlers.GuessHandler(app, c) })
+	do := func(method, target string, form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Accept", "text/html")
+		req.AddCookie(&http.Cookie{Name: constants.SessionCookieName, Value: "nojs-session"})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do(http.MethodPost, constants.RouteGuess, url.Values{"guess": {"paper"}}); w.Code != http.StatusSeeOther || w.Header().Get("Location") != constants.RouteHome {
+		t.Errorf("A guess should send the browser back to the board, got %d %s", w.Code, w.Header().Get("Location"))
+	}
+	w := do(http.MethodPost, constants.RouteGuess, url.Values{"guess": {"paper"}})
+	location := w.Header().Get("Location")
+	if w.Code != http.StatusSeeOther || location != "/?error="+constants.ErrorCodeDuplicateGuess {
+		t.Fatalf("A refused guess should carry its code, got %d %s", w.Code, location)
+	}
+	if w := do(http.MethodGet, location, nil); w.Body.String() != constants.ErrorCodeDuplicateGuess+": "+game.ErrorMessage(constants.ErrorCodeDuplicateGuess) {
+		t.Errorf("Expected the board to explain the refused guess, got %q", w.Body)
+	}
+	if w := do(http.MethodGet, "/?error=made_up", nil); w.Body.String() != ": " {
+		t.Errorf("Unknown codes should be ignored, got %q", w.Body)
+	}
+}
+
+// A pack game continues with a word the player has not solved in the pack,
+// going by the stored progress rather than the client's completed words.
+func TestAutoContinuePackGame(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	app := models.NewApp(config.Default())
+	game.SetDictionary(app, []models.WordEntry{{Word: "ZEBRA"}, {Word: "HORSE"}, {Word: "TIGER"}}, nil)
+	app.Packs = []models.Pack{{ID: "animals", Name: "Animals", Words: []string{"ZEBRA", "HORSE", "TIGER"}}}
+	session.UpdateStats(app, "pack-session", func(stats *auth.Stats) {
+		stats.SolvePackWord("animals", "ZEBRA")
+		stats.SolvePackWord("animals", "HORSE")
+	})
+	router := gin.New()
+	router.GET(constants.RouteGameState, func(c *gin.Context) { handlers.GameStateHandler(app, c) })
+
+	for range 20 {
+		session.SaveGameState(app, "pack-session", &models.GameState{
+			ID:          game.NewGameID(),
+			Guesses:     models.NewRows(constants.MaxGuesses),
+			SessionWord: "ZEBRA",
+			Pack:        "animals",
+			GameOver:    true,
+			Won:         true,
+			ContinueAt:  time.Now().Add(-time.Second),
+		})
+		req := httptest.NewRequest(http.MethodGet, constants.RouteGameState+"?completedWords=[]", nil)
+		req.Header.Set("Accept", "application/json")
+		req.AddCookie(&http.Cookie{Name: constants.SessionCookieName, Value: "pack-session"})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		next := session.GetGameState(app, context.Background(), "pack-session")
+		if w.Code != http.StatusOK || next.Pack != "animals" || next.SessionWord != "TIGER" {
+			t.Fatalf("Expected the unsolved TIGER, got %d, %s from %q", w.Code, next.SessionWord, next.Pack)
+		}
+	}
+}
+
+type linkMailer struct{ links []string }
+
+func (m *linkMailer) SendMagicLink(_, link string) error {
+	m.links = append(m.links, link)
+	return nil
+}
+
+func TestMagicLinkIgnoresHostHeader(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	app := models.NewApp(config.Default())
+	mailer := &linkMailer{}
+	accounts, err := auth.Open("", mailer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := accounts.Register("victim", "victim@example.org", "password1"); err != nil {
+		t.Fatal(err)
+	}
+	app.Accounts = accounts
+	router := gin.New()
+	router.POST(constants.RouteAccountMagicLink, func(c *gin.Context) { handlers.MagicLinkHandler(app, c) })
+	ask := func(email string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, constants.RouteAccountMagicLink, strings.NewReader(url.Values{"email": {email}}.Encode()))
+		req.Host = "evil.example"
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Accept", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	app.PublicURL = "https://play.example.org"
+	known, unknown := ask("victim@example.org"), ask("nobody@example.org")
+	if len(mailer.links) != 1 || !strings.HasPrefix(mailer.links[0], "https://play.example.org/account/magic?token=") {
+		t.Fatalf("Expected one link to PUBLIC_URL, got %v", mailer.links)
+	}
+	if known.Code != unknown.Code || known.Body.String() != unknown.Body.String() {
+		t.Errorf("Expected the same answer for every address, got %d %s and %d %s", known.Code, known.Body, unknown.Code, unknown.Body)
+	}
+
+	app.PublicURL = ""
+	app.IsProduction = true
+	if w := ask("victim@example.org"); w.Code != http.StatusOK || len(mailer.links) != 1 {
+		t.Errorf("Expected no link built from the Host header in production, got %d and %v", w.Code, mailer.links)
+	}
+}
+
+func TestPrivacy(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	app := models.NewApp(config.Default())
+	app.Store = store.NewMemory()
+	game.SetDictionary(app, []models.WordEntry{{Word: "APPLE"}, {Word: "PAPER"}}, nil)
+	gameState := game.CreateNewGame(app, context.Background(), "privacy-session")
+	gameState.SessionWord, gameState.TargetWord = "APPLE", "APPLE"
+	session.SaveGameState(app, "privacy-session", gameState)
+	settings := game.DefaultSettings()
+	settings.DoNotTrack = true
+	session.SaveSettings(app, "privacy-session", settings)
+	router := gin.New()
+	router.POST(constants.RouteGuess, func(c *gin.Context) { handlers.GuessHandler(app, c) })
+	router.GET(constants.RoutePrivacyExport, func(c *gin.Context) { handlers.PrivacyExportHandler(app, c) })
+	router.POST(constants.RoutePrivacyDelete, func(c *gin.Context) { handlers.PrivacyDeleteHandler(app, c) })
+	do := func(method, target string, form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Accept", "application/json")
+		req.AddCookie(&http.Cookie{Name: constants.SessionCookieName, Value: "privacy-session"})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do(http.MethodPost, constants.RouteGuess, url.Values{"guess": {"PAPER"}}); w.Code != http.StatusOK {
+		t.Fatalf("Guess: got %d %s", w.Code, w.Body)
+	}
+	if s := app.Analytics.Summary(); len(s.DailyActiveSessions) != 0 || len(s.MostCommonFirstGuesses) != 0 {
+		t.Errorf("A session that opted out should not be tracked: %+v", s)
+	}
+
+	w := do(http.MethodGet, constants.RoutePrivacyExport, nil)
+	var export struct {
+		SessionID string              `json:"session_id"`
+		Settings  models.UserSettings `json:"settings"`
+		Game      map[string]any      `json:"game"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &export); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("Export: got %d %s", w.Code, w.Body)
+	}
+	if export.SessionID != "privacy-session" || !export.Settings.DoNotTrack || export.Game["guesses"] == nil {
+		t.Errorf("Expected the session's data, got %s", w.Body)
+	}
+	if strings.Contains(w.Body.String(), "APPLE") {
+		t.Error("The export should not give the word of the game in progress away")
+	}
+
+	session.PersistCompletedWords(context.Background(), app, "privacy-session", false, "PAPER")
+	if w := do(http.MethodPost, constants.RoutePrivacyDelete, nil); w.Code != http.StatusOK || !slices.ContainsFunc(w.Header().Values("Set-Cookie"), func(cookie string) bool {
+		return strings.HasPrefix(cookie, constants.SessionCookieName+"=;")
+	}) {
+		t.Fatalf("Delete: got %d %s %q", w.Code, w.Body, w.Header().Values("Set-Cookie"))
+	}
+	if _, ok := app.Sessions.Game("privacy-session"); ok || session.GetSettings(app, "privacy-session").DoNotTrack {
+		t.Error("Expected the session's game and settings erased")
+	}
+	if words, _ := app.Store.CompletedWords(context.Background(), "privacy-session"); len(words) != 0 {
+		t.Errorf("Expected the stored completed words erased, got %v", words)
+	}
+}
+
+func TestConsent(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	app := models.NewApp(config.Default())
+	app.MinimalCookies = true
+	app.Store = store.NewMemory()
+	accounts, err := auth.Open("", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.Accounts = accounts
+	sessionID := app.CSRF.NewSecret()
+	cookie := &http.Cookie{Name: constants.SessionCookieName, Value: app.CSRF.Sign(sessionID)}
+	router := gin.New()
+	router.POST(constants.RouteAccountRegister, func(c *gin.Context) { handlers.RegisterHandler(app, c) })
+	router.POST(constants.RouteConsent, func(c *gin.Context) { handlers.ConsentHandler(app, c) })
+	do := func(target string, form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Accept", "application/json")
+		req.AddCookie(cookie)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	register := url.Values{"username": {"player"}, "email": {"player@example.com"}, "password": {"correct horse"}}
+
+	if w := do(constants.RouteAccountRegister, register); w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), constants.ErrorCodeConsentRequired) {
+		t.Fatalf("Register without consent: got %d %s", w.Code, w.Body)
+	}
+	if w := do(constants.RouteConsent, url.Values{"consent": {"maybe"}}); w.Code != http.StatusBadRequest {
+		t.Errorf("Unclear consent: got %d %s", w.Code, w.Body)
+	}
+	w := do(constants.RouteConsent, url.Values{"consent": {"true"}})
+	if w.Code != http.StatusOK || !session.Consented(app, sessionID) {
+		t.Fatalf("Consent: got %d %s", w.Code, w.Body)
+	}
+	if cookies := w.Result().Cookies(); len(cookies) != 1 || cookies[0].MaxAge <= 0 {
+		t.Errorf("Expected consent to renew the session cookie to last, got %v", cookies)
+	}
+	if w := do(constants.RouteAccountRegister, register); w.Code != http.StatusOK {
+		t.Errorf("Register after consent: got %d %s", w.Code, w.Body)
+	}
+}
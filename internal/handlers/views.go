@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	auth "github.com/CodeAndHammer/vortludo/internal/auth"
+	game "github.com/CodeAndHammer/vortludo/internal/game"
+	keyboard "github.com/CodeAndHammer/vortludo/internal/keyboard"
+	models "github.com/CodeAndHammer/vortludo/internal/models"
+	session "github.com/CodeAndHammer/vortludo/internal/session"
+	"github.com/gin-gonic/gin"
+)
+
+// View is template data built by a presenter. Send stamps it with the
+// request's CSRF token and, for pages, its script nonce; Fail sets the error
+// code.
+type View interface {
+	stamp(c *gin.Context, page bool)
+	fail(code string)
+}
+
+// GameContentView is what the game-content fragment and the partials it
+// includes render.
+type GameContentView struct {
+	Game *models.GameState
+	// Board holds the rows of a classic game, or one BoardView per word of a
+	// multi-board game.
+	Board    any
+	Hint     models.HintView
+	Settings models.UserSettings
+	Motion   Motion
+	// Summary is set once the game is over.
+	Summary *GameSummary
+	// NewGame tells the client that the game just started, so it resets its
+	// board state.
+	NewGame bool
+	// ErrorCode is the code of the error the request ran into, and
+	// ErrorMessage what the page says about it when JavaScript is off.
+	ErrorCode    string
+	ErrorMessage string
+	CSRFToken    string
+}
+
+func (v *GameContentView) stamp(c *gin.Context, page bool) {
+	v.CSRFToken = csrfToken(c)
+}
+
+func (v *GameContentView) fail(code string) {
+	v.ErrorCode = code
+	v.ErrorMessage = game.ErrorMessage(code)
+}
+
+// Motion flags the animations the client may play on a fragment. All are
+// off for players who turned on reduced motion, whose new rows are rendered
+// without reveal delays.
+type Motion struct {
+	// Reveal flips the tiles of a newly scored row one after another.
+	Reveal bool
+	// Shake shakes the current row when a guess is refused.
+	Shake bool
+	// Celebrate bounces the winning row and sets off confetti.
+	Celebrate bool
+}
+
+func motionFor(settings models.UserSettings) Motion {
+	animate := !settings.ReducedMotion
+	return Motion{Reveal: animate, Shake: animate, Celebrate: animate}
+}
+
+// IndexView is what the home page renders: the game content and the page
+// around it.
+type IndexView struct {
+	GameContentView
+	Title    string
+	Keyboard keyboard.Layout
+	Pools    []string
+	CSPNonce string
+	Theme    string
+	Preview  *Preview
+	// AskConsent shows the consent banner, with minimal cookies, until the
+	// player answers it.
+	AskConsent bool
+}
+
+func (v *IndexView) stamp(c *gin.Context, page bool) {
+	v.GameContentView.stamp(c, page)
+	if page {
+		v.CSPNonce = cspNonce(c)
+		v.Theme = theme(c)
+	}
+}
+
+// presentGameContent builds the view of the session's game. newRow is the
+// row to animate, if any, and before the player's stats before the game, if
+// it just ended.
+func presentGameContent(app *models.App, c *gin.Context, sessionID string, gameState *models.GameState, newRow int, before *auth.Stats) *GameContentView {
+	settings := session.GetSettings(app, sessionID)
+	motion := motionFor(settings)
+	board := boardView(gameState, newRow)
+	if !motion.Reveal {
+		board = stillBoard(board)
+	}
+	return &GameContentView{
+		Game:     gameState,
+		Board:    board,
+		Hint:     game.BuildHintView(app, gameState, settings.HintTax),
+		Settings: settings,
+		Motion:   motion,
+		Summary:  buildSummary(app, c, sessionID, gameState, before),
+	}
+}
+
+// stillBoard drops the reveal delays from a board built by boardView, so a
+// new row shows all at once.
+func stillBoard(board any) any {
+	still := func(rows []models.BoardRow) {
+		for i := range rows {
+			for j := range rows[i].Tiles {
+				rows[i].Tiles[j].RevealDelayMs = 0
+			}
+		}
+	}
+	switch board := board.(type) {
+	case []models.BoardRow:
+		still(board)
+	case []models.BoardView:
+		for _, b := range board {
+			still(b.Rows)
+		}
+	}
+	return board
+}
+
+// presentIndex builds the view of the home page showing the session's game.
+func presentIndex(app *models.App, c *gin.Context, sessionID string, gameState *models.GameState, newRow int, before *auth.Stats) *IndexView {
+	content := presentGameContent(app, c, sessionID, gameState, newRow, before)
+	_, answered := session.Consent(app, sessionID)
+	return &IndexView{
+		GameContentView: *content,
+		Title:           "Vortludo - A Libre Wordle Clone",
+		Keyboard:        keyboard.Lookup(content.Settings.KeyboardLayout),
+		Pools:           game.PoolNames(app),
+		Preview:         homePreview(app, c, gameState),
+		AskConsent:      app.MinimalCookies && !answered,
+	}
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	audit "github.com/CodeAndHammer/vortludo/internal/audit"
+	game "github.com/CodeAndHammer/vortludo/internal/game"
+	models "github.com/CodeAndHammer/vortludo/internal/models"
+	session "github.com/CodeAndHammer/vortludo/internal/session"
+	util "github.com/CodeAndHammer/vortludo/internal/util"
+	"github.com/gin-gonic/gin"
+)
+
+// AdminAddWordHandler adds the posted word and hint to the target words.
+func AdminAddWordHandler(app *models.App, c *gin.Context) {
+	entry, err := game.AddWord(app, models.WordEntry{Word: c.PostForm("word"), Hint: c.PostForm("hint")})
+	if err != nil {
+		wordEditFailed(c, err)
+		return
+	}
+	recordAdminAction(app, c, audit.AddWord, nil, entry)
+	c.JSON(http.StatusCreated, gin.H{"word": entry})
+}
+
+// AdminRemoveWordHandler removes :word from the target words, unless a game
+// in progress is playing it: the game would no longer count it as a valid
+// guess, so its player could not win.
+func AdminRemoveWordHandler(app *models.App, c *gin.Context) {
+	playing := 0
+	entry, err := game.RemoveWord(app, c.Param("word"), func(word string) int {
+		playing = session.GamesPlaying(app, word)
+		return playing
+	})
+	if errors.Is(err, game.ErrWordInPlay) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "games": playing})
+		return
+	}
+	if err != nil {
+		wordEditFailed(c, err)
+		return
+	}
+	recordAdminAction(app, c, audit.RemoveWord, entry, nil)
+	c.JSON(http.StatusOK, gin.H{"removed": entry})
+}
+
+// AdminRetireWordHandler stops :word being drawn as a target word, keeping
+// it for the games that have it.
+func AdminRetireWordHandler(app *models.App, c *gin.Context) {
+	entry, err := game.RetireWord(app, c.Param("word"))
+	if err != nil {
+		wordEditFailed(c, err)
+		return
+	}
+	recordAdminAction(app, c, audit.RetireWord, nil, entry)
+	c.JSON(http.StatusOK, gin.H{"word": entry})
+}
+
+// AdminRestoreWordHandler puts a retired :word back in play.
+func AdminRestoreWordHandler(app *models.App, c *gin.Context) {
+	entry, err := game.RestoreWord(app, c.Param("word"))
+	if err != nil {
+		wordEditFailed(c, err)
+		return
+	}
+	recordAdminAction(app, c, audit.RestoreWord, nil, entry)
+	c.JSON(http.StatusOK, gin.H{"word": entry})
+}
+
+func wordEditFailed(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, game.ErrWordLength), errors.Is(err, game.ErrWordHint),
+		errors.Is(err, game.ErrWordNotAccepted), errors.Is(err, game.ErrWordBlocked):
+		status = http.StatusUnprocessableEntity
+	case errors.Is(err, game.ErrWordExists), errors.Is(err, game.ErrLastWord), errors.Is(err, game.ErrWordListReadOnly),
+		errors.Is(err, game.ErrWordRetired), errors.Is(err, game.ErrWordNotRetired):
+		status = http.StatusConflict
+	case errors.Is(err, game.ErrWordMissing):
+		status = http.StatusNotFound
+	}
+	if status == http.StatusInternalServerError {
+		util.LogWarn("Failed to edit the word list: %v", err)
+		c.JSON(status, gin.H{"error": "failed to edit the word list"})
+		return
+	}
+	c.JSON(status, gin.H{"error": err.Error()})
+}
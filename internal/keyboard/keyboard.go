@@ -0,0 +1,84 @@
+// Package keyboard is the registry of on-screen keyboard layouts. The
+// keyboard partial and the letter heatmap both draw their keys from it.
+package keyboard
+
+import (
+	"slices"
+
+	constants "github.com/CodeAndHammer/vortludo/internal/constants"
+)
+
+// Layout is a keyboard's letter keys, row by row. Enter and Backspace are
+// drawn at the ends of the last row.
+type Layout struct {
+	Name  string     `json:"name"`
+	Label string     `json:"label"`
+	Rows  [][]string `json:"rows"`
+}
+
+// Has reports whether the layout has a key for letter.
+func (l Layout) Has(letter string) bool {
+	return slices.ContainsFunc(l.Rows, func(row []string) bool { return slices.Contains(row, letter) })
+}
+
+// IsLastRow reports whether row i is the last, which Enter and Backspace
+// flank.
+func (l Layout) IsLastRow(i int) bool {
+	return i == len(l.Rows)-1
+}
+
+var layouts = []Layout{
+	{
+		Name:  constants.KeyboardLayoutQwerty,
+		Label: "QWERTY",
+		Rows: [][]string{
+			{"Q", "W", "E", "R", "T", "Y", "U", "I", "O", "P"},
+			{"A", "S", "D", "F", "G", "H", "J", "K", "L"},
+			{"Z", "X", "C", "V", "B", "N", "M"},
+		},
+	},
+	{
+		Name:  constants.KeyboardLayoutAzerty,
+		Label: "AZERTY",
+		Rows: [][]string{
+			{"A", "Z", "E", "R", "T", "Y", "U", "I", "O", "P"},
+			{"Q", "S", "D", "F", "G", "H", "J", "K", "L", "M"},
+			{"W", "X", "C", "V", "B", "N"},
+		},
+	},
+	{
+		// The Esperanto alphabet has no Q, W, X or Y; as on the common
+		// Esperanto keyboard, its accented letters take their keys.
+		Name:  constants.KeyboardLayoutEsperanto,
+		Label: "Esperanto",
+		Rows: [][]string{
+			{"Ŝ", "Ĝ", "E", "R", "T", "Ŭ", "U", "I", "O", "P", "Ĵ", "Ĥ"},
+			{"A", "S", "D", "F", "G", "H", "J", "K", "L"},
+			{"Z", "Ĉ", "C", "V", "B", "N", "M"},
+		},
+	},
+}
+
+// All returns the layouts in the order the settings offer them.
+func All() []Layout {
+	return slices.Clone(layouts)
+}
+
+// Names returns the names of the layouts.
+func Names() []string {
+	names := make([]string, len(layouts))
+	for i, layout := range layouts {
+		names[i] = layout.Name
+	}
+	return names
+}
+
+// Lookup returns the named layout, or QWERTY if there is none by that name.
+func Lookup(name string) Layout {
+	for _, layout := range layouts {
+		if layout.Name == name {
+			return layout
+		}
+	}
+	return layouts[0]
+}
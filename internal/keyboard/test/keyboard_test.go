@@ -0,0 +1,45 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	constants "github.com/CodeAndHammer/vortludo/internal/constants"
+	keyboard "github.com/CodeAndHammer/vortludo/internal/keyboard"
+)
+
+func TestLayoutsHaveEachLetterOnce(t *testing.T) {
+	alphabets := map[string]string{
+		constants.KeyboardLayoutQwerty:    "ABCDEFGHIJKLMNOPQRSTUVWXYZ",
+		constants.KeyboardLayoutAzerty:    "ABCDEFGHIJKLMNOPQRSTUVWXYZ",
+		constants.KeyboardLayoutEsperanto: "ABCĈDEFGĜHĤIJĴKLMNOPRSŜTUŬVZ",
+	}
+	for _, layout := range keyboard.All() {
+		var keys []string
+		for _, row := range layout.Rows {
+			keys = append(keys, row...)
+		}
+		slices.Sort(keys)
+		want := strings.Split(alphabets[layout.Name], "")
+		slices.Sort(want)
+		if !slices.Equal(keys, want) {
+			t.Errorf("Layout %s has keys %v, want %v", layout.Name, keys, want)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	if got := keyboard.Lookup(constants.KeyboardLayoutEsperanto); !got.Has("Ŝ") || got.Has("Q") {
+		t.Errorf("Expected the Esperanto layout to swap Q for Ŝ, got %v", got.Rows)
+	}
+	if got := keyboard.Lookup("dvorak"); got.Name != constants.KeyboardLayoutQwerty {
+		t.Errorf("Expected unknown layouts to fall back to QWERTY, got %s", got.Name)
+	}
+	if !slices.Contains(keyboard.Names(), constants.KeyboardLayoutAzerty) {
+		t.Errorf("Expected AZERTY among %v", keyboard.Names())
+	}
+	if layout := keyboard.Lookup(constants.KeyboardLayoutQwerty); !layout.IsLastRow(len(layout.Rows)-1) || layout.IsLastRow(0) {
+		t.Error("IsLastRow should only hold for the last row")
+	}
+}
@@ -0,0 +1,123 @@
+// Package lifecycle runs the server's background routines, such as cleanups
+// and watchers, under one context so that shutdown can stop them and wait
+// for them to finish.
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	util "github.com/CodeAndHammer/vortludo/internal/util"
+	"golang.org/x/sync/errgroup"
+)
+
+// Supervisor owns a set of background routines. A routine that returns an
+// error stops the others; Stop reports the first such error.
+type Supervisor struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	group  *errgroup.Group
+}
+
+func New(ctx context.Context) *Supervisor {
+	ctx, cancel := context.WithCancel(ctx)
+	group, ctx := errgroup.WithContext(ctx)
+	return &Supervisor{ctx: ctx, cancel: cancel, group: group}
+}
+
+// Go runs fn until its context is done. fn should return nil when it stops
+// because of the context.
+func (s *Supervisor) Go(name string, fn func(ctx context.Context) error) {
+	s.group.Go(func() error {
+		if err := fn(s.ctx); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		return nil
+	})
+}
+
+// Every runs fn once per interval until the supervisor stops. A run in
+// progress is waited for.
+func (s *Supervisor) Every(name string, interval time.Duration, fn func(ctx context.Context)) {
+	s.Go(name, func(ctx context.Context) error {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+				fn(ctx)
+			}
+		}
+	})
+	util.LogInfo("Started %s (every %s)", name, interval)
+}
+
+// Jittered runs fn repeatedly until the supervisor stops, waiting interval
+// plus a random part of jitter before each run, so that instances started
+// together spread their work out. A run in progress is waited for.
+func (s *Supervisor) Jittered(name string, interval, jitter time.Duration, fn func(ctx context.Context)) {
+	s.Go(name, func(ctx context.Context) error {
+		for {
+			wait := interval
+			if jitter > 0 {
+				wait += rand.N(jitter)
+			}
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil
+			case <-timer.C:
+				fn(ctx)
+			}
+		}
+	})
+	util.LogInfo("Started %s (every %s, up to %s later)", name, interval, jitter)
+}
+
+// Daily runs fn once a day, offset past midnight UTC, until the supervisor
+// stops. A run in progress is waited for.
+func (s *Supervisor) Daily(name string, offset time.Duration, fn func(ctx context.Context)) {
+	s.Go(name, func(ctx context.Context) error {
+		for {
+			timer := time.NewTimer(time.Until(NextDaily(time.Now(), offset)))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil
+			case <-timer.C:
+				fn(ctx)
+			}
+		}
+	})
+	util.LogInfo("Started %s (daily, %s past midnight UTC)", name, offset)
+}
+
+// NextDaily returns the first time after now that is offset past a midnight
+// UTC.
+func NextDaily(now time.Time, offset time.Duration) time.Time {
+	now = now.UTC()
+	next := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).Add(offset)
+	for !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// Stop cancels the routines and waits for them to return, or for ctx to be
+// done.
+func (s *Supervisor) Stop(ctx context.Context) error {
+	s.cancel()
+	done := make(chan error, 1)
+	go func() { done <- s.group.Wait() }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("background routines did not stop: %w", ctx.Err())
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	lifecycle "github.com/CodeAndHammer/vortludo/internal/lifecycle"
+)
+
+func TestStopWaitsForRoutines(t *testing.T) {
+	sup := lifecycle.New(context.Background())
+	var runs, finished atomic.Int32
+	sup.Every("ticker", time.Millisecond, func(ctx context.Context) {
+		runs.Add(1)
+	})
+	sup.Go("worker", func(ctx context.Context) error {
+		<-ctx.Done()
+		time.Sleep(10 * time.Millisecond)
+		finished.Store(1)
+		return nil
+	})
+	time.Sleep(20 * time.Millisecond)
+
+	if err := sup.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	if finished.Load() != 1 {
+		t.Error("Stop should wait for routines to return")
+	}
+	after := runs.Load()
+	if after == 0 {
+		t.Error("The ticker should have run")
+	}
+	time.Sleep(5 * time.Millisecond)
+	if runs.Load() != after {
+		t.Error("The ticker should not run after Stop")
+	}
+}
+
+func TestFailingRoutineStopsOthers(t *testing.T) {
+	sup := lifecycle.New(context.Background())
+	stopped := make(chan struct{})
+	sup.Go("watcher", func(ctx context.Context) error {
+		<-ctx.Done()
+		close(stopped)
+		return nil
+	})
+	failure := errors.New("boom")
+	sup.Go("broken", func(context.Context) error { return failure })
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("A failing routine should stop the others")
+	}
+	if err := sup.Stop(context.Background()); !errors.Is(err, failure) || err.Error() != "broken: boom" {
+		t.Errorf("Stop should report the failure, got %v", err)
+	}
+}
+
+func TestStopGivesUp(t *testing.T) {
+	sup := lifecycle.New(context.Background())
+	release := make(chan struct{})
+	defer close(release)
+	sup.Go("stuck", func(context.Context) error {
+		<-release
+		return nil
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := sup.Stop(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Stop should give up when ctx is done, got %v", err)
+	}
+}
+
+func TestNextDaily(t *testing.T) {
+	offset := 5 * time.Minute
+	for _, tc := range []struct{ now, want time.Time }{
+		{time.Date(2026, 10, 15, 23, 0, 0, 0, time.UTC), time.Date(2026, 10, 16, 0, 5, 0, 0, time.UTC)},
+		{time.Date(2026, 10, 16, 0, 1, 0, 0, time.UTC), time.Date(2026, 10, 16, 0, 5, 0, 0, time.UTC)},
+		{time.Date(2026, 10, 16, 0, 5, 0, 0, time.UTC), time.Date(2026, 10, 17, 0, 5, 0, 0, time.UTC)},
+		{time.Date(2026, 10, 16, 1, 0, 0, 0, time.FixedZone("CEST", 2*3600)), time.Date(2026, 10, 16, 0, 5, 0, 0, time.UTC)},
+	} {
+		if got := lifecycle.NextDaily(tc.now, offset); !got.Equal(tc.want) {
+			t.Errorf("NextDaily(%s) = %s, want %s", tc.now, got, tc.want)
+		}
+	}
+}
+
+func TestJitteredRuns(t *testing.T) {
+	sup := lifecycle.New(context.Background())
+	var runs atomic.Int32
+	sup.Jittered("sweeper", time.Millisecond, time.Millisecond, func(context.Context) {
+		runs.Add(1)
+	})
+	time.Sleep(30 * time.Millisecond)
+	if err := sup.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	if runs.Load() == 0 {
+		t.Error("The jittered routine should have run")
+	}
+}
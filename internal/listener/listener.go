@@ -0,0 +1,315 @@
+// Package listener runs the HTTP listeners. It can terminate TLS itself,
+// with a certificate from disk or one obtained from an ACME CA such as
+// Let's Encrypt, and redirect plain HTTP to HTTPS, so small deployments
+// need no reverse proxy.
+package listener
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	util "github.com/CodeAndHammer/vortludo/internal/util"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+const (
+	readHeaderTimeout = 10 * time.Second
+	httpsPort         = "443"
+
+	DefaultSocketMode = 0o660
+	// listenFDsStart is the first file descriptor systemd passes.
+	listenFDsStart = 3
+)
+
+// Config configures the listeners. With neither certificate files nor ACME
+// hosts the server speaks plain HTTP.
+type Config struct {
+	Addr string
+	// CertFile and KeyFile are a PEM certificate and its key.
+	CertFile string
+	KeyFile  string
+	// ACMEHosts are the host names to obtain certificates for. Certificates
+	// are never requested for other names.
+	ACMEHosts []string
+	// ACMECacheDir keeps obtained certificates across restarts.
+	ACMECacheDir string
+	ACMEEmail    string
+	// ACMEDirectory is the CA's directory URL; empty means Let's Encrypt.
+	ACMEDirectory string
+	// RedirectAddr is where plain HTTP requests are answered with a redirect
+	// to HTTPS, and ACME HTTP challenges are served. Empty disables it.
+	RedirectAddr string
+	// Socket is a Unix socket to listen on instead of Addr, created with
+	// SocketMode. A socket passed by systemd takes precedence over both.
+	Socket     string
+	SocketMode os.FileMode
+	// HTTP2 enables HTTP/2 over TLS. H2C enables it without TLS, for a proxy
+	// that speaks HTTP/2 to its backends.
+	HTTP2 bool
+	H2C   bool
+}
+
+// TLS reports whether the server terminates TLS.
+func (c Config) TLS() bool {
+	return c.CertFile != "" || c.KeyFile != "" || len(c.ACMEHosts) > 0
+}
+
+func (c Config) validate() error {
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return errors.New("a TLS certificate needs both a certificate and a key file")
+	}
+	if c.CertFile != "" && len(c.ACMEHosts) > 0 {
+		return errors.New("use either a TLS certificate or ACME, not both")
+	}
+	if len(c.ACMEHosts) > 0 && c.ACMECacheDir == "" {
+		return errors.New("ACME needs a cache directory")
+	}
+	return nil
+}
+
+// Server is an http.Server, plus the redirect server when TLS is on.
+type Server struct {
+	*http.Server
+	cfg      Config
+	redirect *http.Server
+}
+
+func New(handler http.Handler, cfg Config) (*Server, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	var protocols http.Protocols
+	protocols.SetHTTP1(true)
+	protocols.SetHTTP2(cfg.HTTP2)
+	protocols.SetUnencryptedHTTP2(cfg.H2C && !cfg.TLS())
+	if cfg.SocketMode == 0 {
+		cfg.SocketMode = DefaultSocketMode
+	}
+	s := &Server{
+		Server: &http.Server{
+			Addr:              cfg.Addr,
+			Handler:           localPeer(handler),
+			ReadHeaderTimeout: readHeaderTimeout,
+			Protocols:         &protocols,
+		},
+		cfg: cfg,
+	}
+	if !cfg.TLS() {
+		return s, nil
+	}
+
+	s.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	redirect := http.Handler(http.HandlerFunc(s.redirectToHTTPS))
+	if len(cfg.ACMEHosts) > 0 {
+		manager := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			Cache:      autocert.DirCache(cfg.ACMECacheDir),
+			HostPolicy: autocert.HostWhitelist(cfg.ACMEHosts...),
+			Email:      cfg.ACMEEmail,
+		}
+		if cfg.ACMEDirectory != "" {
+			manager.Client = &acme.Client{DirectoryURL: cfg.ACMEDirectory}
+		}
+		s.TLSConfig = manager.TLSConfig()
+		s.TLSConfig.MinVersion = tls.VersionTLS12
+		redirect = manager.HTTPHandler(redirect)
+	}
+	if cfg.RedirectAddr != "" {
+		s.redirect = &http.Server{
+			Addr:              cfg.RedirectAddr,
+			Handler:           redirect,
+			ReadHeaderTimeout: readHeaderTimeout,
+		}
+	}
+	return s, nil
+}
+
+// RedirectHandler returns the handler of the redirect server, or nil when
+// there is none.
+func (s *Server) RedirectHandler() http.Handler {
+	if s.redirect == nil {
+		return nil
+	}
+	return s.redirect.Handler
+}
+
+// ListenAndServe serves until the server is shut down. The redirect server
+// failing to start is logged rather than fatal, as HTTPS still works.
+func (s *Server) ListenAndServe() error {
+	l, err := s.listen()
+	if err != nil {
+		return err
+	}
+	if !s.cfg.TLS() {
+		return s.Serve(l)
+	}
+	if s.redirect != nil {
+		go func() {
+			util.LogInfo("Redirecting HTTP on %s to HTTPS", s.redirect.Addr)
+			if err := s.redirect.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				util.LogWarn("HTTP redirect server exited: %v", err)
+			}
+		}()
+	}
+	return s.ServeTLS(l, s.cfg.CertFile, s.cfg.KeyFile)
+}
+
+// listen opens the socket systemd passed, the Unix socket or the TCP address,
+// in that order of preference.
+func (s *Server) listen() (net.Listener, error) {
+	if l, err := systemdListener(); l != nil || err != nil {
+		return l, err
+	}
+	if s.cfg.Socket == "" {
+		return net.Listen("tcp", s.Addr)
+	}
+	if err := removeStaleSocket(s.cfg.Socket); err != nil {
+		return nil, err
+	}
+	l, err := net.Listen("unix", s.cfg.Socket)
+	if err != nil {
+		return nil, err
+	}
+	// The socket file is removed when the listener is closed on shutdown.
+	if err := os.Chmod(s.cfg.Socket, s.cfg.SocketMode); err != nil {
+		l.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+// removeStaleSocket removes a socket file left behind by a server that did
+// not shut down cleanly. A socket still being served is left alone.
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Mode().Type() != fs.ModeSocket {
+		return fmt.Errorf("%s exists and is not a socket", path)
+	}
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		conn.Close()
+		return fmt.Errorf("%s is in use by another server", path)
+	}
+	return os.Remove(path)
+}
+
+// systemdActivated reports whether systemd passed this process sockets.
+func systemdActivated() bool {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return false
+	}
+	fds, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	return err == nil && fds > 0
+}
+
+// systemdListener adopts the first socket systemd passed, if any. The
+// variables passing it are cleared so child processes don't adopt it too.
+func systemdListener() (net.Listener, error) {
+	if !systemdActivated() {
+		return nil, nil
+	}
+	if fds, _ := strconv.Atoi(os.Getenv("LISTEN_FDS")); fds > 1 {
+		util.LogWarn("systemd passed %d sockets; serving only the first", fds)
+	}
+	for _, key := range []string{"LISTEN_PID", "LISTEN_FDS", "LISTEN_FDNAMES"} {
+		os.Unsetenv(key)
+	}
+	f := os.NewFile(listenFDsStart, "systemd socket")
+	defer f.Close()
+	l, err := net.FileListener(f)
+	if err != nil {
+		return nil, fmt.Errorf("adopt systemd socket: %w", err)
+	}
+	return l, nil
+}
+
+// localPeer gives requests arriving over a Unix socket, which have no peer
+// address, a loopback one. They come from a local proxy, and are then
+// trusted to carry the client's address in X-Forwarded-For as over TCP.
+func localPeer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, _, err := net.SplitHostPort(r.RemoteAddr); err != nil {
+			r.RemoteAddr = "127.0.0.1:0"
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) Shutdown(ctx context.Context) error {
+	var redirectErr error
+	if s.redirect != nil {
+		redirectErr = s.redirect.Shutdown(ctx)
+	}
+	return errors.Join(s.Server.Shutdown(ctx), redirectErr)
+}
+
+// Describe summarizes how the server is reached, for the startup log.
+func (s *Server) Describe() string {
+	var where, how string
+	switch {
+	case systemdActivated():
+		where = "the systemd socket"
+	case s.cfg.Socket != "":
+		where = "unix socket " + s.cfg.Socket
+	default:
+		where = s.Addr
+	}
+	switch {
+	case len(s.cfg.ACMEHosts) > 0:
+		how = "HTTPS with ACME certificates for " + strings.Join(s.cfg.ACMEHosts, ", ")
+	case s.cfg.TLS():
+		how = "HTTPS with certificate " + s.cfg.CertFile
+	default:
+		how = "HTTP"
+	}
+	description := where + " over " + how
+	if s.cfg.TLS() && s.Protocols.HTTP2() || s.Protocols.UnencryptedHTTP2() {
+		description += ", HTTP/2 enabled"
+	}
+	return description
+}
+
+// redirectToHTTPS sends a request to the same URL over HTTPS. Requests for
+// hosts outside the ACME whitelist are refused rather than redirected.
+func (s *Server) redirectToHTTPS(w http.ResponseWriter, r *http.Request) {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	if host == "" || len(s.cfg.ACMEHosts) > 0 && !slices.Contains(s.cfg.ACMEHosts, strings.ToLower(host)) {
+		http.Error(w, "Unknown host", http.StatusMisdirectedRequest)
+		return
+	}
+	port := httpsPort
+	if _, p, err := net.SplitHostPort(s.Addr); err == nil && p != "" {
+		port = p
+	}
+	if port != httpsPort {
+		host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	code := http.StatusMovedPermanently
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		code = http.StatusPermanentRedirect
+	}
+	http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), code)
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	listener "github.com/CodeAndHammer/vortludo/internal/listener"
+)
+
+func TestConfigValidation(t *testing.T) {
+	invalid := []listener.Config{
+		{CertFile: "cert.pem"},
+		{KeyFile: "key.pem"},
+		{CertFile: "cert.pem", KeyFile: "key.pem", ACMEHosts: []string{"example.com"}},
+		{ACMEHosts: []string{"example.com"}},
+	}
+	for _, cfg := range invalid {
+		if _, err := listener.New(http.NotFoundHandler(), cfg); err == nil {
+			t.Errorf("New(%+v) should fail", cfg)
+		}
+	}
+
+	srv, err := listener.New(http.NotFoundHandler(), listener.Config{Addr: ":8080", HTTP2: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if srv.TLSConfig != nil || srv.RedirectHandler() != nil {
+		t.Error("A plain HTTP server should have no TLS nor redirect")
+	}
+	if srv.Protocols.UnencryptedHTTP2() {
+		t.Error("h2c should be off unless asked for")
+	}
+}
+
+func TestRedirectToHTTPS(t *testing.T) {
+	srv, err := listener.New(http.NotFoundHandler(), listener.Config{
+		Addr: ":443", CertFile: "cert.pem", KeyFile: "key.pem", RedirectAddr: ":80",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	redirect := srv.RedirectHandler()
+	if redirect == nil {
+		t.Fatal("Expected a redirect server")
+	}
+
+	cases := []struct {
+		method, target, host string
+		code                 int
+		location             string
+	}{
+		{http.MethodGet, "/stats?x=1", "play.example.com", http.StatusMovedPermanently, "https://play.example.com/stats?x=1"},
+		{http.MethodGet, "/", "play.example.com:80", http.StatusMovedPermanently, "https://play.example.com/"},
+		{http.MethodPost, "/guess", "[::1]:80", http.StatusPermanentRedirect, "https://[::1]/guess"},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(tc.method, tc.target, nil)
+		req.Host = tc.host
+		w := httptest.NewRecorder()
+		redirect.ServeHTTP(w, req)
+		if w.Code != tc.code || w.Header().Get("Location") != tc.location {
+			t.Errorf("%s %s on %s: got %d %q, want %d %q", tc.method, tc.target, tc.host, w.Code, w.Header().Get("Location"), tc.code, tc.location)
+		}
+	}
+}
+
+func TestRedirectKeepsPortAndWhitelist(t *testing.T) {
+	srv, err := listener.New(http.NotFoundHandler(), listener.Config{
+		Addr: ":8443", ACMEHosts: []string{"play.example.com"}, ACMECacheDir: t.TempDir(), RedirectAddr: ":8080",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Host = "Play.Example.com:8080"
+	w := httptest.NewRecorder()
+	srv.RedirectHandler().ServeHTTP(w, req)
+	if w.Header().Get("Location") != "https://Play.Example.com:8443/" {
+		t.Errorf("Expected a redirect to the HTTPS port, got %d %q", w.Code, w.Header().Get("Location"))
+	}
+
+	req.Host = "evil.example.net"
+	w = httptest.NewRecorder()
+	srv.RedirectHandler().ServeHTTP(w, req)
+	if w.Code != http.StatusMisdirectedRequest {
+		t.Errorf("Hosts outside the whitelist should not be redirected, got %d", w.Code)
+	}
+}
+
+func TestUnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vortludo.sock")
+	// A socket left behind by a crashed server is replaced.
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	var remoteAddr string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { remoteAddr = r.RemoteAddr })
+	srv, err := listener.New(handler, listener.Config{Socket: path, SocketMode: 0o600})
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- srv.ListenAndServe() }()
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", path)
+		},
+	}}
+	var resp *http.Response
+	for range 50 {
+		if resp, err = client.Get("http://vortludo/"); err == nil {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("Request over the socket failed: %v", err)
+	}
+	resp.Body.Close()
+	if remoteAddr != "127.0.0.1:0" {
+		t.Errorf("Requests over the socket should get a loopback peer, got %q", remoteAddr)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected the socket with mode 0600, got %v, %v", info, err)
+	}
+
+	second, _ := listener.New(handler, listener.Config{Socket: path})
+	if err := second.ListenAndServe(); err == nil {
+		t.Error("A socket in use should not be taken over")
+	}
+
+	if err := srv.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; !errors.Is(err, http.ErrServerClosed) {
+		t.Errorf("Expected ErrServerClosed, got %v", err)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("The socket should be removed on shutdown, got %v", err)
+	}
+}
+
+func TestSocketPathNotASocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	srv, err := listener.New(http.NotFoundHandler(), listener.Config{Socket: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.ListenAndServe(); err == nil {
+		t.Error("A regular file should not be replaced by the socket")
+	}
+}
@@ -0,0 +1,359 @@
+// Package loadtest drives a running server the way browsers do: each
+// simulated player keeps its own session and CSRF cookies, guesses through
+// htmx requests, checks the board now and then and starts a new game when one
+// ends. It reports latency percentiles for each kind of request.
+package loadtest
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math/rand/v2"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	constants "github.com/CodeAndHammer/vortludo/internal/constants"
+)
+
+const (
+	DefaultConcurrency = 10
+	DefaultDuration    = 30 * time.Second
+	DefaultInvalidRate = 0.1
+	DefaultStateRate   = 0.2
+)
+
+// The operations a player performs, as named in reports.
+const (
+	OpHome      = "home"
+	OpGuess     = "guess"
+	OpGameState = "game-state"
+	OpNewGame   = "new-game"
+)
+
+var operations = []string{OpHome, OpGuess, OpGameState, OpNewGame}
+
+// invalidGuess is played for the share of guesses meant to be refused.
+const invalidGuess = "QXZQX"
+
+type Config struct {
+	// URL is the server's base URL, e.g. http://localhost:8080.
+	URL string
+	// Concurrency is how many players run at once, for Duration.
+	Concurrency int
+	Duration    time.Duration
+	// Words are the guesses players pick from; InvalidRate is the share of
+	// guesses that are not words at all.
+	Words       []string
+	InvalidRate float64
+	// StateRate is the share of turns spent reading the board rather than
+	// guessing.
+	StateRate float64
+	// Transport, if set, carries the requests.
+	Transport http.RoundTripper
+}
+
+// Stat summarises the requests of one operation. Errors counts failed
+// requests and 5xx responses; Rejected counts guesses the game refused,
+// e.g. as not in the word list. Latencies are in nanoseconds in JSON.
+type Stat struct {
+	Requests int           `json:"requests"`
+	Errors   int           `json:"errors"`
+	Rejected int           `json:"rejected"`
+	Statuses map[int]int   `json:"statuses"`
+	P50      time.Duration `json:"p50"`
+	P90      time.Duration `json:"p90"`
+	P99      time.Duration `json:"p99"`
+	Max      time.Duration `json:"max"`
+
+	latencies []time.Duration
+}
+
+type Report struct {
+	URL         string           `json:"url"`
+	Concurrency int              `json:"concurrency"`
+	Duration    time.Duration    `json:"duration"`
+	Requests    int              `json:"requests"`
+	Errors      int              `json:"errors"`
+	Throughput  float64          `json:"throughput"`
+	Operations  map[string]*Stat `json:"operations"`
+}
+
+// RateLimited reports whether the server refused any request for going too
+// fast, in which case the report measures its rate limiter.
+func (r *Report) RateLimited() bool {
+	for _, stat := range r.Operations {
+		if stat.Statuses[http.StatusTooManyRequests] > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// SlowestP99 returns the highest 99th percentile latency of any operation.
+func (r *Report) SlowestP99() time.Duration {
+	var slowest time.Duration
+	for _, stat := range r.Operations {
+		slowest = max(slowest, stat.P99)
+	}
+	return slowest
+}
+
+// Run plays against the server until cfg.Duration passes or ctx is done.
+func Run(ctx context.Context, cfg Config) (*Report, error) {
+	cfg.Concurrency = cmp.Or(cfg.Concurrency, DefaultConcurrency)
+	cfg.Duration = cmp.Or(cfg.Duration, DefaultDuration)
+	base, err := url.Parse(cfg.URL)
+	if err != nil || base.Scheme == "" || base.Host == "" {
+		return nil, fmt.Errorf("invalid server URL %q", cfg.URL)
+	}
+	if len(cfg.Words) == 0 {
+		return nil, errors.New("no words to guess")
+	}
+	if cfg.Transport == nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.MaxIdleConnsPerHost = cfg.Concurrency
+		cfg.Transport = transport
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.Duration)
+	defer cancel()
+	start := time.Now()
+	players := make([]*player, cfg.Concurrency)
+	var wg sync.WaitGroup
+	for i := range players {
+		jar, _ := cookiejar.New(nil)
+		players[i] = &player{
+			cfg:  cfg,
+			base: base,
+			client: &http.Client{
+				Transport: cfg.Transport,
+				Jar:       jar,
+				CheckRedirect: func(*http.Request, []*http.Request) error {
+					return http.ErrUseLastResponse
+				},
+			},
+			rand:  rand.New(rand.NewPCG(uint64(time.Now().UnixNano()), uint64(i))),
+			stats: make(map[string]*Stat),
+		}
+		wg.Go(func() { players[i].run(ctx) })
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	report := &Report{
+		URL:         cfg.URL,
+		Concurrency: cfg.Concurrency,
+		Duration:    elapsed,
+		Operations:  make(map[string]*Stat),
+	}
+	for _, p := range players {
+		for op, stat := range p.stats {
+			merged, ok := report.Operations[op]
+			if !ok {
+				merged = &Stat{Statuses: make(map[int]int)}
+				report.Operations[op] = merged
+			}
+			merged.Requests += stat.Requests
+			merged.Errors += stat.Errors
+			merged.Rejected += stat.Rejected
+			for status, n := range stat.Statuses {
+				merged.Statuses[status] += n
+			}
+			merged.latencies = append(merged.latencies, stat.latencies...)
+		}
+	}
+	for _, stat := range report.Operations {
+		stat.summarise()
+		report.Requests += stat.Requests
+		report.Errors += stat.Errors
+	}
+	if elapsed > 0 {
+		report.Throughput = float64(report.Requests) / elapsed.Seconds()
+	}
+	return report, nil
+}
+
+func (s *Stat) summarise() {
+	slices.Sort(s.latencies)
+	s.P50 = percentile(s.latencies, 0.50)
+	s.P90 = percentile(s.latencies, 0.90)
+	s.P99 = percentile(s.latencies, 0.99)
+	if len(s.latencies) > 0 {
+		s.Max = s.latencies[len(s.latencies)-1]
+	}
+}
+
+// percentile returns the nearest-rank percentile of sorted latencies.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(p*float64(len(sorted))+0.5) - 1
+	return sorted[min(max(rank, 0), len(sorted)-1)]
+}
+
+type player struct {
+	cfg    Config
+	base   *url.URL
+	client *http.Client
+	rand   *rand.Rand
+	stats  map[string]*Stat
+	// csrf is the CSRF token of the last response that issued one.
+	csrf string
+}
+
+// result is what a player learns from a response.
+type result struct {
+	status    int
+	errorCode string
+	body      []byte
+}
+
+func (p *player) run(ctx context.Context) {
+	// The first page sets the session and CSRF cookies.
+	if _, err := p.do(ctx, OpHome, http.MethodGet, constants.RouteHome, nil); err != nil {
+		return
+	}
+	for ctx.Err() == nil {
+		if p.rand.Float64() < p.cfg.StateRate {
+			p.do(ctx, OpGameState, http.MethodGet, constants.RouteGameState, nil)
+			continue
+		}
+		guess := p.cfg.Words[p.rand.IntN(len(p.cfg.Words))]
+		if p.rand.Float64() < p.cfg.InvalidRate {
+			guess = invalidGuess
+		}
+		res, err := p.do(ctx, OpGuess, http.MethodPost, constants.RouteGuess, url.Values{
+			"guess":                       {guess},
+			constants.IdempotencyKeyField: {fmt.Sprintf("%x", p.rand.Uint64())},
+		})
+		if err != nil {
+			// Give a failing server a moment rather than spinning.
+			select {
+			case <-ctx.Done():
+			case <-time.After(100 * time.Millisecond):
+			}
+			continue
+		}
+		if res.errorCode == constants.ErrorCodeGameOver || res.errorCode == constants.ErrorCodeNoMoreGuesses ||
+			bytes.Contains(res.body, []byte(`id="game-summary"`)) {
+			p.do(ctx, OpNewGame, http.MethodPost, constants.RouteNewGame, url.Values{})
+		}
+	}
+}
+
+// do sends one request as a browser would and records how it went. Requests
+// cut short because the run ended are not recorded.
+func (p *player) do(ctx context.Context, op, method, path string, form url.Values) (result, error) {
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+	req, err := http.NewRequestWithContext(ctx, method, p.base.JoinPath(path).String(), body)
+	if err != nil {
+		return result{}, err
+	}
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set(constants.CSRFHeader, p.csrf)
+	}
+	if op != OpHome {
+		req.Header.Set("HX-Request", "true")
+	}
+
+	start := time.Now()
+	resp, err := p.client.Do(req)
+	var res result
+	if err == nil {
+		res.status = resp.StatusCode
+		if token := resp.Header.Get(constants.CSRFHeader); token != "" {
+			p.csrf = token
+		}
+		res.body, err = io.ReadAll(resp.Body)
+		resp.Body.Close()
+		res.errorCode = errorCode(resp.Header.Get("HX-Trigger"))
+	}
+	elapsed := time.Since(start)
+	if ctx.Err() != nil {
+		return result{}, ctx.Err()
+	}
+
+	stat, ok := p.stats[op]
+	if !ok {
+		stat = &Stat{Statuses: make(map[int]int)}
+		p.stats[op] = stat
+	}
+	stat.Requests++
+	stat.latencies = append(stat.latencies, elapsed)
+	if err != nil || res.status >= http.StatusInternalServerError {
+		stat.Errors++
+	}
+	if res.status != 0 {
+		stat.Statuses[res.status]++
+	}
+	if res.errorCode != "" {
+		stat.Rejected++
+	}
+	return res, err
+}
+
+// errorCode reads the game error code from an HX-Trigger header.
+func errorCode(trigger string) string {
+	if trigger == "" {
+		return ""
+	}
+	var events map[string]json.RawMessage
+	if json.Unmarshal([]byte(trigger), &events) != nil {
+		return ""
+	}
+	var code string
+	json.Unmarshal(events["server_error_code"], &code)
+	return code
+}
+
+// WriteText writes the report for reading in a terminal.
+func (r *Report) WriteText(w io.Writer) error {
+	b := bufio.NewWriter(w)
+	fmt.Fprintf(b, "%d players for %s against %s: %d requests (%.1f/s), %d errors\n\n",
+		r.Concurrency, r.Duration.Round(time.Millisecond), r.URL, r.Requests, r.Throughput, r.Errors)
+	fmt.Fprintf(b, "%-11s %9s %7s %9s %10s %10s %10s %10s\n", "operation", "requests", "errors", "rejected", "p50", "p90", "p99", "max")
+	for _, op := range operations {
+		stat, ok := r.Operations[op]
+		if !ok {
+			continue
+		}
+		fmt.Fprintf(b, "%-11s %9d %7d %9d %10s %10s %10s %10s\n", op, stat.Requests, stat.Errors, stat.Rejected,
+			round(stat.P50), round(stat.P90), round(stat.P99), round(stat.Max))
+	}
+
+	statuses := make(map[int]int)
+	for _, stat := range r.Operations {
+		for status, n := range stat.Statuses {
+			statuses[status] += n
+		}
+	}
+	items := make([]string, 0, len(statuses))
+	for _, status := range slices.Sorted(maps.Keys(statuses)) {
+		items = append(items, fmt.Sprintf("%d: %d", status, statuses[status]))
+	}
+	fmt.Fprintf(b, "\nStatuses: %s\n", strings.Join(items, ", "))
+	if r.RateLimited() {
+		fmt.Fprintln(b, "Some requests were rate limited; raise RATE_LIMIT_RPS and RATE_LIMIT_BURST on the server to measure it rather than its limiter.")
+	}
+	return b.Flush()
+}
+
+func round(d time.Duration) time.Duration {
+	return d.Round(10 * time.Microsecond)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	loadtest "github.com/CodeAndHammer/vortludo/internal/loadtest"
+)
+
+// fakeServer plays a game of three guesses per session and refuses guesses
+// without the session's CSRF token.
+func fakeServer(t *testing.T) *httptest.Server {
+	var mu sync.Mutex
+	guesses := make(map[string]int)
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		session, err := r.Cookie("session")
+		if err != nil {
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: r.RemoteAddr + time.Now().String(), Path: "/"})
+			w.Header().Set("X-CSRF-Token", "token")
+			return
+		}
+		if r.Method == http.MethodPost {
+			if r.Header.Get("X-CSRF-Token") != "token" {
+				t.Errorf("%s sent without the CSRF token", r.URL.Path)
+				http.Error(w, "invalid csrf token", http.StatusForbidden)
+				return
+			}
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.URL.Path {
+		case "/guess":
+			if r.PostFormValue("guess") == "QXZQX" {
+				w.Header().Set("HX-Trigger", `{"server_error_code":"word_not_accepted"}`)
+				return
+			}
+			guesses[session.Value]++
+			if guesses[session.Value] == 3 {
+				w.Write([]byte(`<div id="game-summary"></div>`))
+			}
+		case "/new-game":
+			guesses[session.Value] = 0
+			http.Redirect(w, r, "/", http.StatusSeeOther)
+		case "/game-state":
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestRun(t *testing.T) {
+	srv := fakeServer(t)
+	defer srv.Close()
+
+	report, err := loadtest.Run(context.Background(), loadtest.Config{
+		URL:         srv.URL,
+		Concurrency: 4,
+		Duration:    200 * time.Millisecond,
+		Words:       []string{"CRANE", "SLATE"},
+		InvalidRate: 0.2,
+		StateRate:   0.2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Operations[loadtest.OpHome].Requests != 4 {
+		t.Errorf("Each player should load the page once, got %+v", report.Operations[loadtest.OpHome])
+	}
+	guess, newGame := report.Operations[loadtest.OpGuess], report.Operations[loadtest.OpNewGame]
+	if guess == nil || newGame == nil || report.Operations[loadtest.OpGameState] == nil {
+		t.Fatalf("Expected every operation to run, got %v", report.Operations)
+	}
+	if guess.Rejected == 0 || newGame.Statuses[http.StatusSeeOther] != newGame.Requests {
+		t.Errorf("Unexpected guesses %+v and new games %+v", guess, newGame)
+	}
+	if report.Errors != 0 || report.Requests == 0 || report.Throughput <= 0 {
+		t.Errorf("Unexpected totals: %+v", report)
+	}
+	if guess.P50 > guess.P90 || guess.P90 > guess.P99 || guess.P99 > guess.Max || guess.Max == 0 {
+		t.Errorf("Percentiles out of order: %+v", guess)
+	}
+
+	var out bytes.Buffer
+	if err := report.WriteText(&out); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(out.Bytes(), []byte("guess")) || !bytes.Contains(out.Bytes(), []byte("Statuses: 200: ")) {
+		t.Errorf("Unexpected report:\n%s", out.String())
+	}
+}
+
+func TestRunRejectsBadConfig(t *testing.T) {
+	if _, err := loadtest.Run(context.Background(), loadtest.Config{URL: "localhost", Words: []string{"CRANE"}}); err == nil {
+		t.Error("A URL without a scheme should be rejected")
+	}
+	if _, err := loadtest.Run(context.Background(), loadtest.Config{URL: "http://localhost"}); err == nil {
+		t.Error("A run without words should be rejected")
+	}
+}
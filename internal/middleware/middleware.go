@@ -2,14 +2,26 @@ package middleware
 
 import (
 	"context"
-	"crypto/rand"
-	"fmt"
+	"crypto/subtle"
+	"errors"
+	"io"
+	"math"
 	"net/http"
+	"net/netip"
+	"runtime/debug"
+	"strconv"
 	"strings"
 	"time"
 
+	compress "github.com/CodeAndHammer/vortludo/internal/compress"
 	constants "github.com/CodeAndHammer/vortludo/internal/constants"
+	errreport "github.com/CodeAndHammer/vortludo/internal/errreport"
+	events "github.com/CodeAndHammer/vortludo/internal/events"
+	game "github.com/CodeAndHammer/vortludo/internal/game"
+	lifecycle "github.com/CodeAndHammer/vortludo/internal/lifecycle"
 	models "github.com/CodeAndHammer/vortludo/internal/models"
+	security "github.com/CodeAndHammer/vortludo/internal/security"
+	session "github.com/CodeAndHammer/vortludo/internal/session"
 	util "github.com/CodeAndHammer/vortludo/internal/util"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -21,41 +33,135 @@ type rateLimiterEntry struct {
 	lastAccessTime time.Time
 }
 
-var cspTemplate = "default-src 'self'; script-src 'self' https://cdn.jsdelivr.net https://cdn.jsdelivr.net/npm 'unsafe-inline' 'unsafe-eval'; style-src 'self' https://cdn.jsdelivr.net https://fonts.bunny.net 'unsafe-inline'; font-src 'self' https://cdn.jsdelivr.net https://fonts.bunny.net; img-src 'self' data:; connect-src 'self' https://cdn.jsdelivr.net; object-src 'none'; base-uri 'self'; form-action 'self'; frame-ancestors 'none';"
+// RecoveryMiddleware recovers from a panic in a later handler. It logs the
+// panic and its stack with the request's ID, hands them to reporter, tagged
+// with a hash of the session, and answers through internalError instead of
+// gin's blank 500.
+func RecoveryMiddleware(reporter *errreport.Reporter, internalError gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			if recovered == http.ErrAbortHandler {
+				// The server's own way of dropping a connection.
+				panic(recovered)
+			}
+			stack := debug.Stack()
+			reqID, _ := c.Request.Context().Value(constants.RequestIDKey).(string)
+			sessionID, _ := c.Cookie(constants.SessionCookieName)
+			util.LogWarn("Panic recovered in request %s: %v\n%s", reqID, recovered, stack)
+			reporter.Report(errreport.NewReport(recovered, stack, c.Request, reqID, sessionID))
+			if c.Writer.Written() {
+				// Too late for an error page; cut the response short.
+				c.Abort()
+				return
+			}
+			internalError(c)
+			c.Abort()
+		}()
+		c.Next()
+	}
+}
 
-func RecoveryMiddleware() gin.HandlerFunc {
-	return gin.CustomRecovery(func(c *gin.Context, recovered interface{}) {
-		if err, ok := recovered.(string); ok {
-			util.LogWarn("Panic recovered: %s", err)
-		} else {
-			util.LogWarn("Panic recovered: %v", recovered)
+// TimeoutMiddleware gives each request the deadline of its route, which the
+// handler and the store and word selection calls it makes see through
+// c.Request.Context(). A request that runs past it without having answered
+// is answered by timedOut. Event streams have no deadline.
+func TimeoutMiddleware(app *models.App, timedOut gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		timeout := routeTimeout(app, strings.TrimPrefix(c.FullPath(), app.BasePath))
+		if timeout <= 0 {
+			c.Next()
+			return
 		}
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
-			"error": "Internal server error",
-		})
-	})
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+		if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return
+		}
+		reqID, _ := ctx.Value(constants.RequestIDKey).(string)
+		util.LogWarn("Request %s to %s ran past its %s deadline", reqID, c.Request.URL.Path, timeout)
+		if !c.Writer.Written() {
+			timedOut(c)
+			c.Abort()
+		}
+	}
+}
+
+// routePath returns the path of the request below app.BasePath, to compare
+// with the routes.
+func routePath(app *models.App, c *gin.Context) string {
+	return strings.TrimPrefix(c.Request.URL.Path, app.BasePath)
 }
 
-func SecurityHeadersMiddleware() gin.HandlerFunc {
+func routeTimeout(app *models.App, route string) time.Duration {
+	switch {
+	case route == constants.RouteAnnouncementEvents || route == constants.RouteWatch+"/:token/events":
+		return 0
+	case route == constants.RouteGuess || route == constants.RouteKey:
+		return app.GuessTimeout
+	case strings.HasPrefix(route, constants.RouteAdminPrefix+"/"):
+		return app.AdminTimeout
+	}
+	return app.RequestTimeout
+}
+
+// SecurityHeadersMiddleware sends app.Security's headers. When the policy
+// uses script nonces, the request's nonce is stored in the context under
+// constants.CSPNonceKey for the templates.
+func SecurityHeadersMiddleware(app *models.App) gin.HandlerFunc {
+	policy := app.Security
+	if policy == nil {
+		policy, _ = security.NewPolicy(security.Config{})
+	}
 	return func(c *gin.Context) {
-		scheme := "http"
-		if c.Request.TLS != nil {
-			scheme = "https"
+		nonce := ""
+		if policy.ScriptNonce() {
+			nonce = security.NewNonce()
+			c.Set(constants.CSPNonceKey, nonce)
 		}
-		origin := scheme + "://" + c.Request.Host
-		csp := strings.ReplaceAll(cspTemplate, "'self'", "'"+origin+"'")
-		c.Header("Content-Security-Policy", csp)
-		c.Header("X-Frame-Options", "DENY")
+		c.Header("Content-Security-Policy", policy.CSP(nonce))
+		c.Header("X-Frame-Options", policy.FrameOptions)
 		c.Header("X-Content-Type-Options", "nosniff")
-		c.Header("Referrer-Policy", "strict-origin-when-cross-origin")
+		c.Header("Referrer-Policy", policy.ReferrerPolicy)
 		if c.Request.TLS != nil {
-			c.Header("Strict-Transport-Security", "max-age=63072000; includeSubDomains; preload")
+			c.Header("Strict-Transport-Security", policy.HSTS)
 		}
 		c.Next()
 	}
 }
 
+// LimiterKey returns the rate limiter key for a client IP. IPv6 clients are
+// keyed by their network prefix, since a single host is usually handed a whole
+// /64; IPv4 addresses, including IPv4-mapped ones, are used as they are.
+func LimiterKey(ip string, ipv6PrefixLen int) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ip
+	}
+	addr = addr.Unmap()
+	if addr.Is4() {
+		return addr.String()
+	}
+	if ipv6PrefixLen <= 0 || ipv6PrefixLen > 128 {
+		ipv6PrefixLen = constants.IPv6PrefixLenDefault
+	}
+	prefix, err := addr.WithZone("").Prefix(ipv6PrefixLen)
+	if err != nil {
+		return addr.String()
+	}
+	return prefix.String()
+}
+
 func GetLimiter(app *models.App, key string) *rate.Limiter {
+	return getLimiter(app, key, app.RateLimitRPS, app.RateLimitBurst)
+}
+
+func getLimiter(app *models.App, key string, rps, burst int) *rate.Limiter {
 	app.LimiterMutex.RLock()
 	entry, ok := app.LimiterMap[key]
 	app.LimiterMutex.RUnlock()
@@ -76,32 +182,168 @@ func GetLimiter(app *models.App, key string) *rate.Limiter {
 	if key == "" || key == "::1" {
 		util.LogWarn("Rate limiter key is empty or loopback: %q", key)
 	}
-	rps := app.RateLimitRPS
 	if rps <= 0 {
 		rps = 1
 	}
-	limiter := rate.NewLimiter(rate.Every(time.Second/time.Duration(rps)), app.RateLimitBurst)
+	limiter := rate.NewLimiter(rate.Every(time.Second/time.Duration(rps)), burst)
 	app.LimiterMap[key] = &models.RateLimiterEntry{
 		Limiter:        limiter,
 		LastAccessTime: time.Now(),
 	}
+	app.LimitersCreated++
+	app.LimiterPeak = max(app.LimiterPeak, len(app.LimiterMap))
 	return limiter
 }
 
 func RateLimitMiddleware(app *models.App) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		key := c.ClientIP()
-		if !GetLimiter(app, key).Allow() {
-			if c.GetHeader("HX-Request") == "true" {
-				c.Header("HX-Trigger", "rate-limit-exceeded")
-			}
-			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests. Please slow down."})
+		key := LimiterKey(c.ClientIP(), app.IPv6PrefixLen)
+		if limiter := GetLimiter(app, key); !limiter.Allow() {
+			strike(app, c, "rate limit exceeded")
+			abortRateLimited(c, limiter)
 			return
 		}
 		c.Next()
 	}
 }
 
+// ScopedRateLimitMiddleware applies an extra limit to the routes it guards.
+// Clients get a separate bucket per scope, on top of the site-wide one.
+func ScopedRateLimitMiddleware(app *models.App, scope string, rps, burst int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := scope + ":" + LimiterKey(c.ClientIP(), app.IPv6PrefixLen)
+		if limiter := getLimiter(app, key, rps, burst); !limiter.Allow() {
+			strike(app, c, scope+" rate limit exceeded")
+			abortRateLimited(c, limiter)
+			return
+		}
+		c.Next()
+	}
+}
+
+// abortRateLimited rejects a request that limiter did not allow. The headers
+// tell the client when its bucket will allow a request again (Retry-After)
+// and when it will be full (X-RateLimit-Reset, in seconds). HTMX requests get
+// a notice fragment for the page's rate limit slot instead of a JSON body.
+func abortRateLimited(c *gin.Context, limiter *rate.Limiter) {
+	tokens := limiter.Tokens()
+	perSecond := float64(limiter.Limit())
+	retryAfter := max(1, int(math.Ceil((1-tokens)/perSecond)))
+	reset := max(1, int(math.Ceil((float64(limiter.Burst())-tokens)/perSecond)))
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	c.Header("X-RateLimit-Limit", strconv.Itoa(limiter.Burst()))
+	c.Header("X-RateLimit-Remaining", strconv.Itoa(max(0, int(tokens))))
+	c.Header("X-RateLimit-Reset", strconv.Itoa(reset))
+
+	if c.GetHeader("HX-Request") == "true" {
+		events.From(c).Flag(events.RateLimitExceeded)
+		c.Header("HX-Retarget", "#"+constants.RateLimitNoticeID)
+		c.Header("HX-Reswap", "innerHTML")
+		c.HTML(http.StatusTooManyRequests, "rate-limited", gin.H{"retry_after": retryAfter})
+		c.Abort()
+		return
+	}
+	gameErr := game.NewGameError(constants.ErrorCodeRateLimited).WithDetail("retry_after", retryAfter)
+	c.AbortWithStatusJSON(gameErr.Status, gin.H{"error": gameErr})
+}
+
+// AbuseMiddleware turns away clients banned by app.Abuse, by IP or by
+// session. Admin and health check routes are never blocked.
+func AbuseMiddleware(app *models.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		path := routePath(app, c)
+		if strings.HasPrefix(path, constants.RouteAdminPrefix+"/") || path == constants.RouteLivez || path == constants.RouteReadyz {
+			c.Next()
+			return
+		}
+		ban, banned := app.Abuse.Banned(time.Now(), abuseKeys(app, c)...)
+		if !banned {
+			c.Next()
+			return
+		}
+		retryAfter := max(1, int(math.Ceil(time.Until(ban.Until).Seconds())))
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		if c.GetHeader("HX-Request") == "true" {
+			c.Header("HX-Retarget", "#"+constants.RateLimitNoticeID)
+			c.Header("HX-Reswap", "innerHTML")
+			c.HTML(http.StatusTooManyRequests, "rate-limited", gin.H{"retry_after": retryAfter, "banned": true})
+			c.Abort()
+			return
+		}
+		gameErr := game.NewGameError(constants.ErrorCodeBanned).WithDetail("retry_after", retryAfter)
+		c.AbortWithStatusJSON(gameErr.Status, gin.H{"error": gameErr})
+	}
+}
+
+// SessionLimitMiddleware holds the sessions playing to app.MaxSessions. A
+// request from a session without a game, which would start one, goes on only
+// if session.Admit lets it in; otherwise serverFull answers it.
+func SessionLimitMiddleware(app *models.App, serverFull gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if session.Admit(app, session.CookieID(app, c)) {
+			c.Next()
+			return
+		}
+		c.Header("Retry-After", strconv.Itoa(int(constants.ServerFullRetryAfter.Seconds())))
+		serverFull(c)
+		c.Abort()
+	}
+}
+
+// BodyLimitMiddleware caps request bodies at app.MaxRequestBytes and the
+// fields in constants.FormFieldLimits at their lengths, before any handler
+// parses them. Requests over either limit are answered by tooLarge.
+func BodyLimitMiddleware(app *models.App, tooLarge gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.ContentLength > app.MaxRequestBytes {
+			abortTooLarge(c, tooLarge, "body of %d bytes", c.Request.ContentLength)
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, app.MaxRequestBytes)
+		var tooLong *http.MaxBytesError
+		if err := c.Request.ParseForm(); errors.As(err, &tooLong) {
+			abortTooLarge(c, tooLarge, "body over %d bytes", tooLong.Limit)
+			return
+		}
+		for field, limit := range constants.FormFieldLimits {
+			for _, value := range c.Request.Form[field] {
+				if len(value) > limit {
+					abortTooLarge(c, tooLarge, "%s field of %d bytes", field, len(value))
+					return
+				}
+			}
+		}
+		c.Next()
+	}
+}
+
+func abortTooLarge(c *gin.Context, tooLarge gin.HandlerFunc, format string, args ...any) {
+	util.LogWarn("Rejected request to %s from %s: "+format, append([]any{c.Request.URL.Path, c.ClientIP()}, args...)...)
+	c.Header("Connection", "close")
+	tooLarge(c)
+	c.Abort()
+}
+
+// abuseKeys identifies the client to app.Abuse: by IP, grouped like rate
+// limits, and by session if it has one.
+func abuseKeys(app *models.App, c *gin.Context) []string {
+	keys := []string{"ip:" + LimiterKey(c.ClientIP(), app.IPv6PrefixLen)}
+	if sessionID := session.CookieID(app, c); sessionID != "" {
+		keys = append(keys, "session:"+sessionID)
+	}
+	return keys
+}
+
+// strike counts an offence against the client, banning it once it has
+// offended too often.
+func strike(app *models.App, c *gin.Context, reason string) {
+	for _, key := range abuseKeys(app, c) {
+		if ban, ok := app.Abuse.Strike(key, reason, time.Now()); ok {
+			util.LogWarn("Banned %s until %s (ban #%d): %s", key, ban.Until.Format(time.RFC3339), ban.Level, reason)
+		}
+	}
+}
+
 func RequestIDMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		reqID := c.Request.Header.Get("X-Request-Id")
@@ -117,18 +359,19 @@ func RequestIDMiddleware() gin.HandlerFunc {
 
 func ValidateCSRFMiddleware(app *models.App) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if strings.HasPrefix(routePath(app, c), constants.RouteAdminPrefix+"/") {
+			// Admin endpoints authenticate with a bearer token, not cookies.
+			c.Next()
+			return
+		}
 		method := c.Request.Method
 		if method == http.MethodPost || method == http.MethodPut || method == http.MethodDelete || method == http.MethodPatch {
-			cookie, _ := c.Cookie("csrf_token")
-			header := c.GetHeader("X-CSRF-Token")
-			form := c.PostForm("csrf_token")
-			var token string
-			if header != "" {
-				token = header
-			} else if form != "" {
-				token = form
+			token := c.GetHeader(constants.CSRFHeader)
+			if token == "" {
+				token = c.PostForm(constants.CSRFFormField)
 			}
-			if token == "" || cookie == "" || token != cookie {
+			if !session.VerifyCSRF(app, c, token) {
+				strike(app, c, "invalid csrf token")
 				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "invalid csrf token"})
 				return
 			}
@@ -137,19 +380,42 @@ func ValidateCSRFMiddleware(app *models.App) gin.HandlerFunc {
 	}
 }
 
+// AdminAuthMiddleware requires "Authorization: Bearer <ADMIN_TOKEN>". Admin
+// routes respond 404 when no token is configured.
+func AdminAuthMiddleware(app *models.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if app.AdminToken == "" {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+		token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(app.AdminToken)) != 1 {
+			util.LogWarn("Rejected admin request from %s to %s", c.ClientIP(), c.Request.URL.Path)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// ThemeMiddleware stores the theme of the request's session, if it has one,
+// for pages to render in. It never creates a session.
+func ThemeMiddleware(app *models.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !strings.HasPrefix(routePath(app, c), constants.RouteStatic+"/") {
+			c.Set(constants.ThemeKey, session.GetSettings(app, session.CookieID(app, c)).Theme)
+		}
+		c.Next()
+	}
+}
+
+// CSRFMiddleware issues every response other than a static asset its own
+// CSRF token, in the X-CSRF-Token header and for templates to embed.
 func CSRFMiddleware(app *models.App) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		token, err := c.Cookie("csrf_token")
-		if err != nil || len(token) < 8 {
-			b := make([]byte, 32)
-			if _, err := rand.Read(b); err == nil {
-				token = fmt.Sprintf("%x", b)
-				secure := app.IsProduction
-				c.SetSameSite(http.SameSiteLaxMode)
-				c.SetCookie("csrf_token", token, int(app.CookieMaxAge.Seconds()), "/", "", secure, false)
-			}
+		if !strings.HasPrefix(routePath(app, c), constants.RouteStatic+"/") {
+			session.IssueCSRFToken(app, c)
 		}
-		c.Set("csrf_token", token)
 		c.Next()
 	}
 }
@@ -172,13 +438,116 @@ func CleanupExpiredLimiters(app *models.App) {
 	}
 }
 
-func StartLimiterCleanup(app *models.App) {
-	ticker := time.NewTicker(10 * time.Minute)
-	go func() {
-		defer ticker.Stop()
-		for range ticker.C {
-			CleanupExpiredLimiters(app)
+func StartLimiterCleanup(app *models.App, sup *lifecycle.Supervisor) {
+	sup.Every("rate limiter cleanup", 10*time.Minute, func(context.Context) {
+		CleanupExpiredLimiters(app)
+		app.Abuse.Cleanup(time.Now())
+	})
+}
+
+// CompressionMiddleware compresses responses with Brotli or gzip, whichever
+// the client prefers. Responses that already carry a Content-Encoding, such
+// as pre-compressed static files, are passed through, as are small ones and
+// types that do not compress.
+func CompressionMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		encoding := compress.Negotiate(c.GetHeader("Accept-Encoding"), compress.Encodings...)
+		if encoding == "" || c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+		w := &compressWriter{ResponseWriter: c.Writer, encoding: encoding}
+		c.Writer = w
+		defer w.close()
+		c.Next()
+	}
+}
+
+// compressWriter holds back the start of the body until it knows whether the
+// response is worth compressing, then either compresses it or passes it on.
+type compressWriter struct {
+	gin.ResponseWriter
+	encoding string
+	buf      []byte
+	decided  bool
+	encoder  io.WriteCloser
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if !w.decided {
+		w.buf = append(w.buf, b...)
+		if len(w.buf) < constants.CompressMinSize {
+			return len(b), nil
 		}
-	}()
-	util.LogInfo("Started rate limiter cleanup goroutine")
+		if err := w.decide(); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+	if w.encoder != nil {
+		return w.encoder.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// WriteHeaderNow is deferred, since the headers depend on the decision.
+func (w *compressWriter) WriteHeaderNow() {}
+
+func (w *compressWriter) Written() bool {
+	return w.decided || len(w.buf) > 0 || w.ResponseWriter.Written()
+}
+
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		w.decide()
+	}
+	if f, ok := w.encoder.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// decide sets the response headers for the buffered start of the body and
+// writes it out.
+func (w *compressWriter) decide() error {
+	w.decided = true
+	header := w.Header()
+	status := w.Status()
+	if len(w.buf) >= constants.CompressMinSize && header.Get("Content-Encoding") == "" &&
+		header.Get("Content-Range") == "" && status != http.StatusNoContent &&
+		status != http.StatusNotModified && status != http.StatusPartialContent &&
+		compress.Compressible(header.Get("Content-Type")) {
+		header.Set("Content-Encoding", w.encoding)
+		header.Add("Vary", "Accept-Encoding")
+		header.Del("Content-Length")
+		if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			header.Set("ETag", "W/"+etag)
+		}
+		w.encoder = compress.NewWriter(w.encoding, w.ResponseWriter)
+	}
+	w.ResponseWriter.WriteHeaderNow()
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	if w.encoder != nil {
+		_, err := w.encoder.Write(buf)
+		return err
+	}
+	_, err := w.ResponseWriter.Write(buf)
+	return err
+}
+
+func (w *compressWriter) close() {
+	if !w.decided {
+		w.decide()
+	}
+	if w.encoder != nil {
+		w.encoder.Close()
+	}
 }
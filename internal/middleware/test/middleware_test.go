@@ -0,0 +1,486 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"html/template"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	abuse "github.com/CodeAndHammer/vortludo/internal/abuse"
+	constants "github.com/CodeAndHammer/vortludo/internal/constants"
+	errreport "github.com/CodeAndHammer/vortludo/internal/errreport"
+	handlers "github.com/CodeAndHammer/vortludo/internal/handlers"
+	middleware "github.com/CodeAndHammer/vortludo/internal/middleware"
+	models "github.com/CodeAndHammer/vortludo/internal/models"
+	security "github.com/CodeAndHammer/vortludo/internal/security"
+	"github.com/gin-gonic/gin"
+)
+
+func TestLimiterKey(t *testing.T) {
+	tests := []struct {
+		ip     string
+		prefix int
+		want   string
+	}{
+		{"203.0.113.7", 64, "203.0.113.7"},
+		{"::ffff:203.0.113.7", 64, "203.0.113.7"},
+		{"2001:db8:1:2:aaaa:bbbb:cccc:dddd", 64, "2001:db8:1:2::/64"},
+		{"2001:db8:1:2::1", 64, "2001:db8:1:2::/64"},
+		{"2001:db8:1:2::1", 48, "2001:db8:1::/48"},
+		{"2001:db8:1:2::1", 128, "2001:db8:1:2::1/128"},
+		{"2001:db8:1:2::1", 0, "2001:db8:1:2::/64"},
+		{"fe80::1%eth0", 64, "fe80::/64"},
+		{"not-an-ip", 64, "not-an-ip"},
+	}
+	for _, tt := range tests {
+		if got := middleware.LimiterKey(tt.ip, tt.prefix); got != tt.want {
+			t.Errorf("LimiterKey(%q, %d) = %q, want %q", tt.ip, tt.prefix, got, tt.want)
+		}
+	}
+}
+
+func TestLimiterCardinality(t *testing.T) {
+	app := &models.App{
+		LimiterMap:     make(map[string]*models.RateLimiterEntry),
+		RateLimitRPS:   5,
+		RateLimitBurst: 10,
+	}
+	for _, ip := range []string{"2001:db8::1", "2001:db8::2", "2001:db8::ffff:1", "2001:db8:0:1::1", "198.51.100.1"} {
+		middleware.GetLimiter(app, middleware.LimiterKey(ip, 64))
+	}
+	if len(app.LimiterMap) != 3 {
+		t.Errorf("Expected 3 limiters for two /64s and one IPv4 host, got %d", len(app.LimiterMap))
+	}
+	if app.LimitersCreated != 3 || app.LimiterPeak != 3 {
+		t.Errorf("Expected 3 created and a peak of 3, got %d and %d", app.LimitersCreated, app.LimiterPeak)
+	}
+}
+
+func TestScopedRateLimitIsSeparate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	app := &models.App{LimiterMap: make(map[string]*models.RateLimiterEntry), RateLimitRPS: 100, RateLimitBurst: 100}
+	r := gin.New()
+	r.Use(middleware.RateLimitMiddleware(app))
+	r.GET("/validate", middleware.ScopedRateLimitMiddleware(app, "validate", 1, 2), func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/other", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	get := func(path string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = "203.0.113.7:1234"
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	for range 2 {
+		if code := get("/validate"); code != http.StatusOK {
+			t.Fatalf("Burst requests should pass, got %d", code)
+		}
+	}
+	if code := get("/validate"); code != http.StatusTooManyRequests {
+		t.Errorf("Scoped limit should reject the third request, got %d", code)
+	}
+	if code := get("/other"); code != http.StatusOK {
+		t.Errorf("Other routes should only use the site-wide limit, got %d", code)
+	}
+}
+
+func TestRateLimitedResponse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	app := &models.App{LimiterMap: make(map[string]*models.RateLimiterEntry), RateLimitRPS: 1, RateLimitBurst: 2}
+	r := gin.New()
+	r.SetHTMLTemplate(template.Must(template.New("rate-limited").Parse(`wait {{.retry_after}}`)))
+	r.Use(middleware.RateLimitMiddleware(app))
+	r.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	get := func(htmx bool) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = "203.0.113.8:1234"
+		if htmx {
+			req.Header.Set("HX-Request", "true")
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+	get(false)
+	get(false)
+
+	w := get(false)
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429, got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") != "1" || w.Header().Get("X-RateLimit-Remaining") != "0" || w.Header().Get("X-RateLimit-Reset") != "2" {
+		t.Errorf("Unexpected rate limit headers: %v", w.Header())
+	}
+	var body struct {
+		Error struct {
+			Code    string         `json:"code"`
+			Details map[string]int `json:"details"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body.Error.Code != "rate_limited" || body.Error.Details["retry_after"] != 1 {
+		t.Errorf("Unexpected JSON body %s: %v", w.Body, err)
+	}
+
+	w = get(true)
+	if w.Code != http.StatusTooManyRequests || w.Body.String() != "wait 1" {
+		t.Errorf("HTMX requests should get the notice fragment, got %d %q", w.Code, w.Body)
+	}
+	if w.Header().Get("HX-Retarget") != "#rate-limit-notice" || w.Header().Get("HX-Trigger") == "" {
+		t.Errorf("Unexpected HTMX headers: %v", w.Header())
+	}
+}
+
+func TestCompressionMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	page := strings.Repeat("<p>vortludo</p>", 200)
+	r := gin.New()
+	r.Use(middleware.CompressionMiddleware())
+	r.GET("/page", func(c *gin.Context) { c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(page)) })
+	r.GET("/small", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+	r.GET("/png", func(c *gin.Context) { c.Data(http.StatusOK, "image/png", []byte(page)) })
+
+	get := func(path, accept string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Accept-Encoding", accept)
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/page", "gzip")
+	if w.Header().Get("Content-Encoding") != "gzip" || w.Header().Get("Vary") != "Accept-Encoding" {
+		t.Fatalf("Expected a gzip response, got headers %v", w.Header())
+	}
+	zr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body, _ := io.ReadAll(zr); string(body) != page {
+		t.Error("Decompressed body differs from the page")
+	}
+	if w := get("/page", "br, gzip"); w.Header().Get("Content-Encoding") != "br" {
+		t.Errorf("Expected Brotli to be preferred, got %q", w.Header().Get("Content-Encoding"))
+	}
+	if w := get("/page", ""); w.Header().Get("Content-Encoding") != "" || w.Body.String() != page {
+		t.Error("Clients without Accept-Encoding should get the page as it is")
+	}
+	for _, path := range []string{"/small", "/png"} {
+		if w := get(path, "br"); w.Header().Get("Content-Encoding") != "" {
+			t.Errorf("%s should not be compressed", path)
+		}
+	}
+}
+
+func TestRepeatedRateLimitBans(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	app := &models.App{
+		LimiterMap:     make(map[string]*models.RateLimiterEntry),
+		RateLimitRPS:   1,
+		RateLimitBurst: 1,
+		Abuse:          abuse.NewTracker(abuse.Config{Threshold: 3}),
+	}
+	router := gin.New()
+	router.Use(middleware.AbuseMiddleware(app), middleware.RateLimitMiddleware(app))
+	router.GET("/", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+	router.GET("/livez", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+
+	codes := func(path string) string {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		var body struct {
+			Error struct {
+				Code string `json:"code"`
+			} `json:"error"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &body)
+		return body.Error.Code
+	}
+	var got []string
+	for range 5 {
+		got = append(got, codes("/"))
+	}
+	want := []string{"", "rate_limited", "rate_limited", "rate_limited", "temporarily_banned"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Got error codes %v, want %v", got, want)
+	}
+	if code := codes("/livez"); code == "temporarily_banned" {
+		t.Errorf("Health checks should not be banned, got %q", code)
+	}
+	if bans := app.Abuse.Bans(time.Now()); len(bans) != 1 || bans[0].Key != "ip:192.0.2.1" {
+		t.Errorf("Unexpected bans: %+v", bans)
+	}
+}
+
+func TestErrorPages(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	app := &models.App{}
+	r := gin.New()
+	r.HandleMethodNotAllowed = true
+	r.SetHTMLTemplate(template.Must(template.New("error.html").Parse(`{{.status}} {{.heading}} {{.request_id}}`)))
+	r.Use(middleware.RequestIDMiddleware(), middleware.RecoveryMiddleware(nil, func(c *gin.Context) { handlers.InternalErrorHandler(app, c) }))
+	r.NoRoute(func(c *gin.Context) { handlers.NotFoundHandler(app, c) })
+	r.NoMethod(func(c *gin.Context) { handlers.MethodNotAllowedHandler(app, c) })
+	r.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/boom", func(c *gin.Context) { panic("boom") })
+
+	do := func(method, path, accept string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+	errorCode := func(w *httptest.ResponseRecorder) string {
+		var body struct {
+			Error struct {
+				Code string `json:"code"`
+			} `json:"error"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &body)
+		return body.Error.Code
+	}
+
+	w := do(http.MethodGet, "/nope", "text/html")
+	if w.Code != http.StatusNotFound || !strings.HasPrefix(w.Body.String(), "404 Page not found") {
+		t.Errorf("Expected the 404 page, got %d %q", w.Code, w.Body)
+	}
+	if w = do(http.MethodGet, "/api/nope", ""); w.Code != http.StatusNotFound || errorCode(w) != "not_found" {
+		t.Errorf("API paths should get the JSON error, got %d %s", w.Code, w.Body)
+	}
+	if w = do(http.MethodGet, "/nope", "application/json"); errorCode(w) != "not_found" {
+		t.Errorf("JSON clients should get the JSON error, got %s", w.Body)
+	}
+
+	w = do(http.MethodPost, "/", "text/html")
+	if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") != http.MethodGet {
+		t.Errorf("Expected 405 allowing GET, got %d %v", w.Code, w.Header())
+	}
+
+	w = do(http.MethodGet, "/boom", "text/html")
+	reqID := w.Header().Get("X-Request-ID")
+	if w.Code != http.StatusInternalServerError || reqID == "" || !strings.HasSuffix(w.Body.String(), reqID) {
+		t.Errorf("Expected the 500 page with request ID %q, got %d %q", reqID, w.Code, w.Body)
+	}
+	if w.Header().Get("Cache-Control") != "no-store" {
+		t.Errorf("Error pages for panics should not be cached, got %q", w.Header().Get("Cache-Control"))
+	}
+}
+
+func TestSessionLimitMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	app := &models.App{MaxSessions: 1, SessionLimit: constants.SessionLimitReject}
+	app.Sessions.SetGame("sess-playing", &models.GameState{LastAccessTime: time.Now()})
+	r := gin.New()
+	r.SetHTMLTemplate(template.Must(template.New("error.html").Parse(`{{.status}} {{.heading}}`)))
+	r.GET("/", middleware.SessionLimitMiddleware(app, func(c *gin.Context) { handlers.ServerFullHandler(app, c) }),
+		func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	do := func(sessionID string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept", "text/html")
+		req.AddCookie(&http.Cookie{Name: constants.SessionCookieName, Value: sessionID})
+		r.ServeHTTP(w, req)
+		return w
+	}
+	if w := do("sess-playing"); w.Code != http.StatusOK {
+		t.Errorf("The playing session should get through, got %d", w.Code)
+	}
+	w := do("sess-new")
+	if w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), "Server full") {
+		t.Errorf("Expected the server full page, got %d %q", w.Code, w.Body)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("Expected a Retry-After header")
+	}
+}
+
+func TestRecoveryReportsPanics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	name := filepath.Join(t.TempDir(), "panics.jsonl")
+	file, err := errreport.OpenFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reporter := errreport.New(file)
+	r := gin.New()
+	r.SetHTMLTemplate(template.Must(template.New("server-error").Parse(`oops {{.request_id}}`)))
+	r.Use(middleware.RecoveryMiddleware(reporter, func(c *gin.Context) { handlers.InternalErrorHandler(&models.App{}, c) }), middleware.RequestIDMiddleware())
+	r.POST("/guess", func(c *gin.Context) { panic("boom") })
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/guess", nil)
+	req.Header.Set("HX-Request", "true")
+	req.AddCookie(&http.Cookie{Name: constants.SessionCookieName, Value: "sess-1"})
+	r.ServeHTTP(w, req)
+	reqID := w.Header().Get("X-Request-ID")
+	if w.Code != http.StatusInternalServerError || w.Body.String() != "oops "+reqID {
+		t.Errorf("Expected the error fragment, got %d %q", w.Code, w.Body)
+	}
+	if w.Header().Get("HX-Retarget") != "#"+constants.RateLimitNoticeID {
+		t.Errorf("Expected the fragment retargeted at the notice slot, got %v", w.Header())
+	}
+
+	reporter.Close(context.Background())
+	data, _ := os.ReadFile(name)
+	var report errreport.Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("Expected one report, got %q", data)
+	}
+	if report.RequestID != reqID || report.Session != errreport.HashSession("sess-1") || report.Panic != "boom" {
+		t.Errorf("Expected the report tagged with the request and session, got %+v", report)
+	}
+	if !strings.Contains(report.Stack, "middleware_test.go") {
+		t.Errorf("Expected the stack of the panic, got %s", report.Stack)
+	}
+}
+
+func TestTimeoutMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	app := &models.App{RequestTimeout: time.Minute, GuessTimeout: 10 * time.Millisecond, AdminTimeout: time.Hour}
+	r := gin.New()
+	r.Use(middleware.TimeoutMiddleware(app, func(c *gin.Context) { handlers.TimeoutHandler(app, c) }))
+	deadline := func(c *gin.Context) {
+		at, ok := c.Request.Context().Deadline()
+		if !ok {
+			c.String(http.StatusOK, "none")
+			return
+		}
+		c.String(http.StatusOK, time.Until(at).Round(time.Minute).String())
+	}
+	r.GET("/", deadline)
+	r.GET(constants.RouteAdminPrefix+constants.RouteAdminBans, deadline)
+	r.GET(constants.RouteAnnouncementEvents, deadline)
+	r.POST(constants.RouteGuess, func(c *gin.Context) { <-c.Request.Context().Done() })
+
+	do := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Accept", "application/json")
+		r.ServeHTTP(w, req)
+		return w
+	}
+	for path, want := range map[string]string{
+		"/": "1m0s",
+		constants.RouteAdminPrefix + constants.RouteAdminBans: "1h0m0s",
+		constants.RouteAnnouncementEvents:                     "none",
+	} {
+		if w := do(http.MethodGet, path); w.Body.String() != want {
+			t.Errorf("Expected a deadline of %s for %s, got %s", want, path, w.Body)
+		}
+	}
+	w := do(http.MethodPost, constants.RouteGuess)
+	if w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), constants.ErrorCodeTimeout) {
+		t.Errorf("Expected a timeout for a slow guess, got %d %s", w.Code, w.Body)
+	}
+}
+
+func TestBodyLimitMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	app := &models.App{MaxRequestBytes: constants.MaxRequestBytesDefault}
+	r := gin.New()
+	r.Use(middleware.BodyLimitMiddleware(app, func(c *gin.Context) { handlers.PayloadTooLargeHandler(app, c) }))
+	r.POST("/guess", func(c *gin.Context) { c.String(http.StatusOK, c.PostForm("guess")) })
+
+	do := func(body io.Reader, contentLength int64) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/guess", body)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Accept", "application/json")
+		req.ContentLength = contentLength
+		r.ServeHTTP(w, req)
+		return w
+	}
+	form := func(values url.Values) *httptest.ResponseRecorder {
+		body := values.Encode()
+		return do(strings.NewReader(body), int64(len(body)))
+	}
+
+	if w := form(url.Values{"guess": {"HELLO"}}); w.Code != http.StatusOK || w.Body.String() != "HELLO" {
+		t.Errorf("A small form should get through, got %d %q", w.Code, w.Body)
+	}
+	if w := form(url.Values{"guess": {strings.Repeat("A", 65)}}); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected 413 for a long guess, got %d", w.Code)
+	}
+	if w := form(url.Values{"completedWords": {strings.Repeat("A", 3<<10+1)}}); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected 413 for long completed words, got %d", w.Code)
+	}
+	w := form(url.Values{"other": {strings.Repeat("A", 5<<10)}})
+	if w.Code != http.StatusRequestEntityTooLarge || !strings.Contains(w.Body.String(), "payload_too_large") {
+		t.Errorf("Expected 413 for a large body, got %d %s", w.Code, w.Body)
+	}
+	// A body sent without a length is cut off while it is read.
+	if w := do(strings.NewReader("other="+strings.Repeat("A", 5<<10)), -1); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected 413 for a large body of unknown length, got %d", w.Code)
+	}
+}
+
+func TestCSRF(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for _, production := range []bool{false, true} {
+		app := &models.App{
+			IsProduction: production,
+			CookieMaxAge: time.Hour,
+			CSRF:         security.NewCSRF([]byte("key")),
+			Abuse:        abuse.NewTracker(abuse.Config{}),
+		}
+		router := gin.New()
+		router.Use(middleware.CSRFMiddleware(app), middleware.ValidateCSRFMiddleware(app))
+		router.GET("/", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+		router.GET("/static/app.js", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+		router.POST("/guess", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		cookies := w.Result().Cookies()
+		if len(cookies) != 1 || cookies[0].Name != constants.CSRFCookieName {
+			t.Fatalf("Expected the CSRF cookie, got %v", cookies)
+		}
+		cookie := cookies[0]
+		if !cookie.HttpOnly || cookie.SameSite != http.SameSiteLaxMode || cookie.Secure != production {
+			t.Errorf("Unexpected CSRF cookie policy in production=%v: %+v", production, cookie)
+		}
+		token := w.Header().Get(constants.CSRFHeader)
+
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/static/app.js", nil))
+		if w.Header().Get(constants.CSRFHeader) != "" || len(w.Result().Cookies()) != 0 {
+			t.Error("Expected static assets to go without CSRF tokens")
+		}
+
+		post := func(cookieValue, header string) int {
+			req := httptest.NewRequest(http.MethodPost, "/guess", nil)
+			req.AddCookie(&http.Cookie{Name: constants.CSRFCookieName, Value: cookieValue})
+			req.Header.Set(constants.CSRFHeader, header)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			return w.Code
+		}
+		if code := post(cookie.Value, token); code != http.StatusOK {
+			t.Errorf("Expected the issued token to be accepted, got %d", code)
+		}
+		if code := post(cookie.Value, cookie.Value); code != http.StatusForbidden {
+			t.Errorf("Expected the secret itself to be refused as a token, got %d", code)
+		}
+		// An attacker able to plant a cookie still cannot make a token for it.
+		planted := app.CSRF.NewSecret()
+		if code := post(planted, planted); code != http.StatusForbidden {
+			t.Errorf("Expected a planted cookie and matching header to be refused, got %d", code)
+		}
+		if code := post(planted, token); code != http.StatusForbidden {
+			t.Errorf("Expected a token for another secret to be refused, got %d", code)
+		}
+	}
+}
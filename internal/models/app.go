@@ -0,0 +1,70 @@
+package models
+
+import (
+	"sync"
+	"time"
+
+	abuse "github.com/CodeAndHammer/vortludo/internal/abuse"
+	analytics "github.com/CodeAndHammer/vortludo/internal/analytics"
+	botdetect "github.com/CodeAndHammer/vortludo/internal/botdetect"
+	config "github.com/CodeAndHammer/vortludo/internal/config"
+	constants "github.com/CodeAndHammer/vortludo/internal/constants"
+	security "github.com/CodeAndHammer/vortludo/internal/security"
+	shareimage "github.com/CodeAndHammer/vortludo/internal/shareimage"
+)
+
+// NewApp builds the application state the configuration describes. Stores
+// that are opened from disk, such as the word lists and accounts, are left
+// for the caller to load.
+func NewApp(cfg *config.Config) *App {
+	app := &App{
+		LimiterMap:      make(map[string]*RateLimiterEntry),
+		IsProduction:    cfg.Production,
+		StartTime:       time.Now(),
+		CookieMaxAge:    cfg.Sessions.CookieMaxAge,
+		StaticCacheAge:  cfg.Assets.StaticCacheAge,
+		RateLimitRPS:    cfg.RateLimit.RPS,
+		RateLimitBurst:  cfg.RateLimit.Burst,
+		SessionTimeout:  cfg.Sessions.Timeout,
+		MaxSessions:     cfg.Sessions.Max,
+		SessionLimit:    cfg.Sessions.LimitPolicy,
+		MinimalCookies:  cfg.Sessions.MinimalCookies,
+		MaxRequestBytes: int64(cfg.Server.MaxRequestBytes),
+		RequestTimeout:  cfg.Server.RequestTimeout,
+		GuessTimeout:    cfg.Server.GuessTimeout,
+		AdminTimeout:    cfg.Server.AdminTimeout,
+		BlocklistPath:   cfg.Words.BlockedFile,
+		AdminToken:      cfg.Accounts.AdminToken,
+		Analytics:       analytics.NewCollector(),
+		ResultPool: &sync.Pool{New: func() any {
+			return new([constants.WordLength]GuessResult)
+		}},
+		BotRaceInterval:   cfg.Game.BotRaceInterval,
+		SolverHintLimit:   cfg.Game.SolverHintLimit,
+		HintLimit:         cfg.Game.HintLimit,
+		AutoContinueDelay: cfg.Game.AutoContinueDelay,
+		GuessCooldown:     cfg.Game.GuessCooldown,
+		DefaultTheme:      cfg.Game.DefaultTheme,
+		IPv6PrefixLen:     cfg.RateLimit.IPv6PrefixLen,
+		ValidateAPI:       cfg.RateLimit.ValidateAPI,
+		ValidateRPS:       cfg.RateLimit.ValidateRPS,
+		ValidateBurst:     cfg.RateLimit.ValidateBurst,
+		Abuse: abuse.NewTracker(abuse.Config{
+			Threshold: cfg.Abuse.Threshold,
+			Window:    cfg.Abuse.Window,
+			BaseBan:   cfg.Abuse.BaseBan,
+			MaxBan:    cfg.Abuse.MaxBan,
+		}),
+		Bots: botdetect.New(botdetect.Config{
+			MinInterval: cfg.Bots.MinGuessInterval,
+			FlagScore:   cfg.Bots.FlagScore,
+		}),
+		ExcludeBots: cfg.Bots.ExcludeFromStandings,
+		PublicURL:   cfg.Server.PublicURL,
+		BasePath:    cfg.Server.BasePath,
+		CSRF:        security.NewCSRF([]byte(cfg.Security.CSRFKey)),
+		ShareImages: shareimage.NewCache(constants.ShareImageCacheSize),
+	}
+	app.Maintenance.Store(cfg.Server.Maintenance)
+	return app
+}
@@ -0,0 +1,46 @@
+package models
+
+import wordset "github.com/CodeAndHammer/vortludo/internal/wordset"
+
+// Dictionary is a loaded set of target words and accepted guesses. It is
+// never changed once published with App.SetDictionary: a reload builds a new
+// one and swaps it in, so readers need no lock.
+type Dictionary struct {
+	Words   []WordEntry
+	WordSet map[string]struct{}
+	// Accepted holds every word accepted as a guess, target words
+	// included.
+	Accepted *wordset.Set
+	Hints    map[string]string
+	// HintStages holds the staged hints of the words that have them.
+	HintStages map[string][]string
+	// Pools maps each word pool to the indexes in Words of its words.
+	Pools map[string][]int
+	// Index maps each target word to its entry in Words.
+	Index map[string]int
+}
+
+var emptyDictionary = &Dictionary{}
+
+// Dictionary returns the current dictionary, which is empty until one is
+// loaded.
+func (app *App) Dictionary() *Dictionary {
+	if dict := app.dictionary.Load(); dict != nil {
+		return dict
+	}
+	return emptyDictionary
+}
+
+// Entry returns the entry of target word word. It is shared with every
+// reader of the dictionary and must not be changed.
+func (d *Dictionary) Entry(word string) (*WordEntry, bool) {
+	i, ok := d.Index[word]
+	if !ok {
+		return nil, false
+	}
+	return &d.Words[i], true
+}
+
+func (app *App) SetDictionary(dict *Dictionary) {
+	app.dictionary.Store(dict)
+}
@@ -0,0 +1,51 @@
+package models
+
+import constants "github.com/CodeAndHammer/vortludo/internal/constants"
+
+// WordRunes are the letters of a word, one rune each. Missing letters are
+// zero.
+type WordRunes [constants.WordLength]rune
+
+// LetterCounts tally the letters of a word: Letters holds its distinct
+// letters in order of first appearance, Counts how often each occurs, and N
+// how many there are. A word has at most WordLength distinct letters, so a
+// lookup is a scan of a handful of runes rather than a map access.
+type LetterCounts struct {
+	Letters WordRunes
+	Counts  [constants.WordLength]uint8
+	N       int
+}
+
+// CountLetters tallies the letters of word.
+func CountLetters(word WordRunes) LetterCounts {
+	var lc LetterCounts
+	for _, r := range word {
+		if i := lc.Index(r); i >= 0 {
+			lc.Counts[i]++
+			continue
+		}
+		lc.Letters[lc.N] = r
+		lc.Counts[lc.N] = 1
+		lc.N++
+	}
+	return lc
+}
+
+// Index returns the position of r in Letters and Counts, or -1 if the word
+// does not have it.
+func (lc *LetterCounts) Index(r rune) int {
+	for i := range lc.N {
+		if lc.Letters[i] == r {
+			return i
+		}
+	}
+	return -1
+}
+
+// Count returns how often the word has r.
+func (lc *LetterCounts) Count(r rune) int {
+	if i := lc.Index(r); i >= 0 {
+		return int(lc.Counts[i])
+	}
+	return 0
+}
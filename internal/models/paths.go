@@ -0,0 +1,22 @@
+package models
+
+import "html/template"
+
+// Path returns the path of route under app.BasePath.
+func (app *App) Path(route string) string {
+	return app.BasePath + route
+}
+
+// CookiePath is the path cookies are scoped to: the base path, or the whole
+// site when the game is served at the root.
+func (app *App) CookiePath() string {
+	return app.BasePath + "/"
+}
+
+// Funcs returns the template functions for linking to routes: basePath
+// prefixes a route's path, as in href="{{basePath}}/settings".
+func (app *App) Funcs() template.FuncMap {
+	return template.FuncMap{
+		"basePath": func() string { return app.BasePath },
+	}
+}
@@ -0,0 +1,124 @@
+package models
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+
+	constants "github.com/CodeAndHammer/vortludo/internal/constants"
+)
+
+// statusBits is how many bits a tile status takes in Rows.Statuses; a row of
+// constants.WordLength tiles must fit in a uint16.
+const statusBits = 2
+
+var _ [16 - statusBits*constants.WordLength]struct{}
+
+// packedStatuses lists the tile statuses by their packed code. Code 0 is a
+// tile that has not been played.
+var packedStatuses = [...]string{
+	"",
+	constants.GuessStatusAbsent,
+	constants.GuessStatusPresent,
+	constants.GuessStatusCorrect,
+}
+
+// Rows is the compact form of a board's guesses: the word guessed in each row
+// and the statuses of its tiles, packed two bits per tile. It takes a fraction
+// of the memory of a GuessResult grid, which matters with many concurrent
+// sessions. Rows are expanded to GuessResults only where letters and statuses
+// are needed one by one, such as for rendering.
+type Rows struct {
+	Words    []string `json:"words"`
+	Statuses []uint16 `json:"statuses"`
+}
+
+// NewRows returns n unplayed rows.
+func NewRows(n int) Rows {
+	return Rows{Words: make([]string, n), Statuses: make([]uint16, n)}
+}
+
+// PackRows returns the compact form of a GuessResult grid.
+func PackRows(results [][]GuessResult) Rows {
+	rows := NewRows(len(results))
+	for i, result := range results {
+		rows.Set(i, result)
+	}
+	return rows
+}
+
+// Clone returns a copy of r that shares no memory with it.
+func (r Rows) Clone() Rows {
+	return Rows{Words: slices.Clone(r.Words), Statuses: slices.Clone(r.Statuses)}
+}
+
+func (r Rows) Len() int {
+	return len(r.Words)
+}
+
+// Set stores the scored guess result in row i.
+func (r Rows) Set(i int, result []GuessResult) {
+	var word strings.Builder
+	var packed uint16
+	for j, tile := range result[:min(len(result), constants.WordLength)] {
+		word.WriteString(tile.Letter)
+		packed |= statusCode(tile.Status) << (statusBits * j)
+	}
+	r.Words[i] = word.String()
+	r.Statuses[i] = packed
+}
+
+// Row expands row i. An unplayed row has WordLength empty tiles.
+func (r Rows) Row(i int) []GuessResult {
+	result := make([]GuessResult, constants.WordLength)
+	j := 0
+	for _, letter := range r.Words[i] {
+		if j == constants.WordLength {
+			break
+		}
+		result[j].Letter = string(letter)
+		j++
+	}
+	for j := range result {
+		result[j].Status = packedStatuses[r.Statuses[i]>>(statusBits*j)&(1<<statusBits-1)]
+	}
+	return result
+}
+
+// Expand returns the first n rows as a GuessResult grid.
+func (r Rows) Expand(n int) [][]GuessResult {
+	n = min(n, r.Len())
+	grid := make([][]GuessResult, n)
+	for i := range n {
+		grid[i] = r.Row(i)
+	}
+	return grid
+}
+
+// UnmarshalJSON also accepts the GuessResult grid that games were stored as
+// before Rows, so older session snapshots still load.
+func (r *Rows) UnmarshalJSON(data []byte) error {
+	var grid [][]GuessResult
+	if err := json.Unmarshal(data, &grid); err == nil {
+		*r = PackRows(grid)
+		return nil
+	}
+	type plain Rows
+	var p plain
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+	statuses := make([]uint16, len(p.Words))
+	copy(statuses, p.Statuses)
+	*r = Rows{Words: p.Words, Statuses: statuses}
+	return nil
+}
+
+func statusCode(status string) uint16 {
+	for code, s := range packedStatuses {
+		if s == status {
+			return uint16(code)
+		}
+	}
+	return 0
+}
@@ -0,0 +1,200 @@
+package models
+
+import (
+	"iter"
+	"sync"
+	"time"
+
+	auth "github.com/CodeAndHammer/vortludo/internal/auth"
+)
+
+// sessionShards is how many parts a SessionStore is split into. Each part
+// has its own lock, so requests from different sessions seldom wait on one
+// another.
+const sessionShards = 64
+
+// SessionShard holds the state of the sessions whose IDs hash to it. Its
+// maps are guarded by its lock and made on first write.
+type SessionShard struct {
+	sync.RWMutex
+	Games    map[string]*GameState
+	Settings map[string]*UserSettings
+	Stats    map[string]*auth.Stats
+	Replays  map[string]*Replay
+	Heatmaps map[string]*Heatmap
+	// Consents holds the answer of each session asked for consent with
+	// minimal cookies.
+	Consents map[string]bool
+	Locks    map[string]*SessionLock
+}
+
+// PutGame stores the session's game. The caller holds the lock.
+func (s *SessionShard) PutGame(sessionID string, game *GameState) {
+	if s.Games == nil {
+		s.Games = make(map[string]*GameState)
+	}
+	s.Games[sessionID] = game
+}
+
+// SessionStore holds the per-session state, sharded by session ID. The zero
+// value is ready to use.
+type SessionStore struct {
+	shards [sessionShards]SessionShard
+	// spectate indexes the sessions by the spectate token of their game, so
+	// spectators find a game without a search of every shard. A session has
+	// at most one token indexed. It is guarded by its own lock, which is taken
+	// after a shard's, never before.
+	spectateMu       sync.RWMutex
+	spectateSessions map[string]string
+	spectateTokens   map[string]string
+}
+
+// Shard returns the shard holding sessionID's state.
+func (s *SessionStore) Shard(sessionID string) *SessionShard {
+	// FNV-1a, inlined to keep the lookup free of allocations.
+	h := uint32(2166136261)
+	for i := 0; i < len(sessionID); i++ {
+		h ^= uint32(sessionID[i])
+		h *= 16777619
+	}
+	return &s.shards[h%sessionShards]
+}
+
+// Shards yields every shard, for work that spans all sessions. Callers lock
+// each shard themselves, one at a time.
+func (s *SessionStore) Shards() iter.Seq[*SessionShard] {
+	return func(yield func(*SessionShard) bool) {
+		for i := range s.shards {
+			if !yield(&s.shards[i]) {
+				return
+			}
+		}
+	}
+}
+
+// Game returns the session's game.
+func (s *SessionStore) Game(sessionID string) (*GameState, bool) {
+	shard := s.Shard(sessionID)
+	shard.RLock()
+	defer shard.RUnlock()
+	game, ok := shard.Games[sessionID]
+	return game, ok
+}
+
+func (s *SessionStore) SetGame(sessionID string, game *GameState) {
+	shard := s.Shard(sessionID)
+	shard.Lock()
+	defer shard.Unlock()
+	shard.PutGame(sessionID, game)
+}
+
+func (s *SessionStore) DeleteGame(sessionID string) {
+	shard := s.Shard(sessionID)
+	shard.Lock()
+	defer shard.Unlock()
+	delete(shard.Games, sessionID)
+	s.UnindexSpectate(sessionID)
+}
+
+// Spectated returns the session whose game has the spectate token.
+func (s *SessionStore) Spectated(token string) (string, bool) {
+	s.spectateMu.RLock()
+	defer s.spectateMu.RUnlock()
+	sessionID, ok := s.spectateSessions[token]
+	return sessionID, ok
+}
+
+// IndexSpectate records token as the spectate token of the session's game,
+// in place of any the session had before.
+func (s *SessionStore) IndexSpectate(sessionID, token string) {
+	s.spectateMu.Lock()
+	defer s.spectateMu.Unlock()
+	if s.spectateSessions == nil {
+		s.spectateSessions = make(map[string]string)
+		s.spectateTokens = make(map[string]string)
+	}
+	if old, ok := s.spectateTokens[sessionID]; ok {
+		delete(s.spectateSessions, old)
+	}
+	s.spectateSessions[token] = sessionID
+	s.spectateTokens[sessionID] = token
+}
+
+// UnindexSpectate drops the session's spectate token from the index.
+func (s *SessionStore) UnindexSpectate(sessionID string) {
+	s.spectateMu.Lock()
+	defer s.spectateMu.Unlock()
+	if token, ok := s.spectateTokens[sessionID]; ok {
+		delete(s.spectateSessions, token)
+		delete(s.spectateTokens, sessionID)
+	}
+}
+
+// Len counts the sessions with a game.
+func (s *SessionStore) Len() int {
+	n := 0
+	for shard := range s.Shards() {
+		shard.RLock()
+		n += len(shard.Games)
+		shard.RUnlock()
+	}
+	return n
+}
+
+// SessionGCRun describes one sweep of a SessionStore for expired sessions.
+type SessionGCRun struct {
+	Started  time.Time
+	Duration time.Duration
+	// LongestLock is the longest any batch held a shard's write lock.
+	LongestLock time.Duration
+	Scanned     int
+	Expired     int
+	Batches     int
+	// Interrupted is set when shutdown stopped the sweep partway.
+	Interrupted bool
+}
+
+// SessionGCStats sums up the sweeps so far, for the metrics summary.
+type SessionGCStats struct {
+	Runs            int64     `json:"runs"`
+	Expired         int64     `json:"expired"`
+	LastRun         time.Time `json:"last_run,omitzero"`
+	LastScanned     int       `json:"last_scanned"`
+	LastExpired     int       `json:"last_expired"`
+	LastBatches     int       `json:"last_batches"`
+	LastDurationMS  float64   `json:"last_duration_ms"`
+	MaxDurationMS   float64   `json:"max_duration_ms"`
+	TotalDurationMS float64   `json:"total_duration_ms"`
+	LastLockMS      float64   `json:"last_lock_ms"`
+	MaxLockMS       float64   `json:"max_lock_ms"`
+}
+
+// SessionGC records sweeps. The zero value is ready to use.
+type SessionGC struct {
+	mu    sync.Mutex
+	stats SessionGCStats
+}
+
+func (g *SessionGC) Record(run SessionGCRun) {
+	ms := func(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	s := &g.stats
+	s.Runs++
+	s.Expired += int64(run.Expired)
+	s.LastRun = run.Started
+	s.LastScanned = run.Scanned
+	s.LastExpired = run.Expired
+	s.LastBatches = run.Batches
+	s.LastDurationMS = ms(run.Duration)
+	s.MaxDurationMS = max(s.MaxDurationMS, s.LastDurationMS)
+	s.TotalDurationMS += s.LastDurationMS
+	s.LastLockMS = ms(run.LongestLock)
+	s.MaxLockMS = max(s.MaxLockMS, s.LastLockMS)
+}
+
+func (g *SessionGC) Stats() SessionGCStats {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.stats
+}
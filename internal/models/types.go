@@ -1,28 +1,265 @@
 package models
 
 import (
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	abuse "github.com/CodeAndHammer/vortludo/internal/abuse"
+	analytics "github.com/CodeAndHammer/vortludo/internal/analytics"
+	announce "github.com/CodeAndHammer/vortludo/internal/announce"
+	assets "github.com/CodeAndHammer/vortludo/internal/assets"
+	audit "github.com/CodeAndHammer/vortludo/internal/audit"
+	auth "github.com/CodeAndHammer/vortludo/internal/auth"
+	botdetect "github.com/CodeAndHammer/vortludo/internal/botdetect"
+	constants "github.com/CodeAndHammer/vortludo/internal/constants"
+	eventlog "github.com/CodeAndHammer/vortludo/internal/eventlog"
+	schedule "github.com/CodeAndHammer/vortludo/internal/schedule"
+	security "github.com/CodeAndHammer/vortludo/internal/security"
+	shareimage "github.com/CodeAndHammer/vortludo/internal/shareimage"
+	store "github.com/CodeAndHammer/vortludo/internal/store"
+	telemetry "github.com/CodeAndHammer/vortludo/internal/telemetry"
+	tournament "github.com/CodeAndHammer/vortludo/internal/tournament"
 )
 
+// WordEntry is a target word. Hint is always offered; Hints are further
+// hints the player can reveal one at a time, from the least to the most
+// revealing. Pools names the word pools games may draw the word from; a
+// word that names none is in the classic pool.
 type WordEntry struct {
-	Word string `json:"word"`
-	Hint string `json:"hint"`
+	Word  string   `json:"word"`
+	Hint  string   `json:"hint"`
+	Hints []string `json:"hints,omitempty"`
+	Pools []string `json:"pools,omitempty"`
+	// Status is empty for a word in play, or WordRetired for one no longer
+	// drawn as a target. A retired word stays an accepted guess, and games
+	// and statistics that already have it keep it.
+	Status string `json:"status,omitempty"`
+	// Runes and Counts are the word's letters and how often each occurs,
+	// worked out when the dictionary is set so that scoring a guess against
+	// the word does not decode or count it again.
+	Runes  WordRunes    `json:"-"`
+	Counts LetterCounts `json:"-"`
+}
+
+// WordRetired is the Status of a word retired from play.
+const WordRetired = "retired"
+
+// Retired reports whether e is no longer drawn as a target word.
+func (e WordEntry) Retired() bool {
+	return e.Status == WordRetired
 }
 
 type WordList struct {
 	Words []WordEntry `json:"words"`
 }
 
+// Definition is a dictionary entry for a target word, shown once the game is
+// over.
+type Definition struct {
+	Word         string `json:"word"`
+	PartOfSpeech string `json:"partOfSpeech,omitempty"`
+	Definition   string `json:"definition"`
+}
+
+type DefinitionList struct {
+	Definitions []Definition `json:"definitions"`
+}
+
+// Pack is a themed set of target words, played in any order, whose
+// progress players keep across games.
+type Pack struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Words       []string `json:"words"`
+}
+
+type PackList struct {
+	Packs []Pack `json:"packs"`
+}
+
 type GameState struct {
-	Guesses        [][]GuessResult `json:"guesses"`
-	CurrentRow     int             `json:"currentRow"`
-	GameOver       bool            `json:"gameOver"`
-	Won            bool            `json:"won"`
-	TargetWord     string          `json:"targetWord"`
-	SessionWord    string          `json:"sessionWord"`
-	GuessHistory   []string        `json:"guessHistory"`
-	LastAccessTime time.Time       `json:"lastAccessTime"`
+	// ID identifies the game publicly, e.g. in bug reports. Seed drove the
+	// choice of its words; see game.ReplayGame.
+	ID             string            `json:"id,omitempty"`
+	Seed           uint64            `json:"seed,omitempty"`
+	Guesses        Rows              `json:"guesses"`
+	CurrentRow     int               `json:"currentRow"`
+	GameOver       bool              `json:"gameOver"`
+	Won            bool              `json:"won"`
+	TargetWord     string            `json:"targetWord"`
+	SessionWord    string            `json:"sessionWord"`
+	GuessHistory   []string          `json:"guessHistory"`
+	LastAccessTime time.Time         `json:"lastAccessTime"`
+	Boards         []Board           `json:"boards,omitempty"`
+	Race           *RaceState        `json:"race,omitempty"`
+	Adversarial    *AdversarialState `json:"adversarial,omitempty"`
+	SolverHints    int               `json:"solverHints,omitempty"`
+	AfterExpiry    bool              `json:"afterExpiry,omitempty"`
+	Tournament     *TournamentRef    `json:"tournament,omitempty"`
+	Spectate       *Spectate         `json:"spectate,omitempty"`
+	// Number counts the session's games from 1. Puzzle is the number of the
+	// daily puzzle the game plays, if it plays one.
+	Number int `json:"number,omitempty"`
+	Puzzle int `json:"puzzle,omitempty"`
+	// Version counts the guesses applied to the game. Clients send it back
+	// with each guess so one played from a stale board can be refused.
+	Version int `json:"version,omitempty"`
+	// Draft is the current row as typed key by key on the server, for
+	// clients without JavaScript. A guess clears it.
+	Draft string `json:"draft,omitempty"`
+	// HintsRevealed counts the word's staged hints the player has revealed;
+	// HintTax counts the rows given up for them in hint tax mode.
+	HintsRevealed int `json:"hintsRevealed,omitempty"`
+	HintTax       int `json:"hintTax,omitempty"`
+	// ContinueAt is when the session's next word starts by itself, if the
+	// game ended with auto-continue on.
+	ContinueAt time.Time `json:"continueAt,omitzero"`
+	// Pool is the word pool the game's words were drawn from; empty means
+	// the classic pool.
+	Pool string `json:"pool,omitempty"`
+	// Pack is the ID of the puzzle pack the game's word came from, if any.
+	Pack string `json:"pack,omitempty"`
+	// Assisted games reveal their word's first letter from the start.
+	Assisted bool `json:"assisted,omitempty"`
+	// GuessTimes holds when each guess arrived, for bot detection.
+	GuessTimes []time.Time `json:"guessTimes,omitempty"`
+	// LastAttempt is when a guess was last sent, accepted or not, for the
+	// cooldown between guesses.
+	LastAttempt time.Time `json:"lastAttempt,omitzero"`
+	// Scored games award points when won. Score is the total of the run of
+	// won games the game belongs to, and Points what the game added to it.
+	Scored bool `json:"scored,omitempty"`
+	Score  int  `json:"score,omitempty"`
+	Points int  `json:"points,omitempty"`
+}
+
+// Label names the game for players to refer to, e.g. in shared results: by
+// its daily puzzle number, or else by its number in the session.
+func (g *GameState) Label() string {
+	switch {
+	case g.Puzzle > 0:
+		return "Vortludo #" + strconv.Itoa(g.Puzzle)
+	case g.Number > 0:
+		return "Vortludo game " + strconv.Itoa(g.Number)
+	}
+	return "Vortludo"
+}
+
+// Clone returns a deep copy of g, which later changes to g do not affect.
+func (g *GameState) Clone() *GameState {
+	c := *g
+	c.Guesses = g.Guesses.Clone()
+	c.GuessHistory = slices.Clone(g.GuessHistory)
+	c.GuessTimes = slices.Clone(g.GuessTimes)
+	if g.Boards != nil {
+		c.Boards = make([]Board, len(g.Boards))
+		for i, board := range g.Boards {
+			board.Guesses = board.Guesses.Clone()
+			c.Boards[i] = board
+		}
+	}
+	if g.Race != nil {
+		race := *g.Race
+		race.BotGuesses = slices.Clone(g.Race.BotGuesses)
+		race.BotRows = slices.Clone(g.Race.BotRows)
+		for i, row := range race.BotRows {
+			race.BotRows[i] = slices.Clone(row)
+		}
+		c.Race = &race
+	}
+	if g.Adversarial != nil {
+		c.Adversarial = &AdversarialState{Candidates: slices.Clone(g.Adversarial.Candidates)}
+	}
+	if g.Tournament != nil {
+		tournament := *g.Tournament
+		c.Tournament = &tournament
+	}
+	if g.Spectate != nil {
+		spectate := *g.Spectate
+		c.Spectate = &spectate
+	}
+	return &c
+}
+
+// Spectate is a game's read-only spectate link. EndsAt is set once the game
+// is over; the link stops working after it.
+type Spectate struct {
+	Token  string    `json:"token"`
+	EndsAt time.Time `json:"endsAt,omitzero"`
+}
+
+// TournamentRef ties a game to the tournament day it is scored for.
+type TournamentRef struct {
+	Week      string    `json:"week"`
+	Day       int       `json:"day"`
+	Player    string    `json:"player"`
+	StartedAt time.Time `json:"startedAt"`
+}
+
+// DayNumber is Day counted from 1, for display.
+func (r *TournamentRef) DayNumber() int {
+	return r.Day + 1
+}
+
+// UserSettings are the player's preferences. They belong to the session
+// rather than to a game, so they survive starting a new game.
+type UserSettings struct {
+	HardMode       bool   `json:"hardMode"`
+	ColorBlind     bool   `json:"colorBlind"`
+	Language       string `json:"language"`
+	KeyboardLayout string `json:"keyboardLayout"`
+	// Theme is one of constants.SupportedThemes.
+	Theme         string `json:"theme"`
+	ReducedMotion bool   `json:"reducedMotion"`
+	HintTax       bool   `json:"hintTax"`
+	AutoContinue  bool   `json:"autoContinue"`
+	// Assisted starts new games with their word's first letter revealed.
+	Assisted bool `json:"assisted"`
+	// Points makes new games score points, kept across a run of wins.
+	Points bool `json:"points"`
+	// DoNotTrack leaves the session out of analytics and telemetry.
+	DoNotTrack bool `json:"doNotTrack"`
+}
+
+// RaceState tracks the bot opponent of a race game. BotRows holds only the
+// statuses of the bot's guesses; the letters are never sent to the player.
+type RaceState struct {
+	StartedAt       time.Time  `json:"startedAt"`
+	IntervalSeconds int        `json:"intervalSeconds"`
+	BotGuesses      []string   `json:"botGuesses"`
+	BotRows         [][]string `json:"botRows"`
+	BotSolved       bool       `json:"botSolved"`
+}
+
+// AdversarialState tracks the words an adversarial game could still be
+// playing. Candidates is nil until the first guess, meaning every word of the
+// game's pool.
+type AdversarialState struct {
+	Candidates []string `json:"candidates,omitempty"`
+}
+
+// Board is one target word of a multi-board game. Classic games leave
+// GameState.Boards empty and use the single-word fields above.
+type Board struct {
+	SessionWord string `json:"sessionWord"`
+	TargetWord  string `json:"targetWord"`
+	Guesses     Rows   `json:"guesses"`
+	Solved      bool   `json:"solved"`
+	SolvedAtRow int    `json:"solvedAtRow"`
+}
+
+// BoardView is a render-ready Board for the multi-board template.
+type BoardView struct {
+	Index      int
+	Rows       []BoardRow
+	Solved     bool
+	TargetWord string
 }
 
 type GuessResult struct {
@@ -30,6 +267,127 @@ type GuessResult struct {
 	Status string `json:"status"`
 }
 
+// BoardRow is the render-ready form of one row of the board. IsNewRow marks
+// the row revealed by the guess that produced this response.
+type BoardRow struct {
+	Index     int
+	Tiles     []BoardTile
+	IsCurrent bool
+	// Assist is the letter an assisted game reveals, shown pre-filled in
+	// the first tile of the current row.
+	Assist string
+	// Draft holds the letters of the game's Draft, tile by tile, on the
+	// current row.
+	Draft    [constants.WordLength]string
+	IsNewRow bool
+	// IsSpent marks a row given up for a hint.
+	IsSpent bool
+}
+
+// BoardTile carries the reveal ordering for the flip animation; RevealDelayMs
+// is only non-zero on tiles of the new row.
+type BoardTile struct {
+	Letter        string
+	Status        string
+	RevealOrder   int
+	RevealDelayMs int
+}
+
+// SessionLock serialises the requests of one session. Refs counts the holders
+// and waiters so the lock can be dropped once nobody needs it.
+type SessionLock struct {
+	sync.Mutex
+	Refs int
+}
+
+// Replay is a response kept so that a request repeated with the same
+// idempotency key gets it again instead of being processed twice.
+type Replay struct {
+	Key     string
+	Status  int
+	Header  http.Header
+	Body    []byte
+	Created time.Time
+}
+
+// GuessEvent is the payload of a guess in the game event log.
+type GuessEvent struct {
+	Guess string `json:"guess"`
+	Row   int    `json:"row"`
+	// Untracked is set when the session opted out of analytics, so that
+	// backfilling them skips the guess.
+	Untracked bool `json:"untracked,omitempty"`
+}
+
+// HintEvent is the payload of a revealed hint in the game event log.
+type HintEvent struct {
+	Stage int  `json:"stage"`
+	Taxed bool `json:"taxed"`
+}
+
+// HintView is the hint area of a game: the word's hint, the staged hints
+// revealed so far and how many more can be revealed.
+type HintView struct {
+	Text     string
+	Revealed []string
+	Left     int
+	Tax      bool
+}
+
+// Heatmap aggregates a session's guesses across its games: how often each
+// letter was played, and how often each position was not scored correct.
+type Heatmap struct {
+	Guesses        int            `json:"guesses"`
+	Letters        map[string]int `json:"letters"`
+	PositionMisses []int          `json:"positionMisses"`
+}
+
+// Record adds the scored tiles of one guess.
+func (h *Heatmap) Record(tiles []GuessResult) {
+	if h.Letters == nil {
+		h.Letters = make(map[string]int)
+	}
+	if len(h.PositionMisses) < len(tiles) {
+		h.PositionMisses = append(h.PositionMisses, make([]int, len(tiles)-len(h.PositionMisses))...)
+	}
+	h.Guesses++
+	for i, tile := range tiles {
+		h.Letters[tile.Letter]++
+		if tile.Status != constants.GuessStatusCorrect {
+			h.PositionMisses[i]++
+		}
+	}
+}
+
+func (h *Heatmap) Clone() *Heatmap {
+	return &Heatmap{
+		Guesses:        h.Guesses,
+		Letters:        maps.Clone(h.Letters),
+		PositionMisses: slices.Clone(h.PositionMisses),
+	}
+}
+
+// HeatmapView is a render-ready Heatmap. Levels run from 0 (never) to
+// HeatLevels-1 (the most of any key or position).
+type HeatmapView struct {
+	Guesses   int
+	Rows      [][]HeatKey
+	Positions []HeatPosition
+}
+
+type HeatKey struct {
+	Letter string
+	Count  int
+	Level  int
+}
+
+type HeatPosition struct {
+	Number int
+	Misses int
+	Rate   int
+	Level  int
+}
+
 // rateLimiterEntry represents a rate limiter entry for a client IP
 type RateLimiterEntry struct {
 	Limiter        interface{} // would be golang.org/x/time/rate.Limiter in actual usage
@@ -37,14 +395,27 @@ type RateLimiterEntry struct {
 }
 
 type App struct {
-	WordList        []WordEntry
-	WordSet         map[string]struct{}
-	AcceptedWordSet map[string]struct{}
-	HintMap         map[string]string
-	GameSessions    map[string]*GameState
-	SessionMutex    sync.RWMutex
+	dictionary     atomic.Pointer[Dictionary]
+	BlockedWordSet map[string]struct{}
+	BlocklistPath  string
+	// WordsFile is the word list admins may add words to and remove words
+	// from; it is empty when the list is remote or pinned by checksum.
+	// WordsMutex serializes the edits, and is read-locked by new games from
+	// drawing their words until they are stored. WordsWithheld counts the
+	// holds on words about to be removed, which new games may not get; it is
+	// guarded by WordsMutex.
+	WordsFile       string
+	WordsMutex      sync.RWMutex
+	WordsWithheld   map[string]int
+	BlockedMutex    sync.RWMutex
+	Definitions     map[string]Definition
+	Packs           []Pack
+	Sessions        SessionStore
 	LimiterMap      map[string]*RateLimiterEntry
 	LimiterMutex    sync.RWMutex
+	LimitersCreated int
+	LimiterPeak     int
+	IPv6PrefixLen   int
 	IsProduction    bool
 	StartTime       time.Time
 	CookieMaxAge    time.Duration
@@ -52,5 +423,68 @@ type App struct {
 	RateLimitRPS    int
 	RateLimitBurst  int
 	SessionTimeout  time.Duration
-	RuneBufPool     *sync.Pool
+	// MaxSessions caps the sessions holding a game, 0 meaning no limit, and
+	// SessionLimit is what happens to new sessions past it: one of the
+	// constants.SessionLimit policies.
+	MaxSessions      int
+	SessionLimit     string
+	SessionsEvicted  atomic.Int64
+	SessionsRejected atomic.Int64
+	SessionGC        SessionGC
+	// MinimalCookies has the session cookie carry the CSRF secret too, and
+	// holds back the player cookie and stored sessions until consent.
+	MinimalCookies bool
+	// MaxRequestBytes caps request bodies.
+	MaxRequestBytes int64
+	// RequestTimeout is the deadline of a request, GuessTimeout and
+	// AdminTimeout those of /guess and the admin routes; 0 sets none.
+	RequestTimeout    time.Duration
+	GuessTimeout      time.Duration
+	AdminTimeout      time.Duration
+	BotRaceInterval   time.Duration
+	SolverHintLimit   int
+	HintLimit         int
+	AutoContinueDelay time.Duration
+	// GuessCooldown is the least time between two guesses of a game; 0
+	// turns the cooldown off.
+	GuessCooldown time.Duration
+	// DefaultTheme is the theme of sessions that have not picked one.
+	DefaultTheme  string
+	ValidateAPI   bool
+	ValidateRPS   int
+	ValidateBurst int
+	// ResultPool holds *[WordLength]GuessResult rows for CheckGuess to
+	// score into.
+	ResultPool    *sync.Pool
+	AdminToken    string
+	Analytics     *analytics.Collector
+	Assets        *assets.Manifest
+	Tournament    *tournament.Store
+	Schedule      *schedule.Store
+	Announcements *announce.Store
+	ShareImages   *shareimage.Cache
+	Audit         *audit.Log
+	Accounts      *auth.Store
+	OIDC          *auth.OIDCProvider
+	Security      *security.Policy
+	CSRF          *security.CSRF
+	EventLog      *eventlog.Log
+	Store         store.Store
+	WriteBehind   *store.WriteBehind
+	Abuse         *abuse.Tracker
+	Bots          *botdetect.Detector
+	// ExcludeBots leaves sessions flagged as bots out of the tournament
+	// standings.
+	ExcludeBots bool
+	Telemetry   *telemetry.Reporter
+	PublicURL   string
+	// BasePath prefixes every route, asset and cookie path; it is empty when
+	// the game is served at the root.
+	BasePath string
+	// Closing is closed when the server starts shutting down, to end
+	// long-lived responses such as event streams.
+	Closing chan struct{}
+	// Maintenance takes the instance out of rotation: /readyz fails while it
+	// is set, so load balancers stop sending new players here.
+	Maintenance atomic.Bool
 }
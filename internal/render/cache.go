@@ -0,0 +1,118 @@
+package render
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"html/template"
+	"sync"
+
+	util "github.com/CodeAndHammer/vortludo/internal/util"
+)
+
+const DefaultMaxEntries = 1024
+
+type cacheKey struct {
+	name   string
+	digest [sha256.Size]byte
+}
+
+// Cache memoises rendered fragments of templates whose output depends only on
+// the data passed in, keyed on (template name, digest of data). Templates opt
+// in by calling {{cached "name" data}} instead of {{template "name" data}}.
+type Cache struct {
+	mu         sync.RWMutex
+	tmpl       *template.Template
+	entries    map[cacheKey]template.HTML
+	maxEntries int
+	hits       uint64
+	misses     uint64
+}
+
+func NewCache(maxEntries int) *Cache {
+	if maxEntries <= 0 {
+		maxEntries = DefaultMaxEntries
+	}
+	return &Cache{
+		entries:    make(map[cacheKey]template.HTML),
+		maxEntries: maxEntries,
+	}
+}
+
+// Funcs returns the template functions backed by this cache. They must be
+// registered before the templates are parsed.
+func (c *Cache) Funcs() template.FuncMap {
+	return template.FuncMap{"cached": c.Fragment}
+}
+
+// SetTemplate installs the template set fragments are rendered from and drops
+// everything rendered by the previous set.
+func (c *Cache) SetTemplate(tmpl *template.Template) {
+	c.mu.Lock()
+	c.tmpl = tmpl
+	c.entries = make(map[cacheKey]template.HTML)
+	c.mu.Unlock()
+}
+
+// Invalidate drops all cached fragments.
+func (c *Cache) Invalidate() {
+	c.mu.Lock()
+	c.entries = make(map[cacheKey]template.HTML)
+	c.mu.Unlock()
+}
+
+func (c *Cache) Fragment(name string, data any) (template.HTML, error) {
+	key := cacheKey{name: name}
+	if data != nil {
+		b, err := json.Marshal(data)
+		if err != nil {
+			return c.execute(name, data)
+		}
+		key.digest = sha256.Sum256(b)
+	}
+
+	c.mu.RLock()
+	html, ok := c.entries[key]
+	c.mu.RUnlock()
+	if ok {
+		c.mu.Lock()
+		c.hits++
+		c.mu.Unlock()
+		return html, nil
+	}
+
+	html, err := c.execute(name, data)
+	if err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	c.misses++
+	if len(c.entries) >= c.maxEntries {
+		util.LogInfo("Render cache full (%d entries), resetting", len(c.entries))
+		c.entries = make(map[cacheKey]template.HTML)
+	}
+	c.entries[key] = html
+	c.mu.Unlock()
+	return html, nil
+}
+
+// Stats returns the number of cached fragments, hits and misses.
+func (c *Cache) Stats() (entries int, hits, misses uint64) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.entries), c.hits, c.misses
+}
+
+func (c *Cache) execute(name string, data any) (template.HTML, error) {
+	c.mu.RLock()
+	tmpl := c.tmpl
+	c.mu.RUnlock()
+
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, name, data); err != nil {
+		return "", err
+	}
+	// #nosec G203 -- output of html/template is already escaped.
+	return template.HTML(buf.String()), nil
+}
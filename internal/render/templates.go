@@ -0,0 +1,120 @@
+package render
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"sync/atomic"
+	"time"
+
+	util "github.com/CodeAndHammer/vortludo/internal/util"
+	ginrender "github.com/gin-gonic/gin/render"
+)
+
+var templatePatterns = []string{"*.html", "partials/*.html"}
+
+// Templates is a gin HTMLRender whose template set can be re-parsed and
+// swapped atomically while requests are being served.
+type Templates struct {
+	fsys    fs.FS
+	cache   *Cache
+	funcs   template.FuncMap
+	current atomic.Pointer[template.Template]
+}
+
+// NewTemplates parses the template set from fsys. funcs are made available to
+// the templates alongside the cache helpers.
+func NewTemplates(fsys fs.FS, cache *Cache, funcs template.FuncMap) (*Templates, error) {
+	t := &Templates{fsys: fsys, cache: cache, funcs: funcs}
+	if err := t.Reload(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Reload re-parses the template set. On failure the previous set stays live.
+func (t *Templates) Reload() error {
+	tmpl, err := template.New("").Funcs(t.cache.Funcs()).Funcs(t.funcs).ParseFS(t.fsys, templatePatterns...)
+	if err != nil {
+		return fmt.Errorf("parse templates: %w", err)
+	}
+	t.current.Store(tmpl)
+	t.cache.SetTemplate(tmpl)
+	return nil
+}
+
+// Template returns the live template set.
+func (t *Templates) Template() *template.Template {
+	return t.current.Load()
+}
+
+// Instance implements gin's render.HTMLRender.
+func (t *Templates) Instance(name string, data any) ginrender.Render {
+	return ginrender.HTML{
+		Template: t.current.Load(),
+		Name:     name,
+		Data:     data,
+	}
+}
+
+// Watch polls the template filesystem every interval until ctx is done and
+// reloads the set when any file is added, removed or modified. It is meant
+// for development, where the templates are read from disk.
+func (t *Templates) Watch(ctx context.Context, interval time.Duration) {
+	last, err := t.fingerprint()
+	if err != nil {
+		util.LogWarn("Template watcher disabled: %v", err)
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	util.LogInfo("Started template watcher (interval %v)", interval)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		current, err := t.fingerprint()
+		if err != nil {
+			util.LogWarn("Template watcher: %v", err)
+			continue
+		}
+		if current == last {
+			continue
+		}
+		last = current
+		if err := t.Reload(); err != nil {
+			util.LogWarn("Template reload failed, keeping previous templates: %v", err)
+			continue
+		}
+		util.LogInfo("Reloaded templates")
+	}
+}
+
+type fingerprint struct {
+	files   int
+	size    int64
+	modTime time.Time
+}
+
+func (t *Templates) fingerprint() (fingerprint, error) {
+	var fp fingerprint
+	err := fs.WalkDir(t.fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		fp.files++
+		fp.size += info.Size()
+		if info.ModTime().After(fp.modTime) {
+			fp.modTime = info.ModTime()
+		}
+		return nil
+	})
+	return fp, err
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"html/template"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	render "github.com/CodeAndHammer/vortludo/internal/render"
+)
+
+func parseWithCache(t *testing.T, cache *render.Cache, src string) *template.Template {
+	t.Helper()
+	tmpl, err := template.New("").Funcs(cache.Funcs()).Parse(src)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	cache.SetTemplate(tmpl)
+	return tmpl
+}
+
+func TestFragmentCachesByNameAndData(t *testing.T) {
+	cache := render.NewCache(0)
+	parseWithCache(t, cache, `{{define "greet"}}<b>hello {{.}}</b>{{end}}`)
+
+	first, err := cache.Fragment("greet", "alice")
+	if err != nil {
+		t.Fatalf("Fragment: %v", err)
+	}
+	if string(first) != "<b>hello alice</b>" {
+		t.Errorf("unexpected fragment: %q", first)
+	}
+	if _, err := cache.Fragment("greet", "alice"); err != nil {
+		t.Fatalf("Fragment: %v", err)
+	}
+	if _, err := cache.Fragment("greet", "bob"); err != nil {
+		t.Fatalf("Fragment: %v", err)
+	}
+
+	entries, hits, misses := cache.Stats()
+	if entries != 2 || hits != 1 || misses != 2 {
+		t.Errorf("Stats = (%d, %d, %d), want (2, 1, 2)", entries, hits, misses)
+	}
+}
+
+func TestSetTemplateInvalidates(t *testing.T) {
+	cache := render.NewCache(0)
+	parseWithCache(t, cache, `{{define "kb"}}old{{end}}`)
+	if html, _ := cache.Fragment("kb", nil); html != "old" {
+		t.Fatalf("got %q, want old", html)
+	}
+
+	parseWithCache(t, cache, `{{define "kb"}}new{{end}}`)
+	if html, _ := cache.Fragment("kb", nil); html != "new" {
+		t.Errorf("got %q after reload, want new", html)
+	}
+}
+
+func TestCachedFuncInTemplate(t *testing.T) {
+	cache := render.NewCache(0)
+	tmpl := parseWithCache(t, cache, `{{define "kb"}}<i>keys</i>{{end}}{{define "page"}}<div>{{cached "kb" nil}}</div>{{end}}`)
+
+	var sb strings.Builder
+	if err := tmpl.ExecuteTemplate(&sb, "page", nil); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if sb.String() != "<div><i>keys</i></div>" {
+		t.Errorf("unexpected output: %q", sb.String())
+	}
+}
+
+func TestTemplatesReloadSwapsSet(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":       {Data: []byte(`{{define "index.html"}}v1{{end}}`)},
+		"partials/kb.html": {Data: []byte(`{{define "kb"}}k{{end}}`)},
+	}
+	templates, err := render.NewTemplates(fsys, render.NewCache(0), nil)
+	if err != nil {
+		t.Fatalf("NewTemplates: %v", err)
+	}
+
+	fsys["index.html"] = &fstest.MapFile{Data: []byte(`{{define "index.html"}}v2{{end}}`)}
+	if err := templates.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	var sb strings.Builder
+	if err := templates.Template().ExecuteTemplate(&sb, "index.html", nil); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if sb.String() != "v2" {
+		t.Errorf("got %q after reload, want v2", sb.String())
+	}
+
+	fsys["index.html"] = &fstest.MapFile{Data: []byte(`{{define "index.html"}}{{end`)}
+	if err := templates.Reload(); err == nil {
+		t.Error("expected parse error on broken template")
+	}
+	sb.Reset()
+	if err := templates.Template().ExecuteTemplate(&sb, "index.html", nil); err != nil || sb.String() != "v2" {
+		t.Errorf("previous templates should stay live, got %q (%v)", sb.String(), err)
+	}
+}
@@ -0,0 +1,184 @@
+// Package schedule keeps the words admins have assigned to future daily
+// puzzles, by date. Days without an entry fall back to the deterministic
+// weekly selection, so a schedule can be as sparse as a single holiday.
+package schedule
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	util "github.com/CodeAndHammer/vortludo/internal/util"
+)
+
+// DateLayout is how a scheduled day is written: its UTC date.
+const DateLayout = time.DateOnly
+
+// Entry assigns Word to the daily puzzle of Date.
+type Entry struct {
+	Date    string    `json:"date"`
+	Word    string    `json:"word"`
+	Note    string    `json:"note,omitempty"`
+	Created time.Time `json:"created"`
+}
+
+// Day returns the UTC midnight that starts t's day.
+func Day(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// ParseDate parses a date written as DateLayout.
+func ParseDate(date string) (time.Time, error) {
+	day, err := time.Parse(DateLayout, date)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("a date must be written as %s", DateLayout)
+	}
+	return day, nil
+}
+
+// Store holds the schedule and persists it to path after every change. An
+// empty path keeps it in memory. A nil Store schedules nothing.
+type Store struct {
+	mu      sync.RWMutex
+	path    string
+	entries map[string]Entry
+}
+
+// Open loads the store from path. A missing file yields an empty store.
+func Open(path string) (*Store, error) {
+	s := &Store{path: path, entries: make(map[string]Entry)}
+	if path == "" {
+		return s, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return s, nil
+		}
+		return nil, err
+	}
+	var entries []Entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("decode schedule: %w", err)
+	}
+	for _, e := range entries {
+		if _, err := ParseDate(e.Date); err != nil {
+			return nil, fmt.Errorf("schedule entry %q: %w", e.Date, err)
+		}
+		s.entries[e.Date] = e
+	}
+	return s, nil
+}
+
+// Word returns the word scheduled for day's puzzle, if there is one.
+func (s *Store) Word(day time.Time) (string, bool) {
+	e, ok := s.Get(Day(day).Format(DateLayout))
+	return e.Word, ok
+}
+
+// Get returns the entry of date.
+func (s *Store) Get(date string) (Entry, bool) {
+	if s == nil {
+		return Entry{}, false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	e, ok := s.entries[date]
+	return e, ok
+}
+
+// Between returns the entries from the day of from to the day of to,
+// inclusive, in date order.
+func (s *Store) Between(from, to time.Time) []Entry {
+	out := []Entry{}
+	if s == nil {
+		return out
+	}
+	first, last := Day(from).Format(DateLayout), Day(to).Format(DateLayout)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, date := range slices.Sorted(maps.Keys(s.entries)) {
+		if date >= first && date <= last {
+			out = append(out, s.entries[date])
+		}
+	}
+	return out
+}
+
+// DateOf returns the date word is scheduled for on or after the day of
+// from, if it is.
+func (s *Store) DateOf(word string, from time.Time) (string, bool) {
+	if s == nil {
+		return "", false
+	}
+	first := Day(from).Format(DateLayout)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for date, e := range s.entries {
+		if e.Word == word && date >= first {
+			return date, true
+		}
+	}
+	return "", false
+}
+
+// Set schedules e, replacing any entry of its date, and returns the entry
+// replaced.
+func (s *Store) Set(e Entry) (Entry, bool, error) {
+	if s == nil {
+		return Entry{}, false, errors.New("there is no schedule")
+	}
+	if _, err := ParseDate(e.Date); err != nil {
+		return Entry{}, false, err
+	}
+	if e.Created.IsZero() {
+		e.Created = time.Now()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old, replaced := s.entries[e.Date]
+	s.entries[e.Date] = e
+	s.saveLocked()
+	return old, replaced, nil
+}
+
+// Remove unschedules date and returns the entry removed.
+func (s *Store) Remove(date string) (Entry, bool) {
+	if s == nil {
+		return Entry{}, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[date]
+	if !ok {
+		return Entry{}, false
+	}
+	delete(s.entries, date)
+	s.saveLocked()
+	return e, true
+}
+
+func (s *Store) saveLocked() {
+	if s.path == "" {
+		return
+	}
+	entries := make([]Entry, 0, len(s.entries))
+	for _, date := range slices.Sorted(maps.Keys(s.entries)) {
+		entries = append(entries, s.entries[date])
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		util.LogWarn("Failed to encode schedule: %v", err)
+		return
+	}
+	if err := util.WriteFileAtomic(s.path, data); err != nil {
+		util.LogWarn("Failed to save schedule: %v", err)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	schedule "github.com/CodeAndHammer/vortludo/internal/schedule"
+)
+
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schedule.json")
+	store, err := schedule.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := store.Set(schedule.Entry{Date: "2026-13-01", Word: "APPLE"}); err == nil {
+		t.Error("Expected an invalid date to be refused")
+	}
+	store.Set(schedule.Entry{Date: "2026-10-20", Word: "APPLE"})
+	store.Set(schedule.Entry{Date: "2026-10-18", Word: "CHAIR"})
+	old, replaced, _ := store.Set(schedule.Entry{Date: "2026-10-20", Word: "TABLE", Note: "Anniversary"})
+	if !replaced || old.Word != "APPLE" {
+		t.Errorf("Expected APPLE replaced, got %+v %v", old, replaced)
+	}
+
+	reopened, err := schedule.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if word, ok := reopened.Word(time.Date(2026, 10, 20, 23, 0, 0, 0, time.UTC)); !ok || word != "TABLE" {
+		t.Errorf("Expected TABLE on the 20th, got %q", word)
+	}
+	between := reopened.Between(time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC), time.Date(2026, 10, 20, 0, 0, 0, 0, time.UTC))
+	if len(between) != 2 || between[0].Word != "CHAIR" || between[1].Note != "Anniversary" {
+		t.Errorf("Between = %+v", between)
+	}
+	if date, ok := reopened.DateOf("CHAIR", time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)); !ok || date != "2026-10-18" {
+		t.Errorf("DateOf(CHAIR) = %q %v", date, ok)
+	}
+	if _, ok := reopened.DateOf("CHAIR", time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)); ok {
+		t.Error("Days before from should be left out")
+	}
+	if _, ok := reopened.Remove("2026-10-18"); !ok {
+		t.Error("Expected the 18th removed")
+	}
+	if _, ok := reopened.Remove("2026-10-18"); ok {
+		t.Error("Removing twice should report nothing removed")
+	}
+}
+
+func TestNilStore(t *testing.T) {
+	var store *schedule.Store
+	if _, ok := store.Word(time.Now()); ok {
+		t.Error("A nil store should schedule nothing")
+	}
+	if len(store.Between(time.Now(), time.Now().AddDate(1, 0, 0))) != 0 {
+		t.Error("A nil store should list nothing")
+	}
+	if _, _, err := store.Set(schedule.Entry{Date: "2026-10-20", Word: "APPLE"}); err == nil {
+		t.Error("A nil store should refuse entries")
+	}
+}
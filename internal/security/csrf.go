@@ -0,0 +1,103 @@
+package security
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+const (
+	csrfSecretSize = 32
+	csrfNonceSize  = 16
+	// signDomain stands in for the nonce when Sign signs a value, and is
+	// never a valid one, so a signature cannot pass as a token's.
+	signDomain = "signed"
+)
+
+// CSRF issues and checks anti-forgery tokens. Each browser holds a random
+// secret in a cookie it cannot read from script; every response carries a
+// new token made of a random nonce and an HMAC, under the server's key, of
+// the secret, the session ID and the nonce. Someone who can plant cookies
+// can plant a secret and a token of their own, but the token only holds for
+// the session it was issued to, so it cannot act for another player's. No
+// two responses carry the same token.
+type CSRF struct {
+	key []byte
+}
+
+// NewCSRF returns a CSRF that signs with key. An empty key is replaced by a
+// random one, so tokens only hold on this instance until it restarts.
+func NewCSRF(key []byte) *CSRF {
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		rand.Read(key)
+	}
+	return &CSRF{key: key}
+}
+
+// NewSecret returns a random secret for a browser.
+func (c *CSRF) NewSecret() string {
+	b := make([]byte, csrfSecretSize)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// ValidSecret reports whether secret could have come from NewSecret.
+func (c *CSRF) ValidSecret(secret string) bool {
+	b, err := hex.DecodeString(secret)
+	return err == nil && len(b) == csrfSecretSize
+}
+
+// Token returns a fresh token for secret and the session sessionID, which is
+// empty before the browser has a session.
+func (c *CSRF) Token(secret, sessionID string) string {
+	nonce := make([]byte, csrfNonceSize)
+	rand.Read(nonce)
+	n := hex.EncodeToString(nonce)
+	return n + "." + hex.EncodeToString(c.mac(secret, sessionID, n))
+}
+
+// Verify reports whether token was issued for secret and sessionID.
+func (c *CSRF) Verify(secret, sessionID, token string) bool {
+	if !c.ValidSecret(secret) {
+		return false
+	}
+	nonce, sig, ok := strings.Cut(token, ".")
+	if !ok || len(nonce) != 2*csrfNonceSize {
+		return false
+	}
+	got, err := hex.DecodeString(sig)
+	return err == nil && hmac.Equal(got, c.mac(secret, sessionID, nonce))
+}
+
+func (c *CSRF) mac(parts ...string) []byte {
+	mac := hmac.New(sha256.New, c.key)
+	for i, part := range parts {
+		if i > 0 {
+			mac.Write([]byte{0})
+		}
+		mac.Write([]byte(part))
+	}
+	return mac.Sum(nil)
+}
+
+// Sign returns value followed by an HMAC of it under the server's key, for a
+// cookie whose value the browser must not be able to choose.
+func (c *CSRF) Sign(value string) string {
+	return value + "." + hex.EncodeToString(c.mac(value, signDomain))
+}
+
+// Unsign returns the value signed in token, and whether the signature holds.
+func (c *CSRF) Unsign(token string) (string, bool) {
+	value, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", false
+	}
+	got, err := hex.DecodeString(sig)
+	if err != nil || !hmac.Equal(got, c.mac(value, signDomain)) {
+		return "", false
+	}
+	return value, true
+}
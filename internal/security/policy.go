@@ -0,0 +1,231 @@
+// Package security builds the security headers sent with every response,
+// including the content security policy, from configuration. Policies are
+// validated when they are built, so a typo fails at startup rather than
+// silently weakening or breaking the site.
+package security
+
+import (
+	"cmp"
+	"crypto/rand"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// DefaultCSP allows the CDNs the templates load scripts, styles and fonts
+// from. Alpine's standard build evaluates expressions at runtime, hence
+// 'unsafe-eval'.
+const DefaultCSP = "default-src 'self'; " +
+	"script-src 'self' https://cdn.jsdelivr.net 'unsafe-inline' 'unsafe-eval'; " +
+	"style-src 'self' https://cdn.jsdelivr.net https://fonts.bunny.net 'unsafe-inline'; " +
+	"font-src 'self' https://cdn.jsdelivr.net https://fonts.bunny.net; " +
+	"img-src 'self' data:; " +
+	"connect-src 'self' https://cdn.jsdelivr.net; " +
+	"object-src 'none'; base-uri 'self'; form-action 'self'; frame-ancestors 'none'"
+
+const (
+	DefaultFrameOptions   = "DENY"
+	DefaultReferrerPolicy = "strict-origin-when-cross-origin"
+	DefaultHSTS           = "max-age=63072000; includeSubDomains; preload"
+)
+
+// Config is the security header configuration. Empty strings select the
+// defaults above.
+type Config struct {
+	CSP string
+	// ScriptNonce adds a fresh nonce to script-src on every response and drops
+	// 'unsafe-inline', which browsers ignore once a nonce is present.
+	ScriptNonce bool
+	// NoUnsafeEval removes 'unsafe-eval' from script-src. Alpine then needs
+	// its CSP build.
+	NoUnsafeEval   bool
+	FrameOptions   string
+	ReferrerPolicy string
+	HSTS           string
+}
+
+// Policy is a validated set of security headers.
+type Policy struct {
+	directives     []directive
+	scriptNonce    bool
+	FrameOptions   string
+	ReferrerPolicy string
+	HSTS           string
+}
+
+type directive struct {
+	name    string
+	sources []string
+}
+
+// NewPolicy validates cfg and builds the policy.
+func NewPolicy(cfg Config) (*Policy, error) {
+	csp := cfg.CSP
+	if csp == "" {
+		csp = DefaultCSP
+	}
+	directives, err := parseCSP(csp)
+	if err != nil {
+		return nil, err
+	}
+	p := &Policy{
+		directives:     directives,
+		scriptNonce:    cfg.ScriptNonce,
+		FrameOptions:   cmp.Or(cfg.FrameOptions, DefaultFrameOptions),
+		ReferrerPolicy: cmp.Or(cfg.ReferrerPolicy, DefaultReferrerPolicy),
+		HSTS:           cmp.Or(cfg.HSTS, DefaultHSTS),
+	}
+	if !slices.Contains([]string{"DENY", "SAMEORIGIN"}, strings.ToUpper(p.FrameOptions)) {
+		return nil, fmt.Errorf("invalid X-Frame-Options %q", p.FrameOptions)
+	}
+	if !slices.Contains(referrerPolicies, p.ReferrerPolicy) {
+		return nil, fmt.Errorf("invalid Referrer-Policy %q", p.ReferrerPolicy)
+	}
+	if !strings.HasPrefix(p.HSTS, "max-age=") {
+		return nil, fmt.Errorf("invalid Strict-Transport-Security %q", p.HSTS)
+	}
+
+	if cfg.NoUnsafeEval || cfg.ScriptNonce {
+		script := p.scriptSrc()
+		script.sources = slices.DeleteFunc(script.sources, func(s string) bool {
+			return (cfg.NoUnsafeEval && s == "'unsafe-eval'") || (cfg.ScriptNonce && s == "'unsafe-inline'")
+		})
+	}
+	return p, nil
+}
+
+// ScriptNonce reports whether responses need a nonce; see NewNonce.
+func (p *Policy) ScriptNonce() bool {
+	return p.scriptNonce
+}
+
+// CSP returns the Content-Security-Policy header value. nonce is added to
+// script-src when the policy uses nonces.
+func (p *Policy) CSP(nonce string) string {
+	var b strings.Builder
+	for i, d := range p.directives {
+		if i > 0 {
+			b.WriteString("; ")
+		}
+		b.WriteString(d.name)
+		for _, source := range d.sources {
+			b.WriteString(" ")
+			b.WriteString(source)
+		}
+		if p.scriptNonce && nonce != "" && d.name == "script-src" {
+			b.WriteString(" 'nonce-" + nonce + "'")
+		}
+	}
+	return b.String()
+}
+
+// scriptSrc returns the script-src directive, adding one based on
+// default-src if the policy has none, so nonces have somewhere to go.
+func (p *Policy) scriptSrc() *directive {
+	for i := range p.directives {
+		if p.directives[i].name == "script-src" {
+			return &p.directives[i]
+		}
+	}
+	script := directive{name: "script-src"}
+	for _, d := range p.directives {
+		if d.name == "default-src" {
+			script.sources = slices.Clone(d.sources)
+		}
+	}
+	p.directives = append(p.directives, script)
+	return &p.directives[len(p.directives)-1]
+}
+
+// NewNonce returns a random script nonce.
+func NewNonce() string {
+	return rand.Text()
+}
+
+var referrerPolicies = []string{
+	"no-referrer", "no-referrer-when-downgrade", "origin", "origin-when-cross-origin",
+	"same-origin", "strict-origin", "strict-origin-when-cross-origin", "unsafe-url",
+}
+
+var knownDirectives = []string{
+	"default-src", "script-src", "script-src-elem", "script-src-attr", "style-src",
+	"style-src-elem", "style-src-attr", "img-src", "font-src", "connect-src", "media-src",
+	"object-src", "frame-src", "child-src", "worker-src", "manifest-src", "base-uri",
+	"form-action", "frame-ancestors", "upgrade-insecure-requests", "report-uri", "report-to",
+	"sandbox",
+}
+
+var keywordSources = []string{
+	"'self'", "'none'", "'unsafe-inline'", "'unsafe-eval'", "'unsafe-hashes'",
+	"'strict-dynamic'", "'report-sample'", "'wasm-unsafe-eval'",
+}
+
+// parseCSP parses and validates a content security policy.
+func parseCSP(policy string) ([]directive, error) {
+	var directives []directive
+	seen := make(map[string]bool)
+	for part := range strings.SplitSeq(policy, ";") {
+		fields := strings.Fields(part)
+		if len(fields) == 0 {
+			continue
+		}
+		name := strings.ToLower(fields[0])
+		if !slices.Contains(knownDirectives, name) {
+			return nil, fmt.Errorf("CSP: unknown directive %q", fields[0])
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("CSP: directive %q appears twice", name)
+		}
+		seen[name] = true
+		d := directive{name: name, sources: fields[1:]}
+		switch name {
+		case "upgrade-insecure-requests":
+			if len(d.sources) > 0 {
+				return nil, fmt.Errorf("CSP: %s takes no sources", name)
+			}
+		case "report-uri", "report-to", "sandbox":
+		default:
+			for _, source := range d.sources {
+				if err := validateSource(source); err != nil {
+					return nil, fmt.Errorf("CSP: %s: %w", name, err)
+				}
+			}
+			if slices.Contains(d.sources, "'none'") && len(d.sources) > 1 {
+				return nil, fmt.Errorf("CSP: %s: 'none' cannot be combined with other sources", name)
+			}
+		}
+		directives = append(directives, d)
+	}
+	if len(directives) == 0 {
+		return nil, fmt.Errorf("CSP: policy is empty")
+	}
+	return directives, nil
+}
+
+func validateSource(source string) error {
+	lower := strings.ToLower(source)
+	switch {
+	case slices.Contains(keywordSources, lower):
+		return nil
+	case strings.HasPrefix(lower, "'nonce-") || strings.HasPrefix(lower, "'sha256-") ||
+		strings.HasPrefix(lower, "'sha384-") || strings.HasPrefix(lower, "'sha512-"):
+		if !strings.HasSuffix(source, "'") || len(source) < len("'nonce-x'") {
+			return fmt.Errorf("malformed source %q", source)
+		}
+		return nil
+	case strings.HasPrefix(source, "'"):
+		return fmt.Errorf("unknown keyword %q; host sources must not be quoted", source)
+	case strings.HasSuffix(source, ":") && !strings.Contains(source, "/"):
+		return nil
+	}
+	host := source
+	if !strings.Contains(host, "://") {
+		host = "https://" + host
+	}
+	u, err := url.Parse(strings.Replace(host, "*.", "wildcard.", 1))
+	if err != nil || u.Host == "" || strings.ContainsAny(source, ",") {
+		return fmt.Errorf("invalid host source %q", source)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	security "github.com/CodeAndHammer/vortludo/internal/security"
+)
+
+func TestCSRFTokens(t *testing.T) {
+	csrf := security.NewCSRF([]byte("key"))
+	secret := csrf.NewSecret()
+	if !csrf.ValidSecret(secret) || csrf.ValidSecret("short") {
+		t.Fatalf("ValidSecret disagrees with NewSecret for %q", secret)
+	}
+
+	first, second := csrf.Token(secret, "s1"), csrf.Token(secret, "s1")
+	if first == second {
+		t.Error("Expected every token to be different")
+	}
+	if !csrf.Verify(secret, "s1", first) || !csrf.Verify(secret, "s1", second) {
+		t.Error("Expected tokens to verify against their secret")
+	}
+	if csrf.Verify(csrf.NewSecret(), "s1", first) {
+		t.Error("Expected a token to fail against another secret")
+	}
+	if csrf.Verify(secret, "s2", first) || csrf.Verify(secret, "", first) {
+		t.Error("Expected a token to fail for another session")
+	}
+	if security.NewCSRF([]byte("other")).Verify(secret, "s1", first) {
+		t.Error("Expected a token to fail under another key")
+	}
+
+	nonce, _, _ := strings.Cut(first, ".")
+	for _, forged := range []string{"", secret, nonce, nonce + ".", nonce + "." + strings.Repeat("0", 64), "." + first} {
+		if csrf.Verify(secret, "s1", forged) {
+			t.Errorf("Forged token %q verified", forged)
+		}
+	}
+}
+
+func TestCSRFRandomKey(t *testing.T) {
+	a, b := security.NewCSRF(nil), security.NewCSRF(nil)
+	secret := a.NewSecret()
+	if b.Verify(secret, "s1", a.Token(secret, "s1")) {
+		t.Error("Expected instances without a key to sign with keys of their own")
+	}
+}
+
+func TestCSRFSign(t *testing.T) {
+	csrf := security.NewCSRF([]byte("key"))
+	secret := csrf.NewSecret()
+	signed := csrf.Sign(secret)
+	if got, ok := csrf.Unsign(signed); !ok || got != secret {
+		t.Fatalf("Unsign(Sign(%q)) = %q, %v", secret, got, ok)
+	}
+	if _, ok := security.NewCSRF([]byte("other")).Unsign(signed); ok {
+		t.Error("Expected a signature to fail under another key")
+	}
+	for _, forged := range []string{"", secret, secret + ".", csrf.NewSecret() + signed[len(secret):]} {
+		if _, ok := csrf.Unsign(forged); ok {
+			t.Errorf("Forged value %q unsigned", forged)
+		}
+	}
+	if csrf.Verify(secret, "", signed) {
+		t.Error("Expected a signature not to pass as a token")
+	}
+	if _, ok := csrf.Unsign(csrf.Token(secret, "")); ok {
+		t.Error("Expected a token not to pass as a signature")
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	security "github.com/CodeAndHammer/vortludo/internal/security"
+)
+
+func TestDefaultPolicy(t *testing.T) {
+	policy, err := security.NewPolicy(security.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policy.CSP("") != security.DefaultCSP {
+		t.Errorf("Default CSP changed in rendering:\n%s", policy.CSP(""))
+	}
+	if policy.ScriptNonce() || policy.FrameOptions != security.DefaultFrameOptions {
+		t.Errorf("Unexpected default policy: %+v", policy)
+	}
+}
+
+func TestPolicyScriptNonce(t *testing.T) {
+	policy, err := security.NewPolicy(security.Config{ScriptNonce: true, NoUnsafeEval: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	csp := policy.CSP("abc123")
+	script := ""
+	for d := range strings.SplitSeq(csp, "; ") {
+		if strings.HasPrefix(d, "script-src ") {
+			script = d
+		}
+	}
+	if !strings.Contains(script, "'nonce-abc123'") {
+		t.Errorf("Expected the nonce in script-src, got %q", script)
+	}
+	if strings.Contains(script, "'unsafe-inline'") || strings.Contains(script, "'unsafe-eval'") {
+		t.Errorf("Expected unsafe sources to be dropped, got %q", script)
+	}
+
+	// A policy without script-src gets one from default-src.
+	policy, _ = security.NewPolicy(security.Config{CSP: "default-src 'self'", ScriptNonce: true})
+	if got := policy.CSP("n"); got != "default-src 'self'; script-src 'self' 'nonce-n'" {
+		t.Errorf("Unexpected CSP: %q", got)
+	}
+}
+
+func TestPolicyRejectsInvalidConfig(t *testing.T) {
+	cases := map[string]security.Config{
+		"unknown directive": {CSP: "default-src 'self'; scirpt-src 'self'"},
+		"quoted host":       {CSP: "default-src 'https://vortludo.example.org'"},
+		"none combined":     {CSP: "object-src 'none' 'self'"},
+		"duplicate":         {CSP: "img-src 'self'; img-src data:"},
+		"referrer policy":   {ReferrerPolicy: "sometimes"},
+		"frame options":     {FrameOptions: "ALLOW-FROM https://example.org"},
+		"hsts":              {HSTS: "includeSubDomains"},
+	}
+	for name, cfg := range cases {
+		if _, err := security.NewPolicy(cfg); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	announce "github.com/CodeAndHammer/vortludo/internal/announce"
+	audit "github.com/CodeAndHammer/vortludo/internal/audit"
+	auth "github.com/CodeAndHammer/vortludo/internal/auth"
+	config "github.com/CodeAndHammer/vortludo/internal/config"
+	constants "github.com/CodeAndHammer/vortludo/internal/constants"
+	digest "github.com/CodeAndHammer/vortludo/internal/digest"
+	errreport "github.com/CodeAndHammer/vortludo/internal/errreport"
+	eventlog "github.com/CodeAndHammer/vortludo/internal/eventlog"
+	game "github.com/CodeAndHammer/vortludo/internal/game"
+	lifecycle "github.com/CodeAndHammer/vortludo/internal/lifecycle"
+	models "github.com/CodeAndHammer/vortludo/internal/models"
+	schedule "github.com/CodeAndHammer/vortludo/internal/schedule"
+	security "github.com/CodeAndHammer/vortludo/internal/security"
+	store "github.com/CodeAndHammer/vortludo/internal/store"
+	telemetry "github.com/CodeAndHammer/vortludo/internal/telemetry"
+	tournament "github.com/CodeAndHammer/vortludo/internal/tournament"
+	util "github.com/CodeAndHammer/vortludo/internal/util"
+)
+
+// open builds s.app from the configuration, loading the word lists and
+// opening the stores and logs it names. It returns the dictionary, for
+// refreshes to reload.
+func (s *Server) open() (*Dictionary, error) {
+	cfg := s.cfg
+	app := models.NewApp(cfg)
+	s.app = app
+
+	dict, err := OpenDictionary(cfg.Words)
+	if err != nil {
+		return nil, fmt.Errorf("invalid word source: %w", err)
+	}
+	if err := loadWords(app, dict, cfg.Words.DefinitionsFile); err != nil {
+		return nil, fmt.Errorf("load words: %w", err)
+	}
+	app.WordsFile = dict.editableFile(cfg.Words)
+	if app.Packs, err = game.LoadPacks(cfg.Words.PacksFile); err != nil {
+		return nil, fmt.Errorf("load puzzle packs: %w", err)
+	}
+	for _, pack := range app.Packs {
+		for _, word := range pack.Words {
+			if !game.IsValidWord(app, word) {
+				util.LogWarn("Puzzle pack %s: %s is not a target word and will be skipped", pack.ID, word)
+			}
+		}
+	}
+
+	if app.Tournament, err = tournament.Open(cfg.Game.TournamentFile); err != nil {
+		return nil, fmt.Errorf("load tournament store: %w", err)
+	}
+	if app.Schedule, err = schedule.Open(cfg.Game.ScheduleFile); err != nil {
+		return nil, fmt.Errorf("load the word schedule: %w", err)
+	}
+	if app.Announcements, err = announce.Open(cfg.Server.AnnouncementFile); err != nil {
+		return nil, fmt.Errorf("load announcement: %w", err)
+	}
+	if app.Audit, err = audit.Open(cfg.Accounts.AuditFile); err != nil {
+		return nil, fmt.Errorf("open the audit log: %w", err)
+	}
+	var mailer auth.Mailer
+	if m := loadMailer(cfg); m != nil {
+		s.mail = auth.NewMailQueue(m)
+		mailer = s.mail
+	}
+	if app.Accounts, err = auth.Open(cfg.Accounts.File, mailer); err != nil {
+		return nil, fmt.Errorf("load account store: %w", err)
+	}
+	if app.OIDC, err = loadOIDC(app, cfg.OIDC); err != nil {
+		return nil, fmt.Errorf("invalid OIDC configuration: %w", err)
+	}
+	if app.Security, err = loadSecurityPolicy(cfg.Security); err != nil {
+		return nil, fmt.Errorf("invalid security header policy: %w", err)
+	}
+	if cfg.EventLog.Dir != "" {
+		app.EventLog, err = eventlog.Open(cfg.EventLog.Dir, eventlog.Options{
+			MaxSize:  cfg.EventLog.MaxSize,
+			MaxFiles: cfg.EventLog.MaxFiles,
+			Sync:     cfg.EventLog.Sync,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("open event log: %w", err)
+		}
+	}
+	if cfg.Store.URL != "" {
+		app.Store, err = store.Open(context.Background(), cfg.Store.URL, store.Options{
+			MaxOpenConns:    cfg.Store.MaxConns,
+			MaxIdleConns:    cfg.Store.MaxIdleConns,
+			ConnMaxLifetime: cfg.Store.ConnMaxLifetime,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("open the store: %w", err)
+		}
+		util.LogInfo("Persisting sessions to %s", store.Redact(cfg.Store.URL))
+		if cfg.Store.FlushInterval > 0 {
+			app.WriteBehind = store.NewWriteBehind(app.Store, cfg.Store.FlushInterval, cfg.Store.FlushBatch)
+		}
+	}
+	if cfg.Telemetry.Enabled {
+		app.Telemetry, err = telemetry.New(telemetry.Config{
+			Interval: cfg.Telemetry.Interval,
+			File:     cfg.Telemetry.File,
+			Endpoint: cfg.Telemetry.Endpoint,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("invalid telemetry configuration: %w", err)
+		}
+		util.LogInfo("Anonymous telemetry enabled, reporting every %s", cfg.Telemetry.Interval)
+	}
+	if app.Maintenance.Load() {
+		util.LogInfo("Starting in maintenance mode; /readyz reports unavailable")
+	}
+	return dict, nil
+}
+
+// openErrorReporter sets up where recovered panics are reported, if
+// anywhere.
+func openErrorReporter(cfg config.Errors) (*errreport.Reporter, error) {
+	var sinks []errreport.Sink
+	if cfg.DSN != "" {
+		sentry, err := errreport.NewSentry(cfg.DSN, nil)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ERROR_REPORT_DSN: %w", err)
+		}
+		sinks = append(sinks, sentry)
+	}
+	if cfg.File != "" {
+		file, err := errreport.OpenFile(cfg.File)
+		if err != nil {
+			return nil, fmt.Errorf("open the error report file: %w", err)
+		}
+		sinks = append(sinks, file)
+	}
+	if len(sinks) > 0 {
+		util.LogInfo("Reporting panics to %d error sinks", len(sinks))
+	}
+	return errreport.New(sinks...), nil
+}
+
+// startDigest writes the previous day's stats digest shortly after each
+// midnight UTC, when a destination is configured.
+func startDigest(app *models.App, cfg *config.Config, sup *lifecycle.Supervisor) error {
+	if cfg.Digest.Destination == "" {
+		return nil
+	}
+	writer, err := digest.NewWriter(cfg.Digest.Destination, cfg.Digest.Formats, s3Config(cfg.Words))
+	if err != nil {
+		return fmt.Errorf("invalid digest configuration: %w", err)
+	}
+	sup.Daily("stats digest", cfg.Digest.Offset, func(ctx context.Context) {
+		now := time.Now()
+		day := now.UTC().AddDate(0, 0, -1).Format(time.DateOnly)
+		written, err := writer.Write(ctx, digest.New(app.Analytics, day, now))
+		if err != nil {
+			util.LogWarn("Failed to write the stats digest of %s to %s: %v", day, writer, err)
+			return
+		}
+		util.LogInfo("Wrote the stats digest of %s to %s", day, strings.Join(written, ", "))
+	})
+	return nil
+}
+
+// loadMailer picks how sign-in links are sent: through the SMTP server when
+// one is set, to the log outside production, and not at all otherwise.
+func loadMailer(cfg *config.Config) auth.Mailer {
+	switch {
+	case cfg.Mail.Host != "":
+		addr := net.JoinHostPort(cfg.Mail.Host, cfg.Mail.Port)
+		util.LogInfo("Emailing sign-in links through %s", addr)
+		return auth.SMTPMailer{Addr: addr, Username: cfg.Mail.Username, Password: cfg.Mail.Password, From: cfg.Mail.From}
+	case !cfg.Production:
+		util.LogInfo("Writing sign-in links to the log; set SMTP_HOST to email them")
+		return auth.LogMailer{}
+	default:
+		util.LogInfo("Sign-in links are off until SMTP_HOST is set")
+		return nil
+	}
+}
+
+// loadOIDC configures sign-in through an OpenID Connect provider when an
+// issuer is set.
+func loadOIDC(app *models.App, cfg config.OIDC) (*auth.OIDCProvider, error) {
+	if cfg.Issuer == "" {
+		return nil, nil
+	}
+	redirectURL := cfg.RedirectURL
+	if redirectURL == "" && app.PublicURL != "" {
+		redirectURL = app.PublicURL + app.Path(constants.RouteAccountOIDCCallback)
+	}
+	provider, err := auth.NewOIDCProvider(auth.OIDCConfig{
+		Name:         cfg.ProviderName,
+		Issuer:       cfg.Issuer,
+		ClientID:     cfg.ClientID,
+		ClientSecret: cfg.ClientSecret,
+		RedirectURL:  redirectURL,
+		Scopes:       cfg.Scopes,
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	util.LogInfo("Sign-in with %s enabled", provider.Name())
+	return provider, nil
+}
+
+// loadSecurityPolicy builds the security headers.
+func loadSecurityPolicy(cfg config.Security) (*security.Policy, error) {
+	return security.NewPolicy(security.Config{
+		CSP:            cfg.CSP,
+		ScriptNonce:    cfg.ScriptNonce,
+		NoUnsafeEval:   !cfg.UnsafeEval,
+		FrameOptions:   cfg.FrameOptions,
+		ReferrerPolicy: cfg.ReferrerPolicy,
+		HSTS:           cfg.HSTS,
+	})
+}
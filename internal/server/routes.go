@@ -0,0 +1,167 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"maps"
+
+	"github.com/CodeAndHammer/vortludo"
+	assets "github.com/CodeAndHammer/vortludo/internal/assets"
+	config "github.com/CodeAndHammer/vortludo/internal/config"
+	constants "github.com/CodeAndHammer/vortludo/internal/constants"
+	errreport "github.com/CodeAndHammer/vortludo/internal/errreport"
+	handlers "github.com/CodeAndHammer/vortludo/internal/handlers"
+	lifecycle "github.com/CodeAndHammer/vortludo/internal/lifecycle"
+	middleware "github.com/CodeAndHammer/vortludo/internal/middleware"
+	models "github.com/CodeAndHammer/vortludo/internal/models"
+	render "github.com/CodeAndHammer/vortludo/internal/render"
+	util "github.com/CodeAndHammer/vortludo/internal/util"
+	"github.com/gin-gonic/gin"
+)
+
+// newRouter builds the router serving the game, its assets and the admin
+// routes. Templates served from disk in development are watched for changes
+// by a routine of sup.
+func newRouter(app *models.App, cfg *config.Config, errorReporter *errreport.Reporter, sup *lifecycle.Supervisor) (*gin.Engine, error) {
+	isProduction := cfg.Production
+	router := gin.New()
+	router.Use(
+		middleware.RecoveryMiddleware(errorReporter, func(c *gin.Context) { handlers.InternalErrorHandler(app, c) }),
+		gin.Logger(),
+		middleware.RequestIDMiddleware(),
+		middleware.TimeoutMiddleware(app, func(c *gin.Context) { handlers.TimeoutHandler(app, c) }),
+		middleware.SecurityHeadersMiddleware(app),
+		middleware.CompressionMiddleware(),
+		middleware.AbuseMiddleware(app),
+		middleware.RateLimitMiddleware(app),
+		middleware.BodyLimitMiddleware(app, func(c *gin.Context) { handlers.PayloadTooLargeHandler(app, c) }),
+		middleware.CSRFMiddleware(app),
+		middleware.ThemeMiddleware(app),
+		middleware.ValidateCSRFMiddleware(app),
+	)
+
+	if vortludo.FromDisk() {
+		util.LogInfo("Serving assets from disk (ASSETS_DIR=%s)", cfg.Assets.Dir)
+	} else {
+		util.LogInfo("Serving embedded assets")
+	}
+	if vortludo.FromDisk() && !isProduction {
+		app.Assets = assets.Passthrough(vortludo.StaticFS(), app.Path(constants.RouteStatic))
+	} else {
+		manifest, err := assets.Build(vortludo.StaticFS(), app.Path(constants.RouteStatic))
+		if err != nil {
+			return nil, fmt.Errorf("fingerprint static assets: %w", err)
+		}
+		app.Assets = manifest
+		util.LogInfo("Fingerprinted %d static assets", manifest.Len())
+	}
+
+	renderCache := render.NewCache(cfg.Assets.RenderCacheSize)
+	funcs := app.Assets.Funcs()
+	maps.Copy(funcs, app.Announcements.Funcs())
+	maps.Copy(funcs, app.Funcs())
+	templates, err := render.NewTemplates(vortludo.TemplatesFS(), renderCache, funcs)
+	if err != nil {
+		return nil, fmt.Errorf("parse templates: %w", err)
+	}
+	router.HTMLRender = templates
+	if vortludo.FromDisk() && !isProduction {
+		sup.Go("template watcher", func(ctx context.Context) error {
+			templates.Watch(ctx, cfg.Assets.TemplateReloadInterval)
+			return nil
+		})
+	}
+
+	// Every route is served under the base path; the rest of the site is
+	// left to whatever mounts the game there.
+	base := router.Group(app.BasePath)
+	base.GET(constants.RouteStatic+"/*filepath", func(c *gin.Context) { handlers.StaticHandler(app, c) })
+	base.HEAD(constants.RouteStatic+"/*filepath", func(c *gin.Context) { handlers.StaticHandler(app, c) })
+
+	// Routes that start a game for a session without one are held to the
+	// session limit.
+	admit := middleware.SessionLimitMiddleware(app, func(c *gin.Context) { handlers.ServerFullHandler(app, c) })
+	base.GET(constants.RouteHome, admit, func(c *gin.Context) { handlers.HomeHandler(app, c) })
+	base.GET(constants.RouteNewGame, admit, func(c *gin.Context) { handlers.NewGameHandler(app, c) })
+	base.POST(constants.RouteNewGame, admit, func(c *gin.Context) { handlers.NewGameHandler(app, c) })
+	base.POST(constants.RouteRetryWord, admit, func(c *gin.Context) { handlers.RetryWordHandler(app, c) })
+	base.POST(constants.RouteGuess, admit, func(c *gin.Context) { handlers.GuessHandler(app, c) })
+	base.POST(constants.RouteKey, admit, func(c *gin.Context) { handlers.KeyHandler(app, c) })
+	base.GET(constants.RouteGameState, admit, func(c *gin.Context) { handlers.GameStateHandler(app, c) })
+	base.GET(constants.RouteRaceState, admit, func(c *gin.Context) { handlers.RaceStateHandler(app, c) })
+	base.GET(constants.RouteSettings, func(c *gin.Context) { handlers.SettingsHandler(app, c) })
+	base.POST(constants.RouteSettings, func(c *gin.Context) { handlers.UpdateSettingsHandler(app, c) })
+	base.POST(constants.RouteSettingsTheme, func(c *gin.Context) { handlers.ThemeHandler(app, c) })
+	base.GET(constants.RoutePreviewImage, func(c *gin.Context) { handlers.PreviewImageHandler(app, c) })
+	base.GET(constants.RouteShareImage, func(c *gin.Context) { handlers.ShareImageHandler(app, c) })
+	base.GET(constants.RouteStats, func(c *gin.Context) { handlers.StatsHandler(app, c) })
+	base.GET(constants.RoutePacks, func(c *gin.Context) { handlers.PacksHandler(app, c) })
+	base.GET(constants.RouteAchievements, func(c *gin.Context) { handlers.AchievementsHandler(app, c) })
+	base.POST(constants.RouteHint, admit, func(c *gin.Context) { handlers.HintHandler(app, c) })
+	base.POST(constants.RouteAPIHintNext, admit, func(c *gin.Context) { handlers.SolverHintHandler(app, c) })
+	base.GET(constants.RouteAPIValidate,
+		middleware.ScopedRateLimitMiddleware(app, "validate", app.ValidateRPS, app.ValidateBurst),
+		func(c *gin.Context) { handlers.ValidateWordHandler(app, c) })
+	base.GET(constants.RouteTournament, func(c *gin.Context) { handlers.TournamentHandler(app, c) })
+	base.GET(constants.RouteAnnouncement, func(c *gin.Context) { handlers.AnnouncementHandler(app, c) })
+	base.GET(constants.RouteAnnouncementEvents, func(c *gin.Context) { handlers.AnnouncementEventsHandler(app, c) })
+	base.POST(constants.RouteSpectate, admit, func(c *gin.Context) { handlers.SpectateHandler(app, c) })
+	base.POST(constants.RouteSpectateRevoke, admit, func(c *gin.Context) { handlers.SpectateRevokeHandler(app, c) })
+	base.GET(constants.RouteWatch+"/:token", func(c *gin.Context) { handlers.WatchHandler(app, c) })
+	base.GET(constants.RouteWatch+"/:token/board", func(c *gin.Context) { handlers.WatchBoardHandler(app, c) })
+	base.GET(constants.RouteWatch+"/:token/events", func(c *gin.Context) { handlers.WatchEventsHandler(app, c) })
+
+	accountLimit := middleware.ScopedRateLimitMiddleware(app, "account", constants.AccountRateLimitRPS, constants.AccountRateLimitBurst)
+	base.GET(constants.RouteAccount, func(c *gin.Context) { handlers.AccountHandler(app, c) })
+	base.POST(constants.RouteAccountRegister, accountLimit, func(c *gin.Context) { handlers.RegisterHandler(app, c) })
+	base.POST(constants.RouteAccountLogin, accountLimit, func(c *gin.Context) { handlers.LoginHandler(app, c) })
+	base.POST(constants.RouteAccountLogout, func(c *gin.Context) { handlers.LogoutHandler(app, c) })
+	base.GET(constants.RoutePrivacyExport, func(c *gin.Context) { handlers.PrivacyExportHandler(app, c) })
+	base.POST(constants.RoutePrivacyDelete, func(c *gin.Context) { handlers.PrivacyDeleteHandler(app, c) })
+	if app.MinimalCookies {
+		base.POST(constants.RouteConsent, func(c *gin.Context) { handlers.ConsentHandler(app, c) })
+	}
+	if app.Accounts.SendsLinks() {
+		base.POST(constants.RouteAccountMagicLink, accountLimit, func(c *gin.Context) { handlers.MagicLinkHandler(app, c) })
+		base.GET(constants.RouteAccountMagic, accountLimit, func(c *gin.Context) { handlers.MagicLinkRedeemHandler(app, c) })
+	}
+	if app.OIDC != nil {
+		base.GET(constants.RouteAccountOIDCLogin, accountLimit, func(c *gin.Context) { handlers.OIDCLoginHandler(app, c) })
+		base.GET(constants.RouteAccountOIDCCallback, func(c *gin.Context) { handlers.OIDCCallbackHandler(app, c) })
+		base.GET(constants.RouteAccountOIDCComplete, accountLimit, func(c *gin.Context) { handlers.OIDCCompleteHandler(app, c) })
+		base.POST(constants.RouteAccountOIDCLogout, func(c *gin.Context) { handlers.OIDCLogoutHandler(app, c) })
+	}
+	router.HandleMethodNotAllowed = true
+	router.NoRoute(func(c *gin.Context) { handlers.NotFoundHandler(app, c) })
+	router.NoMethod(func(c *gin.Context) { handlers.MethodNotAllowedHandler(app, c) })
+
+	base.GET(constants.RouteLivez, func(c *gin.Context) { handlers.LivezHandler(app, c) })
+	base.GET(constants.RouteReadyz, func(c *gin.Context) { handlers.ReadyzHandler(app, c) })
+
+	admin := base.Group(constants.RouteAdminPrefix, middleware.AdminAuthMiddleware(app))
+	admin.POST(constants.RouteAdminReloadBlocklist, func(c *gin.Context) { handlers.AdminReloadBlocklistHandler(app, c) })
+	admin.GET(constants.RouteAdminMetricsSummary, func(c *gin.Context) { handlers.AdminMetricsSummaryHandler(app, c) })
+	admin.GET(constants.RouteAdminWordStats, func(c *gin.Context) { handlers.AdminWordStatsHandler(app, c) })
+	admin.POST(constants.RouteAdminMaintenance, func(c *gin.Context) { handlers.AdminMaintenanceHandler(app, c) })
+	admin.GET(constants.RouteAdminGame, func(c *gin.Context) { handlers.DebugGameHandler(app, c) })
+	admin.GET(constants.RouteAdminBans, func(c *gin.Context) { handlers.AdminBansHandler(app, c) })
+	admin.POST(constants.RouteAdminLiftBan, func(c *gin.Context) { handlers.AdminLiftBanHandler(app, c) })
+	admin.GET(constants.RouteAdminBots, func(c *gin.Context) { handlers.AdminBotsHandler(app, c) })
+	admin.POST(constants.RouteAdminClearBot, func(c *gin.Context) { handlers.AdminClearBotHandler(app, c) })
+	admin.GET(constants.RouteAdminAnnouncement, func(c *gin.Context) { handlers.AdminAnnouncementHandler(app, c) })
+	admin.POST(constants.RouteAdminAnnouncement, func(c *gin.Context) { handlers.AdminSetAnnouncementHandler(app, c) })
+	admin.DELETE(constants.RouteAdminAnnouncement, func(c *gin.Context) { handlers.AdminClearAnnouncementHandler(app, c) })
+	admin.GET(constants.RouteAdminAudit, func(c *gin.Context) { handlers.AdminAuditHandler(app, c) })
+	admin.POST(constants.RouteAdminWords, func(c *gin.Context) { handlers.AdminAddWordHandler(app, c) })
+	admin.DELETE(constants.RouteAdminWord, func(c *gin.Context) { handlers.AdminRemoveWordHandler(app, c) })
+	admin.POST(constants.RouteAdminRetireWord, func(c *gin.Context) { handlers.AdminRetireWordHandler(app, c) })
+	admin.POST(constants.RouteAdminRestoreWord, func(c *gin.Context) { handlers.AdminRestoreWordHandler(app, c) })
+	admin.GET(constants.RouteAdminSchedule, func(c *gin.Context) { handlers.AdminScheduleHandler(app, c) })
+	admin.POST(constants.RouteAdminSchedule, func(c *gin.Context) { handlers.AdminScheduleWordHandler(app, c) })
+	admin.DELETE(constants.RouteAdminScheduleDay, func(c *gin.Context) { handlers.AdminUnscheduleWordHandler(app, c) })
+	if !isProduction {
+		base.GET(constants.RouteDebugGame, func(c *gin.Context) { handlers.DebugGameHandler(app, c) })
+		base.GET(constants.RouteDebugScore, func(c *gin.Context) { handlers.DebugScoreHandler(app, c) })
+	}
+	return router, nil
+}
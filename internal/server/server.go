@@ -0,0 +1,300 @@
+// Package server assembles Vortludo from its configuration: it opens the
+// word lists and stores, builds the router and runs the background
+// routines, once per tenant in a multi-tenant process. Another Go program
+// can mount Handler under a path of its own, and tests can drive the whole
+// stack, without going through main.
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/CodeAndHammer/vortludo"
+	auth "github.com/CodeAndHammer/vortludo/internal/auth"
+	config "github.com/CodeAndHammer/vortludo/internal/config"
+	errreport "github.com/CodeAndHammer/vortludo/internal/errreport"
+	lifecycle "github.com/CodeAndHammer/vortludo/internal/lifecycle"
+	listener "github.com/CodeAndHammer/vortludo/internal/listener"
+	middleware "github.com/CodeAndHammer/vortludo/internal/middleware"
+	models "github.com/CodeAndHammer/vortludo/internal/models"
+	session "github.com/CodeAndHammer/vortludo/internal/session"
+	util "github.com/CodeAndHammer/vortludo/internal/util"
+	"github.com/gin-gonic/gin"
+)
+
+// Config is the configuration the server runs with, as config.Load reads
+// it or config.Default gives it.
+type Config = config.Config
+
+// Server is a running instance of the game, or the tenants of a
+// multi-tenant process, each an instance of its own. Background routines run
+// from New until Close.
+type Server struct {
+	cfg           *config.Config
+	app           *models.App
+	router        *gin.Engine
+	handler       http.Handler
+	sup           *lifecycle.Supervisor
+	errorReporter *errreport.Reporter
+	mail          *auth.MailQueue
+	tenants       []*tenant
+
+	closeStreams sync.Once
+	closeOnce    sync.Once
+}
+
+// New opens what cfg names, restores the sessions saved by the last run
+// and starts the background routines. The server does not listen until Run
+// is called; its Handler can be served by other means instead. With a
+// tenants file, it starts an instance for each tenant instead.
+func New(cfg Config) (_ *Server, err error) {
+	if cfg.Production {
+		gin.SetMode(gin.ReleaseMode)
+	}
+	vortludo.UseAssetsDir(cfg.Assets.Dir)
+	if cfg.Server.TenantsFile != "" {
+		return newTenants(cfg)
+	}
+
+	s := &Server{cfg: &cfg, sup: lifecycle.New(context.Background())}
+	defer func() {
+		if err != nil {
+			s.sup.Stop(context.Background())
+			s.release(context.Background())
+		}
+	}()
+	dict, err := s.open()
+	if err != nil {
+		return nil, err
+	}
+	if s.errorReporter, err = openErrorReporter(cfg.Errors); err != nil {
+		return nil, err
+	}
+	if s.router, err = newRouter(s.app, s.cfg, s.errorReporter, s.sup); err != nil {
+		return nil, err
+	}
+	s.handler = s.router
+	s.app.Closing = make(chan struct{})
+
+	s.restore()
+	if err := s.start(dict); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Handler returns the handler serving the game.
+func (s *Server) Handler() http.Handler {
+	return s.handler
+}
+
+// App returns the state the server runs on, which is nil for a multi-tenant
+// server; see Tenant.
+func (s *Server) App() *models.App {
+	return s.app
+}
+
+// Run serves Handler on the listeners the configuration describes until
+// ctx is done or a listener fails, then shuts the listeners down and
+// closes the server. It returns the listener's error, if one failed.
+func (s *Server) Run(ctx context.Context) error {
+	srv, err := listener.New(s.handler, listenerConfig(s.cfg.Server))
+	if err != nil {
+		s.Close(ctx)
+		return fmt.Errorf("invalid server configuration: %w", err)
+	}
+	srv.RegisterOnShutdown(s.endStreams)
+
+	served := make(chan error, 1)
+	go func() {
+		util.LogInfo("Starting server on %s (production: %v)", srv.Describe(), s.cfg.Production)
+		served <- srv.ListenAndServe()
+	}()
+	var serveErr error
+	select {
+	case <-ctx.Done():
+	case serveErr = <-served:
+	}
+	util.LogInfo("Shutting down server...")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		util.LogWarn("Server shutdown did not complete cleanly: %v", err)
+	}
+	s.Close(shutdownCtx)
+	if errors.Is(serveErr, http.ErrServerClosed) {
+		serveErr = nil
+	}
+	return serveErr
+}
+
+// Close ends open event streams, stops the background routines, saves the
+// sessions for the next run and closes the stores and logs. Failures are
+// logged, as there is nothing left to do about them. Calls after the first
+// do nothing.
+func (s *Server) Close(ctx context.Context) {
+	s.closeOnce.Do(func() {
+		for _, t := range s.tenants {
+			t.server.Close(ctx)
+		}
+		if s.app == nil {
+			return
+		}
+		s.endStreams()
+		if err := s.sup.Stop(ctx); err != nil {
+			util.LogWarn("Background routines did not stop cleanly: %v", err)
+		}
+		s.save(ctx)
+		s.release(ctx)
+		util.LogInfo("Server stopped")
+	})
+}
+
+// endStreams closes app.Closing, which ends the event streams handlers hold
+// open.
+func (s *Server) endStreams() {
+	for _, t := range s.tenants {
+		t.server.endStreams()
+	}
+	s.closeStreams.Do(func() {
+		if s.app != nil && s.app.Closing != nil {
+			close(s.app.Closing)
+		}
+	})
+}
+
+// restore brings back the sessions of the last run, from the snapshot, the
+// store and the event log.
+func (s *Server) restore() {
+	app, cfg := s.app, s.cfg
+	snapshotFile := cfg.Sessions.SnapshotFile
+	var snapshotSeq uint64
+	if snapshotFile != "" {
+		var err error
+		if _, snapshotSeq, err = session.LoadSnapshot(app, snapshotFile); err != nil {
+			util.LogWarn("Failed to restore sessions from %s: %v", snapshotFile, err)
+		}
+	}
+	if app.Store != nil {
+		if _, err := session.LoadFromStore(context.Background(), app); err != nil {
+			util.LogWarn("Failed to restore sessions from the store: %v", err)
+		}
+	}
+	if app.EventLog != nil {
+		if _, err := session.RecoverFromEventLog(app, app.EventLog, snapshotSeq); err != nil {
+			util.LogWarn("Failed to recover games from the event log: %v", err)
+		}
+		if cfg.EventLog.BackfillAnalytics {
+			if _, err := session.BackfillAnalytics(app, app.EventLog); err != nil {
+				util.LogWarn("Failed to backfill analytics from the event log: %v", err)
+			}
+		}
+	}
+}
+
+// start runs the background routines: word list refreshes, session cleanup
+// and snapshots, queued store writes and sign-in emails, the daily digest,
+// rate limiter cleanup and telemetry.
+func (s *Server) start(dict *Dictionary) error {
+	app, cfg, sup := s.app, s.cfg, s.sup
+	startWordRefresh(app, dict, cfg.Words.RefreshInterval, sup)
+	session.StartSessionCleanup(app, sup)
+	if snapshotFile := cfg.Sessions.SnapshotFile; snapshotFile != "" && cfg.Sessions.SnapshotInterval > 0 {
+		sup.Every("session snapshot", cfg.Sessions.SnapshotInterval, func(context.Context) {
+			if _, err := session.SaveSnapshot(app, snapshotFile); err != nil {
+				util.LogWarn("Failed to save sessions to %s: %v", snapshotFile, err)
+			}
+		})
+	}
+	if app.WriteBehind != nil {
+		sup.Go("store writer", func(ctx context.Context) error {
+			app.WriteBehind.Run(ctx)
+			return nil
+		})
+	}
+	if s.mail != nil {
+		sup.Go("sign-in mail", func(ctx context.Context) error {
+			s.mail.Run(ctx)
+			return nil
+		})
+	}
+	middleware.StartLimiterCleanup(app, sup)
+	sup.Go("telemetry", func(ctx context.Context) error {
+		app.Telemetry.Run(ctx)
+		return nil
+	})
+	return startDigest(app, cfg, sup)
+}
+
+// save writes the sessions to the snapshot file and the store, for the next
+// run to restore.
+func (s *Server) save(ctx context.Context) {
+	app := s.app
+	if snapshotFile := s.cfg.Sessions.SnapshotFile; snapshotFile != "" {
+		if _, err := session.SaveSnapshot(app, snapshotFile); err != nil {
+			util.LogWarn("Failed to save sessions to %s: %v", snapshotFile, err)
+		}
+	}
+	if app.WriteBehind != nil {
+		if err := app.WriteBehind.Flush(ctx); err != nil {
+			util.LogWarn("Failed to flush queued store writes: %v", err)
+		}
+	}
+	if app.Store != nil {
+		if _, err := session.SaveToStore(ctx, app); err != nil {
+			util.LogWarn("Failed to save sessions to the store: %v", err)
+		}
+	}
+}
+
+// release closes what open opened, as far as it got.
+func (s *Server) release(ctx context.Context) {
+	app := s.app
+	if app == nil {
+		return
+	}
+	if app.Store != nil {
+		if err := app.Store.Close(); err != nil {
+			util.LogWarn("Failed to close the store: %v", err)
+		}
+	}
+	if err := app.Telemetry.Flush(ctx); err != nil {
+		util.LogWarn("Failed to send telemetry: %v", err)
+	}
+	if err := app.EventLog.Close(); err != nil {
+		util.LogWarn("Failed to close the event log: %v", err)
+	}
+	if err := s.errorReporter.Close(ctx); err != nil {
+		util.LogWarn("Failed to close the error reporter: %v", err)
+	}
+	if err := app.Audit.Close(); err != nil {
+		util.LogWarn("Failed to close the audit log: %v", err)
+	}
+}
+
+// listenerConfig describes the listeners. With TLS on, plain HTTP on the
+// redirect port is redirected to HTTPS.
+func listenerConfig(cfg config.Server) listener.Config {
+	srv := listener.Config{
+		Addr:          ":" + cfg.Port,
+		CertFile:      cfg.TLSCertFile,
+		KeyFile:       cfg.TLSKeyFile,
+		ACMEHosts:     cfg.ACMEHosts,
+		ACMECacheDir:  cfg.ACMECacheDir,
+		ACMEEmail:     cfg.ACMEEmail,
+		ACMEDirectory: cfg.ACMEDirectory,
+		Socket:        cfg.Socket,
+		SocketMode:    os.FileMode(cfg.SocketMode),
+		HTTP2:         cfg.HTTP2,
+		H2C:           cfg.H2C,
+	}
+	if cfg.TLS() && cfg.RedirectPort != "off" {
+		srv.RedirectAddr = ":" + cfg.RedirectPort
+	}
+	return srv
+}
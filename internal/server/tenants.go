@@ -0,0 +1,72 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	config "github.com/CodeAndHammer/vortludo/internal/config"
+	util "github.com/CodeAndHammer/vortludo/internal/util"
+)
+
+// tenant is an instance of a multi-tenant server.
+type tenant struct {
+	config.Tenant
+	server *Server
+}
+
+// newTenants starts an instance for each tenant of cfg's tenants file and
+// serves each request with the instance of the tenant its address belongs
+// to.
+func newTenants(cfg Config) (*Server, error) {
+	tenants, err := config.LoadTenants(cfg.Server.TenantsFile, &cfg)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{cfg: &cfg}
+	for _, t := range tenants {
+		srv, err := New(*t.Config)
+		if err != nil {
+			s.Close(context.Background())
+			return nil, fmt.Errorf("tenant %s: %w", t.Name, err)
+		}
+		s.tenants = append(s.tenants, &tenant{Tenant: t, server: srv})
+		util.LogInfo("Serving tenant %s at %s%s/", t.Name, t.Host, t.Config.Server.BasePath)
+	}
+	s.handler = http.HandlerFunc(s.serveTenant)
+	return s, nil
+}
+
+// Tenant returns the instance of the named tenant, or nil if the server has
+// no such tenant.
+func (s *Server) Tenant(name string) *Server {
+	for _, t := range s.tenants {
+		if t.Name == name {
+			return t.server
+		}
+	}
+	return nil
+}
+
+// serveTenant passes r to the tenant at its host and path. LoadTenants
+// allows no two tenants at one address, so the first match is the only one.
+func (s *Server) serveTenant(w http.ResponseWriter, r *http.Request) {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(host)
+	for _, t := range s.tenants {
+		basePath := t.Config.Server.BasePath
+		if t.Host != "" && t.Host != host {
+			continue
+		}
+		if basePath == "" || r.URL.Path == basePath || strings.HasPrefix(r.URL.Path, basePath+"/") {
+			t.server.Handler().ServeHTTP(w, r)
+			return
+		}
+	}
+	http.NotFound(w, r)
+}
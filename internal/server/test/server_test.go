@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	config "github.com/CodeAndHammer/vortludo/internal/config"
+	constants "github.com/CodeAndHammer/vortludo/internal/constants"
+	server "github.com/CodeAndHammer/vortludo/internal/server"
+	"github.com/gin-gonic/gin"
+)
+
+func testConfig(t *testing.T) server.Config {
+	gin.SetMode(gin.TestMode)
+	cfg := config.Default()
+	data := filepath.Join("..", "..", "..", "data")
+	cfg.Words.Source = filepath.Join(data, "words.json")
+	cfg.Words.AcceptedSource = filepath.Join(data, "accepted_words.txt")
+	cfg.Words.BlockedFile = filepath.Join(t.TempDir(), "blocked_words.txt")
+	cfg.Words.DefinitionsFile = filepath.Join(data, "definitions.json")
+	cfg.Words.PacksFile = filepath.Join(data, "packs.json")
+	cfg.Sessions.SnapshotFile = filepath.Join(t.TempDir(), "sessions.json")
+	return *cfg
+}
+
+func TestServerPlaysAGame(t *testing.T) {
+	cfg := testConfig(t)
+	srv, err := server.New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+	jar, _ := cookiejar.New(nil)
+	client := &http.Client{Jar: jar}
+
+	resp, err := client.Get(ts.URL + constants.RouteHome)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	token := resp.Header.Get(constants.CSRFHeader)
+	if resp.StatusCode != http.StatusOK || token == "" {
+		t.Fatalf("Home: got %d with token %q", resp.StatusCode, token)
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, ts.URL+constants.RouteGuess, strings.NewReader(url.Values{"guess": {"CRANE"}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set(constants.CSRFHeader, token)
+	resp, err = client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var body struct {
+		Guesses []any `json:"guesses"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || resp.StatusCode != http.StatusOK || len(body.Guesses) == 0 {
+		t.Fatalf("Guess: got %d, %v", resp.StatusCode, err)
+	}
+
+	srv.Close(context.Background())
+	srv.Close(context.Background())
+	if n := srv.App().Sessions.Len(); n != 1 {
+		t.Errorf("Expected the session kept until the server closed, got %d", n)
+	}
+}
+
+func TestServerUnderBasePath(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Server.BasePath = "/games/vortludo"
+	srv, err := server.New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close(context.Background())
+
+	w := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/games/vortludo/", nil))
+	page := w.Body.String()
+	if w.Code != http.StatusOK {
+		t.Fatalf("Home: got %d", w.Code)
+	}
+	for _, want := range []string{`href="/games/vortludo/settings"`, `src="/games/vortludo/static/client.`, `hx-post="/games/vortludo/guess"`} {
+		if !strings.Contains(page, want) {
+			t.Errorf("Expected %s in the page", want)
+		}
+	}
+	if cookie := w.Header().Get("Set-Cookie"); !strings.Contains(cookie, "Path=/games/vortludo/") {
+		t.Errorf("Expected cookies scoped to the base path, got %s", cookie)
+	}
+
+	for _, path := range []string{"/", "/guess", "/games/vortludo/static/client.js"} {
+		w := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if want := http.StatusNotFound; path == "/games/vortludo/static/client.js" {
+			if w.Code != http.StatusOK {
+				t.Errorf("%s: expected the asset, got %d", path, w.Code)
+			}
+		} else if w.Code != want {
+			t.Errorf("%s: expected %d outside the base path, got %d", path, want, w.Code)
+		}
+	}
+}
+
+func TestServerTenants(t *testing.T) {
+	cfg := testConfig(t)
+	dir := t.TempDir()
+	cfg.Server.TenantsFile = filepath.Join(dir, "tenants.yaml")
+	tenants := fmt.Sprintf(`
+tenants:
+  - name: eo
+    host: eo.example.com
+    words:
+      blocked_file: %s
+  - name: club
+    host: play.example.com
+    path: /club
+    words:
+      blocked_file: %s
+    game:
+      default_theme: dark
+`, filepath.Join(dir, "eo-blocked.txt"), filepath.Join(dir, "club-blocked.txt"))
+	if err := os.WriteFile(cfg.Server.TenantsFile, []byte(tenants), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg.Sessions.SnapshotFile = ""
+	srv, err := server.New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close(context.Background())
+
+	get := func(host, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Host = host
+		srv.Handler().ServeHTTP(w, req)
+		return w
+	}
+	if w := get("EO.example.com:8080", "/"); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `data-theme="auto"`) {
+		t.Errorf("eo: got %d", w.Code)
+	}
+	if w := get("play.example.com", "/club/"); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `data-theme="dark"`) {
+		t.Errorf("club: expected its default theme, got %d", w.Code)
+	}
+	for _, addr := range [][2]string{{"play.example.com", "/"}, {"other.example.com", "/club/"}, {"play.example.com", "/clubhouse"}} {
+		if w := get(addr[0], addr[1]); w.Code != http.StatusNotFound {
+			t.Errorf("%s%s: expected no tenant, got %d", addr[0], addr[1], w.Code)
+		}
+	}
+	if srv.App() != nil || srv.Tenant("missing") != nil {
+		t.Error("A multi-tenant server has no app of its own")
+	}
+	for _, name := range []string{"eo", "club"} {
+		if n := srv.Tenant(name).App().Sessions.Len(); n != 1 {
+			t.Errorf("%s: expected a session of its own, got %d", name, n)
+		}
+	}
+}
+
+func TestNewRejectsMissingWords(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Words.Source = filepath.Join(t.TempDir(), "missing.json")
+	if _, err := server.New(cfg); err == nil {
+		t.Fatal("Expected New to fail without a word list")
+	}
+}
+
+func TestSignInLinksNeedAMailerInProduction(t *testing.T) {
+	for _, production := range []bool{false, true} {
+		cfg := testConfig(t)
+		cfg.Production = production
+		srv, err := server.New(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer srv.Close(context.Background())
+
+		w := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, constants.RouteAccount, nil))
+		if hasForm := strings.Contains(w.Body.String(), constants.RouteAccountMagicLink); hasForm == production {
+			t.Errorf("Production %v: sign-in link form shown %v", production, hasForm)
+		}
+		w = httptest.NewRecorder()
+		srv.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, constants.RouteAccountMagic+"?token=x", nil))
+		if notFound := w.Code == http.StatusNotFound; notFound != production {
+			t.Errorf("Production %v: redeeming a link got %d", production, w.Code)
+		}
+	}
+}
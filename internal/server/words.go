@@ -0,0 +1,167 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	config "github.com/CodeAndHammer/vortludo/internal/config"
+	game "github.com/CodeAndHammer/vortludo/internal/game"
+	lifecycle "github.com/CodeAndHammer/vortludo/internal/lifecycle"
+	models "github.com/CodeAndHammer/vortludo/internal/models"
+	util "github.com/CodeAndHammer/vortludo/internal/util"
+	wordsource "github.com/CodeAndHammer/vortludo/internal/wordsource"
+)
+
+// Dictionary is where the word lists are loaded from, and the lists last
+// loaded, kept so a refresh can replace just the list that changed.
+type Dictionary struct {
+	words    *wordsource.Loader
+	accepted *wordsource.Loader
+
+	wordList     []models.WordEntry
+	acceptedList map[string]struct{}
+}
+
+// OpenDictionary configures the word list sources. Each takes a file path,
+// an http(s):// URL or s3://bucket/key; remote lists are cached in the cache
+// directory.
+func OpenDictionary(cfg config.Words) (*Dictionary, error) {
+	s3 := s3Config(cfg)
+	loader := func(location, checksum, cacheName string) (*wordsource.Loader, error) {
+		src, err := wordsource.Open(location, s3)
+		if err != nil {
+			return nil, err
+		}
+		l := &wordsource.Loader{Source: src, Checksum: checksum}
+		if cfg.CacheDir != "" && wordsource.Remote(src) {
+			l.CachePath = filepath.Join(cfg.CacheDir, cacheName)
+		}
+		return l, nil
+	}
+
+	words, err := loader(cfg.Source, cfg.SourceSHA256, "words.json")
+	if err != nil {
+		return nil, err
+	}
+	accepted, err := loader(cfg.AcceptedSource, cfg.AcceptedSHA256, "accepted_words.txt")
+	if err != nil {
+		return nil, err
+	}
+	return &Dictionary{words: words, accepted: accepted}, nil
+}
+
+// Words loads the word list.
+func (d *Dictionary) Words(ctx context.Context) ([]models.WordEntry, error) {
+	if _, err := d.words.Load(ctx, d.parseWords); err != nil {
+		return nil, fmt.Errorf("load %s: %w", d.words.Source, err)
+	}
+	return d.wordList, nil
+}
+
+// Accepted loads the list of words accepted as guesses.
+func (d *Dictionary) Accepted(ctx context.Context) (map[string]struct{}, error) {
+	if _, err := d.accepted.Load(ctx, d.parseAccepted); err != nil {
+		return nil, fmt.Errorf("load %s: %w", d.accepted.Source, err)
+	}
+	return d.acceptedList, nil
+}
+
+// s3Config is the S3 storage the word lists, and digests, may be kept in.
+func s3Config(cfg config.Words) wordsource.S3Config {
+	return wordsource.S3Config{
+		Endpoint:     cfg.S3Endpoint,
+		Region:       cfg.S3Region,
+		AccessKey:    cfg.S3AccessKey,
+		SecretKey:    cfg.S3SecretKey,
+		SessionToken: cfg.S3SessionToken,
+	}
+}
+
+// editableFile returns the word list file admins may edit: the list must be
+// a local file, and not pinned by checksum, which an edit would break.
+func (d *Dictionary) editableFile(cfg config.Words) string {
+	file, ok := d.words.Source.(*wordsource.FileSource)
+	if !ok || cfg.SourceSHA256 != "" {
+		return ""
+	}
+	return file.Path
+}
+
+func (d *Dictionary) parseWords(data []byte) error {
+	words, err := game.ParseWordList(data)
+	if err != nil {
+		return err
+	}
+	d.wordList = words
+	return nil
+}
+
+func (d *Dictionary) parseAccepted(data []byte) error {
+	accepted, err := game.ParseAcceptedWords(data)
+	if err != nil {
+		return err
+	}
+	d.acceptedList = accepted
+	return nil
+}
+
+func loadWords(app *models.App, d *Dictionary, definitionsFile string) error {
+	ctx := context.Background()
+	if _, err := d.words.Load(ctx, d.parseWords); err != nil {
+		return err
+	}
+	if _, err := d.accepted.Load(ctx, d.parseAccepted); err != nil {
+		return err
+	}
+	game.SetDictionary(app, d.wordList, d.acceptedList)
+
+	var err error
+	if app.Definitions, err = game.LoadDefinitions(definitionsFile); err != nil {
+		return err
+	}
+	if _, err := game.ReloadBlockedWords(app); err != nil {
+		return err
+	}
+
+	words, accepted := game.DictionarySize(app)
+	util.LogInfo("Loaded %d words from %s, %d accepted words from %s and %d definitions",
+		words, d.words.Source, accepted, d.accepted.Source, len(app.Definitions))
+	return nil
+}
+
+// refreshWords fetches both word lists again and swaps them in if either
+// changed. A list that cannot be fetched or fails verification leaves the
+// current one in place.
+func refreshWords(ctx context.Context, app *models.App, d *Dictionary) {
+	changed := false
+	for _, list := range []struct {
+		loader *wordsource.Loader
+		parse  func([]byte) error
+	}{{d.words, d.parseWords}, {d.accepted, d.parseAccepted}} {
+		_, err := list.loader.Fetch(ctx, list.parse)
+		switch {
+		case err == nil:
+			changed = true
+		case !errors.Is(err, wordsource.ErrNotModified):
+			util.LogWarn("Failed to refresh word list from %s, keeping the current one: %v", list.loader.Source, err)
+		}
+	}
+	if !changed {
+		return
+	}
+	game.SetDictionary(app, d.wordList, d.acceptedList)
+	words, accepted := game.DictionarySize(app)
+	util.LogInfo("Refreshed word lists: %d words, %d accepted words", words, accepted)
+}
+
+func startWordRefresh(app *models.App, d *Dictionary, interval time.Duration, sup *lifecycle.Supervisor) {
+	if interval <= 0 {
+		return
+	}
+	sup.Every("word list refresh", interval, func(ctx context.Context) {
+		refreshWords(ctx, app, d)
+	})
+}
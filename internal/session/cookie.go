@@ -0,0 +1,119 @@
+package session
+
+import (
+	"context"
+	"net/http"
+
+	constants "github.com/CodeAndHammer/vortludo/internal/constants"
+	models "github.com/CodeAndHammer/vortludo/internal/models"
+	util "github.com/CodeAndHammer/vortludo/internal/util"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// CookieID returns the session ID in the request's session cookie, or "" if
+// it has none. One set while handling the request takes precedence. With
+// minimal cookies the ID is signed, and one whose signature does not hold
+// counts as none.
+func CookieID(app *models.App, c *gin.Context) string {
+	if sessionID := c.GetString(constants.SessionIDKey); sessionID != "" {
+		return sessionID
+	}
+	value, err := c.Cookie(constants.SessionCookieName)
+	if err != nil {
+		return ""
+	}
+	if !app.MinimalCookies {
+		return value
+	}
+	sessionID, ok := app.CSRF.Unsign(value)
+	if !ok || !app.CSRF.ValidSecret(sessionID) {
+		return ""
+	}
+	return sessionID
+}
+
+// NewID returns the ID of a new session. With minimal cookies it is a CSRF
+// secret, so that the session cookie can carry both.
+func NewID(app *models.App) string {
+	if app.MinimalCookies {
+		return app.CSRF.NewSecret()
+	}
+	return uuid.NewString()
+}
+
+// SetCookie gives the browser the cookie of sessionID. With minimal cookies
+// it lasts only as long as the browser session until the player consents.
+// A CSRF token already issued with the response is issued again for the
+// session.
+func SetCookie(app *models.App, c *gin.Context, sessionID string) {
+	value, maxAge := sessionID, int(app.CookieMaxAge.Seconds())
+	if app.MinimalCookies {
+		value = app.CSRF.Sign(sessionID)
+		if !Consented(app, sessionID) {
+			maxAge = 0
+		}
+	}
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(constants.SessionCookieName, value, maxAge, app.CookiePath(), "", app.IsProduction, true)
+	c.Set(constants.SessionIDKey, sessionID)
+	if c.GetString(constants.CSRFTokenKey) != "" {
+		IssueCSRFToken(app, c)
+	}
+}
+
+// Consented reports whether the session's stats and preferences may be
+// kept beyond memory: always, unless cookies are minimal and the player has
+// not opted in.
+func Consented(app *models.App, sessionID string) bool {
+	if !app.MinimalCookies {
+		return true
+	}
+	consent, _ := Consent(app, sessionID)
+	return consent
+}
+
+// Consent returns the session's answer when asked for consent, and whether
+// it has answered.
+func Consent(app *models.App, sessionID string) (consent, answered bool) {
+	shard := app.Sessions.Shard(sessionID)
+	shard.RLock()
+	defer shard.RUnlock()
+	consent, answered = shard.Consents[sessionID]
+	return consent, answered
+}
+
+// SetConsent records the session's answer and renews its cookie to last
+// accordingly. Consent writes the session and its stats to app.Store at
+// once; withdrawn consent drops what the store holds for it. Either way the
+// session goes on in memory. The caller holds the session's lock.
+func SetConsent(ctx context.Context, app *models.App, c *gin.Context, sessionID string, consent bool) error {
+	shard := app.Sessions.Shard(sessionID)
+	shard.Lock()
+	if shard.Consents == nil {
+		shard.Consents = make(map[string]bool)
+	}
+	shard.Consents[sessionID] = consent
+	shard.Unlock()
+	SetCookie(app, c, sessionID)
+	util.LogInfo("Session %s answered the consent request: %t", sessionID, consent)
+
+	if app.Store == nil {
+		return nil
+	}
+	if consent {
+		gameState, ok := app.Sessions.Game(sessionID)
+		if !ok {
+			return nil
+		}
+		write, err := sessionWrite(app, sessionID, gameState, true)
+		if err != nil {
+			return err
+		}
+		return writeToStore(ctx, app, write)
+	}
+	if app.WriteBehind != nil {
+		app.WriteBehind.Drop(sessionID)
+	}
+	return app.Store.DeleteSession(ctx, sessionID)
+}
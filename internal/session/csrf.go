@@ -0,0 +1,75 @@
+package session
+
+import (
+	"net/http"
+
+	constants "github.com/CodeAndHammer/vortludo/internal/constants"
+	models "github.com/CodeAndHammer/vortludo/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// IssueCSRFToken gives the response a fresh CSRF token for the browser's
+// session, creating the browser's secret first if it has none. With minimal
+// cookies the secret is the session ID, so the session is created instead.
+// SetCookie calls it again once a token has been issued, so the token
+// follows a session created later in the request.
+func IssueCSRFToken(app *models.App, c *gin.Context) {
+	if app.MinimalCookies {
+		sessionID := GetOrCreateSession(app, c)
+		setCSRFToken(app, c, sessionID, sessionID)
+		return
+	}
+	secret := csrfSecret(app, c)
+	if secret == "" {
+		secret = setCSRFSecret(app, c)
+	}
+	setCSRFToken(app, c, secret, CookieID(app, c))
+}
+
+// RotateCSRF replaces the browser's secret, so that tokens issued before
+// the call stop being accepted. With minimal cookies the secret lasts as
+// long as the session, and only a fresh token is issued.
+func RotateCSRF(app *models.App, c *gin.Context) {
+	if app.MinimalCookies {
+		IssueCSRFToken(app, c)
+		return
+	}
+	setCSRFToken(app, c, setCSRFSecret(app, c), CookieID(app, c))
+}
+
+// VerifyCSRF reports whether token was issued for the browser's secret and
+// session.
+func VerifyCSRF(app *models.App, c *gin.Context, token string) bool {
+	sessionID := CookieID(app, c)
+	if app.MinimalCookies {
+		return app.CSRF.Verify(sessionID, sessionID, token)
+	}
+	secret := csrfSecret(app, c)
+	return secret != "" && app.CSRF.Verify(secret, sessionID, token)
+}
+
+// csrfSecret returns the browser's CSRF secret, or "" if it has none.
+func csrfSecret(app *models.App, c *gin.Context) string {
+	if secret := c.GetString(constants.CSRFSecretKey); secret != "" {
+		return secret
+	}
+	secret, err := c.Cookie(constants.CSRFCookieName)
+	if err != nil || !app.CSRF.ValidSecret(secret) {
+		return ""
+	}
+	return secret
+}
+
+func setCSRFSecret(app *models.App, c *gin.Context) string {
+	secret := app.CSRF.NewSecret()
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(constants.CSRFCookieName, secret, int(app.CookieMaxAge.Seconds()), app.CookiePath(), "", app.IsProduction, true)
+	c.Set(constants.CSRFSecretKey, secret)
+	return secret
+}
+
+func setCSRFToken(app *models.App, c *gin.Context, secret, sessionID string) {
+	token := app.CSRF.Token(secret, sessionID)
+	c.Set(constants.CSRFTokenKey, token)
+	c.Header(constants.CSRFHeader, token)
+}
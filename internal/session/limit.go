@@ -0,0 +1,101 @@
+package session
+
+import (
+	"slices"
+	"time"
+
+	constants "github.com/CodeAndHammer/vortludo/internal/constants"
+	models "github.com/CodeAndHammer/vortludo/internal/models"
+	util "github.com/CodeAndHammer/vortludo/internal/util"
+)
+
+// LimitStatus is how the session ceiling is holding, for /readyz and the
+// metrics summary.
+type LimitStatus struct {
+	Active   int    `json:"active"`
+	Max      int    `json:"max"`
+	Policy   string `json:"policy"`
+	Evicted  int64  `json:"evicted"`
+	Rejected int64  `json:"rejected"`
+}
+
+func Limits(app *models.App) LimitStatus {
+	return LimitStatus{
+		Active:   app.Sessions.Len(),
+		Max:      app.MaxSessions,
+		Policy:   app.SessionLimit,
+		Evicted:  app.SessionsEvicted.Load(),
+		Rejected: app.SessionsRejected.Load(),
+	}
+}
+
+// Admit reports whether sessionID may hold a game under app.MaxSessions. A
+// session that already holds one always may. When the store is full, the
+// evict policy makes room by dropping the sessions accessed least recently,
+// and the reject policy turns the session away. Concurrent admissions can
+// overshoot the ceiling by a few sessions.
+func Admit(app *models.App, sessionID string) bool {
+	if app.MaxSessions <= 0 {
+		return true
+	}
+	if _, ok := app.Sessions.Game(sessionID); ok {
+		return true
+	}
+	over := app.Sessions.Len() - app.MaxSessions + 1
+	if over <= 0 {
+		return true
+	}
+	if app.SessionLimit == constants.SessionLimitReject {
+		app.SessionsRejected.Add(1)
+		util.LogWarn("Session limit of %d reached, turning away a new session", app.MaxSessions)
+		return false
+	}
+	evicted := EvictOldest(app, over)
+	app.SessionsEvicted.Add(int64(evicted))
+	util.LogInfo("Session limit of %d reached, evicted %d least recently used sessions", app.MaxSessions, evicted)
+	return true
+}
+
+// EvictOldest drops the n sessions whose games were accessed least recently,
+// with their settings, stats and heatmaps, and returns how many it dropped.
+// A session used again while the oldest are picked is kept.
+func EvictOldest(app *models.App, n int) int {
+	type candidate struct {
+		id     string
+		access time.Time
+	}
+	var candidates []candidate
+	for shard := range app.Sessions.Shards() {
+		shard.RLock()
+		for id, game := range shard.Games {
+			candidates = append(candidates, candidate{id, game.LastAccessTime})
+		}
+		shard.RUnlock()
+	}
+	slices.SortFunc(candidates, func(a, b candidate) int {
+		return a.access.Compare(b.access)
+	})
+
+	evicted := 0
+	for _, cand := range candidates[:min(n, len(candidates))] {
+		shard := app.Sessions.Shard(cand.id)
+		shard.Lock()
+		if game, ok := shard.Games[cand.id]; ok && !game.LastAccessTime.After(cand.access) {
+			forget(app, shard, cand.id)
+			evicted++
+		}
+		shard.Unlock()
+	}
+	return evicted
+}
+
+// forget drops the session's state from shard, and its spectate token from
+// the index. The caller holds the lock.
+func forget(app *models.App, shard *models.SessionShard, sessionID string) {
+	delete(shard.Games, sessionID)
+	app.Sessions.UnindexSpectate(sessionID)
+	delete(shard.Settings, sessionID)
+	delete(shard.Stats, sessionID)
+	delete(shard.Heatmaps, sessionID)
+	delete(shard.Consents, sessionID)
+}
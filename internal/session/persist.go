@@ -0,0 +1,218 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	auth "github.com/CodeAndHammer/vortludo/internal/auth"
+	models "github.com/CodeAndHammer/vortludo/internal/models"
+	store "github.com/CodeAndHammer/vortludo/internal/store"
+	util "github.com/CodeAndHammer/vortludo/internal/util"
+)
+
+// SaveToStore writes every in-memory session, with its settings, heatmap and
+// stats, to app.Store, leaving out those without consent. Like SaveSnapshot,
+// each game is copied under its session's lock. It returns the number of
+// sessions saved.
+func SaveToStore(ctx context.Context, app *models.App) (int, error) {
+	var sessionIDs []string
+	for shard := range app.Sessions.Shards() {
+		shard.RLock()
+		sessionIDs = slices.AppendSeq(sessionIDs, maps.Keys(shard.Games))
+		shard.RUnlock()
+	}
+
+	saved := 0
+	for _, sessionID := range sessionIDs {
+		if !Consented(app, sessionID) {
+			continue
+		}
+		gameState, ok := Snapshot(app, sessionID)
+		if !ok {
+			continue
+		}
+		write, err := sessionWrite(app, sessionID, &gameState, true)
+		if err != nil {
+			return saved, err
+		}
+		if err := app.Store.Apply(ctx, []store.Write{write}); err != nil {
+			return saved, err
+		}
+		saved++
+	}
+	util.LogInfo("Saved %d sessions to the store", saved)
+	return saved, nil
+}
+
+// Persist writes the session to app.Store after a guess, with its stats once
+// the game is over. The caller holds the session's lock. It does nothing
+// without a store, and a failed write is logged rather than failing the
+// guess; the session is saved again on shutdown. Nothing is written for a
+// session without consent.
+func Persist(ctx context.Context, app *models.App, sessionID string, gameState *models.GameState) {
+	if app.Store == nil || !Consented(app, sessionID) {
+		return
+	}
+	write, err := sessionWrite(app, sessionID, gameState, gameState.GameOver)
+	if err == nil {
+		err = writeToStore(ctx, app, write)
+	}
+	if err != nil {
+		util.LogWarn("Failed to persist session %s: %v", sessionID, err)
+	}
+}
+
+// PersistCompletedWords notes words as completed by the session in
+// app.Store, first forgetting the ones noted so far if clear is set, once
+// the session has consented.
+func PersistCompletedWords(ctx context.Context, app *models.App, sessionID string, clear bool, words ...string) {
+	if app.Store == nil || !Consented(app, sessionID) {
+		return
+	}
+	write := store.Write{SessionID: sessionID, ClearCompleted: clear, CompletedWords: words}
+	if err := writeToStore(ctx, app, write); err != nil {
+		util.LogWarn("Failed to save the completed words of session %s: %v", sessionID, err)
+	}
+}
+
+// writeToStore queues w on app.WriteBehind, or makes it at once when writes
+// are not queued.
+func writeToStore(ctx context.Context, app *models.App, w store.Write) error {
+	if app.WriteBehind != nil {
+		app.WriteBehind.Enqueue(w)
+		return nil
+	}
+	return app.Store.Apply(ctx, []store.Write{w})
+}
+
+// sessionWrite encodes the session for the store, with its stats if
+// withStats is set.
+func sessionWrite(app *models.App, sessionID string, gameState *models.GameState, withStats bool) (store.Write, error) {
+	write := store.Write{SessionID: sessionID}
+	record, err := sessionRecord(app, sessionID, gameState)
+	if err != nil {
+		return write, err
+	}
+	write.Session = &record
+	if !withStats {
+		return write, nil
+	}
+	if stats, ok := storedStats(app, sessionID); ok {
+		write.Stats = &stats
+	}
+	return write, nil
+}
+
+// sessionRecord encodes the session's game, settings and heatmap for the
+// store.
+func sessionRecord(app *models.App, sessionID string, gameState *models.GameState) (store.Session, error) {
+	record := store.Session{ID: sessionID, UpdatedAt: gameState.LastAccessTime}
+	var err error
+	if record.Game, err = json.Marshal(gameState); err != nil {
+		return record, fmt.Errorf("encode game of session %s: %w", sessionID, err)
+	}
+	shard := app.Sessions.Shard(sessionID)
+	shard.RLock()
+	defer shard.RUnlock()
+	if settings, ok := shard.Settings[sessionID]; ok {
+		if record.Settings, err = json.Marshal(settings); err != nil {
+			return record, fmt.Errorf("encode settings of session %s: %w", sessionID, err)
+		}
+	}
+	if heatmap, ok := shard.Heatmaps[sessionID]; ok {
+		if record.Heatmap, err = json.Marshal(heatmap); err != nil {
+			return record, fmt.Errorf("encode heatmap of session %s: %w", sessionID, err)
+		}
+	}
+	return record, nil
+}
+
+func storedStats(app *models.App, sessionID string) (auth.Stats, bool) {
+	shard := app.Sessions.Shard(sessionID)
+	shard.RLock()
+	defer shard.RUnlock()
+	stats, ok := shard.Stats[sessionID]
+	if !ok {
+		return auth.Stats{}, false
+	}
+	return stats.Clone(), true
+}
+
+// LoadFromStore restores the sessions app.Store holds, first pruning those
+// idle longer than app.SessionTimeout. A session already in memory, e.g.
+// from a newer snapshot, is left as it is. It returns the number restored.
+func LoadFromStore(ctx context.Context, app *models.App) (int, error) {
+	cutoff := time.Now().Add(-app.SessionTimeout)
+	expired, err := app.Store.PruneSessions(ctx, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("prune sessions: %w", err)
+	}
+	records, err := app.Store.Sessions(ctx, cutoff)
+	if err != nil {
+		return 0, err
+	}
+
+	restored := 0
+	for _, record := range records {
+		var gameState models.GameState
+		if err := json.Unmarshal(record.Game, &gameState); err != nil {
+			util.LogWarn("Skipping session %s in the store: %v", record.ID, err)
+			continue
+		}
+		var settings *models.UserSettings
+		if len(record.Settings) > 0 && json.Unmarshal(record.Settings, &settings) != nil {
+			settings = nil
+		}
+		var heatmap *models.Heatmap
+		if len(record.Heatmap) > 0 && json.Unmarshal(record.Heatmap, &heatmap) != nil {
+			heatmap = nil
+		}
+		stats, hasStats, err := app.Store.Stats(ctx, record.ID)
+		if err != nil {
+			util.LogWarn("Skipping the stats of session %s in the store: %v", record.ID, err)
+		}
+
+		shard := app.Sessions.Shard(record.ID)
+		shard.Lock()
+		if _, ok := shard.Games[record.ID]; ok {
+			shard.Unlock()
+			continue
+		}
+		shard.PutGame(record.ID, &gameState)
+		indexSpectate(app, record.ID, &gameState)
+		if settings != nil {
+			if shard.Settings == nil {
+				shard.Settings = make(map[string]*models.UserSettings)
+			}
+			shard.Settings[record.ID] = settings
+		}
+		if heatmap != nil {
+			if shard.Heatmaps == nil {
+				shard.Heatmaps = make(map[string]*models.Heatmap)
+			}
+			shard.Heatmaps[record.ID] = heatmap
+		}
+		if hasStats {
+			if shard.Stats == nil {
+				shard.Stats = make(map[string]*auth.Stats)
+			}
+			shard.Stats[record.ID] = &stats
+		}
+		if app.MinimalCookies {
+			// Only sessions that consented were stored.
+			if shard.Consents == nil {
+				shard.Consents = make(map[string]bool)
+			}
+			shard.Consents[record.ID] = true
+		}
+		shard.Unlock()
+		restored++
+	}
+
+	util.LogInfo("Restored %d sessions from the store (pruned %d expired)", restored, expired)
+	return restored, nil
+}
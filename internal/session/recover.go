@@ -0,0 +1,183 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	analytics "github.com/CodeAndHammer/vortludo/internal/analytics"
+	eventlog "github.com/CodeAndHammer/vortludo/internal/eventlog"
+	game "github.com/CodeAndHammer/vortludo/internal/game"
+	models "github.com/CodeAndHammer/vortludo/internal/models"
+	util "github.com/CodeAndHammer/vortludo/internal/util"
+)
+
+// gameRebuilder plays a game back from its logged start state and guesses.
+type gameRebuilder struct {
+	app   *models.App
+	games map[string]*models.GameState
+	// fromMemory starts a session's games from a copy of the one in memory
+	// instead of from its logged start, for replaying only the events after
+	// a snapshot.
+	fromMemory bool
+}
+
+// game returns the game being rebuilt for sessionID.
+func (r *gameRebuilder) game(sessionID string) *models.GameState {
+	if gameState, ok := r.games[sessionID]; ok || !r.fromMemory {
+		return gameState
+	}
+	var gameState *models.GameState
+	if current, ok := Snapshot(r.app, sessionID); ok {
+		gameState = &current
+	}
+	r.games[sessionID] = gameState
+	return gameState
+}
+
+func (r *gameRebuilder) apply(e eventlog.Event) {
+	switch e.Type {
+	case eventlog.GameStarted:
+		var gameState models.GameState
+		if err := json.Unmarshal(e.Data, &gameState); err != nil {
+			util.LogWarn("Skipping game %s in the event log: %v", e.Game, err)
+			return
+		}
+		gameState.LastAccessTime = e.Time
+		r.games[e.Session] = &gameState
+	case eventlog.GuessMade:
+		gameState := r.game(e.Session)
+		var guess models.GuessEvent
+		if gameState == nil || gameState.ID != e.Game || json.Unmarshal(e.Data, &guess) != nil {
+			return
+		}
+		// Guesses are only applied in order, so a guess logged twice, or one
+		// whose game start was rotated away, is not applied.
+		if guess.Row != len(gameState.GuessHistory) || gameState.GameOver {
+			return
+		}
+		game.ApplyGuess(r.app, context.Background(), gameState, guess.Guess)
+		gameState.LastAccessTime = e.Time
+	case eventlog.HintRevealed:
+		gameState := r.game(e.Session)
+		var hint models.HintEvent
+		if gameState == nil || gameState.ID != e.Game || json.Unmarshal(e.Data, &hint) != nil {
+			return
+		}
+		// Hints revealed before the first guess are part of the logged start
+		// state.
+		if hint.Stage != gameState.HintsRevealed {
+			return
+		}
+		if _, err := game.RevealHint(r.app, gameState, hint.Taxed); err == nil {
+			gameState.LastAccessTime = e.Time
+		}
+	case eventlog.SessionErased:
+		r.games[e.Session] = nil
+	}
+}
+
+// RecoverFromEventLog restores games the event log knows more of than memory
+// does: games lost in a crash after the last snapshot, and guesses made and
+// hints revealed since. With since, the last event the snapshot in memory
+// had seen, only the events after it are replayed, on top of the games in
+// memory; with 0, or when the log was started over since the snapshot, the
+// whole log is. Games idle longer than app.SessionTimeout are left out. It
+// returns the number of games restored or brought up to date.
+func RecoverFromEventLog(app *models.App, log *eventlog.Log, since uint64) (int, error) {
+	if since > log.Seq() {
+		util.LogWarn("The event log ends at event %d, before the snapshot's %d; replaying all of it", log.Seq(), since)
+		since = 0
+	}
+	rebuilder := &gameRebuilder{app: app, games: make(map[string]*models.GameState), fromMemory: since > 0}
+	replayed, gap := 0, false
+	if err := log.Replay(func(e eventlog.Event) error {
+		if e.Seq <= since {
+			return nil
+		}
+		if replayed == 0 && e.Seq > since+1 && since > 0 {
+			gap = true
+		}
+		replayed++
+		rebuilder.apply(e)
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+	if gap {
+		util.LogWarn("Events after %d were rotated out of the event log; games they touched may be behind", since)
+	}
+
+	now := time.Now()
+	recovered := 0
+	for sessionID, rebuilt := range rebuilder.games {
+		if rebuilt == nil || now.Sub(rebuilt.LastAccessTime) > app.SessionTimeout {
+			continue
+		}
+		shard := app.Sessions.Shard(sessionID)
+		shard.Lock()
+		current, ok := shard.Games[sessionID]
+		switch {
+		case !ok:
+		case current.ID == rebuilt.ID && (len(current.GuessHistory) < len(rebuilt.GuessHistory) || current.HintsRevealed < rebuilt.HintsRevealed):
+		case current.ID != rebuilt.ID && current.LastAccessTime.Before(rebuilt.LastAccessTime):
+		default:
+			shard.Unlock()
+			continue
+		}
+		shard.PutGame(sessionID, rebuilt)
+		indexSpectate(app, sessionID, rebuilt)
+		shard.Unlock()
+		recovered++
+	}
+
+	util.LogInfo("Recovered %d games from %d events in the event log", recovered, replayed)
+	return recovered, nil
+}
+
+// GameFromEventLog rebuilds the game with the given public ID from the event
+// log, e.g. to replay a game that is no longer in memory. The game is nil if
+// the log does not have it.
+func GameFromEventLog(app *models.App, log *eventlog.Log, gameID string) (*models.GameState, error) {
+	rebuilder := &gameRebuilder{app: app, games: make(map[string]*models.GameState)}
+	err := log.Replay(func(e eventlog.Event) error {
+		if e.Game == gameID {
+			rebuilder.apply(e)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, gameState := range rebuilder.games {
+		return gameState, nil
+	}
+	return nil, nil
+}
+
+// BackfillAnalytics counts the finished games and opening guesses still in
+// the event log, so analytics survive a restart.
+func BackfillAnalytics(app *models.App, log *eventlog.Log) (int, error) {
+	games := 0
+	err := log.Replay(func(e eventlog.Event) error {
+		switch e.Type {
+		case eventlog.GuessMade:
+			var guess models.GuessEvent
+			if json.Unmarshal(e.Data, &guess) == nil && guess.Row == 0 && !guess.Untracked {
+				app.Analytics.RecordFirstGuess(guess.Guess)
+			}
+		case eventlog.GameWon, eventlog.GameLost:
+			var result analytics.GameResult
+			if json.Unmarshal(e.Data, &result) == nil && !result.Untracked {
+				app.Analytics.RecordGame(result)
+				games++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	util.LogInfo("Backfilled analytics with %d games from the event log", games)
+	return games, nil
+}
@@ -2,37 +2,87 @@ package session
 
 import (
 	"context"
-	"net/http"
+	"iter"
+	"maps"
+	"slices"
 	"time"
 
+	auth "github.com/CodeAndHammer/vortludo/internal/auth"
 	constants "github.com/CodeAndHammer/vortludo/internal/constants"
+	eventlog "github.com/CodeAndHammer/vortludo/internal/eventlog"
 	game "github.com/CodeAndHammer/vortludo/internal/game"
+	lifecycle "github.com/CodeAndHammer/vortludo/internal/lifecycle"
 	models "github.com/CodeAndHammer/vortludo/internal/models"
 	util "github.com/CodeAndHammer/vortludo/internal/util"
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 )
 
 func GetOrCreateSession(app *models.App, c *gin.Context) string {
-	sessionID, err := c.Cookie(constants.SessionCookieName)
-	if err != nil || len(sessionID) < 10 {
-		sessionID = uuid.NewString()
-		c.SetSameSite(http.SameSiteStrictMode)
-		secure := app.IsProduction
-		c.SetCookie(constants.SessionCookieName, sessionID, int(app.CookieMaxAge.Seconds()), "/", "", secure, true)
+	sessionID := CookieID(app, c)
+	if len(sessionID) < 10 {
+		sessionID = NewID(app)
+		SetCookie(app, c, sessionID)
+		c.Set(constants.NewSessionKey, true)
 		util.LogInfo("Created new session: %s", sessionID)
+		if err := app.EventLog.Append(eventlog.SessionCreated, sessionID, "", nil); err != nil {
+			util.LogWarn("Failed to log new session %s: %v", sessionID, err)
+		}
 	}
 	return sessionID
 }
 
+// GameExpired reports whether the request came with an existing session
+// cookie whose game is no longer held, i.e. it was pruned by cleanup or lost
+// in a restart. It must be called before GetGameState creates a new game.
+func GameExpired(app *models.App, c *gin.Context, sessionID string) bool {
+	if c.GetBool(constants.NewSessionKey) {
+		return false
+	}
+	_, exists := app.Sessions.Game(sessionID)
+	if !exists {
+		util.LogInfo("Game for session %s expired, starting a new one", sessionID)
+	}
+	return !exists
+}
+
+// Lock serialises read-modify-write access to a session's game state so that
+// concurrent requests from the same session apply one after another. The
+// returned function releases the lock.
+func Lock(app *models.App, sessionID string) func() {
+	shard := app.Sessions.Shard(sessionID)
+	shard.Lock()
+	if shard.Locks == nil {
+		shard.Locks = make(map[string]*models.SessionLock)
+	}
+	lock, ok := shard.Locks[sessionID]
+	if !ok {
+		lock = &models.SessionLock{}
+		shard.Locks[sessionID] = lock
+	}
+	lock.Refs++
+	shard.Unlock()
+
+	lock.Lock()
+	return func() {
+		lock.Unlock()
+		shard.Lock()
+		lock.Refs--
+		if lock.Refs == 0 {
+			delete(shard.Locks, sessionID)
+		}
+		shard.Unlock()
+	}
+}
+
 func GetGameState(app *models.App, ctx context.Context, sessionID string) *models.GameState {
-	app.SessionMutex.RLock()
-	gameState, exists := app.GameSessions[sessionID]
-	app.SessionMutex.RUnlock()
+	shard := app.Sessions.Shard(sessionID)
+	shard.Lock()
+	gameState, exists := shard.Games[sessionID]
 	if exists {
-		app.SessionMutex.Lock()
 		gameState.LastAccessTime = time.Now()
-		app.SessionMutex.Unlock()
+	}
+	shard.Unlock()
+	if exists {
 		util.LogInfo("Retrieved cached game state for session: %s, updated last access time.", sessionID)
 		return gameState
 	}
@@ -42,38 +92,361 @@ func GetGameState(app *models.App, ctx context.Context, sessionID string) *model
 }
 
 func SaveGameState(app *models.App, sessionID string, game *models.GameState) {
-	app.SessionMutex.Lock()
-	app.GameSessions[sessionID] = game
+	shard := app.Sessions.Shard(sessionID)
+	shard.Lock()
+	shard.PutGame(sessionID, game)
 	game.LastAccessTime = time.Now()
-	app.SessionMutex.Unlock()
+	shard.Unlock()
 	util.LogInfo("Updated in-memory game state for session: %s", sessionID)
 }
 
-func CleanupExpiredSessions(app *models.App) {
-	app.SessionMutex.Lock()
-	defer app.SessionMutex.Unlock()
+// Snapshot returns a copy of the session's game, taken under the session's
+// lock, that later guesses cannot change. It is for reading a game outside
+// the request that owns it; a handler already holding the lock can use
+// GameState.Clone instead, as Lock is not reentrant.
+func Snapshot(app *models.App, sessionID string) (models.GameState, bool) {
+	unlock := Lock(app, sessionID)
+	defer unlock()
+	gameState, ok := app.Sessions.Game(sessionID)
+	if !ok {
+		return models.GameState{}, false
+	}
+	return *gameState.Clone(), true
+}
+
+// FindGame returns the session playing the game with the given public ID.
+func FindGame(app *models.App, gameID string) (string, bool) {
+	return findSession(app, func(gameState *models.GameState) bool {
+		return gameState.ID == gameID
+	})
+}
+
+// FindSpectated returns the session playing the game with the given
+// spectate token, going by the store's index rather than a search, as every
+// spectator stream asks once a second. The index can name a session that has
+// since moved on to another game, so the caller checks the game's token.
+func FindSpectated(app *models.App, token string) (string, bool) {
+	if token == "" {
+		return "", false
+	}
+	return app.Sessions.Spectated(token)
+}
+
+// indexSpectate brings the spectate index up to date with a game restored
+// for the session.
+func indexSpectate(app *models.App, sessionID string, game *models.GameState) {
+	if game.Spectate != nil {
+		app.Sessions.IndexSpectate(sessionID, game.Spectate.Token)
+	} else {
+		app.Sessions.UnindexSpectate(sessionID)
+	}
+}
+
+// GamesPlaying counts the unfinished games, or unsolved boards of
+// multi-board games, whose word is word, and the adversarial games that
+// could still pick it. Guesses change what it reads, so
+// each game is read under its session's lock, which the caller must not
+// hold.
+func GamesPlaying(app *models.App, word string) int {
+	var ids []string
+	for shard := range app.Sessions.Shards() {
+		shard.RLock()
+		for id := range shard.Games {
+			ids = append(ids, id)
+		}
+		shard.RUnlock()
+	}
+	playing := 0
+	for _, id := range ids {
+		unlock := Lock(app, id)
+		if gameState, ok := app.Sessions.Game(id); ok && playingWord(gameState, word) {
+			playing++
+		}
+		unlock()
+	}
+	return playing
+}
+
+// playingWord reports whether the unfinished game, or an unsolved board of
+// it, has word, or could still switch to it, as an adversarial game can to
+// any of its candidates. The caller holds the session's lock.
+func playingWord(gameState *models.GameState, word string) bool {
+	if gameState.GameOver {
+		return false
+	}
+	if gameState.Adversarial != nil && slices.Contains(gameState.Adversarial.Candidates, word) {
+		return true
+	}
+	return gameState.SessionWord == word || slices.ContainsFunc(gameState.Boards, func(b models.Board) bool {
+		return !b.Solved && b.SessionWord == word
+	})
+}
+
+// findSession returns a session whose game matches, searching one shard at a
+// time.
+func findSession(app *models.App, match func(*models.GameState) bool) (string, bool) {
+	for shard := range app.Sessions.Shards() {
+		shard.RLock()
+		for sessionID, gameState := range shard.Games {
+			if match(gameState) {
+				shard.RUnlock()
+				return sessionID, true
+			}
+		}
+		shard.RUnlock()
+	}
+	return "", false
+}
+
+// GetSettings returns the session's settings, or the defaults when none were
+// saved.
+func GetSettings(app *models.App, sessionID string) models.UserSettings {
+	shard := app.Sessions.Shard(sessionID)
+	shard.RLock()
+	defer shard.RUnlock()
+	if settings, ok := shard.Settings[sessionID]; ok {
+		return *settings
+	}
+	return DefaultSettings(app)
+}
+
+// DefaultSettings are the settings of a session that has saved none: the
+// game's, in the instance's default theme.
+func DefaultSettings(app *models.App) models.UserSettings {
+	settings := game.DefaultSettings()
+	if app.DefaultTheme != "" {
+		settings.Theme = app.DefaultTheme
+	}
+	return settings
+}
+
+func SaveSettings(app *models.App, sessionID string, settings models.UserSettings) {
+	shard := app.Sessions.Shard(sessionID)
+	shard.Lock()
+	if shard.Settings == nil {
+		shard.Settings = make(map[string]*models.UserSettings)
+	}
+	shard.Settings[sessionID] = &settings
+	shard.Unlock()
+	util.LogInfo("Updated settings for session: %s", sessionID)
+}
+
+// GetStats returns the stats the session gathered while not signed in.
+func GetStats(app *models.App, sessionID string) auth.Stats {
+	shard := app.Sessions.Shard(sessionID)
+	shard.RLock()
+	defer shard.RUnlock()
+	if stats, ok := shard.Stats[sessionID]; ok {
+		return stats.Clone()
+	}
+	return auth.Stats{}
+}
+
+func RecordStats(app *models.App, sessionID string, won bool, guesses int) {
+	UpdateStats(app, sessionID, func(stats *auth.Stats) { stats.Record(won, guesses) })
+}
+
+// UpdateStats changes the session's stats with fn, which must not keep the
+// pointer it is given.
+func UpdateStats(app *models.App, sessionID string, fn func(stats *auth.Stats)) {
+	shard := app.Sessions.Shard(sessionID)
+	shard.Lock()
+	defer shard.Unlock()
+	if shard.Stats == nil {
+		shard.Stats = make(map[string]*auth.Stats)
+	}
+	stats, ok := shard.Stats[sessionID]
+	if !ok {
+		stats = &auth.Stats{}
+		shard.Stats[sessionID] = stats
+	}
+	fn(stats)
+}
 
-	now := time.Now()
-	expiredCount := 0
-	for sessionID, game := range app.GameSessions {
-		if now.Sub(game.LastAccessTime) > app.SessionTimeout {
-			delete(app.GameSessions, sessionID)
-			expiredCount++
+// TakeStats removes and returns the session's stats, to be merged into an
+// account.
+func TakeStats(app *models.App, sessionID string) auth.Stats {
+	shard := app.Sessions.Shard(sessionID)
+	shard.Lock()
+	defer shard.Unlock()
+	stats, ok := shard.Stats[sessionID]
+	if !ok {
+		return auth.Stats{}
+	}
+	delete(shard.Stats, sessionID)
+	return *stats
+}
+
+// GetHeatmap returns the letters the session has guessed across its games.
+func GetHeatmap(app *models.App, sessionID string) models.Heatmap {
+	shard := app.Sessions.Shard(sessionID)
+	shard.RLock()
+	defer shard.RUnlock()
+	heatmap, ok := shard.Heatmaps[sessionID]
+	if !ok {
+		return models.Heatmap{}
+	}
+	return *heatmap.Clone()
+}
+
+func RecordHeatmap(app *models.App, sessionID string, tiles []models.GuessResult) {
+	shard := app.Sessions.Shard(sessionID)
+	shard.Lock()
+	defer shard.Unlock()
+	if shard.Heatmaps == nil {
+		shard.Heatmaps = make(map[string]*models.Heatmap)
+	}
+	heatmap, ok := shard.Heatmaps[sessionID]
+	if !ok {
+		heatmap = &models.Heatmap{}
+		shard.Heatmaps[sessionID] = heatmap
+	}
+	heatmap.Record(tiles)
+}
+
+// GetReplay returns the response the session's last request with key got,
+// if it was made within constants.IdempotencyWindow. Only the session's most
+// recent keyed response is kept.
+func GetReplay(app *models.App, sessionID, key string) (*models.Replay, bool) {
+	shard := app.Sessions.Shard(sessionID)
+	shard.RLock()
+	defer shard.RUnlock()
+	replay, ok := shard.Replays[sessionID]
+	if !ok || replay.Key != key || time.Since(replay.Created) > constants.IdempotencyWindow {
+		return nil, false
+	}
+	return replay, true
+}
+
+func SaveReplay(app *models.App, sessionID string, replay *models.Replay) {
+	shard := app.Sessions.Shard(sessionID)
+	shard.Lock()
+	defer shard.Unlock()
+	if shard.Replays == nil {
+		shard.Replays = make(map[string]*models.Replay)
+	}
+	shard.Replays[sessionID] = replay
+}
+
+// Erase drops everything kept for sessionID, in memory and in app.Store,
+// and its activity in analytics, e.g. when its player asked for their data
+// to be erased. The caller holds the session's lock.
+func Erase(ctx context.Context, app *models.App, sessionID string) error {
+	shard := app.Sessions.Shard(sessionID)
+	shard.Lock()
+	forget(app, shard, sessionID)
+	delete(shard.Replays, sessionID)
+	shard.Unlock()
+	app.Bots.Clear(sessionID)
+	app.Analytics.Forget(sessionID)
+	if app.Store == nil {
+		return nil
+	}
+	if app.WriteBehind != nil {
+		app.WriteBehind.Drop(sessionID)
+	}
+	return app.Store.DeleteSession(ctx, sessionID)
+}
+
+// CleanupExpiredSessions drops the sessions idle longer than
+// app.SessionTimeout, the settings, stats and heatmaps of sessions without a
+// game, and stale replays, and returns how many sessions expired. Each shard
+// is swept in batches of constants.SessionGCBatch sessions, so its write lock
+// is only held briefly and requests get through between batches. A sweep
+// stopped by ctx is recorded as interrupted.
+func CleanupExpiredSessions(ctx context.Context, app *models.App) int {
+	run := models.SessionGCRun{Started: time.Now()}
+	for shard := range app.Sessions.Shards() {
+		if !cleanupShard(ctx, app, shard, &run) {
+			run.Interrupted = true
+			break
+		}
+	}
+	run.Duration = time.Since(run.Started)
+	app.SessionGC.Record(run)
+	if run.Expired > 0 || run.Interrupted {
+		util.LogInfo("Cleaned up %d expired sessions of %d in %s (%d batches, longest lock %s, interrupted: %t)",
+			run.Expired, run.Scanned, run.Duration.Round(time.Millisecond), run.Batches, run.LongestLock, run.Interrupted)
+	}
+	return run.Expired
+}
+
+// cleanupShard sweeps shard, reporting false when ctx stopped it. The IDs to
+// look at are copied under the read lock, which leaves reads of the shard
+// going; each batch is then checked and dropped under the write lock.
+func cleanupShard(ctx context.Context, app *models.App, shard *models.SessionShard, run *models.SessionGCRun) bool {
+	shard.RLock()
+	ids := make([]string, 0, len(shard.Games)+len(shard.Replays))
+	for _, m := range []iter.Seq[string]{
+		maps.Keys(shard.Games), maps.Keys(shard.Settings), maps.Keys(shard.Stats),
+		maps.Keys(shard.Heatmaps), maps.Keys(shard.Replays), maps.Keys(shard.Consents),
+	} {
+		for id := range m {
+			ids = append(ids, id)
 		}
 	}
+	shard.RUnlock()
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
 
-	if expiredCount > 0 {
-		util.LogInfo("Cleaned up %d expired sessions", expiredCount)
+	for batch := range slices.Chunk(ids, constants.SessionGCBatch) {
+		if run.Batches > 0 {
+			select {
+			case <-ctx.Done():
+				return false
+			case <-time.After(constants.SessionGCPause):
+			}
+		}
+		locked := time.Now()
+		shard.Lock()
+		now := time.Now()
+		for _, id := range batch {
+			if sweepSession(app, shard, id, now) {
+				run.Expired++
+			}
+		}
+		shard.Unlock()
+		run.LongestLock = max(run.LongestLock, time.Since(locked))
+		run.Scanned += len(batch)
+		run.Batches++
 	}
+	return true
 }
 
-func StartSessionCleanup(app *models.App) {
-	ticker := time.NewTicker(10 * time.Minute)
-	go func() {
-		defer ticker.Stop()
-		for range ticker.C {
-			CleanupExpiredSessions(app)
+// sweepSession drops what of sessionID's state has expired, reporting
+// whether its game did. The caller holds the shard's lock.
+func sweepSession(app *models.App, shard *models.SessionShard, sessionID string, now time.Time) bool {
+	if replay, ok := shard.Replays[sessionID]; ok && now.Sub(replay.Created) > constants.IdempotencyWindow {
+		delete(shard.Replays, sessionID)
+	}
+	game, ok := shard.Games[sessionID]
+	if ok && now.Sub(game.LastAccessTime) <= app.SessionTimeout {
+		return false
+	}
+	forget(app, shard, sessionID)
+	return ok
+}
+
+// Ping reports whether every part of the session store can be read before
+// ctx is done. A shard that stays locked, e.g. by a stuck writer, would hang
+// the requests of its sessions.
+func Ping(ctx context.Context, app *models.App) error {
+	for shard := range app.Sessions.Shards() {
+		for !shard.TryRLock() {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(10 * time.Millisecond):
+			}
 		}
-	}()
-	util.LogInfo("Started session cleanup goroutine")
+		shard.RUnlock()
+	}
+	return nil
+}
+
+func StartSessionCleanup(app *models.App, sup *lifecycle.Supervisor) {
+	sup.Jittered("session cleanup", constants.SessionGCInterval, constants.SessionGCJitter, func(ctx context.Context) {
+		CleanupExpiredSessions(ctx, app)
+		app.Bots.Cleanup(time.Now())
+	})
 }
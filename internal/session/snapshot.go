@@ -0,0 +1,147 @@
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"slices"
+	"time"
+
+	auth "github.com/CodeAndHammer/vortludo/internal/auth"
+	models "github.com/CodeAndHammer/vortludo/internal/models"
+	util "github.com/CodeAndHammer/vortludo/internal/util"
+)
+
+// Version 1 snapshots stored guesses as GuessResult grids; models.Rows still
+// decodes them, so they load as well.
+const snapshotVersion = 2
+
+type snapshot struct {
+	Version int       `json:"version"`
+	SavedAt time.Time `json:"savedAt"`
+	// EventSeq is the last event logged before the snapshot was taken; the
+	// events after it are replayed on top of the snapshot.
+	EventSeq uint64                          `json:"eventSeq,omitempty"`
+	Sessions map[string]*models.GameState    `json:"sessions"`
+	Settings map[string]*models.UserSettings `json:"settings,omitempty"`
+	Stats    map[string]*auth.Stats          `json:"stats,omitempty"`
+	Heatmaps map[string]*models.Heatmap      `json:"heatmaps,omitempty"`
+	Consents map[string]bool                 `json:"consents,omitempty"`
+}
+
+// SaveSnapshot writes every in-memory session to path. The file is replaced
+// atomically so a crash never leaves a torn snapshot. Each game is copied
+// under its session's lock, so a guess being scored is never half saved.
+// The snapshot notes how far the event log had got before any game was
+// copied, so replaying the events after that point brings it up to date;
+// an event whose guess a copied game already has is skipped on replay.
+func SaveSnapshot(app *models.App, path string) (int, error) {
+	var sessionIDs []string
+	snap := snapshot{
+		Version:  snapshotVersion,
+		SavedAt:  time.Now(),
+		EventSeq: app.EventLog.Seq(),
+		Settings: make(map[string]*models.UserSettings),
+		Stats:    make(map[string]*auth.Stats),
+		Heatmaps: make(map[string]*models.Heatmap),
+		Consents: make(map[string]bool),
+	}
+	for shard := range app.Sessions.Shards() {
+		shard.RLock()
+		sessionIDs = slices.AppendSeq(sessionIDs, maps.Keys(shard.Games))
+		maps.Copy(snap.Settings, shard.Settings)
+		for sessionID, stats := range shard.Stats {
+			copied := stats.Clone()
+			snap.Stats[sessionID] = &copied
+		}
+		for sessionID, heatmap := range shard.Heatmaps {
+			snap.Heatmaps[sessionID] = heatmap.Clone()
+		}
+		maps.Copy(snap.Consents, shard.Consents)
+		shard.RUnlock()
+	}
+	snap.Sessions = make(map[string]*models.GameState, len(sessionIDs))
+	for _, sessionID := range sessionIDs {
+		if game, ok := Snapshot(app, sessionID); ok {
+			snap.Sessions[sessionID] = &game
+		}
+	}
+
+	data, err := json.Marshal(snap)
+	count := len(snap.Sessions)
+	if err != nil {
+		return 0, fmt.Errorf("encode snapshot: %w", err)
+	}
+
+	if err := util.WriteFileAtomic(path, data); err != nil {
+		return 0, err
+	}
+	util.LogInfo("Saved %d sessions to %s", count, path)
+	return count, nil
+}
+
+// LoadSnapshot restores sessions saved by SaveSnapshot, skipping any that
+// have been idle longer than app.SessionTimeout. It also returns the last
+// event logged before the snapshot, for RecoverFromEventLog to replay from.
+// A missing file is not an error.
+func LoadSnapshot(app *models.App, path string) (int, uint64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return 0, 0, nil
+		}
+		return 0, 0, err
+	}
+	var snap snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return 0, 0, fmt.Errorf("decode snapshot: %w", err)
+	}
+	if snap.Version != snapshotVersion && snap.Version != 1 {
+		return 0, 0, fmt.Errorf("unsupported snapshot version %d", snap.Version)
+	}
+
+	now := time.Now()
+	restored, expired := 0, 0
+	for sessionID, game := range snap.Sessions {
+		if game == nil || now.Sub(game.LastAccessTime) > app.SessionTimeout {
+			expired++
+			continue
+		}
+		shard := app.Sessions.Shard(sessionID)
+		shard.Lock()
+		shard.PutGame(sessionID, game)
+		indexSpectate(app, sessionID, game)
+		if settings, ok := snap.Settings[sessionID]; ok && settings != nil {
+			if shard.Settings == nil {
+				shard.Settings = make(map[string]*models.UserSettings)
+			}
+			shard.Settings[sessionID] = settings
+		}
+		if stats, ok := snap.Stats[sessionID]; ok && stats != nil {
+			if shard.Stats == nil {
+				shard.Stats = make(map[string]*auth.Stats)
+			}
+			shard.Stats[sessionID] = stats
+		}
+		if heatmap, ok := snap.Heatmaps[sessionID]; ok && heatmap != nil {
+			if shard.Heatmaps == nil {
+				shard.Heatmaps = make(map[string]*models.Heatmap)
+			}
+			shard.Heatmaps[sessionID] = heatmap
+		}
+		if consent, ok := snap.Consents[sessionID]; ok {
+			if shard.Consents == nil {
+				shard.Consents = make(map[string]bool)
+			}
+			shard.Consents[sessionID] = consent
+		}
+		shard.Unlock()
+		restored++
+	}
+
+	util.LogInfo("Restored %d sessions from %s (discarded %d expired)", restored, path, expired)
+	return restored, snap.EventSeq, nil
+}
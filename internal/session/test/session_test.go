@@ -0,0 +1,770 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	constants "github.com/CodeAndHammer/vortludo/internal/constants"
+	eventlog "github.com/CodeAndHammer/vortludo/internal/eventlog"
+	game "github.com/CodeAndHammer/vortludo/internal/game"
+	models "github.com/CodeAndHammer/vortludo/internal/models"
+	security "github.com/CodeAndHammer/vortludo/internal/security"
+	session "github.com/CodeAndHammer/vortludo/internal/session"
+	store "github.com/CodeAndHammer/vortludo/internal/store"
+	"github.com/gin-gonic/gin"
+)
+
+func testApp() *models.App {
+	return &models.App{
+		SessionTimeout: time.Hour,
+	}
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sessions.json")
+	app := testApp()
+	app.Sessions.SetGame("fresh", &models.GameState{SessionWord: "APPLE", GuessHistory: []string{"TABLE"}, LastAccessTime: time.Now()})
+	app.Sessions.SetGame("stale", &models.GameState{SessionWord: "CHAIR", LastAccessTime: time.Now().Add(-2 * time.Hour)})
+
+	if n, err := session.SaveSnapshot(app, path); err != nil || n != 2 {
+		t.Fatalf("SaveSnapshot = %d, %v", n, err)
+	}
+
+	restored := testApp()
+	n, _, err := session.LoadSnapshot(restored, path)
+	if err != nil {
+		t.Fatalf("LoadSnapshot: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("Expected 1 restored session, got %d", n)
+	}
+	game, _ := restored.Sessions.Game("fresh")
+	if game == nil || game.SessionWord != "APPLE" || len(game.GuessHistory) != 1 {
+		t.Errorf("Fresh session not restored correctly: %+v", game)
+	}
+	if _, ok := restored.Sessions.Game("stale"); ok {
+		t.Error("Expired session should be discarded")
+	}
+}
+
+func TestStoreRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	st := store.NewMemory()
+	app := testApp()
+	app.Store = st
+	app.Sessions.SetGame("fresh", &models.GameState{SessionWord: "APPLE", GuessHistory: []string{"TABLE"}, LastAccessTime: time.Now()})
+	app.Sessions.SetGame("stale", &models.GameState{SessionWord: "CHAIR", LastAccessTime: time.Now().Add(-2 * time.Hour)})
+	session.SaveSettings(app, "fresh", models.UserSettings{Language: "eo", Theme: constants.ThemeDark})
+	session.RecordStats(app, "fresh", true, 3)
+
+	if n, err := session.SaveToStore(ctx, app); err != nil || n != 2 {
+		t.Fatalf("SaveToStore = %d, %v", n, err)
+	}
+
+	restored := testApp()
+	restored.Store = st
+	n, err := session.LoadFromStore(ctx, restored)
+	if err != nil {
+		t.Fatalf("LoadFromStore: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("Expected 1 restored session, got %d", n)
+	}
+	game, _ := restored.Sessions.Game("fresh")
+	if game == nil || game.SessionWord != "APPLE" || len(game.GuessHistory) != 1 {
+		t.Errorf("Fresh session not restored correctly: %+v", game)
+	}
+	if got := session.GetSettings(restored, "fresh"); got.Language != "eo" || got.Theme != constants.ThemeDark {
+		t.Errorf("Settings not restored from the store: %+v", got)
+	}
+	if got := session.GetStats(restored, "fresh"); got.Played != 1 || got.Won != 1 {
+		t.Errorf("Stats not restored from the store: %+v", got)
+	}
+	if _, ok := restored.Sessions.Game("stale"); ok {
+		t.Error("Expired session should be discarded")
+	}
+	if sessions, _ := st.Sessions(ctx, time.Time{}); len(sessions) != 1 {
+		t.Errorf("Expected the expired session pruned from the store, got %d sessions", len(sessions))
+	}
+}
+
+func TestPersistWriteBehind(t *testing.T) {
+	ctx := context.Background()
+	st := store.NewMemory()
+	app := testApp()
+	app.Store = st
+	app.WriteBehind = store.NewWriteBehind(st, time.Hour, 100)
+	gameState := &models.GameState{SessionWord: "APPLE", GameOver: true, Won: true, LastAccessTime: time.Now()}
+	app.Sessions.SetGame("sess1", gameState)
+	session.RecordStats(app, "sess1", true, 1)
+
+	session.Persist(ctx, app, "sess1", gameState)
+	session.PersistCompletedWords(ctx, app, "sess1", false, "APPLE")
+	if sessions, _ := st.Sessions(ctx, time.Time{}); len(sessions) != 0 {
+		t.Fatalf("Expected the write queued, got %d sessions in the store", len(sessions))
+	}
+	if err := app.WriteBehind.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if sessions, _ := st.Sessions(ctx, time.Time{}); len(sessions) != 1 {
+		t.Errorf("Expected the session flushed, got %d", len(sessions))
+	}
+	if stats, ok, _ := st.Stats(ctx, "sess1"); !ok || stats.Won != 1 {
+		t.Errorf("Expected the stats of the finished game flushed, got %+v", stats)
+	}
+	if words, _ := st.CompletedWords(ctx, "sess1"); len(words) != 1 {
+		t.Errorf("Expected the completed word flushed, got %v", words)
+	}
+}
+
+func TestLoadSnapshotMissingFile(t *testing.T) {
+	n, _, err := session.LoadSnapshot(testApp(), filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil || n != 0 {
+		t.Errorf("Missing snapshot should be ignored, got %d, %v", n, err)
+	}
+}
+
+func TestSettingsSurviveNewGameAndSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sessions.json")
+	app := testApp()
+	if got := session.GetSettings(app, "sess1"); got.Language != "en" || got.HardMode {
+		t.Errorf("Expected default settings, got %+v", got)
+	}
+
+	session.SaveSettings(app, "sess1", models.UserSettings{HardMode: true, Language: "eo", KeyboardLayout: "azerty"})
+	session.SaveGameState(app, "sess1", &models.GameState{SessionWord: "APPLE"})
+	session.SaveGameState(app, "sess1", &models.GameState{SessionWord: "TABLE"})
+	if !session.GetSettings(app, "sess1").HardMode {
+		t.Fatal("Settings should persist across games")
+	}
+
+	if _, err := session.SaveSnapshot(app, path); err != nil {
+		t.Fatalf("SaveSnapshot: %v", err)
+	}
+	restored := testApp()
+	if _, _, err := session.LoadSnapshot(restored, path); err != nil {
+		t.Fatalf("LoadSnapshot: %v", err)
+	}
+	if got := session.GetSettings(restored, "sess1"); got.Language != "eo" || got.KeyboardLayout != "azerty" {
+		t.Errorf("Settings not restored from snapshot: %+v", got)
+	}
+}
+
+func TestLockSerialisesSession(t *testing.T) {
+	app := testApp()
+	gameState := &models.GameState{}
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			unlock := session.Lock(app, "sess1")
+			defer unlock()
+			row := gameState.CurrentRow
+			time.Sleep(time.Microsecond)
+			gameState.CurrentRow = row + 1
+		}()
+	}
+	wg.Wait()
+	if gameState.CurrentRow != 50 {
+		t.Errorf("Expected 50 serialised updates, got %d", gameState.CurrentRow)
+	}
+	if locks := app.Sessions.Shard("sess1").Locks; len(locks) != 0 {
+		t.Errorf("Locks should be released once idle, %d left", len(locks))
+	}
+}
+
+func TestSnapshotIsIndependent(t *testing.T) {
+	app := testApp()
+	gameState := &models.GameState{
+		Guesses:      models.NewRows(constants.MaxGuesses),
+		GuessHistory: []string{"TABLE"},
+		Boards:       []models.Board{{SessionWord: "APPLE", Guesses: models.NewRows(constants.MaxGuesses)}},
+		Race:         &models.RaceState{BotGuesses: []string{"CRANE"}, BotRows: [][]string{{"absent"}}},
+		Spectate:     &models.Spectate{Token: "tok"},
+	}
+	gameState.Guesses.Words[0] = "TABLE"
+	app.Sessions.SetGame("sess1", gameState)
+
+	snapshot, ok := session.Snapshot(app, "sess1")
+	if !ok {
+		t.Fatal("Snapshot should find the game")
+	}
+	gameState.Guesses.Words[0] = "CHAIR"
+	gameState.GuessHistory[0] = "CHAIR"
+	gameState.Boards[0].Guesses.Statuses[0] = 1
+	gameState.Race.BotGuesses[0] = "SLATE"
+	gameState.Race.BotRows[0][0] = "correct"
+	gameState.Spectate.EndsAt = time.Now()
+
+	if snapshot.Guesses.Words[0] != "TABLE" || snapshot.GuessHistory[0] != "TABLE" ||
+		snapshot.Boards[0].Guesses.Statuses[0] != 0 || snapshot.Race.BotGuesses[0] != "CRANE" ||
+		snapshot.Race.BotRows[0][0] != "absent" || !snapshot.Spectate.EndsAt.IsZero() {
+		t.Errorf("Snapshot changed with the game: %+v", snapshot)
+	}
+	if _, ok := session.Snapshot(app, "missing"); ok {
+		t.Error("Snapshot of a session without a game should fail")
+	}
+}
+
+// TestSnapshotDuringGuesses is meant for the race detector: snapshots and
+// session snapshots to disk run while guesses are applied.
+func TestSnapshotDuringGuesses(t *testing.T) {
+	app := testApp()
+	app.Sessions.SetGame("sess1", &models.GameState{Guesses: models.NewRows(constants.MaxGuesses)})
+	path := filepath.Join(t.TempDir(), "sessions.json")
+
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		for row := range constants.MaxGuesses {
+			unlock := session.Lock(app, "sess1")
+			gameState, _ := app.Sessions.Game("sess1")
+			gameState.Guesses.Set(row, []models.GuessResult{{Letter: "A", Status: constants.GuessStatusAbsent}})
+			gameState.GuessHistory = append(gameState.GuessHistory, "AAAAA")
+			gameState.CurrentRow = row + 1
+			gameState.GameOver = row+1 == constants.MaxGuesses
+			unlock()
+		}
+	})
+	for range 4 {
+		wg.Go(func() {
+			for range 2000 {
+				snapshot, ok := session.Snapshot(app, "sess1")
+				if !ok {
+					t.Error("Snapshot should find the game")
+					return
+				}
+				if len(snapshot.GuessHistory) != snapshot.CurrentRow {
+					t.Errorf("Torn snapshot: %d guesses at row %d", len(snapshot.GuessHistory), snapshot.CurrentRow)
+				}
+				for row := range snapshot.CurrentRow {
+					if snapshot.Guesses.Words[row] == "" {
+						t.Errorf("Torn snapshot: row %d of %d is empty", row, snapshot.CurrentRow)
+					}
+				}
+			}
+		})
+	}
+	wg.Go(func() {
+		for range 10 {
+			if _, err := session.SaveSnapshot(app, path); err != nil {
+				t.Errorf("SaveSnapshot: %v", err)
+			}
+		}
+	})
+	wg.Wait()
+}
+
+func TestSessionStoreShards(t *testing.T) {
+	app := testApp()
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Go(func() {
+			for j := range 100 {
+				sessionID := fmt.Sprintf("session-%d-%d", i, j)
+				session.SaveGameState(app, sessionID, &models.GameState{})
+				session.SaveSettings(app, sessionID, models.UserSettings{Language: "eo"})
+				if j%2 == 0 {
+					app.Sessions.DeleteGame(sessionID)
+				}
+			}
+		})
+	}
+	wg.Wait()
+	if n := app.Sessions.Len(); n != 400 {
+		t.Errorf("Expected 400 games, got %d", n)
+	}
+
+	used := 0
+	for shard := range app.Sessions.Shards() {
+		if len(shard.Games) > 0 {
+			used++
+		}
+	}
+	if used < 32 {
+		t.Errorf("Sessions should spread across shards, only %d used", used)
+	}
+
+	session.CleanupExpiredSessions(context.Background(), app)
+	if settings := session.GetSettings(app, "session-0-0"); settings.Language == "eo" {
+		t.Error("Cleanup should drop the settings of sessions without a game")
+	}
+	if settings := session.GetSettings(app, "session-0-1"); settings.Language != "eo" {
+		t.Error("Cleanup should keep the settings of live sessions")
+	}
+}
+
+func TestCleanupSweepsInBatches(t *testing.T) {
+	app := testApp()
+	total := 64 * constants.SessionGCBatch * 2
+	for i := range total {
+		game := &models.GameState{LastAccessTime: time.Now()}
+		if i%4 == 0 {
+			game.LastAccessTime = time.Now().Add(-2 * app.SessionTimeout)
+		}
+		app.Sessions.SetGame(fmt.Sprintf("session-%d", i), game)
+	}
+
+	expired := session.CleanupExpiredSessions(context.Background(), app)
+	if expired != total/4 {
+		t.Errorf("Expected %d expired sessions, got %d", total/4, expired)
+	}
+	if n := app.Sessions.Len(); n != total-total/4 {
+		t.Errorf("Expected %d sessions left, got %d", total-total/4, n)
+	}
+	stats := app.SessionGC.Stats()
+	if stats.Runs != 1 || stats.LastScanned != total || stats.LastExpired != total/4 {
+		t.Errorf("Expected 1 run scanning %d and expiring %d, got %+v", total, total/4, stats)
+	}
+	if stats.LastBatches <= 64 {
+		t.Errorf("Expected shards to be swept in several batches, got %d batches", stats.LastBatches)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	session.CleanupExpiredSessions(ctx, app)
+	if stats := app.SessionGC.Stats(); stats.Runs != 2 || stats.LastScanned >= app.Sessions.Len() {
+		t.Errorf("A cancelled sweep should stop early, got %+v", stats)
+	}
+}
+
+func TestSpectateIndex(t *testing.T) {
+	app := testApp()
+	watched := &models.GameState{LastAccessTime: time.Now()}
+	erased := &models.GameState{LastAccessTime: time.Now()}
+	app.Sessions.SetGame("watched", watched)
+	app.Sessions.SetGame("erased", erased)
+	found := func(token string) string {
+		id, _ := session.FindSpectated(app, token)
+		return id
+	}
+
+	first := game.StartSpectating(app, "watched", watched)
+	if found(first) != "watched" {
+		t.Fatal("Expected the spectate token indexed")
+	}
+	game.StopSpectating(app, "watched", watched)
+	if found(first) != "" {
+		t.Error("Expected a revoked token dropped from the index")
+	}
+	token := game.StartSpectating(app, "watched", watched)
+	if found(token) != "watched" || token == first {
+		t.Errorf("Expected a new token indexed, got %q for %q", found(token), token)
+	}
+
+	path := filepath.Join(t.TempDir(), "sessions.json")
+	if _, err := session.SaveSnapshot(app, path); err != nil {
+		t.Fatal(err)
+	}
+	restored := testApp()
+	if _, _, err := session.LoadSnapshot(restored, path); err != nil {
+		t.Fatal(err)
+	}
+	if id, _ := session.FindSpectated(restored, token); id != "watched" {
+		t.Errorf("Expected restored games indexed, got %q", id)
+	}
+
+	other := game.StartSpectating(app, "erased", erased)
+	if err := session.Erase(context.Background(), app, "erased"); err != nil {
+		t.Fatal(err)
+	}
+	if found(other) != "" {
+		t.Error("Expected an erased session dropped from the index")
+	}
+	watched.LastAccessTime = time.Now().Add(-2 * app.SessionTimeout)
+	session.CleanupExpiredSessions(context.Background(), app)
+	if found(token) != "" {
+		t.Error("Expected an expired session dropped from the index")
+	}
+}
+
+func TestGamesPlayingReadsUnderSessionLock(t *testing.T) {
+	app := testApp()
+	for i := range 8 {
+		app.Sessions.SetGame(fmt.Sprintf("player-%d", i), &models.GameState{SessionWord: "CRANE", LastAccessTime: time.Now()})
+	}
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Go(func() {
+			id := fmt.Sprintf("player-%d", i)
+			for range 2000 {
+				unlock := session.Lock(app, id)
+				gameState, _ := app.Sessions.Game(id)
+				gameState.GameOver = !gameState.GameOver
+				unlock()
+			}
+		})
+	}
+	for range 200 {
+		if playing := session.GamesPlaying(app, "CRANE"); playing < 0 || playing > 8 {
+			t.Errorf("Expected at most 8 games playing CRANE, got %d", playing)
+		}
+	}
+	wg.Wait()
+	if playing := session.GamesPlaying(app, "CRANE"); playing != 8 {
+		t.Errorf("Expected every game playing CRANE once the guesses are in, got %d", playing)
+	}
+}
+
+func TestPing(t *testing.T) {
+	app := testApp()
+	if err := session.Ping(context.Background(), app); err != nil {
+		t.Fatalf("Idle store should respond, got %v", err)
+	}
+
+	shard := app.Sessions.Shard("sess1")
+	shard.Lock()
+	defer shard.Unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := session.Ping(ctx, app); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Stuck store should time out, got %v", err)
+	}
+}
+
+func TestReplayKeepsLastKeyWithinWindow(t *testing.T) {
+	app := testApp()
+	session.SaveReplay(app, "sess1", &models.Replay{Key: "a", Status: http.StatusOK, Created: time.Now()})
+	if _, ok := session.GetReplay(app, "sess1", "a"); !ok {
+		t.Error("Expected the saved response for its key")
+	}
+	if _, ok := session.GetReplay(app, "sess1", "b"); ok {
+		t.Error("A different key must not be replayed")
+	}
+	if _, ok := session.GetReplay(app, "sess2", "a"); ok {
+		t.Error("Another session must not get the response")
+	}
+
+	session.SaveReplay(app, "sess1", &models.Replay{Key: "a", Created: time.Now().Add(-constants.IdempotencyWindow - time.Second)})
+	if _, ok := session.GetReplay(app, "sess1", "a"); ok {
+		t.Error("Responses older than the window must not be replayed")
+	}
+	session.CleanupExpiredSessions(context.Background(), app)
+	if len(app.Sessions.Shard("sess1").Replays) != 0 {
+		t.Error("Cleanup should drop stale responses")
+	}
+}
+
+func TestGameExpired(t *testing.T) {
+	app := testApp()
+	newContext := func(cookie string) *gin.Context {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+		if cookie != "" {
+			c.Request.AddCookie(&http.Cookie{Name: constants.SessionCookieName, Value: cookie})
+		}
+		return c
+	}
+
+	c := newContext("")
+	sessionID := session.GetOrCreateSession(app, c)
+	if session.GameExpired(app, c, sessionID) {
+		t.Error("A brand new session has no game to expire")
+	}
+
+	app.Sessions.SetGame("existing-session", &models.GameState{})
+	c = newContext("existing-session")
+	if session.GameExpired(app, c, session.GetOrCreateSession(app, c)) {
+		t.Error("A session with a live game is not expired")
+	}
+
+	c = newContext("pruned-session")
+	if !session.GameExpired(app, c, session.GetOrCreateSession(app, c)) {
+		t.Error("A known cookie without a game should be reported as expired")
+	}
+}
+
+func TestRecoverFromEventLog(t *testing.T) {
+	log, err := eventlog.Open(t.TempDir(), eventlog.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer log.Close()
+	start := &models.GameState{ID: "g1", SessionWord: "APPLE", Guesses: models.NewRows(constants.MaxGuesses), GuessHistory: []string{}}
+	log.Append(eventlog.GameStarted, "s1", "g1", start)
+	log.Append(eventlog.GuessMade, "s1", "g1", models.GuessEvent{Guess: "TABLE", Row: 0})
+	log.Append(eventlog.GuessMade, "s1", "g1", models.GuessEvent{Guess: "TABLE", Row: 0})
+	log.Append(eventlog.GuessMade, "s1", "g1", models.GuessEvent{Guess: "APPLE", Row: 1})
+
+	app := testApp()
+	game.SetDictionary(app, []models.WordEntry{{Word: "APPLE"}, {Word: "TABLE"}}, nil)
+	app.Sessions.SetGame("s1", &models.GameState{ID: "g1", SessionWord: "APPLE", GuessHistory: []string{"TABLE"}, LastAccessTime: time.Now()})
+	if n, err := session.RecoverFromEventLog(app, log, 0); err != nil || n != 1 {
+		t.Fatalf("RecoverFromEventLog = %d, %v", n, err)
+	}
+	game, _ := app.Sessions.Game("s1")
+	if !slices.Equal(game.GuessHistory, []string{"TABLE", "APPLE"}) || !game.Won {
+		t.Errorf("Expected the won game to be restored once per guess, got %+v", game)
+	}
+
+	logged, err := session.GameFromEventLog(app, log, "g1")
+	if err != nil || logged == nil || !logged.Won {
+		t.Errorf("GameFromEventLog = %+v, %v", logged, err)
+	}
+	if missing, _ := session.GameFromEventLog(app, log, "nope"); missing != nil {
+		t.Errorf("Expected no game for an unknown ID, got %+v", missing)
+	}
+}
+
+func TestRecoverSnapshotAndEventLog(t *testing.T) {
+	log, err := eventlog.Open(t.TempDir(), eventlog.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer log.Close()
+	path := filepath.Join(t.TempDir(), "sessions.json")
+	start := &models.GameState{ID: "g1", SessionWord: "APPLE", Guesses: models.NewRows(constants.MaxGuesses), GuessHistory: []string{}}
+	log.Append(eventlog.GameStarted, "s1", "g1", start)
+	log.Append(eventlog.GuessMade, "s1", "g1", models.GuessEvent{Guess: "TABLE", Row: 0})
+
+	app := testApp()
+	app.EventLog = log
+	game.SetDictionary(app, []models.WordEntry{{Word: "APPLE"}, {Word: "TABLE"}}, nil)
+	session.RecoverFromEventLog(app, log, 0)
+	if _, err := session.SaveSnapshot(app, path); err != nil {
+		t.Fatal(err)
+	}
+	// The guess logged twice, as when the snapshot is taken while it is
+	// being made, is applied once.
+	log.Append(eventlog.GuessMade, "s1", "g1", models.GuessEvent{Guess: "TABLE", Row: 0})
+	log.Append(eventlog.GuessMade, "s1", "g1", models.GuessEvent{Guess: "APPLE", Row: 1})
+	log.Append(eventlog.GameStarted, "s2", "g2", &models.GameState{ID: "g2", SessionWord: "TABLE", Guesses: models.NewRows(constants.MaxGuesses), GuessHistory: []string{}})
+
+	restored := testApp()
+	game.SetDictionary(restored, []models.WordEntry{{Word: "APPLE"}, {Word: "TABLE"}}, nil)
+	_, since, err := session.LoadSnapshot(restored, path)
+	if err != nil || since != 2 {
+		t.Fatalf("Expected the snapshot taken after event 2, got %d, %v", since, err)
+	}
+	if n, err := session.RecoverFromEventLog(restored, log, since); err != nil || n != 2 {
+		t.Fatalf("RecoverFromEventLog = %d, %v", n, err)
+	}
+	s1, _ := restored.Sessions.Game("s1")
+	if !slices.Equal(s1.GuessHistory, []string{"TABLE", "APPLE"}) || !s1.Won {
+		t.Errorf("Expected the guess after the snapshot replayed on top of it, got %+v", s1)
+	}
+	if s2, ok := restored.Sessions.Game("s2"); !ok || s2.ID != "g2" {
+		t.Errorf("Expected the game started after the snapshot restored, got %+v", s2)
+	}
+}
+
+func TestRecoverHintsFromEventLog(t *testing.T) {
+	log, err := eventlog.Open(t.TempDir(), eventlog.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer log.Close()
+	start := &models.GameState{ID: "g1", SessionWord: "APPLE", Guesses: models.NewRows(constants.MaxGuesses), GuessHistory: []string{}}
+	log.Append(eventlog.GameStarted, "s1", "g1", start)
+	log.Append(eventlog.GuessMade, "s1", "g1", models.GuessEvent{Guess: "TABLE", Row: 0})
+	log.Append(eventlog.HintRevealed, "s1", "g1", models.HintEvent{Stage: 0, Taxed: true})
+	log.Append(eventlog.HintRevealed, "s1", "g1", models.HintEvent{Stage: 0, Taxed: true})
+
+	app := testApp()
+	app.HintLimit = constants.HintLimitDefault
+	game.SetDictionary(app, []models.WordEntry{{Word: "APPLE"}, {Word: "TABLE"}}, nil)
+	app.Sessions.SetGame("s1", &models.GameState{ID: "g1", SessionWord: "APPLE", GuessHistory: []string{"TABLE"}, LastAccessTime: time.Now()})
+	if n, err := session.RecoverFromEventLog(app, log, 0); err != nil || n != 1 {
+		t.Fatalf("RecoverFromEventLog = %d, %v", n, err)
+	}
+	game, _ := app.Sessions.Game("s1")
+	if game.HintsRevealed != 1 || game.HintTax != 1 {
+		t.Errorf("Expected one taxed hint restored, got %d revealed and %d taxed", game.HintsRevealed, game.HintTax)
+	}
+}
+
+func TestRotateCSRF(t *testing.T) {
+	app := testApp()
+	app.CSRF = security.NewCSRF([]byte("key"))
+	request := func(cookie *http.Cookie) (*gin.Context, *httptest.ResponseRecorder) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/guess", nil)
+		if cookie != nil {
+			c.Request.AddCookie(cookie)
+		}
+		return c, w
+	}
+
+	c, w := request(nil)
+	session.IssueCSRFToken(app, c)
+	secret := w.Result().Cookies()[0]
+	token := c.GetString(constants.CSRFTokenKey)
+
+	c, w = request(secret)
+	if !session.VerifyCSRF(app, c, token) {
+		t.Fatal("Expected the issued token to verify")
+	}
+	session.RotateCSRF(app, c)
+	rotated := w.Result().Cookies()[0]
+	if rotated.Value == secret.Value || w.Header().Get(constants.CSRFHeader) != c.GetString(constants.CSRFTokenKey) {
+		t.Fatalf("Expected a new secret and token, got cookie %v", rotated)
+	}
+
+	c, _ = request(rotated)
+	if session.VerifyCSRF(app, c, token) {
+		t.Error("Expected tokens issued before the rotation to be refused")
+	}
+	if !session.VerifyCSRF(app, c, w.Header().Get(constants.CSRFHeader)) {
+		t.Error("Expected the token issued with the rotation to verify")
+	}
+}
+
+func TestCSRFBoundToSession(t *testing.T) {
+	app := testApp()
+	app.CSRF = security.NewCSRF([]byte("key"))
+	request := func(cookies ...*http.Cookie) (*gin.Context, *httptest.ResponseRecorder) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/guess", nil)
+		for _, cookie := range cookies {
+			c.Request.AddCookie(cookie)
+		}
+		return c, w
+	}
+	cookie := func(w *httptest.ResponseRecorder, name string) *http.Cookie {
+		for _, cookie := range w.Result().Cookies() {
+			if cookie.Name == name {
+				return cookie
+			}
+		}
+		t.Fatalf("Expected a %s cookie, got %v", name, w.Result().Cookies())
+		return nil
+	}
+
+	// The token of a first visit follows the session created after it.
+	c, w := request()
+	session.IssueCSRFToken(app, c)
+	session.GetOrCreateSession(app, c)
+	ownSecret, ownSession := cookie(w, constants.CSRFCookieName), cookie(w, constants.SessionCookieName)
+	ownToken := c.GetString(constants.CSRFTokenKey)
+	if len(w.Result().Cookies()) != 2 || w.Header().Get(constants.CSRFHeader) != ownToken {
+		t.Fatalf("Expected one secret, one session and the reissued token, got %v", w.Result().Cookies())
+	}
+	c, _ = request(ownSecret, ownSession)
+	if !session.VerifyCSRF(app, c, ownToken) {
+		t.Fatal("Expected the token to verify for its session")
+	}
+
+	// Someone who plants their own secret in another browser cannot use
+	// a token issued to them for that browser's session.
+	c, w = request()
+	session.GetOrCreateSession(app, c)
+	victimSession := cookie(w, constants.SessionCookieName)
+	c, _ = request(ownSecret, victimSession)
+	if session.VerifyCSRF(app, c, ownToken) {
+		t.Error("Expected a token issued to one session to fail under another session's cookie")
+	}
+}
+
+func TestMinimalCookies(t *testing.T) {
+	app := testApp()
+	app.CSRF = security.NewCSRF([]byte("key"))
+	app.MinimalCookies = true
+	app.Store = store.NewMemory()
+	request := func(cookie *http.Cookie) (*gin.Context, *httptest.ResponseRecorder) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/guess", nil)
+		if cookie != nil {
+			c.Request.AddCookie(cookie)
+		}
+		return c, w
+	}
+
+	c, w := request(nil)
+	session.IssueCSRFToken(app, c)
+	sessionID := session.GetOrCreateSession(app, c)
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != constants.SessionCookieName || cookies[0].MaxAge != 0 {
+		t.Fatalf("Expected one session cookie for the browser session only, got %v", cookies)
+	}
+	cookie, token := cookies[0], c.GetString(constants.CSRFTokenKey)
+
+	c, _ = request(cookie)
+	if got := session.CookieID(app, c); got != sessionID {
+		t.Fatalf("CookieID = %q, want %q", got, sessionID)
+	}
+	if !session.VerifyCSRF(app, c, token) {
+		t.Error("Expected the token to verify against the session cookie")
+	}
+	c, _ = request(&http.Cookie{Name: constants.SessionCookieName, Value: app.CSRF.NewSecret()})
+	if session.CookieID(app, c) != "" || session.VerifyCSRF(app, c, token) {
+		t.Error("Expected an unsigned session cookie to be ignored")
+	}
+
+	gameState := &models.GameState{SessionWord: "APPLE", LastAccessTime: time.Now()}
+	app.Sessions.SetGame(sessionID, gameState)
+	session.Persist(context.Background(), app, sessionID, gameState)
+	if n, _ := session.SaveToStore(context.Background(), app); n != 0 {
+		t.Errorf("Expected nothing stored before consent, saved %d", n)
+	}
+
+	c, w = request(cookie)
+	if err := session.SetConsent(context.Background(), app, c, sessionID, true); err != nil {
+		t.Fatal(err)
+	}
+	if cookies := w.Result().Cookies(); len(cookies) != 1 || cookies[0].MaxAge != int(app.CookieMaxAge.Seconds()) {
+		t.Errorf("Expected consent to make the session cookie last, got %v", cookies)
+	}
+	if records, _ := app.Store.Sessions(context.Background(), time.Time{}); len(records) != 1 {
+		t.Errorf("Expected the session stored on consent, got %d", len(records))
+	}
+
+	if err := session.SetConsent(context.Background(), app, c, sessionID, false); err != nil {
+		t.Fatal(err)
+	}
+	if records, _ := app.Store.Sessions(context.Background(), time.Time{}); len(records) != 0 {
+		t.Errorf("Expected the stored session dropped when consent is withdrawn, got %d", len(records))
+	}
+	if _, ok := app.Sessions.Game(sessionID); !ok {
+		t.Error("Expected the game to go on after consent is withdrawn")
+	}
+}
+
+func TestSessionLimit(t *testing.T) {
+	now := time.Now()
+	app := testApp()
+	app.MaxSessions = 3
+	app.SessionLimit = constants.SessionLimitEvict
+	for i, id := range []string{"oldest", "older", "newest"} {
+		app.Sessions.SetGame(id, &models.GameState{LastAccessTime: now.Add(time.Duration(i-3) * time.Minute)})
+	}
+	session.SaveSettings(app, "oldest", models.UserSettings{HardMode: true})
+
+	if !session.Admit(app, "newest") {
+		t.Fatal("A session holding a game should always be admitted")
+	}
+	if !session.Admit(app, "newcomer") {
+		t.Fatal("The evict policy should make room for a new session")
+	}
+	if _, ok := app.Sessions.Game("oldest"); ok || session.GetSettings(app, "oldest").HardMode {
+		t.Error("Expected the least recently used session evicted with its settings")
+	}
+	if _, ok := app.Sessions.Game("older"); !ok {
+		t.Error("Only as many sessions as needed should be evicted")
+	}
+
+	app.Sessions.SetGame("newcomer", &models.GameState{LastAccessTime: now})
+	app.SessionLimit = constants.SessionLimitReject
+	if session.Admit(app, "latecomer") || !session.Admit(app, "older") {
+		t.Error("The reject policy should turn away new sessions only")
+	}
+	limits := session.Limits(app)
+	if limits.Active != 3 || limits.Evicted != 1 || limits.Rejected != 1 {
+		t.Errorf("Unexpected limit status: %+v", limits)
+	}
+
+	app.MaxSessions = 0
+	if !session.Admit(app, "latecomer") {
+		t.Error("Without a limit every session should be admitted")
+	}
+}
@@ -0,0 +1,61 @@
+package shareimage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+
+	util "github.com/CodeAndHammer/vortludo/internal/util"
+)
+
+// Cache memoises rendered images by a digest of what they show, so a board
+// shared again, or by several players, is drawn once. Like the render cache,
+// it starts over when full.
+type Cache struct {
+	mu         sync.Mutex
+	entries    map[[sha256.Size]byte][]byte
+	maxEntries int
+}
+
+func NewCache(maxEntries int) *Cache {
+	return &Cache{
+		entries:    make(map[[sha256.Size]byte][]byte),
+		maxEntries: max(maxEntries, 1),
+	}
+}
+
+// Image returns the PNG Render draws for its arguments, and an ETag that
+// changes whenever the image does.
+func (c *Cache) Image(title string, boards []Board, opts Options) ([]byte, string, error) {
+	b, err := json.Marshal(struct {
+		Title  string
+		Boards []Board
+		Opts   Options
+	}{title, boards, opts})
+	if err != nil {
+		return nil, "", err
+	}
+	key := sha256.Sum256(b)
+	etag := hex.EncodeToString(key[:16])
+
+	c.mu.Lock()
+	data, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok {
+		return data, etag, nil
+	}
+
+	data, err = Render(title, boards, opts)
+	if err != nil {
+		return nil, "", err
+	}
+	c.mu.Lock()
+	if len(c.entries) >= c.maxEntries {
+		util.LogInfo("Share image cache full (%d entries), resetting", len(c.entries))
+		c.entries = make(map[[sha256.Size]byte][]byte)
+	}
+	c.entries[key] = data
+	c.mu.Unlock()
+	return data, etag, nil
+}
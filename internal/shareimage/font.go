@@ -0,0 +1,96 @@
+package shareimage
+
+import "unicode"
+
+// glyphWidth and glyphHeight are the size of a glyph in font pixels. Accents
+// take accentHeight more rows above it.
+const (
+	glyphWidth   = 5
+	glyphHeight  = 7
+	accentHeight = 3
+)
+
+// glyphs is a 5×7 bitmap font covering what share images write: the
+// letters of both languages' words, digits and the punctuation of game
+// labels and scores.
+var glyphs = map[rune][glyphHeight]string{
+	'A': {".###.", "#...#", "#...#", "#####", "#...#", "#...#", "#...#"},
+	'B': {"####.", "#...#", "#...#", "####.", "#...#", "#...#", "####."},
+	'C': {".###.", "#...#", "#....", "#....", "#....", "#...#", ".###."},
+	'D': {"####.", "#...#", "#...#", "#...#", "#...#", "#...#", "####."},
+	'E': {"#####", "#....", "#....", "####.", "#....", "#....", "#####"},
+	'F': {"#####", "#....", "#....", "####.", "#....", "#....", "#...."},
+	'G': {".###.", "#...#", "#....", "#.###", "#...#", "#...#", ".####"},
+	'H': {"#...#", "#...#", "#...#", "#####", "#...#", "#...#", "#...#"},
+	'I': {".###.", "..#..", "..#..", "..#..", "..#..", "..#..", ".###."},
+	'J': {"..###", "...#.", "...#.", "...#.", "...#.", "#..#.", ".##.."},
+	'K': {"#...#", "#..#.", "#.#..", "##...", "#.#..", "#..#.", "#...#"},
+	'L': {"#....", "#....", "#....", "#....", "#....", "#....", "#####"},
+	'M': {"#...#", "##.##", "#.#.#", "#.#.#", "#...#", "#...#", "#...#"},
+	'N': {"#...#", "#...#", "##..#", "#.#.#", "#..##", "#...#", "#...#"},
+	'O': {".###.", "#...#", "#...#", "#...#", "#...#", "#...#", ".###."},
+	'P': {"####.", "#...#", "#...#", "####.", "#....", "#....", "#...."},
+	'Q': {".###.", "#...#", "#...#", "#...#", "#.#.#", "#..#.", ".##.#"},
+	'R': {"####.", "#...#", "#...#", "####.", "#.#..", "#..#.", "#...#"},
+	'S': {".####", "#....", "#....", ".###.", "....#", "....#", "####."},
+	'T': {"#####", "..#..", "..#..", "..#..", "..#..", "..#..", "..#.."},
+	'U': {"#...#", "#...#", "#...#", "#...#", "#...#", "#...#", ".###."},
+	'V': {"#...#", "#...#", "#...#", "#...#", "#...#", ".#.#.", "..#.."},
+	'W': {"#...#", "#...#", "#...#", "#.#.#", "#.#.#", "#.#.#", ".#.#."},
+	'X': {"#...#", "#...#", ".#.#.", "..#..", ".#.#.", "#...#", "#...#"},
+	'Y': {"#...#", "#...#", ".#.#.", "..#..", "..#..", "..#..", "..#.."},
+	'Z': {"#####", "....#", "...#.", "..#..", ".#...", "#....", "#####"},
+	'0': {".###.", "#...#", "#..##", "#.#.#", "##..#", "#...#", ".###."},
+	'1': {"..#..", ".##..", "..#..", "..#..", "..#..", "..#..", ".###."},
+	'2': {".###.", "#...#", "....#", "...#.", "..#..", ".#...", "#####"},
+	'3': {"####.", "....#", "....#", ".###.", "....#", "....#", "####."},
+	'4': {"...#.", "..##.", ".#.#.", "#..#.", "#####", "...#.", "...#."},
+	'5': {"#####", "#....", "####.", "....#", "....#", "#...#", ".###."},
+	'6': {".###.", "#....", "#....", "####.", "#...#", "#...#", ".###."},
+	'7': {"#####", "....#", "...#.", "..#..", ".#...", ".#...", ".#..."},
+	'8': {".###.", "#...#", "#...#", ".###.", "#...#", "#...#", ".###."},
+	'9': {".###.", "#...#", "#...#", ".####", "....#", "....#", ".###."},
+	'#': {".#.#.", ".#.#.", "#####", ".#.#.", "#####", ".#.#.", ".#.#."},
+	'/': {"....#", "....#", "...#.", "..#..", ".#...", "#....", "#...."},
+	'-': {".....", ".....", ".....", "#####", ".....", ".....", "....."},
+	' ': {},
+}
+
+// accents are drawn above a base glyph, leaving a blank row between them.
+var (
+	circumflex = [accentHeight - 1]string{"..#..", ".#.#."}
+	breve      = [accentHeight - 1]string{"#...#", ".###."}
+)
+
+// accented maps the Esperanto letters to their base letter and accent.
+var accented = map[rune]struct {
+	base   rune
+	accent [accentHeight - 1]string
+}{
+	'Ĉ': {'C', circumflex},
+	'Ĝ': {'G', circumflex},
+	'Ĥ': {'H', circumflex},
+	'Ĵ': {'J', circumflex},
+	'Ŝ': {'S', circumflex},
+	'Ŭ': {'U', breve},
+}
+
+// glyph returns the rows of r's glyph from the top of its accent, or false
+// for a rune the font lacks.
+func glyph(r rune) ([]string, bool) {
+	r = unicode.ToUpper(r)
+	rows := make([]string, 0, accentHeight+glyphHeight)
+	base := r
+	if a, ok := accented[r]; ok {
+		base = a.base
+		rows = append(rows, a.accent[:]...)
+		rows = append(rows, "")
+	} else {
+		rows = append(rows, "", "", "")
+	}
+	g, ok := glyphs[base]
+	if !ok {
+		return nil, false
+	}
+	return append(rows, g[:]...), true
+}
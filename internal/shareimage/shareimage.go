@@ -0,0 +1,156 @@
+// Package shareimage draws the board of a finished game as a PNG, for
+// sharing on platforms that strip emoji grids. It needs neither fonts nor a
+// browser: the little text it writes comes from a built-in bitmap font.
+package shareimage
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+
+	constants "github.com/CodeAndHammer/vortludo/internal/constants"
+)
+
+// Tile is one scored letter of a board.
+type Tile struct {
+	Letter string
+	Status string
+}
+
+// Board is the rows a board was played for, each of constants.WordLength
+// tiles.
+type Board [][]Tile
+
+// Options change how an image is drawn.
+type Options struct {
+	// SpoilerFree leaves the letters out, so only the colours show.
+	SpoilerFree bool
+	// ColorBlind uses the high-contrast palette of the color-blind setting.
+	ColorBlind bool
+}
+
+// Layout, in image pixels. Letters are drawn letterScale pixels per font
+// pixel and the title titleScale.
+const (
+	padding     = 24
+	tileSize    = 56
+	tileGap     = 6
+	boardGap    = 24
+	boardsWide  = 2
+	letterScale = 4
+	titleScale  = 3
+	titleGap    = 20
+)
+
+// The sepia palette of style.css, and the color-blind one.
+var (
+	background  = color.RGBA{0xf4, 0xec, 0xd8, 0xff}
+	textColor   = color.RGBA{0x6b, 0x54, 0x34, 0xff}
+	letterColor = color.RGBA{0xf7, 0xf2, 0xe3, 0xff}
+	darkLetter  = color.RGBA{0x1a, 0x1a, 0x1a, 0xff}
+	emptyTile   = color.RGBA{0xd2, 0xc2, 0xa3, 0xff}
+
+	statusColors = map[string]color.RGBA{
+		constants.GuessStatusCorrect: {0x8a, 0x7c, 0x4f, 0xff},
+		constants.GuessStatusPresent: {0xbf, 0xa0, 0x55, 0xff},
+		constants.GuessStatusAbsent:  {0xa8, 0x9c, 0x8a, 0xff},
+	}
+	colorBlindColors = map[string]color.RGBA{
+		constants.GuessStatusCorrect: {0xf5, 0x79, 0x3a, 0xff},
+		constants.GuessStatusPresent: {0x85, 0xc0, 0xf9, 0xff},
+	}
+)
+
+// Render draws title above boards, two boards to a line, and encodes the
+// result as a PNG.
+func Render(title string, boards []Board, opts Options) ([]byte, error) {
+	boardWidth := constants.WordLength*(tileSize+tileGap) - tileGap
+	rows := 1
+	for _, board := range boards {
+		rows = max(rows, len(board))
+	}
+	boardHeight := rows*(tileSize+tileGap) - tileGap
+	wide := min(max(len(boards), 1), boardsWide)
+	high := (max(len(boards), 1) + wide - 1) / wide
+	titleHeight := (accentHeight + glyphHeight) * titleScale
+
+	width := max(2*padding+wide*boardWidth+(wide-1)*boardGap, 2*padding+textWidth(title, titleScale))
+	height := 2*padding + titleHeight + titleGap + high*boardHeight + (high-1)*boardGap
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	fill(img, img.Bounds(), background)
+	drawText(img, padding, padding, title, titleScale, textColor)
+
+	top := padding + titleHeight + titleGap
+	for i, board := range boards {
+		x := padding + (i%wide)*(boardWidth+boardGap)
+		y := top + (i/wide)*(boardHeight+boardGap)
+		for r, row := range board {
+			for t, tile := range row {
+				drawTile(img, x+t*(tileSize+tileGap), y+r*(tileSize+tileGap), tile, opts)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func drawTile(img *image.RGBA, x, y int, tile Tile, opts Options) {
+	bg, ok := statusColors[tile.Status]
+	if !ok {
+		bg = emptyTile
+	}
+	fg := letterColor
+	if opts.ColorBlind {
+		if c, ok := colorBlindColors[tile.Status]; ok {
+			bg = c
+		}
+		if tile.Status == constants.GuessStatusPresent {
+			fg = darkLetter
+		}
+	}
+	fill(img, image.Rect(x, y, x+tileSize, y+tileSize), bg)
+	if opts.SpoilerFree || tile.Letter == "" {
+		return
+	}
+	// The letter is centred, and an accent rises above it.
+	w := textWidth(tile.Letter, letterScale)
+	top := (tileSize-glyphHeight*letterScale)/2 - accentHeight*letterScale
+	drawText(img, x+(tileSize-w)/2, y+top, tile.Letter, letterScale, fg)
+}
+
+// textWidth is the width of text drawn at scale, glyphs a font pixel apart.
+func textWidth(text string, scale int) int {
+	n := len([]rune(text))
+	if n == 0 {
+		return 0
+	}
+	return (n*(glyphWidth+1) - 1) * scale
+}
+
+// drawText draws text with its top left, accents included, at x, y. Runes
+// the font lacks are left blank.
+func drawText(img *image.RGBA, x, y int, text string, scale int, c color.RGBA) {
+	for _, r := range text {
+		if rows, ok := glyph(r); ok {
+			for gy, row := range rows {
+				for gx, bit := range row {
+					if bit == '#' {
+						px, py := x+gx*scale, y+gy*scale
+						fill(img, image.Rect(px, py, px+scale, py+scale), c)
+					}
+				}
+			}
+		}
+		x += (glyphWidth + 1) * scale
+	}
+}
+
+func fill(img *image.RGBA, r image.Rectangle, c color.RGBA) {
+	draw.Draw(img, r, &image.Uniform{C: c}, image.Point{}, draw.Src)
+}
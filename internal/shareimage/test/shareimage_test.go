@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"image/color"
+	"image/png"
+	"testing"
+
+	constants "github.com/CodeAndHammer/vortludo/internal/constants"
+	shareimage "github.com/CodeAndHammer/vortludo/internal/shareimage"
+)
+
+func row(word string, statuses ...string) []shareimage.Tile {
+	tiles := make([]shareimage.Tile, 0, len(statuses))
+	for i, letter := range []rune(word) {
+		tiles = append(tiles, shareimage.Tile{Letter: string(letter), Status: statuses[i]})
+	}
+	return tiles
+}
+
+const (
+	absent  = constants.GuessStatusAbsent
+	present = constants.GuessStatusPresent
+	correct = constants.GuessStatusCorrect
+)
+
+var board = shareimage.Board{
+	row("SLATE", absent, absent, correct, absent, correct),
+	row("ĈAPEL", present, correct, absent, correct, absent),
+	row("CRANE", correct, correct, correct, correct, correct),
+}
+
+func TestRender(t *testing.T) {
+	data, err := shareimage.Render("Vortludo game 3 3/6", []shareimage.Board{board}, shareimage.Options{})
+	if err != nil {
+		t.Fatalf("Expected the board to render, got %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Expected a PNG, got %v", err)
+	}
+	if b := img.Bounds(); b.Dy() <= b.Dx()/2 || b.Dx() < 5*56 {
+		t.Errorf("Expected room for three rows of five tiles, got %v", b)
+	}
+
+	// The last row is all correct: its first tile's corner has that colour.
+	corner := color.RGBAModel.Convert(img.At(24, img.Bounds().Dy()-24-1)).(color.RGBA)
+	if corner != (color.RGBA{0x8a, 0x7c, 0x4f, 0xff}) {
+		t.Errorf("Expected the correct colour at the last row's corner, got %v", corner)
+	}
+
+	free, err := shareimage.Render("Vortludo game 3 3/6", []shareimage.Board{board}, shareimage.Options{SpoilerFree: true})
+	if err != nil || bytes.Equal(free, data) {
+		t.Errorf("Expected the spoiler-free image to leave the letters out (%v)", err)
+	}
+}
+
+func TestRenderBoards(t *testing.T) {
+	one, _ := shareimage.Render("X", []shareimage.Board{board}, shareimage.Options{})
+	four, _ := shareimage.Render("X", []shareimage.Board{board, board, board[:1], nil}, shareimage.Options{})
+	oneImg, _ := png.Decode(bytes.NewReader(one))
+	fourImg, _ := png.Decode(bytes.NewReader(four))
+	if fourImg.Bounds().Dx() <= oneImg.Bounds().Dx() || fourImg.Bounds().Dy() <= oneImg.Bounds().Dy() {
+		t.Errorf("Expected four boards two by two, got %v for one and %v for four", oneImg.Bounds(), fourImg.Bounds())
+	}
+}
+
+func TestCache(t *testing.T) {
+	cache := shareimage.NewCache(2)
+	boards := []shareimage.Board{board}
+	first, etag, err := cache.Image("T", boards, shareimage.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, againTag, _ := cache.Image("T", boards, shareimage.Options{})
+	if &first[0] != &again[0] || etag != againTag {
+		t.Errorf("Expected the same board to be served from the cache")
+	}
+	_, freeTag, _ := cache.Image("T", boards, shareimage.Options{SpoilerFree: true})
+	_, blindTag, _ := cache.Image("T", boards, shareimage.Options{ColorBlind: true})
+	if freeTag == etag || blindTag == etag || freeTag == blindTag {
+		t.Errorf("Expected each variant its own ETag, got %s, %s and %s", etag, freeTag, blindTag)
+	}
+}
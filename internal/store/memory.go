@@ -0,0 +1,191 @@
+package store
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	auth "github.com/CodeAndHammer/vortludo/internal/auth"
+)
+
+// Memory is a Store that keeps everything in memory, for development and
+// tests. The zero value is not ready to use; call NewMemory.
+type Memory struct {
+	mu           sync.Mutex
+	sessions     map[string]Session
+	stats        map[string]auth.Stats
+	completed    map[string][]string
+	achievements map[string]map[string]time.Time
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		sessions:     make(map[string]Session),
+		stats:        make(map[string]auth.Stats),
+		completed:    make(map[string][]string),
+		achievements: make(map[string]map[string]time.Time),
+	}
+}
+
+func (m *Memory) SaveSession(_ context.Context, s Session) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s.Game = slices.Clone(s.Game)
+	s.Settings = slices.Clone(s.Settings)
+	s.Heatmap = slices.Clone(s.Heatmap)
+	m.sessions[s.ID] = s
+	return nil
+}
+
+func (m *Memory) Sessions(_ context.Context, since time.Time) ([]Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var sessions []Session
+	for _, s := range m.sessions {
+		if !s.UpdatedAt.Before(since) {
+			sessions = append(sessions, s)
+		}
+	}
+	return sessions, nil
+}
+
+func (m *Memory) DeleteSession(_ context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deleteLocked(id)
+	return nil
+}
+
+func (m *Memory) PruneSessions(_ context.Context, cutoff time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	pruned := 0
+	for id, s := range m.sessions {
+		if s.UpdatedAt.Before(cutoff) {
+			m.deleteLocked(id)
+			pruned++
+		}
+	}
+	// What is kept for sessions that were never saved goes too.
+	orphaned := func(id string) bool {
+		_, ok := m.sessions[id]
+		return !ok
+	}
+	for id := range m.stats {
+		if orphaned(id) {
+			delete(m.stats, id)
+		}
+	}
+	for id := range m.completed {
+		if orphaned(id) {
+			delete(m.completed, id)
+		}
+	}
+	for id := range m.achievements {
+		if orphaned(id) {
+			delete(m.achievements, id)
+		}
+	}
+	return pruned, nil
+}
+
+func (m *Memory) deleteLocked(id string) {
+	delete(m.sessions, id)
+	delete(m.stats, id)
+	delete(m.completed, id)
+	delete(m.achievements, id)
+}
+
+func (m *Memory) SaveStats(_ context.Context, sessionID string, stats auth.Stats) error {
+	stats, achievements := splitAchievements(stats)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stats[sessionID] = stats
+	for id, at := range achievements {
+		m.unlockLocked(sessionID, id, at)
+	}
+	return nil
+}
+
+func (m *Memory) Stats(_ context.Context, sessionID string) (auth.Stats, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stats, ok := m.stats[sessionID]
+	if !ok {
+		return auth.Stats{}, false, nil
+	}
+	stats = stats.Clone()
+	stats.Achievements = maps.Clone(m.achievements[sessionID])
+	return stats, true, nil
+}
+
+func (m *Memory) AddCompletedWords(_ context.Context, sessionID string, words ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, word := range words {
+		if !slices.Contains(m.completed[sessionID], word) {
+			m.completed[sessionID] = append(m.completed[sessionID], word)
+		}
+	}
+	return nil
+}
+
+func (m *Memory) CompletedWords(_ context.Context, sessionID string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Clone(m.completed[sessionID]), nil
+}
+
+func (m *Memory) ClearCompletedWords(_ context.Context, sessionID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.completed, sessionID)
+	return nil
+}
+
+func (m *Memory) UnlockAchievement(_ context.Context, sessionID, id string, at time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.unlockLocked(sessionID, id, at), nil
+}
+
+func (m *Memory) unlockLocked(sessionID, id string, at time.Time) bool {
+	if _, ok := m.achievements[sessionID][id]; ok {
+		return false
+	}
+	if m.achievements[sessionID] == nil {
+		m.achievements[sessionID] = make(map[string]time.Time)
+	}
+	m.achievements[sessionID][id] = at
+	return true
+}
+
+func (m *Memory) Achievements(_ context.Context, sessionID string) (map[string]time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return maps.Clone(m.achievements[sessionID]), nil
+}
+
+func (m *Memory) Apply(ctx context.Context, writes []Write) error {
+	for _, w := range writes {
+		if w.Session != nil {
+			m.SaveSession(ctx, *w.Session)
+		}
+		if w.Stats != nil {
+			m.SaveStats(ctx, w.SessionID, *w.Stats)
+		}
+		if w.ClearCompleted {
+			m.ClearCompletedWords(ctx, w.SessionID)
+		}
+		m.AddCompletedWords(ctx, w.SessionID, w.CompletedWords...)
+	}
+	return nil
+}
+
+func (m *Memory) Ping(context.Context) error { return nil }
+
+func (m *Memory) Pool() PoolStats { return PoolStats{Backend: "memory"} }
+
+func (m *Memory) Close() error { return nil }
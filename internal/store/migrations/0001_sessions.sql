@@ -0,0 +1,31 @@
+-- Sessions, and what is kept for them. Times are Unix nanoseconds, and
+-- encoded state is JSON text, so the schema reads the same in SQLite and
+-- PostgreSQL.
+CREATE TABLE sessions (
+    id TEXT PRIMARY KEY,
+    game TEXT,
+    settings TEXT,
+    heatmap TEXT,
+    updated_at BIGINT NOT NULL
+);
+
+CREATE INDEX sessions_updated_at ON sessions (updated_at);
+
+CREATE TABLE stats (
+    session_id TEXT PRIMARY KEY,
+    stats TEXT NOT NULL
+);
+
+CREATE TABLE completed_words (
+    session_id TEXT NOT NULL,
+    word TEXT NOT NULL,
+    completed_at BIGINT NOT NULL,
+    PRIMARY KEY (session_id, word)
+);
+
+CREATE TABLE achievements (
+    session_id TEXT NOT NULL,
+    achievement TEXT NOT NULL,
+    unlocked_at BIGINT NOT NULL,
+    PRIMARY KEY (session_id, achievement)
+);
@@ -0,0 +1,408 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	auth "github.com/CodeAndHammer/vortludo/internal/auth"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "modernc.org/sqlite"
+)
+
+// dialect is what differs between the SQL databases a store can use. The
+// queries themselves are written once, with ? placeholders.
+type dialect struct {
+	name   string
+	driver string
+	// numbered placeholders are written $1, $2, … instead of ?.
+	numbered bool
+}
+
+var (
+	sqlite   = dialect{name: "sqlite", driver: "sqlite"}
+	postgres = dialect{name: "postgres", driver: "pgx", numbered: true}
+)
+
+// rebind rewrites the ? placeholders of query for d.
+func (d dialect) rebind(query string) string {
+	if !d.numbered {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// SQL is a Store kept in an SQLite or PostgreSQL database.
+type SQL struct {
+	db      *sql.DB
+	dialect dialect
+
+	// The statements of the guess path are prepared once, when the store
+	// is opened; database/sql prepares them again on each new connection.
+	saveSession  *sql.Stmt
+	saveStats    *sql.Stmt
+	addCompleted *sql.Stmt
+	unlock       *sql.Stmt
+}
+
+const (
+	saveSessionQuery = `INSERT INTO sessions (id, game, settings, heatmap, updated_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET game = excluded.game, settings = excluded.settings,
+			heatmap = excluded.heatmap, updated_at = excluded.updated_at`
+	saveStatsQuery = `INSERT INTO stats (session_id, stats) VALUES (?, ?)
+		ON CONFLICT (session_id) DO UPDATE SET stats = excluded.stats`
+	addCompletedQuery   = `INSERT INTO completed_words (session_id, word, completed_at) VALUES (?, ?, ?) ON CONFLICT DO NOTHING`
+	unlockQuery         = `INSERT INTO achievements (session_id, achievement, unlocked_at) VALUES (?, ?, ?) ON CONFLICT DO NOTHING`
+	clearCompletedQuery = `DELETE FROM completed_words WHERE session_id = ?`
+)
+
+// migrationLock is the PostgreSQL advisory lock held while migrating, so
+// replicas starting together apply each migration once.
+const migrationLock = 0x766f72746c75646f
+
+func openSQL(ctx context.Context, d dialect, dsn string, opts Options) (*SQL, error) {
+	if d == sqlite {
+		// SQLite takes one writer at a time; a single connection queues
+		// writers in the pool instead of failing them as busy.
+		dsn = dsnWithPragmas(dsn, "busy_timeout(5000)", "journal_mode(WAL)")
+	}
+	db, err := sql.Open(d.driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	if d == sqlite {
+		db.SetMaxOpenConns(1)
+	} else {
+		opts.apply(db)
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("connect to %s: %w", d.name, err)
+	}
+	if err := migrateLocked(ctx, db, d); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("migrate %s store: %w", d.name, err)
+	}
+
+	s := &SQL{db: db, dialect: d}
+	for _, stmt := range []struct {
+		dst   **sql.Stmt
+		query string
+	}{
+		{&s.saveSession, saveSessionQuery},
+		{&s.saveStats, saveStatsQuery},
+		{&s.addCompleted, addCompletedQuery},
+		{&s.unlock, unlockQuery},
+	} {
+		if *stmt.dst, err = db.PrepareContext(ctx, d.rebind(stmt.query)); err != nil {
+			s.Close()
+			return nil, fmt.Errorf("prepare %s statement: %w", d.name, err)
+		}
+	}
+	return s, nil
+}
+
+// migrateLocked migrates db, holding the migration lock on PostgreSQL. The
+// migrations run on the connection that holds the lock, so they need no
+// second connection from the pool, which may have only the one.
+func migrateLocked(ctx context.Context, db *sql.DB, d dialect) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if d == postgres {
+		if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLock); err != nil {
+			return err
+		}
+		defer conn.ExecContext(context.WithoutCancel(ctx), `SELECT pg_advisory_unlock($1)`, migrationLock)
+	}
+	_, err = migrate(ctx, conn, d)
+	return err
+}
+
+func dsnWithPragmas(dsn string, pragmas ...string) string {
+	sep := "?"
+	if strings.Contains(dsn, "?") {
+		sep = "&"
+	}
+	for _, pragma := range pragmas {
+		dsn += sep + "_pragma=" + pragma
+		sep = "&"
+	}
+	return dsn
+}
+
+func (s *SQL) exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return s.db.ExecContext(ctx, s.dialect.rebind(query), args...)
+}
+
+func (s *SQL) query(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return s.db.QueryContext(ctx, s.dialect.rebind(query), args...)
+}
+
+func (s *SQL) SaveSession(ctx context.Context, session Session) error {
+	_, err := s.saveSession.ExecContext(ctx, session.ID, nullJSON(session.Game), nullJSON(session.Settings), nullJSON(session.Heatmap), session.UpdatedAt.UnixNano())
+	return err
+}
+
+func (s *SQL) Sessions(ctx context.Context, since time.Time) ([]Session, error) {
+	rows, err := s.query(ctx, `SELECT id, game, settings, heatmap, updated_at FROM sessions WHERE updated_at >= ?`, since.UnixNano())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var sessions []Session
+	for rows.Next() {
+		var session Session
+		var game, settings, heatmap sql.NullString
+		var updated int64
+		if err := rows.Scan(&session.ID, &game, &settings, &heatmap, &updated); err != nil {
+			return nil, err
+		}
+		session.Game = rawJSON(game)
+		session.Settings = rawJSON(settings)
+		session.Heatmap = rawJSON(heatmap)
+		session.UpdatedAt = time.Unix(0, updated)
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+func (s *SQL) DeleteSession(ctx context.Context, id string) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		for _, table := range []string{"stats", "completed_words", "achievements"} {
+			if _, err := tx.ExecContext(ctx, s.dialect.rebind(`DELETE FROM `+table+` WHERE session_id = ?`), id); err != nil {
+				return err
+			}
+		}
+		_, err := tx.ExecContext(ctx, s.dialect.rebind(`DELETE FROM sessions WHERE id = ?`), id)
+		return err
+	})
+}
+
+// PruneSessions also removes what is kept for sessions that were never
+// saved, e.g. the completed words of a session lost in a crash.
+func (s *SQL) PruneSessions(ctx context.Context, cutoff time.Time) (int, error) {
+	pruned := 0
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, s.dialect.rebind(`DELETE FROM sessions WHERE updated_at < ?`), cutoff.UnixNano())
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		pruned = int(n)
+		for _, table := range []string{"stats", "completed_words", "achievements"} {
+			if _, err := tx.ExecContext(ctx, `DELETE FROM `+table+` WHERE session_id NOT IN (SELECT id FROM sessions)`); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return pruned, err
+}
+
+func (s *SQL) SaveStats(ctx context.Context, sessionID string, stats auth.Stats) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		return s.saveStatsTx(ctx, tx, sessionID, stats)
+	})
+}
+
+func (s *SQL) saveStatsTx(ctx context.Context, tx *sql.Tx, sessionID string, stats auth.Stats) error {
+	stats, achievements := splitAchievements(stats)
+	data, err := json.Marshal(stats)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.StmtContext(ctx, s.saveStats).ExecContext(ctx, sessionID, string(data)); err != nil {
+		return err
+	}
+	for id, at := range achievements {
+		if _, err := tx.StmtContext(ctx, s.unlock).ExecContext(ctx, sessionID, id, at.UnixNano()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SQL) Stats(ctx context.Context, sessionID string) (auth.Stats, bool, error) {
+	var data string
+	err := s.db.QueryRowContext(ctx, s.dialect.rebind(`SELECT stats FROM stats WHERE session_id = ?`), sessionID).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return auth.Stats{}, false, nil
+	}
+	if err != nil {
+		return auth.Stats{}, false, err
+	}
+	var stats auth.Stats
+	if err := json.Unmarshal([]byte(data), &stats); err != nil {
+		return auth.Stats{}, false, fmt.Errorf("decode stats of session %s: %w", sessionID, err)
+	}
+	if stats.Achievements, err = s.Achievements(ctx, sessionID); err != nil {
+		return auth.Stats{}, false, err
+	}
+	return stats, true, nil
+}
+
+func (s *SQL) AddCompletedWords(ctx context.Context, sessionID string, words ...string) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		return s.addCompletedTx(ctx, tx, sessionID, words)
+	})
+}
+
+func (s *SQL) addCompletedTx(ctx context.Context, tx *sql.Tx, sessionID string, words []string) error {
+	now := time.Now().UnixNano()
+	stmt := tx.StmtContext(ctx, s.addCompleted)
+	for i, word := range words {
+		// Words added together keep their order.
+		if _, err := stmt.ExecContext(ctx, sessionID, word, now+int64(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SQL) CompletedWords(ctx context.Context, sessionID string) ([]string, error) {
+	rows, err := s.query(ctx, `SELECT word FROM completed_words WHERE session_id = ? ORDER BY completed_at, word`, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var words []string
+	for rows.Next() {
+		var word string
+		if err := rows.Scan(&word); err != nil {
+			return nil, err
+		}
+		words = append(words, word)
+	}
+	return words, rows.Err()
+}
+
+func (s *SQL) ClearCompletedWords(ctx context.Context, sessionID string) error {
+	_, err := s.exec(ctx, clearCompletedQuery, sessionID)
+	return err
+}
+
+func (s *SQL) UnlockAchievement(ctx context.Context, sessionID, id string, at time.Time) (bool, error) {
+	res, err := s.unlock.ExecContext(ctx, sessionID, id, at.UnixNano())
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (s *SQL) Achievements(ctx context.Context, sessionID string) (map[string]time.Time, error) {
+	rows, err := s.query(ctx, `SELECT achievement, unlocked_at FROM achievements WHERE session_id = ?`, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var achievements map[string]time.Time
+	for rows.Next() {
+		var id string
+		var at int64
+		if err := rows.Scan(&id, &at); err != nil {
+			return nil, err
+		}
+		if achievements == nil {
+			achievements = make(map[string]time.Time)
+		}
+		achievements[id] = time.Unix(0, at)
+	}
+	return achievements, rows.Err()
+}
+
+func (s *SQL) Apply(ctx context.Context, writes []Write) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		for _, w := range writes {
+			if session := w.Session; session != nil {
+				if _, err := tx.StmtContext(ctx, s.saveSession).ExecContext(ctx, session.ID, nullJSON(session.Game), nullJSON(session.Settings), nullJSON(session.Heatmap), session.UpdatedAt.UnixNano()); err != nil {
+					return fmt.Errorf("save session %s: %w", session.ID, err)
+				}
+			}
+			if w.Stats != nil {
+				if err := s.saveStatsTx(ctx, tx, w.SessionID, *w.Stats); err != nil {
+					return fmt.Errorf("save stats of session %s: %w", w.SessionID, err)
+				}
+			}
+			if w.ClearCompleted {
+				if _, err := tx.ExecContext(ctx, s.dialect.rebind(clearCompletedQuery), w.SessionID); err != nil {
+					return fmt.Errorf("clear completed words of session %s: %w", w.SessionID, err)
+				}
+			}
+			if err := s.addCompletedTx(ctx, tx, w.SessionID, w.CompletedWords); err != nil {
+				return fmt.Errorf("add completed words of session %s: %w", w.SessionID, err)
+			}
+		}
+		return nil
+	})
+}
+
+func (s *SQL) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+func (s *SQL) Pool() PoolStats {
+	stats := s.db.Stats()
+	return PoolStats{
+		Backend:  s.dialect.name,
+		Open:     stats.OpenConnections,
+		InUse:    stats.InUse,
+		Idle:     stats.Idle,
+		MaxOpen:  stats.MaxOpenConnections,
+		Waits:    stats.WaitCount,
+		WaitedMS: float64(stats.WaitDuration) / float64(time.Millisecond),
+	}
+}
+
+func (s *SQL) Close() error {
+	for _, stmt := range []*sql.Stmt{s.saveSession, s.saveStats, s.addCompleted, s.unlock} {
+		if stmt != nil {
+			stmt.Close()
+		}
+	}
+	return s.db.Close()
+}
+
+func (s *SQL) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func nullJSON(data json.RawMessage) sql.NullString {
+	return sql.NullString{String: string(data), Valid: len(data) > 0}
+}
+
+func rawJSON(s sql.NullString) json.RawMessage {
+	if !s.Valid {
+		return nil
+	}
+	return json.RawMessage(s.String)
+}
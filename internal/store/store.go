@@ -0,0 +1,179 @@
+// Package store persists what the server keeps per session — games,
+// settings, stats, completed words and achievements — behind one interface,
+// so the same code runs against memory, SQLite or PostgreSQL. SQL backends
+// are brought up to date by the embedded migrations when they are opened.
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	auth "github.com/CodeAndHammer/vortludo/internal/auth"
+)
+
+// Schemes are the kinds of store URL Open understands.
+var Schemes = []string{"memory", "sqlite", "postgres"}
+
+var ErrUnsupportedURL = errors.New("want memory:, sqlite:<file> or postgres://…")
+
+// Session is the saved state of one session. Its game, settings and heatmap
+// are kept as the session layer encodes them; the store does not interpret
+// them.
+type Session struct {
+	ID        string
+	Game      json.RawMessage
+	Settings  json.RawMessage
+	Heatmap   json.RawMessage
+	UpdatedAt time.Time
+}
+
+// Write is one batched change to a session: only what is set is written.
+// Its completed words are cleared first when ClearCompleted is set.
+type Write struct {
+	SessionID      string
+	Session        *Session
+	Stats          *auth.Stats
+	ClearCompleted bool
+	CompletedWords []string
+}
+
+// merge folds the later write w into q, as if both were made in turn.
+func (q *Write) merge(w Write) {
+	if w.Session != nil {
+		q.Session = w.Session
+	}
+	if w.Stats != nil {
+		q.Stats = w.Stats
+	}
+	if w.ClearCompleted {
+		q.ClearCompleted = true
+		q.CompletedWords = nil
+	}
+	q.CompletedWords = append(q.CompletedWords, w.CompletedWords...)
+}
+
+// Store is a persistence backend. Everything saved for a session is removed
+// together with it.
+type Store interface {
+	// SaveSession replaces the session's saved state.
+	SaveSession(ctx context.Context, s Session) error
+	// Sessions returns the sessions updated at or after since.
+	Sessions(ctx context.Context, since time.Time) ([]Session, error)
+	DeleteSession(ctx context.Context, id string) error
+	// PruneSessions removes the sessions last updated before cutoff, and
+	// reports how many there were.
+	PruneSessions(ctx context.Context, cutoff time.Time) (int, error)
+
+	// SaveStats replaces the session's stats. The achievements in them are
+	// unlocked as by UnlockAchievement.
+	SaveStats(ctx context.Context, sessionID string, stats auth.Stats) error
+	// Stats returns the session's stats, achievements included.
+	Stats(ctx context.Context, sessionID string) (auth.Stats, bool, error)
+
+	// AddCompletedWords notes words as completed by the session.
+	AddCompletedWords(ctx context.Context, sessionID string, words ...string) error
+	// CompletedWords returns the session's completed words, oldest first.
+	CompletedWords(ctx context.Context, sessionID string) ([]string, error)
+	ClearCompletedWords(ctx context.Context, sessionID string) error
+
+	// UnlockAchievement notes achievement id as unlocked at at, unless it
+	// already was, and reports whether it was new.
+	UnlockAchievement(ctx context.Context, sessionID, id string, at time.Time) (bool, error)
+	Achievements(ctx context.Context, sessionID string) (map[string]time.Time, error)
+
+	// Apply makes writes in order, in one transaction where the backend
+	// has them.
+	Apply(ctx context.Context, writes []Write) error
+
+	// Ping reports whether the store can be reached.
+	Ping(ctx context.Context) error
+	// Pool describes the store's connections.
+	Pool() PoolStats
+	Close() error
+}
+
+// Options tune the connection pool of a PostgreSQL store; zero values keep
+// the defaults of database/sql. An SQLite store always uses one connection.
+type Options struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+func (o Options) apply(db *sql.DB) {
+	if o.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(o.MaxOpenConns)
+	}
+	if o.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(o.MaxIdleConns)
+	}
+	if o.ConnMaxLifetime > 0 {
+		db.SetConnMaxLifetime(o.ConnMaxLifetime)
+	}
+	if o.ConnMaxIdleTime > 0 {
+		db.SetConnMaxIdleTime(o.ConnMaxIdleTime)
+	}
+}
+
+// PoolStats describe a store's connections, for /readyz. A memory store
+// has none.
+type PoolStats struct {
+	Backend  string  `json:"backend"`
+	Open     int     `json:"open"`
+	InUse    int     `json:"in_use"`
+	Idle     int     `json:"idle"`
+	MaxOpen  int     `json:"max_open"`
+	Waits    int64   `json:"waits"`
+	WaitedMS float64 `json:"waited_ms"`
+}
+
+// Open opens the store rawURL names and applies any pending migrations:
+// "memory:" keeps everything in memory, "sqlite:<file>" in an SQLite
+// database, and a postgres:// or postgresql:// URL in PostgreSQL.
+func Open(ctx context.Context, rawURL string, opts Options) (Store, error) {
+	scheme, rest, _ := strings.Cut(rawURL, ":")
+	switch strings.ToLower(scheme) {
+	case "memory":
+		return NewMemory(), nil
+	case "sqlite":
+		path := strings.TrimPrefix(rest, "//")
+		if path == "" {
+			return nil, fmt.Errorf("%q: want sqlite:<file>", rawURL)
+		}
+		return openSQL(ctx, sqlite, path, opts)
+	case "postgres", "postgresql":
+		return openSQL(ctx, postgres, rawURL, opts)
+	}
+	return nil, fmt.Errorf("%q: %w", Redact(rawURL), ErrUnsupportedURL)
+}
+
+// Redact hides the password of a store URL, for logging it.
+func Redact(rawURL string) string {
+	scheme, rest, ok := strings.Cut(rawURL, "://")
+	if !ok {
+		return rawURL
+	}
+	userinfo, host, ok := strings.Cut(rest, "@")
+	if !ok {
+		return rawURL
+	}
+	if user, _, hasPassword := strings.Cut(userinfo, ":"); hasPassword {
+		userinfo = user + ":xxxxx"
+	}
+	return scheme + "://" + userinfo + "@" + host
+}
+
+// splitAchievements returns stats without their achievements, and the
+// achievements, which are stored apart from them.
+func splitAchievements(stats auth.Stats) (auth.Stats, map[string]time.Time) {
+	stats = stats.Clone()
+	achievements := stats.Achievements
+	stats.Achievements = nil
+	return stats, achievements
+}
@@ -0,0 +1,153 @@
+package store
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	util "github.com/CodeAndHammer/vortludo/internal/util"
+)
+
+// WriteBehind queues writes for a Store and makes them in batches from a
+// background routine, so a guess does not wait for the database. Writes to
+// a session merge while they wait, keeping the queue at one entry per
+// session however often it is written. What is still queued when the
+// process dies is lost, so the flush interval bounds the loss.
+type WriteBehind struct {
+	store    Store
+	interval time.Duration
+	batch    int
+	wake     chan struct{}
+
+	mu      sync.Mutex
+	pending map[string]*Write
+	order   []string
+
+	flushed atomic.Int64
+	failed  atomic.Int64
+}
+
+// QueueStats describe a write-behind queue, for /readyz.
+type QueueStats struct {
+	Pending int   `json:"pending"`
+	Flushed int64 `json:"flushed"`
+	Failed  int64 `json:"failed"`
+}
+
+// NewWriteBehind queues writes for st, flushing them every interval, or as
+// soon as batch sessions are waiting, at most batch per transaction.
+func NewWriteBehind(st Store, interval time.Duration, batch int) *WriteBehind {
+	return &WriteBehind{
+		store:    st,
+		interval: interval,
+		batch:    max(batch, 1),
+		wake:     make(chan struct{}, 1),
+		pending:  make(map[string]*Write),
+	}
+}
+
+// Enqueue queues w, merging it into what is already queued for its session.
+func (q *WriteBehind) Enqueue(w Write) {
+	q.mu.Lock()
+	if queued, ok := q.pending[w.SessionID]; ok {
+		queued.merge(w)
+	} else {
+		q.pending[w.SessionID] = &w
+		q.order = append(q.order, w.SessionID)
+	}
+	full := len(q.order) >= q.batch
+	q.mu.Unlock()
+	if full {
+		select {
+		case q.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Drop forgets what is queued for sessionID, e.g. before the session is
+// deleted, so that it is not written back.
+func (q *WriteBehind) Drop(sessionID string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, ok := q.pending[sessionID]; !ok {
+		return
+	}
+	delete(q.pending, sessionID)
+	q.order = slices.DeleteFunc(q.order, func(id string) bool { return id == sessionID })
+}
+
+// Run flushes the queue every interval until ctx is done. It leaves the
+// final flush to the caller, which may want a deadline of its own.
+func (q *WriteBehind) Run(ctx context.Context) {
+	ticker := time.NewTicker(q.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-q.wake:
+		}
+		if err := q.Flush(ctx); err != nil {
+			util.LogWarn("Failed to flush queued store writes: %v", err)
+		}
+	}
+}
+
+// Flush writes everything queued, a batch per transaction. A batch that
+// fails goes back on the queue, under anything queued for its sessions
+// since, and stops the flush.
+func (q *WriteBehind) Flush(ctx context.Context) error {
+	for {
+		batch := q.take()
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := q.store.Apply(ctx, batch); err != nil {
+			q.failed.Add(int64(len(batch)))
+			q.requeue(batch)
+			return err
+		}
+		q.flushed.Add(int64(len(batch)))
+	}
+}
+
+func (q *WriteBehind) take() []Write {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	n := min(len(q.order), q.batch)
+	batch := make([]Write, 0, n)
+	for _, id := range q.order[:n] {
+		batch = append(batch, *q.pending[id])
+		delete(q.pending, id)
+	}
+	q.order = q.order[n:]
+	return batch
+}
+
+func (q *WriteBehind) requeue(batch []Write) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var order []string
+	for _, w := range batch {
+		if newer, ok := q.pending[w.SessionID]; ok {
+			w.merge(*newer)
+		} else {
+			order = append(order, w.SessionID)
+		}
+		q.pending[w.SessionID] = &w
+	}
+	q.order = append(order, q.order...)
+}
+
+// Stats reports how many sessions wait to be written, and how many writes
+// have been made or have failed.
+func (q *WriteBehind) Stats() QueueStats {
+	q.mu.Lock()
+	pending := len(q.order)
+	q.mu.Unlock()
+	return QueueStats{Pending: pending, Flushed: q.flushed.Load(), Failed: q.failed.Load()}
+}
@@ -0,0 +1,210 @@
+// Package telemetry reports how much a server is played, for operators who
+// turn it on. It only counts games started and finished, by mode and
+// language, summed in process and written to a file or posted to an
+// endpoint once per interval. Sessions, players, addresses and words are
+// never recorded.
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	util "github.com/CodeAndHammer/vortludo/internal/util"
+)
+
+const (
+	DefaultInterval = 24 * time.Hour
+
+	// maxKeys bounds the modes and languages counted apart; any others are
+	// counted under otherKey.
+	maxKeys      = 16
+	otherKey     = "other"
+	postTimeout  = 10 * time.Second
+	reportFormat = 1
+)
+
+type Config struct {
+	Interval time.Duration
+	// File is replaced with each report.
+	File string
+	// Endpoint is sent each report as a JSON POST.
+	Endpoint string
+	Client   *http.Client
+}
+
+// Report is what is sent for one interval.
+type Report struct {
+	Format       int            `json:"format"`
+	From         time.Time      `json:"from"`
+	To           time.Time      `json:"to"`
+	GamesStarted int            `json:"gamesStarted"`
+	GamesWon     int            `json:"gamesWon"`
+	GamesLost    int            `json:"gamesLost"`
+	Modes        map[string]int `json:"modes"`
+	Languages    map[string]int `json:"languages"`
+}
+
+func (r *Report) empty() bool {
+	return r.GamesStarted == 0 && r.GamesWon == 0 && r.GamesLost == 0
+}
+
+// add merges the counts of other into r.
+func (r *Report) add(other Report) {
+	r.GamesStarted += other.GamesStarted
+	r.GamesWon += other.GamesWon
+	r.GamesLost += other.GamesLost
+	for mode, n := range other.Modes {
+		count(r.Modes, mode, n)
+	}
+	for language, n := range other.Languages {
+		count(r.Languages, language, n)
+	}
+}
+
+func count(counts map[string]int, key string, n int) {
+	if _, ok := counts[key]; !ok && len(counts) >= maxKeys {
+		key = otherKey
+	}
+	counts[key] += n
+}
+
+func newReport(from time.Time) Report {
+	return Report{
+		Format:    reportFormat,
+		From:      from,
+		Modes:     make(map[string]int),
+		Languages: make(map[string]int),
+	}
+}
+
+// Reporter counts games for the current interval. A nil Reporter, which is
+// what a server without telemetry has, ignores everything.
+type Reporter struct {
+	cfg Config
+
+	mu      sync.Mutex
+	current Report
+}
+
+func New(cfg Config) (*Reporter, error) {
+	if cfg.File == "" && cfg.Endpoint == "" {
+		return nil, errors.New("telemetry needs a file or an endpoint to report to")
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultInterval
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: postTimeout}
+	}
+	return &Reporter{cfg: cfg, current: newReport(time.Now().UTC())}, nil
+}
+
+// GameStarted counts a game of the mode, played in the language.
+func (r *Reporter) GameStarted(mode, language string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.current.GamesStarted++
+	count(r.current.Modes, mode, 1)
+	count(r.current.Languages, language, 1)
+}
+
+func (r *Reporter) GameFinished(won bool) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if won {
+		r.current.GamesWon++
+	} else {
+		r.current.GamesLost++
+	}
+}
+
+// Flush reports the current interval and starts the next. An interval in
+// which nothing was played is not reported. When reporting fails, the counts
+// are kept for the next attempt.
+func (r *Reporter) Flush(ctx context.Context) error {
+	if r == nil {
+		return nil
+	}
+	now := time.Now().UTC()
+	r.mu.Lock()
+	report := r.current
+	r.current = newReport(now)
+	r.mu.Unlock()
+	report.To = now
+	if report.empty() {
+		return nil
+	}
+
+	if err := r.send(ctx, report); err != nil {
+		r.mu.Lock()
+		report.add(r.current)
+		report.To = time.Time{}
+		r.current = report
+		r.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+func (r *Reporter) send(ctx context.Context, report Report) error {
+	data, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	if r.cfg.File != "" {
+		if err := util.WriteFileAtomic(r.cfg.File, data); err != nil {
+			return fmt.Errorf("write telemetry report: %w", err)
+		}
+	}
+	if r.cfg.Endpoint == "" {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, postTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.cfg.Endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := r.cfg.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("post telemetry report: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("post telemetry report: %s", resp.Status)
+	}
+	return nil
+}
+
+// Run reports once per interval until ctx is done. The last, partial
+// interval is left for the caller to Flush on shutdown.
+func (r *Reporter) Run(ctx context.Context) {
+	if r == nil {
+		return
+	}
+	ticker := time.NewTicker(r.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.Flush(ctx); err != nil {
+				util.LogWarn("Failed to send telemetry: %v", err)
+			}
+		}
+	}
+}
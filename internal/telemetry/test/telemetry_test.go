@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	telemetry "github.com/CodeAndHammer/vortludo/internal/telemetry"
+)
+
+func readReport(t *testing.T, path string) telemetry.Report {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report telemetry.Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatal(err)
+	}
+	return report
+}
+
+func TestFlushWritesAggregates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "telemetry.json")
+	reporter, err := telemetry.New(telemetry.Config{File: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reporter.GameStarted("classic", "en")
+	reporter.GameStarted("classic", "eo")
+	reporter.GameStarted("race", "en")
+	reporter.GameFinished(true)
+	reporter.GameFinished(false)
+	if err := reporter.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	report := readReport(t, path)
+	if report.GamesStarted != 3 || report.GamesWon != 1 || report.GamesLost != 1 {
+		t.Errorf("Unexpected totals: %+v", report)
+	}
+	if report.Modes["classic"] != 2 || report.Modes["race"] != 1 || report.Languages["en"] != 2 || report.Languages["eo"] != 1 {
+		t.Errorf("Unexpected breakdown: %+v", report)
+	}
+	if report.From.IsZero() || report.To.Before(report.From) {
+		t.Errorf("Unexpected period %s to %s", report.From, report.To)
+	}
+
+	// A quiet interval is not reported, so the last report stays.
+	if err := reporter.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if again := readReport(t, path); again.GamesStarted != 3 {
+		t.Errorf("An empty interval replaced the report: %+v", again)
+	}
+}
+
+func TestFlushPostsAndRetries(t *testing.T) {
+	var received []telemetry.Report
+	fail := true
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		var report telemetry.Report
+		if err := json.NewDecoder(r.Body).Decode(&report); err != nil || r.Method != http.MethodPost {
+			t.Errorf("Bad report request: %s, %v", r.Method, err)
+		}
+		received = append(received, report)
+	}))
+	defer srv.Close()
+
+	reporter, err := telemetry.New(telemetry.Config{Endpoint: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reporter.GameStarted("dordle", "en")
+	if err := reporter.Flush(context.Background()); err == nil {
+		t.Fatal("Expected the failed post to be reported")
+	}
+	fail = false
+	reporter.GameStarted("dordle", "en")
+	if err := reporter.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if len(received) != 1 || received[0].GamesStarted != 2 || received[0].Modes["dordle"] != 2 {
+		t.Errorf("Counts of the failed interval should be sent with the next, got %+v", received)
+	}
+}
+
+func TestReporterBoundsKeysAndIgnoresNil(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "telemetry.json")
+	reporter, err := telemetry.New(telemetry.Config{File: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range 100 {
+		reporter.GameStarted("classic", fmt.Sprintf("lang%d", i))
+	}
+	if err := reporter.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if report := readReport(t, path); len(report.Languages) > 17 || report.Languages["other"] == 0 {
+		t.Errorf("Languages should be capped, got %d keys", len(report.Languages))
+	}
+
+	var disabled *telemetry.Reporter
+	disabled.GameStarted("classic", "en")
+	disabled.GameFinished(true)
+	if err := disabled.Flush(context.Background()); err != nil {
+		t.Errorf("Disabled telemetry should do nothing, got %v", err)
+	}
+	if _, err := telemetry.New(telemetry.Config{}); err == nil {
+		t.Error("Telemetry without a destination should be rejected")
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	tournament "github.com/CodeAndHammer/vortludo/internal/tournament"
+)
+
+var words = []string{"APPLE", "TABLE", "CHAIR", "HOUSE", "PLANT", "BRICK", "STONE"}
+
+func pick(string) []string { return words }
+
+func TestWeekAndDay(t *testing.T) {
+	wednesday := time.Date(2026, 10, 14, 15, 0, 0, 0, time.UTC)
+	if got := tournament.WeekID(wednesday); got != "2026-W42" {
+		t.Errorf("WeekID = %s", got)
+	}
+	if got := tournament.DayIndex(wednesday); got != 2 {
+		t.Errorf("DayIndex = %d, want 2", got)
+	}
+	if got := tournament.DayIndex(wednesday.AddDate(0, 0, 4)); got != 6 {
+		t.Errorf("Sunday should be the last day, got %d", got)
+	}
+}
+
+func TestPuzzleNumber(t *testing.T) {
+	if got := tournament.PuzzleNumber(tournament.Epoch); got != 1 {
+		t.Errorf("The epoch should be puzzle 1, got %d", got)
+	}
+	late := time.Date(2025, 1, 2, 23, 59, 0, 0, time.FixedZone("UTC-5", -5*3600))
+	if got := tournament.PuzzleNumber(late); got != 3 {
+		t.Errorf("Puzzles should change at midnight UTC, got %d", got)
+	}
+	if got := tournament.PuzzleNumber(time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)); got != 366 {
+		t.Errorf("PuzzleNumber a year on = %d, want 366", got)
+	}
+}
+
+func TestRecordAndStandings(t *testing.T) {
+	store, _ := tournament.Open("")
+	monday := time.Date(2026, 10, 12, 9, 0, 0, 0, time.UTC)
+	week := store.Current(monday, pick).Week
+
+	if !store.Record(week, 0, "alice-player", tournament.DayResult{Solved: true, Guesses: 3, Seconds: 60}) {
+		t.Fatal("First result should be recorded")
+	}
+	if store.Record(week, 0, "alice-player", tournament.DayResult{Solved: true, Guesses: 1}) {
+		t.Error("Only the first result of a day should count")
+	}
+	store.Record(week, 0, "bobby-player", tournament.DayResult{Solved: true, Guesses: 3, Seconds: 30})
+	store.Record(week, 1, "alice-player", tournament.DayResult{Solved: false, Guesses: 6, Seconds: 90})
+	if !store.HasPlayed(week, 1, "alice-player") || store.HasPlayed(week, 1, "bobby-player") {
+		t.Error("HasPlayed does not match the recorded results")
+	}
+
+	standings := store.Standings(monday.Add(30 * time.Hour))
+	if len(standings) != 2 {
+		t.Fatalf("Expected 2 players, got %d", len(standings))
+	}
+	// Tuesday: alice scored 3 + 7, bobby 3 + 7 for the missed day; bobby was faster.
+	if standings[0].Player != tournament.PlayerLabel("bobby-player") || standings[0].Score != 10 || standings[1].Score != 10 {
+		t.Errorf("Unexpected standings: %+v", standings)
+	}
+	if standings[1].DaysSolved != 1 || standings[1].DaysPlayed != 2 {
+		t.Errorf("Unexpected day counts: %+v", standings[1])
+	}
+
+	store.Record(week, 1, "bobby-player", tournament.DayResult{Solved: true, Guesses: 2, Seconds: 5, Flagged: true})
+	standings = store.Standings(monday.Add(30 * time.Hour))
+	if len(standings) != 1 || standings[0].Player != tournament.PlayerLabel("alice-player") {
+		t.Errorf("Players with a flagged result should be left out: %+v", standings)
+	}
+
+	if got, results := store.Results("alice-player"); got != week || len(results) != 2 || results[1].Guesses != 6 {
+		t.Errorf("Unexpected results of alice: %s %+v", got, results)
+	}
+	if !store.Forget("alice-player") || store.Forget("alice-player") || store.HasPlayed(week, 0, "alice-player") {
+		t.Error("A forgotten player should have no results left")
+	}
+	if len(store.Standings(monday.Add(30*time.Hour))) != 0 {
+		t.Error("A forgotten player should leave the standings")
+	}
+}
+
+func TestRolloverArchivesAndPersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tournament.json")
+	store, err := tournament.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	monday := time.Date(2026, 10, 12, 9, 0, 0, 0, time.UTC)
+	week := store.Current(monday, pick).Week
+	store.Record(week, 0, "alice-player", tournament.DayResult{Solved: true, Guesses: 2})
+
+	next := store.Current(monday.AddDate(0, 0, 7), pick)
+	if next.Week == week {
+		t.Fatal("Tournament should roll over with the week")
+	}
+	if store.Record(week, 1, "alice-player", tournament.DayResult{Solved: true, Guesses: 2}) {
+		t.Error("Results for an archived week should be dropped")
+	}
+
+	reopened, err := tournament.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive := reopened.Archive()
+	if len(archive) != 1 || archive[0].Week != week || archive[0].Players != 1 {
+		t.Fatalf("Unexpected archive: %+v", archive)
+	}
+	if archive[0].Standings[0].Score != 2+6*tournament.FailPenalty {
+		t.Errorf("Final standings should count unplayed days, got %+v", archive[0].Standings[0])
+	}
+	if reopened.Current(monday.AddDate(0, 0, 8), pick).Week != next.Week {
+		t.Error("Reopened store should keep the running tournament")
+	}
+}
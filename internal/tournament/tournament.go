@@ -0,0 +1,356 @@
+// Package tournament runs the weekly tournament: one word per day for a
+// week, with players ranked on their cumulative score. Finished weeks are
+// archived with their final standings.
+package tournament
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	util "github.com/CodeAndHammer/vortludo/internal/util"
+)
+
+const (
+	Days = 7
+
+	// FailPenalty is the score of a day that was lost or not played.
+	FailPenalty = 7
+
+	MaxArchivedWeeks     = 52
+	ArchivedStandingsTop = 10
+)
+
+// DayResult is a player's outcome for one tournament day.
+type DayResult struct {
+	Solved  bool `json:"solved"`
+	Guesses int  `json:"guesses"`
+	Seconds int  `json:"seconds"`
+	// Flagged is set when the result came from a session flagged as a
+	// bot. Players with a flagged result are left out of the standings.
+	Flagged bool `json:"flagged,omitempty"`
+}
+
+// Score is the number of points a day costs; lower is better.
+func (r DayResult) Score() int {
+	if !r.Solved {
+		return FailPenalty
+	}
+	return r.Guesses
+}
+
+type Player struct {
+	Days map[int]DayResult `json:"days"`
+}
+
+func (p *Player) flagged() bool {
+	for _, r := range p.Days {
+		if r.Flagged {
+			return true
+		}
+	}
+	return false
+}
+
+type Tournament struct {
+	Week     string             `json:"week"`
+	StartsAt time.Time          `json:"startsAt"`
+	Words    []string           `json:"words"`
+	Players  map[string]*Player `json:"players"`
+}
+
+// Standing is a player's position in a tournament.
+type Standing struct {
+	Rank       int    `json:"rank"`
+	Player     string `json:"player"`
+	DaysPlayed int    `json:"daysPlayed"`
+	DaysSolved int    `json:"daysSolved"`
+	Score      int    `json:"score"`
+	Seconds    int    `json:"seconds"`
+}
+
+type Archived struct {
+	Week      string     `json:"week"`
+	Words     []string   `json:"words"`
+	Players   int        `json:"players"`
+	Standings []Standing `json:"standings"`
+}
+
+// Store holds the running tournament and the archive, and persists both to
+// path after every change. An empty path keeps everything in memory.
+type Store struct {
+	mu      sync.Mutex
+	path    string
+	current *Tournament
+	archive []Archived
+}
+
+type storeFile struct {
+	Current *Tournament `json:"current"`
+	Archive []Archived  `json:"archive"`
+}
+
+// WeekID identifies the ISO week containing t, e.g. "2026-W42".
+func WeekID(t time.Time) string {
+	year, week := t.UTC().ISOWeek()
+	return fmt.Sprintf("%d-W%02d", year, week)
+}
+
+// Epoch is the day of daily puzzle #1. Puzzles are numbered from it, so
+// every instance agrees on the day's number.
+var Epoch = time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// PuzzleNumber returns the number of the daily puzzle of t's UTC day.
+func PuzzleNumber(t time.Time) int {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return int(day.Sub(Epoch).Hours()/24) + 1
+}
+
+// DayIndex returns the tournament day of t, 0 for Monday through 6 for Sunday.
+func DayIndex(t time.Time) int {
+	return (int(t.UTC().Weekday()) + 6) % 7
+}
+
+// WeekStart returns the UTC midnight starting the Monday of t's week.
+func WeekStart(t time.Time) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return day.AddDate(0, 0, -DayIndex(t))
+}
+
+// Open loads the store from path. A missing file yields an empty store.
+func Open(path string) (*Store, error) {
+	s := &Store{path: path}
+	if path == "" {
+		return s, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return s, nil
+		}
+		return nil, err
+	}
+	var file storeFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("decode tournament store: %w", err)
+	}
+	s.current = file.Current
+	s.archive = file.Archive
+	return s, nil
+}
+
+// Current returns the week's tournament, without its players, rolling over
+// to a new one when the week has changed. pick chooses the words of a new week.
+func (s *Store) Current(now time.Time, pick func(week string) []string) Tournament {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rolloverLocked(now, pick)
+	t := *s.current
+	t.Players = nil
+	return t
+}
+
+func (s *Store) rolloverLocked(now time.Time, pick func(week string) []string) {
+	week := WeekID(now)
+	if s.current != nil && s.current.Week == week {
+		return
+	}
+	if s.current != nil {
+		s.archiveLocked(*s.current)
+	}
+	s.current = &Tournament{
+		Week:     week,
+		StartsAt: WeekStart(now),
+		Words:    pick(week),
+		Players:  make(map[string]*Player),
+	}
+	util.LogInfo("Started tournament %s", week)
+	s.saveLocked()
+}
+
+func (s *Store) archiveLocked(t Tournament) {
+	standings := standings(&t, Days)
+	if len(standings) > ArchivedStandingsTop {
+		standings = standings[:ArchivedStandingsTop]
+	}
+	s.archive = append(s.archive, Archived{
+		Week:      t.Week,
+		Words:     t.Words,
+		Players:   len(t.Players),
+		Standings: standings,
+	})
+	if len(s.archive) > MaxArchivedWeeks {
+		s.archive = slices.Delete(s.archive, 0, len(s.archive)-MaxArchivedWeeks)
+	}
+	util.LogInfo("Archived tournament %s with %d players", t.Week, len(t.Players))
+}
+
+// SetWord replaces the word of day of the running tournament, which must be
+// week. Days already begun by now keep their word, since players may have
+// played it.
+func (s *Store) SetWord(week string, day int, word string, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.current == nil || s.current.Week != week || day < 0 || day >= len(s.current.Words) {
+		return false
+	}
+	if !now.Before(s.current.StartsAt.AddDate(0, 0, day)) || s.current.Words[day] == word {
+		return false
+	}
+	s.current.Words[day] = word
+	s.saveLocked()
+	return true
+}
+
+// HasPlayed reports whether player already has a result for day of week.
+func (s *Store) HasPlayed(week string, day int, player string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.current == nil || s.current.Week != week {
+		return false
+	}
+	p, ok := s.current.Players[player]
+	if !ok {
+		return false
+	}
+	_, played := p.Days[day]
+	return played
+}
+
+// Record stores a player's result. Only the first result of a day counts, and
+// results for a week that has already rolled over are dropped.
+func (s *Store) Record(week string, day int, player string, result DayResult) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.current == nil || s.current.Week != week || day < 0 || day >= Days {
+		return false
+	}
+	p, ok := s.current.Players[player]
+	if !ok {
+		p = &Player{Days: make(map[int]DayResult)}
+		s.current.Players[player] = p
+	}
+	if _, played := p.Days[day]; played {
+		return false
+	}
+	p.Days[day] = result
+	s.saveLocked()
+	return true
+}
+
+// Results returns the week of the running tournament and player's results
+// in it, by day.
+func (s *Store) Results(player string) (string, map[int]DayResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.current == nil {
+		return "", nil
+	}
+	p, ok := s.current.Players[player]
+	if !ok {
+		return s.current.Week, nil
+	}
+	return s.current.Week, maps.Clone(p.Days)
+}
+
+// Forget removes player from the running tournament and reports whether
+// they had played in it. Archived standings only keep a player's label.
+func (s *Store) Forget(player string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.current == nil {
+		return false
+	}
+	if _, ok := s.current.Players[player]; !ok {
+		return false
+	}
+	delete(s.current.Players, player)
+	s.saveLocked()
+	return true
+}
+
+// Standings ranks the current tournament as of now.
+func (s *Store) Standings(now time.Time) []Standing {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.current == nil {
+		return []Standing{}
+	}
+	return standings(s.current, min(int(now.Sub(s.current.StartsAt)/(24*time.Hour))+1, Days))
+}
+
+// Archive returns the archived tournaments, most recent first.
+func (s *Store) Archive() []Archived {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	archive := make([]Archived, len(s.archive))
+	copy(archive, s.archive)
+	slices.Reverse(archive)
+	return archive
+}
+
+// PlayerLabel is the public name of a player. Player IDs double as
+// credentials, so standings never show them directly.
+func PlayerLabel(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return "Player " + hex.EncodeToString(sum[:3])
+}
+
+// standings ranks the players of t after daysElapsed days. Days a player has
+// not played by then cost FailPenalty.
+func standings(t *Tournament, daysElapsed int) []Standing {
+	out := make([]Standing, 0, len(t.Players))
+	for id, p := range t.Players {
+		if p.flagged() {
+			continue
+		}
+		st := Standing{Player: PlayerLabel(id), DaysPlayed: len(p.Days)}
+		for _, r := range p.Days {
+			st.Score += r.Score()
+			st.Seconds += r.Seconds
+			if r.Solved {
+				st.DaysSolved++
+			}
+		}
+		st.Score += max(daysElapsed-st.DaysPlayed, 0) * FailPenalty
+		out = append(out, st)
+	}
+	slices.SortFunc(out, func(a, b Standing) int {
+		if c := cmp.Compare(a.Score, b.Score); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(a.Seconds, b.Seconds); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Player, b.Player)
+	})
+	for i := range out {
+		out[i].Rank = i + 1
+	}
+	return out
+}
+
+func (s *Store) saveLocked() {
+	if s.path == "" {
+		return
+	}
+	data, err := json.Marshal(storeFile{Current: s.current, Archive: s.archive})
+	if err != nil {
+		util.LogWarn("Failed to encode tournament store: %v", err)
+		return
+	}
+	if err := util.WriteFileAtomic(s.path, data); err != nil {
+		util.LogWarn("Failed to save tournament store: %v", err)
+	}
+}
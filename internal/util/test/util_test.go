@@ -1,7 +1,6 @@
 package main
 
 import (
-	"os"
 	"testing"
 	"time"
 
@@ -50,38 +49,6 @@ func TestPlural(t *testing.T) {
 	}
 }
 
-func TestGetEnvDuration(t *testing.T) {
-	os.Setenv("TEST_DURATION", "2s")
-	defer os.Unsetenv("TEST_DURATION")
-	if got := util.GetEnvDuration("TEST_DURATION", time.Second); got != 2*time.Second {
-		t.Errorf("GetEnvDuration = %v, want 2s", got)
-	}
-	os.Setenv("TEST_DURATION", "notaduration")
-	if got := util.GetEnvDuration("TEST_DURATION", 3*time.Second); got != 3*time.Second {
-		t.Errorf("GetEnvDuration fallback = %v, want 3s", got)
-	}
-	os.Unsetenv("TEST_DURATION")
-	if got := util.GetEnvDuration("TEST_DURATION", 4*time.Second); got != 4*time.Second {
-		t.Errorf("GetEnvDuration fallback unset = %v, want 4s", got)
-	}
-}
-
-func TestGetEnvInt(t *testing.T) {
-	os.Setenv("TEST_INT", "42")
-	defer os.Unsetenv("TEST_INT")
-	if got := util.GetEnvInt("TEST_INT", 7); got != 42 {
-		t.Errorf("GetEnvInt = %d, want 42", got)
-	}
-	os.Setenv("TEST_INT", "notanint")
-	if got := util.GetEnvInt("TEST_INT", 8); got != 8 {
-		t.Errorf("GetEnvInt fallback = %d, want 8", got)
-	}
-	os.Unsetenv("TEST_INT")
-	if got := util.GetEnvInt("TEST_INT", 9); got != 9 {
-		t.Errorf("GetEnvInt fallback unset = %d, want 9", got)
-	}
-}
-
 func plural(n int) string {
 	if n == 1 {
 		return ""
@@ -4,7 +4,7 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"strconv"
+	"path/filepath"
 	"time"
 )
 
@@ -20,6 +20,24 @@ func DirExists(path string) bool {
 	return info.IsDir()
 }
 
+// WriteFileAtomic writes data to a temporary sibling of path and renames it
+// into place, so readers never see a partially written file.
+func WriteFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
 func FormatUptime(d time.Duration) string {
 	seconds := int(d.Seconds()) % 60
 	minutes := int(d.Minutes()) % 60
@@ -46,36 +64,6 @@ func plural(n int) string {
 	return "s"
 }
 
-func GetEnvDuration(key string, fallback time.Duration) time.Duration {
-	val := os.Getenv(key)
-	if val == "" {
-		return fallback
-	}
-	d, err := time.ParseDuration(val)
-	if err != nil {
-		LogWarn("Invalid duration for %s: %v, using default %v", key, err, fallback)
-		return fallback
-	}
-	return d
-}
-
-func GetEnvInt(key string, fallback int) int {
-	val := os.Getenv(key)
-	if val == "" {
-		return fallback
-	}
-	i, err := parseInt(val)
-	if err != nil {
-		LogWarn("Invalid int for %s: %v, using default %d", key, err, fallback)
-		return fallback
-	}
-	return i
-}
-
-func parseInt(val string) (int, error) {
-	return strconv.Atoi(val)
-}
-
 func LogInfo(format string, v ...any) {
 	log.Printf("[INFO] "+format, v...)
 }
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	wordset "github.com/CodeAndHammer/vortludo/internal/wordset"
+)
+
+func TestSet(t *testing.T) {
+	words := []string{"CRANE", "CRATE", "SLATE", "ŜAĈOJ", "ĈAMBO", "CRANE", "PLATE", "CRAN"}
+	set := wordset.New(words)
+
+	want := slices.Compact(slices.Sorted(slices.Values(words)))
+	if got := slices.Collect(set.All()); !slices.Equal(got, want) {
+		t.Fatalf("All() = %v, want %v", got, want)
+	}
+	if set.Len() != len(want) {
+		t.Errorf("Len() = %d, want %d", set.Len(), len(want))
+	}
+	for _, word := range want {
+		if !set.Contains(word) {
+			t.Errorf("Expected %q in the set", word)
+		}
+	}
+	for _, word := range []string{"", "CRA", "CRANES", "TRANE", "ŜAĈO", "\xc5"} {
+		if set.Contains(word) {
+			t.Errorf("Expected %q not in the set", word)
+		}
+	}
+
+	for prefix, wantPrefix := range map[string]bool{"": true, "CR": true, "CRAN": true, "Ŝ": true, "\xc5": true, "CRANES": false, "Q": false} {
+		if got := set.HasPrefix(prefix); got != wantPrefix {
+			t.Errorf("HasPrefix(%q) = %v, want %v", prefix, got, wantPrefix)
+		}
+	}
+	if got := slices.Collect(set.WithPrefix("CRA")); !slices.Equal(got, []string{"CRAN", "CRANE", "CRATE"}) {
+		t.Errorf("WithPrefix(CRA) = %v", got)
+	}
+}
+
+func TestEmptySet(t *testing.T) {
+	for _, set := range []*wordset.Set{nil, wordset.New(nil)} {
+		if set.Len() != 0 || set.Contains("") || set.HasPrefix("") || len(slices.Collect(set.All())) != 0 {
+			t.Errorf("Expected %v to be empty", set)
+		}
+	}
+}
+
+// A set built from many words sharing tails must still hold exactly them.
+func TestSetMergesTails(t *testing.T) {
+	var words []string
+	for a := 'A'; a <= 'Z'; a++ {
+		for b := 'A'; b <= 'Z'; b += 3 {
+			words = append(words, fmt.Sprintf("%c%cATE", a, b), fmt.Sprintf("%c%cING", a, b))
+		}
+	}
+	set := wordset.New(words)
+	got := slices.Collect(set.All())
+	if !slices.Equal(got, slices.Sorted(slices.Values(words))) {
+		t.Fatalf("Expected the words back in order, got %d of %d", len(got), len(words))
+	}
+	if set.Contains("AAAT") || set.Contains("ABATE") || !set.Contains("ZYING") {
+		t.Error("Merged tails should not add or lose words")
+	}
+}
+
+func BenchmarkContains(b *testing.B) {
+	var words []string
+	for a := 'A'; a <= 'Z'; a++ {
+		for c := 'A'; c <= 'Z'; c++ {
+			words = append(words, fmt.Sprintf("%cR%cNE", a, c))
+		}
+	}
+	set := wordset.New(words)
+	b.ReportAllocs()
+	for b.Loop() {
+		set.Contains("SRONE")
+	}
+}
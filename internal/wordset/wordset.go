@@ -0,0 +1,214 @@
+// Package wordset holds a large set of words compactly: as a minimal
+// acyclic automaton, a trie whose identical tails are stored once. Words
+// sharing a prefix share its path and words sharing a suffix share its
+// nodes, so the accepted guesses take a fraction of the memory of a map of
+// strings, and a prefix can be looked up as cheaply as a whole word.
+package wordset
+
+import (
+	"bytes"
+	"iter"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Set is an immutable set of words. Words are stored byte by byte, so any
+// string can be a member and they come back in sorted order. A nil Set is
+// empty.
+type Set struct {
+	// Node i's edges are labels[first[i]:first[i+1]], in ascending order,
+	// leading to the nodes targets[first[i]:first[i+1]]. Node 0 is the
+	// root; final marks the nodes that end a word.
+	first   []uint32
+	final   []bool
+	labels  []byte
+	targets []uint32
+	size    int
+}
+
+// New returns the set of words. Duplicates count once.
+func New(words []string) *Set {
+	words = slices.Clone(words)
+	slices.Sort(words)
+	words = slices.Compact(words)
+
+	b := newBuilder()
+	for _, word := range words {
+		b.insert(word)
+	}
+	return b.finish(len(words))
+}
+
+// Len returns the number of words in the set.
+func (s *Set) Len() int {
+	if s == nil {
+		return 0
+	}
+	return s.size
+}
+
+// Contains reports whether word is in the set.
+func (s *Set) Contains(word string) bool {
+	n, ok := s.walk(word)
+	return ok && s.final[n]
+}
+
+// HasPrefix reports whether any word in the set starts with prefix. Every
+// node leads on to a word, so reaching prefix's node is enough.
+func (s *Set) HasPrefix(prefix string) bool {
+	_, ok := s.walk(prefix)
+	return ok && s.Len() > 0
+}
+
+// All yields the words of the set in ascending order.
+func (s *Set) All() iter.Seq[string] {
+	return s.WithPrefix("")
+}
+
+// WithPrefix yields the words of the set that start with prefix, in
+// ascending order.
+func (s *Set) WithPrefix(prefix string) iter.Seq[string] {
+	return func(yield func(string) bool) {
+		n, ok := s.walk(prefix)
+		if !ok || s.Len() == 0 {
+			return
+		}
+		s.yieldFrom(n, []byte(prefix), yield)
+	}
+}
+
+func (s *Set) yieldFrom(n uint32, word []byte, yield func(string) bool) bool {
+	if s.final[n] && !yield(string(word)) {
+		return false
+	}
+	for e := s.first[n]; e < s.first[n+1]; e++ {
+		if !s.yieldFrom(s.targets[e], append(word, s.labels[e]), yield) {
+			return false
+		}
+	}
+	return true
+}
+
+// walk follows word from the root and returns the node it ends on.
+func (s *Set) walk(word string) (uint32, bool) {
+	if s == nil || len(s.first) == 0 {
+		return 0, false
+	}
+	n := uint32(0)
+	for i := range len(word) {
+		lo, hi := s.first[n], s.first[n+1]
+		e := bytes.IndexByte(s.labels[lo:hi], word[i])
+		if e < 0 {
+			return 0, false
+		}
+		n = s.targets[lo+uint32(e)]
+	}
+	return n, true
+}
+
+// builder builds the automaton from words in ascending order, merging each
+// finished tail with an identical one already built (Daciuk et al.,
+// "Incremental Construction of Minimal Acyclic Finite-State Automata").
+type builder struct {
+	root *node
+	prev string
+	// unchecked holds the path of the previous word below the point it
+	// shares with every earlier word; its nodes may still change.
+	unchecked []step
+	// minimized holds the finished nodes by their signature.
+	minimized map[string]*node
+	nodes     []*node
+}
+
+type node struct {
+	id     int
+	final  bool
+	labels []byte
+	next   []*node
+}
+
+type step struct {
+	parent *node
+	child  *node
+}
+
+func newBuilder() *builder {
+	root := &node{}
+	return &builder{root: root, minimized: make(map[string]*node), nodes: []*node{root}}
+}
+
+func (b *builder) insert(word string) {
+	common := 0
+	for common < len(word) && common < len(b.prev) && word[common] == b.prev[common] {
+		common++
+	}
+	b.minimize(common)
+
+	n := b.root
+	if len(b.unchecked) > 0 {
+		n = b.unchecked[len(b.unchecked)-1].child
+	}
+	for i := common; i < len(word); i++ {
+		child := &node{}
+		n.labels = append(n.labels, word[i])
+		n.next = append(n.next, child)
+		b.unchecked = append(b.unchecked, step{parent: n, child: child})
+		n = child
+	}
+	n.final = true
+	b.prev = word
+}
+
+// minimize merges the unchecked nodes below depth down into the finished
+// ones, deepest first, so a node's children are always merged before it.
+func (b *builder) minimize(depth int) {
+	for len(b.unchecked) > depth {
+		last := b.unchecked[len(b.unchecked)-1]
+		key := last.child.signature()
+		if same, ok := b.minimized[key]; ok {
+			last.parent.next[len(last.parent.next)-1] = same
+		} else {
+			last.child.id = len(b.nodes)
+			b.nodes = append(b.nodes, last.child)
+			b.minimized[key] = last.child
+		}
+		b.unchecked = b.unchecked[:len(b.unchecked)-1]
+	}
+}
+
+// signature identifies a node by whether it ends a word and where its
+// edges lead: two nodes with the same signature have the same tails.
+func (n *node) signature() string {
+	var sig strings.Builder
+	if n.final {
+		sig.WriteByte('1')
+	} else {
+		sig.WriteByte('0')
+	}
+	for i, label := range n.labels {
+		sig.WriteByte(label)
+		sig.WriteString(strconv.Itoa(n.next[i].id))
+		sig.WriteByte(',')
+	}
+	return sig.String()
+}
+
+func (b *builder) finish(size int) *Set {
+	b.minimize(0)
+	s := &Set{
+		first: make([]uint32, 0, len(b.nodes)+1),
+		final: make([]bool, 0, len(b.nodes)),
+		size:  size,
+	}
+	for _, n := range b.nodes {
+		s.first = append(s.first, uint32(len(s.labels)))
+		s.final = append(s.final, n.final)
+		s.labels = append(s.labels, n.labels...)
+		for _, next := range n.next {
+			s.targets = append(s.targets, uint32(next.id))
+		}
+	}
+	s.first = append(s.first, uint32(len(s.labels)))
+	return s
+}
@@ -0,0 +1,196 @@
+package wordsource
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// emptyPayloadHash is the SHA-256 of an empty request body.
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// S3Config locates and authenticates against S3-compatible object storage.
+// Requests are signed with AWS Signature Version 4 when AccessKey is set and
+// sent anonymously otherwise.
+type S3Config struct {
+	// Endpoint is the storage URL, e.g. "https://minio.internal:9000". It
+	// defaults to AWS S3 in Region.
+	Endpoint     string
+	Region       string
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+}
+
+// S3Source fetches a word list from an S3 bucket using path-style URLs, which
+// every S3-compatible store supports.
+type S3Source struct {
+	Bucket string
+	Key    string
+	Config S3Config
+	Client *http.Client
+
+	mu   sync.Mutex
+	etag string
+}
+
+func (s *S3Source) Fetch(ctx context.Context) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.objectURL(), nil)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	etag := s.etag
+	s.mu.Unlock()
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	if s.Config.AccessKey != "" {
+		signV4(req, s.Config, s.region(), time.Now(), emptyPayloadHash)
+	}
+	data, etag, err := fetch(s.Client, req)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.etag = etag
+	s.mu.Unlock()
+	return data, nil
+}
+
+// Put uploads data to the object, replacing it, e.g. to publish a report
+// next to the word lists.
+func (s *S3Source) Put(ctx context.Context, data []byte, contentType string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.objectURL(), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if s.Config.AccessKey != "" {
+		sum := sha256.Sum256(data)
+		signV4(req, s.Config, s.region(), time.Now(), hex.EncodeToString(sum[:]))
+	}
+	client := s.Client
+	if client == nil {
+		client = &http.Client{Timeout: fetchTimeout}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("PUT %s: %s", req.URL.Redacted(), resp.Status)
+	}
+	return nil
+}
+
+func (s *S3Source) String() string {
+	return "s3://" + s.Bucket + "/" + s.Key
+}
+
+func (s *S3Source) region() string {
+	if s.Config.Region == "" {
+		return "us-east-1"
+	}
+	return s.Config.Region
+}
+
+func (s *S3Source) objectURL() string {
+	endpoint := strings.TrimSuffix(s.Config.Endpoint, "/")
+	if endpoint == "" {
+		endpoint = "https://s3." + s.region() + ".amazonaws.com"
+	}
+	return endpoint + "/" + s.Bucket + "/" + (&url.URL{Path: s.Key}).EscapedPath()
+}
+
+// signV4 adds an AWS Signature Version 4 Authorization header to req, whose
+// body has the hex SHA-256 payloadHash. The host, Range and x-amz-* headers
+// are signed.
+func signV4(req *http.Request, cfg S3Config, region string, now time.Time, payloadHash string) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if cfg.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", cfg.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		lower := strings.ToLower(name)
+		if strings.HasPrefix(lower, "x-amz-") || lower == "range" {
+			headers[lower] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := date + "/" + region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+cfg.SecretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+cfg.AccessKey+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	var parts []string
+	for _, key := range keys {
+		values := slices.Clone(query[key])
+		slices.Sort(values)
+		for _, value := range values {
+			parts = append(parts, awsEscape(key)+"="+awsEscape(value))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// awsEscape percent-encodes everything but the unreserved characters, as
+// Signature Version 4 requires.
+func awsEscape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
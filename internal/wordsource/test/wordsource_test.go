@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	wordsource "github.com/CodeAndHammer/vortludo/internal/wordsource"
+)
+
+func TestOpen(t *testing.T) {
+	cases := map[string]string{
+		"data/words.json":             "*wordsource.FileSource",
+		"file:///srv/words.json":      "*wordsource.FileSource",
+		"https://words.example/w.txt": "*wordsource.HTTPSource",
+		"s3://lists/prod/words.json":  "*wordsource.S3Source",
+	}
+	for location, want := range cases {
+		src, err := wordsource.Open(location, wordsource.S3Config{})
+		if err != nil {
+			t.Errorf("Open(%q): %v", location, err)
+			continue
+		}
+		if got := typeName(src); got != want {
+			t.Errorf("Open(%q) = %s, want %s", location, got, want)
+		}
+	}
+	for _, location := range []string{"ftp://words.example/w.txt", "s3://bucket-only"} {
+		if _, err := wordsource.Open(location, wordsource.S3Config{}); err == nil {
+			t.Errorf("Expected Open(%q) to fail", location)
+		}
+	}
+}
+
+func typeName(src wordsource.WordSource) string {
+	switch src.(type) {
+	case *wordsource.FileSource:
+		return "*wordsource.FileSource"
+	case *wordsource.HTTPSource:
+		return "*wordsource.HTTPSource"
+	case *wordsource.S3Source:
+		return "*wordsource.S3Source"
+	}
+	return "unknown"
+}
+
+func TestHTTPSourceNotModified(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte("CRANE\n"))
+	}))
+	defer srv.Close()
+
+	src := &wordsource.HTTPSource{URL: srv.URL}
+	data, err := src.Fetch(context.Background())
+	if err != nil || string(data) != "CRANE\n" {
+		t.Fatalf("First fetch = %q, %v", data, err)
+	}
+	if _, err := src.Fetch(context.Background()); !errors.Is(err, wordsource.ErrNotModified) {
+		t.Errorf("Expected ErrNotModified on refetch, got %v", err)
+	}
+}
+
+func TestS3SourceSignsRequests(t *testing.T) {
+	var path, auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, auth = r.URL.Path, r.Header.Get("Authorization")
+		w.Write([]byte("CRANE\n"))
+	}))
+	defer srv.Close()
+
+	src := &wordsource.S3Source{Bucket: "lists", Key: "prod/words.txt", Config: wordsource.S3Config{
+		Endpoint:  srv.URL,
+		Region:    "eu-west-1",
+		AccessKey: "AKIDEXAMPLE",
+		SecretKey: "secret",
+	}}
+	if _, err := src.Fetch(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if path != "/lists/prod/words.txt" {
+		t.Errorf("Expected a path-style request, got %q", path)
+	}
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/") ||
+		!strings.Contains(auth, "/eu-west-1/s3/aws4_request") ||
+		!strings.Contains(auth, "SignedHeaders=host;x-amz-content-sha256;x-amz-date") {
+		t.Errorf("Unexpected Authorization header %q", auth)
+	}
+}
+
+func TestLoaderFallsBackToLastGoodCopy(t *testing.T) {
+	body := "CRANE\n"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	sum := sha256.Sum256([]byte("CRANE\n"))
+	loader := &wordsource.Loader{
+		Source:    &wordsource.HTTPSource{URL: srv.URL},
+		Checksum:  hex.EncodeToString(sum[:]),
+		CachePath: filepath.Join(t.TempDir(), "words.txt"),
+	}
+	if _, err := loader.Load(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if cached, err := os.ReadFile(loader.CachePath); err != nil || string(cached) != body {
+		t.Fatalf("Expected the list to be cached, got %q, %v", cached, err)
+	}
+
+	body = "SLATE\n"
+	if _, err := loader.Fetch(context.Background(), nil); !errors.Is(err, wordsource.ErrChecksum) {
+		t.Errorf("Expected a checksum mismatch, got %v", err)
+	}
+	data, err := loader.Load(context.Background(), nil)
+	if err != nil || string(data) != "CRANE\n" {
+		t.Errorf("Expected the cached copy, got %q, %v", data, err)
+	}
+
+	loader.Checksum = ""
+	rejected := errors.New("rejected")
+	if _, err := loader.Fetch(context.Background(), func([]byte) error { return rejected }); !errors.Is(err, rejected) {
+		t.Errorf("Expected the parse error, got %v", err)
+	}
+	if cached, _ := os.ReadFile(loader.CachePath); string(cached) != "CRANE\n" {
+		t.Errorf("A rejected list replaced the cached copy: %q", cached)
+	}
+}
+
+func TestS3SourcePut(t *testing.T) {
+	var gotPath, gotHash, gotBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotPath, gotHash, gotBody = r.URL.Path, r.Header.Get("X-Amz-Content-Sha256"), string(body)
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 ") {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer srv.Close()
+
+	src := &wordsource.S3Source{
+		Bucket: "stats",
+		Key:    "daily/report.csv",
+		Config: wordsource.S3Config{Endpoint: srv.URL, AccessKey: "key", SecretKey: "secret"},
+	}
+	if err := src.Put(context.Background(), []byte("a,b\n"), "text/csv"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	sum := sha256.Sum256([]byte("a,b\n"))
+	if gotPath != "/stats/daily/report.csv" || gotBody != "a,b\n" || gotHash != hex.EncodeToString(sum[:]) {
+		t.Errorf("Unexpected upload to %s with hash %s: %q", gotPath, gotHash, gotBody)
+	}
+}
@@ -0,0 +1,234 @@
+// Package wordsource fetches word lists from local files, HTTP(S) URLs or
+// S3-compatible object storage, so several instances can share one centrally
+// curated dictionary.
+package wordsource
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	util "github.com/CodeAndHammer/vortludo/internal/util"
+)
+
+const (
+	// MaxSize caps the size of a fetched word list.
+	MaxSize = 32 << 20
+
+	fetchTimeout = 30 * time.Second
+)
+
+var (
+	// ErrNotModified is returned by Fetch when the source has not changed
+	// since the previous fetch.
+	ErrNotModified = errors.New("word list not modified")
+
+	ErrChecksum = errors.New("word list checksum mismatch")
+)
+
+// WordSource is where a word list is read from.
+type WordSource interface {
+	Fetch(ctx context.Context) ([]byte, error)
+	String() string
+}
+
+// Open returns the source for location: a file path or file:// URL, an
+// http:// or https:// URL, or s3://bucket/key.
+func Open(location string, s3 S3Config) (WordSource, error) {
+	u, err := url.Parse(location)
+	if err != nil || u.Scheme == "" || len(u.Scheme) == 1 {
+		// Not a URL, or a Windows drive letter.
+		return &FileSource{Path: location}, nil
+	}
+	switch u.Scheme {
+	case "file":
+		return &FileSource{Path: u.Path}, nil
+	case "http", "https":
+		return &HTTPSource{URL: location}, nil
+	case "s3":
+		key := strings.TrimPrefix(u.Path, "/")
+		if u.Host == "" || key == "" {
+			return nil, fmt.Errorf("word source %q: want s3://bucket/key", location)
+		}
+		return &S3Source{Bucket: u.Host, Key: key, Config: s3}, nil
+	}
+	return nil, fmt.Errorf("word source %q: unsupported scheme %q", location, u.Scheme)
+}
+
+// Remote reports whether src is fetched over the network.
+func Remote(src WordSource) bool {
+	_, local := src.(*FileSource)
+	return !local
+}
+
+type FileSource struct {
+	Path string
+}
+
+func (s *FileSource) Fetch(ctx context.Context) ([]byte, error) {
+	return os.ReadFile(s.Path)
+}
+
+func (s *FileSource) String() string {
+	return s.Path
+}
+
+// HTTPSource fetches a word list with a GET request. It remembers the
+// response's ETag, so refreshing an unchanged list costs a 304.
+type HTTPSource struct {
+	URL    string
+	Client *http.Client
+
+	mu   sync.Mutex
+	etag string
+}
+
+func (s *HTTPSource) Fetch(ctx context.Context) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	etag := s.etag
+	s.mu.Unlock()
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	data, etag, err := fetch(s.Client, req)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.etag = etag
+	s.mu.Unlock()
+	return data, nil
+}
+
+func (s *HTTPSource) String() string {
+	return s.URL
+}
+
+// fetch sends req and returns the body of a 200 response and its ETag.
+func fetch(client *http.Client, req *http.Request) ([]byte, string, error) {
+	if client == nil {
+		client = &http.Client{Timeout: fetchTimeout}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		return nil, "", ErrNotModified
+	default:
+		return nil, "", fmt.Errorf("GET %s: %s", req.URL.Redacted(), resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, MaxSize+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(data) > MaxSize {
+		return nil, "", fmt.Errorf("GET %s: word list larger than %d bytes", req.URL.Redacted(), MaxSize)
+	}
+	return data, resp.Header.Get("ETag"), nil
+}
+
+// Loader fetches a word list, verifies it and keeps the last good copy on
+// disk to fall back to when the source is unavailable.
+type Loader struct {
+	Source WordSource
+	// Checksum is the expected hex SHA-256 of the word list, if pinned.
+	Checksum string
+	// CachePath is where the last good copy is kept; empty disables it.
+	CachePath string
+
+	last [sha256.Size]byte
+}
+
+// Fetch reads the word list from its source. The list is only kept when its
+// checksum matches and accept, which parses it, returns nil. Fetching the
+// list last kept again returns ErrNotModified.
+func (l *Loader) Fetch(ctx context.Context, accept func([]byte) error) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, fetchTimeout)
+	defer cancel()
+	data, err := l.Source.Fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	if sum == l.last {
+		return nil, ErrNotModified
+	}
+	if err := l.verify(data, accept); err != nil {
+		return nil, fmt.Errorf("%s: %w", l.Source, err)
+	}
+	l.last = sum
+	if l.CachePath != "" {
+		if err := writeCache(l.CachePath, data); err != nil {
+			util.LogWarn("Failed to cache word list from %s at %s: %v", l.Source, l.CachePath, err)
+		}
+	}
+	return data, nil
+}
+
+// Cached returns the last good copy of the word list.
+func (l *Loader) Cached(accept func([]byte) error) ([]byte, error) {
+	if l.CachePath == "" {
+		return nil, errors.New("no cached copy configured")
+	}
+	data, err := os.ReadFile(l.CachePath)
+	if err != nil {
+		return nil, err
+	}
+	if err := l.verify(data, accept); err != nil {
+		return nil, fmt.Errorf("%s: %w", l.CachePath, err)
+	}
+	l.last = sha256.Sum256(data)
+	return data, nil
+}
+
+// Load fetches the word list, falling back to the cached copy.
+func (l *Loader) Load(ctx context.Context, accept func([]byte) error) ([]byte, error) {
+	data, err := l.Fetch(ctx, accept)
+	if err == nil || l.CachePath == "" {
+		return data, err
+	}
+	cached, cacheErr := l.Cached(accept)
+	if cacheErr != nil {
+		return nil, fmt.Errorf("%w (no usable cached copy: %v)", err, cacheErr)
+	}
+	util.LogWarn("Failed to fetch word list from %s, using the copy cached at %s: %v", l.Source, l.CachePath, err)
+	return cached, nil
+}
+
+func writeCache(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return util.WriteFileAtomic(path, data)
+}
+
+func (l *Loader) verify(data []byte, accept func([]byte) error) error {
+	if l.Checksum != "" {
+		sum := sha256.Sum256(data)
+		if !strings.EqualFold(hex.EncodeToString(sum[:]), l.Checksum) {
+			return ErrChecksum
+		}
+	}
+	if accept != nil {
+		return accept(data)
+	}
+	return nil
+}
@@ -1,19 +1,30 @@
 const WORD_LENGTH = 5;
 const MAX_GUESSES = 6;
 const ANIMATION_DELAY = 100;
+const ACHIEVEMENT_TOAST_DELAY = 1500;
 const COMPLETED_WORDS_KEY = 'vortludo-completed-words';
+// Only the latest completed words are sent, keeping requests within the
+// server's size limit.
+const COMPLETED_WORDS_SENT = 200;
 
 const SELECTORS = {
     GAME_BOARD: '#game-board',
     GUESS_ROW: '.guess-row',
+    NEW_ROW: '.guess-row[data-new-row]',
+    ASSIST_ROW: '.guess-row[data-assist]',
     TILE: '.tile',
     FILLED_TILE: '.tile.filled',
     GAME_CONTENT_CONTAINER: '#game-content-container',
     CSRF_META: 'meta[name="csrf-token"]',
+    BASE_PATH_META: 'meta[name="base-path"]',
     GUESS_INPUT: '#guess-input',
+    GUESS_KEY_INPUT: '#guess-idempotency-key',
+    GUESS_ROW_INPUT: '#guess-row',
+    GUESS_VERSION_INPUT: '#guess-version',
     GUESS_FORM: '#guess-form',
     SR_LIVE: '#sr-live',
     NOTIFICATION_TOAST: '#notification-toast',
+    RATE_LIMIT_NOTICE: '#rate-limit-notice',
     COPY_MODAL: '.modal',
     COPY_MODAL_TEXTAREA: '.copy-modal textarea',
 };
@@ -29,10 +40,12 @@ const CSS_CLASSES = {
     ANIMATED: 'animated',
     WINNER: 'winner',
     PRESSED: 'pressed',
+    VIOLATION: 'violation',
+    ASSIST: 'tile-assist',
 };
 
 const REGEX = {
-    LETTER: /^[a-zA-Z]$/,
+    LETTER: /^\p{L}$/u,
 };
 
 const CONFETTI_COLORS = [
@@ -86,14 +99,34 @@ const debounce = (func, wait) => {
 };
 
 /**
- * Reads a cookie value by name.
- * @param {string} name - The name of the cookie.
- * @returns {string} The cookie value or empty string if not found.
+ * Reads the page's current CSRF token.
+ * @returns {string} The token or empty string if the page has none.
  */
-const readCookie = (name) => {
-    const v = `; ${document.cookie}`;
-    const parts = v.split(`; ${name}=`);
-    return parts.length === 2 ? parts.pop().split(';').shift() : '';
+const readCsrfToken = () => {
+    const meta = document.querySelector(SELECTORS.CSRF_META);
+    return meta ? meta.getAttribute('content') : '';
+};
+
+/**
+ * Prefixes a route with the path the game is served under.
+ * @param {string} route - The route, such as '/game-state'.
+ * @returns {string} The route's path on this site.
+ */
+const routePath = (route) => {
+    const meta = document.querySelector(SELECTORS.BASE_PATH_META);
+    return (meta ? meta.getAttribute('content') : '') + route;
+};
+
+/**
+ * Keeps the token a response issued for the page's next request. Tokens
+ * change with every response and are replaced when a game ends.
+ * @param {string|null} token - The X-CSRF-Token response header.
+ */
+const storeCsrfToken = (token) => {
+    const meta = document.querySelector(SELECTORS.CSRF_META);
+    if (meta && token) {
+        meta.setAttribute('content', token);
+    }
 };
 
 /**
@@ -111,8 +144,10 @@ window.gameApp = function () {
         showCopyModal: false,
         copyModalText: '',
         submittingGuess: false,
+        guessKey: null,
         lastServerError: '',
         keepInputAfterError: false,
+        validateEnabled: true,
         _gameRows: null,
         _guessRows: null,
         _toast: null,
@@ -122,6 +157,10 @@ window.gameApp = function () {
                 text: 'Game is already over! Start a new game! 🎮',
                 type: 'warning',
             },
+            game_in_progress: {
+                text: 'Finish the game to share it as an image! 🖼️',
+                type: 'info',
+            },
             invalid_length: {
                 text: `Word must be ${WORD_LENGTH} letters long! ✏️`,
                 type: 'warning',
@@ -142,6 +181,82 @@ window.gameApp = function () {
                 text: 'You already guessed that word! 🔂',
                 type: 'warning',
             },
+            word_blocked: {
+                text: "That word isn't allowed. Try another! 🚫",
+                type: 'warning',
+            },
+            assist_letter_moved: {
+                text: 'Assisted: keep the revealed first letter! 🅰️',
+                type: 'warning',
+            },
+            hard_mode_violation: {
+                text: 'Hard mode: use every revealed hint! 💪',
+                type: 'warning',
+            },
+            hint_unavailable: {
+                text: 'That hint is not available right now. 🔒',
+                type: 'info',
+            },
+            hints_exhausted: {
+                text: 'No hints of that kind left for this game! 💡',
+                type: 'warning',
+            },
+            session_expired: {
+                text: 'Your previous game expired. Here is a new word! ⌛',
+                type: 'info',
+            },
+            state_out_of_sync: {
+                text: 'Your board was out of date and has been refreshed. 🔄',
+                type: 'info',
+            },
+            invalid_key: {
+                text: 'That key cannot be typed. ⌨️',
+                type: 'warning',
+            },
+            invalid_completed_words: {
+                text: 'Your list of completed words could not be read, so it was skipped. 📋',
+                type: 'warning',
+            },
+            tournament_played: {
+                text: "You've already played today's tournament word! 🏆",
+                type: 'info',
+            },
+            unknown_pool: {
+                text: 'That word pool does not exist. Here is a classic word! 📚',
+                type: 'info',
+            },
+            unknown_pack: {
+                text: 'That puzzle pack does not exist. Here is a classic word! 🧩',
+                type: 'info',
+            },
+            rate_limited: {
+                text: 'Too many requests. Please slow down! 🐢',
+                type: 'warning',
+            },
+            too_fast: {
+                text: 'Not so fast! Wait a moment before your next guess. ⏳',
+                type: 'warning',
+            },
+            temporarily_banned: {
+                text: 'Too many bad requests. Please try again later! ⛔',
+                type: 'error',
+            },
+            server_full: {
+                text: 'The server is full right now. Please try again in a few minutes! 🈵',
+                type: 'error',
+            },
+            payload_too_large: {
+                text: 'That request was too large. Please reload the page and try again! 📦',
+                type: 'error',
+            },
+            request_timeout: {
+                text: 'The server took too long to answer. Please try again! ⏱️',
+                type: 'error',
+            },
+            internal_error: {
+                text: 'Something went wrong on our side. Please try again! ❗',
+                type: 'error',
+            },
             unknown_error: {
                 text: 'An unexpected error occurred. ❗',
                 type: 'error',
@@ -198,9 +313,11 @@ window.gameApp = function () {
             this.clearDOMCache();
         },
         initTheme() {
-            const savedTheme = localStorage.getItem('theme') || 'light';
-            this.isDarkMode = savedTheme === 'dark';
-            document.documentElement.setAttribute('data-bs-theme', savedTheme);
+            // The page is rendered in the session's theme; theme.js has
+            // already resolved "auto".
+            this.isDarkMode =
+                document.documentElement.getAttribute('data-bs-theme') ===
+                'dark';
         },
         _handleTriggerHeader(header) {
             if (!header) {
@@ -214,17 +331,32 @@ window.gameApp = function () {
                 if (typeof parsed['clear-completed-words'] !== 'undefined') {
                     this.clearCompletedWords();
                 }
+                if (Array.isArray(parsed['achievement-unlocked'])) {
+                    this.announceAchievements(parsed['achievement-unlocked']);
+                }
+                if (
+                    parsed['session-expired'] ||
+                    parsed.server_error_code === 'session_expired'
+                ) {
+                    this.resetGameState();
+                    this.tempCurrentGuess = null;
+                }
                 if (parsed.server_error_code) {
                     const code = parsed.server_error_code;
+                    const details = parsed.server_error_details;
                     const info = this.errorCodeMessages[code] || {
                         text: `An unexpected error occurred. (code: ${code}) ❗`,
                         type: 'error',
                     };
                     this.lastServerError = code;
                     this.keepInputAfterError = true;
-                    this.showToastNotification(info.text, info.type);
+                    this.showToastNotification(
+                        this.describeViolation(code, details) || info.text,
+                        info.type
+                    );
                     this.submittingGuess = false;
                     this.shakeCurrentRow();
+                    this.highlightViolations(details);
                 } else {
                     this.lastServerError = '';
                     this.updateGameState();
@@ -239,9 +371,20 @@ window.gameApp = function () {
                 }
             }
         },
+        // responseErrorCode reads the code of a JSON error envelope, such as
+        // the one a "server full" refusal comes with.
+        responseErrorCode(xhr) {
+            try {
+                return JSON.parse(xhr.responseText).error?.code;
+            } catch {
+                return undefined;
+            }
+        },
         setupHTMXHandlers() {
             document.body.addEventListener('htmx:afterSwap', (evt) => {
+                if (this.isRaceBoardEvent(evt)) return;
                 this.submittingGuess = false;
+                this.guessKey = null;
                 this.clearDOMCache();
                 this.restoreUserInput();
                 const targetEl =
@@ -256,7 +399,9 @@ window.gameApp = function () {
                 }
             });
 
-            document.body.addEventListener('htmx:beforeSwap', () => {
+            document.body.addEventListener('htmx:beforeSwap', (evt) => {
+                if (this.isRaceBoardEvent(evt) || this.isRateLimitNotice(evt))
+                    return;
                 if (this.currentGuess) {
                     this.tempCurrentGuess = this.currentGuess;
                     this.tempCurrentRow = this.currentRow;
@@ -264,6 +409,14 @@ window.gameApp = function () {
             });
 
             document.body.addEventListener('htmx:responseError', (evt) => {
+                const info =
+                    this.errorCodeMessages[
+                        this.responseErrorCode(evt.detail.xhr)
+                    ];
+                if (info) {
+                    this.showToastNotification(info.text, info.type);
+                    return;
+                }
                 const message =
                     evt.detail.xhr.status === 429
                         ? 'Too many requests. Please slow down!'
@@ -271,7 +424,10 @@ window.gameApp = function () {
                 this.showToastNotification(message, 'warning');
             });
 
+            // The guess may still have reached the server; submitting it
+            // again reuses its idempotency key.
             document.body.addEventListener('htmx:sendError', () => {
+                this.submittingGuess = false;
                 this.showToastNotification(
                     'Network error. Check your connection!',
                     'error'
@@ -279,6 +435,7 @@ window.gameApp = function () {
             });
 
             document.body.addEventListener('htmx:timeout', () => {
+                this.submittingGuess = false;
                 this.showToastNotification(
                     'Request timed out. Please try again!',
                     'error'
@@ -286,7 +443,18 @@ window.gameApp = function () {
             });
 
             document.body.addEventListener('htmx:afterSettle', (evt) => {
+                if (this.isRateLimitNotice(evt)) return;
                 const xhr = evt?.detail?.xhr;
+                if (this.isRaceBoardEvent(evt)) {
+                    const header = xhr?.getResponseHeader?.('HX-Trigger');
+                    if (header && header.includes('race-finished')) {
+                        htmx.ajax('GET', routePath('/game-state'), {
+                            target: SELECTORS.GAME_CONTENT_CONTAINER,
+                            swap: 'innerHTML',
+                        });
+                    }
+                    return;
+                }
                 if (xhr && typeof xhr.getResponseHeader === 'function') {
                     this._handleTriggerHeader(
                         xhr.getResponseHeader('HX-Trigger')
@@ -297,20 +465,100 @@ window.gameApp = function () {
             });
 
             if (window.htmx) {
+                // Rate limited requests, and ones the server failed, come
+                // with a notice fragment that the server retargets at
+                // SELECTORS.RATE_LIMIT_NOTICE.
+                if (Array.isArray(htmx.config.responseHandling)) {
+                    htmx.config.responseHandling.unshift(
+                        { code: '429', swap: true, error: false },
+                        { code: '500', swap: true, error: false }
+                    );
+                }
                 htmx.on('htmx:configRequest', (evt) => {
-                    let token = readCookie('csrf_token');
-                    if (!token) {
-                        const meta = document.querySelector(
-                            SELECTORS.CSRF_META
-                        );
-                        token = meta ? meta.getAttribute('content') : '';
-                    }
+                    const token = readCsrfToken();
                     if (token) {
                         evt.detail.headers['X-CSRF-Token'] = token;
                     }
+                    // The poll that starts the next word of an auto-continued
+                    // game skips the words completed so far.
+                    if (evt.detail.elt?.hasAttribute('data-auto-continue')) {
+                        const completedWords = this.getCompletedWords().slice(
+                            -COMPLETED_WORDS_SENT
+                        );
+                        if (completedWords.length > 0) {
+                            evt.detail.parameters.completedWords =
+                                JSON.stringify(completedWords);
+                        }
+                    }
                 });
+                htmx.on('htmx:afterRequest', (evt) => {
+                    storeCsrfToken(
+                        evt.detail.xhr?.getResponseHeader('X-CSRF-Token')
+                    );
+                });
+            }
+        },
+        async requestSolverHint() {
+            try {
+                const response = await fetch(routePath('/api/v1/hint/next'), {
+                    method: 'POST',
+                    headers: {
+                        Accept: 'application/json',
+                        'X-CSRF-Token': readCsrfToken(),
+                    },
+                });
+                storeCsrfToken(response.headers.get('X-CSRF-Token'));
+                const data = await response.json();
+                if (!response.ok) {
+                    const code = data?.error?.code || 'unknown_error';
+                    const info =
+                        this.errorCodeMessages[code] ||
+                        this.errorCodeMessages.unknown_error;
+                    this.showToastNotification(info.text, info.type);
+                    return;
+                }
+                const words = data.suggestions.map((s) => s.word).join(', ');
+                this.showToastNotification(
+                    `Try: ${words} (${data.remaining} possible, ${data.hints_left} left)`,
+                    'info'
+                );
+            } catch {
+                this.showToastNotification(
+                    this.errorCodeMessages.unknown_error.text,
+                    'error'
+                );
             }
         },
+        async checkWord(word) {
+            if (!this.validateEnabled) return;
+            try {
+                const response = await fetch(
+                    routePath(
+                        `/api/v1/validate?word=${encodeURIComponent(word)}`
+                    ),
+                    { headers: { Accept: 'application/json' } }
+                );
+                if (response.status === 404) {
+                    this.validateEnabled = false;
+                    return;
+                }
+                if (!response.ok) return;
+                const data = await response.json();
+                if (!data.valid && this.currentGuess === word) {
+                    const info = this.errorCodeMessages.word_not_accepted;
+                    this.showToastNotification(info.text, info.type);
+                }
+            } catch {
+                // The check is advisory; the guess is validated on submit.
+            }
+        },
+        isRaceBoardEvent(evt) {
+            return evt?.detail?.elt?.id === 'race-board';
+        },
+        isRateLimitNotice(evt) {
+            const target = evt?.detail?.target || evt?.target;
+            return target?.matches?.(SELECTORS.RATE_LIMIT_NOTICE) === true;
+        },
         restoreUserInput() {
             if (this.tempCurrentGuess && !this.currentGuess) {
                 this.currentGuess = this.tempCurrentGuess;
@@ -338,15 +586,54 @@ window.gameApp = function () {
                 } else {
                     tile.classList.remove(CSS_CLASSES.FILLED);
                 }
+                tile.classList.toggle(
+                    CSS_CLASSES.ASSIST,
+                    i === 0 && this.isAssisted(this.currentGuess)
+                );
             });
         }, 50),
         shakeCurrentRow() {
+            if (!this.motionAllowed('shake')) return;
             const row = this.getGuessRows()?.[Math.max(0, this.currentRow)];
             if (row) {
                 row.classList.add(CSS_CLASSES.SHAKE);
                 setTimeout(() => row.classList.remove(CSS_CLASSES.SHAKE), 500);
             }
         },
+        describeViolation(code, details) {
+            const violation = details?.violations?.[0];
+            if (code !== 'hard_mode_violation' || !violation) return '';
+            return violation.position
+                ? `Hard mode: ${violation.letter} must stay in position ${violation.position}! 💪`
+                : `Hard mode: your guess must contain ${violation.letter}! 💪`;
+        },
+        // Marks the tiles of the current row at the positions the server
+        // refused, and the keys of letters that had to be reused.
+        highlightViolations(details) {
+            if (!details) return;
+            const marked = [];
+            const row = this.getGuessRows()?.[Math.max(0, this.currentRow)];
+            const tiles = row ? row.querySelectorAll('.tile') : [];
+            (details.positions || []).forEach((position) => {
+                if (tiles[position - 1]) marked.push(tiles[position - 1]);
+            });
+            (details.violations || [])
+                .filter((violation) => !violation.position)
+                .forEach((violation) => {
+                    const key = document.querySelector(
+                        `.key-button[data-key="${CSS.escape(violation.letter)}"]`
+                    );
+                    if (key) marked.push(key);
+                });
+            marked.forEach((el) => el.classList.add(CSS_CLASSES.VIOLATION));
+            setTimeout(
+                () =>
+                    marked.forEach((el) =>
+                        el.classList.remove(CSS_CLASSES.VIOLATION)
+                    ),
+                1500
+            );
+        },
         handleKeyInput(key, evt) {
             if (this.gameOver) {
                 this.showToastNotification(
@@ -368,7 +655,7 @@ window.gameApp = function () {
             } else if (key === 'Backspace' || key === 'BACKSPACE') {
                 this.deleteLetter();
             } else if (REGEX.LETTER.test(key)) {
-                this.addLetter(key.toUpperCase());
+                this.addLetter(key.normalize('NFC').toUpperCase());
             }
         },
         handleKeyPress(e) {
@@ -381,6 +668,9 @@ window.gameApp = function () {
             if (this.currentGuess.length < WORD_LENGTH) {
                 this.currentGuess += letter;
                 this.updateDisplay();
+                if (this.currentGuess.length === WORD_LENGTH) {
+                    this.checkWord(this.currentGuess);
+                }
             } else {
                 this.showToastNotification(
                     `Word is already ${WORD_LENGTH} letters! Press Enter to submit!`,
@@ -389,8 +679,20 @@ window.gameApp = function () {
                 this.shakeCurrentRow();
             }
         },
+        // assistLetter is the first letter an assisted game reveals, which
+        // the current row starts with and which cannot be deleted.
+        assistLetter() {
+            return (
+                document.querySelector(SELECTORS.ASSIST_ROW)?.dataset.assist ||
+                ''
+            );
+        },
+        isAssisted(guess) {
+            const letter = this.assistLetter();
+            return letter !== '' && guess?.[0] === letter;
+        },
         deleteLetter() {
-            if (this.currentGuess.length > 0) {
+            if (this.currentGuess.length > this.assistLetter().length) {
                 this.currentGuess = this.currentGuess.slice(0, -1);
                 this.updateDisplay();
             }
@@ -398,15 +700,24 @@ window.gameApp = function () {
         toggleTheme() {
             this.isDarkMode = !this.isDarkMode;
             const theme = this.isDarkMode ? 'dark' : 'light';
-            document.documentElement.setAttribute('data-bs-theme', theme);
-            localStorage.setItem('theme', theme);
+            const root = document.documentElement;
+            root.dataset.theme = theme;
+            root.setAttribute('data-bs-theme', theme);
+            htmx.ajax('POST', routePath('/settings/theme'), {
+                values: { theme },
+                swap: 'none',
+            });
         },
         updateGameState() {
             const board = document.querySelector(SELECTORS.GAME_BOARD);
             if (!board) return;
 
             const errEl = board.querySelector('[data-error-code]');
-            if (errEl) {
+            // A guess from a stale board comes back with the server's board,
+            // which is read below like any other.
+            const outOfSync =
+                errEl?.getAttribute('data-error-code') === 'state_out_of_sync';
+            if (errEl && !outOfSync) {
                 const code = errEl.getAttribute('data-error-code');
                 if (code) {
                     const info = this.errorCodeMessages[code] || {
@@ -421,12 +732,11 @@ window.gameApp = function () {
             if (this.keepInputAfterError) {
                 this.keepInputAfterError = false;
             } else {
-                this.currentGuess = '';
+                this.currentGuess = this.assistLetter();
             }
 
-            const gameOverContainer = board.parentElement.querySelector(
-                '.mt-3.p-3.bg-body-secondary'
-            );
+            const gameOverContainer =
+                board.parentElement.querySelector('#game-summary');
             const wasGameOver = this.gameOver;
             this.gameOver = gameOverContainer !== null;
             if (!wasGameOver && this.gameOver) {
@@ -434,6 +744,18 @@ window.gameApp = function () {
             }
 
             const rows = this.getGuessRows();
+            if (board.dataset.boardCount) {
+                this.currentRow = Number(board.dataset.currentRow) || 0;
+                this.updateKeyboardColors(rows);
+                const newRows = document.querySelectorAll(SELECTORS.NEW_ROW);
+                newRows.forEach((row) => this.animateRow(row));
+                if (board.dataset.won === 'true' && newRows.length > 0) {
+                    this.launchConfetti();
+                }
+                if (outOfSync) this.updateDisplay();
+                return;
+            }
+
             let completedRows = 0;
             rows.forEach((row) => {
                 const tiles = row.querySelectorAll(SELECTORS.FILLED_TILE);
@@ -449,6 +771,7 @@ window.gameApp = function () {
             });
 
             this.currentRow = Math.min(completedRows, rows.length - 1);
+            if (outOfSync) this.updateDisplay();
             this.updateKeyboardColors(rows);
             this.animateNewGuess(rows);
             this.checkForWin(rows, gameOverContainer);
@@ -483,8 +806,36 @@ window.gameApp = function () {
             if (guessInput) {
                 guessInput.value = this.currentGuess;
             }
+            const keyInput = document.querySelector(SELECTORS.GUESS_KEY_INPUT);
+            if (keyInput) {
+                keyInput.value = this.idempotencyKey();
+            }
+            const board = document.querySelector(SELECTORS.GAME_BOARD);
+            const rowInput = document.querySelector(SELECTORS.GUESS_ROW_INPUT);
+            if (rowInput) {
+                rowInput.value = this.currentRow;
+            }
+            const versionInput = document.querySelector(
+                SELECTORS.GUESS_VERSION_INPUT
+            );
+            if (versionInput) {
+                versionInput.value = board?.dataset.version ?? '';
+            }
             htmx.trigger(SELECTORS.GUESS_FORM, 'submit');
         },
+        // idempotencyKey keeps the same key until a response arrives, so a
+        // guess resent after a lost response is answered with the first
+        // response rather than being played twice.
+        idempotencyKey() {
+            const attempt = `${this.currentRow}:${this.currentGuess}`;
+            if (!this.guessKey || this.guessKey.attempt !== attempt) {
+                const id = window.crypto?.randomUUID
+                    ? crypto.randomUUID()
+                    : `${Date.now()}-${Math.random().toString(36).slice(2)}`;
+                this.guessKey = { attempt, id };
+            }
+            return this.guessKey.id;
+        },
         updateKeyboardColors(rows) {
             this.keyStatus = {};
             rows.forEach((row) => {
@@ -516,22 +867,39 @@ window.gameApp = function () {
         },
         animateNewGuess(allRows) {
             const rows = allRows || this.getGameRows();
-            const row = rows?.[this.currentRow - 1];
+            const row =
+                document.querySelector(SELECTORS.NEW_ROW) ||
+                rows?.[this.currentRow - 1];
+            this.animateRow(row);
+        },
+        // Reports whether the board allows an animation: the server flags
+        // the ones a player turned off with reduced motion.
+        motionAllowed(kind) {
+            const board = document.querySelector(SELECTORS.GAME_BOARD);
+            return !board?.hasAttribute(`data-no-${kind}`);
+        },
+        animateRow(row) {
             if (!row || row.classList.contains(CSS_CLASSES.ANIMATED)) return;
 
             const tiles = row.querySelectorAll(SELECTORS.FILLED_TILE);
             if (tiles.length !== WORD_LENGTH) return;
 
-            tiles.forEach((tile, index) => {
-                tile.style.setProperty('--tile-index', index);
-                setTimeout(() => {
-                    tile.classList.add(CSS_CLASSES.FLIP);
-                    setTimeout(
-                        () => tile.classList.add(CSS_CLASSES.FLIP_REVEALED),
-                        300
+            if (this.motionAllowed('reveal')) {
+                tiles.forEach((tile, index) => {
+                    const order = Number(tile.dataset.revealOrder ?? index);
+                    const delay = Number(
+                        tile.dataset.revealDelayMs ?? order * ANIMATION_DELAY
                     );
-                }, index * ANIMATION_DELAY);
-            });
+                    tile.style.setProperty('--tile-index', order);
+                    setTimeout(() => {
+                        tile.classList.add(CSS_CLASSES.FLIP);
+                        setTimeout(
+                            () => tile.classList.add(CSS_CLASSES.FLIP_REVEALED),
+                            300
+                        );
+                    }, delay);
+                });
+            }
             row.classList.add(CSS_CLASSES.ANIMATED);
             row.classList.remove('submitting');
 
@@ -567,7 +935,10 @@ window.gameApp = function () {
 
             if (winningRow) {
                 this.gameOver = true;
-                if (!winningRow.classList.contains(CSS_CLASSES.WINNER)) {
+                if (
+                    this.motionAllowed('celebrate') &&
+                    !winningRow.classList.contains(CSS_CLASSES.WINNER)
+                ) {
                     winningRow.classList.add(CSS_CLASSES.WINNER);
                     winningRow
                         .querySelectorAll(SELECTORS.TILE)
@@ -615,6 +986,7 @@ window.gameApp = function () {
             }
         },
         launchConfetti() {
+            if (!this.motionAllowed('celebrate')) return;
             if (
                 !window._confettiScriptLoaded &&
                 typeof window.confetti !== 'function'
@@ -695,7 +1067,10 @@ window.gameApp = function () {
                 }
             });
 
-            let emojiGrid = `Vortludo ${
+            const label =
+                document.querySelector(SELECTORS.GAME_BOARD)?.dataset
+                    .gameLabel || 'Vortludo';
+            let emojiGrid = `${label} ${
                 hasWon ? completedRowCount : 'X'
             }/6\n\n`;
 
@@ -771,6 +1146,18 @@ window.gameApp = function () {
                 );
             }
         },
+        // Each unlocked achievement gets its own toast, after any the
+        // finished game raised itself.
+        announceAchievements(unlocked) {
+            unlocked.forEach((achievement, i) => {
+                setTimeout(() => {
+                    this.showToastNotification(
+                        `${achievement.icon} Achievement unlocked: ${achievement.name}!`,
+                        'success'
+                    );
+                }, ACHIEVEMENT_TOAST_DELAY * (i + 1));
+            });
+        },
         showToastNotification(message, type = 'info') {
             this.toastMessage = message;
             this.toastType = type;
@@ -833,7 +1220,9 @@ window.gameApp = function () {
             );
         },
         prepareNewGameData(event) {
-            const completedWords = this.getCompletedWords();
+            const completedWords = this.getCompletedWords().slice(
+                -COMPLETED_WORDS_SENT
+            );
             const form = event.target;
             const completedWordsInput = form.querySelector(
                 'input[name="completedWords"]'
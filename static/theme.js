@@ -0,0 +1,16 @@
+/**
+ * Resolves the "auto" theme before the page is first painted, and follows
+ * the browser's colour scheme while it stays auto. Pages in a light or dark
+ * theme are rendered in it by the server.
+ */
+(() => {
+    const root = document.documentElement;
+    const dark = window.matchMedia('(prefers-color-scheme: dark)');
+    const apply = () => {
+        if (root.dataset.theme === 'auto') {
+            root.setAttribute('data-bs-theme', dark.matches ? 'dark' : 'light');
+        }
+    };
+    apply();
+    dark.addEventListener('change', apply);
+})();